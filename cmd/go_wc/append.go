@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// appendRecord is one line of an --output-append log: a file's result, in
+// the daemon's JSON form, tagged with the run's time and host, as described
+// by jsonSchema.
+type appendRecord struct {
+	SchemaVersion int    `json:"schema_version"`
+	Time          string `json:"time"`
+	Host          string `json:"host"`
+	remoteResult
+	FilenameBase64 string    `json:"filename_base64,omitempty"` // see jsonResult
+	Metadata       *fileMeta `json:"metadata,omitempty"`
+}
+
+// writeAppendLog writes one JSON line per input, with the counts m
+// selects, failed ones included with their error, all stamped with the run
+// start in UTC. meta holds the --with-metadata details, if any.
+func writeAppendLog(w io.Writer, all []wc.FileResult, m wc.Metrics, start time.Time, meta map[string]*fileMeta, name func(string) string) error {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	enc := json.NewEncoder(w)
+	when := start.UTC().Format(time.RFC3339)
+	for _, r := range all {
+		rec := appendRecord{SchemaVersion: jsonSchemaVersion, Time: when, Host: host, remoteResult: toRemoteResult(r, m), Metadata: meta[r.Filename]}
+		rec.Filename = name(r.Filename)
+		rec.FilenameBase64 = nameBytes(rec.Filename)
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openOutput opens the --output file: a replacement for it that commit
+// renames into place or, with --output-append, the file itself, appended to.
+func openOutput(cfg cliConfig) (*outputFile, error) {
+	if cfg.outputAppend {
+		f, err := os.OpenFile(cfg.output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		return &outputFile{File: f}, nil
+	}
+	return createOutput(cfg.output)
+}
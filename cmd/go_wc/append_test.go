@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestWriteAppendLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	cfg := cliConfig{output: path, outputAppend: true}
+	all := []wc.FileResult{
+		{Filename: "a.txt", Lines: 2, Words: 5},
+		{Filename: "gone", Err: errors.New("no such file")},
+	}
+	start := time.Date(2026, 5, 4, 3, 2, 1, 0, time.FixedZone("X", -3600))
+	for i := 0; i < 2; i++ {
+		f, err := openOutput(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writeAppendLog(f, all, wc.Metrics{Lines: true, Words: true}, start, nil, filepath.Base); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var recs []map[string]any
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var rec map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		recs = append(recs, rec)
+	}
+	if len(recs) != 4 {
+		t.Fatalf("got %d records, want 4 from two runs", len(recs))
+	}
+	host, _ := os.Hostname()
+	first := recs[0]
+	if first["time"] != "2026-05-04T04:02:01Z" || first["filename"] != "a.txt" || first["lines"] != 2.0 {
+		t.Errorf("first record = %v", first)
+	}
+	if host != "" && first["host"] != host {
+		t.Errorf("host = %v, want %q", first["host"], host)
+	}
+	if recs[1]["error"] != "no such file" {
+		t.Errorf("failed file record = %v", recs[1])
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// outputFile is a report file being written. Unless it is written in place,
+// it lives under a temporary name next to its destination until commit
+// syncs it and renames it over the destination, so that a reader sees the
+// previous report or the complete new one, never a partial one, even if
+// go_wc is killed or the machine crashes midway.
+type outputFile struct {
+	*os.File
+	dest string // path renamed to by commit, or "" if written in place
+}
+
+// createOutput starts writing the file path. Paths that exist but are not
+// regular files, such as /dev/null or a named pipe, are written in place.
+func createOutput(path string) (*outputFile, error) {
+	perm := os.FileMode(0o644)
+	if st, err := os.Stat(path); err == nil {
+		if !st.Mode().IsRegular() {
+			f, err := os.Create(path)
+			if err != nil {
+				return nil, err
+			}
+			return &outputFile{File: f}, nil
+		}
+		perm = st.Mode().Perm()
+	}
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, "."+base+".tmp*")
+	if err != nil {
+		return nil, err
+	}
+	// CreateTemp makes the file private; give it the mode the report had,
+	// where the file system keeps modes at all.
+	_ = f.Chmod(perm)
+	return &outputFile{File: f, dest: path}, nil
+}
+
+// commit finishes the file and moves it into place.
+func (o *outputFile) commit() error {
+	if o.dest == "" {
+		return o.Close()
+	}
+	if err := o.Sync(); err != nil {
+		o.abort()
+		return err
+	}
+	if err := o.Close(); err != nil {
+		os.Remove(o.Name())
+		return err
+	}
+	if err := os.Rename(o.Name(), o.dest); err != nil {
+		os.Remove(o.Name())
+		return err
+	}
+	syncDir(filepath.Dir(o.dest))
+	return nil
+}
+
+// abort discards the file, leaving the destination as it was.
+func (o *outputFile) abort() {
+	o.Close()
+	if o.dest != "" {
+		os.Remove(o.Name())
+	}
+}
+
+// syncDir makes a rename in dir durable. Not every system can sync a
+// directory (Windows cannot), so failures are ignored.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestOutputFileCommit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.txt")
+	if err := os.WriteFile(path, []byte("old\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	o, err := createOutput(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.WriteString("new\n"); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "old\n" {
+		t.Errorf("before commit the report reads %q, want the old one", b)
+	}
+	if err := o.commit(); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "new\n" {
+		t.Errorf("after commit the report reads %q, want %q", b, "new\n")
+	}
+	if st, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && st.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want the old file's -rw-------", st.Mode())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want only the report", len(entries))
+	}
+}
+
+func TestOutputFileAbort(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.txt")
+	o, err := createOutput(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.WriteString("partial")
+	o.abort()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("abort left %d entries behind", len(entries))
+	}
+}
+
+func TestOutputFileInPlace(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no /dev/null")
+	}
+	o, err := createOutput(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.dest != "" {
+		t.Errorf("%s would be replaced by %s", os.DevNull, o.Name())
+	}
+	if err := o.commit(); err != nil {
+		t.Fatal(err)
+	}
+}
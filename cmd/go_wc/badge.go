@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+// badgeColors are the named colors of shields.io badges.
+var badgeColors = map[string]string{
+	"brightgreen": "#4c1",
+	"green":       "#97ca00",
+	"yellowgreen": "#a4a61d",
+	"yellow":      "#dfb317",
+	"orange":      "#fe7d37",
+	"red":         "#e05d44",
+	"blue":        "#007ec6",
+	"lightgrey":   "#9f9f9f",
+	"grey":        "#555",
+}
+
+// badgeColor returns the SVG color of a --color value: a name of
+// badgeColors or a hex color, with or without its "#".
+func badgeColor(s string) (string, bool) {
+	if c, ok := badgeColors[s]; ok {
+		return c, true
+	}
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 3 && len(hex) != 6 {
+		return "", false
+	}
+	if _, err := strconv.ParseUint(hex, 16, 32); err != nil {
+		return "", false
+	}
+	return "#" + hex, true
+}
+
+// compactCount formats n as shields.io does counts: 950, 12.3k, 4.5M.
+func compactCount(n uint64) string {
+	const units = "kMGTPE"
+	if n < 1000 {
+		return strconv.FormatUint(n, 10)
+	}
+	v, i := float64(n)/1000, 0
+	for v >= 999.5 && i < len(units)-1 {
+		v /= 1000
+		i++
+	}
+	s := strconv.FormatFloat(v, 'f', 1, 64)
+	if v >= 100 {
+		s = strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return strings.TrimSuffix(s, ".0") + units[i:i+1]
+}
+
+// badgeTextWidth estimates the width in pixels of s in 11px Verdana, the
+// font of shields.io badges, from the widths of classes of characters.
+func badgeTextWidth(s string) int {
+	w := 0.0
+	for _, r := range s {
+		switch {
+		case strings.ContainsRune("fijlrtI!.,:;'|()[] ", r):
+			w += 4
+		case strings.ContainsRune("mwMW@%", r):
+			w += 10.5
+		case r >= 'A' && r <= 'Z':
+			w += 7.5
+		case r < 0x80:
+			w += 6.8
+		default:
+			w += 8
+		}
+	}
+	return int(w + 0.5)
+}
+
+// renderBadge returns a flat shields.io-style SVG badge showing label on
+// grey and value on color.
+func renderBadge(label, value, color string) string {
+	lw, vw := badgeTextWidth(label)+10, badgeTextWidth(value)+10
+	w := lw + vw
+	l, v := html.EscapeString(label), html.EscapeString(value)
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`+"\n", w, l, v)
+	fmt.Fprintf(&b, "<title>%s: %s</title>\n", l, v)
+	b.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>` + "\n")
+	fmt.Fprintf(&b, `<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`+"\n", w)
+	fmt.Fprintf(&b, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`+"\n",
+		lw, lw, vw, color, w)
+	b.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">` + "\n")
+	for _, t := range []struct {
+		x    int
+		text string
+	}{{lw / 2, l}, {lw + vw/2, v}} {
+		fmt.Fprintf(&b, `<text x="%d" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%d" y="14">%s</text>`+"\n", t.x, t.text, t.x, t.text)
+	}
+	b.WriteString("</g>\n</svg>\n")
+	return b.String()
+}
+
+// fileSetFlags defines on fset the options of the count command that
+// select files, --git, --files-from and --files0-from, with --encoding
+// and -j, stored in cfg.
+func fileSetFlags(fset *flag.FlagSet, cfg *cliConfig) {
+	fset.BoolVar(&cfg.git, "git", false, "")
+	fset.StringVar(&cfg.filesFrom, "files-from", "", "")
+	fset.StringVar(&cfg.files0From, "files0-from", "", "")
+	fset.StringVar(&cfg.encoding, "encoding", "", "")
+	cfg.jobs = runtime.GOMAXPROCS(0)
+	fset.IntVar(&cfg.jobs, "jobs", cfg.jobs, "")
+	fset.IntVar(&cfg.jobs, "j", cfg.jobs, "")
+}
+
+// countFileSet counts with m the files that the fileSetFlags in cfg and
+// the operands select, and returns their total and number. Files that
+// cannot be counted are reported, and fail it.
+func countFileSet(cfg cliConfig, operands []string, m wc.Metrics) (wc.FileResult, int, error) {
+	if cfg.git && (cfg.filesFrom != "" || cfg.files0From != "") || cfg.filesFrom != "" && cfg.files0From != "" {
+		return wc.FileResult{}, 0, errors.New("--git, --files-from and --files0-from cannot be combined")
+	}
+	inputs, err := collectInputs(cfg, operands)
+	if err != nil {
+		return wc.FileResult{}, 0, err
+	}
+	cs := countSettings{
+		metrics: m,
+		opts:    wc.Options{BufferSize: 1024 * 1024, Locale: locale.Detect(cfg.encoding)},
+	}
+	var tot wc.Totals
+	failed := 0
+	for _, r := range countInputs(inputs, cs, cfg.jobs) {
+		if r.Err != nil {
+			reportFailure(r.Filename, r.Err)
+			failed++
+			continue
+		}
+		tot.Add(r)
+	}
+	if failed > 0 {
+		return wc.FileResult{}, 0, fmt.Errorf("%d of %d files could not be counted", failed, len(inputs))
+	}
+	return tot.Result(), len(inputs), nil
+}
+
+// runBadge implements "go_wc badge": count the files, as the count command
+// would with --git or --files-from, and write an SVG badge of the total of
+// one metric, for a README to show counts kept current by a docs build.
+func runBadge(args []string, stdout, stderr io.Writer) int {
+	var cfg cliConfig
+	fset := flag.NewFlagSet("go_wc badge", flag.ContinueOnError)
+	fset.SetOutput(io.Discard)
+	metricName := fset.String("metric", "lines", "")
+	label := fset.String("label", "", "")
+	colorName := fset.String("color", "blue", "")
+	fset.StringVar(&cfg.output, "output", "", "")
+	fileSetFlags(fset, &cfg)
+	if err := fset.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	var metric *treeMetric
+	for i := range treeMetrics {
+		if treeMetrics[i].name == *metricName {
+			metric = &treeMetrics[i]
+		}
+	}
+	if metric == nil {
+		fmt.Fprintf(stderr, "go_wc: badge: unknown metric %q (want lines, words, chars or bytes)\n", *metricName)
+		return 1
+	}
+	color, ok := badgeColor(*colorName)
+	if !ok {
+		fmt.Fprintf(stderr, "go_wc: badge: invalid color %q\n", *colorName)
+		return 1
+	}
+	if *label == "" {
+		*label = metric.name
+	}
+
+	var m wc.Metrics
+	metric.set(&m)
+	total, _, err := countFileSet(cfg, fset.Args(), m)
+	if err != nil {
+		// a badge missing some files would look right and be wrong
+		fmt.Fprintf(stderr, "go_wc: badge: %v\n", err)
+		return 1
+	}
+
+	svg := renderBadge(*label, compactCount(metric.get(total)), color)
+	if cfg.output == "" {
+		_, err = io.WriteString(stdout, svg)
+	} else {
+		var f *outputFile
+		if f, err = createOutput(cfg.output); err == nil {
+			if _, err = f.WriteString(svg); err != nil {
+				f.abort()
+			} else {
+				err = f.commit()
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "go_wc: %v\n", err)
+		return 1
+	}
+	return 0
+}
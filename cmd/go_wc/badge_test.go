@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompactCount(t *testing.T) {
+	for n, want := range map[uint64]string{
+		0: "0", 999: "999", 1000: "1k", 1049: "1k", 1050: "1.1k", 12345: "12.3k",
+		123456: "123k", 999499: "999k", 999500: "1M", 4500000: "4.5M", 1 << 63: "9.2E",
+	} {
+		if got := compactCount(n); got != want {
+			t.Errorf("compactCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestBadgeColor(t *testing.T) {
+	for in, want := range map[string]string{"brightgreen": "#4c1", "ff8800": "#ff8800", "#abc": "#abc"} {
+		if got, ok := badgeColor(in); !ok || got != want {
+			t.Errorf("badgeColor(%q) = %q, %v, want %q", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"", "purple-ish", "#12345", "ggg"} {
+		if _, ok := badgeColor(in); ok {
+			t.Errorf("badgeColor(%q): expected it to be invalid", in)
+		}
+	}
+}
+
+func TestRunBadge(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.md"), filepath.Join(dir, "b.md")
+	os.WriteFile(a, []byte("one two\nthree\n"), 0o644)
+	os.WriteFile(b, []byte("four <five> & six\n"), 0o644)
+	out := filepath.Join(dir, "badge.svg")
+
+	var stdout, stderr strings.Builder
+	if code := runBadge([]string{"--metric=words", "--label=docs <words>", "--color=green", "--output", out, a, b}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	svg, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`aria-label="docs &lt;words&gt;: 7"`, `fill="#97ca00"`, `>7</text>`} {
+		if !strings.Contains(string(svg), want) {
+			t.Errorf("badge lacks %q:\n%s", want, svg)
+		}
+	}
+	var doc struct{ XMLName xml.Name }
+	if err := xml.Unmarshal(svg, &doc); err != nil || doc.XMLName.Local != "svg" {
+		t.Errorf("badge is not an SVG document: %v", err)
+	}
+
+	if code := runBadge([]string{a}, &stdout, &stderr); code != 0 || !strings.Contains(stdout.String(), `aria-label="lines: 2"`) {
+		t.Errorf("default metric: exit %d, output %q", code, stdout.String())
+	}
+	for _, args := range [][]string{
+		{"--metric=pages", a},
+		{"--color=nope", a},
+		{"--output", filepath.Join(dir, "missing.svg"), filepath.Join(dir, "missing.md")},
+	} {
+		if code := runBadge(args, &stdout, &stderr); code != 1 {
+			t.Errorf("%q: exit %d, want 1", args, code)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing.svg")); err == nil {
+		t.Error("a badge was written despite the failure")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+// benchRun is one pass of "go_wc bench" over the files.
+type benchRun struct {
+	elapsed time.Duration
+	bytes   uint64
+}
+
+// mbps returns the throughput of r in MB/s.
+func (r benchRun) mbps() float64 {
+	if r.elapsed <= 0 {
+		return 0
+	}
+	return float64(r.bytes) / 1e6 / r.elapsed.Seconds()
+}
+
+// writeBench prints the time and throughput of every run, then the
+// fastest, median and slowest.
+func writeBench(w io.Writer, runs []benchRun, files int) error {
+	var b strings.Builder
+	for i, r := range runs {
+		fmt.Fprintf(&b, "run %-3d %8.3fs %10.1f MB/s\n", i+1, r.elapsed.Seconds(), r.mbps())
+	}
+	sorted := slices.Clone(runs)
+	slices.SortFunc(sorted, func(a, b benchRun) int { return int(a.elapsed - b.elapsed) })
+	for _, s := range []struct {
+		name string
+		r    benchRun
+	}{{"min", sorted[0]}, {"median", sorted[len(sorted)/2]}, {"max", sorted[len(sorted)-1]}} {
+		fmt.Fprintf(&b, "%-7s %8.3fs %10.1f MB/s\n", s.name, s.r.elapsed.Seconds(), s.r.mbps())
+	}
+	fmt.Fprintf(&b, "%d bytes in %d files per run\n", runs[0].bytes, files)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// runBench implements "go_wc bench": count the files --warmup times
+// untimed, then -n times, and print how long each run took and its
+// throughput, to compare options, builds or machines.
+func runBench(args []string, stdout, stderr io.Writer) int {
+	var cfg cliConfig
+	fset := flag.NewFlagSet("go_wc bench", flag.ContinueOnError)
+	fset.SetOutput(io.Discard)
+	runs := fset.Int("n", 5, "")
+	warmup := fset.Int("warmup", 1, "")
+	selected := metricFlags(fset)
+	fileSetFlags(fset, &cfg)
+	if err := fset.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if *runs < 1 || *warmup < 0 {
+		fmt.Fprintln(stderr, "go_wc: bench: -n must be at least 1 and --warmup not negative")
+		return 1
+	}
+	inputs, err := collectInputs(cfg, fset.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "go_wc: bench: %v\n", err)
+		return 1
+	}
+	if len(inputs) == 0 {
+		fmt.Fprintln(stderr, "usage: go_wc bench [OPTIONS] FILE...")
+		return 1
+	}
+
+	var m wc.Metrics
+	for _, tm := range selected() {
+		tm.set(&m)
+	}
+	cs := countSettings{
+		metrics: m,
+		opts:    wc.Options{BufferSize: 1024 * 1024, Locale: locale.Detect(cfg.encoding)},
+	}
+	var results []benchRun
+	for i := 0; i < *warmup+*runs; i++ {
+		start := time.Now()
+		all := countInputs(inputs, cs, cfg.jobs)
+		r := benchRun{elapsed: time.Since(start)}
+		for _, fr := range all {
+			if fr.Err != nil {
+				// a run that skips a file is not comparable with the others
+				fmt.Fprintf(stderr, "go_wc: %s: %v\n", quoteName(fr.Filename), fr.Err)
+				return 1
+			}
+			r.bytes += fr.Bytes
+		}
+		if i >= *warmup {
+			results = append(results, r)
+		}
+	}
+	if err := writeBench(stdout, results, len(inputs)); err != nil {
+		fmt.Fprintf(stderr, "go_wc: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteBench(t *testing.T) {
+	runs := []benchRun{{3 * time.Second, 6e6}, {time.Second, 6e6}, {2 * time.Second, 6e6}}
+	var b strings.Builder
+	if err := writeBench(&b, runs, 2); err != nil {
+		t.Fatal(err)
+	}
+	want := "run 1      3.000s        2.0 MB/s\n" +
+		"run 2      1.000s        6.0 MB/s\n" +
+		"run 3      2.000s        3.0 MB/s\n" +
+		"min        1.000s        6.0 MB/s\n" +
+		"median     2.000s        3.0 MB/s\n" +
+		"max        3.000s        2.0 MB/s\n" +
+		"6000000 bytes in 2 files per run\n"
+	if b.String() != want {
+		t.Errorf("got\n%swant\n%s", b.String(), want)
+	}
+}
+
+func TestRunBench(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.txt": "one two\n"})
+	var stdout, stderr strings.Builder
+	if code := runBench([]string{"-n", "2", "-l", filepath.Join(dir, "a.txt")}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	if got := strings.Count(stdout.String(), "\nrun ") + 1; got != 2 || !strings.HasSuffix(stdout.String(), "8 bytes in 1 files per run\n") {
+		t.Errorf("output:\n%s", stdout.String())
+	}
+	if code := runBench([]string{filepath.Join(dir, "missing")}, &stdout, &stderr); code != 1 {
+		t.Errorf("missing file: exit %d, want 1", code)
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// checkpointVersion is the format of --checkpoint files: a header line
+// with the version and the countSpec of the run, then one JSON line per
+// counted file, in the order the files finished. A checkpoint only
+// resumes a run with the same countSpec.
+const checkpointVersion = 1
+
+// checkpointInterval is how often recorded results are written out; an
+// interrupted run recounts at most the files finished since.
+const checkpointInterval = time.Second
+
+type checkpointHeader struct {
+	Checkpoint int             `json:"checkpoint"`
+	Settings   json.RawMessage `json:"settings"`
+}
+
+// checkpointRecord is the result of a file and the identity the file had
+// when it was counted.
+type checkpointRecord struct {
+	Path  string `json:"path"` // absolute
+	Size  int64  `json:"size"`
+	MTime int64  `json:"mtime_ns"`
+	storedResult
+}
+
+// storedResult is a FileResult in the daemon's JSON form, plus the extra
+// counts and truncation that form lacks.
+type storedResult struct {
+	remoteResult
+	Counts    []uint64 `json:"counts,omitempty"` // char, string and regexp counts
+	Truncated bool     `json:"truncated,omitempty"`
+}
+
+func storeResult(fr wc.FileResult) storedResult {
+	return storedResult{
+		remoteResult: toRemoteResult(fr, everyCount),
+		Counts:       slices.Concat(fr.CharCounts, fr.StringCounts, fr.RegexpCounts),
+		Truncated:    fr.Truncated,
+	}
+}
+
+// result returns the FileResult s holds, whose Counts start with nChars
+// char counts and nStrings string counts.
+func (s storedResult) result(nChars, nStrings int) wc.FileResult {
+	fr := fromRemoteResult(s.remoteResult)
+	fr.Truncated = s.Truncated
+	counts := slices.Clone(s.Counts)
+	fr.CharCounts = counts[:min(nChars, len(counts))]
+	counts = counts[len(fr.CharCounts):]
+	fr.StringCounts = counts[:min(nStrings, len(counts))]
+	fr.RegexpCounts = counts[len(fr.StringCounts):]
+	return fr
+}
+
+// fileKey is the identity of a file as of a stat: a change of its size or
+// mtime invalidates a recorded result, as it does the daemon's cache.
+type fileKey struct {
+	path  string // absolute
+	size  int64
+	mtime int64
+}
+
+// checkpoint serves the results recorded in the --resume file and records
+// new ones in the --checkpoint file. Its methods are safe for concurrent
+// use and do nothing on a nil checkpoint.
+type checkpoint struct {
+	done     map[string]checkpointRecord // --resume results by absolute path
+	nChars   int                         // char counts that start Counts
+	nStrings int                         // string counts that follow them
+	copyDone bool                        // record resumed results too
+
+	mu      sync.Mutex
+	f       *os.File // nil without --checkpoint
+	w       *bufio.Writer
+	resumed int // results served from done
+	err     error
+	stop    chan struct{} // ends flushLoop
+	stopped chan struct{}
+}
+
+// openCheckpoint loads the --resume file, if any, and starts the
+// --checkpoint file. A --resume file that does not exist is taken as
+// empty, so that the same command line starts and resumes a run. The
+// --checkpoint file is replaced, unless it is the --resume file, which is
+// appended to.
+func openCheckpoint(cfg cliConfig, spec countSpec, nChars, nStrings int) (*checkpoint, error) {
+	want, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	c := &checkpoint{done: make(map[string]checkpointRecord), nChars: nChars, nStrings: nStrings}
+	var valid int64 // length of the --resume file up to its last whole line
+	if cfg.resume != "" {
+		if valid, err = c.load(cfg.resume, want); err != nil {
+			return nil, fmt.Errorf("--resume: %w", err)
+		}
+	}
+	if cfg.checkpoint == "" {
+		return c, nil
+	}
+	appending := false
+	if valid > 0 {
+		st1, err1 := os.Stat(cfg.resume)
+		st2, err2 := os.Stat(cfg.checkpoint)
+		appending = err1 == nil && err2 == nil && os.SameFile(st1, st2)
+	}
+	if appending {
+		c.f, err = os.OpenFile(cfg.checkpoint, os.O_WRONLY, 0)
+		if err == nil {
+			// drop a line cut short by the interruption
+			if err = c.f.Truncate(valid); err == nil {
+				_, err = c.f.Seek(valid, io.SeekStart)
+			}
+			if err != nil {
+				c.f.Close()
+			}
+		}
+	} else {
+		c.f, err = os.Create(cfg.checkpoint)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("--checkpoint: %w", err)
+	}
+	c.w = bufio.NewWriterSize(c.f, 64*1024)
+	c.copyDone = !appending
+	if !appending {
+		hdr, _ := json.Marshal(checkpointHeader{Checkpoint: checkpointVersion, Settings: want})
+		c.w.Write(append(hdr, '\n'))
+	}
+	c.stop, c.stopped = make(chan struct{}), make(chan struct{})
+	go c.flushLoop()
+	return c, nil
+}
+
+// flushLoop writes out and syncs the recorded results every
+// checkpointInterval until close.
+func (c *checkpoint) flushLoop() {
+	defer close(c.stopped)
+	t := time.NewTicker(checkpointInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-c.stop:
+			return
+		}
+		c.mu.Lock()
+		if c.err == nil && c.w.Buffered() > 0 {
+			if c.err = c.w.Flush(); c.err == nil {
+				c.err = c.f.Sync()
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+// load reads the results recorded in the checkpoint file path, which must
+// have been written with the countSpec want, and returns the length of the file
+// up to its last complete line. The last line may have been cut short by
+// an interruption and is then ignored.
+func (c *checkpoint) load(path string, want []byte) (int64, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	valid := bytes.LastIndexByte(data, '\n') + 1
+	lines := bytes.Split(data[:valid], []byte{'\n'})
+	if valid == 0 {
+		return 0, nil
+	}
+	var hdr checkpointHeader
+	if err := json.Unmarshal(lines[0], &hdr); err != nil || hdr.Checkpoint == 0 {
+		return 0, fmt.Errorf("%s: not a checkpoint file", path)
+	}
+	if hdr.Checkpoint != checkpointVersion {
+		return 0, fmt.Errorf("%s: unsupported checkpoint version %d", path, hdr.Checkpoint)
+	}
+	if !bytes.Equal(hdr.Settings, want) {
+		return 0, fmt.Errorf("%s: the checkpoint was written with different counting options", path)
+	}
+	for i, line := range lines[1:] {
+		if len(line) == 0 {
+			continue
+		}
+		var rec checkpointRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return 0, fmt.Errorf("%s:%d: %v", path, i+2, err)
+		}
+		c.done[rec.Path] = rec
+	}
+	return int64(valid), nil
+}
+
+// key returns the identity of the named input, or nil for standard input
+// and files whose size says nothing about their contents.
+func (c *checkpoint) key(name string) *fileKey {
+	if c == nil || name == "-" {
+		return nil
+	}
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil
+	}
+	st, err := os.Stat(abs)
+	if err != nil {
+		return nil
+	}
+	size, ok := statSize(abs, st)
+	if !ok {
+		return nil
+	}
+	return &fileKey{path: abs, size: size, mtime: st.ModTime().UnixNano()}
+}
+
+// lookup returns the recorded result of the input name, with key its
+// identity now, if the file has not changed since it was counted.
+func (c *checkpoint) lookup(name string, key *fileKey) (wc.FileResult, bool) {
+	if c == nil || key == nil {
+		return wc.FileResult{}, false
+	}
+	rec, ok := c.done[key.path]
+	if !ok || rec.Size != key.size || rec.MTime != key.mtime {
+		return wc.FileResult{}, false
+	}
+	fr := rec.result(c.nChars, c.nStrings)
+	fr.Filename = name
+
+	c.mu.Lock()
+	c.resumed++
+	c.mu.Unlock()
+	if c.copyDone {
+		c.write(rec)
+	}
+	return fr, true
+}
+
+// record adds the result of a file counted with identity key. Failed
+// counts are left out, to be retried by a resumed run.
+func (c *checkpoint) record(key *fileKey, fr wc.FileResult) {
+	if c == nil || c.f == nil || key == nil || fr.Err != nil {
+		return
+	}
+	c.write(checkpointRecord{Path: key.path, Size: key.size, MTime: key.mtime, storedResult: storeResult(fr)})
+}
+
+func (c *checkpoint) write(rec checkpointRecord) {
+	if c.f == nil {
+		return
+	}
+	line, err := json.Marshal(rec)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	if err == nil {
+		_, err = c.w.Write(append(line, '\n'))
+	}
+	c.err = err
+}
+
+// close writes out the remaining results and returns the first error
+// writing the checkpoint met, and the number of results resumed.
+func (c *checkpoint) close() (int, error) {
+	if c == nil {
+		return 0, nil
+	}
+	if c.f == nil {
+		return c.resumed, nil
+	}
+	close(c.stop)
+	<-c.stopped
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.err
+	if err == nil {
+		err = c.w.Flush()
+	}
+	if cerr := c.f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return c.resumed, fmt.Errorf("--checkpoint: %w", err)
+	}
+	return c.resumed, nil
+}
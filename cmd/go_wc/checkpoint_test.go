@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	for name, data := range map[string]string{a: "one two\n", b: "three\n"} {
+		if err := os.WriteFile(name, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ck := filepath.Join(dir, "run.ckpt")
+	cfg := cliConfig{checkpoint: ck, resume: ck}
+	m := wc.Metrics{Lines: true, Words: true}
+	spec := countSpec{Metrics: m, CountStrings: []string{"o"}}
+	cs := countSettings{metrics: m, opts: wc.Options{BufferSize: 4096, CountStrings: []string{"o"}}}
+	run := func() ([]wc.FileResult, int) {
+		t.Helper()
+		var err error
+		if cs.checkpoint, err = openCheckpoint(cfg, spec, 0, 1); err != nil {
+			t.Fatal(err)
+		}
+		all := countInputs([]string{a, b}, cs, 1)
+		resumed, err := cs.checkpoint.close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return all, resumed
+	}
+
+	if _, resumed := run(); resumed != 0 {
+		t.Errorf("first run resumed %d results", resumed)
+	}
+	// an interrupted run leaves a partial line; b changes before the resume
+	f, err := os.OpenFile(ck, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"path":"`)
+	f.Close()
+	if err := os.WriteFile(b, []byte("four five six\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	all, resumed := run()
+	if resumed != 1 {
+		t.Errorf("resumed %d results, want 1", resumed)
+	}
+	if r := all[0]; r.Filename != a || r.Words != 2 || len(r.StringCounts) != 1 || r.StringCounts[0] != 2 {
+		t.Errorf("resumed result: %+v", r)
+	}
+	if r := all[1]; r.Words != 3 || r.StringCounts[0] != 1 {
+		t.Errorf("recounted result: %+v", r)
+	}
+
+	data, err := os.ReadFile(ck)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"); len(lines) != 4 {
+		t.Errorf("checkpoint has %d lines, want header and 3 records:\n%s", len(lines), data)
+	}
+	if _, resumed := run(); resumed != 2 {
+		t.Errorf("third run resumed %d results, want 2", resumed)
+	}
+
+	spec.Metrics.Chars = true
+	if _, err := openCheckpoint(cfg, spec, 0, 1); err == nil || !strings.Contains(err.Error(), "different counting options") {
+		t.Errorf("resuming with other metrics: got %v", err)
+	}
+}
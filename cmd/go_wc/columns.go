@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/format"
+)
+
+// Pseudo-columns of --columns, taken from stat rather than counted.
+const (
+	columnSize     = "size"
+	columnMTime    = "mtime"
+	columnFilename = "filename"
+)
+
+// mtimeLayout prints --columns mtime like ls -l --time-style=long-iso.
+const mtimeLayout = "2006-01-02 15:04"
+
+// tableColumn is one entry of --columns: the counts of a metric, or a
+// pseudo-column when metric is zero.
+type tableColumn struct {
+	name   string
+	metric wc.Metrics
+}
+
+// parseColumns parses a --columns list of metric names (as for the daemon,
+// e.g. lines or max-line-bytes) and the pseudo-columns size, mtime and
+// filename, returning the columns in order and the metrics they count.
+func parseColumns(spec string) ([]tableColumn, wc.Metrics, error) {
+	var cols []tableColumn
+	var names []string
+	for _, name := range strings.Split(spec, ",") {
+		switch name {
+		case columnSize, columnMTime, columnFilename:
+			cols = append(cols, tableColumn{name: name})
+			continue
+		case "file":
+			cols = append(cols, tableColumn{name: columnFilename})
+			continue
+		}
+		m, err := wc.MetricsFromStrings([]string{name})
+		if err != nil || name == "all" {
+			return nil, wc.Metrics{}, fmt.Errorf("invalid --columns entry %q (want a metric name, size, mtime or filename)", name)
+		}
+		cols = append(cols, tableColumn{name: name, metric: m})
+		names = append(names, name)
+	}
+	all, _ := wc.MetricsFromStrings(names)
+	return cols, all, nil
+}
+
+// needsStat reports whether cols include a pseudo-column read from stat.
+func needsStat(cols []tableColumn) bool {
+	for _, c := range cols {
+		if c.name == columnSize || c.name == columnMTime {
+			return true
+		}
+	}
+	return false
+}
+
+// printColumns writes the counts as wc does, but with the --columns layout.
+// Counts and sizes are right-aligned to a common width; inputs without stat
+// metadata show "-" for size and mtime. The total sums the sizes and shows
+// the newest mtime.
+func printColumns(out io.Writer, cfg cliConfig, cols []tableColumn, inputs []string, all []wc.FileResult, totals wc.FileResult, m wc.Metrics, meta map[string]*fileMeta) {
+	width := columnWidth(cfg, inputs, all, totals, m)
+	var sizeTotal int64
+	var newest time.Time
+	for _, r := range all {
+		if fm := meta[r.Filename]; fm != nil && r.Err == nil {
+			sizeTotal += fm.Size
+			if fm.modTime.After(newest) {
+				newest = fm.modTime
+			}
+		}
+	}
+	if !cfg.noAlign && cfg.width == 0 && needsStat(cols) {
+		width = max(width, len(strconv.FormatInt(sizeTotal, 10)))
+	}
+	pad := func(s string) string {
+		return fmt.Sprintf("%*s", width, s)
+	}
+	// A file name followed by other columns is padded to line them up.
+	nameWidth := 0
+	if !cfg.noAlign && cols[len(cols)-1].name != columnFilename {
+		nameWidth = len("total")
+		for _, r := range all {
+			nameWidth = max(nameWidth, len(shownName(cfg, r.Filename)))
+		}
+	}
+
+	if cfg.header {
+		var parts []string
+		for _, c := range cols {
+			switch c.name {
+			case columnFilename:
+				parts = append(parts, fmt.Sprintf("%-*s", nameWidth, "file"))
+			case columnMTime:
+				parts = append(parts, fmt.Sprintf("%-*s", len(mtimeLayout), "mtime"))
+			case columnSize:
+				parts = append(parts, pad("size"))
+			default:
+				for _, l := range format.CSVHeader(c.metric, nil)[1:] {
+					parts = append(parts, pad(l))
+				}
+			}
+		}
+		fmt.Fprintln(out, strings.TrimRight(strings.Join(parts, " "), " "))
+	}
+
+	row := func(r wc.FileResult, size string, mtime time.Time) string {
+		var parts []string
+		for _, c := range cols {
+			switch c.name {
+			case columnFilename:
+				name := r.Filename
+				if r.Truncated {
+					name += " (truncated)"
+				}
+				parts = append(parts, fmt.Sprintf("%-*s", nameWidth, name))
+			case columnSize:
+				parts = append(parts, pad(size))
+			case columnMTime:
+				s := "-"
+				if !mtime.IsZero() {
+					s = mtime.Local().Format(mtimeLayout)
+				}
+				parts = append(parts, fmt.Sprintf("%-*s", len(mtimeLayout), s))
+			default:
+				for _, v := range format.CSVRecord(r, c.metric)[1:] {
+					if !c.metric.LongestWord {
+						v = pad(v)
+					} else if v == "" {
+						v = "-"
+					}
+					parts = append(parts, v)
+				}
+			}
+		}
+		return strings.TrimRight(strings.Join(parts, " "), " ")
+	}
+
+	for _, r := range all {
+		if r.Err != nil {
+			reportFailure(r.Filename, r.Err)
+			continue
+		}
+		size, mtime := "-", time.Time{}
+		if fm := meta[r.Filename]; fm != nil {
+			size, mtime = strconv.FormatInt(fm.Size, 10), fm.modTime
+		}
+		r.Filename = shownName(cfg, r.Filename)
+		fmt.Fprintln(out, row(r, size, mtime))
+	}
+	if len(inputs) > 1 {
+		totals.Filename = "total"
+		fmt.Fprintln(out, row(totals, strconv.FormatInt(sizeTotal, 10), newest))
+	}
+}
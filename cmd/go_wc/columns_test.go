@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestParseColumns(t *testing.T) {
+	cols, m, err := parseColumns("lines,size,mtime,file,max-line-length")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range cols {
+		names = append(names, c.name)
+	}
+	if got := strings.Join(names, ","); got != "lines,size,mtime,filename,max-line-length" {
+		t.Errorf("columns = %s", got)
+	}
+	if want := (wc.Metrics{Lines: true, MaxLineBytes: true}); m != want {
+		t.Errorf("metrics = %+v, want %+v", m, want)
+	}
+	if !needsStat(cols) || needsStat(cols[:1]) {
+		t.Error("needsStat() is wrong")
+	}
+	for _, bad := range []string{"lines,,words", "all", "inode"} {
+		if _, _, err := parseColumns(bad); err == nil {
+			t.Errorf("parseColumns(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestPrintColumns(t *testing.T) {
+	cols, m, _ := parseColumns("filename,lines,size,longest-word,mtime")
+	mtime := time.Date(2026, 1, 2, 3, 4, 0, 0, time.Local)
+	meta := map[string]*fileMeta{
+		"a.txt": {Size: 1234, modTime: mtime},
+		"b.txt": {Size: 5, modTime: mtime.Add(-time.Hour)},
+		"bad":   {Size: 99, modTime: mtime.Add(time.Hour)},
+	}
+	all := []wc.FileResult{
+		{Filename: "a.txt", Lines: 10, LongestWord: 4, LongestWordText: "word"},
+		{Filename: "b.txt", Lines: 2},
+		{Filename: "-", Lines: 1},
+		{Filename: "bad", Err: errors.New("permission denied")},
+	}
+	var sb strings.Builder
+	inputs := []string{"a.txt", "b.txt", "-", "bad"}
+	printColumns(&sb, cliConfig{minWidth: 1, header: true}, cols, inputs, all, wc.Sum(all), m, meta)
+	want := "file    lines    size longest mtime\n" +
+		"a.txt      10    1234 word 2026-01-02 03:04\n" +
+		"b.txt       2       5 - 2026-01-02 02:04\n" +
+		"-           1       - - -\n" +
+		"total      13    1239 word 2026-01-02 03:04\n"
+	if sb.String() != want {
+		t.Errorf("printColumns() =\n%s\nwant\n%s", sb.String(), want)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// command is a subcommand, run as "go_wc NAME ARGS...".
+type command struct {
+	name    string
+	aliases []string
+	args    string // synopsis of the arguments, for usage
+	run     func(args []string) int
+}
+
+// commands lists the subcommands. count is the default: arguments that do
+// not start with a command name are counted as with wc, and so is a first
+// operand that names a command but also an existing file, so that
+// "go_wc check" still counts a file called check as wc would.
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "count", args: "[OPTIONS] [FILE...]", run: runCount},
+		{name: "serve", aliases: []string{"daemon"}, args: "[--socket PATH] [-j N] [--buffer-size BYTES] [--log-level LEVEL] [--log-json]", run: runDaemon},
+		{name: "serve-coordinator", args: "--listen ADDR [--chunk-size SIZE] [OPTIONS] [FILE...]", run: runCoordinator},
+		{name: "worker", args: "--connect ADDR [-j N] [--buffer-size BYTES] [--log-level LEVEL] [--log-json]", run: runWorker},
+		{name: "check", args: "[--policy FILE] [--root DIR] [--restrict-to-root] [--format text|github-annotations|gnu] [FILE...]", run: func(args []string) int {
+			return runCheck(args, os.Stdout, os.Stderr)
+		}},
+		{name: "watch", args: "[-lwmc] [--interval DURATION] [--tui] [-j N] [--encoding NAME] FILE|DIR...", run: func(args []string) int {
+			return runWatch(args, os.Stdout, os.Stderr)
+		}},
+		{name: "diff", args: "[-lwmc] [--format text|json] [--include GLOB] [--exclude GLOB] [--gitignore] [-j N] A B", run: func(args []string) int {
+			return runDiff(args, os.Stdout, os.Stderr)
+		}},
+		{name: "diff-tree", args: "[-lwmc] [--format text|json] [--include GLOB] [--exclude GLOB] [--gitignore] [-j N] DIR_A DIR_B", run: func(args []string) int {
+			return runDiffTree(args, os.Stdout, os.Stderr)
+		}},
+		{name: "git-diff", args: "[--format text|json] REV1..REV2 [-- PATHSPEC...]", run: func(args []string) int {
+			return runGitDiff(args, os.Stdout, os.Stderr)
+		}},
+		{name: "badge", args: "[--metric lines|words|chars|bytes] [--label TEXT] [--color COLOR] [--output FILE] [--git] [FILE...]", run: func(args []string) int {
+			return runBadge(args, os.Stdout, os.Stderr)
+		}},
+		{name: "trend", args: "--db FILE [-n RUNS] [--no-record] [-lwmc] [--git] [FILE...]", run: func(args []string) int {
+			return runTrend(args, os.Stdout, os.Stderr)
+		}},
+		{name: "bench", args: "[-n RUNS] [--warmup RUNS] [-lwmc] [--git] [-j N] [FILE...]", run: func(args []string) int {
+			return runBench(args, os.Stdout, os.Stderr)
+		}},
+		{name: "completion", args: "bash|zsh|fish", run: func(args []string) int {
+			return runCompletion(args, os.Stdout, os.Stderr)
+		}},
+	}
+}
+
+// lookupCommand returns the command called name, or nil.
+func lookupCommand(name string) *command {
+	for i, c := range commands {
+		if c.name == name {
+			return &commands[i]
+		}
+		for _, a := range c.aliases {
+			if a == name {
+				return &commands[i]
+			}
+		}
+	}
+	return nil
+}
+
+// run dispatches args, the command line without the program name, and
+// returns the exit code.
+func run(args []string) int {
+	if len(args) > 0 {
+		if c := lookupCommand(args[0]); c != nil && !pathExists(args[0]) {
+			return c.run(args[1:])
+		}
+	}
+	return runCount(args)
+}
+
+// pathExists reports whether name is a file, directory or symbolic link,
+// dangling or not.
+func pathExists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
+
+// commandNames returns the names and aliases of the subcommands.
+func commandNames() []string {
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.name)
+		names = append(names, c.aliases...)
+	}
+	return names
+}
+
+// completionFlags returns the options of "go_wc count" as typed on the
+// command line: "-c" or "--lines" for switches and "--format=" for options
+// taking a value.
+func completionFlags() []string {
+	var out []string
+	countFlags(&cliConfig{}).VisitAll(func(f *flag.Flag) {
+		name := "--" + f.Name
+		if len(f.Name) == 1 {
+			name = "-" + f.Name
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+			if len(f.Name) > 1 {
+				name += "="
+			}
+		}
+		out = append(out, name)
+	})
+	sort.Strings(out)
+	return out
+}
+
+// runCompletion implements "go_wc completion SHELL", printing a script that
+// completes subcommands, options and file names.
+func runCompletion(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "usage: go_wc completion bash|zsh|fish")
+		return 1
+	}
+	cmds := strings.Join(commandNames(), " ")
+	flags := completionFlags()
+	switch args[0] {
+	case "bash":
+		fmt.Fprintf(stdout, `_go_wc() {
+	local cur=${COMP_WORDS[COMP_CWORD]}
+	if [[ $cur == -* ]]; then
+		COMPREPLY=($(compgen -W "%s" -- "$cur"))
+		[[ ${COMPREPLY[0]} == *= ]] && compopt -o nospace
+		return
+	fi
+	COMPREPLY=($(compgen -f -- "$cur"))
+	if [[ $COMP_CWORD -eq 1 ]]; then
+		COMPREPLY+=($(compgen -W "%s" -- "$cur"))
+	fi
+}
+complete -o filenames -F _go_wc go_wc
+`, strings.Join(flags, " "), cmds)
+	case "zsh":
+		var switches, values []string
+		for _, f := range flags {
+			if strings.HasSuffix(f, "=") {
+				values = append(values, f)
+			} else {
+				switches = append(switches, f)
+			}
+		}
+		fmt.Fprintf(stdout, `#compdef go_wc
+_go_wc() {
+	if [[ $PREFIX == -* ]]; then
+		compadd -- %s
+		compadd -S '' -- %s
+		return
+	fi
+	(( CURRENT == 2 )) && compadd -- %s
+	_files
+}
+compdef _go_wc go_wc
+`, strings.Join(switches, " "), strings.Join(values, " "), cmds)
+	case "fish":
+		fmt.Fprintf(stdout, "complete -c go_wc -n __fish_use_subcommand -a '%s'\n", cmds)
+		for _, f := range flags {
+			name := strings.TrimSuffix(strings.TrimLeft(f, "-"), "=")
+			opt := "-l"
+			if !strings.HasPrefix(f, "--") {
+				opt = "-s"
+			}
+			if strings.HasSuffix(f, "=") {
+				fmt.Fprintf(stdout, "complete -c go_wc %s %s -r\n", opt, name)
+			} else {
+				fmt.Fprintf(stdout, "complete -c go_wc %s %s\n", opt, name)
+			}
+		}
+	default:
+		fmt.Fprintf(stderr, "go_wc: completion: unsupported shell %q (want bash, zsh or fish)\n", args[0])
+		return 1
+	}
+	return 0
+}
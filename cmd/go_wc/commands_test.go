@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLookupCommand(t *testing.T) {
+	for name, want := range map[string]string{"count": "count", "daemon": "serve", "serve": "serve", "check": "check"} {
+		if c := lookupCommand(name); c == nil || c.name != want {
+			t.Errorf("lookupCommand(%q) = %v, want %s", name, c, want)
+		}
+	}
+	for _, name := range []string{"file.txt", "-l", "--", ""} {
+		if c := lookupCommand(name); c != nil {
+			t.Errorf("lookupCommand(%q) = %s, want nil", name, c.name)
+		}
+	}
+}
+
+func TestPathExists(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "check")
+	if pathExists(name) {
+		t.Fatalf("%s exists before it is created", name)
+	}
+	if err := os.WriteFile(name, []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !pathExists(name) {
+		t.Errorf("%s: want it to exist, so that it is counted", name)
+	}
+	if err := os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, "trend")); err == nil && !pathExists(filepath.Join(dir, "trend")) {
+		t.Errorf("a dangling symbolic link must count as existing")
+	}
+}
+
+func TestCompletionFlags(t *testing.T) {
+	flags := strings.Join(completionFlags(), " ")
+	for _, want := range []string{"-c", "--lines", "--format=", "--jobs=", "--stats"} {
+		if !strings.Contains(" "+flags+" ", " "+want+" ") {
+			t.Errorf("completion flags lack %q", want)
+		}
+	}
+}
+
+func TestRunCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var stdout, stderr bytes.Buffer
+		if code := runCompletion([]string{shell}, &stdout, &stderr); code != 0 {
+			t.Fatalf("%s: exit %d: %s", shell, code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), "max-line-length-chars") {
+			t.Errorf("%s script lacks options", shell)
+		}
+		if bin, err := exec.LookPath(shell); err == nil {
+			cmd := exec.Command(bin, "-n")
+			cmd.Stdin = &stdout
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("%s -n: %v: %s", shell, err, out)
+			}
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	if code := runCompletion([]string{"tcsh"}, &stdout, &stderr); code != 1 {
+		t.Errorf("tcsh: exit %d, want 1", code)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import "strings"
+
+// compatBSD is the --compat value for the quirks of the wc of FreeBSD and
+// macOS.
+const compatBSD = "bsd"
+
+// bsdWidth is the width BSD wc prints every count in, after a space,
+// however large the counts.
+const bsdWidth = 7
+
+// compatMode returns the value of --compat among args, before any "--",
+// or "".
+func compatMode(args []string) string {
+	for i, a := range args {
+		if a == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		if !strings.HasPrefix(a, "-") || name != "compat" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// bsdArgs prepares args for parsing under --compat=bsd: it splits grouped
+// options such as -lc, as BSD's getopt reads them, and reports which of
+// -c and -m comes last, 'c' or 'm', so that it can win over the other.
+func bsdArgs(args []string) ([]string, byte) {
+	var out []string
+	var last byte
+	for i, a := range args {
+		if a == "--" {
+			return append(out, args[i:]...), last
+		}
+		switch strings.TrimLeft(a, "-") {
+		case "c", "bytes":
+			last = 'c'
+		case "m", "chars":
+			last = 'm'
+		}
+		if len(a) < 3 || a[0] != '-' || strings.Trim(a[1:], "clmwL") != "" {
+			out = append(out, a)
+			continue
+		}
+		for _, o := range a[1:] {
+			out = append(out, "-"+string(o))
+			if o == 'c' || o == 'm' {
+				last = byte(o)
+			}
+		}
+	}
+	return out, last
+}
+
+// bsdOptions applies the quirks of BSD wc to cfg, whose arguments bsdArgs
+// prepared: the last of -c and -m wins, and -L measures lines in
+// characters with -m.
+func bsdOptions(cfg *cliConfig, last byte) {
+	if cfg.countBytes && cfg.countChars {
+		cfg.countBytes, cfg.countChars = last == 'c', last == 'm'
+	}
+	if cfg.countMaxBytes && cfg.countChars {
+		cfg.countMaxBytes, cfg.countMaxChars = false, true
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestBSDArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+		last byte
+	}{
+		{[]string{"-lc", "a"}, []string{"-l", "-c", "a"}, 'c'},
+		{[]string{"-m", "--bytes", "-wL"}, []string{"-m", "--bytes", "-w", "-L"}, 'c'},
+		{[]string{"-cm", "--", "-lw"}, []string{"-c", "-m", "--", "-lw"}, 'm'},
+		{[]string{"-lx", "--width", "3"}, []string{"-lx", "--width", "3"}, 0},
+	}
+	for _, tt := range tests {
+		got, last := bsdArgs(tt.args)
+		if !reflect.DeepEqual(got, tt.want) || last != tt.last {
+			t.Errorf("%q: got %q, %q; want %q, %q", tt.args, got, last, tt.want, tt.last)
+		}
+	}
+	if mode := compatMode([]string{"-l", "--compat", "bsd"}); mode != compatBSD {
+		t.Errorf("compat mode: got %q, want %q", mode, compatBSD)
+	}
+}
+
+func TestPrintTextBSD(t *testing.T) {
+	m := wc.DefaultMetrics()
+	all := []wc.FileResult{{Filename: "a", Lines: 2, Words: 3, Bytes: 12}, {Index: 1, Filename: "b", Lines: 123456789, Words: 1, Bytes: 1}}
+	totals := wc.Sum(all)
+	cfg := cliConfig{compat: compatBSD}
+	var out bytes.Buffer
+	printText(&out, cfg, []string{"a", "b"}, all, totals, m, nil, nil)
+	want := "       2       3      12 a\n 123456789       1       1 b\n 123456791       4      13 total\n"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	cfg.unnamed = true
+	all = []wc.FileResult{{Filename: "-", Lines: 1, Words: 1, Bytes: 2}}
+	printText(&out, cfg, []string{"-"}, all, all[0], m, nil, nil)
+	if want := "       1       1       2\n"; out.String() != want {
+		t.Errorf("standard input: got %q, want %q", out.String(), want)
+	}
+}
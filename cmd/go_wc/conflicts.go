@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// optionUsed tells, for each option named in optionConflicts, whether the
+// count command line parsed into cfg uses it.
+var optionUsed = map[string]func(cfg cliConfig) bool{
+	"--abort-if-line-exceeds":   func(cfg cliConfig) bool { return cfg.abortLine > 0 },
+	"--abort-if-word-exceeds":   func(cfg cliConfig) bool { return cfg.abortWord > 0 },
+	"--checkpoint":              func(cfg cliConfig) bool { return cfg.checkpoint != "" },
+	"--columns":                 func(cfg cliConfig) bool { return cfg.columns != "" },
+	"--estimate":                func(cfg cliConfig) bool { return cfg.estimate != "" },
+	"--extract-with":            func(cfg cliConfig) bool { return len(cfg.extractWith) > 0 },
+	"--files-from":              func(cfg cliConfig) bool { return cfg.filesFrom != "" },
+	"--files0-from":             func(cfg cliConfig) bool { return cfg.files0From != "" },
+	"--git":                     func(cfg cliConfig) bool { return cfg.git },
+	"--hyphen":                  func(cfg cliConfig) bool { return cfg.hyphen != "" },
+	"--apostrophe":              func(cfg cliConfig) bool { return cfg.apostrophe != "" },
+	"--interval":                func(cfg cliConfig) bool { return cfg.interval != "" },
+	"--jobs=auto":               func(cfg cliConfig) bool { return cfg.autoJobs },
+	"--length":                  func(cfg cliConfig) bool { return cfg.length != 0 },
+	"--list-only":               func(cfg cliConfig) bool { return cfg.listOnly },
+	"--match":                   func(cfg cliConfig) bool { return cfg.match != "" },
+	"--max-bytes":               func(cfg cliConfig) bool { return cfg.maxBytes > 0 },
+	"--max-lines":               func(cfg cliConfig) bool { return cfg.maxLines > 0 },
+	"--min-word-length":         func(cfg cliConfig) bool { return cfg.minWordLen > 0 },
+	"--ngrams":                  func(cfg cliConfig) bool { return cfg.ngrams != "" },
+	"--no-stat-optimizations":   func(cfg cliConfig) bool { return cfg.noStatOpt },
+	"--offset":                  func(cfg cliConfig) bool { return cfg.offset != 0 },
+	"--output-append":           func(cfg cliConfig) bool { return cfg.outputAppend },
+	"--output-shards":           func(cfg cliConfig) bool { return cfg.outputShards > 0 },
+	"--output-sqlite":           func(cfg cliConfig) bool { return cfg.outSQLite != "" },
+	"--per-line":                func(cfg cliConfig) bool { return cfg.perLine },
+	"--per-record":              func(cfg cliConfig) bool { return cfg.perRecord },
+	"--region":                  func(cfg cliConfig) bool { return cfg.region != "" },
+	"--remote":                  func(cfg cliConfig) bool { return cfg.remote },
+	"--resume":                  func(cfg cliConfig) bool { return cfg.resume != "" },
+	"--sandbox":                 func(cfg cliConfig) bool { return cfg.sandbox },
+	"--scripts":                 func(cfg cliConfig) bool { return cfg.scripts },
+	"--single-byte-chars=bytes": func(cfg cliConfig) bool { return cfg.sbChars == "bytes" },
+	"--status":                  func(cfg cliConfig) bool { return cfg.status },
+	"--streaming":               func(cfg cliConfig) bool { return cfg.streaming },
+	"--strip":                   func(cfg cliConfig) bool { return cfg.strip != "" },
+	"--truncate":                func(cfg cliConfig) bool { return cfg.truncate },
+	"--unique-words":            func(cfg cliConfig) bool { return cfg.uniqueWords != "" },
+	"--with-metadata":           func(cfg cliConfig) bool { return cfg.withMeta },
+	"--wrap":                    func(cfg cliConfig) bool { return cfg.wrap },
+	"serve-coordinator":         func(cfg cliConfig) bool { return cfg.listen != "" },
+}
+
+// optionConflicts lists the options of the count command that cannot be
+// used together: option with any of with. checkArgs reports the first
+// conflict found, in this order.
+var optionConflicts = []struct {
+	option string
+	with   []string
+}{
+	{"--region", []string{"--offset", "--length"}},
+	{"--strip", []string{"--estimate"}},
+	// the extractor commands run where the files are counted, by this process
+	{"--extract-with", []string{"--remote", "--estimate", "serve-coordinator", "--sandbox", "--per-line"}},
+	{"--truncate", []string{"--wrap"}},
+	{"--output-shards", []string{"--output-append", "--per-line", "--scripts"}},
+	{"--sandbox", []string{"--remote", "--output-sqlite"}},
+	{"--files0-from", []string{"--files-from"}},
+	// --sandbox: the sandboxed process lists the files again, and may not run git
+	{"--git", []string{"--files0-from", "--files-from", "--streaming", "--sandbox"}},
+	// these need every name, or every result, before they start
+	{"--streaming", []string{"--remote", "--per-line", "--interval", "--estimate", "--ngrams", "--scripts", "--columns",
+		"--with-metadata", "--output-append", "--output-shards", "--checkpoint", "--resume", "--sandbox", "--list-only", "--jobs=auto"}},
+	{"--scripts", []string{"--remote", "--estimate", "--ngrams", "--per-line", "--interval", "--columns", "--output-append"}},
+	{"--per-line", []string{"--remote", "--estimate", "--ngrams", "--columns", "--output-append"}},
+	// records are cut from the whole input as read, not from a window of it
+	{"--per-record", []string{"--per-line", "--remote", "--estimate", "--ngrams", "--columns", "--output-append",
+		"--region", "--offset", "--length", "--strip", "--max-lines", "--max-bytes", "--interval", "--status",
+		"--streaming", "--scripts", "--checkpoint", "--resume", "--match", "--extract-with", "--output-shards", "serve-coordinator"}},
+	{"--interval", []string{"--remote", "--estimate", "--region", "--offset", "--length", "--max-lines", "--max-bytes"}},
+	{"--abort-if-line-exceeds", []string{"--remote", "--estimate"}},
+	{"--abort-if-word-exceeds", []string{"--remote", "--estimate"}},
+	// the checkpoint holds the counts alone, not word sets or scripts
+	{"--checkpoint", []string{"--remote", "--per-line", "--interval", "--estimate", "--unique-words", "--ngrams", "--scripts"}},
+	{"--resume", []string{"--remote", "--per-line", "--interval", "--estimate", "--unique-words", "--ngrams", "--scripts"}},
+	{"--status", []string{"--remote", "--per-line", "--interval"}},
+	// --estimate samples by file size
+	{"--estimate", []string{"--no-stat-optimizations"}},
+	{"--match", []string{"--remote", "--estimate", "--per-line"}},
+	{"--min-word-length", []string{"--remote", "--estimate"}},
+	{"--hyphen", []string{"--remote", "--estimate"}},
+	{"--apostrophe", []string{"--remote", "--estimate"}},
+	{"--single-byte-chars=bytes", []string{"--remote"}},
+}
+
+// checkConflicts returns an error naming the first of optionConflicts that
+// cfg runs into, or nil.
+func checkConflicts(cfg cliConfig) error {
+	for _, c := range optionConflicts {
+		if !optionUsed[c.option](cfg) {
+			continue
+		}
+		for _, w := range c.with {
+			if optionUsed[w](cfg) {
+				return fmt.Errorf("%s cannot be combined with %s", c.option, orList(c.with))
+			}
+		}
+	}
+	return nil
+}
+
+// orList joins names as "a, b or c".
+func orList(names []string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
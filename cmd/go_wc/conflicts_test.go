@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestOptionConflicts(t *testing.T) {
+	for _, c := range optionConflicts {
+		for _, name := range append([]string{c.option}, c.with...) {
+			if optionUsed[name] == nil {
+				t.Errorf("%s: optionUsed lacks %s", c.option, name)
+			}
+		}
+	}
+
+	err := checkConflicts(cliConfig{status: true, interval: "1s"})
+	if want := "--status cannot be combined with --remote, --per-line or --interval"; err == nil || err.Error() != want {
+		t.Errorf("got %v, want %q", err, want)
+	}
+	if err := checkConflicts(cliConfig{truncate: true, status: true}); err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io"
+	"unicode/utf8"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+// consoleWriter transcodes the UTF-8 text written to it for a console
+// that uses another encoding, so that names and reports are not printed
+// as mojibake. Characters the console cannot show become '?'. Bytes that
+// are not UTF-8, such as a file name in the console's own encoding, pass
+// through unchanged.
+type consoleWriter struct {
+	w     io.Writer
+	enc   locale.Encoder
+	carry []byte // an incomplete sequence at the end of the last write
+	buf   []byte
+}
+
+func (cw *consoleWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(cw.carry) > 0 {
+		p = append(cw.carry, p...)
+		cw.carry = nil
+	}
+	out := cw.buf[:0]
+	for len(p) > 0 {
+		if p[0] < utf8.RuneSelf {
+			out = append(out, p[0])
+			p = p[1:]
+			continue
+		}
+		if !utf8.FullRune(p) {
+			cw.carry = append([]byte(nil), p...)
+			break
+		}
+		r, size := utf8.DecodeRune(p)
+		if r == utf8.RuneError && size == 1 {
+			out = append(out, p[0])
+		} else if o, ok := cw.enc.Encode(out, r); ok {
+			out = o
+		} else {
+			out = append(out, '?')
+		}
+		p = p[size:]
+	}
+	cw.buf = out
+	if _, err := cw.w.Write(out); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// consoleEncoder returns the encoder for the console's encoding, or nil
+// when output needs no transcoding.
+func consoleEncoder() locale.Encoder {
+	return locale.EncoderFor(consoleEncoding())
+}
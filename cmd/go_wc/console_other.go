@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import "github.com/rajasatyajit/go-wc/pkg/wc/locale"
+
+// consoleEncoding returns the encoding of the locale the terminal is
+// assumed to display.
+func consoleEncoding() string {
+	return locale.Detect("").Encoding
+}
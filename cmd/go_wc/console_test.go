@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+func TestConsoleWriter(t *testing.T) {
+	in := []byte("      3 naïve–€.txt\n      1 caf\xe9.txt\n")
+	tests := []struct {
+		enc  string
+		want string
+	}{
+		{"iso-8859-1", "      3 na\xefve??.txt\n      1 caf\xe9.txt\n"},
+		{"windows-1252", "      3 na\xefve\x96\x80.txt\n      1 caf\xe9.txt\n"},
+		{"cp437", "      3 na\x8bve??.txt\n      1 caf\xe9.txt\n"},
+		{"us-ascii", "      3 na?ve??.txt\n      1 caf\xe9.txt\n"},
+	}
+	for _, tt := range tests {
+		for _, step := range []int{1, 2, len(in)} {
+			var sb bytes.Buffer
+			cw := &consoleWriter{w: &sb, enc: locale.EncoderFor(tt.enc)}
+			for rest := in; len(rest) > 0; {
+				k := min(step, len(rest))
+				if n, err := cw.Write(rest[:k]); n != k || err != nil {
+					t.Fatalf("Write() = %d, %v", n, err)
+				}
+				rest = rest[k:]
+			}
+			if sb.String() != tt.want {
+				t.Errorf("%s, writes of %d: got %q, want %q", tt.enc, step, sb.String(), tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"strconv"
+	"syscall"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+var getConsoleOutputCP = syscall.NewLazyDLL("kernel32.dll").NewProc("GetConsoleOutputCP")
+
+// consoleEncoding returns the encoding of the console's output code page.
+func consoleEncoding() string {
+	cp, _, _ := getConsoleOutputCP.Call()
+	switch cp {
+	case 0:
+		return "" // no console
+	case 65001:
+		return "utf-8"
+	}
+	return locale.Detect("cp" + strconv.FormatUint(uint64(cp), 10)).Encoding
+}
@@ -0,0 +1,502 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+// defaultSocketPath is used by "go_wc serve" and --remote when no socket is
+// given: in $XDG_RUNTIME_DIR, or else in a directory of the temporary
+// directory private to the user, never in the shared temporary directory
+// itself.
+var defaultSocketPath = socketPath()
+
+// maxCacheEntries bounds the daemon's result cache; past it, an arbitrary
+// entry makes room for each new one.
+const maxCacheEntries = 4096
+
+func socketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "go_wc.sock")
+	}
+	return filepath.Join(privateSocketDir(), "go_wc.sock")
+}
+
+// privateSocketDir is the directory of the default socket without
+// XDG_RUNTIME_DIR.
+func privateSocketDir() string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("go_wc-%d", os.Getuid()))
+}
+
+// remoteRequest is sent by the client, one JSON document per line.
+type remoteRequest struct {
+	Files    []string   `json:"files"`
+	Metrics  wc.Metrics `json:"metrics"`
+	Encoding string     `json:"encoding,omitempty"`
+}
+
+// remoteResult mirrors wc.FileResult with the error flattened to a string.
+// A count is present only when its metric was selected (see
+// toRemoteResult), so that a 0 always means a count of 0.
+type remoteResult struct {
+	Filename     string  `json:"filename"`
+	Lines        *uint64 `json:"lines,omitempty"`
+	Words        *uint64 `json:"words,omitempty"`
+	Bytes        *uint64 `json:"bytes,omitempty"`
+	Chars        *uint64 `json:"chars,omitempty"`
+	MaxLineBytes *uint64 `json:"max_line_bytes,omitempty"`
+	MaxLineChars *uint64 `json:"max_line_chars,omitempty"`
+	// WhitespaceLines counts lines holding nothing but whitespace.
+	WhitespaceLines *uint64 `json:"whitespace_lines,omitempty"`
+	LFEndings       *uint64 `json:"lf_endings,omitempty"`
+	CRLFEndings     *uint64 `json:"crlf_endings,omitempty"`
+	CREndings       *uint64 `json:"cr_endings,omitempty"`
+	// LongestWord, LongestWordText and WordChars back --word-lengths and
+	// --longest-word.
+	LongestWord     *uint64 `json:"longest_word,omitempty"`
+	LongestWordText *string `json:"longest_word_text,omitempty"`
+	WordChars       *uint64 `json:"word_chars,omitempty"`
+	// MinLineWords, MaxLineWords and AllLines back --words-per-line.
+	MinLineWords *uint64 `json:"min_line_words,omitempty"`
+	MaxLineWords *uint64 `json:"max_line_words,omitempty"`
+	AllLines     *uint64 `json:"all_lines,omitempty"`
+	Emoji        *uint64 `json:"emoji,omitempty"`
+	// NumberTokens, URLTokens and EmailTokens back --token-stats.
+	NumberTokens *uint64 `json:"number_tokens,omitempty"`
+	URLTokens    *uint64 `json:"url_tokens,omitempty"`
+	EmailTokens  *uint64 `json:"email_tokens,omitempty"`
+	// NumericWords, AlphanumericWords and AlphabeticWords back --word-kinds.
+	NumericWords      *uint64 `json:"numeric_words,omitempty"`
+	AlphanumericWords *uint64 `json:"alphanumeric_words,omitempty"`
+	AlphabeticWords   *uint64 `json:"alphabetic_words,omitempty"`
+	// CodeIdentifiers, CodeLiterals and CodeOperators back --code-tokens.
+	CodeIdentifiers *uint64 `json:"code_identifiers,omitempty"`
+	CodeLiterals    *uint64 `json:"code_literals,omitempty"`
+	CodeOperators   *uint64 `json:"code_operators,omitempty"`
+	// MatchingLines and NonMatchingLines back --match.
+	MatchingLines    *uint64 `json:"matching_lines,omitempty"`
+	NonMatchingLines *uint64 `json:"non_matching_lines,omitempty"`
+	// NoFinalNewline is set when the last line of a file lacks a trailing
+	// newline; never in a total, whose NoFinalNewlineFiles counts such files.
+	NoFinalNewline      bool   `json:"no_final_newline,omitempty"`
+	NoFinalNewlineFiles uint64 `json:"no_final_newline_files,omitempty"`
+	Error               string `json:"error,omitempty"`
+	Cached              bool   `json:"cached,omitempty"`
+}
+
+// everyCount selects all the counts of a remoteResult, for the daemon and
+// checkpoints, which hand results on rather than report them.
+var everyCount = func() wc.Metrics {
+	m := wc.AllMetrics()
+	m.MatchLines = true
+	return m
+}()
+
+type remoteResponse struct {
+	Results []remoteResult `json:"results"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// cacheKey identifies a counted file; a changed size or mtime invalidates it.
+type cacheKey struct {
+	path     string
+	size     int64
+	modTime  time.Time
+	metrics  wc.Metrics
+	encoding string
+}
+
+type daemonTask struct {
+	idx   int
+	name  string
+	cs    countSettings
+	reply chan<- wc.FileResult
+}
+
+// daemon serves count requests over a Unix socket using a worker pool that
+// lives for the whole process, plus a result cache keyed by file identity.
+type daemon struct {
+	bufSize int
+	tasks   chan daemonTask
+
+	mu    sync.Mutex
+	cache map[cacheKey]wc.FileResult
+}
+
+func newDaemon(workers, bufSize int) *daemon {
+	if workers < 1 {
+		workers = 1
+	}
+	d := &daemon{
+		bufSize: bufSize,
+		tasks:   make(chan daemonTask),
+		cache:   make(map[cacheKey]wc.FileResult),
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for t := range d.tasks {
+				fr := countFile(context.Background(), t.name, t.cs, nil)
+				fr.Index = t.idx
+				t.reply <- fr
+			}
+		}()
+	}
+	return d
+}
+
+// count answers a single request, consulting the cache first.
+func (d *daemon) count(req remoteRequest) remoteResponse {
+	cs := countSettings{
+		metrics: req.Metrics,
+		opts:    wc.Options{BufferSize: d.bufSize, Locale: locale.Detect(req.Encoding)},
+	}
+	out := make([]wc.FileResult, len(req.Files))
+	cached := make([]bool, len(req.Files))
+	keys := make([]*cacheKey, len(req.Files))
+	reply := make(chan wc.FileResult)
+	pending := 0
+
+	for i, name := range req.Files {
+		if name == "-" || !filepath.IsAbs(name) {
+			out[i] = wc.FileResult{Filename: name, Err: errors.New("daemon requires absolute paths")}
+			continue
+		}
+		st, _ := os.Stat(name)
+		// pseudo files change without their size or mtime changing
+		if size, ok := statSize(name, st); ok {
+			k := cacheKey{path: name, size: size, modTime: st.ModTime(), metrics: req.Metrics, encoding: req.Encoding}
+			keys[i] = &k
+			d.mu.Lock()
+			hit, ok := d.cache[k]
+			d.mu.Unlock()
+			if ok {
+				logger.Debug("cache hit", "file", name)
+				out[i] = hit
+				cached[i] = true
+				continue
+			}
+			logger.Debug("cache miss", "file", name)
+		}
+		pending++
+		go func(i int, name string) {
+			d.tasks <- daemonTask{idx: i, name: name, cs: cs, reply: reply}
+		}(i, name)
+	}
+	for ; pending > 0; pending-- {
+		fr := <-reply
+		out[fr.Index] = fr
+		if fr.Err == nil && keys[fr.Index] != nil {
+			d.store(*keys[fr.Index], fr)
+		}
+	}
+
+	resp := remoteResponse{Results: make([]remoteResult, len(out))}
+	for i, fr := range out {
+		resp.Results[i] = toRemoteResult(fr, everyCount)
+		resp.Results[i].Cached = cached[i]
+	}
+	return resp
+}
+
+// store caches fr under k, evicting an arbitrary entry when the cache is
+// full.
+func (d *daemon) store(k cacheKey, fr wc.FileResult) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.cache[k]; !ok && len(d.cache) >= maxCacheEntries {
+		for old := range d.cache {
+			delete(d.cache, old)
+			break
+		}
+	}
+	d.cache[k] = fr
+}
+
+func (d *daemon) serveConn(conn net.Conn) {
+	defer conn.Close()
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	for {
+		var req remoteRequest
+		if err := dec.Decode(&req); err != nil {
+			if !errors.Is(err, io.EOF) {
+				_ = enc.Encode(remoteResponse{Error: err.Error()})
+			}
+			return
+		}
+		if err := enc.Encode(d.count(req)); err != nil {
+			return
+		}
+	}
+}
+
+// serve accepts connections until the listener is closed.
+func (d *daemon) serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go d.serveConn(conn)
+	}
+}
+
+// runDaemon implements "go_wc serve", also known as "go_wc daemon", and
+// returns the process exit code.
+func runDaemon(args []string) int {
+	fs := flag.NewFlagSet("go_wc serve", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	socket := fs.String("socket", defaultSocketPath, "")
+	jobs := fs.Int("jobs", runtime.GOMAXPROCS(0), "")
+	fs.IntVar(jobs, "j", runtime.GOMAXPROCS(0), "")
+	bufSize := fs.Int("buffer-size", 1*1024*1024, "")
+	logLevel := fs.String("log-level", "", "")
+	logJSON := fs.Bool("log-json", false, "")
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := setupLogging(*logLevel, *logJSON); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *socket == defaultSocketPath && os.Getenv("XDG_RUNTIME_DIR") == "" {
+		if err := makePrivateDir(privateSocketDir()); err != nil {
+			fmt.Fprintf(os.Stderr, "go_wc: %v\n", err)
+			return 1
+		}
+	}
+	ln, err := listenSocket(*socket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "go_wc: %v\n", err)
+		return 1
+	}
+	// the listener would unlink the path on Close even after another
+	// daemon took it over; remove it only while it is still this socket
+	ln.SetUnlinkOnClose(false)
+	own, statErr := os.Lstat(*socket)
+	defer func() {
+		if cur, err := os.Lstat(*socket); statErr == nil && err == nil && os.SameFile(own, cur) {
+			_ = os.Remove(*socket)
+		}
+	}()
+	logger.Info("daemon listening", "socket", *socket, "workers", *jobs)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		_ = ln.Close()
+	}()
+
+	if err := newDaemon(*jobs, *bufSize).serve(ln); err != nil {
+		fmt.Fprintf(os.Stderr, "go_wc: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// listenSocket listens on the Unix socket path. A socket left over by a
+// daemon that died, on which a connection is refused, is removed first; one
+// that accepts connections belongs to a running daemon, and is an error.
+func listenSocket(path string) (*net.UnixListener, error) {
+	conn, err := net.Dial("unix", path)
+	if err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s: daemon already running", path)
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		if st, err := os.Lstat(path); err == nil && st.Mode()&os.ModeSocket != 0 {
+			_ = os.Remove(path)
+		}
+	}
+	return net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+}
+
+// makePrivateDir creates dir accessible to its owner only, or checks that
+// an existing one is a real directory that no one else can use.
+func makePrivateDir(dir string) error {
+	if err := os.Mkdir(dir, 0o700); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	st, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !st.IsDir() || !ownedByUser(st) || runtime.GOOS != "windows" && st.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("%s: not a directory private to its owner", dir)
+	}
+	return nil
+}
+
+// countRemote submits inputs to a running daemon. Relative paths are resolved
+// against the client's working directory but reported as given. It also
+// returns how many results the daemon served from its cache.
+func countRemote(socket string, inputs []string, metrics wc.Metrics, encoding string) ([]wc.FileResult, int, error) {
+	req := remoteRequest{Files: make([]string, len(inputs)), Metrics: metrics, Encoding: encoding}
+	for i, name := range inputs {
+		if name == "-" {
+			return nil, 0, errors.New("standard input cannot be counted with --remote")
+		}
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return nil, 0, err
+		}
+		req.Files[i] = abs
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, 0, err
+	}
+	var resp remoteResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, 0, err
+	}
+	if resp.Error != "" {
+		return nil, 0, errors.New(resp.Error)
+	}
+	if len(resp.Results) != len(inputs) {
+		return nil, 0, fmt.Errorf("daemon returned %d results for %d files", len(resp.Results), len(inputs))
+	}
+
+	all := make([]wc.FileResult, len(resp.Results))
+	hits := 0
+	for i, rr := range resp.Results {
+		if rr.Cached {
+			hits++
+		}
+		all[i] = fromRemoteResult(rr)
+		all[i].Index = i
+		all[i].Filename = inputs[i]
+	}
+	return all, hits, nil
+}
+
+// toRemoteResult returns fr in the daemon's JSON form, with the counts of
+// the metrics m selects.
+func toRemoteResult(fr wc.FileResult, m wc.Metrics) remoteResult {
+	rr := remoteResult{Filename: fr.Filename, NoFinalNewline: fr.NoFinalNewline, NoFinalNewlineFiles: fr.NoFinalNewlineFiles}
+	set := func(on bool, dst **uint64, v uint64) {
+		if on {
+			*dst = &v
+		}
+	}
+	set(m.Lines, &rr.Lines, fr.Lines)
+	set(m.Words, &rr.Words, fr.Words)
+	set(m.Bytes, &rr.Bytes, fr.Bytes)
+	set(m.Chars, &rr.Chars, fr.Chars)
+	set(m.MaxLineBytes, &rr.MaxLineBytes, fr.MaxLineBytes)
+	set(m.MaxLineChars, &rr.MaxLineChars, fr.MaxLineChars)
+
+	set(m.WhitespaceLines, &rr.WhitespaceLines, fr.WhitespaceLines)
+	set(m.LineEndings, &rr.LFEndings, fr.LFEndings)
+	set(m.LineEndings, &rr.CRLFEndings, fr.CRLFEndings)
+	set(m.LineEndings, &rr.CREndings, fr.CREndings)
+	set(m.WordLengths, &rr.LongestWord, fr.LongestWord)
+	set(m.WordLengths, &rr.WordChars, fr.WordChars)
+	if m.LongestWord {
+		text := fr.LongestWordText
+		rr.LongestWordText = &text
+	}
+
+	set(m.WordsPerLine, &rr.MinLineWords, fr.MinLineWords)
+	set(m.WordsPerLine, &rr.MaxLineWords, fr.MaxLineWords)
+	set(m.WordsPerLine, &rr.AllLines, fr.AllLines)
+	set(m.Emoji, &rr.Emoji, fr.Emoji)
+	set(m.TokenStats, &rr.NumberTokens, fr.NumberTokens)
+	set(m.TokenStats, &rr.URLTokens, fr.URLTokens)
+	set(m.TokenStats, &rr.EmailTokens, fr.EmailTokens)
+
+	set(m.WordKinds, &rr.NumericWords, fr.NumericWords)
+	set(m.WordKinds, &rr.AlphanumericWords, fr.AlphanumericWords)
+	set(m.WordKinds, &rr.AlphabeticWords, fr.AlphabeticWords)
+
+	set(m.CodeTokens, &rr.CodeIdentifiers, fr.CodeIdentifiers)
+	set(m.CodeTokens, &rr.CodeLiterals, fr.CodeLiterals)
+	set(m.CodeTokens, &rr.CodeOperators, fr.CodeOperators)
+
+	set(m.MatchLines, &rr.MatchingLines, fr.MatchingLines)
+	set(m.MatchLines, &rr.NonMatchingLines, fr.NonMatchingLines)
+	if fr.Err != nil {
+		rr.Error = fr.Err.Error()
+	}
+	return rr
+}
+
+func fromRemoteResult(rr remoteResult) wc.FileResult {
+	get := func(p *uint64) uint64 {
+		if p == nil {
+			return 0
+		}
+		return *p
+	}
+	fr := wc.FileResult{
+		Filename:     rr.Filename,
+		Lines:        get(rr.Lines),
+		Words:        get(rr.Words),
+		Bytes:        get(rr.Bytes),
+		Chars:        get(rr.Chars),
+		MaxLineBytes: get(rr.MaxLineBytes),
+		MaxLineChars: get(rr.MaxLineChars),
+
+		WhitespaceLines: get(rr.WhitespaceLines),
+		LFEndings:       get(rr.LFEndings),
+		CRLFEndings:     get(rr.CRLFEndings),
+		CREndings:       get(rr.CREndings),
+		LongestWord:     get(rr.LongestWord),
+		WordChars:       get(rr.WordChars),
+		NoFinalNewline:  rr.NoFinalNewline,
+
+		NoFinalNewlineFiles: rr.NoFinalNewlineFiles,
+
+		MinLineWords: get(rr.MinLineWords),
+		MaxLineWords: get(rr.MaxLineWords),
+		AllLines:     get(rr.AllLines),
+		Emoji:        get(rr.Emoji),
+		NumberTokens: get(rr.NumberTokens),
+		URLTokens:    get(rr.URLTokens),
+		EmailTokens:  get(rr.EmailTokens),
+
+		NumericWords:      get(rr.NumericWords),
+		AlphanumericWords: get(rr.AlphanumericWords),
+		AlphabeticWords:   get(rr.AlphabeticWords),
+
+		CodeIdentifiers: get(rr.CodeIdentifiers),
+		CodeLiterals:    get(rr.CodeLiterals),
+		CodeOperators:   get(rr.CodeOperators),
+
+		MatchingLines:    get(rr.MatchingLines),
+		NonMatchingLines: get(rr.NonMatchingLines),
+	}
+	if rr.LongestWordText != nil {
+		fr.LongestWordText = *rr.LongestWordText
+	}
+	if rr.Error != "" {
+		fr.Err = errors.New(rr.Error)
+	}
+	return fr
+}
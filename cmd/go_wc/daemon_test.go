@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func startTestDaemon(t *testing.T) (string, *daemon) {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "wc.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	d := newDaemon(2, 4096)
+	go d.serve(ln)
+	t.Cleanup(func() { ln.Close() })
+	return socket, d
+}
+
+func TestCountRemote(t *testing.T) {
+	socket, d := startTestDaemon(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("hello world\nfoo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	metrics := wc.Metrics{Lines: true, Words: true, Bytes: true}
+
+	for i := 0; i < 2; i++ {
+		all, hits, err := countRemote(socket, []string{path, filepath.Join(dir, "missing")}, metrics, "utf-8")
+		if err != nil {
+			t.Fatalf("countRemote failed: %v", err)
+		}
+		if hits != i {
+			t.Errorf("cache hits on pass %d: got %d, want %d", i, hits, i)
+		}
+		if len(all) != 2 {
+			t.Fatalf("got %d results, want 2", len(all))
+		}
+		if all[0].Lines != 2 || all[0].Words != 3 || all[0].Bytes != 16 {
+			t.Errorf("unexpected counts: %+v", all[0])
+		}
+		if all[0].Filename != path {
+			t.Errorf("Filename: got %q, want %q", all[0].Filename, path)
+		}
+		if all[1].Err == nil {
+			t.Error("expected error for missing file")
+		}
+	}
+
+	d.mu.Lock()
+	cached := len(d.cache)
+	d.mu.Unlock()
+	if cached != 1 {
+		t.Errorf("cache entries: got %d, want 1", cached)
+	}
+}
+
+func TestCountRemoteRejectsStdin(t *testing.T) {
+	if _, _, err := countRemote("/nonexistent.sock", []string{"-"}, wc.Metrics{Lines: true}, ""); err == nil {
+		t.Error("expected error for stdin with --remote")
+	}
+}
+
+func TestListenSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "wc.sock")
+	ln, err := listenSocket(socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	if _, err := listenSocket(socket); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("second daemon: got %v, want already running", err)
+	}
+	// a daemon that died leaves its socket behind
+	ln.SetUnlinkOnClose(false)
+	ln.Close()
+	ln, err = listenSocket(socket)
+	if err != nil {
+		t.Fatalf("stale socket: %v", err)
+	}
+	ln.Close()
+}
+
+func TestMakePrivateDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run")
+	if err := makePrivateDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := makePrivateDir(dir); err != nil {
+		t.Errorf("existing private directory: %v", err)
+	}
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if err := os.Chmod(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := makePrivateDir(dir); err == nil {
+		t.Error("expected an error for a directory others can read")
+	}
+}
+
+func TestDaemonCacheLimit(t *testing.T) {
+	d := newDaemon(1, 4096)
+	for i := 0; i <= maxCacheEntries; i++ {
+		d.store(cacheKey{path: fmt.Sprint(i)}, wc.FileResult{})
+	}
+	if n := len(d.cache); n != maxCacheEntries {
+		t.Errorf("cache entries: got %d, want %d", n, maxCacheEntries)
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+// treeMetric is a count "go_wc diff-tree" compares.
+type treeMetric struct {
+	name string
+	set  func(*wc.Metrics)
+	get  func(wc.FileResult) uint64
+}
+
+var treeMetrics = []treeMetric{
+	{"lines", func(m *wc.Metrics) { m.Lines = true }, func(r wc.FileResult) uint64 { return r.Lines }},
+	{"words", func(m *wc.Metrics) { m.Words = true }, func(r wc.FileResult) uint64 { return r.Words }},
+	{"chars", func(m *wc.Metrics) { m.Chars = true }, func(r wc.FileResult) uint64 { return r.Chars }},
+	{"bytes", func(m *wc.Metrics) { m.Bytes = true }, func(r wc.FileResult) uint64 { return r.Bytes }},
+}
+
+// metricFlags defines -l, -w, -m and -c, and their long forms, on fset and
+// returns a function giving the metrics they select once fset is parsed:
+// lines, words and bytes, as wc, when none is given.
+func metricFlags(fset *flag.FlagSet) func() []treeMetric {
+	var selected [4]bool
+	for i, names := range [][2]string{{"l", "lines"}, {"w", "words"}, {"m", "chars"}, {"c", "bytes"}} {
+		fset.BoolVar(&selected[i], names[0], false, "")
+		fset.BoolVar(&selected[i], names[1], false, "")
+	}
+	return func() []treeMetric {
+		var metrics []treeMetric
+		for i, on := range selected {
+			if on {
+				metrics = append(metrics, treeMetrics[i])
+			}
+		}
+		if len(metrics) == 0 {
+			metrics = []treeMetric{treeMetrics[0], treeMetrics[1], treeMetrics[3]}
+		}
+		return metrics
+	}
+}
+
+// treeChange is a path whose counts differ between the two trees. A is nil
+// for a path only in the second tree, B for one only in the first.
+type treeChange struct {
+	Path   string            `json:"path"`
+	Status string            `json:"status"` // added, removed or changed
+	A      map[string]uint64 `json:"a,omitempty"`
+	B      map[string]uint64 `json:"b,omitempty"`
+	Delta  map[string]int64  `json:"delta"`
+}
+
+// treeCounts walks root with w's settings and counts its files, keyed by
+// their slash-separated path relative to root. Files that cannot be
+// counted are reported on stderr and left out.
+func treeCounts(w *wc.Walker, cs countSettings, jobs int, stderr io.Writer) (map[string]wc.FileResult, bool, error) {
+	var rels, paths []string
+	err := w.Walk(func(rel string) error {
+		rels = append(rels, rel)
+		paths = append(paths, filepath.Join(w.Root, filepath.FromSlash(rel)))
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	counts := make(map[string]wc.FileResult, len(rels))
+	ok := true
+	for i, r := range countInputs(paths, cs, jobs) {
+		if r.Err != nil {
+			fmt.Fprintf(stderr, "go_wc: %s: %v\n", r.Filename, r.Err)
+			ok = false
+			continue
+		}
+		counts[rels[i]] = r
+	}
+	return counts, ok, nil
+}
+
+// diffTrees returns the paths of a and b whose counts of metrics differ,
+// in lexical order.
+func diffTrees(a, b map[string]wc.FileResult, metrics []treeMetric) []treeChange {
+	names := make([]string, 0, len(a)+len(b))
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []treeChange
+	for _, name := range names {
+		ra, inA := a[name]
+		rb, inB := b[name]
+		c := treeChange{Path: name, Delta: make(map[string]int64, len(metrics))}
+		switch {
+		case !inA:
+			c.Status = "added"
+		case !inB:
+			c.Status = "removed"
+		default:
+			c.Status = "changed"
+		}
+		if inA {
+			c.A = make(map[string]uint64, len(metrics))
+		}
+		if inB {
+			c.B = make(map[string]uint64, len(metrics))
+		}
+		differ := !inA || !inB
+		for _, m := range metrics {
+			var va, vb uint64
+			if inA {
+				va = m.get(ra)
+				c.A[m.name] = va
+			}
+			if inB {
+				vb = m.get(rb)
+				c.B[m.name] = vb
+			}
+			c.Delta[m.name] = int64(vb - va)
+			differ = differ || va != vb
+		}
+		if differ {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// signed formats a delta with its sign: +3, -2 or 0.
+func signed(d int64) string {
+	if d > 0 {
+		return "+" + strconv.FormatInt(d, 10)
+	}
+	return strconv.FormatInt(d, 10)
+}
+
+// writeTreeChanges prints one line per change, its status and the deltas
+// of metrics aligned as by wc, then the total of the deltas when there
+// are several changes.
+func writeTreeChanges(w io.Writer, changes []treeChange, metrics []treeMetric) error {
+	total := make(map[string]int64, len(metrics))
+	width := 1
+	for _, c := range changes {
+		for _, m := range metrics {
+			total[m.name] += c.Delta[m.name]
+			width = max(width, len(signed(c.Delta[m.name])))
+		}
+	}
+	for _, m := range metrics {
+		width = max(width, len(signed(total[m.name])))
+	}
+	line := func(status string, delta map[string]int64, name string) error {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%-7s", status)
+		for _, m := range metrics {
+			fmt.Fprintf(&b, " %*s", width, signed(delta[m.name]))
+		}
+		b.WriteString(" " + quoteName(name) + "\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	for _, c := range changes {
+		if err := line(c.Status, c.Delta, c.Path); err != nil {
+			return err
+		}
+	}
+	if len(changes) > 1 {
+		return line("", total, "total")
+	}
+	return nil
+}
+
+// runDiffTree implements "go_wc diff-tree DIR_A DIR_B": count both trees
+// and report the paths added, removed or whose counts changed from the
+// first to the second. As with diff, it exits 1 when they differ and 2
+// on errors.
+func runDiffTree(args []string, stdout, stderr io.Writer) int {
+	return diffCounts("diff-tree", false, args, stdout, stderr)
+}
+
+// runDiff implements "go_wc diff A B": diff-tree for two directories, and
+// for two files the change of the counts of B from those of A.
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	return diffCounts("diff", true, args, stdout, stderr)
+}
+
+// diffCounts runs the diff-tree command as cmd; with files, its operands
+// may also be two files, compared as trees of a single file named like
+// the second.
+func diffCounts(cmd string, files bool, args []string, stdout, stderr io.Writer) int {
+	fset := flag.NewFlagSet("go_wc "+cmd, flag.ContinueOnError)
+	fset.SetOutput(io.Discard)
+	selected := metricFlags(fset)
+	formatName := fset.String("format", "text", "")
+	jobs := fset.Int("jobs", runtime.GOMAXPROCS(0), "")
+	fset.IntVar(jobs, "j", runtime.GOMAXPROCS(0), "")
+	encoding := fset.String("encoding", "", "")
+	gitignore := fset.Bool("gitignore", false, "")
+	var include, exclude []string
+	fset.Var(stringList{&include}, "include", "")
+	fset.Var(stringList{&exclude}, "exclude", "")
+	if err := fset.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if fset.NArg() != 2 {
+		if files {
+			fmt.Fprintf(stderr, "usage: go_wc %s [OPTIONS] A B\n", cmd)
+		} else {
+			fmt.Fprintf(stderr, "usage: go_wc %s [OPTIONS] DIR_A DIR_B\n", cmd)
+		}
+		return 2
+	}
+	if *formatName != "text" && *formatName != "json" {
+		fmt.Fprintf(stderr, "go_wc: %s: unsupported format %q (want text or json)\n", cmd, *formatName)
+		return 2
+	}
+
+	metrics := selected()
+	var m wc.Metrics
+	for _, tm := range metrics {
+		tm.set(&m)
+	}
+	cs := countSettings{
+		metrics: m,
+		opts:    wc.Options{BufferSize: 1024 * 1024, Locale: locale.Detect(*encoding)},
+	}
+
+	exit := 0
+	var trees [2]map[string]wc.FileResult
+	if files {
+		dirs := 0
+		for _, name := range fset.Args() {
+			if st, err := os.Stat(name); err == nil && st.IsDir() {
+				dirs++
+			}
+		}
+		switch dirs {
+		case 0:
+			// the counts of two files, under the name of the second
+			for i, r := range countInputs(fset.Args(), cs, *jobs) {
+				if r.Err != nil {
+					fmt.Fprintf(stderr, "go_wc: %s: %v\n", quoteName(r.Filename), r.Err)
+					return 2
+				}
+				trees[i] = map[string]wc.FileResult{fset.Arg(1): r}
+			}
+		case 1:
+			fmt.Fprintf(stderr, "go_wc: %s: cannot compare a file with a directory\n", cmd)
+			return 2
+		}
+	}
+	for i, root := range fset.Args() {
+		if trees[i] != nil {
+			continue
+		}
+		w := &wc.Walker{
+			Root:      root,
+			SkipDir:   func(rel string) bool { return path.Base(rel) == ".git" },
+			Include:   include,
+			Exclude:   exclude,
+			Gitignore: *gitignore,
+		}
+		counts, ok, err := treeCounts(w, cs, *jobs, stderr)
+		if err != nil {
+			fmt.Fprintf(stderr, "go_wc: %v\n", err)
+			return 2
+		}
+		if !ok {
+			exit = 2
+		}
+		trees[i] = counts
+	}
+
+	changes := diffTrees(trees[0], trees[1], metrics)
+	var err error
+	if *formatName == "json" {
+		if changes == nil {
+			changes = []treeChange{}
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(struct {
+			Changes []treeChange `json:"changes"`
+		}{changes})
+	} else {
+		err = writeTreeChanges(stdout, changes, metrics)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "go_wc: %v\n", err)
+		return 2
+	}
+	if exit == 0 && len(changes) > 0 {
+		exit = 1
+	}
+	return exit
+}
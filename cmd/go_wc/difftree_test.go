@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, text := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestRunDiffTree(t *testing.T) {
+	a := writeTree(t, map[string]string{
+		"same.txt":    "one two\n",
+		"docs/x.md":   "one\ntwo\n",
+		"old.txt":     "gone\n",
+		".git/config": "ignored\n",
+	})
+	b := writeTree(t, map[string]string{
+		"same.txt":     "one two\n",
+		"docs/x.md":    "one\n",
+		"docs/new.md":  "a b c\n",
+		".git/HEAD":    "ignored\n",
+		"docs/sub/y.m": "",
+	})
+
+	var stdout, stderr strings.Builder
+	if code := runDiffTree([]string{a, b}, &stdout, &stderr); code != 1 {
+		t.Fatalf("exit code: got %d, want 1 (stderr %q)", code, stderr.String())
+	}
+	want := "added   +1 +3 +6 docs/new.md\n" +
+		"added    0  0  0 docs/sub/y.m\n" +
+		"changed -1 -1 -4 docs/x.md\n" +
+		"removed -1 -1 -5 old.txt\n" +
+		"        -1 +1 -3 total\n"
+	if got := stdout.String(); got != want {
+		t.Errorf("text output:\ngot\n%swant\n%s", got, want)
+	}
+
+	stdout.Reset()
+	if code := runDiffTree([]string{"-l", "--format=json", "--exclude=sub", a, b}, &stdout, &stderr); code != 1 {
+		t.Fatalf("json: exit code %d (stderr %q)", code, stderr.String())
+	}
+	var doc struct{ Changes []treeChange }
+	if err := json.Unmarshal([]byte(stdout.String()), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Changes) != 3 {
+		t.Fatalf("json: got %+v", doc.Changes)
+	}
+	if c := doc.Changes[1]; c.Path != "docs/x.md" || c.Status != "changed" || c.A["lines"] != 2 || c.B["lines"] != 1 || c.Delta["lines"] != -1 || len(c.Delta) != 1 {
+		t.Errorf("json change: got %+v", c)
+	}
+	if c := doc.Changes[0]; c.Status != "added" || c.A != nil {
+		t.Errorf("json added: got %+v", c)
+	}
+
+	stdout.Reset()
+	if code := runDiffTree([]string{a, a}, &stdout, &stderr); code != 0 || stdout.Len() != 0 {
+		t.Errorf("same tree: exit %d, output %q", code, stdout.String())
+	}
+	for _, args := range [][]string{{a}, {"--format=csv", a, b}, {a, filepath.Join(a, "missing")}} {
+		if code := runDiffTree(args, &stdout, &stderr); code != 2 {
+			t.Errorf("%q: exit %d, want 2", args, code)
+		}
+	}
+}
+
+func TestRunDiffFiles(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.txt": "one two\n", "b.txt": "one\ntwo three\n", "sub/c.txt": "x\n"})
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+
+	var stdout, stderr strings.Builder
+	if code := runDiff([]string{a, b}, &stdout, &stderr); code != 1 {
+		t.Fatalf("exit code: got %d, want 1 (stderr %q)", code, stderr.String())
+	}
+	if want := "changed +1 +1 +6 " + b + "\n"; stdout.String() != want {
+		t.Errorf("got %q, want %q", stdout.String(), want)
+	}
+
+	stdout.Reset()
+	if code := runDiff([]string{a, a}, &stdout, &stderr); code != 0 || stdout.String() != "" {
+		t.Errorf("same file: exit %d, output %q", code, stdout.String())
+	}
+	if code := runDiff([]string{a, filepath.Join(dir, "sub")}, &stdout, &stderr); code != 2 {
+		t.Errorf("file and directory: exit %d, want 2", code)
+	}
+	if code := runDiffTree([]string{a, b}, &stdout, &stderr); code != 2 {
+		t.Errorf("diff-tree of files: exit %d, want 2", code)
+	}
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// distVersion is the protocol between serve-coordinator and its workers:
+// newline-delimited JSON over TCP. The coordinator greets each worker with
+// a distHello. The worker then asks for tasks with a distRequest, which
+// carries the results of the tasks it was given last, and the coordinator
+// answers every request with a distAssignment, until one says Done.
+const distVersion = 1
+
+// distHello tells a worker how to count.
+type distHello struct {
+	Version int       `json:"version"`
+	Spec    countSpec `json:"spec"`
+}
+
+// distTask is a file to count, or with Chunk set, the Length bytes at
+// Offset in it, to be merged with the other chunks of the file.
+type distTask struct {
+	ID     int    `json:"id"`
+	Path   string `json:"path"` // absolute, as workers see the files on a shared file system
+	Chunk  bool   `json:"chunk,omitempty"`
+	Offset int64  `json:"offset,omitempty"`
+	Length int64  `json:"length,omitempty"`
+}
+
+type distRequest struct {
+	Want    int          `json:"want"` // tasks the worker can take on
+	Results []distResult `json:"results,omitempty"`
+}
+
+// distResult is the result of a task: a storedResult for a whole file, or
+// for a chunk the ChunkResult, unless it failed.
+type distResult struct {
+	Task int `json:"task"`
+	storedResult
+	Chunk *wc.ChunkResult `json:"chunk,omitempty"`
+}
+
+type distAssignment struct {
+	Tasks []distTask `json:"tasks,omitempty"`
+	Done  bool       `json:"done,omitempty"`
+}
+
+// distDrainTimeout bounds how long a finished coordinator waits to tell
+// its workers so.
+const distDrainTimeout = 5 * time.Second
+
+// coordinator hands out the tasks of a run to the workers that connect and
+// collects their results. A task given to a worker that disconnects before
+// returning its result is handed out again.
+type coordinator struct {
+	spec countSpec
+
+	mu      sync.Mutex
+	cond    *sync.Cond // signalled when queue grows or left drops to 0
+	tasks   []distTask
+	queue   []int // tasks waiting for a worker
+	left    int   // tasks without a result
+	results []*distResult
+	workers int // workers that connected
+}
+
+// coordinate counts inputs on the workers that connect to ln, split into
+// chunks of chunkSize bytes where the options allow it, and returns the
+// results in input order and how many workers took part. It closes ln.
+func coordinate(ln net.Listener, inputs []string, spec countSpec, opts wc.Options, chunkSize uint64) ([]wc.FileResult, int, error) {
+	c := &coordinator{spec: spec}
+	c.cond = sync.NewCond(&c.mu)
+	defer ln.Close()
+	first := make([]int, len(inputs)+1) // the tasks of input i are first[i]:first[i+1]
+	for i, name := range inputs {
+		first[i] = len(c.tasks)
+		if name == "-" {
+			return nil, 0, errors.New("standard input cannot be counted by workers")
+		}
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return nil, 0, err
+		}
+		size := int64(0)
+		if st, err := os.Stat(abs); err == nil && st.Mode().IsRegular() {
+			size = st.Size()
+		}
+		if chunkSize == 0 || !spec.chunkable() || uint64(size) <= chunkSize {
+			c.tasks = append(c.tasks, distTask{ID: len(c.tasks), Path: abs})
+			continue
+		}
+		for off := int64(0); off < size; off += int64(chunkSize) {
+			n := min(int64(chunkSize), size-off)
+			c.tasks = append(c.tasks, distTask{ID: len(c.tasks), Path: abs, Chunk: true, Offset: off, Length: n})
+		}
+	}
+	first[len(inputs)] = len(c.tasks)
+	c.left = len(c.tasks)
+	c.results = make([]*distResult, len(c.tasks))
+	for i := range c.tasks {
+		c.queue = append(c.queue, i)
+	}
+
+	logger.Info("coordinator listening", "addr", ln.Addr().String(), "files", len(inputs), "tasks", len(c.tasks))
+	var conns sync.WaitGroup
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns.Add(1)
+			go func() {
+				defer conns.Done()
+				c.serveWorker(conn)
+			}()
+		}
+	}()
+
+	c.mu.Lock()
+	for c.left > 0 {
+		c.cond.Wait()
+	}
+	workers := c.workers
+	c.mu.Unlock()
+	ln.Close()
+	drained := make(chan struct{})
+	go func() {
+		conns.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(distDrainTimeout):
+		logger.Info("workers did not ask for more work", "timeout", distDrainTimeout)
+	}
+
+	all := make([]wc.FileResult, len(inputs))
+	for i, name := range inputs {
+		all[i] = c.merge(c.results[first[i]:first[i+1]], opts)
+		all[i].Filename = name
+		all[i].Index = i
+	}
+	return all, workers, nil
+}
+
+// chunkable reports whether files counted under s can be split into
+// chunks: string and regexp counts would miss matches across chunk edges,
+// and the other options act on whole files.
+func (s countSpec) chunkable() bool {
+	return len(s.CountStrings) == 0 && len(s.CountRegexps) == 0 && s.Match == "" &&
+		s.Offset == 0 && s.Length == 0 && s.MaxLines == 0 && s.MaxBytes == 0 && s.Strip == "" &&
+		s.MinWordLength == 0 && s.Hyphen == "" && s.Apostrophe == "" && s.AbortLine == 0 && s.AbortWord == 0
+}
+
+// merge returns the result of a file from the results of its tasks.
+func (c *coordinator) merge(results []*distResult, opts wc.Options) wc.FileResult {
+	if len(results) == 1 && results[0].Chunk == nil {
+		return results[0].result(len(opts.CountChars), len(opts.CountStrings))
+	}
+	chunks := make([]wc.ChunkResult, len(results))
+	for i, r := range results {
+		if r.Chunk == nil {
+			return r.result(len(opts.CountChars), len(opts.CountStrings))
+		}
+		chunks[i] = *r.Chunk
+		chunks[i].CharClasses = opts.CountChars // not serialized
+	}
+	return wc.MergeChunks(chunks).Final()
+}
+
+// serveWorker runs the protocol with one worker.
+func (c *coordinator) serveWorker(conn net.Conn) {
+	defer conn.Close()
+	owned := make(map[int]bool) // tasks given to the worker without a result
+	defer c.requeue(owned)
+	remote := conn.RemoteAddr().String()
+	c.mu.Lock()
+	c.workers++
+	c.mu.Unlock()
+	logger.Info("worker connected", "worker", remote)
+
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	if err := enc.Encode(distHello{Version: distVersion, Spec: c.spec}); err != nil {
+		return
+	}
+	for {
+		var req distRequest
+		if err := dec.Decode(&req); err != nil {
+			if !errors.Is(err, io.EOF) {
+				logger.Warn("worker failed", "worker", remote, "error", err.Error())
+			}
+			return
+		}
+		c.finish(req.Results, owned)
+		tasks, done := c.next(max(req.Want, 1), owned)
+		if err := enc.Encode(distAssignment{Tasks: tasks, Done: done}); err != nil || done {
+			return
+		}
+		logger.Debug("assigned", "worker", remote, "tasks", len(tasks))
+	}
+}
+
+// next takes up to want tasks off the queue for a worker, waiting while
+// the queue is empty but other workers still hold tasks. done reports that
+// every task has its result.
+func (c *coordinator) next(want int, owned map[int]bool) (tasks []distTask, done bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.queue) == 0 && c.left > 0 {
+		c.cond.Wait()
+	}
+	if c.left == 0 {
+		return nil, true
+	}
+	n := min(want, len(c.queue))
+	for _, id := range c.queue[:n] {
+		tasks = append(tasks, c.tasks[id])
+		owned[id] = true
+	}
+	c.queue = c.queue[n:]
+	return tasks, false
+}
+
+// finish records the results of tasks the worker owns.
+func (c *coordinator) finish(results []distResult, owned map[int]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range results {
+		r := &results[i]
+		if !owned[r.Task] {
+			continue // not this worker's to report
+		}
+		delete(owned, r.Task)
+		c.results[r.Task] = r
+		c.left--
+	}
+	if c.left == 0 {
+		c.cond.Broadcast()
+	}
+}
+
+// requeue hands the tasks a departed worker owned to the others.
+func (c *coordinator) requeue(owned map[int]bool) {
+	if len(owned) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id := range owned {
+		c.queue = append(c.queue, id)
+	}
+	logger.Info("requeued tasks of a departed worker", "tasks", len(owned))
+	c.cond.Broadcast()
+}
+
+// runCoordinator implements "go_wc serve-coordinator": the count command,
+// with the files counted by the workers that connect to --listen.
+func runCoordinator(args []string) int {
+	return countWith(parseCoordinatorArgs(args))
+}
+
+// parseCoordinatorArgs parses the command line of serve-coordinator: the
+// options of count, with --listen and --chunk-size, and the files.
+func parseCoordinatorArgs(args []string) (cliConfig, []string, error) {
+	var cfg cliConfig
+	fs := countFlags(&cfg)
+	fs.StringVar(&cfg.listen, "listen", "", "")
+	fs.Var(sizeValue{&cfg.chunkSize}, "chunk-size", "")
+	if err := fs.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+	if cfg.listen == "" {
+		return cfg, nil, errors.New("serve-coordinator: --listen=ADDR is required")
+	}
+	// workers return the counts alone, and count with nothing but the countSpec
+	if cfg.remote || cfg.stdioRPC || cfg.perLine || cfg.interval != "" || cfg.status || cfg.estimate != "" ||
+		cfg.uniqueWords != "" || cfg.ngrams != "" || cfg.scripts || cfg.checkpoint != "" || cfg.resume != "" ||
+		cfg.sandbox || cfg.profile || cfg.streaming || cfg.fileTimeout > 0 || cfg.halt != haltNever {
+		return cfg, nil, errors.New("serve-coordinator cannot be combined with --remote, --stdio-rpc, --per-line, --interval, --status, --estimate, --unique-words, --ngrams, --scripts, --checkpoint, --resume, --sandbox, --profile-summary, --streaming, --file-timeout or --halt")
+	}
+	return checkArgs(cfg, fs.Args())
+}
+
+// runWorker implements "go_wc worker": it counts the tasks of the
+// coordinator at --connect until the coordinator has no more, and returns
+// the process exit code.
+func runWorker(args []string) int {
+	fs := flag.NewFlagSet("go_wc worker", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	connect := fs.String("connect", "", "")
+	jobs := fs.Int("jobs", runtime.GOMAXPROCS(0), "")
+	fs.IntVar(jobs, "j", runtime.GOMAXPROCS(0), "")
+	bufSize := fs.Int("buffer-size", 1*1024*1024, "")
+	logLevel := fs.String("log-level", "", "")
+	logJSON := fs.Bool("log-json", false, "")
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *connect == "" {
+		fmt.Fprintln(os.Stderr, "go_wc worker: --connect=HOST:PORT is required")
+		return 1
+	}
+	if err := setupLogging(*logLevel, *logJSON); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	conn, err := net.Dial("tcp", *connect)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "go_wc: %v\n", err)
+		return 1
+	}
+	defer conn.Close()
+	if err := serveCoordinator(conn, max(*jobs, 1), *bufSize); err != nil {
+		fmt.Fprintf(os.Stderr, "go_wc: %s: %v\n", *connect, err)
+		return 1
+	}
+	return 0
+}
+
+// serveCoordinator counts the tasks the coordinator at the other end of
+// conn assigns, up to jobs at a time, until it has no more.
+func serveCoordinator(conn io.ReadWriter, jobs, bufSize int) error {
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	var hello distHello
+	if err := dec.Decode(&hello); err != nil {
+		return err
+	}
+	if hello.Version != distVersion {
+		return fmt.Errorf("unsupported coordinator protocol version %d", hello.Version)
+	}
+	opts, err := hello.Spec.options(bufSize)
+	if err != nil {
+		return err
+	}
+	m := hello.Spec.Metrics
+	var results []distResult
+	for {
+		if err := enc.Encode(distRequest{Want: jobs, Results: results}); err != nil {
+			return err
+		}
+		var a distAssignment
+		if err := dec.Decode(&a); err != nil {
+			return err
+		}
+		if a.Done {
+			return nil
+		}
+		results = make([]distResult, len(a.Tasks))
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, jobs)
+		for i, t := range a.Tasks {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				results[i] = countTask(t, m, opts)
+				<-sem
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+// countTask counts a task of the coordinator.
+func countTask(t distTask, m wc.Metrics, opts wc.Options) distResult {
+	if !t.Chunk {
+		fr := wc.CountFile(context.Background(), t.Path, m, opts)
+		logger.Debug("counted", "file", t.Path, "duration", fr.Duration)
+		return distResult{Task: t.ID, storedResult: storeResult(fr)}
+	}
+	fail := func(err error) distResult {
+		return distResult{Task: t.ID, storedResult: storeResult(wc.FileResult{Err: err})}
+	}
+	f, err := os.Open(t.Path)
+	if err != nil {
+		return fail(err)
+	}
+	defer f.Close()
+	opts.Language = wc.LanguageForFile(t.Path)
+	c := wc.NewChunkCounter(m, opts)
+	if _, err := io.CopyBuffer(c, io.NewSectionReader(f, t.Offset, t.Length), make([]byte, opts.BufferSize)); err != nil {
+		return fail(err)
+	}
+	cr := c.Chunk()
+	if cr.Bytes != uint64(t.Length) {
+		return fail(fmt.Errorf("file shrank while it was counted: read %d bytes at offset %d, want %d", cr.Bytes, t.Offset, t.Length))
+	}
+	logger.Debug("counted", "file", t.Path, "offset", t.Offset, "length", t.Length)
+	return distResult{Task: t.ID, Chunk: &cr}
+}
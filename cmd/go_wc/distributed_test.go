@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+func TestCoordinate(t *testing.T) {
+	dir := t.TempDir()
+	var inputs []string
+	for i, text := range []string{"one\n", strings.Repeat("héllo wörld  日本語\n", 300), strings.Repeat("x", 5000)} {
+		name := filepath.Join(dir, string(rune('a'+i))+".txt")
+		if err := os.WriteFile(name, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, name)
+	}
+	inputs = append(inputs, filepath.Join(dir, "missing"))
+
+	m := wc.Metrics{Lines: true, Words: true, Chars: true, Bytes: true, MaxLineChars: true}
+	spec := countSpec{Metrics: m, Encoding: "utf-8", CountChars: []string{"ö"}}
+	opts, err := spec.options(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	addr := ln.Addr().String()
+
+	// a worker that takes tasks and leaves without results
+	dropout := make(chan error, 1)
+	go func() {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			dropout <- err
+			return
+		}
+		defer conn.Close()
+		dec := json.NewDecoder(bufio.NewReader(conn))
+		var hello distHello
+		if err := dec.Decode(&hello); err != nil {
+			dropout <- err
+			return
+		}
+		json.NewEncoder(conn).Encode(distRequest{Want: 3})
+		var a distAssignment
+		err = dec.Decode(&a)
+		if err == nil && len(a.Tasks) == 0 {
+			err = errors.New("no tasks assigned")
+		}
+		dropout <- err
+	}()
+	type coordinated struct {
+		all     []wc.FileResult
+		workers int
+		err     error
+	}
+	done := make(chan coordinated, 1)
+	go func() {
+		all, workers, err := coordinate(ln, inputs, spec, opts, 1000)
+		done <- coordinated{all, workers, err}
+	}()
+	if err := <-dropout; err != nil {
+		t.Fatalf("dropout worker: %v", err)
+	}
+	for range 2 {
+		go func() {
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			serveCoordinator(conn, 2, 512)
+		}()
+	}
+
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if len(res.all) != len(inputs) {
+		t.Fatalf("got %d results, want %d", len(res.all), len(inputs))
+	}
+	local := wc.Options{BufferSize: 512, Locale: locale.Detect("utf-8"), CountChars: opts.CountChars}
+	for i, name := range inputs[:3] {
+		want := wc.CountFile(context.Background(), name, m, local)
+		got := res.all[i]
+		if got.Err != nil || got.Filename != name || got.Lines != want.Lines || got.Words != want.Words ||
+			got.Chars != want.Chars || got.Bytes != want.Bytes || got.MaxLineChars != want.MaxLineChars ||
+			got.CharCounts[0] != want.CharCounts[0] {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+	}
+	if res.all[3].Err == nil {
+		t.Error("missing file: expected an error")
+	}
+}
+
+func TestParseCoordinatorArgs(t *testing.T) {
+	cfg, files, err := parseCoordinatorArgs([]string{"--listen=:7070", "--chunk-size=4MiB", "-l", "a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.listen != ":7070" || cfg.chunkSize != 4<<20 || !cfg.countLines || len(files) != 2 {
+		t.Errorf("got cfg %+v, files %q", cfg, files)
+	}
+	for _, args := range [][]string{
+		{"a"},
+		{"--listen=:7070", "--remote", "a"},
+		{"--listen=:7070", "--checkpoint=run.ckpt", "a"},
+		{"--listen=:7070", "--chunk-size=lots", "a"},
+	} {
+		if _, _, err := parseCoordinatorArgs(args); err == nil {
+			t.Errorf("%q: expected an error", args)
+		}
+	}
+}
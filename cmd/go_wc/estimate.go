@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// parseEstimate converts an --estimate sample percentage to a fraction.
+func parseEstimate(s string) (float64, error) {
+	pct, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil || pct <= 0 || pct > 100 {
+		return 0, fmt.Errorf("invalid --estimate sample %q (want a percentage in (0, 100])", s)
+	}
+	return pct / 100, nil
+}
+
+// estimateLog remembers the sampled estimates made by countFile so that
+// their confidence intervals can be printed next to the counts.
+type estimateLog struct {
+	mu     sync.Mutex
+	byName map[string]wc.Estimate
+}
+
+func newEstimateLog() *estimateLog {
+	return &estimateLog{byName: make(map[string]wc.Estimate)}
+}
+
+func (l *estimateLog) count(name string, cs countSettings) wc.FileResult {
+	est := wc.EstimateFile(name, cs.metrics, cs.opts, cs.estimate)
+	if est.Err == nil && !est.Exact {
+		l.mu.Lock()
+		l.byName[name] = est
+		l.mu.Unlock()
+	}
+	return est.FileResult
+}
+
+func (l *estimateLog) get(name string) (wc.Estimate, bool) {
+	if l == nil {
+		return wc.Estimate{}, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	est, ok := l.byName[name]
+	return est, ok
+}
+
+// total combines the margins of all estimated results; independent errors
+// add in quadrature.
+func (l *estimateLog) total(all []wc.FileResult) (wc.Estimate, bool) {
+	var sq [3]float64
+	found := false
+	for _, r := range all {
+		est, ok := l.get(r.Filename)
+		if !ok || r.Err != nil {
+			continue
+		}
+		found = true
+		for i, v := range [3]uint64{est.LinesMargin, est.WordsMargin, est.CharsMargin} {
+			sq[i] += float64(v) * float64(v)
+		}
+	}
+	return wc.Estimate{
+		LinesMargin: uint64(math.Ceil(math.Sqrt(sq[0]))),
+		WordsMargin: uint64(math.Ceil(math.Sqrt(sq[1]))),
+		CharsMargin: uint64(math.Ceil(math.Sqrt(sq[2]))),
+	}, found
+}
+
+// estimateNote describes the 95% confidence intervals of an estimate for
+// the metrics being printed.
+func estimateNote(est wc.Estimate, m wc.Metrics) string {
+	var parts []string
+	if m.Lines {
+		parts = append(parts, fmt.Sprintf("lines ±%d", est.LinesMargin))
+	}
+	if m.Words {
+		parts = append(parts, fmt.Sprintf("words ±%d", est.WordsMargin))
+	}
+	if m.Chars {
+		parts = append(parts, fmt.Sprintf("chars ±%d", est.CharsMargin))
+	}
+	if len(parts) == 0 {
+		return " (estimated)"
+	}
+	return " (estimated, 95% CI " + strings.Join(parts, ", ") + ")"
+}
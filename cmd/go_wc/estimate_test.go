@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestParseEstimate(t *testing.T) {
+	for in, want := range map[string]float64{"1": 0.01, "5%": 0.05, "100": 1} {
+		if got, err := parseEstimate(in); err != nil || got != want {
+			t.Errorf("parseEstimate(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"0", "-3", "150", "lots"} {
+		if _, err := parseEstimate(bad); err == nil {
+			t.Errorf("parseEstimate(%q): expected error", bad)
+		}
+	}
+}
+
+func TestEstimateNote(t *testing.T) {
+	est := wc.Estimate{LinesMargin: 12, WordsMargin: 345}
+	if got, want := estimateNote(est, wc.Metrics{Lines: true, Words: true, Bytes: true}), " (estimated, 95% CI lines ±12, words ±345)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := estimateNote(est, wc.Metrics{Bytes: true}); got != " (estimated)" {
+		t.Errorf("bytes only: got %q", got)
+	}
+
+	var log *estimateLog
+	if _, ok := log.total([]wc.FileResult{{Filename: "a"}}); ok {
+		t.Error("nil log should report no estimates")
+	}
+	log = newEstimateLog()
+	log.byName["a"] = wc.Estimate{LinesMargin: 3}
+	log.byName["b"] = wc.Estimate{LinesMargin: 4}
+	if tot, ok := log.total([]wc.FileResult{{Filename: "a"}, {Filename: "b"}, {Filename: "c"}}); !ok || tot.LinesMargin != 5 {
+		t.Errorf("total: got %+v, %v; want lines margin 5", tot, ok)
+	}
+}
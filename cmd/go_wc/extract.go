@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// extractor is an --extract-with command, which converts the files whose
+// base names match glob, or any file when glob is empty, to the text that
+// is counted in their place.
+type extractor struct {
+	glob string
+	argv []string // "{}" in an argument stands for the file name
+}
+
+// extractGlob is the "GLOB=" that may begin an --extract-with value: a
+// pattern with a wildcard, without white space or "=".
+var extractGlob = regexp.MustCompile(`^([^\s=]*[*?[][^\s=]*)=`)
+
+// parseExtractor parses an --extract-with value, "[GLOB=]COMMAND". The
+// command is split into words at white space outside single or double
+// quotes and run directly, not by a shell, so that file names need no
+// quoting: an argument "{}", or one holding it such as "--in={}", gets
+// the name of the file; without one, the file is the command's standard
+// input.
+func parseExtractor(s string) (extractor, error) {
+	var x extractor
+	if m := extractGlob.FindStringSubmatch(s); m != nil {
+		if _, err := filepath.Match(m[1], ""); err != nil {
+			return x, fmt.Errorf("invalid --extract-with pattern %q", m[1])
+		}
+		x.glob, s = m[1], s[len(m[0]):]
+	}
+	argv, err := splitCommand(s)
+	if err != nil {
+		return x, fmt.Errorf("invalid --extract-with %q: %v", s, err)
+	}
+	if len(argv) == 0 {
+		return x, errors.New("--extract-with needs a command")
+	}
+	x.argv = argv
+	return x, nil
+}
+
+// splitCommand splits s into words at white space, keeping quoted text,
+// in single or double quotes, in one word without its quotes.
+func splitCommand(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// extractors are the --extract-with commands, in the order given.
+type extractors []extractor
+
+// parseExtractors parses the --extract-with values.
+func parseExtractors(values []string) (extractors, error) {
+	var xs extractors
+	for _, v := range values {
+		x, err := parseExtractor(v)
+		if err != nil {
+			return nil, err
+		}
+		xs = append(xs, x)
+	}
+	return xs, nil
+}
+
+// find returns the first extractor for the file name, or nil to count the
+// file as it is.
+func (xs extractors) find(name string) *extractor {
+	base := filepath.Base(name)
+	for i := range xs {
+		if xs[i].glob == "" {
+			return &xs[i]
+		}
+		if ok, _ := filepath.Match(xs[i].glob, base); ok {
+			return &xs[i]
+		}
+	}
+	return nil
+}
+
+// countExtracted counts, as countFile would the file itself, the text that
+// x writes to its standard output for the named file. A command that
+// fails fails the file, with what it printed on standard error.
+func countExtracted(ctx context.Context, name string, x *extractor, cs countSettings) wc.FileResult {
+	start := time.Now()
+	fail := func(err error) wc.FileResult {
+		return wc.FileResult{Filename: name, Err: fmt.Errorf("--extract-with %s: %w", x.argv[0], err)}
+	}
+	args := make([]string, len(x.argv)-1)
+	named := false
+	for i, a := range x.argv[1:] {
+		args[i] = strings.ReplaceAll(a, "{}", name)
+		named = named || args[i] != a
+	}
+	cmd := exec.CommandContext(ctx, x.argv[0], args...)
+	if !named {
+		f, err := os.Open(name)
+		if err != nil {
+			return wc.FileResult{Filename: name, Err: explainOpenError(name, err)}
+		}
+		defer f.Close()
+		cmd.Stdin = f
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return fail(err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fail(err)
+	}
+	opts := cs.opts
+	opts.TotalBytes = 0
+	fr := wc.CountReader(bufio.NewReaderSize(out, opts.BufferSize), cs.metrics, opts)
+	// read the rest, which --max-lines and --max-bytes may leave, so that
+	// the command is not stopped by a broken pipe
+	_, _ = io.Copy(io.Discard, out)
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		} else if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%v: %s", err, msg)
+		}
+		return fail(err)
+	}
+	fr.Filename = name
+	fr.Duration = time.Since(start)
+	return fr
+}
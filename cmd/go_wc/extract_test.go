@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestParseExtractor(t *testing.T) {
+	tests := []struct {
+		in   string
+		glob string
+		argv []string
+	}{
+		{"pdftotext {} -", "", []string{"pdftotext", "{}", "-"}},
+		{"*.pdf=pdftotext -layout {} -", "*.pdf", []string{"pdftotext", "-layout", "{}", "-"}},
+		{"*.docx=pandoc --to=plain {}", "*.docx", []string{"pandoc", "--to=plain", "{}"}},
+		{"pandoc --to=plain", "", []string{"pandoc", "--to=plain"}},
+		{`sh -c 'unzip -p "$1" word/document.xml' sh {}`, "", []string{"sh", "-c", `unzip -p "$1" word/document.xml`, "sh", "{}"}},
+		{`"C:\Program Files\x.exe" {}`, "", []string{`C:\Program Files\x.exe`, "{}"}},
+	}
+	for _, tt := range tests {
+		x, err := parseExtractor(tt.in)
+		if err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+			continue
+		}
+		if x.glob != tt.glob || !reflect.DeepEqual(x.argv, tt.argv) {
+			t.Errorf("%q: got %q %q, want %q %q", tt.in, x.glob, x.argv, tt.glob, tt.argv)
+		}
+	}
+	for _, bad := range []string{"", "*.pdf=", "cat 'unterminated", "[.pdf=cat"} {
+		if _, err := parseExtractor(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestExtractorsFind(t *testing.T) {
+	xs, err := parseExtractors([]string{"*.pdf=pdftotext {} -", "*.docx=pandoc {}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"a/b.pdf": "pdftotext", "c.docx": "pandoc", "d.txt": ""} {
+		got := ""
+		if x := xs.find(name); x != nil {
+			got = x.argv[0]
+		}
+		if got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+	xs = append(xs, extractor{argv: []string{"cat"}})
+	if x := xs.find("d.txt"); x == nil || x.argv[0] != "cat" {
+		t.Errorf("a command without a pattern should match any file")
+	}
+}
+
+func TestCountExtracted(t *testing.T) {
+	for _, bin := range []string{"cat", "tr", "false"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s not installed", bin)
+		}
+	}
+	root := writeTree(t, map[string]string{"doc.bin": "one two\nthree\n"})
+	name := filepath.Join(root, "doc.bin")
+	cs := countSettings{
+		metrics: wc.Metrics{Lines: true, Words: true, Bytes: true},
+		opts:    wc.Options{BufferSize: 4096},
+	}
+	for _, cmd := range []string{"cat {}", "tr e E"} {
+		x, _ := parseExtractor(cmd)
+		fr := countExtracted(context.Background(), name, &x, cs)
+		if fr.Err != nil || fr.Lines != 2 || fr.Words != 3 || fr.Bytes != 14 || fr.Filename != name {
+			t.Errorf("%s: got %+v", cmd, fr)
+		}
+	}
+
+	cs.opts.StopAfterLines = 1
+	x, _ := parseExtractor("cat")
+	if fr := countExtracted(context.Background(), name, &x, cs); fr.Err != nil || fr.Lines != 1 || !fr.Truncated {
+		t.Errorf("--max-lines: got %+v", fr)
+	}
+
+	x, _ = parseExtractor("false")
+	if fr := countExtracted(context.Background(), name, &x, cs); fr.Err == nil {
+		t.Error("expected the failure of the command to fail the file")
+	}
+	x, _ = parseExtractor("go-wc-no-such-extractor {}")
+	if fr := countExtracted(context.Background(), name, &x, cs); fr.Err == nil {
+		t.Error("expected an error for a missing command")
+	}
+}
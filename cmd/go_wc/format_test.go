@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/format"
+)
+
+// tsv is a stand-in for a format registered by a program building go_wc.
+type tsv struct{ w io.Writer }
+
+func (f *tsv) Begin(w io.Writer, _ format.Layout) error { f.w = w; return nil }
+func (f *tsv) WriteResult(r wc.FileResult) error {
+	_, err := io.WriteString(f.w, r.Filename+"\t"+format.FormatLine(wc.FileResult{Lines: r.Lines}, wc.Metrics{Lines: true}, 1)+"\n")
+	return err
+}
+func (f *tsv) WriteTotals(r wc.FileResult) error { return f.WriteResult(r) }
+func (f *tsv) End() error                        { return nil }
+
+func TestRegisteredFormat(t *testing.T) {
+	format.Register("test-tsv", func() format.Formatter { return &tsv{} })
+	t.Setenv(posixEnv, "")
+	cfg, _, err := parseArgs([]string{"--format=test-tsv", "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := format.New(cfg.format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := []wc.FileResult{
+		{Filename: "dir/a", Lines: 2},
+		{Filename: "b", Err: errors.New("boom")},
+		{Filename: "dir/c", Lines: 3},
+	}
+	var buf bytes.Buffer
+	name := func(s string) string { return displayName(cliConfig{basename: true}, s) }
+	if err := writeFormatted(&buf, f, format.Layout{}, all, wc.Sum(all), true, name); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "a\t2\nc\t3\ntotal\t5\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
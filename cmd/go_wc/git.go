@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitFiles returns the files of the working tree of the git repository
+// holding dir that its index tracks, limited to those matching one of
+// pathspecs when there are any, named as "git ls-files" names them below
+// dir, dir included. Submodules, and tracked files deleted from the
+// working tree, are left out; a file with merge conflicts is listed once.
+// It runs git, which must be on PATH.
+func gitFiles(dir string, pathspecs []string) ([]string, error) {
+	out, err := runGit(dir, append([]string{"ls-files", "-z", "--stage", "--"}, pathspecs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("--git: %w", err)
+	}
+
+	var names []string
+	last := ""
+	for _, entry := range bytes.Split(out, []byte{0}) {
+		// "MODE OBJECT STAGE\tPATH"
+		meta, path, ok := strings.Cut(string(entry), "\t")
+		if !ok || path == last {
+			continue
+		}
+		last = path
+		if strings.HasPrefix(meta, "160000 ") {
+			continue // a submodule
+		}
+		name := filepath.Join(dir, filepath.FromSlash(path))
+		if _, err := os.Lstat(name); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// runGit runs git with args in dir and returns its standard output. A
+// failure is reported with what git printed on standard error.
+func runGit(dir string, args ...string) ([]byte, error) {
+	bin, err := exec.LookPath("git")
+	if err != nil {
+		return nil, fmt.Errorf("the git command is required: %w", err)
+	}
+	cmd := exec.Command(bin, args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGitFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		".gitignore":      "vendor/\n",
+		"main.go":         "package main\n",
+		"src/a.go":        "package src\n",
+		"src/gone.go":     "package src\n",
+		"vendor/dep.go":   "package dep\n",
+		"untracked.txt":   "x\n",
+		"docs/readme.txt": "docs\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testGit(t, dir, "init", "-q")
+	testGit(t, dir, "add", ".gitignore", "main.go", "src", "docs")
+	os.Remove(filepath.Join(dir, "src", "gone.go"))
+
+	files, err := gitFiles(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".gitignore", "docs/readme.txt", "main.go", "src/a.go"}
+	for i, name := range want {
+		want[i] = filepath.Join(dir, filepath.FromSlash(name))
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("tracked files: got %q, want %q", files, want)
+	}
+
+	files, err = gitFiles(dir, []string{"*.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(dir, "main.go"), filepath.Join(dir, "src", "a.go")}; !reflect.DeepEqual(files, want) {
+		t.Errorf("pathspec: got %q, want %q", files, want)
+	}
+
+	if _, err := gitFiles(t.TempDir(), nil); err == nil {
+		t.Error("outside a repository: expected an error")
+	}
+}
+
+// testGit runs git with args in dir, failing the test if it fails.
+func testGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
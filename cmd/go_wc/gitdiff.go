@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// gitFileDiff is what a file gained and lost between two revisions.
+type gitFileDiff struct {
+	Path         string `json:"path"`
+	LinesAdded   uint64 `json:"lines_added"`
+	LinesRemoved uint64 `json:"lines_removed"`
+	WordsAdded   uint64 `json:"words_added"`
+	WordsRemoved uint64 `json:"words_removed"`
+	Binary       bool   `json:"binary,omitempty"` // no lines or words are counted
+}
+
+// gitDiff returns, in git's order, the files that differ between the
+// revisions of rev ("A..B", or "A" for A against the working tree) in the
+// repository holding dir, limited to pathspecs when there are any. Lines
+// are those of "git diff --numstat"; words are runs of non-whitespace in
+// the changes of "git diff --word-diff", which tells a reworded line from
+// a rewritten one. Renames are not detected, so a renamed file is removed
+// under one name and added under the other.
+func gitDiff(dir, rev string, pathspecs []string) ([]gitFileDiff, error) {
+	base := []string{"-c", "core.quotepath=off", "diff", "--no-color", "--no-ext-diff", "--no-renames"}
+	tail := append([]string{rev, "--"}, pathspecs...)
+	numstat, err := runGit(dir, append(append(base, "--numstat", "-z"), tail...)...)
+	if err != nil {
+		return nil, err
+	}
+	diffs, err := parseNumstat(numstat)
+	if err != nil {
+		return nil, err
+	}
+	words, err := runGit(dir, append(append(base, "--word-diff=porcelain", "-U0"), tail...)...)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]*gitFileDiff, len(diffs))
+	for i := range diffs {
+		index[diffs[i].Path] = &diffs[i]
+	}
+	if err := addWordDiff(bytes.NewReader(words), index); err != nil {
+		return nil, err
+	}
+	return diffs, nil
+}
+
+// parseNumstat parses the output of "git diff --numstat -z --no-renames":
+// "ADDED\tREMOVED\tPATH" records ended by NULs, with "-" for the counts of
+// binary files.
+func parseNumstat(out []byte) ([]gitFileDiff, error) {
+	var diffs []gitFileDiff
+	for _, rec := range strings.Split(string(out), "\x00") {
+		if rec == "" {
+			continue
+		}
+		fields := strings.SplitN(rec, "\t", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected git diff --numstat record %q", rec)
+		}
+		d := gitFileDiff{Path: fields[2]}
+		if fields[0] == "-" && fields[1] == "-" {
+			d.Binary = true
+		} else {
+			var err1, err2 error
+			d.LinesAdded, err1 = strconv.ParseUint(fields[0], 10, 64)
+			d.LinesRemoved, err2 = strconv.ParseUint(fields[1], 10, 64)
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("unexpected git diff --numstat record %q", rec)
+			}
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
+
+// addWordDiff adds the words of the changes in r, the output of "git diff
+// --word-diff=porcelain", to the entries of diffs, keyed by path. In the
+// hunks, lines starting with "+" and "-" hold added and removed text.
+func addWordDiff(r io.Reader, diffs map[string]*gitFileDiff) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<30)
+	var cur *gitFileDiff
+	inHunk := false
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			cur, inHunk = nil, false
+		case !inHunk && strings.HasPrefix(line, "--- "):
+			if name, ok := diffPath(line[4:]); ok {
+				cur = diffs[name]
+			}
+		case !inHunk && strings.HasPrefix(line, "+++ "):
+			if name, ok := diffPath(line[4:]); ok {
+				cur = diffs[name]
+			}
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case inHunk && cur != nil && strings.HasPrefix(line, "+"):
+			cur.WordsAdded += uint64(len(strings.Fields(line[1:])))
+		case inHunk && cur != nil && strings.HasPrefix(line, "-"):
+			cur.WordsRemoved += uint64(len(strings.Fields(line[1:])))
+		}
+	}
+	return sc.Err()
+}
+
+// diffPath returns the path of a "---" or "+++" header of git diff, "a/"
+// or "b/" prefixed and quoted as a C string when unusual, or false for
+// /dev/null. Git ends an unquoted path containing a space with a tab,
+// which is dropped; a tab of the name itself would be quoted.
+func diffPath(s string) (string, bool) {
+	s = strings.TrimSuffix(s, "\t")
+	if strings.HasPrefix(s, `"`) {
+		u, err := strconv.Unquote(s)
+		if err != nil {
+			return "", false
+		}
+		s = u
+	}
+	if s == "/dev/null" || len(s) < 2 || (s[0] != 'a' && s[0] != 'b') || s[1] != '/' {
+		return "", false
+	}
+	return s[2:], true
+}
+
+// writeGitDiff prints one line per file, the lines and words it gained
+// and lost aligned as by wc, then the totals when there are several files.
+func writeGitDiff(w io.Writer, diffs []gitFileDiff) error {
+	var total gitFileDiff
+	for _, d := range diffs {
+		total.LinesAdded += d.LinesAdded
+		total.LinesRemoved += d.LinesRemoved
+		total.WordsAdded += d.WordsAdded
+		total.WordsRemoved += d.WordsRemoved
+	}
+	cells := func(d gitFileDiff) []string {
+		if d.Binary {
+			return []string{"-", "-", "-", "-"}
+		}
+		return []string{
+			"+" + strconv.FormatUint(d.LinesAdded, 10), "-" + strconv.FormatUint(d.LinesRemoved, 10),
+			"+" + strconv.FormatUint(d.WordsAdded, 10), "-" + strconv.FormatUint(d.WordsRemoved, 10),
+		}
+	}
+	width := 1
+	for _, c := range cells(total) {
+		width = max(width, len(c))
+	}
+	line := func(d gitFileDiff, name string) error {
+		var b strings.Builder
+		for i, c := range cells(d) {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			fmt.Fprintf(&b, "%*s", width, c)
+		}
+		b.WriteString(" " + quoteName(name) + "\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	for _, d := range diffs {
+		if err := line(d, d.Path); err != nil {
+			return err
+		}
+	}
+	if len(diffs) > 1 {
+		return line(total, "total")
+	}
+	return nil
+}
+
+// runGitDiff implements "go_wc git-diff REV1..REV2 [-- PATHSPEC...]":
+// report the lines and words each file gained and lost between two
+// revisions of the repository holding the current directory.
+func runGitDiff(args []string, stdout, stderr io.Writer) int {
+	fset := flag.NewFlagSet("go_wc git-diff", flag.ContinueOnError)
+	fset.SetOutput(io.Discard)
+	formatName := fset.String("format", "text", "")
+	if err := fset.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	rest := fset.Args()
+	if len(rest) == 0 || strings.HasPrefix(rest[0], "-") {
+		fmt.Fprintln(stderr, "usage: go_wc git-diff [--format text|json] REV1..REV2 [-- PATHSPEC...]")
+		return 1
+	}
+	rev, pathspecs := rest[0], rest[1:]
+	if len(pathspecs) > 0 && pathspecs[0] == "--" {
+		pathspecs = pathspecs[1:]
+	}
+	if *formatName != "text" && *formatName != "json" {
+		fmt.Fprintf(stderr, "go_wc: git-diff: unsupported format %q (want text or json)\n", *formatName)
+		return 1
+	}
+
+	diffs, err := gitDiff("", rev, pathspecs)
+	if err != nil {
+		fmt.Fprintf(stderr, "go_wc: git-diff: %v\n", err)
+		return 1
+	}
+	if *formatName == "json" {
+		if diffs == nil {
+			diffs = []gitFileDiff{}
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(struct {
+			Files []gitFileDiff `json:"files"`
+		}{diffs})
+	} else {
+		err = writeGitDiff(stdout, diffs)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "go_wc: %v\n", err)
+		return 1
+	}
+	return 0
+}
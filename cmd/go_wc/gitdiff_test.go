@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNumstat(t *testing.T) {
+	diffs, err := parseNumstat([]byte("3\t1\tdocs/a.md\x00-\t-\timg.png\x000\t2\twith\ttab\x00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []gitFileDiff{
+		{Path: "docs/a.md", LinesAdded: 3, LinesRemoved: 1},
+		{Path: "img.png", Binary: true},
+		{Path: "with\ttab", LinesRemoved: 2},
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("got %+v, want %+v", diffs, want)
+	}
+	if _, err := parseNumstat([]byte("x\t1\tname\x00")); err == nil {
+		t.Error("bad count: expected an error")
+	}
+}
+
+func TestAddWordDiff(t *testing.T) {
+	const out = `diff --git a/a.md b/a.md
+index 1111111..2222222 100644
+--- a/a.md
++++ b/a.md
+@@ -1 +1 @@
+ The
+-quick brown
++slow red old
+ fox
+~
+@@ -3,0 +4 @@
++--- a rule, +++ not a header
+~
+diff --git "a/sp\303\251cial\tname" "b/sp\303\251cial\tname"
+deleted file mode 100644
+--- "a/sp\303\251cial\tname"
++++ /dev/null
+@@ -1 +0,0 @@
+-gone now
+~
+diff --git a/sp ace.md b/sp ace.md
+new file mode 100644
+--- /dev/null
++++ b/sp ace.md	
+@@ -0,0 +1 @@
++new file
+~
+`
+	a := &gitFileDiff{Path: "a.md"}
+	special := &gitFileDiff{Path: "spécial\tname"}
+	spaced := &gitFileDiff{Path: "sp ace.md"}
+	if err := addWordDiff(strings.NewReader(out), map[string]*gitFileDiff{"a.md": a, special.Path: special, spaced.Path: spaced}); err != nil {
+		t.Fatal(err)
+	}
+	if a.WordsAdded != 10 || a.WordsRemoved != 2 {
+		t.Errorf("a.md: got +%d -%d words, want +10 -2", a.WordsAdded, a.WordsRemoved)
+	}
+	if special.WordsAdded != 0 || special.WordsRemoved != 2 {
+		t.Errorf("quoted name: got +%d -%d words, want +0 -2", special.WordsAdded, special.WordsRemoved)
+	}
+	if spaced.WordsAdded != 2 || spaced.WordsRemoved != 0 {
+		t.Errorf("name with a space: got +%d -%d words, want +2 -0", spaced.WordsAdded, spaced.WordsRemoved)
+	}
+}
+
+func TestGitDiff(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	write := func(name, text string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testGit(t, dir, "init", "-q")
+	write("doc.md", "one two three\nfour\n")
+	write("old.md", "to be removed\n")
+	testGit(t, dir, "add", ".")
+	testGit(t, dir, "commit", "-q", "-m", "first")
+	write("doc.md", "one 2 three\nfour\nfive six\n")
+	os.Remove(filepath.Join(dir, "old.md"))
+	write("new.md", "brand new\n")
+	write("sp ace.md", "new file\n")
+	testGit(t, dir, "add", "-A")
+	testGit(t, dir, "commit", "-q", "-m", "second")
+
+	diffs, err := gitDiff(dir, "HEAD~1..HEAD", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []gitFileDiff{
+		{Path: "doc.md", LinesAdded: 2, LinesRemoved: 1, WordsAdded: 3, WordsRemoved: 1},
+		{Path: "new.md", LinesAdded: 1, WordsAdded: 2},
+		{Path: "old.md", LinesRemoved: 1, WordsRemoved: 3},
+		{Path: "sp ace.md", LinesAdded: 1, WordsAdded: 2},
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("got %+v\nwant %+v", diffs, want)
+	}
+
+	if diffs, err = gitDiff(dir, "HEAD~1..HEAD", []string{"new.md"}); err != nil || len(diffs) != 1 {
+		t.Errorf("pathspec: got %+v, %v", diffs, err)
+	}
+	if _, err := gitDiff(dir, "nosuchrev..HEAD", nil); err == nil {
+		t.Error("bad revision: expected an error")
+	}
+
+	var stdout strings.Builder
+	if err := writeGitDiff(&stdout, want); err != nil {
+		t.Fatal(err)
+	}
+	wantText := "+2 -1 +3 -1 doc.md\n" +
+		"+1 -0 +2 -0 new.md\n" +
+		"+0 -1 +0 -3 old.md\n" +
+		"+1 -0 +2 -0 sp ace.md\n" +
+		"+4 -2 +7 -4 total\n"
+	if stdout.String() != wantText {
+		t.Errorf("text output:\ngot\n%swant\n%s", stdout.String(), wantText)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io"
+	"sort"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/format"
+)
+
+// htmlReport writes the counts for --format=html: a page with the files and
+// their totals per --group-by key, the extension by default. Failed inputs
+// are reported on stderr.
+func htmlReport(w io.Writer, cfg cliConfig, all []wc.FileResult, m wc.Metrics, extra []string, multiple bool) error {
+	ok := succeeded(all)
+	rep := format.HTMLReport{Title: "go_wc report", Files: displayRows(cfg, ok)}
+	if multiple {
+		t := wc.Sum(ok)
+		t.Filename = "total"
+		rep.Total = &t
+	}
+
+	by := cfg.groupBy
+	if by == "" {
+		by = "ext"
+	}
+	rep.GroupBy = map[string]string{"ext": "extension", "dir": "directory"}[by]
+	groups := make(map[string][]wc.FileResult)
+	for _, r := range ok {
+		key := groupKey(by, r.Filename)
+		groups[key] = append(groups[key], r)
+	}
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		t := wc.Sum(groups[k])
+		t.Filename = k
+		rep.Groups = append(rep.Groups, t)
+	}
+	return format.WriteHTML(w, rep, m, extra)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/format"
+)
+
+// sizeUnits are the size suffixes --interval and the --abort-if flags
+// accept, longest first so that "MiB" is not taken for "B".
+var sizeUnits = []struct {
+	suffix string
+	size   uint64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9},
+	{"B", 1},
+}
+
+// parseSize parses a positive amount of bytes such as 64MB or 1GiB, or a
+// bare number of bytes if bare is set.
+func parseSize(s string, bare bool) (uint64, bool) {
+	unit := uint64(0)
+	for _, u := range sizeUnits {
+		if num, ok := strings.CutSuffix(s, u.suffix); ok {
+			s, unit = num, u.size
+			break
+		}
+	}
+	if unit == 0 {
+		if !bare {
+			return 0, false
+		}
+		unit = 1
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || n == 0 || n > math.MaxUint64/unit {
+		return 0, false
+	}
+	return n * unit, true
+}
+
+// parseInterval parses an --interval value: a duration such as 10s, or an
+// amount of input such as 64MB or 1GiB. Exactly one of the results is
+// non-zero.
+func parseInterval(s string) (time.Duration, uint64, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		if d <= 0 {
+			return 0, 0, fmt.Errorf("invalid --interval %q (want a positive duration or size)", s)
+		}
+		return d, 0, nil
+	}
+	if n, ok := parseSize(s, false); ok {
+		return 0, n, nil
+	}
+	return 0, 0, fmt.Errorf("invalid --interval %q (want a duration like 10s or a size like 64MB)", s)
+}
+
+// countSnapshots counts r like wc.CountReader and passes the running totals
+// to snap every period of time, or whenever another step bytes have been
+// read, until r ends. A slow stream still gets its timed snapshots, since
+// they are taken while the read is blocked. snap is never called
+// concurrently and not at all after countSnapshots returns.
+func countSnapshots(r io.Reader, m wc.Metrics, opts wc.Options, period time.Duration, step uint64, snap func(wc.FileResult)) wc.FileResult {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 64 * 1024
+	}
+	c := wc.NewCounter(m, opts)
+	var mu sync.Mutex // guards c
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	if period > 0 {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ticker.C:
+					mu.Lock()
+					res := c.Result()
+					mu.Unlock()
+					snap(res)
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	buf := make([]byte, opts.BufferSize)
+	var read uint64
+	next := step
+	var err error
+	for {
+		t := time.Now()
+		n, rerr := r.Read(buf)
+		if opts.Profile != nil {
+			opts.Profile.AddReadWait(time.Since(t))
+		}
+		if n > 0 {
+			mu.Lock()
+			_, _ = c.Write(buf[:n])
+			if gerr := c.Err(); gerr != nil {
+				mu.Unlock()
+				err = gerr
+				break
+			}
+			read += uint64(n)
+			if step > 0 && read >= next {
+				next = (read/step + 1) * step
+				res := c.Result()
+				mu.Unlock()
+				snap(res)
+			} else {
+				mu.Unlock()
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			err = rerr
+			break
+		}
+	}
+	close(done)
+	wg.Wait()
+	res := c.Result()
+	res.Err = err
+	return res
+}
+
+// countInterval counts standard input for --interval, printing a line of
+// running totals on standard output at every snapshot; the final counts
+// are printed as usual.
+func countInterval(cfg cliConfig, inputs []string, m wc.Metrics, opts wc.Options) wc.FileResult {
+	period, step, _ := parseInterval(cfg.interval)
+	start := time.Now()
+	snap := func(r wc.FileResult) {
+		r.Filename = "-"
+		w := columnWidth(cfg, inputs, []wc.FileResult{r}, r, m)
+		fmt.Fprintln(os.Stdout, format.FormatLine(r, m, w))
+	}
+	fr := countSnapshots(opts.Strip.Reader(os.Stdin), m, opts, period, step, snap)
+	fr.Filename = "-"
+	fr.Duration = time.Since(start)
+	return fr
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		in     string
+		period time.Duration
+		step   uint64
+		ok     bool
+	}{
+		{"10s", 10 * time.Second, 0, true},
+		{"1m30s", 90 * time.Second, 0, true},
+		{"64MB", 0, 64e6, true},
+		{"1GiB", 0, 1 << 30, true},
+		{"512KiB", 0, 512 << 10, true},
+		{"4096B", 0, 4096, true},
+		{"0s", 0, 0, false},
+		{"0MB", 0, 0, false},
+		{"-1s", 0, 0, false},
+		{"10", 0, 0, false},
+		{"1.5MB", 0, 0, false},
+		{"MB", 0, 0, false},
+	}
+	for _, tt := range tests {
+		period, step, err := parseInterval(tt.in)
+		if (err == nil) != tt.ok || period != tt.period || step != tt.step {
+			t.Errorf("parseInterval(%q) = %v, %d, %v", tt.in, period, step, err)
+		}
+	}
+}
+
+func TestCountSnapshotsStep(t *testing.T) {
+	in := strings.Repeat("ab\n", 10) // 30 bytes
+	var snaps []uint64
+	opts := wc.Options{BufferSize: 4}
+	res := countSnapshots(strings.NewReader(in), wc.DefaultMetrics(), opts, 0, 10, func(r wc.FileResult) {
+		snaps = append(snaps, r.Bytes)
+	})
+	// reads of 4 bytes reach 10, 20 and 30 bytes at 12, 20 and 30
+	if want := []uint64{12, 20, 30}; !slices.Equal(snaps, want) {
+		t.Errorf("snapshots at %v bytes, want %v", snaps, want)
+	}
+	if res.Lines != 10 || res.Words != 10 || res.Bytes != 30 || res.Err != nil {
+		t.Errorf("result %+v", res)
+	}
+}
+
+func TestCountSnapshotsPeriod(t *testing.T) {
+	pr, pw := io.Pipe()
+	snaps := make(chan wc.FileResult, 100)
+	go func() {
+		_, _ = pw.Write([]byte("one two\n"))
+		time.Sleep(50 * time.Millisecond) // idle: timed snapshots keep coming
+		_, _ = pw.Write([]byte("three\n"))
+		pw.Close()
+	}()
+	res := countSnapshots(pr, wc.DefaultMetrics(), wc.Options{}, 5*time.Millisecond, 0, func(r wc.FileResult) {
+		snaps <- r
+	})
+	close(snaps)
+	var n int
+	var last uint64
+	for r := range snaps {
+		n++
+		if r.Bytes < last || r.Lines > 2 {
+			t.Errorf("snapshot %+v after %d bytes", r, last)
+		}
+		last = r.Bytes
+	}
+	if n == 0 {
+		t.Error("no snapshots while the stream was idle")
+	}
+	if res.Lines != 2 || res.Words != 3 {
+		t.Errorf("result %+v", res)
+	}
+}
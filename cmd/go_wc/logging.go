@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// logger receives diagnostics: scheduling, cache decisions, halts, time-outs
+// and, with --log-json, failed inputs. main and runDaemon configure it with
+// setupLogging; until then only warnings are shown.
+var logger = newLogger(os.Stderr, slog.LevelWarn, false)
+
+// logJSON is set by --log-json.
+var logJSON bool
+
+// parseLogLevel parses a --log-level value; "" is warn.
+func parseLogLevel(s string) (slog.Level, error) {
+	switch s {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "", "warn":
+		return slog.LevelWarn, nil
+	}
+	return 0, fmt.Errorf("invalid --log-level value %q (want debug, info or warn)", s)
+}
+
+// setupLogging points logger at stderr with the --log-level and --log-json
+// settings.
+func setupLogging(level string, asJSON bool) error {
+	l, err := parseLogLevel(level)
+	if err != nil {
+		return err
+	}
+	logger = newLogger(os.Stderr, l, asJSON)
+	logJSON = asJSON
+	return nil
+}
+
+// newLogger returns a logger writing records of at least level to w, as
+// JSON objects or as key=value lines without the time.
+func newLogger(w io.Writer, level slog.Level, asJSON bool) *slog.Logger {
+	if asJSON {
+		return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+	}
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+// reportFailure reports an input that could not be counted: as wc does,
+// "go_wc: name: error", or as a warning record with --log-json.
+func reportFailure(name string, err error) {
+	if logJSON {
+		logger.Warn("count failed", "file", name, "error", err.Error())
+		return
+	}
+	fmt.Fprintf(os.Stderr, "go_wc: %s: %v\n", quoteName(name), err)
+}
+
+// reportFailures reports each failed input of all.
+func reportFailures(all []wc.FileResult) {
+	for _, r := range all {
+		if r.Err != nil {
+			reportFailure(r.Filename, r.Err)
+		}
+	}
+}
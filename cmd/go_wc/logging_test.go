@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	for in, want := range map[string]slog.Level{"": slog.LevelWarn, "warn": slog.LevelWarn, "info": slog.LevelInfo, "debug": slog.LevelDebug} {
+		if got, err := parseLogLevel(in); err != nil || got != want {
+			t.Errorf("parseLogLevel(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := parseLogLevel("trace"); err == nil {
+		t.Error("expected an error for trace")
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := newLogger(&buf, slog.LevelInfo, false)
+	l.Debug("hidden")
+	l.Info("halting", "policy", "soon")
+	if got, want := buf.String(), "level=INFO msg=halting policy=soon\n"; got != want {
+		t.Errorf("text log = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	l = newLogger(&buf, slog.LevelDebug, true)
+	l.Debug("cache hit", "file", "/a")
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("json log %q: %v", buf.String(), err)
+	}
+	if rec["msg"] != "cache hit" || rec["file"] != "/a" || rec["level"] != "DEBUG" || rec["time"] == nil {
+		t.Errorf("json record = %v", rec)
+	}
+	if strings.Count(buf.String(), "\n") != 1 {
+		t.Errorf("want one line, got %q", buf.String())
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math/big"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/format"
+)
+
+// fileMeta is what --with-metadata reports about an input, as stat saw it
+// when the inputs were collected, before counting. A size that differs from
+// the counted bytes, or an mtime later than the run, shows that the file
+// changed in between.
+type fileMeta struct {
+	Size  int64  `json:"size"`
+	MTime string `json:"mtime"`
+	Mode  string `json:"mode"`
+	Inode uint64 `json:"inode,omitempty"`
+	Dev   uint64 `json:"dev,omitempty"`
+
+	modTime time.Time
+}
+
+// statInputs returns the metadata of each named input; standard input and
+// files that cannot be stat'ed have none.
+func statInputs(inputs []string) map[string]*fileMeta {
+	meta := make(map[string]*fileMeta, len(inputs))
+	for _, name := range inputs {
+		if name == "-" {
+			continue
+		}
+		st, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		m := &fileMeta{
+			modTime: st.ModTime(),
+			Size:    st.Size(),
+			MTime:   st.ModTime().UTC().Format(time.RFC3339Nano),
+			Mode:    st.Mode().String(),
+		}
+		m.Dev, m.Inode = fileID(st)
+		meta[name] = m
+	}
+	return meta
+}
+
+// jsonResult is a --format=json record, as described by jsonSchema: a
+// result in the daemon's JSON form, the extra count columns keyed by their
+// labels and, with --with-metadata, its file's metadata.
+type jsonResult struct {
+	SchemaVersion int `json:"schema_version"`
+	remoteResult
+	// FilenameBase64 holds the exact bytes of a file name that is not
+	// valid UTF-8, which Filename can only approximate.
+	FilenameBase64 string            `json:"filename_base64,omitempty"`
+	Counts         map[string]uint64 `json:"counts,omitempty"`
+	Metadata       *fileMeta         `json:"metadata,omitempty"`
+}
+
+// extraCounts maps the labels of the extra columns (--count-char,
+// --count-string and --patterns-from) to r's counts.
+func extraCounts(r wc.FileResult, extra []string) map[string]uint64 {
+	if len(extra) == 0 {
+		return nil
+	}
+	vals := slices.Concat(r.CharCounts, r.StringCounts, r.RegexpCounts)
+	out := make(map[string]uint64, len(extra))
+	for i, label := range extra {
+		if i < len(vals) {
+			out[label] = vals[i]
+		}
+	}
+	return out
+}
+
+// writeJSON prints the results, with the counts m selects, and their total
+// named "total" when there are several, as one JSON array. Failed inputs
+// carry their error. The counts in exact replace those of the total (see
+// bigTotal).
+func writeJSON(w io.Writer, all []wc.FileResult, totals wc.FileResult, multiple bool, m wc.Metrics, extra []string, meta map[string]*fileMeta, exact map[string]*big.Int, name func(string) string) error {
+	recs := make([]any, 0, len(all)+1)
+	for _, r := range all {
+		rec := jsonResult{SchemaVersion: jsonSchemaVersion, remoteResult: toRemoteResult(r, m), Counts: extraCounts(r, extra), Metadata: meta[r.Filename]}
+		rec.Filename = name(r.Filename)
+		rec.FilenameBase64 = nameBytes(rec.Filename)
+		recs = append(recs, rec)
+	}
+	if multiple {
+		recs = append(recs, bigTotal(totalRecord(totals, m, extra), exact))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(recs)
+}
+
+// totalRecord returns the --format=json record of totals.
+func totalRecord(totals wc.FileResult, m wc.Metrics, extra []string) jsonResult {
+	totals.Filename = "total"
+	return jsonResult{SchemaVersion: jsonSchemaVersion, remoteResult: toRemoteResult(totals, m), Counts: extraCounts(totals, extra)}
+}
+
+// metaColumns are the CSV columns added by --with-metadata.
+var metaColumns = []string{"size", "mtime", "mode", "inode", "dev"}
+
+// writeCSV prints a header and one row per counted file, then the total
+// when there are several, with the counts in exact in place of its own.
+// Failed inputs are reported on stderr and left out; with meta, the
+// metadata columns follow the counts, empty for inputs without any. When a
+// name is not valid UTF-8, the file column shows it with replacement
+// characters and a last column, filename_base64, has its exact bytes as
+// in JSON.
+func writeCSV(w io.Writer, all []wc.FileResult, totals wc.FileResult, multiple bool, m wc.Metrics, extra []string, meta map[string]*fileMeta, exact map[string]*big.Int, name func(string) string) error {
+	cw := csv.NewWriter(w)
+	header := format.CSVHeader(m, extra)
+	if meta != nil {
+		header = append(header, metaColumns...)
+	}
+	counted := succeeded(all)
+	names := make([]string, len(counted))
+	invalid := false
+	for i, r := range counted {
+		names[i] = name(r.Filename)
+		invalid = invalid || nameBytes(names[i]) != ""
+	}
+	if invalid {
+		header = append(header, "filename_base64")
+	}
+	_ = cw.Write(header)
+	row := func(r wc.FileResult, fm *fileMeta, exact map[string]*big.Int) {
+		exactName := nameBytes(r.Filename)
+		r.Filename = strings.ToValidUTF8(r.Filename, "\uFFFD")
+		rec := format.CSVRecord(r, m)
+		bigRecord(header, rec, exact)
+		if meta != nil {
+			if fm != nil {
+				rec = append(rec, strconv.FormatInt(fm.Size, 10), fm.MTime, fm.Mode,
+					strconv.FormatUint(fm.Inode, 10), strconv.FormatUint(fm.Dev, 10))
+			} else {
+				rec = append(rec, make([]string, len(metaColumns))...)
+			}
+		}
+		if invalid {
+			rec = append(rec, exactName)
+		}
+		_ = cw.Write(rec)
+	}
+	for i, r := range counted {
+		fm := meta[r.Filename]
+		r.Filename = names[i]
+		row(r, fm, nil)
+	}
+	if multiple {
+		totals.Filename = "total"
+		row(totals, nil, exact)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// bigTotal returns the total record rec, with the counts in exact, which
+// do not fit in a uint64, in place of its wrapped ones. Its fields are
+// then in alphabetical order.
+func bigTotal(rec jsonResult, exact map[string]*big.Int) any {
+	if len(exact) == 0 {
+		return rec
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return rec
+	}
+	fields := make(map[string]any)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil {
+		return rec
+	}
+	for name, v := range exact {
+		fields[name] = v
+	}
+	return fields
+}
+
+// bigRecord puts the counts in exact in place of those of the CSV record
+// rec, whose columns header names.
+func bigRecord(header, rec []string, exact map[string]*big.Int) {
+	for i, col := range header {
+		if v, ok := exact[col]; ok && i < len(rec) {
+			rec[i] = v.String()
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestStatInputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("hello\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	meta := statInputs([]string{path, "-", filepath.Join(t.TempDir(), "missing")})
+	if len(meta) != 1 {
+		t.Fatalf("got metadata for %d inputs, want 1", len(meta))
+	}
+	m := meta[path]
+	if m == nil || m.Size != 6 || m.Mode != "-rw-r-----" || m.MTime == "" {
+		t.Errorf("metadata = %+v", m)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	all := []wc.FileResult{
+		{Filename: "a,b.txt", Lines: 1, Words: 2},
+		{Filename: "gone", Err: errors.New("no such file")},
+		{Filename: "-", Lines: 3, Words: 4},
+	}
+	meta := map[string]*fileMeta{"a,b.txt": {Size: 10, MTime: "2026-01-02T03:04:05Z", Mode: "-rw-r--r--", Inode: 7, Dev: 9}}
+	var sb strings.Builder
+	m := wc.Metrics{Lines: true, Words: true}
+	if err := writeCSV(&sb, all, wc.Sum(all), true, m, nil, meta, nil, func(s string) string { return s }); err != nil {
+		t.Fatal(err)
+	}
+	want := "file,lines,words,size,mtime,mode,inode,dev\n" +
+		"\"a,b.txt\",1,2,10,2026-01-02T03:04:05Z,-rw-r--r--,7,9\n" +
+		"-,3,4,,,,,\n" +
+		"total,4,6,,,,,\n"
+	if sb.String() != want {
+		t.Errorf("writeCSV() =\n%s\nwant\n%s", sb.String(), want)
+	}
+
+	sb.Reset()
+	if err := writeCSV(&sb, all[:1], all[0], false, m, []string{"tabs"}, nil, nil, func(s string) string { return s }); err != nil {
+		t.Fatal(err)
+	}
+	if want := "file,lines,words,tabs\n\"a,b.txt\",1,2\n"; sb.String() != want {
+		t.Errorf("writeCSV() without metadata = %q, want %q", sb.String(), want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	all := []wc.FileResult{
+		{Filename: "a.txt", Lines: 1, Bytes: 2, NoFinalNewline: true},
+		{Filename: "gone", Err: errors.New("no such file")},
+	}
+	meta := map[string]*fileMeta{"a.txt": {Size: 2, Mode: "-rw-r--r--"}}
+	var sb strings.Builder
+	if err := writeJSON(&sb, all, wc.Sum(all), true, wc.Metrics{Lines: true, Chars: true}, nil, meta, nil, strings.ToUpper); err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal([]byte(sb.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d records, want 3", len(got))
+	}
+	if got[0]["filename"] != "A.TXT" || got[0]["metadata"].(map[string]any)["size"] != 2.0 {
+		t.Errorf("first record = %v", got[0])
+	}
+	if got[1]["error"] != "no such file" || got[1]["metadata"] != nil {
+		t.Errorf("failed record = %v", got[1])
+	}
+	if got[2]["filename"] != "total" || got[2]["lines"] != 1.0 {
+		t.Errorf("total record = %v", got[2])
+	}
+	// only the selected counts, a count of 0 among them
+	for _, rec := range got {
+		if rec["chars"] != 0.0 {
+			t.Errorf("record %v: want chars 0", rec)
+		}
+		for _, field := range []string{"bytes", "words", "max_line_bytes", "max_line_chars"} {
+			if v, ok := rec[field]; ok {
+				t.Errorf("record %v: unselected %s = %v", rec["filename"], field, v)
+			}
+		}
+	}
+	if got[0]["no_final_newline"] != true {
+		t.Errorf("first record = %v, want no_final_newline", got[0])
+	}
+	if v, ok := got[2]["no_final_newline"]; ok {
+		t.Errorf("total record has no_final_newline %v", v)
+	}
+	if got[2]["no_final_newline_files"] != 1.0 {
+		t.Errorf("total record = %v, want no_final_newline_files 1", got[2])
+	}
+}
+
+func TestWriteJSONInvalidName(t *testing.T) {
+	all := []wc.FileResult{{Filename: "caf\xe9", Lines: 1}}
+	var sb strings.Builder
+	if err := writeJSON(&sb, all, wc.Sum(all), true, wc.Metrics{Lines: true}, nil, nil, nil, func(s string) string { return s }); err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal([]byte(sb.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got[0]["filename_base64"] != "Y2Fm6Q==" {
+		t.Errorf("record = %v, want filename_base64 Y2Fm6Q==", got[0])
+	}
+	if _, ok := got[len(got)-1]["filename_base64"]; ok {
+		t.Errorf("total record has filename_base64: %v", got[len(got)-1])
+	}
+}
+
+func TestWriteCSVInvalidName(t *testing.T) {
+	all := []wc.FileResult{{Filename: "caf\xe9", Lines: 1}, {Filename: "plain", Lines: 2}}
+	var sb strings.Builder
+	if err := writeCSV(&sb, all, wc.Sum(all), true, wc.Metrics{Lines: true}, nil, nil, nil, func(s string) string { return s }); err != nil {
+		t.Fatal(err)
+	}
+	want := "file,lines,filename_base64\n" +
+		"caf\uFFFD,1,Y2Fm6Q==\n" +
+		"plain,2,\n" +
+		"total,3,\n"
+	if sb.String() != want {
+		t.Errorf("writeCSV() =\n%s\nwant\n%s", sb.String(), want)
+	}
+}
+
+func TestBigTotals(t *testing.T) {
+	var tot wc.Totals
+	all := []wc.FileResult{
+		{Filename: "a", Lines: 1, Bytes: math.MaxUint64},
+		{Filename: "b", Lines: 2, Bytes: 2},
+	}
+	for _, r := range all {
+		tot.Add(r)
+	}
+	exact := tot.Exact()
+	id := func(s string) string { return s }
+
+	var sb strings.Builder
+	if err := writeCSV(&sb, all, tot.Result(), true, wc.Metrics{Lines: true, Bytes: true}, nil, nil, exact, id); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(sb.String(), "\ntotal,3,18446744073709551617\n") {
+		t.Errorf("writeCSV() =\n%s", sb.String())
+	}
+
+	sb.Reset()
+	if err := writeJSON(&sb, all, tot.Result(), true, wc.Metrics{Lines: true, Bytes: true}, nil, nil, exact, id); err != nil {
+		t.Fatal(err)
+	}
+	var recs []map[string]any
+	dec := json.NewDecoder(strings.NewReader(sb.String()))
+	dec.UseNumber()
+	if err := dec.Decode(&recs); err != nil {
+		t.Fatal(err)
+	}
+	if total := recs[2]; total["bytes"] != json.Number("18446744073709551617") || total["lines"] != json.Number("3") {
+		t.Errorf("total record = %v", total)
+	}
+}
@@ -2,12 +2,20 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"math/big"
+	"net"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -33,17 +41,411 @@ type cliConfig struct {
 	countWords bool
 	countMaxBytes bool
 	countMaxChars bool
+	countWSLines  bool
+	countEndings  bool
+	countWordLens bool
+	countWPL      bool
+	countEmoji    bool
+	tokenStats    bool
+	wordKinds     bool
+	codeTokens    bool
+	match         string
+	patternsFrom  string
+	maxMatchLen   int
+	countLongest  bool
+	uniqueWords   string
+	foldCase      bool
+	stem          string
+	stopwords     string
+	minWordLen    int
+	hyphen        string // --hyphen: "", "join" or "split"
+	apostrophe    string // --apostrophe: "", "internal" or "split"
 
 	files0From string
+	filesFrom  string
+	git        bool // --git: count the files git tracks
+	streaming  bool
 	encoding   string
+	sbChars    string // --single-byte-chars: "", "charmap" or "bytes"
 	jobs       int
 	bufSize    int
 	showHelp   bool
+	posix      bool // --posix or POSIXLY_CORRECT, see parsePOSIXArgs and posixlyCorrect
+	compat     string // --compat: "" or "bsd"
+	unnamed    bool   // standard input counted for want of operands goes unnamed, in POSIX or BSD mode
+	skipped    int    // inputs --devices=skip left out, for --stats
 	showVer    bool
+	schema     bool // print the JSON output schema
+
+	remote   bool
+	socket   string
+	stdioRPC bool
+
+	fileTimeout time.Duration
+	listOnly    bool
+	print0      bool
+	stats       string
+	profile     bool
+	sandbox     bool
+	header      bool
+	width       int
+	minWidth    int
+	noAlign     bool
+	basename    bool
+	relativeTo  string
+	halt        string
+	inputOrder  bool
+	countChar   []string
+	countString []string
+	offset      int64
+	length      int64
+	region      string   // --region=START:END, turned into offset and length
+	strip       string   // --strip: "", "markdown" or "html"
+	extractWith []string // --extract-with, "[GLOB=]COMMAND" each
+	maxLines    uint64
+	maxBytes    uint64
+	abortLine   uint64
+	abortWord   uint64
+	estimate    string
+	invisibles  bool
+	showNoEOL   bool
+	requireEOL  bool
+	ngrams      string
+	ngramFormat string
+	perLine     bool
+	perRecord   bool
+	records     string
+	recordSep   string
+	interval    string
+	status      bool
+	devices     string
+	noStatOpt   bool
+	scripts     bool
+	reportDir   string
+	format      string
+	groupBy     string
+	output      string
+	outputAppend bool
+	outputShards int
+	bigTotals    bool
+	lineHist     bool
+	logLevel    string
+	logJSON     bool
+	withMeta    bool
+	columns     string
+	truncate    bool
+	wrap        bool
+	outSQLite   string
+	autoJobs    bool
+	checkpoint  string
+	resume      string
+	listen      string // serve-coordinator --listen
+	chunkSize   uint64 // serve-coordinator --chunk-size
+}
+
+// stringList is a repeatable string flag.
+type stringList struct{ dst *[]string }
+
+func (l stringList) String() string {
+	if l.dst == nil {
+		return ""
+	}
+	return strings.Join(*l.dst, ",")
+}
+
+func (l stringList) Set(s string) error {
+	*l.dst = append(*l.dst, s)
+	return nil
+}
+
+// jobsValue is the --jobs flag: a number of workers or "auto".
+type jobsValue struct {
+	n    *int
+	auto *bool
+}
+
+func (v jobsValue) String() string {
+	if v.auto != nil && *v.auto {
+		return "auto"
+	}
+	if v.n == nil {
+		return ""
+	}
+	return strconv.Itoa(*v.n)
+}
+
+func (v jobsValue) Set(s string) error {
+	if s == "auto" {
+		*v.auto = true
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid jobs value %q (want a number or auto)", s)
+	}
+	*v.n, *v.auto = n, false
+	return nil
+}
+
+// sizeValue is a flag holding a number of bytes, with an optional unit
+// suffix as parseSize accepts.
+type sizeValue struct{ dst *uint64 }
+
+func (v sizeValue) String() string {
+	if v.dst == nil {
+		return ""
+	}
+	return strconv.FormatUint(*v.dst, 10)
+}
+
+func (v sizeValue) Set(s string) error {
+	n, ok := parseSize(s, true)
+	if !ok {
+		return fmt.Errorf("invalid size %q (want bytes, or a size like 64KiB or 1MB)", s)
+	}
+	*v.dst = n
+	return nil
 }
 
 func parseArgs(args []string) (cliConfig, []string, error) {
+	if posixMode(args) {
+		return parsePOSIXArgs(args)
+	}
 	var cfg cliConfig
+	var last byte
+	bsd := compatMode(args) == compatBSD
+	if bsd {
+		args, last = bsdArgs(args)
+	}
+	fs := countFlags(&cfg)
+	if err := fs.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+	if cfg.posix {
+		// --posix after an option posixMode stopped at: reject that
+		return parsePOSIXArgs(args)
+	}
+	if bsd {
+		bsdOptions(&cfg, last)
+	}
+	cfg, rem, err := checkArgs(cfg, fs.Args())
+	if err == nil && posixEnabled() {
+		posixlyCorrect(&cfg)
+	}
+	return cfg, rem, err
+}
+
+// checkArgs validates the options of cfg, parsed from the command line of
+// the count command or one built on it, and passes them and the remaining
+// arguments rem through.
+func checkArgs(cfg cliConfig, rem []string) (cliConfig, []string, error) {
+	if cfg.offset < 0 || cfg.length < 0 {
+		return cfg, nil, errors.New("--offset and --length must not be negative")
+	}
+	if err := checkConflicts(cfg); err != nil {
+		return cfg, nil, err
+	}
+	if cfg.region != "" {
+		start, end, err := parseRegion(cfg.region)
+		if err != nil {
+			return cfg, nil, err
+		}
+		cfg.offset = start
+		if end > 0 {
+			cfg.length = end - start
+		}
+	}
+	if cfg.estimate != "" {
+		if _, err := parseEstimate(cfg.estimate); err != nil {
+			return cfg, nil, err
+		}
+	}
+	if cfg.strip != "" {
+		if _, err := wc.ParseStrip(cfg.strip); err != nil {
+			return cfg, nil, fmt.Errorf("--strip: %v", err)
+		}
+	}
+	if len(cfg.extractWith) > 0 {
+		if _, err := parseExtractors(cfg.extractWith); err != nil {
+			return cfg, nil, err
+		}
+	}
+	switch cfg.halt {
+	case haltNever, haltSoon, haltNow:
+	default:
+		return cfg, nil, fmt.Errorf("invalid --halt value %q (want now, soon or never)", cfg.halt)
+	}
+	if cfg.ngrams != "" {
+		if _, _, err := parseNGrams(cfg.ngrams); err != nil {
+			return cfg, nil, err
+		}
+	}
+	if cfg.stem != "" {
+		if _, err := wc.ParseStemmer(cfg.stem); err != nil {
+			return cfg, nil, fmt.Errorf("--stem: %v", err)
+		}
+	}
+	switch cfg.format {
+	case "", "html", "json":
+	default:
+		if _, err := format.New(cfg.format); err != nil {
+			return cfg, nil, fmt.Errorf("invalid --format value %q (want %s)", cfg.format, strings.Join(formatNames(), ", "))
+		}
+	}
+	if cfg.columns != "" {
+		if _, _, err := parseColumns(cfg.columns); err != nil {
+			return cfg, nil, err
+		}
+		if cfg.format != "" && cfg.format != "text" {
+			return cfg, nil, fmt.Errorf("--columns only applies to the text format")
+		}
+	}
+	if cfg.withMeta && cfg.format != "json" && cfg.format != "csv" && !cfg.outputAppend {
+		return cfg, nil, fmt.Errorf("--with-metadata requires --format=json, --format=csv or --output-append")
+	}
+	switch cfg.groupBy {
+	case "", "ext", "dir":
+	default:
+		return cfg, nil, fmt.Errorf("invalid --group-by value %q (want ext or dir)", cfg.groupBy)
+	}
+	if _, err := parseLogLevel(cfg.logLevel); err != nil {
+		return cfg, nil, err
+	}
+	if cfg.outputAppend && cfg.output == "" {
+		return cfg, nil, fmt.Errorf("--output-append requires --output")
+	}
+	if cfg.bigTotals && cfg.format != "json" && cfg.format != "csv" {
+		return cfg, nil, errors.New("--big-totals requires --format=json or --format=csv")
+	}
+	if cfg.lineHist && cfg.format != "parquet" {
+		return cfg, nil, errors.New("--line-histogram requires --format=parquet")
+	}
+	if cfg.outputShards < 0 {
+		return cfg, nil, fmt.Errorf("invalid --output-shards value %d", cfg.outputShards)
+	}
+	if cfg.outputShards > 0 {
+		if cfg.output == "" {
+			return cfg, nil, errors.New("--output-shards requires --output")
+		}
+		if !slices.Contains(shardFormats, cfg.format) {
+			return cfg, nil, fmt.Errorf("--output-shards requires --format=%s", strings.Join(shardFormats, ", --format="))
+		}
+	}
+	if cfg.sandbox && (cfg.files0From == "-" || cfg.filesFrom == "-") {
+		// the sandboxed process reads the names again
+		return cfg, nil, fmt.Errorf("--sandbox cannot read --files0-from or --files-from from standard input")
+	}
+	if cfg.streaming {
+		if cfg.files0From == "" && cfg.filesFrom == "" {
+			return cfg, nil, errors.New("--streaming requires --files-from or --files0-from")
+		}
+		switch cfg.format {
+		case "", "text", "csv":
+		default:
+			return cfg, nil, fmt.Errorf("--streaming writes text or csv, not --format=%s", cfg.format)
+		}
+	}
+	if cfg.outputAppend && cfg.format != "" && cfg.format != "text" {
+		return cfg, nil, fmt.Errorf("--output-append writes JSON lines and cannot be combined with --format=%s", cfg.format)
+	}
+	switch cfg.ngramFormat {
+	case "", "csv", "json":
+	default:
+		return cfg, nil, fmt.Errorf("invalid --ngram-format value %q (want csv or json)", cfg.ngramFormat)
+	}
+	if cfg.scripts {
+		switch cfg.format {
+		case "", "csv", "json":
+		default:
+			return cfg, nil, fmt.Errorf("--scripts writes csv or json, not --format=%s", cfg.format)
+		}
+	}
+	if cfg.perLine {
+		switch cfg.format {
+		case "", "text", "csv", "json":
+		default:
+			return cfg, nil, fmt.Errorf("--per-line writes text, csv or json, not --format=%s", cfg.format)
+		}
+	}
+	if cfg.perRecord {
+		switch cfg.format {
+		case "", "text", "csv", "json":
+		default:
+			return cfg, nil, fmt.Errorf("--per-record writes text, csv or json, not --format=%s", cfg.format)
+		}
+		switch {
+		case cfg.records != "" && cfg.recordSep != "":
+			return cfg, nil, errors.New("--records and --record-separator cannot be used together")
+		case cfg.records == "" && cfg.recordSep == "":
+			return cfg, nil, errors.New("--per-record needs --records=csv|jsonl or --record-separator=SEP")
+		}
+	} else if cfg.records != "" || cfg.recordSep != "" {
+		return cfg, nil, errors.New("--records and --record-separator only apply to --per-record")
+	}
+	switch cfg.records {
+	case "", "csv", "jsonl":
+	default:
+		return cfg, nil, fmt.Errorf("invalid --records value %q (want csv or jsonl)", cfg.records)
+	}
+	if cfg.recordSep != "" {
+		if _, err := parseSeparator(cfg.recordSep); err != nil {
+			return cfg, nil, fmt.Errorf("--record-separator: %v", err)
+		}
+	}
+	if cfg.interval != "" {
+		if _, _, err := parseInterval(cfg.interval); err != nil {
+			return cfg, nil, err
+		}
+		if cfg.format != "" && cfg.format != "text" || cfg.columns != "" || cfg.perLine || cfg.ngrams != "" {
+			return cfg, nil, errors.New("--interval only applies to the text format, without --columns, --per-line or --ngrams")
+		}
+	}
+	if cfg.match != "" {
+		if _, err := regexp.Compile(cfg.match); err != nil {
+			return cfg, nil, fmt.Errorf("--match: %v", err)
+		}
+	}
+	if cfg.maxMatchLen < 0 {
+		return cfg, nil, fmt.Errorf("invalid --max-match-length value %d", cfg.maxMatchLen)
+	}
+	if cfg.minWordLen < 0 {
+		return cfg, nil, fmt.Errorf("invalid --min-word-length value %d", cfg.minWordLen)
+	}
+	if cfg.hyphen != "" {
+		if _, err := wc.ParseHyphen(cfg.hyphen); err != nil {
+			return cfg, nil, fmt.Errorf("--hyphen: %v", err)
+		}
+	}
+	if cfg.apostrophe != "" {
+		if _, err := wc.ParseApostrophe(cfg.apostrophe); err != nil {
+			return cfg, nil, fmt.Errorf("--apostrophe: %v", err)
+		}
+	}
+	switch cfg.compat {
+	case "", compatBSD:
+	default:
+		return cfg, nil, fmt.Errorf("invalid --compat value %q (want bsd)", cfg.compat)
+	}
+	switch cfg.sbChars {
+	case "", "charmap", "bytes":
+	default:
+		return cfg, nil, fmt.Errorf("invalid --single-byte-chars value %q (want charmap or bytes)", cfg.sbChars)
+	}
+	switch cfg.devices {
+	case "", devicesRead, devicesSkip:
+	default:
+		return cfg, nil, fmt.Errorf("invalid --devices value %q (want read or skip)", cfg.devices)
+	}
+	switch cfg.uniqueWords {
+	case "", "exact", "approx":
+	default:
+		return cfg, nil, fmt.Errorf("invalid --unique-words value %q (want exact or approx)", cfg.uniqueWords)
+	}
+	return cfg, rem, nil
+}
+
+// countFlags returns the flags of "go_wc count", which set cfg.
+func countFlags(cfg *cliConfig) *flag.FlagSet {
 	fs := flag.NewFlagSet("go_wc", flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
 
@@ -58,25 +460,112 @@ func parseArgs(args []string) (cliConfig, []string, error) {
 	fs.BoolVar(&cfg.countMaxBytes, "L", false, "")
 	fs.BoolVar(&cfg.countMaxBytes, "max-line-length", false, "")
 	fs.BoolVar(&cfg.countMaxChars, "max-line-length-chars", false, "")
+	fs.BoolVar(&cfg.countWSLines, "whitespace-lines", false, "")
+	fs.BoolVar(&cfg.countEndings, "line-ending-stats", false, "")
+	fs.BoolVar(&cfg.countWordLens, "word-lengths", false, "")
+	fs.BoolVar(&cfg.countWPL, "words-per-line", false, "")
+	fs.BoolVar(&cfg.countEmoji, "emoji", false, "")
+	fs.BoolVar(&cfg.tokenStats, "token-stats", false, "")
+	fs.BoolVar(&cfg.wordKinds, "word-kinds", false, "")
+	fs.BoolVar(&cfg.codeTokens, "code-tokens", false, "")
+	fs.StringVar(&cfg.match, "match", "", "")
+	fs.StringVar(&cfg.patternsFrom, "patterns-from", "", "")
+	fs.IntVar(&cfg.maxMatchLen, "max-match-length", 0, "")
+	fs.BoolVar(&cfg.countLongest, "longest-word", false, "")
+	fs.Var(optionalValue{dst: &cfg.uniqueWords, bare: "exact"}, "unique-words", "")
+	fs.BoolVar(&cfg.foldCase, "fold-case", false, "")
+	fs.StringVar(&cfg.stem, "stem", "", "")
+	fs.StringVar(&cfg.stopwords, "stopwords", "", "")
+	fs.IntVar(&cfg.minWordLen, "min-word-length", 0, "")
+	fs.StringVar(&cfg.hyphen, "hyphen", "", "")
+	fs.StringVar(&cfg.apostrophe, "apostrophe", "", "")
 
 	fs.StringVar(&cfg.files0From, "files0-from", "", "")
+	fs.StringVar(&cfg.filesFrom, "files-from", "", "")
+	fs.BoolVar(&cfg.git, "git", false, "")
+	fs.BoolVar(&cfg.streaming, "streaming", false, "")
 	fs.StringVar(&cfg.encoding, "encoding", "", "")
-	fs.IntVar(&cfg.jobs, "jobs", runtime.GOMAXPROCS(0), "")
-	fs.IntVar(&cfg.jobs, "j", runtime.GOMAXPROCS(0), "")
+	fs.StringVar(&cfg.sbChars, "single-byte-chars", "", "")
+	cfg.jobs = runtime.GOMAXPROCS(0)
+	fs.Var(jobsValue{&cfg.jobs, &cfg.autoJobs}, "jobs", "")
+	fs.Var(jobsValue{&cfg.jobs, &cfg.autoJobs}, "j", "")
 	fs.IntVar(&cfg.bufSize, "buffer-size", 1*1024*1024, "")
 	fs.BoolVar(&cfg.showHelp, "help", false, "")
 	fs.BoolVar(&cfg.showVer, "version", false, "")
-
-	if err := fs.Parse(args); err != nil {
-		return cfg, nil, err
-	}
-	rem := fs.Args()
-	return cfg, rem, nil
+	fs.BoolVar(&cfg.schema, "schema", false, "")
+	fs.BoolVar(&cfg.remote, "remote", false, "")
+	fs.StringVar(&cfg.socket, "socket", defaultSocketPath, "")
+	fs.BoolVar(&cfg.stdioRPC, "stdio-rpc", false, "")
+	fs.DurationVar(&cfg.fileTimeout, "file-timeout", 0, "")
+	fs.BoolVar(&cfg.listOnly, "list-only", false, "")
+	fs.BoolVar(&cfg.print0, "print0", false, "")
+	fs.Var(optionalValue{dst: &cfg.stats, bare: "text"}, "stats", "")
+	fs.BoolVar(&cfg.profile, "profile-summary", false, "")
+	fs.BoolVar(&cfg.sandbox, "sandbox", false, "")
+	fs.BoolVar(&cfg.header, "header", false, "")
+	fs.IntVar(&cfg.width, "width", 0, "")
+	fs.IntVar(&cfg.minWidth, "min-width", 0, "")
+	fs.BoolVar(&cfg.noAlign, "no-align", false, "")
+	fs.BoolVar(&cfg.posix, "posix", false, "")
+	fs.StringVar(&cfg.compat, "compat", "", "")
+	fs.BoolVar(&cfg.basename, "basename", false, "")
+	fs.StringVar(&cfg.relativeTo, "relative-to", "", "")
+	fs.StringVar(&cfg.halt, "halt", haltNever, "")
+	fs.BoolVar(&cfg.inputOrder, "input-order", false, "")
+	fs.Var(stringList{&cfg.countChar}, "count-char", "")
+	fs.Var(stringList{&cfg.countString}, "count-string", "")
+	fs.BoolVar(&cfg.invisibles, "count-invisibles", false, "")
+	fs.BoolVar(&cfg.showNoEOL, "missing-final-newline", false, "")
+	fs.BoolVar(&cfg.requireEOL, "require-final-newline", false, "")
+	fs.Int64Var(&cfg.offset, "offset", 0, "")
+	fs.Int64Var(&cfg.length, "length", 0, "")
+	fs.StringVar(&cfg.region, "region", "", "")
+	fs.StringVar(&cfg.strip, "strip", "", "")
+	fs.Var(stringList{&cfg.extractWith}, "extract-with", "")
+	fs.Uint64Var(&cfg.maxLines, "max-lines", 0, "")
+	fs.Uint64Var(&cfg.maxBytes, "max-bytes", 0, "")
+	fs.Var(sizeValue{&cfg.abortLine}, "abort-if-line-exceeds", "")
+	fs.Var(sizeValue{&cfg.abortWord}, "abort-if-word-exceeds", "")
+	fs.Var(optionalValue{dst: &cfg.estimate, bare: "1"}, "estimate", "")
+	fs.StringVar(&cfg.ngrams, "ngrams", "", "")
+	fs.StringVar(&cfg.ngramFormat, "ngram-format", "", "")
+	fs.BoolVar(&cfg.perLine, "per-line", false, "")
+	fs.BoolVar(&cfg.perRecord, "per-record", false, "")
+	fs.StringVar(&cfg.records, "records", "", "")
+	fs.StringVar(&cfg.recordSep, "record-separator", "", "")
+	fs.BoolVar(&cfg.scripts, "scripts", false, "")
+	fs.StringVar(&cfg.interval, "interval", "", "")
+	fs.BoolVar(&cfg.status, "status", false, "")
+	fs.StringVar(&cfg.devices, "devices", "", "")
+	fs.BoolVar(&cfg.noStatOpt, "no-stat-optimizations", false, "")
+	fs.StringVar(&cfg.reportDir, "report-dir", "", "")
+	fs.StringVar(&cfg.format, "format", "", "")
+	fs.StringVar(&cfg.groupBy, "group-by", "", "")
+	fs.StringVar(&cfg.output, "output", "", "")
+	fs.BoolVar(&cfg.outputAppend, "output-append", false, "")
+	fs.IntVar(&cfg.outputShards, "output-shards", 0, "")
+	fs.BoolVar(&cfg.bigTotals, "big-totals", false, "")
+	fs.BoolVar(&cfg.lineHist, "line-histogram", false, "")
+	fs.StringVar(&cfg.logLevel, "log-level", "", "")
+	fs.BoolVar(&cfg.logJSON, "log-json", false, "")
+	fs.BoolVar(&cfg.withMeta, "with-metadata", false, "")
+	fs.StringVar(&cfg.columns, "columns", "", "")
+	fs.BoolVar(&cfg.truncate, "truncate", false, "")
+	fs.BoolVar(&cfg.wrap, "wrap", false, "")
+	fs.StringVar(&cfg.outSQLite, "output-sqlite", "", "")
+	fs.StringVar(&cfg.checkpoint, "checkpoint", "", "")
+	fs.StringVar(&cfg.resume, "resume", "", "")
+	return fs
 }
 
 func usage() {
 	fmt.Println("go_wc - compatible and fast wc implementation in pure Go")
 	fmt.Println("Usage: go_wc [OPTIONS] [FILE...]")
+	for _, c := range commands {
+		name := strings.Join(append([]string{c.name}, c.aliases...), "|")
+		fmt.Printf("       go_wc %s %s\n", name, c.args)
+	}
+	fmt.Println("A first FILE named like a command is counted when it exists, as wc would.")
 	fmt.Println("Options:")
 	fmt.Println("  -c, --bytes                 print the byte counts")
 	fmt.Println("  -m, --chars                 print the character counts")
@@ -84,136 +573,1034 @@ func usage() {
 	fmt.Println("  -w, --words                 print the word counts")
 	fmt.Println("  -L, --max-line-length       print the maximum line length in bytes")
 	fmt.Println("      --max-line-length-chars print the maximum line length in characters")
+	fmt.Println("      --whitespace-lines      print the number of lines containing only whitespace")
+	fmt.Println("      --line-ending-stats     print the number of LF, CRLF and lone CR line terminators")
+	fmt.Println("      --word-lengths          print the longest word length and the average word length")
+	fmt.Println("      --words-per-line        print the fewest, average and most words on a line")
+	fmt.Println("      --emoji                 print the number of emoji; a ZWJ sequence, flag or keycap is one")
+	fmt.Println("      --token-stats           print the number of words that are numbers, URLs and emails")
+	fmt.Println("      --word-kinds            print the number of words that are numbers, alphanumeric")
+	fmt.Println("                              identifiers and alphabetic words")
+	fmt.Println("      --code-tokens           lex the files as source code by extension and print their")
+	fmt.Println("                              identifiers, literals, operators and tokens per line")
+	fmt.Println("      --match=REGEX           also print the number of lines REGEX matches and does not match")
+	fmt.Println("      --longest-word          print the longest word itself, after the other counts")
+	fmt.Println("      --unique-words[=MODE]   print the number of distinct words; MODE approx estimates it")
+	fmt.Println("                              in bounded memory (default exact)")
+	fmt.Println("      --fold-case             ignore case when comparing words for --unique-words and --ngrams")
+	fmt.Println("      --stem=porter           reduce words to their English stems for --unique-words and --ngrams")
+	fmt.Println("      --stopwords=LIST        leave the words in LIST out of --unique-words and --ngrams; LIST")
+	fmt.Println("                              is a file of words or builtin:LANG (en, de, fr, es)")
+	fmt.Println("      --min-word-length=N     leave words of fewer than N characters out of the word counts,")
+	fmt.Println("                              --unique-words and --ngrams")
+	fmt.Println("      --hyphen=join           also count a word hyphenated at a line break as one word")
+	fmt.Println("      --hyphen=split          count the parts of hyphenated words as words of their own")
+	fmt.Println("      --apostrophe=internal   keep apostrophes in words only between their letters, so")
+	fmt.Println("                              quoting ones and lone ones belong to no word")
+	fmt.Println("      --apostrophe=split      end a word at every apostrophe: \"don't\" is two words")
+	fmt.Println("      --ngrams=N[,K]          instead of counts, print the K (default 10) most frequent")
+	fmt.Println("                              sequences of N words per file and in total")
+	fmt.Println("      --ngram-format=FMT      print n-grams as csv (default) or json")
+	fmt.Println("      --scripts               instead of counts, print the characters of each Unicode script")
+	fmt.Println("                              (Latin, Cyrillic, Han, ...) per file, as csv or json")
+	fmt.Println("      --per-line              instead of counts, print the line number, bytes, chars and")
+	fmt.Println("                              words of every line as it is read (text, csv or json format)")
+	fmt.Println("      --per-record            like --per-line, for the records --records or --record-separator")
+	fmt.Println("                              cut the input into")
+	fmt.Println("      --records=MODE          records are csv rows (quoted newlines kept) or jsonl lines")
+	fmt.Println("                              (blank ones skipped)")
+	fmt.Println("      --record-separator=SEP  records end at SEP, a string with escapes like \\0 or \\x1e")
+	fmt.Println("      --interval=EVERY        while counting standard input, print the running totals every")
+	fmt.Println("                              EVERY of time (10s) or input (64MB, 1GiB)")
+	fmt.Println("      --status                on a terminal, show bytes read, lines and throughput on an")
+	fmt.Println("                              updating stderr line while counting")
+	fmt.Println("      --report-dir=DIR        write the --ngrams and --stats reports to files in DIR")
+	fmt.Println("                              (ngrams.csv, stats.txt, ...) and print the counts as usual")
+	fmt.Println("      --missing-final-newline print 1 for files whose last line has no newline, else 0, and")
+	fmt.Println("                              in the total the number of such files")
+	fmt.Println("      --require-final-newline report files whose last line has no newline and exit 1")
+	fmt.Println("      --count-char=CHAR       also count occurrences of CHAR (repeatable); CHAR is a")
+	fmt.Println("                              character, an escape like \\t or \\0, or a class like [:digit:]")
+	fmt.Println("      --count-string=STR      also count non-overlapping occurrences of STR (repeatable)")
+	fmt.Println("      --patterns-from=FILE    also count the occurrences of every pattern in FILE, one per")
+	fmt.Println("                              line: a fixed string, or re:REGEX for matches within lines")
+	fmt.Println("      --max-match-length=N    longest --match or re: match sure to be found in lines")
+	fmt.Println("                              longer than 2*N bytes, which are scanned in windows (65536)")
+	fmt.Println("      --count-invisibles      also count zero-width characters, mid-file BOMs, bidi controls")
+	fmt.Println("                              and other invisible characters")
 	fmt.Println("      --files0-from=FILE      read input file names from FILE, separated by NULs; - means standard input")
+	fmt.Println("      --files-from=FILE       read input file names from FILE, one per line; - means standard input")
+	fmt.Println("      --git                   count the files the git repository tracks, below the current")
+	fmt.Println("                              directory or matching the FILE operands, taken as pathspecs")
+	fmt.Println("      --streaming             count the names of --files-from or --files0-from as they arrive and")
+	fmt.Println("                              print each file's counts as soon as those before it are printed,")
+	fmt.Println("                              in text (aligned to 7 columns unless --width says otherwise) or csv")
+	fmt.Println("      --devices=ACTION        read (default) or skip FIFOs, devices and sockets among the files")
+	fmt.Println("      --no-stat-optimizations never act on file sizes from stat: no largest-first")
+	fmt.Println("                              order or small-file batching")
 	fmt.Println("      --encoding=NAME         override detected locale encoding (e.g., utf-8)")
-	fmt.Println("  -j, --jobs N                process up to N files concurrently (default: GOMAXPROCS)")
+	fmt.Println("      --single-byte-chars=MODE  in single-byte encodings such as ISO-8859-1, decode characters")
+	fmt.Println("                              with the charmap (default) or count bytes as glibc wc does")
+	fmt.Println("  -j, --jobs N                process up to N files concurrently (default: GOMAXPROCS);")
+	fmt.Println("                              auto picks per device: 1 on spinning disks, 8 on network")
+	fmt.Println("                              filesystems, GOMAXPROCS on SSDs")
 	fmt.Println("      --buffer-size BYTES     set I/O buffer size (default: 1MiB)")
+	fmt.Println("      --file-timeout DURATION give up on a single file after DURATION (e.g. 10s)")
+	fmt.Println("      --offset=N              skip the first N bytes of each input (seeking when possible)")
+	fmt.Println("      --length=M              count at most M bytes of each input, starting at --offset")
+	fmt.Println("      --region=START:END      count only bytes START to END (exclusive) of each input, as an")
+	fmt.Println("                              editor selection; END may be left out to count to the end")
+	fmt.Println("      --strip=markdown        count only the prose of Markdown inputs, leaving out front")
+	fmt.Println("                              matter, code blocks, link URLs and markup")
+	fmt.Println("      --strip=html            count only the text of HTML or XML inputs, leaving out tags,")
+	fmt.Println("                              attributes, comments, scripts and styles")
+	fmt.Println("      --extract-with=[GLOB=]CMD  count what CMD prints for each file (matching GLOB) instead of")
+	fmt.Println("                              the file, e.g. '*.pdf=pdftotext {} -'; {} is the file name, or")
+	fmt.Println("                              without it the file is CMD's input. May be repeated")
+	fmt.Println("      --max-lines=N           stop reading each input after N lines; partial counts are")
+	fmt.Println("                              marked (truncated) when the input goes on")
+	fmt.Println("      --max-bytes=N           stop reading each input after N bytes, likewise")
+	fmt.Println("      --abort-if-line-exceeds=SIZE stop counting an input at a line longer than SIZE")
+	fmt.Println("                              (bytes, or e.g. 64KiB) and report it as failed")
+	fmt.Println("      --abort-if-word-exceeds=SIZE likewise, at a run of SIZE bytes without white space")
+	fmt.Println("      --estimate[=PCT]        sample about PCT% (default 1) of each large file and")
+	fmt.Println("                              extrapolate, printing 95% confidence intervals")
+	fmt.Println("      --halt=WHEN             on the first error: never (default) keep going, soon stop")
+	fmt.Println("                              scheduling new files, now also abandon files in progress")
+	fmt.Println("      --input-order           start files in the order given instead of largest first")
+	fmt.Println("      --checkpoint=FILE       record the result of every counted file in FILE as the run")
+	fmt.Println("                              goes, for --resume")
+	fmt.Println("      --resume=FILE           reuse the results a --checkpoint FILE recorded for files that")
+	fmt.Println("                              have not changed since, counting only the rest")
+	fmt.Println("      --header                print a column header line before the counts")
+	fmt.Println("      --truncate              on a terminal, shorten long file names with … to fit")
+	fmt.Println("      --wrap                  on a terminal, wrap long file names onto indented lines")
+	fmt.Println("      --columns=LIST          print these columns in this order: metric names (lines,")
+	fmt.Println("                              words, bytes, max-line-bytes, ...), size and mtime from")
+	fmt.Println("                              stat, and filename")
+	fmt.Println("      --format=FMT            print the counts as text (default), a markdown table or")
+	fmt.Println("                              a standalone html page, or a parquet file of the files'")
+	fmt.Println("                              counts (best with --output); json and csv print one")
+	fmt.Println("                              record per file and the total")
+	fmt.Println("      --schema                print the JSON Schema of json and --output-append records")
+	fmt.Println("      --with-metadata         add each file's size, mtime, mode, inode and device, as")
+	fmt.Println("                              stat saw them before counting, to json, csv and")
+	fmt.Println("                              --output-append records")
+	fmt.Println("      --group-by=KEY          with --format=markdown, one table per file extension (ext)")
+	fmt.Println("                              or directory (dir), each with its own total; with html,")
+	fmt.Println("                              the key of the group table (default ext)")
+	fmt.Println("      --output=FILE           write the counts to FILE instead of standard output")
+	fmt.Println("      --output-append         append one JSON line per file to the --output FILE, with")
+	fmt.Println("                              the run time and host name, to build an audit log")
+	fmt.Println("      --output-shards=N       split the json, csv or parquet records across N files named")
+	fmt.Println("                              after --output FILE.EXT (FILE-00000-of-0000N.EXT), and write")
+	fmt.Println("                              the total and the list of shards to FILE.manifest.json,")
+	fmt.Println("                              without the extension: out.json gives out.manifest.json")
+	fmt.Println("      --big-totals            print json and csv totals too large for 64 bits exactly, as")
+	fmt.Println("                              longer integers, instead of failing")
+	fmt.Println("      --line-histogram        add a line_lengths column to parquet output: the number")
+	fmt.Println("                              of empty lines, then of lines of 1, 2-3, 4-7, ... bytes")
+	fmt.Println("      --output-sqlite=FILE    also record one row per file in the SQLite database FILE,")
+	fmt.Println("                              tagged with a run id and time, in one transaction; needs the")
+	fmt.Println("                              sqlite3 command on PATH, and fails before counting without it")
+	fmt.Println("      --width N               use exactly N columns per count")
+	fmt.Println("      --min-width N           pad counts to at least N columns")
+	fmt.Println("      --no-align              separate counts by single spaces, without padding")
+	fmt.Println("      --basename              print only the last path element of each file name")
+	fmt.Println("      --relative-to=DIR       print file names relative to DIR")
+	fmt.Println("      --list-only             print the files that would be counted and exit")
+	fmt.Println("      --print0                separate --list-only output with NULs instead of newlines")
+	fmt.Println("      --stats[=json]          report run statistics (wall time, throughput, failures) on stderr")
+	fmt.Println("      --profile-summary       report on stderr where the run spent its time (discovery, I/O")
+	fmt.Println("                              wait, decode, word-scan, formatting) and its peak memory")
+	fmt.Println("      --sandbox               on Linux, count with read-only access to the inputs only,")
+	fmt.Println("                              using Landlock and seccomp")
+	fmt.Println("      --log-level=LEVEL       log diagnostics on stderr: debug (scheduling, every file),")
+	fmt.Println("                              info (halts, time-outs) or warn (default)")
+	fmt.Println("      --log-json              log JSON records, failed files included")
+	fmt.Println("      --remote                submit files to a running go_wc daemon")
+	fmt.Println("      --socket PATH           daemon socket path (default: $XDG_RUNTIME_DIR/go_wc.sock,")
+	fmt.Println("                              else $TMPDIR/go_wc-UID/go_wc.sock)")
+	fmt.Println("      --stdio-rpc             serve JSON-RPC (countText, countFile, cancel) on stdin/stdout")
+	fmt.Println("      --posix                 behave exactly as POSIX specifies wc, for portable scripts:")
+	fmt.Println("                              only -c, -l, -m and -w, single-space output; the output")
+	fmt.Println("                              alone when POSIXLY_CORRECT is set")
+	fmt.Println("      --compat=bsd            behave like the wc of macOS and FreeBSD: counts in 8 columns,")
+	fmt.Println("                              the last of -c and -m wins, -L in characters with -m,")
+	fmt.Println("                              grouped options such as -lc")
 	fmt.Println("      --help                  display this help and exit")
 	fmt.Println("      --version               output version information and exit")
 }
 
 func main() {
-	cfg, files, err := parseArgs(os.Args[1:])
+	os.Exit(run(os.Args[1:]))
+}
+
+// runCount implements "go_wc count", the default command, and returns the
+// process exit code.
+func runCount(args []string) int {
+	return countWith(parseArgs(args))
+}
+
+// countWith runs the count command with the options and file arguments
+// parsed from its command line, or reports err.
+func countWith(cfg cliConfig, files []string, err error) int {
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
+		if cfg.posix {
+			fmt.Fprintln(os.Stderr, posixUsage)
+			return 1
+		}
 		usage()
-		os.Exit(1)
+		return 1
 	}
 	if cfg.showHelp {
 		usage()
-		return
+		return 0
 	}
+	_ = setupLogging(cfg.logLevel, cfg.logJSON) // validated by parseArgs
 	if cfg.showVer {
 		fmt.Printf("go_wc version %s\n", version)
 		fmt.Printf("  commit: %s\n", commit)
 		fmt.Printf("  built: %s\n", buildTime)
 		fmt.Printf("  go: %s\n", goVersion)
-		return
+		return 0
+	}
+	if cfg.schema {
+		fmt.Print(jsonSchema)
+		return 0
+	}
+	if cfg.stdioRPC {
+		return runStdioRPC(os.Stdin, os.Stdout, wc.Options{BufferSize: cfg.bufSize, AbortLineBytes: cfg.abortLine, AbortWordBytes: cfg.abortWord}, cfg.encoding)
+	}
+
+	metrics := wc.Metrics{
+		Bytes:        cfg.countBytes,
+		Chars:        cfg.countChars,
+		Lines:        cfg.countLines,
+		Words:        cfg.countWords,
+		MaxLineBytes: cfg.countMaxBytes,
+		MaxLineChars: cfg.countMaxChars,
+
+		WhitespaceLines: cfg.countWSLines,
+		LineEndings:     cfg.countEndings,
+		WordLengths:     cfg.countWordLens,
+		WordsPerLine:    cfg.countWPL,
+		Emoji:           cfg.countEmoji,
+		TokenStats:      cfg.tokenStats,
+		WordKinds:       cfg.wordKinds,
+		CodeTokens:      cfg.codeTokens,
+		LongestWord:     cfg.countLongest,
+		UniqueWords:     cfg.uniqueWords != "",
+	}
+	var columns []tableColumn
+	if cfg.columns != "" {
+		columns, metrics, _ = parseColumns(cfg.columns)
+	}
+	if metrics.IsZero() {
+		metrics = wc.DefaultMetrics()
+	}
+	metrics.NoFinalNewline = cfg.showNoEOL // an extra column, not a selection
+	metrics.MatchLines = cfg.match != ""    // likewise
+	metrics.LineLengths = cfg.lineHist
+
+	var prof *runProfile
+	if cfg.profile {
+		prof = &runProfile{start: time.Now()}
+	}
+	inputs, err := collectInputs(cfg, files)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	cfg.unnamed = (cfg.posix || cfg.compat == compatBSD) && len(files) == 0 && len(inputs) == 1 && inputs[0] == "-"
+	if cfg.devices == devicesSkip {
+		n := len(inputs)
+		inputs = skipDevices(inputs)
+		cfg.skipped = n - len(inputs)
+	}
+	if cfg.listOnly {
+		return listInputs(os.Stdout, inputs, cfg.print0)
+	}
+	if cfg.interval != "" && (len(inputs) != 1 || inputs[0] != "-") {
+		fmt.Fprintln(os.Stderr, "go_wc: --interval only applies when counting standard input alone")
+		return 1
+	}
+	var meta map[string]*fileMeta
+	if cfg.withMeta || needsStat(columns) {
+		meta = statInputs(inputs)
+	}
+	if prof != nil {
+		prof.discovery = time.Since(prof.start)
+	}
+
+	loc := locale.Detect(cfg.encoding)
+	if cfg.sbChars == "bytes" {
+		loc = loc.ByteChars()
+	}
+
+	var classes []wc.CharClass
+	for _, spec := range cfg.countChar {
+		cl, err := wc.ParseCharClass(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "go_wc: --count-char: %v\n", err)
+			return 1
+		}
+		classes = append(classes, cl)
+	}
+	if cfg.invisibles {
+		classes = append(classes, wc.InvisibleClasses()...)
+	}
+	for _, s := range cfg.countString {
+		if s == "" {
+			fmt.Fprintln(os.Stderr, "go_wc: --count-string: empty string")
+			return 1
+		}
+	}
+	var patterns patternSet
+	if cfg.patternsFrom != "" {
+		if patterns, err = loadPatterns(cfg.patternsFrom); err != nil {
+			fmt.Fprintf(os.Stderr, "go_wc: --patterns-from: %v\n", err)
+			return 1
+		}
+	}
+	if cfg.remote && (len(classes)+len(cfg.countString) > 0 || cfg.patternsFrom != "") {
+		fmt.Fprintln(os.Stderr, "go_wc: --count-char, --count-string, --count-invisibles and --patterns-from are not supported with --remote")
+		return 1
+	}
+	if cfg.remote && (metrics.UniqueWords || cfg.ngrams != "") {
+		// totals need the word sets, which the daemon does not return
+		fmt.Fprintln(os.Stderr, "go_wc: --unique-words and --ngrams are not supported with --remote")
+		return 1
+	}
+	if cfg.ngrams != "" && cfg.estimate != "" {
+		fmt.Fprintln(os.Stderr, "go_wc: --ngrams cannot be combined with --estimate")
+		return 1
+	}
+	if cfg.remote && (cfg.offset > 0 || cfg.length > 0 || cfg.maxLines > 0 || cfg.maxBytes > 0 || cfg.estimate != "" || cfg.strip != "") {
+		fmt.Fprintln(os.Stderr, "go_wc: --offset, --length, --max-lines, --max-bytes, --estimate and --strip are not supported with --remote")
+		return 1
+	}
+
+	opts := wc.Options{
+		BufferSize:     cfg.bufSize,
+		Locale:         loc,
+		CountChars:     classes,
+		CountStrings:   append(slices.Clip(cfg.countString), patterns.strings...),
+		CountRegexps:   patterns.regexps,
+		MaxMatchLength: cfg.maxMatchLen,
+		Offset:         cfg.offset,
+		Length:         cfg.length,
+
+		StopAfterLines: cfg.maxLines,
+		StopAfterBytes: cfg.maxBytes,
+		AbortLineBytes: cfg.abortLine,
+		AbortWordBytes: cfg.abortWord,
+		UniqueFold:     cfg.foldCase,
+		UniqueApprox:   cfg.uniqueWords == "approx",
+		MinWordLength:  cfg.minWordLen,
+		Scripts:        cfg.scripts,
+		POSIXSpace:     cfg.posix,
+	}
+	if cfg.match != "" {
+		opts.MatchLines = regexp.MustCompile(cfg.match)
+	}
+	var topNGrams int
+	if cfg.ngrams != "" {
+		opts.NGrams, topNGrams, _ = parseNGrams(cfg.ngrams)
+	}
+	if cfg.stem != "" {
+		opts.Stem, _ = wc.ParseStemmer(cfg.stem)
+	}
+	if cfg.strip != "" {
+		opts.Strip, _ = wc.ParseStrip(cfg.strip)
+	}
+	if cfg.hyphen != "" {
+		opts.Hyphen, _ = wc.ParseHyphen(cfg.hyphen)
+	}
+	if cfg.apostrophe != "" {
+		opts.Apostrophe, _ = wc.ParseApostrophe(cfg.apostrophe)
+	}
+	if cfg.stopwords != "" {
+		opts.StopWords, err = loadStopWords(cfg.stopwords)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "go_wc: --stopwords: %v\n", err)
+			return 1
+		}
+	}
+	var sqlite3 string
+	if cfg.outSQLite != "" {
+		if sqlite3, err = findSQLite(); err != nil {
+			fmt.Fprintf(os.Stderr, "go_wc: --output-sqlite: %v\n", err)
+			return 1
+		}
+	}
+	if prof != nil {
+		opts.Profile = &prof.Profile
+		prof.remote = cfg.remote
+	}
+	if cfg.sandbox {
+		if err := enterSandbox(cfg, inputs); err != nil {
+			fmt.Fprintf(os.Stderr, "go_wc: --sandbox: %v\n", err)
+			return 1
+		}
+	}
+
+	var extra []string
+	for _, cl := range classes {
+		extra = append(extra, cl.Name)
+	}
+	extra = append(extra, cfg.countString...)
+	extra = append(extra, patterns.labels()...)
+	if cfg.perLine || cfg.perRecord {
+		return runPerLine(cfg, inputs, metrics, opts, prof)
+	}
+	if cfg.streaming {
+		return runStreaming(cfg, inputs, metrics, extra, opts, prof)
+	}
+
+	var status *statusLine
+	if cfg.status && terminalWidth(os.Stderr) > 0 {
+		opts.Progress = &wc.Progress{}
+		status = startStatus(os.Stderr, opts.Progress)
 	}
 
-	metrics := wc.Metrics{}
-	if !(cfg.countBytes || cfg.countChars || cfg.countLines || cfg.countWords || cfg.countMaxBytes || cfg.countMaxChars) {
-		// default: lines, words, bytes
-		metrics.Lines = true
-		metrics.Words = true
-		metrics.Bytes = true
+	var all []wc.FileResult
+	var estimates *estimateLog
+	runStart := time.Now()
+	workers := cfg.jobs
+	cacheHits := 0
+	skipped := cfg.skipped
+	checkpointFailed := false
+	if cfg.remote {
+		all, cacheHits, err = countRemote(cfg.socket, inputs, metrics, cfg.encoding)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "go_wc: %v\n", err)
+			return 1
+		}
+		logger.Debug("counted remotely", "socket", cfg.socket, "files", len(inputs), "cache_hits", cacheHits)
+		workers = 1
+	} else if cfg.interval != "" {
+		all = []wc.FileResult{countInterval(cfg, inputs, metrics, opts)}
+		workers = 1
+	} else if cfg.listen != "" {
+		var ln net.Listener
+		if ln, err = net.Listen("tcp", cfg.listen); err == nil {
+			all, workers, err = coordinate(ln, inputs, newCountSpec(cfg, metrics, loc, opts), opts, cfg.chunkSize)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "go_wc: %v\n", err)
+			return 1
+		}
 	} else {
-		metrics.Bytes = cfg.countBytes
-		metrics.Chars = cfg.countChars
-		metrics.Lines = cfg.countLines
-		metrics.Words = cfg.countWords
-		metrics.MaxLineBytes = cfg.countMaxBytes
-		metrics.MaxLineChars = cfg.countMaxChars
+		cs := countSettings{metrics: metrics, opts: opts, fileTimeout: cfg.fileTimeout, halt: cfg.halt, inputOrder: cfg.inputOrder, noStatSizes: cfg.noStatOpt}
+		cs.extract, _ = parseExtractors(cfg.extractWith)
+		if cfg.estimate != "" {
+			cs.estimate, _ = parseEstimate(cfg.estimate)
+			cs.estimates = newEstimateLog()
+			estimates = cs.estimates
+		}
+		if cfg.autoJobs {
+			cs.storage = planStorage(inputs)
+		}
+		if cfg.checkpoint != "" || cfg.resume != "" {
+			if cs.checkpoint, err = openCheckpoint(cfg, newCountSpec(cfg, metrics, loc, opts), len(opts.CountChars), len(opts.CountStrings)); err != nil {
+				fmt.Fprintf(os.Stderr, "go_wc: %v\n", err)
+				return 1
+			}
+		}
+		all = countInputs(inputs, cs, cfg.jobs)
+		skipped += len(inputs) - len(all) // after a --halt
+		if cacheHits, err = cs.checkpoint.close(); err != nil {
+			fmt.Fprintf(os.Stderr, "go_wc: %v\n", err)
+			checkpointFailed = true
+		}
+		if cs.storage != nil {
+			workers = workerCount(cs.storage.lanes(identityOrder(len(inputs))))
+		}
+	}
+	if status != nil {
+		status.clear()
+	}
+	if prof != nil {
+		prof.counted = time.Now()
+	}
+	var exitCode int
+	if checkpointFailed {
+		exitCode = 1
+	}
+	for _, r := range all {
+		if r.Err != nil {
+			exitCode = 1
+		}
+	}
+	if cfg.requireEOL {
+		for _, r := range all {
+			if r.Err == nil && r.NoFinalNewline {
+				fmt.Fprintf(os.Stderr, "go_wc: %s: no newline at end of file\n", r.Filename)
+				exitCode = 1
+			}
+		}
+	}
+
+	// Compute totals and formatting
+	multiple := len(inputs) > 1
+	var tot wc.Totals
+	for _, r := range all {
+		tot.Add(r)
+	}
+	totals := tot.Result()
+	var exact map[string]*big.Int
+	if cfg.bigTotals {
+		exact = tot.Exact()
+	}
+	if totals.Overflow && len(exact) == 0 {
+		reportOverflow()
+		exitCode = 1
 	}
 
-	// Build file list possibly augmented by --files0-from
+	name := func(s string) string { return displayName(cfg, s) }
+	ngramReport := func(w io.Writer) error {
+		return writeNGrams(w, all, totals, multiple, topNGrams, cfg.ngramFormat, name)
+	}
+	if cfg.ngrams != "" && cfg.reportDir == "" {
+		// the n-gram report replaces the counts
+		reportFailures(all)
+		bw := newBufferedOutput(os.Stdout)
+		err := ngramReport(bw)
+		if ferr := bw.Flush(); err == nil {
+			err = ferr
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "go_wc: %v\n", err)
+			exitCode = 1
+		}
+		return finishRun(cfg, all, runStart, workers, cacheHits, skipped, exitCode, prof)
+	}
+
+	if cfg.scripts {
+		// the script report replaces the counts
+		reportFailures(all)
+		bw := newBufferedOutput(os.Stdout)
+		err := writeScripts(bw, all, totals, multiple, cfg.format, name)
+		if ferr := bw.Flush(); err == nil {
+			err = ferr
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "go_wc: %v\n", err)
+			exitCode = 1
+		}
+		return finishRun(cfg, all, runStart, workers, cacheHits, skipped, exitCode, prof)
+	}
+	outFile := os.Stdout
+	var report *outputFile
+	if cfg.output != "" && cfg.outputShards == 0 {
+		report, err = openOutput(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "go_wc: --output: %v\n", err)
+			return 1
+		}
+		outFile = report.File
+	}
+	out := newBufferedOutput(outFile)
+	if cfg.posix {
+		out.untranscoded()
+	}
+	switch {
+	case cfg.outputShards > 0:
+		err = writeShards(cfg, all, totals, metrics, extra, exact, shardWriter(cfg, metrics, extra, meta, name))
+	case cfg.outputAppend:
+		reportFailures(all)
+		err = writeAppendLog(out, all, metrics, runStart, meta, name)
+	case cfg.format == "markdown":
+		fmt.Fprint(out, markdownReport(cfg, all, metrics, extra, multiple))
+	case cfg.format == "html":
+		err = htmlReport(out, cfg, all, metrics, extra, multiple)
+	case cfg.format == "json":
+		reportFailures(all)
+		err = writeJSON(out, all, totals, multiple, metrics, extra, meta, exact, name)
+	case cfg.format == "csv":
+		err = writeCSV(out, all, totals, multiple, metrics, extra, meta, exact, name)
+	case columns != nil:
+		printColumns(out, cfg, columns, inputs, all, totals, metrics, meta)
+	case cfg.format == "" || cfg.format == "text":
+		printText(out, cfg, inputs, all, totals, metrics, extra, estimates)
+	default:
+		var f format.Formatter
+		if f, err = format.New(cfg.format); err == nil {
+			err = writeFormatted(out, f, format.Layout{Metrics: metrics, Extra: extra}, all, totals, multiple, name)
+		}
+	}
+	if ferr := out.Flush(); err == nil {
+		err = ferr
+	}
+	if report != nil {
+		if err != nil {
+			report.abort()
+		} else {
+			err = report.commit()
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "go_wc: %v\n", err)
+		exitCode = 1
+	}
+	if cfg.outSQLite != "" {
+		sql := sqliteInserts(newRunID(), runStart, all, metrics, name)
+		if err := writeSQLite(sqlite3, cfg.outSQLite, sql); err != nil {
+			fmt.Fprintf(os.Stderr, "go_wc: --output-sqlite: %v\n", err)
+			exitCode = 1
+		}
+	}
+	if cfg.ngrams != "" {
+		if err := writeReport(cfg.reportDir, reportName("ngrams", cfg.ngramFormat, "csv"), ngramReport); err != nil {
+			fmt.Fprintf(os.Stderr, "go_wc: --report-dir: %v\n", err)
+			exitCode = 1
+		}
+	}
+	return finishRun(cfg, all, runStart, workers, cacheHits, skipped, exitCode, prof)
+}
+
+// printText writes the counts to out in wc's format, reporting failed
+// inputs on stderr.
+func printText(out io.Writer, cfg cliConfig, inputs []string, all []wc.FileResult, totals wc.FileResult, metrics wc.Metrics, extra []string, estimates *estimateLog) {
+	// Determine column width based on all results and totals
+	width := columnWidth(cfg, inputs, all, totals, metrics)
+	cols := 0
+	if cfg.truncate || cfg.wrap {
+		cols = terminalWidth(out)
+	}
+	table := &format.Table{Finish: func(line string, r wc.FileResult) string {
+		// r.Index is -1 for the total
+		est, estimated := estimates.total(all)
+		if r.Index >= 0 {
+			est, estimated = estimates.get(inputs[r.Index])
+		}
+		if estimated {
+			line += estimateNote(est, metrics)
+		}
+		lead := 0
+		if cfg.compat == compatBSD && !cfg.noAlign {
+			line, lead = " "+line, 1 // BSD prints every count after a space
+		}
+		if cols > 0 {
+			r.Filename = ""
+			line = fitName(line, lead+len(format.FormatLine(r, metrics, width))+1, cols, cfg.wrap)
+		}
+		return line
+	}}
+
+	// Print results
+	_ = table.Begin(out, format.Layout{Metrics: metrics, Extra: extra, Width: width, Header: cfg.header})
+	for _, r := range all {
+		if r.Err != nil {
+			reportFailure(r.Filename, r.Err)
+			continue
+		}
+		r.Filename = shownName(cfg, r.Filename)
+		_ = table.WriteResult(r)
+	}
+	if len(inputs) > 1 {
+		totals.Filename = "total"
+		totals.Index = -1
+		_ = table.WriteTotals(totals)
+	}
+	_ = table.End()
+}
+
+// formatNames lists the --format values: the registered formatters and
+// the reports main writes itself.
+func formatNames() []string {
+	names := append(format.Names(), "html", "json")
+	sort.Strings(names)
+	return names
+}
+
+// writeFormatted writes the counts with f, after reporting failed inputs
+// on stderr. The total, named "total", follows when there are several
+// inputs.
+func writeFormatted(w io.Writer, f format.Formatter, l format.Layout, all []wc.FileResult, totals wc.FileResult, multiple bool, name func(string) string) error {
+	if err := f.Begin(w, l); err != nil {
+		return err
+	}
+	for _, r := range succeeded(all) {
+		r.Filename = name(r.Filename)
+		if err := f.WriteResult(r); err != nil {
+			return err
+		}
+	}
+	if multiple {
+		totals.Filename = "total"
+		if err := f.WriteTotals(totals); err != nil {
+			return err
+		}
+	}
+	return f.End()
+}
+
+// reportOverflow warns that a total wrapped around.
+func reportOverflow() {
+	fmt.Fprintln(os.Stderr, "go_wc: the total exceeds 2^64 and wrapped around; --big-totals prints json and csv totals exactly")
+}
+
+// finishRun prints the --stats summary, if requested, to stderr or its
+// --report-dir file, then the --profile-summary to stderr, and returns the
+// exit code. skipped is the number of inputs left uncounted: devices that
+// --devices=skip dropped and those a --halt stopped short of.
+func finishRun(cfg cliConfig, all []wc.FileResult, runStart time.Time, workers, cacheHits, skipped, exitCode int, prof *runProfile) int {
+	end := time.Now()
+	if cfg.stats != "" {
+		st := collectStats(all, time.Since(runStart), workers)
+		st.CacheHits = cacheHits
+		st.Skipped = skipped
+		write := func(w io.Writer) error { return writeStats(w, st, cfg.stats) }
+		var err error
+		if cfg.reportDir != "" {
+			if err = writeReport(cfg.reportDir, reportName("stats", cfg.stats, "txt"), write); err != nil {
+				fmt.Fprintf(os.Stderr, "go_wc: --report-dir: %v\n", err)
+			}
+		} else {
+			err = write(os.Stderr)
+		}
+		if err != nil {
+			exitCode = 1
+		}
+	}
+	if prof != nil {
+		if err := writeProfile(os.Stderr, prof, end, workers); err != nil {
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
+
+// columnWidth applies --no-align/--width/--min-width, falling back to GNU's
+// sizing: a single count for a single input is unpadded, otherwise the
+// minimum width comes from the inputs' stat sizes.
+func columnWidth(cfg cliConfig, inputs []string, all []wc.FileResult, totals wc.FileResult, m wc.Metrics) int {
+	if cfg.noAlign {
+		return 1
+	}
+	if cfg.width > 0 {
+		return cfg.width
+	}
+	if cfg.compat == compatBSD && cfg.minWidth <= 0 {
+		return bsdWidth
+	}
+	minWidth := cfg.minWidth
+	if minWidth <= 0 {
+		columns := len(m.Names()) + len(cfg.countChar) + len(totals.StringCounts) + len(totals.RegexpCounts)
+		if m.LineEndings {
+			columns += 2 // lf, crlf and cr
+		}
+		if m.WordLengths {
+			columns++ // maxword and avgword
+		}
+		if m.WordsPerLine {
+			columns += 2 // minwpl, avgwpl and maxwpl
+		}
+		if m.TokenStats {
+			columns += 2 // numbers, urls and emails
+		}
+		if m.WordKinds {
+			columns += 2 // numeric, alnum and alpha
+		}
+		if m.CodeTokens {
+			columns += 3 // idents, literals, operators and tpl
+		}
+		if m.MatchLines {
+			columns += 2 // match and nomatch
+		}
+		if columns == 1 && !cfg.invisibles && len(inputs) == 1 {
+			minWidth = 1
+		} else {
+			minWidth = format.GNUWidth(statTotal(all))
+		}
+	}
+	if cfg.header && minWidth < 7 {
+		minWidth = 7 // keep header labels aligned
+	}
+	return format.ComputeWidthMin(all, totals, m, minWidth)
+}
+
+// statTotal sums the sizes of successfully counted inputs and reports
+// whether all of them are regular files.
+func statTotal(all []wc.FileResult) (uint64, bool) {
+	var total uint64
+	for _, r := range all {
+		if r.Err != nil {
+			continue
+		}
+		if r.Filename == "-" {
+			return 0, false
+		}
+		st, err := os.Stat(r.Filename)
+		if err != nil || !st.Mode().IsRegular() {
+			return 0, false
+		}
+		total += uint64(st.Size())
+	}
+	return total, true
+}
+
+// displayName rewrites a file name for printing per --basename and
+// --relative-to. Standard input and names that cannot be made relative are
+// printed as given.
+func displayName(cfg cliConfig, name string) string {
+	if name == "-" {
+		return name
+	}
+	if cfg.basename {
+		return filepath.Base(name)
+	}
+	if cfg.relativeTo != "" {
+		base, err := filepath.Abs(cfg.relativeTo)
+		if err != nil {
+			return name
+		}
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return name
+		}
+		if rel, err := filepath.Rel(base, abs); err == nil {
+			return rel
+		}
+	}
+	return name
+}
+
+// collectInputs builds the operand list from the command line and
+// --files0-from or --files-from, defaulting to standard input. With
+// --streaming, the names in those files are left to streamNames; with
+// --git, the operands select among the tracked files.
+func collectInputs(cfg cliConfig, files []string) ([]string, error) {
+	if cfg.git {
+		return gitFiles("", files)
+	}
 	inputs := make([]string, 0, len(files)+8)
 	inputs = append(inputs, files...)
-	if cfg.files0From != "" {
-		names, ferr := readFiles0From(cfg.files0From)
-		if ferr != nil {
-			fmt.Fprintln(os.Stderr, ferr)
-			os.Exit(1)
+	if cfg.streaming {
+		return inputs, nil
+	}
+	list, sep := fileList(cfg)
+	if list != "" {
+		names, err := readFileList(list, sep)
+		if err != nil {
+			return nil, err
 		}
 		inputs = append(inputs, names...)
 	}
 	if len(inputs) == 0 {
 		inputs = []string{"-"}
 	}
+	return inputs, nil
+}
 
-	loc := locale.Detect(cfg.encoding)
+// listInputs prints the inputs that would be counted, without reading them.
+func listInputs(w io.Writer, inputs []string, print0 bool) int {
+	sep := "\n"
+	if print0 {
+		sep = "\x00"
+	}
+	bw := bufio.NewWriter(w)
+	for _, name := range inputs {
+		_, _ = bw.WriteString(name + sep)
+	}
+	if err := bw.Flush(); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// stdinSource reads standard input at most once so that repeated "-"
+// operands share the same data.
+type stdinSource struct {
+	once sync.Once
+	data []byte
+	err  error
+}
+
+func (s *stdinSource) read(bufSize int) ([]byte, error) {
+	s.once.Do(func() {
+		s.data, s.err = io.ReadAll(bufio.NewReaderSize(os.Stdin, bufSize))
+	})
+	return s.data, s.err
+}
+
+// countSettings carries the per-run options applied to every input.
+type countSettings struct {
+	metrics     wc.Metrics
+	opts        wc.Options
+	fileTimeout time.Duration
+	halt        string
+	inputOrder  bool
+	estimate    float64 // sample fraction for --estimate; 0 counts exactly
+	estimates   *estimateLog
+	storage     *storagePlan // per-device queues for --jobs=auto
+	noStatSizes bool         // --no-stat-optimizations
+	extract     extractors   // --extract-with
+	checkpoint  *checkpoint  // --checkpoint and --resume
+}
 
-	opts := wc.Options{BufferSize: cfg.bufSize, Locale: loc}
+// countFile counts a single named input. "-" is served from stdin.
+func countFile(ctx context.Context, name string, cs countSettings, stdin *stdinSource) wc.FileResult {
+	if name != "-" && cs.estimate > 0 {
+		return cs.estimates.count(name, cs)
+	}
+	if name != "-" {
+		if cs.fileTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cs.fileTimeout)
+			defer cancel()
+		}
+		var fr wc.FileResult
+		if x := cs.extract.find(name); x != nil {
+			fr = countExtracted(ctx, name, x, cs)
+		} else {
+			fr = wc.CountFile(ctx, name, cs.metrics, cs.opts)
+		}
+		switch {
+		case errors.Is(fr.Err, context.DeadlineExceeded):
+			logger.Info("file timed out", "file", name, "timeout", cs.fileTimeout)
+			fr.Err = fmt.Errorf("timed out after %s", cs.fileTimeout)
+		case fr.Err != nil && fr.Bytes == 0:
+			fr.Err = explainOpenError(name, fr.Err)
+		}
+		return fr
+	}
 
-	// Prepare jobs and worker pool
-	type job struct {
-		idx  int
-		name string
+	start := time.Now()
+	var fr wc.FileResult
+	data, err := stdin.read(cs.opts.BufferSize)
+	if err != nil {
+		fr = wc.FileResult{Filename: name, Err: err}
+	} else {
+		fr = wc.CountBytes(data, cs.metrics, cs.opts)
+		fr.Filename = name
 	}
-	jobs := make(chan job)
+	fr.Duration = time.Since(start)
+	return fr
+}
+
+// Halt policies for --halt, modelled on GNU parallel.
+const (
+	haltNever = "never" // count everything regardless of errors
+	haltSoon  = "soon"  // stop scheduling new files after the first error
+	haltNow   = "now"   // also abandon files being counted
+)
+
+// countInputs counts inputs using up to workers goroutines, or with
+// cs.storage as many per device as suits it, and returns the results in
+// input order. When cs.halt stops the run early, inputs that
+// were never counted (or were abandoned) are left out.
+func countInputs(inputs []string, cs countSettings, workers int) []wc.FileResult {
 	results := make(chan wc.FileResult)
 	var wg sync.WaitGroup
-	workerCount := cfg.jobs
-	if workerCount < 1 {
-		workerCount = 1
+	if workers < 1 {
+		workers = 1
+	}
+	stdin := &stdinSource{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := make(chan struct{})
+	halting := cs.halt == haltSoon || cs.halt == haltNow
+	var haltOnce sync.Once
+	halt := func() {
+		haltOnce.Do(func() {
+			close(stop)
+			if cs.halt == haltNow {
+				cancel()
+			}
+		})
+	}
+	stopped := func() bool {
+		select {
+		case <-stop:
+			return true
+		default:
+			return false
+		}
 	}
 
-	stdinOnce := sync.Once{}
-	var stdinData []byte // If stdin referenced multiple times, slurp once.
-	stdinErr := error(nil)
-
-	worker := func() {
+	worker := func(jobs <-chan job) {
 		defer wg.Done()
-		for j := range jobs {
-			var fr wc.FileResult
-			start := time.Now()
-			if j.name == "-" {
-				stdinOnce.Do(func() {
-					stdinData, stdinErr = io.ReadAll(bufio.NewReaderSize(os.Stdin, opts.BufferSize))
-				})
-				if stdinErr != nil {
-					fr = wc.FileResult{Filename: j.name, Err: stdinErr}
-				} else {
-					fr = wc.CountBytes(stdinData, metrics, opts)
-					fr.Filename = j.name
+		var buf []byte // reused for every small file
+		for !stopped() {
+			j, ok := <-jobs
+			if !ok {
+				return
+			}
+			for _, i := range j.idx {
+				if stopped() {
+					break // --halt: leave the rest of the batch
 				}
-			} else {
-				f, e := os.Open(j.name)
-				if e != nil {
-					fr = wc.FileResult{Filename: j.name, Err: e}
-				} else {
-					fr = wc.CountReader(bufio.NewReaderSize(f, opts.BufferSize), metrics, opts)
-					fr.Filename = j.name
-					_ = f.Close()
+				fr := countInput(ctx, inputs[i], j.small, cs, stdin, &buf)
+				fr.Index = i
+				if fr.Err != nil && halting {
+					// before the result goes out, so that no worker
+					// starts another file in the meantime
+					halt()
 				}
+				results <- fr
 			}
-			fr.Duration = time.Since(start)
-			fr.Index = j.idx
-			results <- fr
 		}
 	}
 
-	wg.Add(workerCount)
-	for i := 0; i < workerCount; i++ {
-		go worker()
+	// Sizes are needed to order the inputs and to batch small files, which
+	// only pays off when several workers share a queue.
+	var sizes []int64
+	order := identityOrder(len(inputs))
+	orderName := "input"
+	if (workers > 1 || cs.storage != nil) && !cs.noStatSizes {
+		sizes = inputSizes(inputs)
+		if !cs.inputOrder {
+			order = largestFirst(sizes)
+			orderName = "largest-first"
+		}
 	}
-	go func() {
-		for i, name := range inputs {
-			jobs <- job{idx: i, name: name}
+	lanes := []lane{{order: order, workers: workers}}
+	if cs.storage != nil {
+		lanes = cs.storage.lanes(order)
+	}
+	logger.Debug("scheduling", "files", len(inputs), "workers", workerCount(lanes), "queues", len(lanes), "order", orderName)
+	for _, l := range lanes {
+		jobs := make(chan job)
+		wg.Add(l.workers)
+		for i := 0; i < l.workers; i++ {
+			go worker(jobs)
 		}
-		close(jobs)
+		go func(batches []job) {
+			defer close(jobs)
+			for _, j := range batches {
+				if stopped() {
+					return
+				}
+				select {
+				case jobs <- j:
+				case <-stop:
+					return
+				}
+			}
+		}(batchJobs(l.order, sizes))
+	}
+	go func() {
+		wg.Wait()
+		close(results)
 	}()
 
-	// Collect and print in order
+	// Collect in order
 	pending := make(map[int]wc.FileResult)
 	next := 0
-	var exitCode int
-
 	all := make([]wc.FileResult, 0, len(inputs))
-	for range inputs {
-		res := <-results
-		if res.Err != nil {
-			exitCode = 1
+	halted := false
+	for res := range results {
+		if cs.halt == haltNow && stopped() && errors.Is(res.Err, context.Canceled) {
+			continue // abandoned by --halt=now
+		}
+		if res.Err != nil && !halted && halting {
+			logger.Info("halting", "policy", cs.halt, "file", res.Filename)
+			halted = true
+			halt()
 		}
 		pending[res.Index] = res
 		for {
@@ -226,46 +1613,150 @@ func main() {
 			}
 		}
 	}
-	wg.Wait()
+	// Inputs skipped after a halt leave gaps; keep the rest in order.
+	for i := next; len(pending) > 0; i++ {
+		if pr, ok := pending[i]; ok {
+			all = append(all, pr)
+			delete(pending, i)
+		}
+	}
+	if skipped := len(inputs) - len(all); skipped > 0 {
+		logger.Info("skipped files after halt", "count", skipped)
+	}
+	return all
+}
 
-	// Compute totals and formatting
-	var totals wc.FileResult
-	multiple := len(inputs) > 1
-	for _, r := range all {
-		if r.Err == nil {
-			totals.Lines += r.Lines
-			totals.Words += r.Words
-			totals.Bytes += r.Bytes
-			totals.Chars += r.Chars
-			if r.MaxLineBytes > totals.MaxLineBytes {
-				totals.MaxLineBytes = r.MaxLineBytes
-			}
-			if r.MaxLineChars > totals.MaxLineChars {
-				totals.MaxLineChars = r.MaxLineChars
-			}
+// countInput counts the named input for a worker of countInputs: from the
+// checkpoint when it holds the result, else through the worker's buffer
+// *buf when small says the file is small.
+func countInput(ctx context.Context, name string, small bool, cs countSettings, stdin *stdinSource, buf *[]byte) wc.FileResult {
+	key := cs.checkpoint.key(name)
+	fr, resumed := cs.checkpoint.lookup(name, key)
+	if resumed {
+		logger.Debug("resumed", "file", name)
+		return fr
+	}
+	if small && cs.fileTimeout == 0 && cs.estimate == 0 && cs.extract.find(name) == nil {
+		if *buf == nil {
+			*buf = make([]byte, smallFileSize)
 		}
+		fr = wc.CountSmallFile(ctx, name, cs.metrics, cs.opts, *buf)
+	} else {
+		fr = countFile(ctx, name, cs, stdin)
 	}
+	cs.checkpoint.record(key, fr)
+	logger.Debug("counted", "file", name, "duration", fr.Duration)
+	return fr
+}
 
-	// Determine column width based on all results and totals
-	width := format.ComputeWidth(all, totals, metrics)
+func identityOrder(n int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	return order
+}
 
-	// Print results
-	for _, r := range all {
-		if r.Err != nil {
-			fmt.Fprintf(os.Stderr, "go_wc: %s: %v\n", r.Filename, r.Err)
+// inputSizes returns the size of each input whose stat size can be
+// trusted, and -1 for the others (stdin, pipes, /proc files, stat errors).
+func inputSizes(inputs []string) []int64 {
+	sizes := make([]int64, len(inputs))
+	for i, name := range inputs {
+		sizes[i] = -1
+		if name == "-" {
 			continue
 		}
-		fmt.Println(format.FormatLine(r, metrics, width))
+		st, _ := os.Stat(name)
+		if size, ok := statSize(name, st); ok {
+			sizes[i] = size
+		}
 	}
-	if multiple {
-		totals.Filename = "total"
-		fmt.Println(format.FormatLine(totals, metrics, width))
+	return sizes
+}
+
+// statSize is wc.StatSize for the result of os.Stat, where st is nil if
+// it failed.
+func statSize(name string, st os.FileInfo) (int64, bool) {
+	if st == nil {
+		return 0, false
 	}
+	return wc.StatSize(name, st)
+}
 
-	os.Exit(exitCode)
+// largestFirst returns input indices ordered by decreasing file size, so
+// that the biggest files start early and small ones fill in around them
+// instead of one worker finishing a large file alone. Inputs whose size is
+// unknown go first since they may be unbounded.
+func largestFirst(sizes []int64) []int {
+	order := identityOrder(len(sizes))
+	sort.SliceStable(order, func(a, b int) bool {
+		sa, sb := sizes[order[a]], sizes[order[b]]
+		if sa < 0 || sb < 0 {
+			return sa < 0 && sb >= 0
+		}
+		return sa > sb
+	})
+	return order
+}
+
+// Trees of many tiny files spend more time handing files to workers than
+// counting them, so files under smallFileSize travel in batches of up to
+// smallBatch and are read whole into a buffer each worker reuses.
+const (
+	smallFileSize = 4 * 1024
+	smallBatch    = 64
+)
+
+// job is a unit of work for a countInputs worker: one input, or a batch of
+// small ones.
+type job struct {
+	idx   []int
+	small bool
+}
+
+// batchJobs turns order into jobs, grouping consecutive inputs whose size
+// is known to be under smallFileSize. With nil sizes every input is a job
+// of its own.
+func batchJobs(order []int, sizes []int64) []job {
+	jobs := make([]job, 0, len(order))
+	var batch []int
+	flush := func() {
+		if len(batch) > 0 {
+			jobs = append(jobs, job{idx: batch, small: true})
+			batch = nil
+		}
+	}
+	for _, i := range order {
+		if sizes != nil && sizes[i] >= 0 && sizes[i] < smallFileSize {
+			batch = append(batch, i)
+			if len(batch) == smallBatch {
+				flush()
+			}
+			continue
+		}
+		flush()
+		jobs = append(jobs, job{idx: []int{i}})
+	}
+	flush()
+	return jobs
+}
+
+// fileList returns the --files0-from or --files-from file and the byte
+// that separates its names, or "" if there is neither.
+func fileList(cfg cliConfig) (string, byte) {
+	if cfg.filesFrom != "" {
+		return cfg.filesFrom, '\n'
+	}
+	return cfg.files0From, 0
 }
 
 func readFiles0From(path string) ([]string, error) {
+	return readFileList(path, 0)
+}
+
+// readFileList returns the names in the file path ("-" for standard
+// input), separated by sep. Empty names are left out.
+func readFileList(path string, sep byte) ([]string, error) {
 	var r io.Reader
 	if path == "-" {
 		r = os.Stdin
@@ -281,7 +1772,7 @@ func readFiles0From(path string) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	parts := strings.Split(string(data), "\x00")
+	parts := strings.Split(string(data), string(sep))
 	out := make([]string, 0, len(parts))
 	for _, p := range parts {
 		if p == "" {
@@ -292,3 +1783,22 @@ func readFiles0From(path string) ([]string, error) {
 	return out, nil
 }
 
+
+// parseRegion parses a --region value, "START:END" or "START:", byte
+// offsets of which END is exclusive and 0 when left out.
+func parseRegion(s string) (start, end int64, err error) {
+	a, b, ok := strings.Cut(s, ":")
+	if ok {
+		start, err = strconv.ParseInt(a, 10, 64)
+	}
+	if ok && err == nil && b != "" {
+		end, err = strconv.ParseInt(b, 10, 64)
+		if err == nil && end <= start {
+			return 0, 0, fmt.Errorf("invalid --region %q: END must be greater than START", s)
+		}
+	}
+	if !ok || err != nil || start < 0 {
+		return 0, 0, fmt.Errorf("invalid --region %q (want START:END or START:, byte offsets)", s)
+	}
+	return start, end, nil
+}
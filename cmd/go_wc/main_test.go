@@ -1,93 +1,1554 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
 )
 
 func TestParseArgs(t *testing.T) {
+	t.Setenv(posixEnv, "") // POSIX mode would reject most of these
 	tests := []struct {
-		name         string
-		args         []string
-		expectedCfg  cliConfig
-		expectedRem  []string
-		expectError  bool
+		name        string
+		args        []string
+		expectedCfg cliConfig
+		expectedRem []string
+		expectError bool
 	}{
 		{
-			name: "default config",
-			args: []string{},
+			name: "default config",
+			args: []string{},
+			expectedCfg: cliConfig{
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "count bytes short flag",
+			args: []string{"-c", "file.txt"},
+			expectedCfg: cliConfig{
+				countBytes: true,
+				jobs:       runtime.GOMAXPROCS(0),
+				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
+			},
+			expectedRem: []string{"file.txt"},
+		},
+		{
+			name: "count bytes long flag",
+			args: []string{"--bytes", "file.txt"},
+			expectedCfg: cliConfig{
+				countBytes: true,
+				jobs:       runtime.GOMAXPROCS(0),
+				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
+			},
+			expectedRem: []string{"file.txt"},
+		},
+		{
+			name: "multiple flags",
+			args: []string{"-l", "-w", "-c", "file1.txt", "file2.txt"},
+			expectedCfg: cliConfig{
+				countLines: true,
+				countWords: true,
+				countBytes: true,
+				jobs:       runtime.GOMAXPROCS(0),
+				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
+			},
+			expectedRem: []string{"file1.txt", "file2.txt"},
+		},
+		{
+			name: "max line length flags",
+			args: []string{"-L", "--max-line-length-chars"},
+			expectedCfg: cliConfig{
+				countMaxBytes: true,
+				countMaxChars: true,
+				jobs:          runtime.GOMAXPROCS(0),
+				bufSize:       1 * 1024 * 1024,
+				halt:          "never",
+				socket:        defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "custom jobs and buffer size",
+			args: []string{"-j", "4", "--buffer-size", "2048"},
+			expectedCfg: cliConfig{
+				jobs:    4,
+				bufSize: 2048,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "auto jobs",
+			args: []string{"--jobs=auto"},
+			expectedCfg: cliConfig{
+				autoJobs: true,
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "bad jobs",
+			args: []string{"-j", "many"},
+			expectedCfg: cliConfig{
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "files0-from and encoding",
+			args: []string{"--files0-from", "filelist.txt", "--encoding", "utf-8"},
+			expectedCfg: cliConfig{
+				files0From: "filelist.txt",
+				encoding:   "utf-8",
+				jobs:       runtime.GOMAXPROCS(0),
+				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "remote with socket",
+			args: []string{"--remote", "--socket", "/tmp/wc.sock", "a.txt"},
+			expectedCfg: cliConfig{
+				remote:  true,
+				halt:    "never",
+				socket:  "/tmp/wc.sock",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "file timeout",
+			args: []string{"--file-timeout", "10s", "a.txt"},
+			expectedCfg: cliConfig{
+				fileTimeout: 10 * time.Second,
+				jobs:        runtime.GOMAXPROCS(0),
+				bufSize:     1 * 1024 * 1024,
+				halt:        "never",
+				socket:      defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "list only with print0",
+			args: []string{"--list-only", "--print0", "a.txt"},
+			expectedCfg: cliConfig{
+				listOnly: true,
+				print0:   true,
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "stats bare and json",
+			args: []string{"--stats=json"},
+			expectedCfg: cliConfig{
+				stats:   "json",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "profile summary",
+			args: []string{"--profile-summary", "a.txt"},
+			expectedCfg: cliConfig{
+				profile: true,
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "bare stats",
+			args: []string{"--stats", "a.txt"},
+			expectedCfg: cliConfig{
+				stats:   "text",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "header",
+			args: []string{"--header"},
+			expectedCfg: cliConfig{
+				header:  true,
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "width overrides",
+			args: []string{"--width", "3", "--min-width", "2"},
+			expectedCfg: cliConfig{
+				width:    3,
+				minWidth: 2,
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "no align",
+			args: []string{"--no-align", "a.txt"},
+			expectedCfg: cliConfig{
+				noAlign: true,
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "filename display",
+			args: []string{"--basename", "--relative-to", "/src"},
+			expectedCfg: cliConfig{
+				basename:   true,
+				relativeTo: "/src",
+				jobs:       runtime.GOMAXPROCS(0),
+				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "halt soon",
+			args: []string{"--halt=soon", "a.txt"},
+			expectedCfg: cliConfig{
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "soon",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "input order",
+			args: []string{"--input-order", "a.txt"},
+			expectedCfg: cliConfig{
+				inputOrder: true,
+				jobs:       runtime.GOMAXPROCS(0),
+				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "repeated count-char",
+			args: []string{"--count-char=;", "--count-char", "[:digit:]", "a.txt"},
+			expectedCfg: cliConfig{
+				countChar: []string{";", "[:digit:]"},
+				jobs:      runtime.GOMAXPROCS(0),
+				bufSize:   1 * 1024 * 1024,
+				halt:      "never",
+				socket:    defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "count invisibles",
+			args: []string{"--count-invisibles", "a.txt"},
+			expectedCfg: cliConfig{
+				invisibles: true,
+				jobs:       runtime.GOMAXPROCS(0),
+				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "whitespace lines",
+			args: []string{"--whitespace-lines", "a.txt"},
+			expectedCfg: cliConfig{
+				countWSLines: true,
+				jobs:         runtime.GOMAXPROCS(0),
+				bufSize:      1 * 1024 * 1024,
+				halt:         "never",
+				socket:       defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "line ending stats",
+			args: []string{"--line-ending-stats"},
+			expectedCfg: cliConfig{
+				countEndings: true,
+				jobs:         runtime.GOMAXPROCS(0),
+				bufSize:      1 * 1024 * 1024,
+				halt:         "never",
+				socket:       defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "word lengths",
+			args: []string{"--word-lengths", "--longest-word", "a.txt"},
+			expectedCfg: cliConfig{
+				countWordLens: true,
+				countLongest:  true,
+				jobs:          runtime.GOMAXPROCS(0),
+				bufSize:       1 * 1024 * 1024,
+				halt:          "never",
+				socket:        defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "words per line",
+			args: []string{"--words-per-line", "a.txt"},
+			expectedCfg: cliConfig{
+				countWPL: true,
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "emoji",
+			args: []string{"--emoji", "a.txt"},
+			expectedCfg: cliConfig{
+				countEmoji: true,
+				jobs:       runtime.GOMAXPROCS(0),
+				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "token stats",
+			args: []string{"--token-stats", "a.txt"},
+			expectedCfg: cliConfig{
+				tokenStats: true,
+				jobs:       runtime.GOMAXPROCS(0),
+				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "word kinds",
+			args: []string{"--word-kinds", "a.txt"},
+			expectedCfg: cliConfig{
+				wordKinds: true,
+				jobs:      runtime.GOMAXPROCS(0),
+				bufSize:   1 * 1024 * 1024,
+				halt:      "never",
+				socket:    defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "code tokens",
+			args: []string{"--code-tokens", "main.go"},
+			expectedCfg: cliConfig{
+				codeTokens: true,
+				jobs:       runtime.GOMAXPROCS(0),
+				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
+			},
+			expectedRem: []string{"main.go"},
+		},
+		{
+			name: "min word length",
+			args: []string{"--min-word-length=3", "--unique-words"},
+			expectedCfg: cliConfig{
+				minWordLen:  3,
+				uniqueWords: "exact",
+				jobs:        runtime.GOMAXPROCS(0),
+				bufSize:     1 * 1024 * 1024,
+				halt:        "never",
+				socket:      defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "negative min word length",
+			args: []string{"--min-word-length=-1"},
+			expectedCfg: cliConfig{
+				minWordLen: -1,
+				jobs:       runtime.GOMAXPROCS(0),
+				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "min word length with estimate",
+			args: []string{"--min-word-length=2", "--estimate=0.1"},
+			expectedCfg: cliConfig{
+				minWordLen: 2,
+				estimate:   "0.1",
+				jobs:       runtime.GOMAXPROCS(0),
+				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "hyphen",
+			args: []string{"--hyphen=split"},
+			expectedCfg: cliConfig{
+				hyphen:  "split",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "unknown hyphen",
+			args: []string{"--hyphen=keep"},
+			expectedCfg: cliConfig{
+				hyphen:  "keep",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "hyphen with remote",
+			args: []string{"--hyphen=join", "--remote"},
+			expectedCfg: cliConfig{
+				hyphen:  "join",
+				remote:  true,
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "apostrophe",
+			args: []string{"--apostrophe=internal"},
+			expectedCfg: cliConfig{
+				apostrophe: "internal",
+				jobs:       runtime.GOMAXPROCS(0),
+				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "unknown apostrophe",
+			args: []string{"--apostrophe=keep"},
+			expectedCfg: cliConfig{
+				apostrophe: "keep",
+				jobs:       runtime.GOMAXPROCS(0),
+				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "single byte chars",
+			args: []string{"--encoding=latin1", "--single-byte-chars=bytes"},
+			expectedCfg: cliConfig{
+				encoding: "latin1",
+				sbChars:  "bytes",
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "invalid single byte chars",
+			args: []string{"--single-byte-chars=decoded"},
+			expectedCfg: cliConfig{
+				sbChars: "decoded",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "posix",
+			args: []string{"--posix", "-lw", "a.txt"},
+			expectedCfg: cliConfig{
+				posix:      true,
+				noAlign:    true,
+				countLines: true,
+				countWords: true,
+				jobs:       runtime.GOMAXPROCS(0),
+				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "compat bsd",
+			args: []string{"--compat=bsd", "-cLm", "-w", "a.txt"},
+			expectedCfg: cliConfig{
+				compat:        compatBSD,
+				countChars:    true,
+				countWords:    true,
+				countMaxChars: true,
+				jobs:          runtime.GOMAXPROCS(0),
+				bufSize:       1 * 1024 * 1024,
+				halt:          "never",
+				socket:        defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "invalid compat",
+			args: []string{"--compat=gnu"},
+			expectedCfg: cliConfig{
+				compat:  "gnu",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "unique words",
+			args: []string{"--unique-words", "--fold-case"},
+			expectedCfg: cliConfig{
+				uniqueWords: "exact",
+				foldCase:    true,
+				jobs:        runtime.GOMAXPROCS(0),
+				bufSize:     1 * 1024 * 1024,
+				halt:        "never",
+				socket:      defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "stemmed word frequencies",
+			args: []string{"--ngrams=1", "--fold-case", "--stem=porter"},
+			expectedCfg: cliConfig{
+				ngrams:   "1",
+				foldCase: true,
+				stem:     "porter",
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "stopwords",
+			args: []string{"--unique-words", "--stopwords=builtin:en"},
+			expectedCfg: cliConfig{
+				uniqueWords: "exact",
+				stopwords:   "builtin:en",
+				jobs:        runtime.GOMAXPROCS(0),
+				bufSize:     1 * 1024 * 1024,
+				halt:        "never",
+				socket:      defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "unknown stemmer",
+			args: []string{"--stem=snowball"},
+			expectedCfg: cliConfig{
+				stem:    "snowball",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "approximate unique words",
+			args: []string{"--unique-words=approx"},
+			expectedCfg: cliConfig{
+				uniqueWords: "approx",
+				jobs:        runtime.GOMAXPROCS(0),
+				bufSize:     1 * 1024 * 1024,
+				halt:        "never",
+				socket:      defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "ngrams",
+			args: []string{"--ngrams=2,5", "--ngram-format=json", "a.txt"},
+			expectedCfg: cliConfig{
+				ngrams:      "2,5",
+				ngramFormat: "json",
+				jobs:        runtime.GOMAXPROCS(0),
+				bufSize:     1 * 1024 * 1024,
+				halt:        "never",
+				socket:      defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "report dir",
+			args: []string{"--ngrams=2", "--stats=json", "--report-dir=out"},
+			expectedCfg: cliConfig{
+				ngrams:    "2",
+				stats:     "json",
+				reportDir: "out",
+				jobs:      runtime.GOMAXPROCS(0),
+				bufSize:   1 * 1024 * 1024,
+				halt:      "never",
+				socket:    defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "markdown grouped",
+			args: []string{"--format=markdown", "--group-by=ext", "a.go"},
+			expectedCfg: cliConfig{
+				format:  "markdown",
+				groupBy: "ext",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{"a.go"},
+		},
+		{
+			name: "html output",
+			args: []string{"--format=html", "--output=report.html", "a.go"},
+			expectedCfg: cliConfig{
+				format:  "html",
+				output:  "report.html",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{"a.go"},
+		},
+		{
+			name: "parquet output",
+			args: []string{"--format=parquet", "--output=counts.parquet"},
+			expectedCfg: cliConfig{
+				format:  "parquet",
+				output:  "counts.parquet",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "parquet line histogram",
+			args: []string{"--format=parquet", "--line-histogram", "--output=counts.parquet"},
+			expectedCfg: cliConfig{
+				format:   "parquet",
+				lineHist: true,
+				output:   "counts.parquet",
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "line histogram in csv",
+			args: []string{"--format=csv", "--line-histogram"},
+			expectedCfg: cliConfig{
+				format:   "csv",
+				lineHist: true,
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "sqlite output",
+			args: []string{"--output-sqlite=counts.db"},
+			expectedCfg: cliConfig{
+				outSQLite: "counts.db",
+				jobs:      runtime.GOMAXPROCS(0),
+				bufSize:   1 * 1024 * 1024,
+				halt:      "never",
+				socket:    defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "output append",
+			args: []string{"--output=audit.log", "--output-append"},
+			expectedCfg: cliConfig{
+				output:       "audit.log",
+				outputAppend: true,
+				jobs:         runtime.GOMAXPROCS(0),
+				bufSize:      1 * 1024 * 1024,
+				halt:         "never",
+				socket:       defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "output append without output",
+			args: []string{"--output-append"},
+			expectedCfg: cliConfig{
+				outputAppend: true,
+				jobs:         runtime.GOMAXPROCS(0),
+				bufSize:      1 * 1024 * 1024,
+				halt:         "never",
+				socket:       defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "logging",
+			args: []string{"--log-level=debug", "--log-json"},
+			expectedCfg: cliConfig{
+				logLevel: "debug",
+				logJSON:  true,
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "invalid log level",
+			args: []string{"--log-level=loud"},
+			expectedCfg: cliConfig{
+				logLevel: "loud",
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "csv with metadata",
+			args: []string{"--format=csv", "--with-metadata"},
+			expectedCfg: cliConfig{
+				format:   "csv",
+				withMeta: true,
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "metadata needs machine output",
+			args: []string{"--with-metadata"},
+			expectedCfg: cliConfig{
+				withMeta: true,
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "columns",
+			args: []string{"--columns=lines,size,mtime,filename"},
+			expectedCfg: cliConfig{
+				columns: "lines,size,mtime,filename",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "invalid column",
+			args: []string{"--columns=lines,owner"},
+			expectedCfg: cliConfig{
+				columns: "lines,owner",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "truncate names",
+			args: []string{"--truncate"},
+			expectedCfg: cliConfig{
+				truncate: true,
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "truncate and wrap",
+			args: []string{"--truncate", "--wrap"},
+			expectedCfg: cliConfig{
+				truncate: true,
+				wrap:     true,
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "schema",
+			args: []string{"--schema"},
+			expectedCfg: cliConfig{
+				schema:  true,
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "per line",
+			args: []string{"--per-line", "--format=csv", "a.txt"},
+			expectedCfg: cliConfig{
+				perLine: true,
+				format:  "csv",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "per line markdown",
+			args: []string{"--per-line", "--format=markdown"},
+			expectedCfg: cliConfig{
+				perLine: true,
+				format:  "markdown",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "per record",
+			args: []string{"--per-record", "--record-separator=\\0", "a.bin"},
+			expectedCfg: cliConfig{
+				perRecord: true,
+				recordSep: `\0`,
+				jobs:      runtime.GOMAXPROCS(0),
+				bufSize:   1 * 1024 * 1024,
+				halt:      "never",
+				socket:    defaultSocketPath,
+			},
+			expectedRem: []string{"a.bin"},
+		},
+		{
+			name: "per record without records",
+			args: []string{"--per-record"},
+			expectedCfg: cliConfig{
+				perRecord: true,
+				jobs:      runtime.GOMAXPROCS(0),
+				bufSize:   1 * 1024 * 1024,
+				halt:      "never",
+				socket:    defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "records without per record",
+			args: []string{"--records=csv"},
+			expectedCfg: cliConfig{
+				records: "csv",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "interval",
+			args: []string{"--interval=10s", "-l"},
+			expectedCfg: cliConfig{
+				interval:   "10s",
+				countLines: true,
+				jobs:       runtime.GOMAXPROCS(0),
+				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "interval with json",
+			args: []string{"--interval=1MB", "--format=json"},
+			expectedCfg: cliConfig{
+				interval: "1MB",
+				format:   "json",
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "status",
+			args: []string{"--status", "big.log"},
+			expectedCfg: cliConfig{
+				status:  true,
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{"big.log"},
+		},
+		{
+			name: "skip devices",
+			args: []string{"--devices=skip", "a", "b"},
+			expectedCfg: cliConfig{
+				devices: "skip",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{"a", "b"},
+		},
+		{
+			name: "invalid devices",
+			args: []string{"--devices=open"},
+			expectedCfg: cliConfig{
+				devices: "open",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "no stat optimizations",
+			args: []string{"--no-stat-optimizations", "/proc/cpuinfo"},
+			expectedCfg: cliConfig{
+				noStatOpt: true,
+				jobs:      runtime.GOMAXPROCS(0),
+				bufSize:   1 * 1024 * 1024,
+				halt:      "never",
+				socket:    defaultSocketPath,
+			},
+			expectedRem: []string{"/proc/cpuinfo"},
+		},
+		{
+			name: "no stat optimizations with estimate",
+			args: []string{"--no-stat-optimizations", "--estimate"},
+			expectedCfg: cliConfig{
+				noStatOpt: true,
+				estimate:  "1",
+				jobs:      runtime.GOMAXPROCS(0),
+				bufSize:   1 * 1024 * 1024,
+				halt:      "never",
+				socket:    defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "scripts",
+			args: []string{"--scripts", "--format=json", "strings.xml"},
+			expectedCfg: cliConfig{
+				scripts: true,
+				format:  "json",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{"strings.xml"},
+		},
+		{
+			name: "scripts with ngrams",
+			args: []string{"--scripts", "--ngrams=2"},
+			expectedCfg: cliConfig{
+				scripts: true,
+				ngrams:  "2",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "match",
+			args: []string{"--match=^ERROR", "a.txt"},
+			expectedCfg: cliConfig{
+				match:   "^ERROR",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "invalid match",
+			args: []string{"--match=("},
+			expectedCfg: cliConfig{
+				match:   "(",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "match with per-line",
+			args: []string{"--match=x", "--per-line"},
+			expectedCfg: cliConfig{
+				match:   "x",
+				perLine: true,
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "patterns from",
+			args: []string{"--patterns-from=pats.txt", "a.log"},
+			expectedCfg: cliConfig{
+				patternsFrom: "pats.txt",
+				jobs:         runtime.GOMAXPROCS(0),
+				bufSize:      1 * 1024 * 1024,
+				halt:         "never",
+				socket:       defaultSocketPath,
+			},
+			expectedRem: []string{"a.log"},
+		},
+		{
+			name: "max match length",
+			args: []string{"--match=^ERROR", "--max-match-length=4096", "a.log"},
+			expectedCfg: cliConfig{
+				match:       "^ERROR",
+				maxMatchLen: 4096,
+				jobs:        runtime.GOMAXPROCS(0),
+				bufSize:     1 * 1024 * 1024,
+				halt:        "never",
+				socket:      defaultSocketPath,
+			},
+			expectedRem: []string{"a.log"},
+		},
+		{
+			name: "negative max match length",
+			args: []string{"--max-match-length=-1"},
+			expectedCfg: cliConfig{
+				maxMatchLen: -1,
+				jobs:        runtime.GOMAXPROCS(0),
+				bufSize:     1 * 1024 * 1024,
+				halt:        "never",
+				socket:      defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "sandbox",
+			args: []string{"--sandbox", "upload.txt"},
+			expectedCfg: cliConfig{
+				sandbox: true,
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{"upload.txt"},
+		},
+		{
+			name: "sandbox with remote",
+			args: []string{"--sandbox", "--remote"},
+			expectedCfg: cliConfig{
+				sandbox: true,
+				remote:  true,
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "sandbox with names from stdin",
+			args: []string{"--sandbox", "--files0-from=-"},
+			expectedCfg: cliConfig{
+				sandbox:    true,
+				files0From: "-",
+				jobs:       runtime.GOMAXPROCS(0),
+				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "abort guards",
+			args: []string{"--abort-if-line-exceeds=64KiB", "--abort-if-word-exceeds", "4096"},
+			expectedCfg: cliConfig{
+				abortLine: 64 << 10,
+				abortWord: 4096,
+				jobs:      runtime.GOMAXPROCS(0),
+				bufSize:   1 * 1024 * 1024,
+				halt:      "never",
+				socket:    defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "abort guard with remote",
+			args: []string{"--abort-if-line-exceeds=1MB", "--remote"},
+			expectedCfg: cliConfig{
+				abortLine: 1e6,
+				remote:    true,
+				jobs:      runtime.GOMAXPROCS(0),
+				bufSize:   1 * 1024 * 1024,
+				halt:      "never",
+				socket:    defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid abort guard size",
+			args: []string{"--abort-if-line-exceeds=0"},
 			expectedCfg: cliConfig{
 				jobs:    runtime.GOMAXPROCS(0),
 				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "checkpoint with unique words",
+			args: []string{"--checkpoint=run.ckpt", "--resume=run.ckpt", "--unique-words"},
+			expectedCfg: cliConfig{
+				checkpoint:  "run.ckpt",
+				resume:      "run.ckpt",
+				uniqueWords: "exact",
+				jobs:        runtime.GOMAXPROCS(0),
+				bufSize:     1 * 1024 * 1024,
+				halt:        "never",
+				socket:      defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "output shards",
+			args: []string{"--format=csv", "--output=counts.csv", "--output-shards=4", "a"},
+			expectedCfg: cliConfig{
+				format:       "csv",
+				output:       "counts.csv",
+				outputShards: 4,
+				jobs:         runtime.GOMAXPROCS(0),
+				bufSize:      1 * 1024 * 1024,
+				halt:         "never",
+				socket:       defaultSocketPath,
+			},
+			expectedRem: []string{"a"},
+		},
+		{
+			name: "output shards in text",
+			args: []string{"--output=counts.txt", "--output-shards=4"},
+			expectedCfg: cliConfig{
+				output:       "counts.txt",
+				outputShards: 4,
+				jobs:         runtime.GOMAXPROCS(0),
+				bufSize:      1 * 1024 * 1024,
+				halt:         "never",
+				socket:       defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "streaming files-from",
+			args: []string{"--files-from=-", "--streaming", "--format=csv"},
+			expectedCfg: cliConfig{
+				filesFrom: "-",
+				streaming: true,
+				format:    "csv",
+				jobs:      runtime.GOMAXPROCS(0),
+				bufSize:   1 * 1024 * 1024,
+				halt:      "never",
+				socket:    defaultSocketPath,
 			},
 			expectedRem: []string{},
 		},
 		{
-			name: "count bytes short flag",
-			args: []string{"-c", "file.txt"},
+			name: "streaming without a file list",
+			args: []string{"--streaming", "a"},
 			expectedCfg: cliConfig{
-				countBytes: true,
-				jobs:       runtime.GOMAXPROCS(0),
-				bufSize:    1 * 1024 * 1024,
+				streaming: true,
+				jobs:      runtime.GOMAXPROCS(0),
+				bufSize:   1 * 1024 * 1024,
+				halt:      "never",
+				socket:    defaultSocketPath,
 			},
-			expectedRem: []string{"file.txt"},
+			expectError: true,
 		},
 		{
-			name: "count bytes long flag",
-			args: []string{"--bytes", "file.txt"},
+			name: "big totals in text",
+			args: []string{"--big-totals"},
 			expectedCfg: cliConfig{
-				countBytes: true,
+				bigTotals: true,
+				jobs:      runtime.GOMAXPROCS(0),
+				bufSize:   1 * 1024 * 1024,
+				halt:      "never",
+				socket:    defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "git",
+			args: []string{"--git", "-l", "src"},
+			expectedCfg: cliConfig{
+				git:        true,
+				countLines: true,
 				jobs:       runtime.GOMAXPROCS(0),
 				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
 			},
-			expectedRem: []string{"file.txt"},
+			expectedRem: []string{"src"},
 		},
 		{
-			name: "multiple flags",
-			args: []string{"-l", "-w", "-c", "file1.txt", "file2.txt"},
+			name: "git with a file list",
+			args: []string{"--git", "--files-from=list"},
 			expectedCfg: cliConfig{
-				countLines: true,
+				git:       true,
+				filesFrom: "list",
+				jobs:      runtime.GOMAXPROCS(0),
+				bufSize:   1 * 1024 * 1024,
+				halt:      "never",
+				socket:    defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "region",
+			args: []string{"--region=10:25", "f"},
+			expectedCfg: cliConfig{
+				region:  "10:25",
+				offset:  10,
+				length:  15,
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{"f"},
+		},
+		{
+			name: "region to the end",
+			args: []string{"--region=10:"},
+			expectedCfg: cliConfig{
+				region:  "10:",
+				offset:  10,
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "empty region",
+			args: []string{"--region=10:10"},
+			expectedCfg: cliConfig{
+				region:  "10:10",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "region with offset",
+			args: []string{"--region=1:5", "--offset=2"},
+			expectedCfg: cliConfig{
+				region:  "1:5",
+				offset:  2,
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "strip markdown",
+			args: []string{"--strip=markdown", "-w", "README.md"},
+			expectedCfg: cliConfig{
+				strip:      "markdown",
 				countWords: true,
-				countBytes: true,
 				jobs:       runtime.GOMAXPROCS(0),
 				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
 			},
-			expectedRem: []string{"file1.txt", "file2.txt"},
+			expectedRem: []string{"README.md"},
 		},
 		{
-			name: "max line length flags",
-			args: []string{"-L", "--max-line-length-chars"},
+			name: "strip html",
+			args: []string{"--strip=html", "page.html"},
 			expectedCfg: cliConfig{
-				countMaxBytes: true,
-				countMaxChars: true,
-				jobs:          runtime.GOMAXPROCS(0),
-				bufSize:       1 * 1024 * 1024,
+				strip:   "html",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
 			},
-			expectedRem: []string{},
+			expectedRem: []string{"page.html"},
 		},
 		{
-			name: "custom jobs and buffer size",
-			args: []string{"-j", "4", "--buffer-size", "2048"},
+			name: "unknown strip",
+			args: []string{"--strip=rtf"},
 			expectedCfg: cliConfig{
-				jobs:    4,
-				bufSize: 2048,
+				strip:   "rtf",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
 			},
-			expectedRem: []string{},
+			expectError: true,
 		},
 		{
-			name: "files0-from and encoding",
-			args: []string{"--files0-from", "filelist.txt", "--encoding", "utf-8"},
+			name: "strip with estimate",
+			args: []string{"--strip=markdown", "--estimate"},
 			expectedCfg: cliConfig{
-				files0From: "filelist.txt",
-				encoding:   "utf-8",
+				strip:    "markdown",
+				estimate: "1",
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "extract with",
+			args: []string{"--extract-with=*.pdf=pdftotext {} -", "--extract-with", "*.docx=pandoc -t plain {}", "docs"},
+			expectedCfg: cliConfig{
+				extractWith: []string{"*.pdf=pdftotext {} -", "*.docx=pandoc -t plain {}"},
+				jobs:        runtime.GOMAXPROCS(0),
+				bufSize:     1 * 1024 * 1024,
+				halt:        "never",
+				socket:      defaultSocketPath,
+			},
+			expectedRem: []string{"docs"},
+		},
+		{
+			name: "extract with remote",
+			args: []string{"--extract-with=cat", "--remote"},
+			expectedCfg: cliConfig{
+				extractWith: []string{"cat"},
+				remote:      true,
+				jobs:        runtime.GOMAXPROCS(0),
+				bufSize:     1 * 1024 * 1024,
+				halt:        "never",
+				socket:      defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid format",
+			args: []string{"--format=xml"},
+			expectedCfg: cliConfig{
+				format:  "xml",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid group by",
+			args: []string{"--group-by=size"},
+			expectedCfg: cliConfig{
+				groupBy: "size",
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "final newline checks",
+			args: []string{"--missing-final-newline", "--require-final-newline"},
+			expectedCfg: cliConfig{
+				showNoEOL:  true,
+				requireEOL: true,
 				jobs:       runtime.GOMAXPROCS(0),
 				bufSize:    1 * 1024 * 1024,
+				halt:       "never",
+				socket:     defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "repeated count-string",
+			args: []string{"--count-string=ERROR", "--count-string", "WARN", "a.txt"},
+			expectedCfg: cliConfig{
+				countString: []string{"ERROR", "WARN"},
+				jobs:        runtime.GOMAXPROCS(0),
+				bufSize:     1 * 1024 * 1024,
+				halt:        "never",
+				socket:      defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "byte window",
+			args: []string{"--offset=1024", "--length", "4096", "a.txt"},
+			expectedCfg: cliConfig{
+				offset:  1024,
+				length:  4096,
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectedRem: []string{"a.txt"},
+		},
+		{
+			name: "early exit limits",
+			args: []string{"--max-lines=1000000", "--max-bytes", "4096"},
+			expectedCfg: cliConfig{
+				maxLines: 1000000,
+				maxBytes: 4096,
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
+			},
+			expectedRem: []string{},
+		},
+		{
+			name: "bare estimate",
+			args: []string{"--estimate", "big.log"},
+			expectedCfg: cliConfig{
+				estimate: "1",
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
+			},
+			expectedRem: []string{"big.log"},
+		},
+		{
+			name: "estimate percentage",
+			args: []string{"--estimate=0.5%"},
+			expectedCfg: cliConfig{
+				estimate: "0.5%",
+				jobs:     runtime.GOMAXPROCS(0),
+				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
 			},
 			expectedRem: []string{},
 		},
+		{
+			name: "negative offset",
+			args: []string{"--offset=-1"},
+			expectedCfg: cliConfig{
+				offset:  -1,
+				jobs:    runtime.GOMAXPROCS(0),
+				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
+			},
+			expectError: true,
+		},
+		{
+			name: "bad unique words mode",
+			args: []string{"--unique-words=fuzzy"},
+			expectedCfg: cliConfig{
+				uniqueWords: "fuzzy",
+				jobs:        runtime.GOMAXPROCS(0),
+				bufSize:     1 * 1024 * 1024,
+				halt:        "never",
+				socket:      defaultSocketPath,
+			},
+			expectError: true,
+		},
 		{
 			name: "help flag",
 			args: []string{"--help"},
@@ -95,6 +1556,8 @@ func TestParseArgs(t *testing.T) {
 				showHelp: true,
 				jobs:     runtime.GOMAXPROCS(0),
 				bufSize:  1 * 1024 * 1024,
+				halt:     "never",
+				socket:   defaultSocketPath,
 			},
 			expectedRem: []string{},
 		},
@@ -105,6 +1568,8 @@ func TestParseArgs(t *testing.T) {
 				showVer: true,
 				jobs:    runtime.GOMAXPROCS(0),
 				bufSize: 1 * 1024 * 1024,
+				halt:    "never",
+				socket:  defaultSocketPath,
 			},
 			expectedRem: []string{},
 		},
@@ -113,7 +1578,7 @@ func TestParseArgs(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			cfg, rem, err := parseArgs(tt.args)
-			
+
 			if tt.expectError && err == nil {
 				t.Error("Expected error but got none")
 				return
@@ -122,11 +1587,11 @@ func TestParseArgs(t *testing.T) {
 				t.Errorf("Unexpected error: %v", err)
 				return
 			}
-			
+
 			if !reflect.DeepEqual(cfg, tt.expectedCfg) {
 				t.Errorf("Config mismatch:\ngot:  %+v\nwant: %+v", cfg, tt.expectedCfg)
 			}
-			
+
 			if !reflect.DeepEqual(rem, tt.expectedRem) {
 				t.Errorf("Remaining args mismatch:\ngot:  %v\nwant: %v", rem, tt.expectedRem)
 			}
@@ -175,19 +1640,19 @@ func TestReadFiles0From(t *testing.T) {
 				t.Fatalf("Failed to create temp file: %v", err)
 			}
 			defer os.Remove(tmpFile.Name())
-			
+
 			// Write test content
 			if _, err := tmpFile.WriteString(tt.content); err != nil {
 				t.Fatalf("Failed to write to temp file: %v", err)
 			}
 			tmpFile.Close()
-			
+
 			// Test the function
 			result, err := readFiles0From(tmpFile.Name())
 			if err != nil {
 				t.Fatalf("readFiles0From failed: %v", err)
 			}
-			
+
 			if !reflect.DeepEqual(result, tt.expected) {
 				t.Errorf("Result mismatch:\ngot:  %v\nwant: %v", result, tt.expected)
 			}
@@ -198,30 +1663,30 @@ func TestReadFiles0From(t *testing.T) {
 func TestReadFiles0FromStdin(t *testing.T) {
 	// Test reading from stdin (represented by "-")
 	content := "file1.txt\x00file2.txt\x00"
-	
+
 	// Create a pipe to simulate stdin
 	r, w, err := os.Pipe()
 	if err != nil {
 		t.Fatalf("Failed to create pipe: %v", err)
 	}
-	
+
 	// Save original stdin and restore after test
 	origStdin := os.Stdin
 	defer func() { os.Stdin = origStdin }()
 	os.Stdin = r
-	
+
 	// Write content to pipe in a goroutine
 	go func() {
 		defer w.Close()
 		w.WriteString(content)
 	}()
-	
+
 	// Test the function
 	result, err := readFiles0From("-")
 	if err != nil {
 		t.Fatalf("readFiles0From failed: %v", err)
 	}
-	
+
 	expected := []string{"file1.txt", "file2.txt"}
 	if !reflect.DeepEqual(result, expected) {
 		t.Errorf("Result mismatch:\ngot:  %v\nwant: %v", result, expected)
@@ -235,6 +1700,210 @@ func TestReadFiles0FromNonexistentFile(t *testing.T) {
 	}
 }
 
+func TestCollectInputs(t *testing.T) {
+	list, err := os.CreateTemp("", "test_files0_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(list.Name())
+	list.WriteString("b.txt\x00c.txt\x00")
+	list.Close()
+
+	got, err := collectInputs(cliConfig{files0From: list.Name()}, []string{"a.txt"})
+	if err != nil {
+		t.Fatalf("collectInputs failed: %v", err)
+	}
+	if want := []string{"a.txt", "b.txt", "c.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	got, _ = collectInputs(cliConfig{}, nil)
+	if want := []string{"-"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("default inputs: got %v, want %v", got, want)
+	}
+}
+
+func TestCountInputsHalt(t *testing.T) {
+	dir := t.TempDir()
+	var inputs []string
+	inputs = append(inputs, filepath.Join(dir, "missing"))
+	for i := 0; i < 20; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("f%d.txt", i))
+		if err := os.WriteFile(name, []byte("x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, name)
+	}
+	cs := countSettings{metrics: wc.Metrics{Lines: true}, opts: wc.Options{BufferSize: 4096}}
+
+	cs.halt = haltNever
+	if got := countInputs(inputs, cs, 1); len(got) != len(inputs) {
+		t.Errorf("never: got %d results, want %d", len(got), len(inputs))
+	}
+
+	cs.halt = haltSoon
+	got := countInputs(inputs, cs, 1)
+	if len(got) == 0 || got[0].Err == nil {
+		t.Fatalf("soon: expected the failing input first, got %+v", got)
+	}
+	if len(got) >= len(inputs) {
+		t.Errorf("soon: got %d results, expected the run to stop early", len(got))
+	}
+	for i, r := range got {
+		if r.Index != i {
+			t.Errorf("soon: result %d has index %d", i, r.Index)
+		}
+	}
+
+	if _, _, err := parseArgs([]string{"--halt=later"}); err == nil {
+		t.Error("expected error for invalid --halt value")
+	}
+
+	// with one worker in input order, nothing after the failing input starts
+	cs.inputOrder = true
+	for _, policy := range []string{haltSoon, haltNow} {
+		cs.halt = policy
+		for run := 0; run < 50; run++ {
+			if got := countInputs(inputs, cs, 1); len(got) != 1 || got[0].Err == nil {
+				t.Fatalf("%s, run %d: got %d results, want only the failing input", policy, run, len(got))
+			}
+		}
+	}
+}
+
+func TestLargestFirst(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small")
+	big := filepath.Join(dir, "big")
+	mid := filepath.Join(dir, "mid")
+	for name, n := range map[string]int{small: 1, big: 100, mid: 10} {
+		if err := os.WriteFile(name, make([]byte, n), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := largestFirst(inputSizes([]string{small, big, "-", mid, filepath.Join(dir, "missing")}))
+	if want := []int{2, 4, 1, 3, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	cs := countSettings{metrics: wc.Metrics{Bytes: true}, opts: wc.Options{BufferSize: 4096}}
+	all := countInputs([]string{small, big, mid}, cs, 2)
+	for i, want := range []uint64{1, 100, 10} {
+		if all[i].Bytes != want || all[i].Index != i {
+			t.Errorf("result %d: got %+v, want %d bytes", i, all[i], want)
+		}
+	}
+}
+
+func TestBatchJobs(t *testing.T) {
+	sizes := []int64{10, 5000, -1, 0}
+	for i := 0; i < smallBatch+1; i++ {
+		sizes = append(sizes, 100)
+	}
+	got := batchJobs(identityOrder(len(sizes)), sizes)
+	if len(got) != 5 {
+		t.Fatalf("got %d jobs, want 5", len(got))
+	}
+	for i, want := range []struct {
+		first, n int
+		small    bool
+	}{{0, 1, true}, {1, 1, false}, {2, 1, false}, {3, smallBatch, true}, {smallBatch + 3, 2, true}} {
+		if j := got[i]; j.idx[0] != want.first || len(j.idx) != want.n || j.small != want.small {
+			t.Errorf("job %d = %+v, want first %d, %d inputs, small %v", i, j, want.first, want.n, want.small)
+		}
+	}
+	if got := batchJobs([]int{1, 0}, nil); len(got) != 2 || got[0].small || got[0].idx[0] != 1 {
+		t.Errorf("without sizes: got %+v", got)
+	}
+}
+
+func TestCountInputsSmallFiles(t *testing.T) {
+	dir := t.TempDir()
+	var inputs []string
+	for i := 0; i < 3*smallBatch; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("f%03d", i))
+		if err := os.WriteFile(name, []byte(strings.Repeat("x\n", i)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, name)
+	}
+	for _, noStat := range []bool{false, true} {
+		cs := countSettings{metrics: wc.Metrics{Lines: true, Bytes: true}, opts: wc.Options{BufferSize: 4096}, noStatSizes: noStat}
+		all := countInputs(inputs, cs, 4)
+		if len(all) != len(inputs) {
+			t.Fatalf("no stat sizes %v: got %d results, want %d", noStat, len(all), len(inputs))
+		}
+		for i, r := range all {
+			if r.Err != nil || r.Index != i || r.Filename != inputs[i] || r.Lines != uint64(i) || r.Bytes != uint64(2*i) {
+				t.Errorf("no stat sizes %v: result %d: %+v", noStat, i, r)
+			}
+		}
+	}
+}
+
+func TestListInputs(t *testing.T) {
+	var buf strings.Builder
+	listInputs(&buf, []string{"a", "b"}, false)
+	if buf.String() != "a\nb\n" {
+		t.Errorf("newline list: got %q", buf.String())
+	}
+	buf.Reset()
+	listInputs(&buf, []string{"a", "b"}, true)
+	if buf.String() != "a\x00b\x00" {
+		t.Errorf("NUL list: got %q", buf.String())
+	}
+}
+
+func TestColumnWidth(t *testing.T) {
+	dir := t.TempDir()
+	a := dir + "/a.txt"
+	b := dir + "/b.txt"
+	os.WriteFile(a, []byte(strings.Repeat("x", 600)), 0o644)
+	os.WriteFile(b, []byte(strings.Repeat("y", 500)), 0o644)
+	all := []wc.FileResult{{Filename: a, Bytes: 600}, {Filename: b, Bytes: 500}}
+	totals := wc.Sum(all)
+	m := wc.DefaultMetrics()
+
+	if w := columnWidth(cliConfig{}, []string{a, b}, all, totals, m); w != 4 {
+		t.Errorf("regular files: got %d, want 4", w)
+	}
+	if w := columnWidth(cliConfig{}, []string{a}, all[:1], all[0], wc.Metrics{Bytes: true}); w != 3 {
+		t.Errorf("single count: got %d, want 3", w)
+	}
+	stdin := []wc.FileResult{{Filename: "-", Bytes: 5}}
+	if w := columnWidth(cliConfig{}, []string{"-"}, stdin, stdin[0], m); w != 7 {
+		t.Errorf("stdin: got %d, want 7", w)
+	}
+	if w := columnWidth(cliConfig{width: 2}, []string{a, b}, all, totals, m); w != 2 {
+		t.Errorf("--width: got %d, want 2", w)
+	}
+	if w := columnWidth(cliConfig{minWidth: 9}, []string{a, b}, all, totals, m); w != 9 {
+		t.Errorf("--min-width: got %d, want 9", w)
+	}
+	if w := columnWidth(cliConfig{noAlign: true, header: true}, []string{a, b}, all, totals, m); w != 1 {
+		t.Errorf("--no-align: got %d, want 1", w)
+	}
+}
+
+func TestDisplayName(t *testing.T) {
+	tests := []struct {
+		cfg  cliConfig
+		name string
+		want string
+	}{
+		{cliConfig{}, "/a/b/c.txt", "/a/b/c.txt"},
+		{cliConfig{basename: true}, "/a/b/c.txt", "c.txt"},
+		{cliConfig{relativeTo: "/a"}, "/a/b/c.txt", "b/c.txt"},
+		{cliConfig{relativeTo: "/a/x"}, "/a/b/c.txt", "../b/c.txt"},
+		{cliConfig{basename: true}, "-", "-"},
+	}
+	for _, tt := range tests {
+		if got := displayName(tt.cfg, tt.name); got != tt.want {
+			t.Errorf("displayName(%+v, %q) = %q, want %q", tt.cfg, tt.name, got, tt.want)
+		}
+	}
+}
+
 // Test helper functions and edge cases
 func TestVersion(t *testing.T) {
 	if version == "" {
@@ -249,22 +1918,22 @@ func TestUsageDoesNotPanic(t *testing.T) {
 			t.Errorf("usage() panicked: %v", r)
 		}
 	}()
-	
+
 	// Capture output to avoid cluttering test output
 	origStdout := os.Stdout
 	r, w, _ := os.Pipe()
 	os.Stdout = w
-	
+
 	usage()
-	
+
 	w.Close()
 	os.Stdout = origStdout
-	
+
 	// Read the output to ensure it's not empty
 	buf := make([]byte, 1024)
 	n, _ := r.Read(buf)
 	output := string(buf[:n])
-	
+
 	if !strings.Contains(output, "go_wc") {
 		t.Error("Usage output should contain 'go_wc'")
 	}
@@ -273,7 +1942,7 @@ func TestUsageDoesNotPanic(t *testing.T) {
 // Benchmark tests
 func BenchmarkParseArgs(b *testing.B) {
 	args := []string{"-l", "-w", "-c", "file1.txt", "file2.txt", "file3.txt"}
-	
+
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		parseArgs(args)
@@ -288,12 +1957,12 @@ func BenchmarkReadFiles0From(b *testing.B) {
 		b.Fatalf("Failed to create temp file: %v", err)
 	}
 	defer os.Remove(tmpFile.Name())
-	
+
 	tmpFile.WriteString(content)
 	tmpFile.Close()
-	
+
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		readFiles0From(tmpFile.Name())
 	}
-}
\ No newline at end of file
+}
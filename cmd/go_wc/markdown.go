@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/format"
+)
+
+// markdownReport renders the counts for --format=markdown: a single table,
+// or with --group-by one section per group, each with its own total, and a
+// closing grand total. Failed inputs are reported on stderr.
+func markdownReport(cfg cliConfig, all []wc.FileResult, m wc.Metrics, extra []string, multiple bool) string {
+	ok := succeeded(all)
+
+	if cfg.groupBy == "" {
+		var total *wc.FileResult
+		if multiple {
+			t := wc.Sum(ok)
+			t.Filename = "total"
+			total = &t
+		}
+		return format.MarkdownTable(displayRows(cfg, ok), total, m, extra)
+	}
+
+	groups := make(map[string][]wc.FileResult)
+	for _, r := range ok {
+		key := groupKey(cfg.groupBy, r.Filename)
+		groups[key] = append(groups[key], r)
+	}
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		t := wc.Sum(groups[k])
+		t.Filename = "total"
+		fmt.Fprintf(&sb, "### %s\n\n", k)
+		sb.WriteString(format.MarkdownTable(displayRows(cfg, groups[k]), &t, m, extra))
+	}
+	t := wc.Sum(ok)
+	t.Filename = "total"
+	if len(keys) > 0 {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("### total\n\n")
+	sb.WriteString(format.MarkdownTable(nil, &t, m, extra))
+	return sb.String()
+}
+
+// succeeded reports the failed inputs on stderr and returns the others.
+func succeeded(all []wc.FileResult) []wc.FileResult {
+	reportFailures(all)
+	var ok []wc.FileResult
+	for _, r := range all {
+		if r.Err == nil {
+			ok = append(ok, r)
+		}
+	}
+	return ok
+}
+
+// displayRows applies the display name and truncation marker to results
+// for the markdown and html reports.
+func displayRows(cfg cliConfig, results []wc.FileResult) []wc.FileResult {
+	rows := make([]wc.FileResult, len(results))
+	for i, r := range results {
+		r.Filename = shownName(cfg, r.Filename)
+		if r.Truncated {
+			r.Filename += " (truncated)"
+		}
+		rows[i] = r
+	}
+	return rows
+}
+
+// groupKey names the --group-by group of a file: its extension, or its
+// directory. Files without an extension and standard input get their own
+// groups.
+func groupKey(by, name string) string {
+	if name == "-" {
+		return "(stdin)"
+	}
+	if by == "dir" {
+		return filepath.Dir(name)
+	}
+	if ext := filepath.Ext(name); ext != "" {
+		return ext
+	}
+	return "(no extension)"
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/base64"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// quoteName makes a file name safe to print in a table or message. A name
+// that is valid UTF-8 without control characters is returned as is.
+// Otherwise its invalid bytes become \xHH, control characters \n, \t, \r
+// or \xHH, and backslashes are doubled, so that the escaped form is
+// unambiguous and cannot break a line or the terminal.
+func quoteName(name string) string {
+	if utf8.ValidString(name) && strings.IndexFunc(name, unicode.IsControl) < 0 {
+		return name
+	}
+	const hex = "0123456789abcdef"
+	var sb strings.Builder
+	for i := 0; i < len(name); {
+		r, size := utf8.DecodeRuneInString(name[i:])
+		switch {
+		case r == utf8.RuneError && size == 1, r < 0x20, r == 0x7f:
+			b := name[i]
+			switch b {
+			case '\n':
+				sb.WriteString(`\n`)
+			case '\t':
+				sb.WriteString(`\t`)
+			case '\r':
+				sb.WriteString(`\r`)
+			default:
+				sb.WriteString(`\x`)
+				sb.WriteByte(hex[b>>4])
+				sb.WriteByte(hex[b&0xf])
+			}
+		case r == '\\':
+			sb.WriteString(`\\`)
+		case unicode.IsControl(r):
+			sb.WriteString(`\u`)
+			for shift := 12; shift >= 0; shift -= 4 {
+				sb.WriteByte(hex[r>>shift&0xf])
+			}
+		default:
+			sb.WriteString(name[i : i+size])
+		}
+		i += size
+	}
+	return sb.String()
+}
+
+// shownName is the name of an input as tables and messages print it.
+func shownName(cfg cliConfig, name string) string {
+	if cfg.unnamed {
+		return ""
+	}
+	if cfg.posix {
+		return name // byte for byte, as POSIX has it
+	}
+	return quoteName(displayName(cfg, name))
+}
+
+// nameBytes returns the base64 encoding of a name that is not valid UTF-8,
+// which JSON cannot carry byte-exactly, and "" for any other name.
+func nameBytes(name string) string {
+	if utf8.ValidString(name) {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString([]byte(name))
+}
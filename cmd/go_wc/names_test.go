@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestQuoteName(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"a.txt", "a.txt"},
+		{"café", "café"},
+		{`dir\a.txt`, `dir\a.txt`},
+		{"a\nb", `a\nb`},
+		{"tab\there", `tab\there`},
+		{"caf\xe9", `caf\xe9`},
+		{"caf\xe9\\x", `caf\xe9\\x`},
+		{"del\x7f", `del\x7f`},
+		{"\x1b[31mred", `\x1b[31mred`},
+		{"c1\u0085", `c1\u0085`},
+	}
+	for _, c := range cases {
+		if got := quoteName(c.in); got != c.want {
+			t.Errorf("quoteName(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestNameBytes(t *testing.T) {
+	if got := nameBytes("café"); got != "" {
+		t.Errorf("nameBytes(valid) = %q, want empty", got)
+	}
+	got := nameBytes("caf\xe9")
+	b, err := base64.StdEncoding.DecodeString(got)
+	if err != nil || string(b) != "caf\xe9" {
+		t.Errorf("nameBytes(invalid) = %q, decodes to %q, %v", got, b, err)
+	}
+}
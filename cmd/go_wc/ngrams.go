@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// defaultTopNGrams is how many n-grams --ngrams=N reports per file.
+const defaultTopNGrams = 10
+
+// parseNGrams parses an --ngrams spec, N[,topK].
+func parseNGrams(s string) (n, k int, err error) {
+	ns, ks, hasK := strings.Cut(s, ",")
+	n, err = strconv.Atoi(ns)
+	if err != nil || n < 1 {
+		return 0, 0, fmt.Errorf("invalid --ngrams size %q (want N[,topK] with N >= 1)", s)
+	}
+	k = defaultTopNGrams
+	if hasK {
+		k, err = strconv.Atoi(ks)
+		if err != nil || k < 1 {
+			return 0, 0, fmt.Errorf("invalid --ngrams top count %q (want N[,topK] with topK >= 1)", s)
+		}
+	}
+	return n, k, nil
+}
+
+// loadStopWords reads the --stopwords list: builtin:LANG or a file name.
+func loadStopWords(spec string) (wc.StopWords, error) {
+	if lang, ok := strings.CutPrefix(spec, "builtin:"); ok {
+		return wc.BuiltinStopWords(lang)
+	}
+	f, err := os.Open(spec)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return wc.ReadStopWords(f)
+}
+
+// ngramReport is the --ngram-format=json record for one file or the total.
+type ngramReport struct {
+	File   string     `json:"file"`
+	NGrams []wc.NGram `json:"ngrams"`
+}
+
+// writeNGrams prints the k most frequent n-grams of each counted file, and
+// of all of them under the name "total" when there are several, as CSV
+// rows (file,ngram,count) or one JSON array.
+func writeNGrams(w io.Writer, all []wc.FileResult, totals wc.FileResult, multiple bool, k int, format string, name func(string) string) error {
+	var reports []ngramReport
+	for _, r := range all {
+		if r.Err == nil {
+			reports = append(reports, ngramReport{File: name(r.Filename), NGrams: wc.TopNGrams(r.NGrams, k)})
+		}
+	}
+	if multiple {
+		reports = append(reports, ngramReport{File: "total", NGrams: wc.TopNGrams(totals.NGrams, k)})
+	}
+
+	if format == "json" {
+		return json.NewEncoder(w).Encode(reports)
+	}
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"file", "ngram", "count"})
+	for _, rep := range reports {
+		for _, g := range rep.NGrams {
+			_ = cw.Write([]string{rep.File, g.Text, strconv.FormatUint(g.Count, 10)})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
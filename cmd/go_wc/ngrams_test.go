@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestParseNGrams(t *testing.T) {
+	tests := []struct {
+		spec string
+		n, k int
+		ok   bool
+	}{
+		{"2", 2, defaultTopNGrams, true},
+		{"3,5", 3, 5, true},
+		{"0", 0, 0, false},
+		{"2,0", 0, 0, false},
+		{"x", 0, 0, false},
+		{"2,", 0, 0, false},
+	}
+	for _, tt := range tests {
+		n, k, err := parseNGrams(tt.spec)
+		if (err == nil) != tt.ok || n != tt.n || k != tt.k {
+			t.Errorf("parseNGrams(%q) = %d, %d, %v", tt.spec, n, k, err)
+		}
+	}
+}
+
+func TestWriteNGrams(t *testing.T) {
+	all := []wc.FileResult{
+		{Filename: "a.txt", NGrams: map[string]uint64{"of the": 3, "in a": 1, "x, y": 1}},
+		{Filename: "b.txt", NGrams: map[string]uint64{"in a": 4}},
+	}
+	totals := wc.Sum(all)
+	id := func(s string) string { return s }
+
+	var csv strings.Builder
+	if err := writeNGrams(&csv, all, totals, true, 2, "", id); err != nil {
+		t.Fatal(err)
+	}
+	want := "file,ngram,count\na.txt,of the,3\na.txt,in a,1\nb.txt,in a,4\ntotal,in a,5\ntotal,of the,3\n"
+	if csv.String() != want {
+		t.Errorf("csv:\n%s\nwant:\n%s", csv.String(), want)
+	}
+
+	var js strings.Builder
+	if err := writeNGrams(&js, all[1:], totals, false, 1, "json", id); err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"file":"b.txt","ngrams":[{"ngram":"in a","count":4}]}]` + "\n"; js.String() != want {
+		t.Errorf("json: got %s", js.String())
+	}
+}
+
+func TestLoadStopWords(t *testing.T) {
+	if sw, err := loadStopWords("builtin:de"); err != nil || len(sw) == 0 {
+		t.Errorf("builtin:de: %d words, %v", len(sw), err)
+	}
+	if _, err := loadStopWords("builtin:xx"); err == nil {
+		t.Error("builtin:xx: expected an error")
+	}
+	path := filepath.Join(t.TempDir(), "stop.txt")
+	if err := os.WriteFile(path, []byte("foo bar\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if sw, err := loadStopWords(path); err != nil || len(sw) != 2 {
+		t.Errorf("file: %v, %v", sw, err)
+	}
+	if _, err := loadStopWords(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("missing file: expected an error")
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+// outputBufferSize is how much output is collected before it is written,
+// so that a run over hundreds of thousands of files does not spend its
+// time in one write call per line.
+const outputBufferSize = 256 * 1024
+
+// bufferedOutput batches writes to f; Flush must be called before f is
+// closed or the process exits. As with stdio, a terminal is flushed at
+// every newline so that output keeps its place among messages on stderr,
+// and transcoded when it does not use UTF-8 (see consoleWriter).
+type bufferedOutput struct {
+	*bufio.Writer
+	f         *os.File
+	lineFlush bool
+}
+
+func newBufferedOutput(f *os.File) *bufferedOutput {
+	var w io.Writer = f
+	b := &bufferedOutput{f: f}
+	if st, err := f.Stat(); err == nil && st.Mode()&os.ModeCharDevice != 0 {
+		b.lineFlush = true
+		if enc := consoleEncoder(); enc != nil {
+			w = &consoleWriter{w: f, enc: enc}
+		}
+	}
+	b.Writer = bufio.NewWriterSize(w, outputBufferSize)
+	return b
+}
+
+// untranscoded makes b write what it is given as it is, to a terminal that
+// does not use UTF-8 too. It must be called before anything is written.
+func (b *bufferedOutput) untranscoded() {
+	b.Writer.Reset(b.f)
+}
+
+func (b *bufferedOutput) Write(p []byte) (int, error) {
+	n, err := b.Writer.Write(p)
+	if err == nil && b.lineFlush && n > 0 && p[n-1] == '\n' {
+		err = b.Flush()
+	}
+	return n, err
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBufferedOutput(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	b := newBufferedOutput(f)
+	if b.lineFlush {
+		t.Error("regular file should not be flushed per line")
+	}
+	for i := 0; i < 1000; i++ {
+		if _, err := b.Write([]byte("      1 name\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if st, _ := f.Stat(); st.Size() != 0 {
+		t.Errorf("%d bytes written before Flush", st.Size())
+	}
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := f.Stat(); st.Size() != 13000 {
+		t.Errorf("size after Flush = %d, want 13000", st.Size())
+	}
+	if terminalWidth(b) != 0 {
+		t.Error("terminalWidth of a buffered file should be 0")
+	}
+}
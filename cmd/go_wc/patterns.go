@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// patternSet holds the patterns of a --patterns-from file: fixed strings,
+// counted like --count-string, and regular expressions, whose matches are
+// counted within each line.
+type patternSet struct {
+	strings []string
+	regexps []*regexp.Regexp
+}
+
+// readPatterns parses a --patterns-from file: one pattern per line, blank
+// lines ignored. A line is a fixed string unless it starts with "re:",
+// which makes the rest a regular expression; "str:" marks a fixed string
+// that would otherwise start with "re:".
+func readPatterns(r io.Reader) (patternSet, error) {
+	var ps patternSet
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSuffix(sc.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if expr, ok := strings.CutPrefix(line, "re:"); ok {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return ps, fmt.Errorf("line %d: %v", n, err)
+			}
+			ps.regexps = append(ps.regexps, re)
+			continue
+		}
+		s, _ := strings.CutPrefix(line, "str:")
+		if s == "" {
+			return ps, fmt.Errorf("line %d: empty string", n)
+		}
+		ps.strings = append(ps.strings, s)
+	}
+	if err := sc.Err(); err != nil {
+		return ps, err
+	}
+	if len(ps.strings)+len(ps.regexps) == 0 {
+		return ps, errors.New("no patterns")
+	}
+	return ps, nil
+}
+
+func loadPatterns(path string) (patternSet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return patternSet{}, err
+	}
+	defer f.Close()
+	return readPatterns(f)
+}
+
+// labels names the columns of the patterns as written in the file: the
+// fixed strings, then the regular expressions.
+func (ps patternSet) labels() []string {
+	out := make([]string, 0, len(ps.strings)+len(ps.regexps))
+	for _, s := range ps.strings {
+		if strings.HasPrefix(s, "re:") {
+			s = "str:" + s
+		}
+		out = append(out, s)
+	}
+	for _, re := range ps.regexps {
+		out = append(out, "re:"+re.String())
+	}
+	return out
+}
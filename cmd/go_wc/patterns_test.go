@@ -0,0 +1,48 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestReadPatterns(t *testing.T) {
+	ps, err := readPatterns(strings.NewReader("ERROR\r\n\nre:time=\\d+ms\nstr:re:x\nWARN\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"ERROR", "re:x", "WARN"}; !slices.Equal(ps.strings, want) {
+		t.Errorf("strings = %q, want %q", ps.strings, want)
+	}
+	if len(ps.regexps) != 1 || ps.regexps[0].String() != `time=\d+ms` {
+		t.Errorf("regexps = %v", ps.regexps)
+	}
+	if want := []string{"ERROR", "str:re:x", "WARN", `re:time=\d+ms`}; !slices.Equal(ps.labels(), want) {
+		t.Errorf("labels = %q, want %q", ps.labels(), want)
+	}
+
+	for _, bad := range []string{"", "\n\n", "ok\nre:(", "str:\n"} {
+		if _, err := readPatterns(strings.NewReader(bad)); err == nil {
+			t.Errorf("readPatterns(%q): no error", bad)
+		}
+	}
+}
+
+func TestExtraCounts(t *testing.T) {
+	r := wc.FileResult{CharCounts: []uint64{1}, StringCounts: []uint64{2, 3}, RegexpCounts: []uint64{4}}
+	got := extraCounts(r, []string{"[:digit:]", "a", "b", "re:c+"})
+	want := map[string]uint64{"[:digit:]": 1, "a": 2, "b": 3, "re:c+": 4}
+	if len(got) != len(want) {
+		t.Fatalf("extraCounts = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("extraCounts[%q] = %d, want %d", k, got[k], v)
+		}
+	}
+	if extraCounts(r, nil) != nil {
+		t.Error("extraCounts without labels is not nil")
+	}
+}
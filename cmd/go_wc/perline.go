@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// lineRecord is a --per-line --format=json record, one per output line.
+type lineRecord struct {
+	Filename       string `json:"filename"`
+	FilenameBase64 string `json:"filename_base64,omitempty"` // see jsonResult
+	Line           uint64 `json:"line"`
+	Bytes          uint64 `json:"bytes"`
+	Chars          uint64 `json:"chars"`
+	Words          uint64 `json:"words"`
+}
+
+// recordEntry is a --per-record --format=json record, one per output line.
+type recordEntry struct {
+	Filename       string `json:"filename"`
+	FilenameBase64 string `json:"filename_base64,omitempty"` // see jsonResult
+	Record         uint64 `json:"record"`
+	Bytes          uint64 `json:"bytes"`
+	Chars          uint64 `json:"chars"`
+	Words          uint64 `json:"words"`
+}
+
+// lineWriter writes --per-line and --per-record records in one of the
+// formats it supports.
+type lineWriter struct {
+	format string // "text", "csv" or "json"
+	unit   string // "line" or "record"
+	width  int
+	w      io.Writer
+	csv    *csv.Writer
+	json   *json.Encoder
+}
+
+func newLineWriter(w io.Writer, cfg cliConfig) *lineWriter {
+	lw := &lineWriter{format: cfg.format, unit: "line", w: w, width: 7}
+	if cfg.perRecord {
+		lw.unit = "record"
+	}
+	switch {
+	case cfg.noAlign:
+		lw.width = 1
+	case cfg.width > 0:
+		lw.width = cfg.width
+	}
+	switch cfg.format {
+	case "csv":
+		lw.csv = csv.NewWriter(w)
+		_ = lw.csv.Write([]string{"file", lw.unit, "bytes", "chars", "words"})
+	case "json":
+		lw.json = json.NewEncoder(w)
+	default:
+		lw.format = "text"
+		if cfg.header {
+			fmt.Fprintln(w, lw.pad(lw.unit), lw.pad("bytes"), lw.pad("chars"), lw.pad("words"), "file")
+		}
+	}
+	return lw
+}
+
+func (lw *lineWriter) pad(s string) string {
+	for len(s) < lw.width {
+		s = " " + s
+	}
+	return s
+}
+
+func (lw *lineWriter) write(name string, l wc.LineCount) {
+	num := func(v uint64) string { return strconv.FormatUint(v, 10) }
+	switch lw.format {
+	case "csv":
+		_ = lw.csv.Write([]string{name, num(l.Line), num(l.Bytes), num(l.Chars), num(l.Words)})
+	case "json":
+		if lw.unit == "record" {
+			_ = lw.json.Encode(recordEntry{Filename: name, FilenameBase64: nameBytes(name), Record: l.Line, Bytes: l.Bytes, Chars: l.Chars, Words: l.Words})
+			break
+		}
+		_ = lw.json.Encode(lineRecord{Filename: name, FilenameBase64: nameBytes(name), Line: l.Line, Bytes: l.Bytes, Chars: l.Chars, Words: l.Words})
+	default:
+		fmt.Fprintln(lw.w, lw.pad(num(l.Line)), lw.pad(num(l.Bytes)), lw.pad(num(l.Chars)), lw.pad(num(l.Words)), name)
+	}
+}
+
+// flush writes out records buffered by the CSV writer and reports the first
+// write error.
+func (lw *lineWriter) flush() error {
+	if lw.csv != nil {
+		lw.csv.Flush()
+		return lw.csv.Error()
+	}
+	return nil
+}
+
+// countPerLine counts inputs one after another, writing a record for every
+// line to lw as it is read, and returns the per-input results. Standard
+// input is streamed rather than read whole, so a second "-" finds it empty.
+func countPerLine(lw *lineWriter, inputs []string, m wc.Metrics, opts wc.Options, name func(string) string) []wc.FileResult {
+	all := make([]wc.FileResult, 0, len(inputs))
+	for _, in := range inputs {
+		shown := name(in)
+		opts.OnLine = func(l wc.LineCount) { lw.write(shown, l) }
+		start := time.Now()
+		var fr wc.FileResult
+		if in == "-" {
+			fr = wc.CountReader(bufio.NewReaderSize(os.Stdin, opts.BufferSize), m, opts)
+		} else if f, err := os.Open(in); err != nil {
+			fr = wc.FileResult{Err: explainOpenError(in, err)}
+		} else {
+			fr = wc.CountReader(bufio.NewReaderSize(f, opts.BufferSize), m, opts)
+			_ = f.Close()
+		}
+		fr.Filename = in
+		fr.Duration = time.Since(start)
+		if fr.Err != nil {
+			reportFailure(in, fr.Err)
+		}
+		all = append(all, fr)
+	}
+	return all
+}
+
+// runPerLine implements --per-line and --per-record: it replaces the count
+// table with a record per line, or per record, of every input.
+func runPerLine(cfg cliConfig, inputs []string, m wc.Metrics, opts wc.Options, prof *runProfile) int {
+	runStart := time.Now()
+	outFile := os.Stdout
+	var report *outputFile
+	if cfg.output != "" {
+		var err error
+		if report, err = openOutput(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "go_wc: --output: %v\n", err)
+			return 1
+		}
+		outFile = report.File
+	}
+	out := newBufferedOutput(outFile)
+	lw := newLineWriter(out, cfg)
+	name := func(s string) string { return displayName(cfg, s) }
+	var all []wc.FileResult
+	if cfg.perRecord {
+		all = countPerRecord(lw, inputs, cfg, m, opts, name)
+	} else {
+		all = countPerLine(lw, inputs, m, opts, name)
+	}
+	if prof != nil {
+		prof.counted = time.Now()
+	}
+
+	var exitCode int
+	for _, r := range all {
+		if r.Err != nil {
+			exitCode = 1
+		}
+	}
+	err := lw.flush()
+	if ferr := out.Flush(); err == nil {
+		err = ferr
+	}
+	if report != nil {
+		if err != nil {
+			report.abort()
+		} else {
+			err = report.commit()
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "go_wc: %v\n", err)
+		exitCode = 1
+	}
+	return finishRun(cfg, all, runStart, 1, 0, cfg.skipped, exitCode, prof)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+func TestCountPerLine(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(a, []byte("one two\nhéllo\n\nlast"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := wc.Options{BufferSize: 4, Locale: locale.Info{IsUTF8: true}}
+	base := func(s string) string { return filepath.Base(s) }
+
+	tests := []struct {
+		cfg  cliConfig
+		want string
+	}{
+		{cliConfig{noAlign: true}, "1 7 7 2 a.txt\n2 6 5 1 a.txt\n3 0 0 0 a.txt\n4 4 4 1 a.txt\n"},
+		{cliConfig{width: 2, header: true}, "line bytes chars words file\n 1  7  7  2 a.txt\n 2  6  5  1 a.txt\n 3  0  0  0 a.txt\n 4  4  4  1 a.txt\n"},
+		{cliConfig{format: "csv"}, "file,line,bytes,chars,words\na.txt,1,7,7,2\na.txt,2,6,5,1\na.txt,3,0,0,0\na.txt,4,4,4,1\n"},
+		{cliConfig{format: "json"}, `{"filename":"a.txt","line":1,"bytes":7,"chars":7,"words":2}
+{"filename":"a.txt","line":2,"bytes":6,"chars":5,"words":1}
+{"filename":"a.txt","line":3,"bytes":0,"chars":0,"words":0}
+{"filename":"a.txt","line":4,"bytes":4,"chars":4,"words":1}
+`},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		lw := newLineWriter(&out, tt.cfg)
+		all := countPerLine(lw, []string{a}, wc.DefaultMetrics(), opts, base)
+		if err := lw.flush(); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("%+v:\n%s\nwant:\n%s", tt.cfg, out.String(), tt.want)
+		}
+		if len(all) != 1 || all[0].Err != nil || all[0].Lines != 3 || all[0].Words != 4 {
+			t.Errorf("%+v: results %+v", tt.cfg, all)
+		}
+	}
+
+	var out strings.Builder
+	all := countPerLine(newLineWriter(&out, cliConfig{}), []string{filepath.Join(dir, "missing")}, wc.DefaultMetrics(), opts, base)
+	if len(all) != 1 || all[0].Err == nil || out.Len() != 0 {
+		t.Errorf("missing file: results %+v, output %q", all, out.String())
+	}
+}
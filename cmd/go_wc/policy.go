@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+const defaultPolicyFile = ".wc-policy.yaml"
+
+// policyRule holds the budgets declared for one glob.
+type policyRule struct {
+	glob   string
+	re     *regexp.Regexp
+	limits map[string]uint64 // budget key (e.g. "max_words") -> limit
+}
+
+// policyLimits maps budget keys to the metric they constrain.
+var policyLimits = map[string]struct {
+	enable func(*wc.Metrics)
+	value  func(wc.FileResult) uint64
+}{
+	"max_lines":       {func(m *wc.Metrics) { m.Lines = true }, func(r wc.FileResult) uint64 { return r.Lines }},
+	"max_words":       {func(m *wc.Metrics) { m.Words = true }, func(r wc.FileResult) uint64 { return r.Words }},
+	"max_chars":       {func(m *wc.Metrics) { m.Chars = true }, func(r wc.FileResult) uint64 { return r.Chars }},
+	"max_bytes":       {func(m *wc.Metrics) { m.Bytes = true }, func(r wc.FileResult) uint64 { return r.Bytes }},
+	"max_line_length": {func(m *wc.Metrics) { m.MaxLineBytes = true }, func(r wc.FileResult) uint64 { return r.MaxLineBytes }},
+}
+
+// parsePolicy reads the small YAML subset used by policy files: top-level
+// glob keys, each followed by indented "max_*: N" budgets.
+//
+//	docs/**.md:
+//	  max_words: 5000
+//	"*.go":
+//	  max_lines: 1000
+func parsePolicy(r io.Reader) ([]policyRule, error) {
+	var rules []policyRule
+	sc := bufio.NewScanner(r)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		raw := sc.Text()
+		if i := strings.Index(raw, " #"); i >= 0 {
+			raw = raw[:i]
+		}
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := cutKey(line)
+		if !ok {
+			return nil, fmt.Errorf("policy line %d: expected \"key: value\"", lineNo)
+		}
+		if raw[0] != ' ' && raw[0] != '\t' {
+			if value != "" {
+				return nil, fmt.Errorf("policy line %d: glob %q must be followed by indented budgets", lineNo, key)
+			}
+			re, err := globRegexp(key)
+			if err != nil {
+				return nil, fmt.Errorf("policy line %d: %v", lineNo, err)
+			}
+			rules = append(rules, policyRule{glob: key, re: re, limits: map[string]uint64{}})
+			continue
+		}
+		if len(rules) == 0 {
+			return nil, fmt.Errorf("policy line %d: budget outside of a glob", lineNo)
+		}
+		if _, known := policyLimits[key]; !known {
+			return nil, fmt.Errorf("policy line %d: unknown budget %q", lineNo, key)
+		}
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("policy line %d: %s: %v", lineNo, key, err)
+		}
+		rules[len(rules)-1].limits[key] = n
+	}
+	return rules, sc.Err()
+}
+
+// cutKey splits "key: value", unquoting the key.
+func cutKey(line string) (string, string, bool) {
+	var key string
+	if line[0] == '"' || line[0] == '\'' {
+		end := strings.IndexByte(line[1:], line[0])
+		if end < 0 {
+			return "", "", false
+		}
+		key = line[1 : end+1]
+		line = line[end+2:]
+		if !strings.HasPrefix(line, ":") {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(line[1:]), true
+	}
+	i := strings.LastIndex(line, ":")
+	if i < 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]), true
+}
+
+// globRegexp compiles a slash-separated glob where "**" crosses directories.
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && i+1 < len(glob) && glob[i+1] == '*':
+			i++
+			if i+1 < len(glob) && glob[i+1] == '/' {
+				i++
+				b.WriteString("(?:.*/)?")
+			} else {
+				b.WriteString(".*")
+			}
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// policyMetrics returns the metrics needed to evaluate rules.
+func policyMetrics(rules []policyRule) wc.Metrics {
+	var m wc.Metrics
+	for _, r := range rules {
+		for key := range r.limits {
+			policyLimits[key].enable(&m)
+		}
+	}
+	return m
+}
+
+// policyViolation is a count of a file over the budget of a rule.
+type policyViolation struct {
+	path   string // slash-separated, relative to the root
+	metric string // e.g. "words"
+	value  uint64
+	limit  uint64
+	glob   string
+}
+
+// message describes v without its file.
+func (v policyViolation) message() string {
+	return fmt.Sprintf("%s %d exceeds %d (%s)", v.metric, v.value, v.limit, v.glob)
+}
+
+// policyViolations checks a counted file against every matching rule.
+func policyViolations(rules []policyRule, rel string, r wc.FileResult) []policyViolation {
+	var out []policyViolation
+	for _, rule := range rules {
+		if !rule.re.MatchString(rel) {
+			continue
+		}
+		for _, key := range sortedKeys(rule.limits) {
+			limit := rule.limits[key]
+			if v := policyLimits[key].value(r); v > limit {
+				out = append(out, policyViolation{rel, strings.TrimPrefix(key, "max_"), v, limit, rule.glob})
+			}
+		}
+	}
+	return out
+}
+
+// violationFormats are the --format values of "go_wc check": text, one
+// "FILE: MESSAGE" line per violation; github-annotations, workflow
+// commands that GitHub Actions shows as warnings on the lines of a pull
+// request; and gnu, the "FILE:LINE: warning: MESSAGE" of compilers, which
+// problem matchers, Vim's errorformat and Emacs's compilation mode read.
+// The text format names files relative to the root; the others name them
+// by their path from the working directory, where CI and editors look for
+// them, at line 1, as a budget is about the whole file.
+var violationFormats = map[string]func(file string, v policyViolation) string{
+	"text": func(file string, v policyViolation) string {
+		return file + ": " + v.message()
+	},
+	"github-annotations": func(file string, v policyViolation) string {
+		return fmt.Sprintf("::warning file=%s,line=1,title=%s::%s",
+			escapeAnnotation(file, true), escapeAnnotation("go_wc: "+v.metric+" budget exceeded", true), escapeAnnotation(v.message(), false))
+	},
+	"gnu": func(file string, v policyViolation) string {
+		return file + ":1: warning: " + v.message()
+	},
+}
+
+// escapeAnnotation escapes s for a GitHub Actions workflow command, as its
+// message or, with property, as the value of one of its properties.
+func escapeAnnotation(s string, property bool) string {
+	s = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+	if property {
+		s = strings.NewReplacer(":", "%3A", ",", "%2C").Replace(s)
+	}
+	return s
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// policyFiles lists the files w finds matched by any rule, as slash paths
+// relative to its root.
+func policyFiles(w *wc.Walker, rules []policyRule) ([]string, error) {
+	var out []string
+	err := w.Walk(func(rel string) error {
+		for _, r := range rules {
+			if r.re.MatchString(rel) {
+				out = append(out, rel)
+				break
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+// runCheck implements "go_wc check": count files covered by the policy and
+// report budget violations, exiting 1 if there are any.
+func runCheck(args []string, stdout, stderr io.Writer) int {
+	fset := flag.NewFlagSet("go_wc check", flag.ContinueOnError)
+	fset.SetOutput(io.Discard)
+	policyPath := fset.String("policy", defaultPolicyFile, "")
+	root := fset.String("root", ".", "")
+	jobs := fset.Int("jobs", runtime.GOMAXPROCS(0), "")
+	fset.IntVar(jobs, "j", runtime.GOMAXPROCS(0), "")
+	encoding := fset.String("encoding", "", "")
+	restrict := fset.Bool("restrict-to-root", false, "")
+	formatName := fset.String("format", "text", "")
+	if err := fset.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	format, ok := violationFormats[*formatName]
+	if !ok {
+		fmt.Fprintf(stderr, "go_wc: check: unsupported format %q (want text, github-annotations or gnu)\n", *formatName)
+		return 2
+	}
+
+	f, err := os.Open(*policyPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "go_wc: %v\n", err)
+		return 2
+	}
+	rules, err := parsePolicy(f)
+	_ = f.Close()
+	if err != nil {
+		fmt.Fprintf(stderr, "go_wc: %s: %v\n", *policyPath, err)
+		return 2
+	}
+
+	walker := &wc.Walker{
+		Root:           *root,
+		RestrictToRoot: *restrict,
+		SkipDir:        func(rel string) bool { return path.Base(rel) == ".git" },
+	}
+	defer walker.Close()
+	rels := fset.Args()
+	if len(rels) == 0 {
+		if rels, err = policyFiles(walker, rules); err != nil {
+			fmt.Fprintf(stderr, "go_wc: %v\n", err)
+			return 2
+		}
+	}
+	paths := make([]string, len(rels))
+	for i, rel := range rels {
+		paths[i] = filepath.Join(*root, filepath.FromSlash(rel))
+	}
+
+	cs := countSettings{
+		metrics: policyMetrics(rules),
+		opts:    wc.Options{BufferSize: 1024 * 1024, Locale: locale.Detect(*encoding)},
+	}
+	if *restrict {
+		// counted files, named ones included, are opened beneath the root
+		cs.opts.Open = func(name string) (*os.File, error) {
+			rel, err := filepath.Rel(*root, name)
+			if err != nil {
+				return nil, err
+			}
+			return walker.Open(rel)
+		}
+	}
+	exit := 0
+	for i, r := range countInputs(paths, cs, *jobs) {
+		if r.Err != nil {
+			fmt.Fprintf(stderr, "go_wc: %s: %v\n", r.Filename, r.Err)
+			exit = 2
+			continue
+		}
+		file := filepath.ToSlash(filepath.Join(*root, filepath.FromSlash(rels[i])))
+		if *formatName == "text" {
+			file = filepath.ToSlash(rels[i])
+		}
+		for _, v := range policyViolations(rules, filepath.ToSlash(rels[i]), r) {
+			fmt.Fprintln(stdout, format(file, v))
+			if exit == 0 {
+				exit = 1
+			}
+		}
+	}
+	return exit
+}
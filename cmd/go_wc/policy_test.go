@@ -0,0 +1,178 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+const testPolicy = `# budgets
+docs/**.md:
+  max_words: 5   # keep it short
+"*.go":
+  max_lines: 2
+  max_bytes: 100
+`
+
+func TestParsePolicy(t *testing.T) {
+	rules, err := parsePolicy(strings.NewReader(testPolicy))
+	if err != nil {
+		t.Fatalf("parsePolicy failed: %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("got %d rules, want 2", len(rules))
+	}
+	if rules[0].glob != "docs/**.md" || rules[0].limits["max_words"] != 5 {
+		t.Errorf("rule 0: %+v", rules[0])
+	}
+	if rules[1].glob != "*.go" || rules[1].limits["max_lines"] != 2 || rules[1].limits["max_bytes"] != 100 {
+		t.Errorf("rule 1: %+v", rules[1])
+	}
+	if m := policyMetrics(rules); m != (wc.Metrics{Words: true, Lines: true, Bytes: true}) {
+		t.Errorf("policyMetrics: %+v", m)
+	}
+
+	for _, bad := range []string{"  max_words: 5\n", "x:\n  max_fish: 1\n", "x:\n  max_words: lots\n", "x: 5\n"} {
+		if _, err := parsePolicy(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestGlobRegexp(t *testing.T) {
+	tests := []struct {
+		glob, path string
+		want       bool
+	}{
+		{"docs/**.md", "docs/a.md", true},
+		{"docs/**.md", "docs/x/y/a.md", true},
+		{"docs/**/*.md", "docs/a.md", true},
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"a?.txt", "ab.txt", true},
+		{"a?.txt", "a/.txt", false},
+	}
+	for _, tt := range tests {
+		re, err := globRegexp(tt.glob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := re.MatchString(tt.path); got != tt.want {
+			t.Errorf("%q matching %q = %v, want %v", tt.glob, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRunCheck(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "docs", "sub"), 0o755)
+	os.WriteFile(filepath.Join(root, "docs", "ok.md"), []byte("one two\n"), 0o644)
+	os.WriteFile(filepath.Join(root, "docs", "sub", "long.md"), []byte("a b c d e f g\n"), 0o644)
+	os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0o644)
+	policy := filepath.Join(root, ".wc-policy.yaml")
+	os.WriteFile(policy, []byte(testPolicy), 0o644)
+
+	files, err := policyFiles(&wc.Walker{Root: root}, mustParse(t, testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"docs/ok.md", "docs/sub/long.md", "main.go"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("policyFiles: got %v, want %v", files, want)
+	}
+
+	var stdout, stderr strings.Builder
+	code := runCheck([]string{"--policy", policy, "--root", root}, &stdout, &stderr)
+	if code != 1 {
+		t.Errorf("exit code: got %d, want 1 (stderr %q)", code, stderr.String())
+	}
+	if got := stdout.String(); got != "docs/sub/long.md: words 7 exceeds 5 (docs/**.md)\n" {
+		t.Errorf("violations: got %q", got)
+	}
+
+	stdout.Reset()
+	if code := runCheck([]string{"--policy", policy, "--root", root, "docs/ok.md"}, &stdout, &stderr); code != 0 {
+		t.Errorf("clean check: got exit %d, output %q", code, stdout.String())
+	}
+}
+
+func TestRunCheckFormats(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "docs", "sub"), 0o755)
+	os.WriteFile(filepath.Join(root, "docs", "sub", "long.md"), []byte("a b c d e f g\n"), 0o644)
+	policy := filepath.Join(root, ".wc-policy.yaml")
+	os.WriteFile(policy, []byte(testPolicy), 0o644)
+	file := filepath.ToSlash(filepath.Join(root, "docs", "sub", "long.md"))
+
+	for format, want := range map[string]string{
+		"github-annotations": "::warning file=" + escapeAnnotation(file, true) + ",line=1,title=go_wc%3A words budget exceeded::words 7 exceeds 5 (docs/**.md)\n",
+		"gnu":                file + ":1: warning: words 7 exceeds 5 (docs/**.md)\n",
+	} {
+		var stdout, stderr strings.Builder
+		if code := runCheck([]string{"--policy", policy, "--root", root, "--format", format}, &stdout, &stderr); code != 1 {
+			t.Errorf("%s: exit code %d, want 1 (stderr %q)", format, code, stderr.String())
+		}
+		if got := stdout.String(); got != want {
+			t.Errorf("%s: got %q, want %q", format, got, want)
+		}
+	}
+	var stdout, stderr strings.Builder
+	if code := runCheck([]string{"--policy", policy, "--root", root, "--format", "xml"}, &stdout, &stderr); code != 2 {
+		t.Errorf("unknown format: exit code %d, want 2", code)
+	}
+}
+
+func TestEscapeAnnotation(t *testing.T) {
+	if got := escapeAnnotation("50% a,b: c\r\nd", false); got != "50%25 a,b: c%0D%0Ad" {
+		t.Errorf("message: got %q", got)
+	}
+	if got := escapeAnnotation("C:/x,y%", true); got != "C%3A/x%2Cy%25" {
+		t.Errorf("property: got %q", got)
+	}
+}
+
+func TestRunCheckRestrictToRoot(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges")
+	}
+	outside := filepath.Join(t.TempDir(), "secret.md")
+	os.WriteFile(outside, []byte("a b c d e f g\n"), 0o644)
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "docs"), 0o755)
+	if err := os.Symlink(outside, filepath.Join(root, "docs", "leak.md")); err != nil {
+		t.Fatal(err)
+	}
+	policy := filepath.Join(root, ".wc-policy.yaml")
+	os.WriteFile(policy, []byte(testPolicy), 0o644)
+
+	var stdout, stderr strings.Builder
+	if code := runCheck([]string{"--policy", policy, "--root", root}, &stdout, &stderr); code != 1 {
+		t.Errorf("unrestricted: exit %d, want the link followed to a violation (stderr %q)", code, stderr.String())
+	}
+	stdout.Reset()
+	stderr.Reset()
+	for _, args := range [][]string{nil, {"docs/leak.md"}, {"../" + filepath.Base(filepath.Dir(outside)) + "/secret.md"}} {
+		args = append([]string{"--policy", policy, "--root", root, "--restrict-to-root"}, args...)
+		if code := runCheck(args, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "escapes the root") {
+			t.Errorf("%v: exit %d, stderr %q; want exit 2 for a path escaping the root", args[6:], code, stderr.String())
+		}
+		if stdout.Len() > 0 {
+			t.Errorf("%v: counted the file outside: %q", args[6:], stdout.String())
+		}
+		stdout.Reset()
+		stderr.Reset()
+	}
+}
+
+func mustParse(t *testing.T, s string) []policyRule {
+	t.Helper()
+	rules, err := parsePolicy(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rules
+}
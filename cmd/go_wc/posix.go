@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// posixEnv is the environment variable that, set to anything, has go_wc
+// print and count as POSIX specifies, as --posix does, while still taking
+// the options of GNU wc and go_wc's commands.
+const posixEnv = "POSIXLY_CORRECT"
+
+// posixUsage is the synopsis POSIX gives wc, reported on usage errors in
+// POSIX mode in place of the full help.
+const posixUsage = "usage: go_wc [-c|-m] [-lw] [file...]"
+
+// posixEnabled reports whether POSIXLY_CORRECT is set.
+func posixEnabled() bool {
+	return os.Getenv(posixEnv) != ""
+}
+
+// posixMode reports whether args, the count command's command line, ask
+// for POSIX mode by --posix among the options.
+func posixMode(args []string) bool {
+	for _, a := range args {
+		if a == "--" || a == "-" || !strings.HasPrefix(a, "-") {
+			break
+		}
+		if a == "--posix" || a == "-posix" {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePOSIXArgs parses the command line of wc as POSIX specifies it: the
+// options -c, -l, -m and -w, which may be grouped as in -lw, and --posix,
+// then the file operands, after "--" if one may start with '-'. Every
+// other option is an extension, and an error, but for --help and
+// --version, which GNU wc keeps in POSIX mode too. The counts are printed
+// separated by single spaces, as with --no-align.
+func parsePOSIXArgs(args []string) (cliConfig, []string, error) {
+	var cfg cliConfig
+	countFlags(&cfg) // sets the defaults
+	cfg.posix, cfg.noAlign = true, true
+	for len(args) > 0 {
+		a := args[0]
+		if a == "--" {
+			args = args[1:]
+			break
+		}
+		if a == "-" || !strings.HasPrefix(a, "-") {
+			break
+		}
+		args = args[1:]
+		switch a {
+		case "--posix", "-posix":
+			continue
+		case "--help", "-help":
+			cfg.showHelp = true
+			continue
+		case "--version", "-version":
+			cfg.showVer = true
+			continue
+		}
+		if strings.HasPrefix(a, "--") {
+			return cfg, nil, fmt.Errorf("--posix: %s is not a POSIX option (want -c, -l, -m or -w)", a)
+		}
+		for _, o := range a[1:] {
+			switch o {
+			case 'c':
+				cfg.countBytes = true
+			case 'l':
+				cfg.countLines = true
+			case 'm':
+				cfg.countChars = true
+			case 'w':
+				cfg.countWords = true
+			default:
+				return cfg, nil, fmt.Errorf("--posix: -%c is not a POSIX option (want -c, -l, -m or -w)", o)
+			}
+		}
+	}
+	if cfg.countBytes && cfg.countChars {
+		return cfg, nil, errors.New("--posix: -c and -m cannot be combined")
+	}
+	return checkArgs(cfg, args)
+}
+
+// posixlyCorrect gives cfg, parsed and checked as any count command line,
+// the output of POSIX mode for POSIXLY_CORRECT: single spaces between the
+// counts unless a width or --compat says otherwise, standard input
+// unnamed, names byte for byte and glibc's white space. Options are
+// already taken in POSIX order, the first operand ending them.
+func posixlyCorrect(cfg *cliConfig) {
+	cfg.posix = true
+	if cfg.width == 0 && cfg.minWidth == 0 && cfg.compat == "" {
+		cfg.noAlign = true
+	}
+}
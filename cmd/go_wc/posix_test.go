@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestParsePOSIXArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		env     bool
+		set     string // the counts set, of "clmw"
+		rem     []string
+		wantErr bool
+	}{
+		{args: []string{"--posix", "-lw", "-c", "a"}, set: "clw", rem: []string{"a"}},
+		{args: []string{"-m", "--posix", "--", "-l"}, set: "m", rem: []string{"-l"}},
+		{args: []string{"-w", "-", "-l"}, env: true, set: "w", rem: []string{"-", "-l"}},
+		{args: []string{"serve"}, env: true, rem: []string{"serve"}},
+		{args: []string{"--posix", "-L"}, wantErr: true},
+		{args: []string{"--lines", "-"}, env: true, set: "l", rem: []string{"-"}},
+		{args: []string{"--posix", "-L"}, env: true, wantErr: true},
+		{args: []string{"--width", "3", "--posix"}, wantErr: true},
+		{args: []string{"--posix", "-cm"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		if tt.env {
+			t.Setenv(posixEnv, "1")
+		} else {
+			t.Setenv(posixEnv, "")
+		}
+		cfg, rem, err := parseArgs(tt.args)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error", tt.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		set := ""
+		for _, c := range []struct {
+			name string
+			on   bool
+		}{{"c", cfg.countBytes}, {"l", cfg.countLines}, {"m", cfg.countChars}, {"w", cfg.countWords}} {
+			if c.on {
+				set += c.name
+			}
+		}
+		if cfg.showHelp || cfg.showVer || !cfg.posix || !cfg.noAlign || set != tt.set || !reflect.DeepEqual(rem, tt.rem) {
+			t.Errorf("%q: got posix %v, no-align %v, counts %q, operands %q; want counts %q, operands %q",
+				tt.args, cfg.posix, cfg.noAlign, set, rem, tt.set, tt.rem)
+		}
+	}
+}
+
+func TestPOSIXHelpVersion(t *testing.T) {
+	for _, env := range []string{"", "1"} {
+		t.Setenv(posixEnv, env)
+		for _, args := range [][]string{{"--posix", "--help"}, {"--help"}, {"--posix", "--version"}, {"--version"}} {
+			cfg, _, err := parseArgs(args)
+			if err != nil {
+				t.Errorf("POSIXLY_CORRECT=%q %q: %v", env, args, err)
+				continue
+			}
+			if want := args[len(args)-1] == "--help"; cfg.showHelp != want || cfg.showVer == want {
+				t.Errorf("POSIXLY_CORRECT=%q %q: got help %v, version %v", env, args, cfg.showHelp, cfg.showVer)
+			}
+		}
+	}
+
+	t.Setenv(posixEnv, "1")
+	cfg, _, err := parseArgs([]string{"--width", "3"})
+	if err != nil || !cfg.posix || cfg.noAlign || cfg.width != 3 {
+		t.Errorf("POSIXLY_CORRECT --width 3: got posix %v, no-align %v, width %d, err %v", cfg.posix, cfg.noAlign, cfg.width, err)
+	}
+}
+
+func TestPrintTextPOSIX(t *testing.T) {
+	m := wc.DefaultMetrics()
+	all := []wc.FileResult{{Filename: "a\tb", Lines: 2, Words: 3, Bytes: 12}, {Index: 1, Filename: "c", Lines: 10, Words: 1, Bytes: 1}}
+	totals := wc.Sum(all)
+	cfg := cliConfig{posix: true, noAlign: true}
+	var out bytes.Buffer
+	printText(&out, cfg, []string{"a\tb", "c"}, all, totals, m, nil, nil)
+	if want := "2 3 12 a\tb\n10 1 1 c\n12 4 13 total\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	cfg.unnamed = true
+	all = []wc.FileResult{{Filename: "-", Lines: 1, Words: 1, Bytes: 2}}
+	printText(&out, cfg, []string{"-"}, all, all[0], m, nil, nil)
+	if want := "1 1 2\n"; out.String() != want {
+		t.Errorf("standard input: got %q, want %q", out.String(), want)
+	}
+}
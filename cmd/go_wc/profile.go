@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// runProfile collects the --profile-summary timings of a run. The counting
+// phases come from the counters through wc.Profile; discovery and
+// formatting are timed here.
+type runProfile struct {
+	wc.Profile
+	start     time.Time     // input discovery began
+	discovery time.Duration // collecting and stat'ing the inputs
+	counted   time.Time     // counting ended and formatting began
+	remote    bool          // the daemon counted, so there are no counting phases
+}
+
+// writeProfile writes the summary of p, for a run ending at end, in the
+// format of --stats. The counting phases are summed over the workers, so
+// with more than one they may add up to more than the wall time.
+func writeProfile(w io.Writer, p *runProfile, end time.Time, workers int) error {
+	var sb strings.Builder
+	phase := func(name string, d time.Duration) {
+		fmt.Fprintf(&sb, "%-12s%8.3fs\n", name+":", d.Seconds())
+	}
+	sb.WriteString("profile:\n")
+	phase("discovery", p.discovery)
+	if p.remote {
+		sb.WriteString("counting:   by the daemon, not profiled\n")
+	} else {
+		phase("I/O wait", p.ReadWait())
+		phase("decode", p.Decode())
+		phase("word-scan", p.Scan())
+	}
+	var formatting time.Duration
+	if !p.counted.IsZero() {
+		formatting = end.Sub(p.counted)
+	}
+	phase("formatting", formatting)
+	phase("wall time", end.Sub(p.start))
+	if user, sys, rss, ok := resourceUsage(); ok {
+		fmt.Fprintf(&sb, "cpu time:   %8.3fs (user %.3fs, system %.3fs)\n", (user + sys).Seconds(), user.Seconds(), sys.Seconds())
+		if rss > 0 {
+			fmt.Fprintf(&sb, "peak rss:   %8.1f MiB\n", float64(rss)/(1<<20))
+		}
+	}
+	if workers > 1 && !p.remote {
+		fmt.Fprintf(&sb, "(I/O wait, decode and word-scan are summed over %d workers)\n", workers)
+	}
+	if v := p.verdict(); v != "" {
+		sb.WriteString(v + "\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// verdict says whether counting mostly waited for input or mostly used the
+// CPU, or "" if it was not profiled or too short to tell.
+func (p *runProfile) verdict() string {
+	wait, cpu := p.ReadWait(), p.Decode()+p.Scan()
+	if p.remote || wait+cpu < time.Millisecond {
+		return ""
+	}
+	share := 100 * wait.Seconds() / (wait + cpu).Seconds()
+	if wait > cpu {
+		return fmt.Sprintf("bound by:   I/O (%.0f%% of counting time waiting for input)", share)
+	}
+	return fmt.Sprintf("bound by:   CPU (%.0f%% of counting time decoding and scanning)", 100-share)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteProfile(t *testing.T) {
+	start := time.Now()
+	p := &runProfile{start: start, discovery: 5 * time.Millisecond, counted: start.Add(time.Second)}
+	p.AddReadWait(3 * time.Second)
+	var sb strings.Builder
+	if err := writeProfile(&sb, p, start.Add(1500*time.Millisecond), 4); err != nil {
+		t.Fatal(err)
+	}
+	got := sb.String()
+	for _, want := range []string{
+		"discovery:     0.005s\n",
+		"I/O wait:      3.000s\n",
+		"decode:        0.000s\n",
+		"formatting:    0.500s\n",
+		"wall time:     1.500s\n",
+		"summed over 4 workers",
+		"bound by:   I/O (100% of counting time waiting for input)\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("profile lacks %q:\n%s", want, got)
+		}
+	}
+
+	remote := &runProfile{start: start, remote: true}
+	sb.Reset()
+	if err := writeProfile(&sb, remote, start, 4); err != nil {
+		t.Fatal(err)
+	}
+	if got := sb.String(); !strings.Contains(got, "by the daemon") || strings.Contains(got, "I/O wait") || strings.Contains(got, "bound by") {
+		t.Errorf("remote profile:\n%s", got)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// parseSeparator decodes a --record-separator value: its characters as
+// they are, and Go escapes such as \t, \x1e or \u241e, plus \0 for NUL.
+func parseSeparator(spec string) ([]byte, error) {
+	var sep []byte
+	for s := spec; s != ""; {
+		if len(s) >= 2 && s[:2] == `\0` && (len(s) == 2 || s[2] < '0' || s[2] > '7') {
+			sep = append(sep, 0)
+			s = s[2:]
+			continue
+		}
+		r, multibyte, tail, err := strconv.UnquoteChar(s, '"')
+		if err != nil {
+			return nil, fmt.Errorf("invalid escape in %q", spec)
+		}
+		if r < utf8.RuneSelf || multibyte {
+			sep = utf8.AppendRune(sep, r)
+		} else {
+			sep = append(sep, byte(r)) // \xHH or an octal escape: a byte
+		}
+		s = tail
+	}
+	if len(sep) == 0 {
+		return nil, fmt.Errorf("empty separator")
+	}
+	return sep, nil
+}
+
+// recordSplitter cuts the bytes written to it into records and passes the
+// counts of each, its terminator excluded, to emit.
+type recordSplitter struct {
+	mode    string // "csv", "jsonl", or "" for records ending at sep
+	sep     []byte
+	opts    wc.Options
+	emit    func(wc.LineCount)
+	n       uint64
+	pending []byte // the unfinished record
+	scanned int    // bytes of pending already searched for its end
+	quoted  bool   // csv: the scanned bytes end inside a quoted field
+}
+
+func newRecordSplitter(cfg cliConfig, opts wc.Options, emit func(wc.LineCount)) *recordSplitter {
+	s := &recordSplitter{mode: cfg.records, sep: []byte("\n"), emit: emit}
+	if cfg.recordSep != "" {
+		s.sep, _ = parseSeparator(cfg.recordSep)
+	}
+	opts.OnLine, opts.Progress, opts.Profile = nil, nil, nil
+	s.opts = opts
+	return s
+}
+
+// Write takes in the next bytes of the input. It never fails.
+func (s *recordSplitter) Write(p []byte) (int, error) {
+	s.pending = append(s.pending, p...)
+	start := 0
+	for {
+		end, next := s.cut(start)
+		if end < 0 {
+			break
+		}
+		s.record(s.pending[start:end])
+		start = next
+		s.scanned = next
+	}
+	s.pending = append(s.pending[:0], s.pending[start:]...)
+	s.scanned -= start
+	return len(p), nil
+}
+
+// cut finds the end of the record starting at start in pending, returning
+// where it ends and where the next one starts, or -1 for an unfinished one.
+func (s *recordSplitter) cut(start int) (end, next int) {
+	if s.mode != "csv" {
+		from := max(start, s.scanned-len(s.sep)+1)
+		if i := bytes.Index(s.pending[from:], s.sep); i >= 0 {
+			end = from + i
+			return end, end + len(s.sep)
+		}
+		s.scanned = len(s.pending)
+		return -1, 0
+	}
+	for i := max(start, s.scanned); i < len(s.pending); i++ {
+		switch s.pending[i] {
+		case '"':
+			s.quoted = !s.quoted
+		case '\n':
+			if s.quoted {
+				continue
+			}
+			end = i
+			if end > start && s.pending[end-1] == '\r' {
+				end--
+			}
+			return end, i + 1
+		}
+	}
+	s.scanned = len(s.pending)
+	return -1, 0
+}
+
+// record counts a record, bar a blank jsonl line, which is none.
+func (s *recordSplitter) record(rec []byte) {
+	if s.mode == "jsonl" {
+		rec = bytes.TrimSuffix(rec, []byte("\r"))
+		if len(bytes.TrimSpace(rec)) == 0 {
+			return
+		}
+	}
+	s.n++
+	c := wc.NewCounter(wc.Metrics{Words: true, Chars: true}, s.opts)
+	c.Write(rec)
+	r := c.Result()
+	s.emit(wc.LineCount{Line: s.n, Bytes: uint64(len(rec)), Chars: r.Chars, Words: r.Words})
+}
+
+// close counts an unterminated last record at the end of the input.
+func (s *recordSplitter) close() {
+	if len(s.pending) > 0 {
+		s.record(s.pending)
+		s.pending = s.pending[:0]
+	}
+}
+
+// countPerRecord is countPerLine for --per-record: it writes a record to lw
+// for every record cfg cuts each input into, as it is read.
+func countPerRecord(lw *lineWriter, inputs []string, cfg cliConfig, m wc.Metrics, opts wc.Options, name func(string) string) []wc.FileResult {
+	all := make([]wc.FileResult, 0, len(inputs))
+	for _, in := range inputs {
+		shown := name(in)
+		split := newRecordSplitter(cfg, opts, func(l wc.LineCount) { lw.write(shown, l) })
+		start := time.Now()
+		var fr wc.FileResult
+		if in == "-" {
+			fr = wc.CountReader(bufio.NewReaderSize(io.TeeReader(os.Stdin, split), opts.BufferSize), m, opts)
+		} else if f, err := os.Open(in); err != nil {
+			fr = wc.FileResult{Err: explainOpenError(in, err)}
+		} else {
+			fr = wc.CountReader(bufio.NewReaderSize(io.TeeReader(f, split), opts.BufferSize), m, opts)
+			_ = f.Close()
+		}
+		if fr.Err == nil {
+			split.close()
+		}
+		fr.Filename = in
+		fr.Duration = time.Since(start)
+		if fr.Err != nil {
+			reportFailure(in, fr.Err)
+		}
+		all = append(all, fr)
+	}
+	return all
+}
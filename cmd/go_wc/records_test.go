@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+func TestParseSeparator(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{`\0`, "\x00"},
+		{`\0\0`, "\x00\x00"},
+		{`\000`, "\x00"},
+		{`\x1e`, "\x1e"},
+		{`\xff`, "\xff"},
+		{`\u241e`, "␞"},
+		{`--\n`, "--\n"},
+		{`é`, "é"},
+	}
+	for _, tt := range tests {
+		got, err := parseSeparator(tt.spec)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("parseSeparator(%q) = %q, %v; want %q", tt.spec, got, err, tt.want)
+		}
+	}
+	for _, spec := range []string{`\q`, `\x1`, `\`} {
+		if _, err := parseSeparator(spec); err == nil {
+			t.Errorf("parseSeparator(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestRecordSplitter(t *testing.T) {
+	tests := []struct {
+		cfg   cliConfig
+		input string
+		want  []wc.LineCount
+	}{
+		{cliConfig{recordSep: `\0`}, "one\x00two three\x00\x00é",
+			[]wc.LineCount{{Line: 1, Bytes: 3, Chars: 3, Words: 1}, {Line: 2, Bytes: 9, Chars: 9, Words: 2}, {Line: 3}, {Line: 4, Bytes: 2, Chars: 1, Words: 1}}},
+		{cliConfig{recordSep: `\n--\n`}, "a b\n--\nc\n--\n",
+			[]wc.LineCount{{Line: 1, Bytes: 3, Chars: 3, Words: 2}, {Line: 2, Bytes: 1, Chars: 1, Words: 1}}},
+		{cliConfig{records: "csv"}, "a,\"b\nc\",d\r\nx y,z\n\"\"\"q\"\"\"\n\nlast",
+			[]wc.LineCount{{Line: 1, Bytes: 9, Chars: 9, Words: 2}, {Line: 2, Bytes: 5, Chars: 5, Words: 2}, {Line: 3, Bytes: 7, Chars: 7, Words: 1}, {Line: 4}, {Line: 5, Bytes: 4, Chars: 4, Words: 1}}},
+		{cliConfig{records: "jsonl"}, "{\"a\": 1}\r\n\n  \n{\"b\":[1,2]}\n",
+			[]wc.LineCount{{Line: 1, Bytes: 8, Chars: 8, Words: 2}, {Line: 2, Bytes: 11, Chars: 11, Words: 1}}},
+	}
+	for _, tt := range tests {
+		// every split of the input into writes gives the same records
+		for size := 1; size <= len(tt.input); size++ {
+			var got []wc.LineCount
+			s := newRecordSplitter(tt.cfg, wc.Options{Locale: locale.Info{IsUTF8: true}}, func(l wc.LineCount) { got = append(got, l) })
+			for in := []byte(tt.input); len(in) > 0; {
+				n := min(size, len(in))
+				s.Write(in[:n])
+				in = in[n:]
+			}
+			s.close()
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("%+v, %d-byte writes of %q: %+v, want %+v", tt.cfg, size, tt.input, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestCountPerRecord(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.csv")
+	data := "id,text\n1,\"two\nlines\"\n"
+	if err := os.WriteFile(a, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := wc.Options{BufferSize: 4, Locale: locale.Info{IsUTF8: true}}
+	base := func(s string) string { return filepath.Base(s) }
+
+	tests := []struct {
+		cfg  cliConfig
+		want string
+	}{
+		{cliConfig{noAlign: true, header: true}, "record bytes chars words file\n1 7 7 1 a.csv\n2 13 13 2 a.csv\n"},
+		{cliConfig{format: "csv"}, "file,record,bytes,chars,words\na.csv,1,7,7,1\na.csv,2,13,13,2\n"},
+		{cliConfig{format: "json"}, `{"filename":"a.csv","record":1,"bytes":7,"chars":7,"words":1}
+{"filename":"a.csv","record":2,"bytes":13,"chars":13,"words":2}
+`},
+	}
+	for _, tt := range tests {
+		tt.cfg.perRecord, tt.cfg.records = true, "csv"
+		var out strings.Builder
+		lw := newLineWriter(&out, tt.cfg)
+		all := countPerRecord(lw, []string{a}, tt.cfg, wc.DefaultMetrics(), opts, base)
+		if err := lw.flush(); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("%+v:\n%s\nwant:\n%s", tt.cfg, out.String(), tt.want)
+		}
+		// the counts of the input are those of the whole file
+		want := wc.CountReader(bufio.NewReader(strings.NewReader(data)), wc.DefaultMetrics(), opts)
+		if len(all) != 1 || all[0].Err != nil || all[0].Lines != want.Lines || all[0].Words != want.Words || all[0].Bytes != want.Bytes {
+			t.Errorf("%+v: results %+v", tt.cfg, all)
+		}
+	}
+
+	var out bytes.Buffer
+	cfg := cliConfig{perRecord: true, records: "jsonl"}
+	all := countPerRecord(newLineWriter(&out, cfg), []string{filepath.Join(dir, "missing")}, cfg, wc.DefaultMetrics(), opts, base)
+	if len(all) != 1 || all[0].Err == nil || out.Len() != 0 {
+		t.Errorf("missing file: results %+v, output %q", all, out.String())
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// writeReport writes one --report-dir file, dir/name, replacing the file of
+// an earlier run. dir is created if needed.
+func writeReport(dir, name string, write func(io.Writer) error) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// reportName names the --report-dir file of a report kind written in the
+// given format; an empty format uses def.
+func reportName(kind, format, def string) string {
+	if format == "" || format == "text" {
+		format = def
+	}
+	return kind + "." + format
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteReport(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "reports")
+	for _, body := range []string{"first run\n", "second\n"} {
+		err := writeReport(dir, "ngrams.csv", func(w io.Writer) error {
+			_, err := io.WriteString(w, body)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(dir, "ngrams.csv"))
+		if err != nil || string(got) != body {
+			t.Errorf("got %q, %v; want %q", got, err, body)
+		}
+	}
+
+	err := writeReport(dir, "stats.txt", func(io.Writer) error { return fmt.Errorf("boom") })
+	if err == nil {
+		t.Error("expected the write error")
+	}
+}
+
+func TestReportName(t *testing.T) {
+	for _, tt := range []struct{ kind, format, def, want string }{
+		{"ngrams", "", "csv", "ngrams.csv"},
+		{"ngrams", "json", "csv", "ngrams.json"},
+		{"stats", "text", "txt", "stats.txt"},
+		{"stats", "json", "txt", "stats.json"},
+	} {
+		if got := reportName(tt.kind, tt.format, tt.def); got != tt.want {
+			t.Errorf("reportName(%q, %q) = %q, want %q", tt.kind, tt.format, got, tt.want)
+		}
+	}
+}
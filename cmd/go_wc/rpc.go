@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+// JSON-RPC 2.0 error codes; -32800 is the LSP "request cancelled" code.
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+	rpcCancelled      = -32800
+	// rpcGuardAborted is the code of a count that --abort-if-line-exceeds
+	// or --abort-if-word-exceeds stopped; its data is an rpcGuardData.
+	rpcGuardAborted = -32001
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// rpcGuardData describes the bound that aborted a count.
+type rpcGuardData struct {
+	Guard string `json:"guard"` // "line" or "word"
+	Limit uint64 `json:"limit"` // in bytes
+	Line  uint64 `json:"line"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcCountParams is shared by countText and countFile. Metrics defaults to
+// lines, words, chars and bytes, which is what editors usually display.
+type rpcCountParams struct {
+	Text     string      `json:"text"`
+	Path     string      `json:"path"`
+	Metrics  *wc.Metrics `json:"metrics,omitempty"`
+	Encoding string      `json:"encoding,omitempty"`
+	Region   *rpcRegion  `json:"region,omitempty"`
+}
+
+// rpcRegion limits a count to the bytes from Start up to End, exclusive, of
+// the text or file, as for --region: an editor's selection.
+type rpcRegion struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+type rpcCancelParams struct {
+	ID json.RawMessage `json:"id"`
+}
+
+// rpcServer answers newline-delimited JSON-RPC requests. Requests run
+// concurrently so that a long countFile can be cancelled.
+type rpcServer struct {
+	base     wc.Options // buffer size and guards of every request
+	encoding string
+
+	outMu sync.Mutex
+	enc   *json.Encoder
+
+	mu       sync.Mutex
+	inflight map[string]context.CancelFunc
+}
+
+// runStdioRPC serves requests from r until EOF, writing responses to w.
+// Requests are counted under base with the locale of their encoding.
+func runStdioRPC(r io.Reader, w io.Writer, base wc.Options, encoding string) int {
+	s := &rpcServer{
+		base:     base,
+		encoding: encoding,
+		enc:      json.NewEncoder(w),
+		inflight: make(map[string]context.CancelFunc),
+	}
+	var wg sync.WaitGroup
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var req rpcRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			s.reply(rpcResponse{ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: err.Error()}})
+			continue
+		}
+		if req.Method == "cancel" {
+			s.cancel(req)
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		key := string(req.ID)
+		if req.ID != nil {
+			s.mu.Lock()
+			s.inflight[key] = cancel
+			s.mu.Unlock()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handle(ctx, req)
+			if req.ID != nil {
+				s.mu.Lock()
+				delete(s.inflight, key)
+				s.mu.Unlock()
+			}
+			cancel()
+		}()
+	}
+	wg.Wait()
+	if err := sc.Err(); err != nil {
+		return 1
+	}
+	return 0
+}
+
+func (s *rpcServer) reply(resp rpcResponse) {
+	resp.JSONRPC = "2.0"
+	if resp.ID == nil {
+		resp.ID = json.RawMessage("null")
+	}
+	s.outMu.Lock()
+	_ = s.enc.Encode(resp)
+	s.outMu.Unlock()
+}
+
+func (s *rpcServer) cancel(req rpcRequest) {
+	var p rpcCancelParams
+	if err := json.Unmarshal(req.Params, &p); err != nil {
+		if req.ID != nil {
+			s.reply(rpcResponse{ID: req.ID, Error: &rpcError{Code: rpcInvalidParams, Message: err.Error()}})
+		}
+		return
+	}
+	s.mu.Lock()
+	cancel, ok := s.inflight[string(p.ID)]
+	s.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	if req.ID != nil {
+		s.reply(rpcResponse{ID: req.ID, Result: ok})
+	}
+}
+
+func (s *rpcServer) handle(ctx context.Context, req rpcRequest) {
+	var p rpcCountParams
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			s.reply(rpcResponse{ID: req.ID, Error: &rpcError{Code: rpcInvalidParams, Message: err.Error()}})
+			return
+		}
+	}
+	metrics := wc.Metrics{Lines: true, Words: true, Chars: true, Bytes: true}
+	if p.Metrics != nil {
+		metrics = *p.Metrics
+	}
+	encoding := s.encoding
+	if p.Encoding != "" {
+		encoding = p.Encoding
+	}
+	opts := s.base
+	opts.Locale = locale.Detect(encoding)
+	if r := p.Region; r != nil {
+		if r.Start < 0 || r.End < r.Start {
+			s.reply(rpcResponse{ID: req.ID, Error: &rpcError{Code: rpcInvalidParams, Message: "region: want 0 <= start <= end"}})
+			return
+		}
+		if r.End == r.Start {
+			// an empty selection; a Length of 0 would count to the end
+			s.reply(rpcResponse{ID: req.ID, Result: toRemoteResult(wc.FileResult{Filename: p.Path}, metrics)})
+			return
+		}
+		opts.Offset, opts.Length = r.Start, r.End-r.Start
+	}
+
+	var fr wc.FileResult
+	switch req.Method {
+	case "countText":
+		fr = wc.CountReader(bufio.NewReaderSize(&ctxReader{ctx: ctx, r: strings.NewReader(p.Text)}, opts.BufferSize), metrics, opts)
+	case "countFile":
+		if p.Path == "" {
+			s.reply(rpcResponse{ID: req.ID, Error: &rpcError{Code: rpcInvalidParams, Message: "path is required"}})
+			return
+		}
+		f, err := os.Open(p.Path)
+		if err != nil {
+			s.reply(rpcResponse{ID: req.ID, Error: &rpcError{Code: rpcInternalError, Message: err.Error()}})
+			return
+		}
+		if opts.Offset > 0 {
+			// seek rather than read up to the region when the file allows it
+			if _, err := f.Seek(opts.Offset, io.SeekStart); err == nil {
+				opts.Offset = 0
+			}
+		}
+		fr = wc.CountReader(bufio.NewReaderSize(&ctxReader{ctx: ctx, r: f}, opts.BufferSize), metrics, opts)
+		fr.Filename = p.Path
+		_ = f.Close()
+	default:
+		if req.ID != nil {
+			s.reply(rpcResponse{ID: req.ID, Error: &rpcError{Code: rpcMethodNotFound, Message: "unknown method " + req.Method}})
+		}
+		return
+	}
+	if req.ID == nil {
+		return // notification
+	}
+	if fr.Err != nil {
+		rerr := &rpcError{Code: rpcInternalError, Message: fr.Err.Error()}
+		var ge *wc.GuardError
+		switch {
+		case errors.Is(fr.Err, context.Canceled):
+			rerr.Code = rpcCancelled
+		case errors.As(fr.Err, &ge):
+			rerr.Code = rpcGuardAborted
+			rerr.Data = rpcGuardData{Guard: ge.Guard, Limit: ge.Limit, Line: ge.Line}
+		}
+		s.reply(rpcResponse{ID: req.ID, Error: rerr})
+		return
+	}
+	s.reply(rpcResponse{ID: req.ID, Result: toRemoteResult(fr, metrics)})
+}
+
+// ctxReader fails reads once ctx is done, letting CountReader stop early.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
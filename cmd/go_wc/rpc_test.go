@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestStdioRPC(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.txt")
+	if err := os.WriteFile(path, []byte("one two\nthree\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pathJSON, _ := json.Marshal(path)
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"countText","params":{"text":"héllo wörld\n"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"countFile","params":{"path":` + string(pathJSON) + `}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"bogus"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"countText","params":{"text":"","metrics":{"Lines":true}}}`,
+		`not json`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if code := runStdioRPC(strings.NewReader(input), &out, wc.Options{BufferSize: 4096}, "utf-8"); code != 0 {
+		t.Fatalf("exit code: got %d, want 0", code)
+	}
+
+	byID := map[string]map[string]any{}
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var resp map[string]any
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		id, _ := json.Marshal(resp["id"])
+		byID[string(id)] = resp
+	}
+
+	text, _ := byID["1"]["result"].(map[string]any)
+	if text["words"] != 2.0 || text["chars"] != 12.0 || text["bytes"] != 14.0 {
+		t.Errorf("countText result: %v", byID["1"])
+	}
+	file, _ := byID["2"]["result"].(map[string]any)
+	if file["lines"] != 2.0 || file["words"] != 3.0 {
+		t.Errorf("countFile result: %v", byID["2"])
+	}
+	for _, field := range []string{"max_line_bytes", "max_line_chars", "whitespace_lines"} {
+		if v, ok := text[field]; ok {
+			t.Errorf("countText result has unrequested %s = %v", field, v)
+		}
+	}
+	if lines, _ := byID["4"]["result"].(map[string]any); len(lines) != 2 || lines["lines"] != 0.0 {
+		t.Errorf("countText result of lines only: %v, want filename and lines 0", byID["4"])
+	}
+	if errObj, _ := byID["3"]["error"].(map[string]any); errObj["code"] != float64(rpcMethodNotFound) {
+		t.Errorf("unknown method response: %v", byID["3"])
+	}
+	if errObj, _ := byID["null"]["error"].(map[string]any); errObj["code"] != float64(rpcParseError) {
+		t.Errorf("parse error response: %v", byID["null"])
+	}
+}
+
+func TestStdioRPCGuard(t *testing.T) {
+	input := `{"jsonrpc":"2.0","id":1,"method":"countText","params":{"text":"short\n` + strings.Repeat("x", 100) + `\n"}}`
+	var out bytes.Buffer
+	if code := runStdioRPC(strings.NewReader(input), &out, wc.Options{BufferSize: 4096, AbortLineBytes: 64}, "utf-8"); code != 0 {
+		t.Fatalf("exit code: got %d, want 0", code)
+	}
+	var resp struct {
+		Error *struct {
+			Code int          `json:"code"`
+			Data rpcGuardData `json:"data"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response %q: %v", out.String(), err)
+	}
+	want := rpcGuardData{Guard: "line", Limit: 64, Line: 2}
+	if resp.Error == nil || resp.Error.Code != rpcGuardAborted || resp.Error.Data != want {
+		t.Errorf("response: %s", out.String())
+	}
+}
+
+func TestStdioRPCRegion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.txt")
+	if err := os.WriteFile(path, []byte("one two\nthree four five\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pathJSON, _ := json.Marshal(path)
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"countFile","params":{"path":` + string(pathJSON) + `,"region":{"start":4,"end":19}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"countText","params":{"text":"héllo wörld","region":{"start":7,"end":13}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"countFile","params":{"path":` + string(pathJSON) + `,"region":{"start":5,"end":5}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"countText","params":{"text":"x","region":{"start":3,"end":1}}}`,
+	}, "\n")
+	var out bytes.Buffer
+	if code := runStdioRPC(strings.NewReader(input), &out, wc.Options{BufferSize: 4096}, "utf-8"); code != 0 {
+		t.Fatalf("exit code: got %d, want 0", code)
+	}
+	byID := map[string]map[string]any{}
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var resp map[string]any
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		id, _ := json.Marshal(resp["id"])
+		byID[string(id)] = resp
+	}
+
+	// "two\nthree four"
+	if file, _ := byID["1"]["result"].(map[string]any); file["lines"] != 1.0 || file["words"] != 3.0 || file["bytes"] != 15.0 {
+		t.Errorf("countFile region: %v", byID["1"])
+	}
+	// "wörld"
+	if text, _ := byID["2"]["result"].(map[string]any); text["words"] != 1.0 || text["chars"] != 5.0 || text["bytes"] != 6.0 {
+		t.Errorf("countText region: %v", byID["2"])
+	}
+	if empty, _ := byID["3"]["result"].(map[string]any); empty["bytes"] != 0.0 || empty["words"] != 0.0 {
+		t.Errorf("empty region: %v", byID["3"])
+	}
+	if errObj, _ := byID["4"]["error"].(map[string]any); errObj["code"] != float64(rpcInvalidParams) {
+		t.Errorf("reversed region: %v", byID["4"])
+	}
+}
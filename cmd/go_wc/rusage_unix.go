@@ -0,0 +1,23 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package main
+
+import (
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// resourceUsage returns the CPU time the process has used and its peak
+// resident set size in bytes.
+func resourceUsage() (user, sys time.Duration, peakRSS uint64, ok bool) {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0, 0, 0, false
+	}
+	peakRSS = uint64(ru.Maxrss)
+	if runtime.GOOS != "darwin" {
+		peakRSS *= 1024 // kilobytes everywhere but macOS
+	}
+	return time.Duration(ru.Utime.Nano()), time.Duration(ru.Stime.Nano()), peakRSS, true
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var getProcessMemoryInfo = syscall.NewLazyDLL("kernel32.dll").NewProc("K32GetProcessMemoryInfo")
+
+// processMemoryCounters is PROCESS_MEMORY_COUNTERS.
+type processMemoryCounters struct {
+	cb                         uint32
+	pageFaultCount             uint32
+	peakWorkingSetSize         uintptr
+	workingSetSize             uintptr
+	quotaPeakPagedPoolUsage    uintptr
+	quotaPagedPoolUsage        uintptr
+	quotaPeakNonPagedPoolUsage uintptr
+	quotaNonPagedPoolUsage     uintptr
+	pagefileUsage              uintptr
+	peakPagefileUsage          uintptr
+}
+
+// resourceUsage returns the CPU time the process has used and its peak
+// working set in bytes.
+func resourceUsage() (user, sys time.Duration, peakRSS uint64, ok bool) {
+	proc, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	var created, exited, kernel, usr syscall.Filetime
+	if err := syscall.GetProcessTimes(proc, &created, &exited, &kernel, &usr); err != nil {
+		return 0, 0, 0, false
+	}
+	// FILETIME durations count 100ns intervals
+	user = time.Duration(int64(usr.HighDateTime)<<32|int64(usr.LowDateTime)) * 100
+	sys = time.Duration(int64(kernel.HighDateTime)<<32|int64(kernel.LowDateTime)) * 100
+	pmc := processMemoryCounters{cb: uint32(unsafe.Sizeof(processMemoryCounters{}))}
+	if r, _, _ := getProcessMemoryInfo.Call(uintptr(proc), uintptr(unsafe.Pointer(&pmc)), uintptr(pmc.cb)); r != 0 {
+		peakRSS = uint64(pmc.peakWorkingSetSize)
+	}
+	return user, sys, peakRSS, true
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sandboxAccess lists what a --sandbox run may touch. Paths that do not
+// exist are left out.
+type sandboxAccess struct {
+	read  []string // inputs and option files, and directories to read beneath
+	exec  []string // the go_wc binary and the files loading it needs
+	write []string // directories to create and replace reports in
+}
+
+// dynamicLoaderPaths are read and executed when a dynamically linked
+// go_wc is re-executed: the loader, the C library and the loader's cache.
+var dynamicLoaderPaths = []string{"/lib", "/lib64", "/usr/lib", "/usr/lib64", "/etc/ld.so.cache"}
+
+// sandboxPaths returns the access a run over inputs needs: reading the
+// inputs and the files named by options, which the re-executed process
+// reads again, and writing the --output and --report-dir reports.
+func sandboxPaths(cfg cliConfig, inputs []string) (sandboxAccess, error) {
+	var acc sandboxAccess
+	for _, in := range inputs {
+		if in != "-" {
+			acc.read = append(acc.read, in)
+		}
+	}
+	for _, p := range []string{cfg.files0From, cfg.filesFrom, cfg.patternsFrom} {
+		if p != "" {
+			acc.read = append(acc.read, p)
+		}
+	}
+	if cfg.stopwords != "" && !strings.HasPrefix(cfg.stopwords, "builtin:") {
+		acc.read = append(acc.read, cfg.stopwords)
+	}
+	acc.read = append(acc.read, "/etc/localtime")
+	if cfg.autoJobs {
+		acc.read = append(acc.read, "/sys/devices") // rotational flags
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return acc, err
+	}
+	acc.exec = append([]string{exe}, dynamicLoaderPaths...)
+
+	if cfg.output != "" {
+		acc.write = append(acc.write, filepath.Dir(cfg.output))
+	}
+	if cfg.reportDir != "" {
+		// created now, as it cannot be once the sandbox is in place
+		if err := os.MkdirAll(cfg.reportDir, 0o755); err != nil {
+			return acc, err
+		}
+		acc.write = append(acc.write, cfg.reportDir)
+	}
+	return acc, nil
+}
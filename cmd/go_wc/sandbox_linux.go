@@ -0,0 +1,297 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// sandboxFd is where the process --sandbox re-executes inside its Landlock
+// domain finds the Landlock ruleset of that domain. Holding one there marks
+// it as that process: nothing in the environment or arguments a caller
+// passes can, as only a process that built a ruleset has one.
+const sandboxFd = 100
+
+// enterSandbox confines the run to the access sandboxPaths grants. Landlock
+// only restricts the thread that asks, and a Go program has many, so the
+// thread restricts itself and then re-executes go_wc: the new process and
+// every thread it starts inherit the restriction. The new process repeats
+// the run up to here and then installs a seccomp filter, which covers all
+// threads at once, against starting programs, opening sockets and the like.
+func enterSandbox(cfg cliConfig, inputs []string) error {
+	if seccompArch == 0 {
+		return fmt.Errorf("seccomp filters are not supported on %s", runtime.GOARCH)
+	}
+	if sandboxed() {
+		syscall.Close(sandboxFd)
+		return installSeccomp()
+	}
+	acc, err := sandboxPaths(cfg, inputs)
+	if err != nil {
+		return err
+	}
+	runtime.LockOSThread() // restrict and exec from the same thread
+	defer runtime.UnlockOSThread()
+	ruleset, err := landlockRestrict(acc)
+	if err != nil {
+		return err
+	}
+	// without O_CLOEXEC, the ruleset stays open across the exec
+	if err := syscall.Dup3(ruleset, sandboxFd, 0); err != nil {
+		return fmt.Errorf("handing over the Landlock ruleset: %w", err)
+	}
+	return syscall.Exec(acc.exec[0], os.Args, os.Environ())
+}
+
+// sandboxed reports whether sandboxFd is a Landlock ruleset, which only
+// the process enterSandbox re-executed inherits: adding a rule to it
+// succeeds.
+func sandboxed() bool {
+	root, err := syscall.Open("/", oPath|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return false
+	}
+	defer syscall.Close(root)
+	attr := landlockPathBeneathAttr{allowedAccess: llReadDir, parentFd: int32(root)}
+	_, _, errno := syscall.Syscall6(sysLandlockAddRule, sandboxFd, landlockRulePathBeneath, uintptr(unsafe.Pointer(&attr)), 0, 0, 0)
+	return errno == 0
+}
+
+// Landlock system calls and flags (linux/landlock.h).
+const (
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+
+	landlockCreateRulesetVersion = 1
+	landlockRulePathBeneath      = 1
+
+	prSetNoNewPrivs = 38 // linux/prctl.h
+)
+
+// Landlock file system access rights.
+const (
+	llExecute = 1 << iota
+	llWriteFile
+	llReadFile
+	llReadDir
+	llRemoveDir
+	llRemoveFile
+	llMakeChar
+	llMakeDir
+	llMakeReg
+	llMakeSock
+	llMakeFifo
+	llMakeBlock
+	llMakeSym
+	llRefer    // ABI 2
+	llTruncate // ABI 3
+	llIoctlDev // ABI 5
+
+	// llFileRights are the rights that apply to files, not directories.
+	llFileRights = llExecute | llWriteFile | llReadFile | llTruncate | llIoctlDev
+)
+
+type landlockRulesetAttr struct {
+	handledAccessFS uint64
+}
+
+// landlockPathBeneathAttr is packed in C; the kernel reads its first 12
+// bytes.
+type landlockPathBeneathAttr struct {
+	allowedAccess uint64
+	parentFd      int32
+}
+
+// landlockHandled returns the access rights the running kernel's Landlock
+// ABI can deny, or an error if Landlock is unavailable.
+func landlockHandled() (uint64, error) {
+	abi, _, errno := syscall.Syscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	if errno != 0 {
+		return 0, fmt.Errorf("Landlock is not available: %w", errno)
+	}
+	handled := uint64(llRefer - 1)
+	if abi >= 2 {
+		handled |= llRefer
+	}
+	if abi >= 3 {
+		handled |= llTruncate
+	}
+	if abi >= 5 {
+		handled |= llIoctlDev
+	}
+	return handled, nil
+}
+
+// landlockRestrict denies the calling thread all file system access but
+// what acc grants, and returns the ruleset it enforced.
+func landlockRestrict(acc sandboxAccess) (int, error) {
+	fd, err := landlockRuleset(acc)
+	if err != nil {
+		return -1, err
+	}
+	if err := setNoNewPrivs(); err != nil {
+		syscall.Close(fd)
+		return -1, err
+	}
+	if _, _, errno := syscall.Syscall(sysLandlockRestrictSelf, uintptr(fd), 0, 0); errno != 0 {
+		syscall.Close(fd)
+		return -1, fmt.Errorf("enforcing the Landlock ruleset: %w", errno)
+	}
+	return fd, nil
+}
+
+// landlockRuleset returns a Landlock ruleset granting the access of acc.
+func landlockRuleset(acc sandboxAccess) (int, error) {
+	handled, err := landlockHandled()
+	if err != nil {
+		return -1, err
+	}
+	attr := landlockRulesetAttr{handledAccessFS: handled}
+	r, _, errno := syscall.Syscall(sysLandlockCreateRuleset, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return -1, fmt.Errorf("creating the Landlock ruleset: %w", errno)
+	}
+	fd := int(r)
+
+	grant := func(paths []string, access uint64) error {
+		for _, p := range paths {
+			if err := landlockAllow(fd, p, access&handled); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err = grant(acc.read, llReadFile|llReadDir)
+	if err == nil {
+		err = grant(acc.exec, llReadFile|llExecute)
+	}
+	if err == nil {
+		// os.CreateTemp opens the files it makes for reading too
+		err = grant(acc.write, llReadDir|llReadFile|llWriteFile|llMakeReg|llRemoveFile|llTruncate)
+	}
+	if err != nil {
+		syscall.Close(fd)
+		return -1, err
+	}
+	return fd, nil
+}
+
+// landlockAllow adds a rule granting access beneath path, or to path alone
+// if it is not a directory. A path that does not exist is skipped.
+func landlockAllow(ruleset int, path string, access uint64) error {
+	fd, err := syscall.Open(path, oPath|syscall.O_CLOEXEC, 0)
+	if errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.ENOTDIR) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	defer syscall.Close(fd)
+	var st syscall.Stat_t
+	if err := syscall.Fstat(fd, &st); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if st.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+		access &= llFileRights
+	}
+	if access == 0 {
+		return nil
+	}
+	attr := landlockPathBeneathAttr{allowedAccess: access, parentFd: int32(fd)}
+	if _, _, errno := syscall.Syscall6(sysLandlockAddRule, uintptr(ruleset), landlockRulePathBeneath, uintptr(unsafe.Pointer(&attr)), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("%s: adding a Landlock rule: %w", path, errno)
+	}
+	return nil
+}
+
+// setNoNewPrivs keeps the thread, and the programs it executes, from
+// gaining privileges, as Landlock and seccomp require of unprivileged users.
+func setNoNewPrivs() error {
+	if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0, 0, 0, 0); errno != 0 {
+		return fmt.Errorf("setting no_new_privs: %w", errno)
+	}
+	return nil
+}
+
+// Classic BPF instructions and seccomp return values (linux/filter.h,
+// linux/seccomp.h).
+const (
+	bpfLdWAbs = 0x20 // BPF_LD | BPF_W | BPF_ABS
+	bpfJeqK   = 0x15 // BPF_JMP | BPF_JEQ | BPF_K
+	bpfJgeK   = 0x35 // BPF_JMP | BPF_JGE | BPF_K
+	bpfRetK   = 0x06 // BPF_RET | BPF_K
+
+	seccompRetKillProcess = 0x80000000
+	seccompRetErrno       = 0x00050000
+	seccompRetAllow       = 0x7fff0000
+
+	seccompSetModeFilter   = 1
+	seccompFilterFlagTsync = 1
+
+	// offsets in struct seccomp_data
+	seccompDataNr   = 0
+	seccompDataArch = 4
+)
+
+type sockFilter struct {
+	code uint16
+	jt   uint8
+	jf   uint8
+	k    uint32
+}
+
+type sockFprog struct {
+	len    uint16
+	filter *sockFilter
+}
+
+// seccompFilter returns a filter that fails deniedSyscalls, and on amd64
+// every x32 system call, with EPERM, and kills the process on a system
+// call of another architecture.
+func seccompFilter() []sockFilter {
+	var checks []sockFilter
+	if seccompX32 != 0 {
+		checks = append(checks, sockFilter{code: bpfJgeK, k: seccompX32})
+	}
+	for _, nr := range deniedSyscalls {
+		checks = append(checks, sockFilter{code: bpfJeqK, k: nr})
+	}
+	// checks jump to the deny return, after the allow return
+	n := len(checks)
+	for i := range checks {
+		checks[i].jt = uint8(n - i)
+	}
+	prog := []sockFilter{
+		{code: bpfLdWAbs, k: seccompDataArch},
+		{code: bpfJeqK, jf: uint8(n + 3), k: seccompArch},
+		{code: bpfLdWAbs, k: seccompDataNr},
+	}
+	prog = append(prog, checks...)
+	return append(prog,
+		sockFilter{code: bpfRetK, k: seccompRetAllow},
+		sockFilter{code: bpfRetK, k: seccompRetErrno | uint32(syscall.EPERM)},
+		sockFilter{code: bpfRetK, k: seccompRetKillProcess},
+	)
+}
+
+// installSeccomp applies seccompFilter to every thread of the process.
+func installSeccomp() error {
+	if err := setNoNewPrivs(); err != nil {
+		return err
+	}
+	filter := seccompFilter()
+	prog := sockFprog{len: uint16(len(filter)), filter: &filter[0]}
+	r, _, errno := syscall.Syscall(sysSeccomp, seccompSetModeFilter, seccompFilterFlagTsync, uintptr(unsafe.Pointer(&prog)))
+	runtime.KeepAlive(filter)
+	if errno != 0 {
+		return fmt.Errorf("installing the seccomp filter: %w", errno)
+	}
+	if r != 0 {
+		return fmt.Errorf("installing the seccomp filter: thread %d could not be synchronized", r)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+// seccompArch is AUDIT_ARCH_X86_64; seccompX32 is the bit that marks x32
+// system calls, which the filter denies wholesale.
+const (
+	seccompArch = 0xc000003e
+	seccompX32  = 0x40000000
+	sysSeccomp  = 317
+	oPath       = 0x200000 // O_PATH, which package syscall lacks
+)
+
+// deniedSyscalls are the system calls a sandboxed go_wc never needs:
+// starting programs, reaching other processes, opening sockets, and the
+// kernel interfaces most often used to escalate privileges.
+var deniedSyscalls = []uint32{
+	59,  // execve
+	322, // execveat
+	101, // ptrace
+	310, // process_vm_readv
+	311, // process_vm_writev
+	41,  // socket
+	272, // unshare
+	308, // setns
+	165, // mount
+	321, // bpf
+	298, // perf_event_open
+	323, // userfaultfd
+	248, // add_key
+	249, // request_key
+	250, // keyctl
+	425, // io_uring_setup
+	426, // io_uring_enter
+	427, // io_uring_register
+}
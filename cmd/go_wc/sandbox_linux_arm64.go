@@ -0,0 +1,32 @@
+package main
+
+// seccompArch is AUDIT_ARCH_AARCH64.
+const (
+	seccompArch = 0xc00000b7
+	seccompX32  = 0
+	sysSeccomp  = 277
+	oPath       = 0x200000
+)
+
+// deniedSyscalls are the system calls a sandboxed go_wc never needs; see
+// the amd64 list.
+var deniedSyscalls = []uint32{
+	221, // execve
+	281, // execveat
+	117, // ptrace
+	270, // process_vm_readv
+	271, // process_vm_writev
+	198, // socket
+	97,  // unshare
+	268, // setns
+	40,  // mount
+	280, // bpf
+	241, // perf_event_open
+	282, // userfaultfd
+	217, // add_key
+	218, // request_key
+	219, // keyctl
+	425, // io_uring_setup
+	426, // io_uring_enter
+	427, // io_uring_register
+}
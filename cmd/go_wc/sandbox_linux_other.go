@@ -0,0 +1,14 @@
+//go:build linux && !amd64 && !arm64
+
+package main
+
+// The seccomp filter knows the system call numbers of amd64 and arm64 only;
+// a zero seccompArch makes --sandbox refuse to run elsewhere.
+const (
+	seccompArch = 0
+	seccompX32  = 0
+	sysSeccomp  = 0
+	oPath       = 0
+)
+
+var deniedSyscalls []uint32
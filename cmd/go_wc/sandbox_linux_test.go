@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// runFilter interprets the instructions seccompFilter uses.
+func runFilter(t *testing.T, prog []sockFilter, arch, nr uint32) uint32 {
+	var acc uint32
+	for pc := 0; pc < len(prog); pc++ {
+		in := prog[pc]
+		switch in.code {
+		case bpfLdWAbs:
+			acc = map[uint32]uint32{seccompDataNr: nr, seccompDataArch: arch}[in.k]
+		case bpfJeqK, bpfJgeK:
+			if acc == in.k || in.code == bpfJgeK && acc > in.k {
+				pc += int(in.jt)
+			} else {
+				pc += int(in.jf)
+			}
+		case bpfRetK:
+			return in.k
+		default:
+			t.Fatalf("unknown instruction %#x", in.code)
+		}
+	}
+	t.Fatal("filter fell off its end")
+	return 0
+}
+
+func TestSeccompFilter(t *testing.T) {
+	if seccompArch == 0 {
+		t.Skip("no seccomp filter on this architecture")
+	}
+	prog := seccompFilter()
+	deny := seccompRetErrno | uint32(syscall.EPERM)
+	for _, nr := range deniedSyscalls {
+		if got := runFilter(t, prog, seccompArch, nr); got != deny {
+			t.Errorf("system call %d: %#x, want EPERM", nr, got)
+		}
+	}
+	for _, nr := range []uint32{syscall.SYS_READ, syscall.SYS_WRITE, syscall.SYS_OPENAT, syscall.SYS_MMAP} {
+		if got := runFilter(t, prog, seccompArch, uint32(nr)); got != seccompRetAllow {
+			t.Errorf("system call %d: %#x, want allowed", nr, got)
+		}
+	}
+	if seccompX32 != 0 {
+		if got := runFilter(t, prog, seccompArch, seccompX32|syscall.SYS_READ); got != deny {
+			t.Errorf("x32 read: %#x, want EPERM", got)
+		}
+	}
+	if got := runFilter(t, prog, seccompArch^1, syscall.SYS_READ); got != seccompRetKillProcess {
+		t.Errorf("foreign architecture: %#x, want the process killed", got)
+	}
+}
+
+// TestSandbox runs TestSandboxHelper in a child test process, which
+// sandboxes itself and reports what it could still do.
+func TestSandbox(t *testing.T) {
+	if seccompArch == 0 {
+		t.Skip("no seccomp filter on this architecture")
+	}
+	if _, err := landlockHandled(); err != nil {
+		t.Skip(err)
+	}
+	dir := t.TempDir()
+	allowed := filepath.Join(dir, "allowed.txt")
+	denied := filepath.Join(dir, "denied.txt")
+	for _, name := range []string{allowed, denied} {
+		if err := os.WriteFile(name, []byte("x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// neither the environment variable that once marked the re-executed
+	// process nor a file at sandboxFd that is no ruleset skips Landlock
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	defer pw.Close()
+	for _, forged := range []bool{false, true} {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestSandboxHelper$")
+		cmd.Env = append(os.Environ(), "GO_WC_SANDBOX_HELPER="+allowed+string(os.PathListSeparator)+denied)
+		if forged {
+			cmd.Env = append(cmd.Env, "GO_WC_SANDBOXED=1")
+			cmd.ExtraFiles = make([]*os.File, sandboxFd-2)
+			cmd.ExtraFiles[sandboxFd-3] = pr
+		}
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%v\n%s", err, out)
+		}
+		for _, want := range []string{"read allowed: ok", "read denied: permission denied", "exec: operation not permitted"} {
+			if !strings.Contains(string(out), want) {
+				t.Errorf("sandboxed process (forged marker %v) output lacks %q:\n%s", forged, want, out)
+			}
+		}
+	}
+}
+
+func TestSandboxHelper(t *testing.T) {
+	paths := filepath.SplitList(os.Getenv("GO_WC_SANDBOX_HELPER"))
+	if len(paths) != 2 {
+		t.Skip("run by TestSandbox")
+	}
+	if err := enterSandbox(cliConfig{}, paths[:1]); err != nil {
+		t.Fatal(err)
+	}
+	result := func(err error) string {
+		var errno syscall.Errno
+		if errors.As(err, &errno) {
+			return errno.Error()
+		}
+		if err != nil {
+			return err.Error()
+		}
+		return "ok"
+	}
+	_, err := os.ReadFile(paths[0])
+	fmt.Println("read allowed:", result(err))
+	_, err = os.ReadFile(paths[1])
+	fmt.Println("read denied:", result(err))
+	err = syscall.Exec(os.Args[0], os.Args, os.Environ())
+	fmt.Println("exec:", result(err))
+}
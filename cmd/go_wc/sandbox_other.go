@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// enterSandbox has no Landlock or seccomp to use here.
+func enterSandbox(cliConfig, []string) error {
+	return errors.New("only supported on Linux")
+}
@@ -0,0 +1,71 @@
+package main
+
+// jsonSchemaVersion is the schema_version of --format=json and
+// --output-append records. Adding an optional field keeps it, so parsers
+// must ignore fields they do not know; removing or renaming a field, or
+// changing what one means, bumps it.
+const jsonSchemaVersion = 2
+
+// jsonSchema, printed by --schema, describes a --format=json record (an
+// element of the printed array) and an --output-append line, which adds
+// time and host. Only the counts that were selected are present.
+const jsonSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "https://github.com/rajasatyajit/go-wc/schema/v2/record.json",
+  "title": "go_wc record",
+  "description": "One input of a go_wc run, or the total (filename \"total\") that ends --format=json output for several inputs. --format=json prints an array of records; --output-append writes one record per line. A count is present only when its metric was selected, so a present 0 is a count of 0.",
+  "type": "object",
+  "required": ["schema_version", "filename"],
+  "properties": {
+    "schema_version": {"const": 2, "description": "Version of this schema."},
+    "time": {"type": "string", "format": "date-time", "description": "--output-append only: start of the run, in UTC."},
+    "host": {"type": "string", "description": "--output-append only: host name of the machine that counted."},
+    "filename": {"type": "string", "description": "The input as displayed (after --basename or --relative-to); \"-\" is standard input."},
+    "filename_base64": {"type": "string", "contentEncoding": "base64", "description": "Only for a file name that is not valid UTF-8: its exact bytes, which filename shows with replacement characters."},
+    "lines": {"type": "integer", "minimum": 0, "description": "Newline bytes."},
+    "words": {"type": "integer", "minimum": 0, "description": "Maximal runs of non-space characters in the input's encoding."},
+    "bytes": {"type": "integer", "minimum": 0, "description": "Bytes read."},
+    "chars": {"type": "integer", "minimum": 0, "description": "Characters in the input's encoding; invalid bytes are not counted."},
+    "max_line_bytes": {"type": "integer", "minimum": 0, "description": "Length in bytes of the longest line, without its terminator."},
+    "max_line_chars": {"type": "integer", "minimum": 0, "description": "Length in characters of the longest line."},
+    "whitespace_lines": {"type": "integer", "minimum": 0, "description": "Lines holding only whitespace, empty lines excluded."},
+    "lf_endings": {"type": "integer", "minimum": 0, "description": "Lines ended by LF alone."},
+    "crlf_endings": {"type": "integer", "minimum": 0, "description": "Lines ended by CR LF."},
+    "cr_endings": {"type": "integer", "minimum": 0, "description": "Lines ended by CR alone."},
+    "longest_word": {"type": "integer", "minimum": 0, "description": "Length in characters of the longest word."},
+    "longest_word_text": {"type": "string", "description": "The first longest word, with --longest-word."},
+    "word_chars": {"type": "integer", "minimum": 0, "description": "Characters in all words; divided by words it gives the average word length."},
+    "min_line_words": {"type": "integer", "minimum": 0, "description": "Fewest words on a line, with --words-per-line."},
+    "max_line_words": {"type": "integer", "minimum": 0, "description": "Most words on a line, with --words-per-line."},
+    "all_lines": {"type": "integer", "minimum": 0, "description": "Lines including an unterminated last one, with --words-per-line; divided into words it gives the average words per line."},
+    "emoji": {"type": "integer", "minimum": 0, "description": "Emoji, counting a ZWJ sequence, flag or keycap once, with --emoji."},
+    "number_tokens": {"type": "integer", "minimum": 0, "description": "Words that are numbers, with --token-stats."},
+    "url_tokens": {"type": "integer", "minimum": 0, "description": "Words that are URLs, with --token-stats."},
+    "email_tokens": {"type": "integer", "minimum": 0, "description": "Words that are email addresses, with --token-stats."},
+    "numeric_words": {"type": "integer", "minimum": 0, "description": "Words that are numbers, with --word-kinds."},
+    "alphanumeric_words": {"type": "integer", "minimum": 0, "description": "Words that are identifiers of letters, digits and underscores, with --word-kinds."},
+    "alphabetic_words": {"type": "integer", "minimum": 0, "description": "Words of letters, apostrophes and hyphens, with --word-kinds."},
+    "code_identifiers": {"type": "integer", "minimum": 0, "description": "Identifiers and keywords of source code, with --code-tokens."},
+    "code_literals": {"type": "integer", "minimum": 0, "description": "Number and string literals of source code, with --code-tokens."},
+    "code_operators": {"type": "integer", "minimum": 0, "description": "Operators and punctuation of source code, with --code-tokens."},
+    "matching_lines": {"type": "integer", "minimum": 0, "description": "Lines the --match expression matches."},
+    "non_matching_lines": {"type": "integer", "minimum": 0, "description": "Lines the --match expression does not match."},
+    "no_final_newline": {"type": "boolean", "description": "The input is not empty and its last line lacks a newline; never in the total."},
+    "no_final_newline_files": {"type": "integer", "minimum": 1, "description": "The total only: how many inputs lack a final newline, when any does."},
+    "counts": {"type": "object", "additionalProperties": {"type": "integer", "minimum": 0}, "description": "--format=json only: the --count-char, --count-string and --patterns-from counts, keyed by their column labels."},
+    "error": {"type": "string", "description": "Why the input could not be counted; counts cover what was read before the failure."},
+    "metadata": {
+      "type": "object",
+      "description": "--with-metadata: the file as stat saw it before counting; absent for standard input and files that could not be stat'ed.",
+      "required": ["size", "mtime", "mode"],
+      "properties": {
+        "size": {"type": "integer", "minimum": 0, "description": "Size in bytes."},
+        "mtime": {"type": "string", "format": "date-time", "description": "Modification time in UTC."},
+        "mode": {"type": "string", "description": "Type and permission bits, as ls prints them."},
+        "inode": {"type": "integer", "minimum": 0, "description": "Inode number, where the system has one."},
+        "dev": {"type": "integer", "minimum": 0, "description": "Device number, where the system has one."}
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// jsonFields returns the JSON names of t's fields, embedded ones included.
+func jsonFields(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			names = append(names, jsonFields(f.Type)...)
+			continue
+		}
+		if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func TestJSONSchema(t *testing.T) {
+	var schema struct {
+		Required   []string `json:"required"`
+		Properties map[string]struct {
+			Const      *int                       `json:"const"`
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal([]byte(jsonSchema), &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	if v := schema.Properties["schema_version"].Const; v == nil || *v != jsonSchemaVersion {
+		t.Errorf("schema_version const = %v, want %d", v, jsonSchemaVersion)
+	}
+
+	// Every field written must be documented. cached is only sent by the
+	// daemon and never appears in a record.
+	fields := append(jsonFields(reflect.TypeOf(jsonResult{})), jsonFields(reflect.TypeOf(appendRecord{}))...)
+	for _, name := range fields {
+		if _, ok := schema.Properties[name]; !ok && name != "cached" {
+			t.Errorf("field %q is missing from the schema", name)
+		}
+	}
+	for name := range schema.Properties {
+		if !slices.Contains(fields, name) {
+			t.Errorf("schema property %q is never written", name)
+		}
+	}
+	for _, name := range jsonFields(reflect.TypeOf(fileMeta{})) {
+		if _, ok := schema.Properties["metadata"].Properties[name]; !ok {
+			t.Errorf("metadata field %q is missing from the schema", name)
+		}
+	}
+	for _, name := range schema.Required {
+		if _, ok := schema.Properties[name]; !ok {
+			t.Errorf("required field %q has no property", name)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// scriptReport is the --scripts --format=json record for one file or the
+// total.
+type scriptReport struct {
+	File    string           `json:"file"`
+	Scripts []wc.ScriptCount `json:"scripts"`
+}
+
+// writeScripts prints the characters of each Unicode script in every
+// counted file, and in all of them under the name "total" when there are
+// several, most frequent first: as CSV rows (file,script,chars), or as one
+// JSON array for format "json".
+func writeScripts(w io.Writer, all []wc.FileResult, totals wc.FileResult, multiple bool, format string, name func(string) string) error {
+	var reports []scriptReport
+	for _, r := range all {
+		if r.Err == nil {
+			reports = append(reports, scriptReport{File: name(r.Filename), Scripts: wc.SortScripts(r.Scripts)})
+		}
+	}
+	if multiple {
+		reports = append(reports, scriptReport{File: "total", Scripts: wc.SortScripts(totals.Scripts)})
+	}
+
+	if format == "json" {
+		return json.NewEncoder(w).Encode(reports)
+	}
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"file", "script", "chars"})
+	for _, rep := range reports {
+		for _, s := range rep.Scripts {
+			_ = cw.Write([]string{rep.File, s.Script, strconv.FormatUint(s.Chars, 10)})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestWriteScripts(t *testing.T) {
+	all := []wc.FileResult{
+		{Filename: "en.txt", Scripts: map[string]uint64{"Latin": 10, "Common": 2}},
+		{Filename: "ru.txt", Scripts: map[string]uint64{"Cyrillic": 8, "Latin": 1}},
+	}
+	totals := wc.Sum(all)
+	id := func(s string) string { return s }
+
+	var csv strings.Builder
+	if err := writeScripts(&csv, all, totals, true, "", id); err != nil {
+		t.Fatal(err)
+	}
+	want := "file,script,chars\nen.txt,Latin,10\nen.txt,Common,2\nru.txt,Cyrillic,8\nru.txt,Latin,1\n" +
+		"total,Latin,11\ntotal,Cyrillic,8\ntotal,Common,2\n"
+	if csv.String() != want {
+		t.Errorf("csv:\n%s\nwant:\n%s", csv.String(), want)
+	}
+
+	var js strings.Builder
+	if err := writeScripts(&js, all[1:], totals, false, "json", id); err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"file":"ru.txt","scripts":[{"script":"Cyrillic","chars":8},{"script":"Latin","chars":1}]}]` + "\n"; js.String() != want {
+		t.Errorf("json: got %s", js.String())
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"path/filepath"
+	"strings"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/format"
+)
+
+// shardManifestVersion is the format of the --output-shards manifest.
+const shardManifestVersion = 1
+
+// shardFormats are the --format values --output-shards can split: those
+// whose records a loader reads file by file.
+var shardFormats = []string{"csv", "json", "parquet"}
+
+// shardManifest lists the shards of a run in order, with the total of the
+// run, which the shards themselves leave out.
+type shardManifest struct {
+	ManifestVersion int          `json:"manifest_version"`
+	Format          string       `json:"format"`
+	Files           int          `json:"files"`  // inputs, failed ones included
+	Failed          int          `json:"failed"` // inputs that could not be counted
+	Shards          []shardEntry `json:"shards"`
+	Total           any          `json:"total"` // a jsonResult, see bigTotal
+}
+
+// shardEntry describes one shard file; Path is relative to the manifest.
+type shardEntry struct {
+	Path    string `json:"path"`
+	Records int    `json:"records"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// shardPath returns the name of shard i of n for the --output path out:
+// counts.csv becomes counts-00000-of-00004.csv.
+func shardPath(out string, i, n int) string {
+	ext := filepath.Ext(out)
+	return fmt.Sprintf("%s-%05d-of-%05d%s", strings.TrimSuffix(out, ext), i, n, ext)
+}
+
+// manifestPath returns the name of the manifest for the --output path out:
+// counts.csv becomes counts.manifest.json.
+func manifestPath(out string) string {
+	return strings.TrimSuffix(out, filepath.Ext(out)) + ".manifest.json"
+}
+
+// writeShards writes the results across cfg.outputShards files next to
+// cfg.output, each a complete document of cfg.format holding a contiguous
+// run of the results without a total, then the manifest, whose total
+// has the counts m selects, those in exact in place of its own. The manifest is
+// put in place last, so a loader that waits for it never reads a shard
+// still being written. write writes the records of part to w and returns
+// how many it wrote.
+func writeShards(cfg cliConfig, all []wc.FileResult, totals wc.FileResult, m wc.Metrics, extra []string, exact map[string]*big.Int, write func(w io.Writer, part []wc.FileResult) (int, error)) error {
+	n := cfg.outputShards
+	man := shardManifest{ManifestVersion: shardManifestVersion, Format: cfg.format, Files: len(all)}
+	files := make([]*outputFile, 0, n)
+	defer func() {
+		for _, f := range files {
+			f.abort()
+		}
+	}()
+	for i := range n {
+		path := shardPath(cfg.output, i, n)
+		f, err := createOutput(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+		out := newBufferedOutput(f.File)
+		records, err := write(out, all[i*len(all)/n:(i+1)*len(all)/n])
+		if ferr := out.Flush(); err == nil {
+			err = ferr
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		entry := shardEntry{Path: filepath.Base(path), Records: records}
+		if st, err := f.Stat(); err == nil {
+			entry.Bytes = st.Size()
+		}
+		man.Shards = append(man.Shards, entry)
+	}
+	for _, r := range all {
+		if r.Err != nil {
+			man.Failed++
+		}
+	}
+	man.Total = bigTotal(totalRecord(totals, m, extra), exact)
+
+	for len(files) > 0 {
+		err := files[0].commit()
+		files = files[1:]
+		if err != nil {
+			return err
+		}
+	}
+	mf, err := createOutput(manifestPath(cfg.output))
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(mf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(man); err != nil {
+		mf.abort()
+		return err
+	}
+	return mf.commit()
+}
+
+// shardWriter returns the function writeShards calls to write a shard in
+// cfg.format.
+func shardWriter(cfg cliConfig, metrics wc.Metrics, extra []string, meta map[string]*fileMeta, name func(string) string) func(io.Writer, []wc.FileResult) (int, error) {
+	return func(w io.Writer, part []wc.FileResult) (int, error) {
+		switch cfg.format {
+		case "json":
+			reportFailures(part)
+			return len(part), writeJSON(w, part, wc.FileResult{}, false, metrics, extra, meta, nil, name)
+		case "csv":
+			return countSucceeded(part), writeCSV(w, part, wc.FileResult{}, false, metrics, extra, meta, nil, name)
+		default:
+			f, err := format.New(cfg.format)
+			if err != nil {
+				return 0, err
+			}
+			return countSucceeded(part), writeFormatted(w, f, format.Layout{Metrics: metrics, Extra: extra}, part, wc.FileResult{}, false, name)
+		}
+	}
+}
+
+func countSucceeded(all []wc.FileResult) int {
+	n := 0
+	for _, r := range all {
+		if r.Err == nil {
+			n++
+		}
+	}
+	return n
+}
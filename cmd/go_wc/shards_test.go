@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestWriteShards(t *testing.T) {
+	dir := t.TempDir()
+	cfg := cliConfig{format: "csv", output: filepath.Join(dir, "counts.csv"), outputShards: 3}
+	m := wc.Metrics{Lines: true, Words: true}
+	all := []wc.FileResult{
+		{Filename: "a", Lines: 1, Words: 2},
+		{Filename: "b", Lines: 3, Words: 4},
+		{Filename: "gone", Err: errors.New("no such file")},
+		{Filename: "c", Lines: 5, Words: 6},
+	}
+	totals := wc.FileResult{Lines: 9, Words: 12}
+	write := shardWriter(cfg, m, nil, nil, func(s string) string { return s })
+	if err := writeShards(cfg, all, totals, m, nil, nil, write); err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []string
+	for i := range 3 {
+		data, err := os.ReadFile(shardPath(cfg.output, i, 3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		if lines[0] != "file,lines,words" {
+			t.Errorf("shard %d header = %q", i, lines[0])
+		}
+		rows = append(rows, lines[1:]...)
+	}
+	if got := strings.Join(rows, " "); got != "a,1,2 b,3,4 c,5,6" {
+		t.Errorf("shard rows = %q", got)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "counts.manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var man shardManifest
+	if err := json.Unmarshal(data, &man); err != nil {
+		t.Fatal(err)
+	}
+	total, _ := man.Total.(map[string]any)
+	if man.Files != 4 || man.Failed != 1 || len(man.Shards) != 3 || total["words"] != 12.0 || total["filename"] != "total" {
+		t.Errorf("manifest = %+v", man)
+	}
+	if s := man.Shards[1]; s.Path != "counts-00001-of-00003.csv" || s.Records != 1 || s.Bytes != int64(len("file,lines,words\nb,3,4\n")) {
+		t.Errorf("shard 1 = %+v", s)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"regexp"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+// countSpec describes the options that shape the counts of a run, in a
+// form that survives the run: --checkpoint records it, and
+// serve-coordinator sends it to its workers.
+type countSpec struct {
+	Metrics        wc.Metrics `json:"metrics"`
+	Encoding       string     `json:"encoding"`
+	ByteChars      bool       `json:"byte_chars,omitempty"`
+	CountChars     []string   `json:"count_chars,omitempty"`
+	Invisibles     bool       `json:"invisibles,omitempty"`
+	CountStrings   []string   `json:"count_strings,omitempty"`
+	CountRegexps   []string   `json:"count_regexps,omitempty"`
+	Match          string     `json:"match,omitempty"`
+	MaxMatchLength int        `json:"max_match_length,omitempty"`
+	Offset         int64      `json:"offset,omitempty"`
+	Length         int64      `json:"length,omitempty"`
+	MaxLines       uint64     `json:"max_lines,omitempty"`
+	MaxBytes       uint64     `json:"max_bytes,omitempty"`
+	Strip          string     `json:"strip,omitempty"`
+	MinWordLength  int        `json:"min_word_length,omitempty"`
+	Hyphen         string     `json:"hyphen,omitempty"`
+	Apostrophe     string     `json:"apostrophe,omitempty"`
+	ExtractWith    []string   `json:"extract_with,omitempty"`
+	AbortLine      uint64     `json:"abort_line,omitempty"`
+	AbortWord      uint64     `json:"abort_word,omitempty"`
+}
+
+// newCountSpec describes a run counting metrics with opts, which runCount
+// derived from cfg under loc.
+func newCountSpec(cfg cliConfig, metrics wc.Metrics, loc locale.Info, opts wc.Options) countSpec {
+	spec := countSpec{
+		Metrics:        metrics,
+		Encoding:       loc.Encoding,
+		ByteChars:      cfg.sbChars == "bytes",
+		CountChars:     cfg.countChar,
+		Invisibles:     cfg.invisibles,
+		CountStrings:   opts.CountStrings,
+		Match:          cfg.match,
+		MaxMatchLength: cfg.maxMatchLen,
+		Offset:         cfg.offset,
+		Length:         cfg.length,
+		MaxLines:       cfg.maxLines,
+		MaxBytes:       cfg.maxBytes,
+		Strip:          cfg.strip,
+		MinWordLength:  cfg.minWordLen,
+		Hyphen:         cfg.hyphen,
+		Apostrophe:     cfg.apostrophe,
+		ExtractWith:    cfg.extractWith,
+		AbortLine:      cfg.abortLine,
+		AbortWord:      cfg.abortWord,
+	}
+	for _, re := range opts.CountRegexps {
+		spec.CountRegexps = append(spec.CountRegexps, re.String())
+	}
+	return spec
+}
+
+// options returns the options s describes, for counting with s.Metrics.
+func (s countSpec) options(bufSize int) (wc.Options, error) {
+	opts := wc.Options{
+		BufferSize:     bufSize,
+		Locale:         locale.Detect(s.Encoding),
+		CountStrings:   s.CountStrings,
+		MaxMatchLength: s.MaxMatchLength,
+		Offset:         s.Offset,
+		Length:         s.Length,
+		StopAfterLines: s.MaxLines,
+		StopAfterBytes: s.MaxBytes,
+		AbortLineBytes: s.AbortLine,
+		AbortWordBytes: s.AbortWord,
+		MinWordLength:  s.MinWordLength,
+	}
+	if s.ByteChars {
+		opts.Locale = opts.Locale.ByteChars()
+	}
+	if s.Strip != "" {
+		strip, err := wc.ParseStrip(s.Strip)
+		if err != nil {
+			return opts, err
+		}
+		opts.Strip = strip
+	}
+	if s.Hyphen != "" {
+		hyphen, err := wc.ParseHyphen(s.Hyphen)
+		if err != nil {
+			return opts, err
+		}
+		opts.Hyphen = hyphen
+	}
+	if s.Apostrophe != "" {
+		apostrophe, err := wc.ParseApostrophe(s.Apostrophe)
+		if err != nil {
+			return opts, err
+		}
+		opts.Apostrophe = apostrophe
+	}
+	for _, c := range s.CountChars {
+		cl, err := wc.ParseCharClass(c)
+		if err != nil {
+			return opts, err
+		}
+		opts.CountChars = append(opts.CountChars, cl)
+	}
+	if s.Invisibles {
+		opts.CountChars = append(opts.CountChars, wc.InvisibleClasses()...)
+	}
+	for _, expr := range s.CountRegexps {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return opts, err
+		}
+		opts.CountRegexps = append(opts.CountRegexps, re)
+	}
+	if s.Match != "" {
+		re, err := regexp.Compile(s.Match)
+		if err != nil {
+			return opts, err
+		}
+		opts.MatchLines = re
+	}
+	return opts, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// Actions for --devices, named after grep's option of the same name.
+const (
+	devicesRead = "read" // count FIFOs and devices like files; sockets fail
+	devicesSkip = "skip" // leave them out
+)
+
+// specialKind names the kind of special file mode describes, or returns ""
+// for regular files, directories and symlinks.
+func specialKind(mode os.FileMode) string {
+	switch {
+	case mode&os.ModeNamedPipe != 0:
+		return "FIFO"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeCharDevice != 0:
+		return "character device"
+	case mode&os.ModeDevice != 0:
+		return "block device"
+	}
+	return ""
+}
+
+// skipDevices returns inputs without the FIFOs, devices and sockets among
+// them, for --devices=skip. Standard input is kept, as is any name that
+// cannot be stat'ed, so that its error is reported.
+func skipDevices(inputs []string) []string {
+	kept := inputs[:0:0]
+	for _, name := range inputs {
+		if name != "-" {
+			if st, err := os.Stat(name); err == nil {
+				if kind := specialKind(st.Mode()); kind != "" {
+					logger.Debug("skipped special file", "file", name, "kind", kind)
+					continue
+				}
+			}
+		}
+		kept = append(kept, name)
+	}
+	return kept
+}
+
+// errSocket replaces the error of opening a socket, which cannot be read
+// as a file.
+var errSocket = errors.New("is a socket")
+
+// explainOpenError returns errSocket when name is a socket, and err
+// otherwise.
+func explainOpenError(name string, err error) error {
+	if st, serr := os.Stat(name); serr == nil && st.Mode()&os.ModeSocket != 0 {
+		return errSocket
+	}
+	return err
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSpecialKind(t *testing.T) {
+	tests := []struct {
+		mode os.FileMode
+		want string
+	}{
+		{0o644, ""},
+		{os.ModeDir | 0o755, ""},
+		{os.ModeSymlink, ""},
+		{os.ModeNamedPipe, "FIFO"},
+		{os.ModeSocket, "socket"},
+		{os.ModeDevice | os.ModeCharDevice, "character device"},
+		{os.ModeDevice, "block device"},
+	}
+	for _, tt := range tests {
+		if got := specialKind(tt.mode); got != tt.want {
+			t.Errorf("specialKind(%v) = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestSkipDevices(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(a, []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+	inputs := []string{a, "-", missing}
+	if got := skipDevices(inputs); !slices.Equal(got, inputs) {
+		t.Errorf("got %v, want %v", got, inputs)
+	}
+}
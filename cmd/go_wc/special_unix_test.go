@@ -0,0 +1,46 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+)
+
+func TestSpecialFiles(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(a, []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fifo := filepath.Join(dir, "fifo")
+	if err := syscall.Mkfifo(fifo, 0o600); err != nil {
+		t.Skipf("mkfifo unavailable: %v", err)
+	}
+	sock := filepath.Join(dir, "sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer l.Close()
+
+	if got := skipDevices([]string{fifo, a, sock, "-"}); !slices.Equal(got, []string{a, "-"}) {
+		t.Errorf("skipDevices: got %v", got)
+	}
+
+	_, err = os.Open(sock)
+	if err == nil {
+		t.Fatal("opening a socket succeeded")
+	}
+	if got := explainOpenError(sock, err); !errors.Is(got, errSocket) {
+		t.Errorf("socket: got %v", got)
+	}
+	if got := explainOpenError(a, err); got != err {
+		t.Errorf("regular file: got %v, want the error unchanged", got)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// sqliteSchema creates the --output-sqlite table on first use. Counts that
+// were not selected for a run are NULL.
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS go_wc_counts (
+	run_id TEXT NOT NULL,
+	run_time TEXT NOT NULL,
+	file TEXT NOT NULL,
+	lines INTEGER,
+	words INTEGER,
+	chars INTEGER,
+	bytes INTEGER,
+	max_line_bytes INTEGER,
+	max_line_chars INTEGER,
+	error TEXT
+);
+CREATE INDEX IF NOT EXISTS go_wc_counts_run_time ON go_wc_counts (run_time);
+`
+
+const sqliteTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// newRunID returns a random identifier shared by the rows of one run.
+func newRunID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// sqliteInserts returns the SQL that records one row per input of a run,
+// for writeSQLite to run in a single transaction. Times are stored in UTC at millisecond precision,
+// a fixed-width form that sorts correctly as text and that SQLite's date
+// functions understand.
+func sqliteInserts(runID string, start time.Time, all []wc.FileResult, m wc.Metrics, name func(string) string) string {
+	var sb strings.Builder
+	sb.WriteString(sqliteSchema)
+	when := sqlQuote(start.UTC().Format(sqliteTimeFormat))
+	for _, r := range all {
+		count := func(on bool, v uint64) string {
+			if !on || r.Err != nil {
+				return "NULL"
+			}
+			return strconv.FormatUint(v, 10)
+		}
+		errText := "NULL"
+		if r.Err != nil {
+			errText = sqlQuote(r.Err.Error())
+		}
+		fmt.Fprintf(&sb, "INSERT INTO go_wc_counts VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s);\n",
+			sqlQuote(runID), when, sqlQuote(name(r.Filename)),
+			count(m.Lines, r.Lines), count(m.Words, r.Words), count(m.Chars, r.Chars), count(m.Bytes, r.Bytes),
+			count(m.MaxLineBytes, r.MaxLineBytes), count(m.MaxLineChars, r.MaxLineChars), errText)
+	}
+	return sb.String()
+}
+
+// sqlQuote returns s as an SQL string literal.
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// findSQLite returns the path of the sqlite3 command-line shell. go_wc has
+// no SQLite driver of its own, so --output-sqlite needs sqlite3 on PATH,
+// and looks for it before counting rather than after.
+func findSQLite() (string, error) {
+	bin, err := exec.LookPath("sqlite3")
+	if err != nil {
+		return "", errors.New("the sqlite3 command is not installed or not on PATH; install SQLite's command-line shell to write databases")
+	}
+	return bin, nil
+}
+
+// writeSQLite has the sqlite3 shell bin run sql against the database file
+// path, creating it if needed. The statements run in one transaction that
+// sqlite3 rolls back if any of them fails, leaving the database as it was.
+func writeSQLite(bin, path, sql string) error {
+	cmd := exec.Command(bin, "-bail", path)
+	cmd.Stdin = strings.NewReader("BEGIN IMMEDIATE;\n" + sql + "COMMIT;\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %s", path, msg)
+		}
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestSQLiteInserts(t *testing.T) {
+	all := []wc.FileResult{
+		{Filename: "it's.txt", Lines: 3, Words: 7, Bytes: 40},
+		{Filename: "gone", Err: errors.New("no such file")},
+	}
+	start := time.Date(2026, 3, 1, 12, 30, 0, 5e6, time.FixedZone("CET", 3600))
+	sql := sqliteInserts("abc", start, all, wc.DefaultMetrics(), func(s string) string { return s })
+	for _, want := range []string{
+		"CREATE TABLE IF NOT EXISTS go_wc_counts",
+		"VALUES ('abc', '2026-03-01T11:30:00.005Z', 'it''s.txt', 3, 7, NULL, 40, NULL, NULL, NULL);",
+		"VALUES ('abc', '2026-03-01T11:30:00.005Z', 'gone', NULL, NULL, NULL, NULL, NULL, NULL, 'no such file');",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("SQL lacks %q:\n%s", want, sql)
+		}
+	}
+}
+
+func TestWriteSQLite(t *testing.T) {
+	bin, err := findSQLite()
+	if err != nil {
+		t.Skip(err)
+	}
+	db := filepath.Join(t.TempDir(), "counts.db")
+	all := []wc.FileResult{{Filename: "a.txt", Lines: 2}}
+	for i, id := range []string{"run1", "run2"} {
+		sql := sqliteInserts(id, time.Unix(int64(i), 0), all, wc.Metrics{Lines: true}, func(s string) string { return s })
+		if err := writeSQLite(bin, db, sql); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err := exec.Command("sqlite3", db, "SELECT run_id, lines FROM go_wc_counts ORDER BY run_time").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out), "run1|2\nrun2|2\n"; got != want {
+		t.Errorf("rows = %q, want %q", got, want)
+	}
+	if err := writeSQLite(bin, db, "INSERT INTO go_wc_counts (run_id, run_time, file) VALUES ('run3', 'x', 'b');\nNOT SQL;"); err == nil {
+		t.Error("expected an error for bad SQL")
+	}
+	out, err = exec.Command("sqlite3", db, "SELECT count(*) FROM go_wc_counts").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(out); got != "2\n" {
+		t.Errorf("after failed SQL the table holds %q rows, want the 2 of before", got)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(db)); len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want only the database", len(entries))
+	}
+}
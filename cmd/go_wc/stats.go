@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// optionalValue is a string flag that may also be given bare (--flag), in
+// which case *dst is set to bare.
+type optionalValue struct {
+	dst  *string
+	bare string
+}
+
+func (o optionalValue) String() string {
+	if o.dst == nil {
+		return ""
+	}
+	return *o.dst
+}
+
+func (o optionalValue) Set(s string) error {
+	if s == "true" {
+		s = o.bare
+	}
+	*o.dst = s
+	return nil
+}
+
+func (o optionalValue) IsBoolFlag() bool { return true }
+
+// runStats summarizes a run for --stats.
+type runStats struct {
+	Files             int     `json:"files"`
+	Failed            int     `json:"failed"`
+	Skipped           int     `json:"skipped"`
+	CacheHits         int     `json:"cache_hits"`
+	Bytes             uint64  `json:"bytes"`
+	WallSeconds       float64 `json:"wall_seconds"`
+	BytesPerSecond    float64 `json:"bytes_per_second"`
+	Workers           int     `json:"workers"`
+	WorkerUtilization float64 `json:"worker_utilization"`
+}
+
+// collectStats derives run statistics from the results. Utilization is the
+// time workers spent counting divided by the wall time they were available.
+func collectStats(all []wc.FileResult, wall time.Duration, workers int) runStats {
+	if workers < 1 {
+		workers = 1
+	}
+	st := runStats{Workers: workers, WallSeconds: wall.Seconds()}
+	var busy time.Duration
+	for _, r := range all {
+		st.Files++
+		busy += r.Duration
+		if r.Err != nil {
+			st.Failed++
+			continue
+		}
+		st.Bytes += r.Bytes
+	}
+	if wall > 0 {
+		st.BytesPerSecond = float64(st.Bytes) / wall.Seconds()
+		st.WorkerUtilization = busy.Seconds() / (wall.Seconds() * float64(workers))
+		if st.WorkerUtilization > 1 {
+			st.WorkerUtilization = 1
+		}
+	}
+	return st
+}
+
+func writeStats(w io.Writer, st runStats, mode string) error {
+	if mode == "json" {
+		return json.NewEncoder(w).Encode(st)
+	}
+	_, err := fmt.Fprintf(w,
+		"files: %d (failed %d, skipped %d, cache hits %d)\n"+
+			"bytes: %d\nwall time: %.3fs\nthroughput: %.1f MiB/s\n"+
+			"workers: %d (utilization %.0f%%)\n",
+		st.Files, st.Failed, st.Skipped, st.CacheHits,
+		st.Bytes, st.WallSeconds, st.BytesPerSecond/(1<<20),
+		st.Workers, st.WorkerUtilization*100)
+	return err
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestCollectStats(t *testing.T) {
+	all := []wc.FileResult{
+		{Bytes: 1 << 20, Duration: time.Second},
+		{Bytes: 1 << 20, Duration: time.Second},
+		{Err: errors.New("boom"), Duration: 0},
+	}
+	st := collectStats(all, 2*time.Second, 2)
+	if st.Files != 3 || st.Failed != 1 || st.Bytes != 2<<20 {
+		t.Errorf("counts: %+v", st)
+	}
+	if st.BytesPerSecond != float64(1<<20) {
+		t.Errorf("BytesPerSecond: got %v", st.BytesPerSecond)
+	}
+	if st.WorkerUtilization != 0.5 {
+		t.Errorf("WorkerUtilization: got %v, want 0.5", st.WorkerUtilization)
+	}
+}
+
+func TestWriteStats(t *testing.T) {
+	st := runStats{Files: 2, Bytes: 10, Workers: 1}
+	var text, js strings.Builder
+	if err := writeStats(&text, st, "text"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.String(), "files: 2") {
+		t.Errorf("text stats: %q", text.String())
+	}
+	if err := writeStats(&js, st, "json"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(js.String(), `"files":2`) {
+		t.Errorf("json stats: %q", js.String())
+	}
+}
+
+func TestFinishRunSkipped(t *testing.T) {
+	dir := t.TempDir()
+	cfg := cliConfig{stats: "json", reportDir: dir}
+	all := []wc.FileResult{{Bytes: 3}}
+	if code := finishRun(cfg, all, time.Now(), 1, 0, 2, 0, nil); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "stats.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"files":1`) || !strings.Contains(string(data), `"skipped":2`) {
+		t.Errorf("stats = %s, want 1 file and 2 skipped", data)
+	}
+}
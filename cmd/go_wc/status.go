@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// statusPeriod is how often --status redraws its line.
+const statusPeriod = 250 * time.Millisecond
+
+// statusLine keeps a single line on a terminal up to date with the progress
+// of a scan: bytes read, lines so far and throughput.
+type statusLine struct {
+	w     io.Writer
+	p     *wc.Progress
+	start time.Time
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// startStatus starts redrawing the status line of p on w.
+func startStatus(w io.Writer, p *wc.Progress) *statusLine {
+	s := &statusLine{w: w, p: p, start: time.Now(), stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		t := time.NewTicker(statusPeriod)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				fmt.Fprint(s.w, "\r"+s.render(time.Since(s.start))+"\x1b[K")
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// render returns the status after elapsed time.
+func (s *statusLine) render(elapsed time.Duration) string {
+	mib := float64(s.p.Bytes()) / (1 << 20)
+	var rate float64
+	if secs := elapsed.Seconds(); secs > 0 {
+		rate = mib / secs
+	}
+	return fmt.Sprintf("go_wc: %.1f MiB read, %d lines, %.1f MiB/s", mib, s.p.Lines(), rate)
+}
+
+// clear stops the updates and erases the line, leaving the cursor at its
+// start for the output that follows.
+func (s *statusLine) clear() {
+	close(s.stop)
+	<-s.done
+	fmt.Fprint(s.w, "\r\x1b[K")
+}
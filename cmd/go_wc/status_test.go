@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestStatusLine(t *testing.T) {
+	var p wc.Progress
+	wc.CountBytes([]byte(strings.Repeat("x\n", 1<<19)), wc.Metrics{}, wc.Options{Progress: &p})
+	s := &statusLine{p: &p}
+	if got, want := s.render(2*time.Second), "go_wc: 1.0 MiB read, 524288 lines, 0.5 MiB/s"; got != want {
+		t.Errorf("render: got %q, want %q", got, want)
+	}
+
+	var out strings.Builder
+	s = startStatus(&out, &p)
+	time.Sleep(statusPeriod + 50*time.Millisecond)
+	s.clear()
+	got := out.String()
+	if !strings.HasPrefix(got, "\rgo_wc: 1.0 MiB read, 524288 lines,") || !strings.HasSuffix(got, "\x1b[K\r\x1b[K") {
+		t.Errorf("status output %q", got)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import "runtime"
+
+// storageKind is the kind of device an input is stored on, for --jobs=auto.
+type storageKind int
+
+const (
+	storageSSD        storageKind = iota // or unknown: no seek penalty
+	storageRotational                    // spinning disk: one file at a time
+	storageNetwork                       // network filesystem: latency bound
+)
+
+func (k storageKind) String() string {
+	switch k {
+	case storageRotational:
+		return "rotational"
+	case storageNetwork:
+		return "network"
+	}
+	return "ssd"
+}
+
+// networkJobs is how many files --jobs=auto reads at once from one network
+// filesystem, hiding round trips without flooding the server.
+const networkJobs = 8
+
+// jobLimit returns how many files --jobs=auto counts at once on a device.
+func (k storageKind) jobLimit() int {
+	switch k {
+	case storageRotational:
+		return 1 // parallel reads make the disk seek back and forth
+	case storageNetwork:
+		return networkJobs
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// storagePlan assigns inputs to devices for --jobs=auto, so that each
+// device gets its own queue and as many workers as suits it.
+type storagePlan struct {
+	device []uint64 // per input
+	kind   map[uint64]storageKind
+}
+
+// planStorage finds the device of each input. Inputs that cannot be
+// stat'ed, and standard input, share device 0 with the SSD limit.
+func planStorage(inputs []string) *storagePlan {
+	p := &storagePlan{device: make([]uint64, len(inputs)), kind: map[uint64]storageKind{0: storageSSD}}
+	for i, name := range inputs {
+		if name == "-" {
+			continue
+		}
+		dev, ok := inputDevice(name)
+		if !ok {
+			continue
+		}
+		p.device[i] = dev
+		if _, seen := p.kind[dev]; !seen {
+			p.kind[dev] = deviceKind(name, dev)
+			logger.Debug("storage", "device", dev, "kind", p.kind[dev], "jobs", p.kind[dev].jobLimit())
+		}
+	}
+	return p
+}
+
+// lane is a queue of inputs, by index in counting order, served by its own
+// workers.
+type lane struct {
+	order   []int
+	workers int
+}
+
+// lanes splits order into one lane per device, keeping the order within
+// each, with no more workers than the lane has inputs.
+func (p *storagePlan) lanes(order []int) []lane {
+	byDev := make(map[uint64]int)
+	var out []lane
+	for _, i := range order {
+		dev := p.device[i]
+		li, ok := byDev[dev]
+		if !ok {
+			li = len(out)
+			byDev[dev] = li
+			out = append(out, lane{workers: p.kind[dev].jobLimit()})
+		}
+		out[li].order = append(out[li].order, i)
+	}
+	for i := range out {
+		out[i].workers = min(out[i].workers, len(out[i].order))
+	}
+	return out
+}
+
+// workerCount returns the total number of workers of the lanes.
+func workerCount(lanes []lane) int {
+	n := 0
+	for _, l := range lanes {
+		n += l.workers
+	}
+	return n
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Filesystem magic numbers (statfs f_type) of network filesystems.
+var networkFilesystems = map[uint32]bool{
+	0x6969:     true, // NFS
+	0x517b:     true, // SMB
+	0xff534d42: true, // CIFS
+	0xfe534d42: true, // SMB2
+	0x00c36400: true, // Ceph
+	0x47504653: true, // GPFS
+	0x0bd00bd0: true, // Lustre
+	0x5346414f: true, // AFS
+	0x013111a8: true, // IBRIX
+	0x19830326: true, // fhgfs / BeeGFS
+}
+
+// inputDevice returns the device holding the named file.
+func inputDevice(name string) (uint64, bool) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(name, &st); err != nil {
+		return 0, false
+	}
+	return uint64(st.Dev), true
+}
+
+// deviceKind classifies the device dev holding the named file: network
+// filesystems by their statfs type, and block devices by the rotational
+// flag the kernel exposes in sysfs, looked up on the whole disk for a
+// partition.
+func deviceKind(name string, dev uint64) storageKind {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(name, &fs); err == nil && networkFilesystems[uint32(fs.Type)] {
+		return storageNetwork
+	}
+	dir, err := filepath.EvalSymlinks(fmt.Sprintf("/sys/dev/block/%d:%d", devMajor(dev), devMinor(dev)))
+	if err != nil {
+		return storageSSD // tmpfs, overlay and other devices without a queue
+	}
+	for _, d := range []string{dir, filepath.Dir(dir)} {
+		if b, err := os.ReadFile(filepath.Join(d, "queue", "rotational")); err == nil {
+			if strings.TrimSpace(string(b)) == "1" {
+				return storageRotational
+			}
+			return storageSSD
+		}
+	}
+	return storageSSD
+}
+
+// devMajor and devMinor split a Linux dev_t.
+func devMajor(dev uint64) uint64 {
+	return (dev>>8)&0xfff | (dev>>32)&^0xfff
+}
+
+func devMinor(dev uint64) uint64 {
+	return dev&0xff | (dev>>12)&0xffffff00
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestDevNumbers(t *testing.T) {
+	// makedev as glibc encodes dev_t
+	makedev := func(major, minor uint64) uint64 {
+		return (major&0xfff)<<8 | (major&^0xfff)<<32 | minor&0xff | (minor&^0xff)<<12
+	}
+	if makedev(8, 1) != 0x801 || makedev(259, 3) != 0x10303 {
+		t.Fatal("bad makedev")
+	}
+	for _, tt := range []struct{ major, minor uint64 }{
+		{8, 1}, {259, 3}, {253, 0}, {0x12345, 0xc}, {0x56, 0x12341}, {0xfffff, 0xfffff},
+	} {
+		dev := makedev(tt.major, tt.minor)
+		if got := devMajor(dev); got != tt.major {
+			t.Errorf("devMajor(%#x) = %#x, want %#x", dev, got, tt.major)
+		}
+		if got := devMinor(dev); got != tt.minor {
+			t.Errorf("devMinor(%#x) = %#x, want %#x", dev, got, tt.minor)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package main
+
+// inputDevice cannot tell devices apart here, so --jobs=auto uses a single
+// queue.
+func inputDevice(string) (uint64, bool) {
+	return 0, false
+}
+
+// deviceKind treats every device as an SSD.
+func deviceKind(string, uint64) storageKind {
+	return storageSSD
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestStorageLanes(t *testing.T) {
+	p := &storagePlan{
+		device: []uint64{1, 2, 1, 3, 2, 1},
+		kind:   map[uint64]storageKind{1: storageRotational, 2: storageNetwork, 3: storageSSD},
+	}
+	got := p.lanes([]int{5, 4, 3, 2, 1, 0})
+	want := []lane{
+		{order: []int{5, 2, 0}, workers: 1},
+		{order: []int{4, 1}, workers: 2},
+		{order: []int{3}, workers: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lanes() = %+v, want %+v", got, want)
+	}
+	if n := workerCount(got); n != 4 {
+		t.Errorf("workerCount() = %d, want 4", n)
+	}
+	if storageNetwork.jobLimit() != networkJobs || storageSSD.jobLimit() != runtime.GOMAXPROCS(0) {
+		t.Error("unexpected job limits")
+	}
+}
+
+func TestCountInputsByDevice(t *testing.T) {
+	dir := t.TempDir()
+	var inputs []string
+	for i := 0; i < 12; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("f%d", i))
+		if err := os.WriteFile(name, make([]byte, i), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, name)
+	}
+	cs := countSettings{metrics: wc.Metrics{Bytes: true}, opts: wc.Options{BufferSize: 4096}}
+	cs.storage = &storagePlan{
+		device: []uint64{1, 2, 1, 2, 1, 2, 1, 2, 1, 2, 1, 2},
+		kind:   map[uint64]storageKind{1: storageRotational, 2: storageSSD},
+	}
+	all := countInputs(inputs, cs, 0)
+	if len(all) != len(inputs) {
+		t.Fatalf("got %d results, want %d", len(all), len(inputs))
+	}
+	for i, r := range all {
+		if r.Index != i || r.Bytes != uint64(i) {
+			t.Errorf("result %d: %+v", i, r)
+		}
+	}
+
+	p := planStorage(append(inputs[:2:2], "-"))
+	if p.device[0] != p.device[1] || p.device[2] != 0 {
+		t.Errorf("planStorage() devices = %v", p.device)
+	}
+}
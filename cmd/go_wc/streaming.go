@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/format"
+)
+
+// streamWidth is the count column width of --streaming text output when
+// neither --width nor --no-align sets one: the counts are printed before
+// the later files are known, so, as GNU wc does for inputs of unknown
+// size, a fixed width of 7 is used.
+const streamWidth = 7
+
+// runStreaming implements --streaming: the files on the command line and
+// then the names of --files-from or --files0-from are counted as the
+// names arrive, and each file's counts are printed as soon as those of
+// the files before it are, followed by the total.
+func runStreaming(cfg cliConfig, files []string, m wc.Metrics, extra []string, opts wc.Options, prof *runProfile) int {
+	runStart := time.Now()
+	list, sep := fileList(cfg)
+	var r io.Reader = os.Stdin
+	if list != "-" {
+		f, err := os.Open(filepath.Clean(list))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		r = f
+	}
+	outFile := os.Stdout
+	var report *outputFile
+	if cfg.output != "" {
+		var err error
+		if report, err = openOutput(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "go_wc: --output: %v\n", err)
+			return 1
+		}
+		outFile = report.File
+	}
+
+	names := make(chan string)
+	var listErr error
+	var devices atomic.Int64
+	go func() {
+		defer close(names)
+		listErr = streamNames(names, files, r, sep, cfg.devices == devicesSkip, &devices)
+	}()
+
+	out := newBufferedOutput(outFile)
+	sw := newStreamWriter(out, cfg, m, extra)
+	cs := countSettings{metrics: m, opts: opts, fileTimeout: cfg.fileTimeout, halt: cfg.halt}
+	cs.extract, _ = parseExtractors(cfg.extractWith)
+	var err error
+	all, uncounted := countStream(names, cs, cfg.jobs, func(batch []wc.FileResult) {
+		for _, r := range batch {
+			if err == nil {
+				err = sw.write(r)
+			}
+		}
+		if err == nil {
+			err = out.Flush()
+		}
+	})
+	if prof != nil {
+		prof.counted = time.Now()
+	}
+
+	var exitCode int
+	if listErr != nil {
+		fmt.Fprintln(os.Stderr, listErr)
+		exitCode = 1
+	}
+	var tot wc.Totals
+	for _, r := range all {
+		tot.Add(r)
+		if r.Err != nil {
+			exitCode = 1
+		} else if cfg.requireEOL && r.NoFinalNewline {
+			fmt.Fprintf(os.Stderr, "go_wc: %s: no newline at end of file\n", r.Filename)
+			exitCode = 1
+		}
+	}
+	var exact map[string]*big.Int
+	if cfg.bigTotals {
+		exact = tot.Exact()
+	}
+	if tot.Result().Overflow && len(exact) == 0 {
+		reportOverflow()
+		exitCode = 1
+	}
+	if err == nil && len(all) > 1 {
+		err = sw.end(tot.Result(), exact)
+	}
+	if ferr := out.Flush(); err == nil {
+		err = ferr
+	}
+	if report != nil {
+		if err != nil {
+			report.abort()
+		} else {
+			err = report.commit()
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "go_wc: %v\n", err)
+		exitCode = 1
+	}
+	return finishRun(cfg, all, runStart, cfg.jobs, 0, int(devices.Load())+uncounted, exitCode, prof)
+}
+
+// streamNames sends files, then each name read from r, separated by sep,
+// as soon as its separator (or the end of r) is read. Empty names, and
+// with skipDevs the names of devices, FIFOs and sockets, are left out;
+// skipped counts the latter.
+func streamNames(names chan<- string, files []string, r io.Reader, sep byte, skipDevs bool, skipped *atomic.Int64) error {
+	send := func(name string) {
+		if name == "" {
+			return
+		}
+		if skipDevs && len(skipDevices([]string{name})) == 0 {
+			skipped.Add(1)
+			return
+		}
+		names <- name
+	}
+	for _, name := range files {
+		send(name)
+	}
+	br := bufio.NewReader(r)
+	for {
+		name, err := br.ReadString(sep)
+		if err == nil {
+			name = name[:len(name)-1]
+		}
+		send(name)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// countStream counts the names received from names with workers workers
+// until names is closed, and calls emit with each run of results that
+// follows, in the order of the names, those already emitted. It returns
+// all the results in that order, and how many of the names it received
+// were left uncounted. As with countInputs, a failure under --halt=soon
+// or now stops the scheduling of further names.
+func countStream(names <-chan string, cs countSettings, workers int, emit func([]wc.FileResult)) ([]wc.FileResult, int) {
+	type task struct {
+		index int
+		name  string
+	}
+	tasks := make(chan task)
+	results := make(chan wc.FileResult)
+	stdin := &stdinSource{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(max(workers, 1))
+	for range max(workers, 1) {
+		go func() {
+			defer wg.Done()
+			var buf []byte
+			for t := range tasks {
+				fr := countInput(ctx, t.name, false, cs, stdin, &buf)
+				fr.Index = t.index
+				results <- fr
+			}
+		}()
+	}
+	received := 0
+	go func() {
+		defer close(tasks)
+		for name := range names {
+			received++
+			select {
+			case tasks <- task{received - 1, name}:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := make(map[int]wc.FileResult)
+	var all []wc.FileResult
+	halted := false
+	for res := range results {
+		if halted && errors.Is(res.Err, context.Canceled) {
+			continue // abandoned by --halt=now
+		}
+		if res.Err != nil && !halted && (cs.halt == haltSoon || cs.halt == haltNow) {
+			logger.Info("halting", "policy", cs.halt, "file", res.Filename)
+			halted = true
+			close(stop)
+			if cs.halt == haltNow {
+				cancel()
+			}
+		}
+		pending[res.Index] = res
+		start := len(all)
+		for {
+			pr, ok := pending[len(all)]
+			if !ok {
+				break
+			}
+			all = append(all, pr)
+			delete(pending, pr.Index)
+		}
+		if len(all) > start {
+			emit(all[start:])
+		}
+	}
+	// results abandoned by --halt=now leave gaps; keep the rest in order
+	for i := len(all); len(pending) > 0; i++ {
+		if pr, ok := pending[i]; ok {
+			all = append(all, pr)
+			emit(all[len(all)-1:])
+			delete(pending, i)
+		}
+	}
+	// the tasks are closed, and received final, once results is
+	return all, received - len(all)
+}
+
+// streamWriter prints --streaming results as text or csv.
+type streamWriter struct {
+	table  *format.Table // text
+	cw     *csv.Writer   // csv
+	m      wc.Metrics
+	header []string // of csv
+	cfg    cliConfig
+}
+
+func newStreamWriter(w io.Writer, cfg cliConfig, m wc.Metrics, extra []string) *streamWriter {
+	sw := &streamWriter{m: m, cfg: cfg}
+	if cfg.format == "csv" {
+		sw.cw, sw.header = csv.NewWriter(w), format.CSVHeader(m, extra)
+		_ = sw.cw.Write(sw.header)
+		return sw
+	}
+	width := streamWidth
+	switch {
+	case cfg.noAlign:
+		width = 1
+	case cfg.width > 0:
+		width = cfg.width
+	case cfg.minWidth > 0:
+		width = max(cfg.minWidth, streamWidth)
+	}
+	cols := 0
+	if cfg.truncate || cfg.wrap {
+		cols = terminalWidth(w)
+	}
+	sw.table = &format.Table{Finish: func(line string, r wc.FileResult) string {
+		if cols > 0 {
+			r.Filename = ""
+			line = fitName(line, len(format.FormatLine(r, m, width))+1, cols, cfg.wrap)
+		}
+		return line
+	}}
+	_ = sw.table.Begin(w, format.Layout{Metrics: m, Extra: extra, Width: width, Header: cfg.header})
+	return sw
+}
+
+// write prints r, or reports its failure on stderr.
+func (sw *streamWriter) write(r wc.FileResult) error {
+	if r.Err != nil {
+		reportFailure(r.Filename, r.Err)
+		return nil
+	}
+	if sw.cw != nil {
+		r.Filename = displayName(sw.cfg, r.Filename)
+		_ = sw.cw.Write(format.CSVRecord(r, sw.m))
+		sw.cw.Flush()
+		return sw.cw.Error()
+	}
+	r.Filename = shownName(sw.cfg, r.Filename)
+	return sw.table.WriteResult(r)
+}
+
+// end prints the total, with the counts in exact in place of its own.
+func (sw *streamWriter) end(totals wc.FileResult, exact map[string]*big.Int) error {
+	totals.Filename = "total"
+	totals.Index = -1
+	if sw.cw != nil {
+		rec := format.CSVRecord(totals, sw.m)
+		bigRecord(sw.header, rec, exact)
+		_ = sw.cw.Write(rec)
+		sw.cw.Flush()
+		return sw.cw.Error()
+	}
+	return sw.table.WriteTotals(totals)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestStreamNames(t *testing.T) {
+	pr, pw := io.Pipe()
+	names := make(chan string)
+	done := make(chan error, 1)
+	go func() {
+		done <- streamNames(names, []string{"arg"}, pr, '\n', false, nil)
+		close(names)
+	}()
+	if got := <-names; got != "arg" {
+		t.Errorf("first name = %q, want the command-line file", got)
+	}
+	// a name is sent as soon as its line is complete, before EOF
+	go pw.Write([]byte("a.txt\n\nb"))
+	select {
+	case got := <-names:
+		if got != "a.txt" {
+			t.Errorf("got %q, want a.txt", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a.txt was not sent before the end of the list")
+	}
+	go pw.Close()
+	if got := <-names; got != "b" {
+		t.Errorf("got %q, want the unterminated last name b", got)
+	}
+	if _, ok := <-names; ok {
+		t.Error("names not closed")
+	}
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCountStream(t *testing.T) {
+	dir := t.TempDir()
+	names := make(chan string, 4)
+	for i, text := range []string{"a\n", "b c\n", "d\ne\n"} {
+		name := filepath.Join(dir, string(rune('a'+i)))
+		if err := os.WriteFile(name, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		names <- name
+	}
+	names <- filepath.Join(dir, "missing")
+	close(names)
+
+	cs := countSettings{metrics: wc.Metrics{Lines: true, Words: true}, opts: wc.Options{BufferSize: 4096}, halt: haltNever}
+	var emitted []wc.FileResult
+	all, _ := countStream(names, cs, 3, func(batch []wc.FileResult) {
+		emitted = append(emitted, batch...)
+	})
+	if len(all) != 4 || len(emitted) != 4 {
+		t.Fatalf("got %d results, %d emitted, want 4", len(all), len(emitted))
+	}
+	for i, want := range []uint64{1, 2, 2} {
+		if r := emitted[i]; r.Index != i || r.Err != nil || r.Words != want {
+			t.Errorf("result %d = %+v, want %d words", i, r, want)
+		}
+	}
+	if emitted[3].Err == nil {
+		t.Error("missing file: expected an error")
+	}
+}
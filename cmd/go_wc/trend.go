@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// trendRecord is one line of a "go_wc trend" store: the totals of a run.
+// Every metric is kept, so that any of them can be shown later.
+type trendRecord struct {
+	Time  string `json:"time"` // RFC 3339, UTC
+	Files int    `json:"files"`
+	Lines uint64 `json:"lines"`
+	Words uint64 `json:"words"`
+	Chars uint64 `json:"chars"`
+	Bytes uint64 `json:"bytes"`
+}
+
+// result returns the counts of rec, for treeMetric.get.
+func (rec trendRecord) result() wc.FileResult {
+	return wc.FileResult{Lines: rec.Lines, Words: rec.Words, Chars: rec.Chars, Bytes: rec.Bytes}
+}
+
+// readTrend returns the records of the store path, oldest first; a store
+// that does not exist yet has none.
+func readTrend(path string) ([]trendRecord, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var recs []trendRecord
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		var rec trendRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		recs = append(recs, rec)
+	}
+	return recs, sc.Err()
+}
+
+// appendTrend adds rec to the end of the store path, creating it if needed.
+func appendTrend(path string, rec trendRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// sparkBars are the levels of a sparkline, lowest first.
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws values as bars scaled between their minimum and maximum;
+// values that are all equal are drawn at mid height.
+func sparkline(values []uint64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		i := len(sparkBars) / 2
+		if hi > lo {
+			i = int(float64(v-lo) / float64(hi-lo) * float64(len(sparkBars)-1))
+		}
+		b.WriteRune(sparkBars[i])
+	}
+	return b.String()
+}
+
+// percentChange formats the change from first to last: +12.5%, -3.0% or,
+// from nothing, n/a.
+func percentChange(first, last uint64) string {
+	if first == 0 {
+		if last == 0 {
+			return "+0.0%"
+		}
+		return "n/a"
+	}
+	pct := strconv.FormatFloat((float64(last)-float64(first))/float64(first)*100, 'f', 1, 64) + "%"
+	if last >= first {
+		pct = "+" + pct
+	}
+	return pct
+}
+
+// writeTrend prints, for each metric, a sparkline of recs, the last value
+// and its change since the first of recs.
+func writeTrend(w io.Writer, recs []trendRecord, metrics []treeMetric) error {
+	if len(recs) == 0 {
+		_, err := io.WriteString(w, "no runs recorded\n")
+		return err
+	}
+	since := recs[0].Time
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		since = t.Local().Format("2006-01-02 15:04")
+	}
+	span := fmt.Sprintf("over %d runs since %s", len(recs), since)
+	if len(recs) == 1 {
+		span = "since " + since
+	}
+	var b strings.Builder
+	width := 1
+	for _, m := range metrics {
+		width = max(width, len(strconv.FormatUint(m.get(recs[len(recs)-1].result()), 10)))
+	}
+	for _, m := range metrics {
+		values := make([]uint64, len(recs))
+		for i, rec := range recs {
+			values[i] = m.get(rec.result())
+		}
+		fmt.Fprintf(&b, "%-5s %s %*d %s %s\n", m.name, sparkline(values), width, values[len(values)-1], percentChange(values[0], values[len(values)-1]), span)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// runTrend implements "go_wc trend --db FILE": count the files, append
+// their totals to the store FILE and print how the totals moved over the
+// last runs. With --no-record nothing is counted or stored.
+func runTrend(args []string, stdout, stderr io.Writer) int {
+	var cfg cliConfig
+	fset := flag.NewFlagSet("go_wc trend", flag.ContinueOnError)
+	fset.SetOutput(io.Discard)
+	db := fset.String("db", "", "")
+	runs := fset.Int("n", 10, "")
+	noRecord := fset.Bool("no-record", false, "")
+	selected := metricFlags(fset)
+	fileSetFlags(fset, &cfg)
+	if err := fset.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if *db == "" {
+		fmt.Fprintln(stderr, "go_wc: trend: --db=FILE is required")
+		return 1
+	}
+	if *runs < 2 {
+		fmt.Fprintln(stderr, "go_wc: trend: -n must be at least 2")
+		return 1
+	}
+
+	recs, err := readTrend(*db)
+	if err != nil {
+		fmt.Fprintf(stderr, "go_wc: trend: %v\n", err)
+		return 1
+	}
+	if !*noRecord {
+		m := wc.Metrics{Lines: true, Words: true, Chars: true, Bytes: true}
+		total, files, err := countFileSet(cfg, fset.Args(), m)
+		if err != nil {
+			// a partial total would show as a drop
+			fmt.Fprintf(stderr, "go_wc: trend: %v\n", err)
+			return 1
+		}
+		rec := trendRecord{
+			Time:  time.Now().UTC().Format(time.RFC3339),
+			Files: files,
+			Lines: total.Lines,
+			Words: total.Words,
+			Chars: total.Chars,
+			Bytes: total.Bytes,
+		}
+		if err := appendTrend(*db, rec); err != nil {
+			fmt.Fprintf(stderr, "go_wc: trend: %v\n", err)
+			return 1
+		}
+		recs = append(recs, rec)
+	}
+	if len(recs) > *runs {
+		recs = recs[len(recs)-*runs:]
+	}
+	if err := writeTrend(stdout, recs, selected()); err != nil {
+		fmt.Fprintf(stderr, "go_wc: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSparkline(t *testing.T) {
+	for _, tc := range []struct {
+		values []uint64
+		want   string
+	}{
+		{nil, ""},
+		{[]uint64{5, 5, 5}, "▅▅▅"},
+		{[]uint64{0, 7, 14}, "▁▄█"},
+		{[]uint64{100, 107, 101, 100}, "▁█▂▁"},
+	} {
+		if got := sparkline(tc.values); got != tc.want {
+			t.Errorf("sparkline(%v) = %q, want %q", tc.values, got, tc.want)
+		}
+	}
+}
+
+func TestPercentChange(t *testing.T) {
+	for _, tc := range []struct {
+		first, last uint64
+		want        string
+	}{
+		{200, 225, "+12.5%"},
+		{200, 194, "-3.0%"},
+		{0, 0, "+0.0%"},
+		{0, 10, "n/a"},
+	} {
+		if got := percentChange(tc.first, tc.last); got != tc.want {
+			t.Errorf("percentChange(%d, %d) = %q, want %q", tc.first, tc.last, got, tc.want)
+		}
+	}
+}
+
+func TestRunTrend(t *testing.T) {
+	dir := t.TempDir()
+	doc, db := filepath.Join(dir, "doc.md"), filepath.Join(dir, "trend.jsonl")
+	var stdout, stderr strings.Builder
+	for _, text := range []string{"one two\n", "one two\nthree\n", "one two\nthree four five six\n"} {
+		os.WriteFile(doc, []byte(text), 0o644)
+		stdout.Reset()
+		if code := runTrend([]string{"--db", db, "-n", "2", "-w", doc}, &stdout, &stderr); code != 0 {
+			t.Fatalf("exit code %d: %s", code, stderr.String())
+		}
+	}
+	recs, err := readTrend(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 3 || recs[2].Words != 6 || recs[2].Lines != 2 || recs[2].Files != 1 || recs[0].Bytes != 8 {
+		t.Errorf("store: got %+v", recs)
+	}
+	if got := stdout.String(); !strings.HasPrefix(got, "words ▁█ 6 +100.0% over 2 runs since ") {
+		t.Errorf("output: got %q", got)
+	}
+
+	stdout.Reset()
+	if code := runTrend([]string{"--db", db, "--no-record"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("--no-record: exit code %d: %s", code, stderr.String())
+	}
+	if recs, _ := readTrend(db); len(recs) != 3 {
+		t.Errorf("--no-record stored a run: %d runs", len(recs))
+	}
+	if lines := strings.Split(strings.TrimSpace(stdout.String()), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[0], "lines ▁██  2 +100.0% over 3 runs") {
+		t.Errorf("--no-record output: got %q", stdout.String())
+	}
+
+	os.WriteFile(filepath.Join(dir, "bad.jsonl"), []byte("{\"lines\": 1}\nnot json\n"), 0o644)
+	for _, args := range [][]string{
+		{doc},
+		{"--db", db, "-n", "1", doc},
+		{"--db", filepath.Join(dir, "bad.jsonl"), "--no-record"},
+		{"--db", db, filepath.Join(dir, "missing")},
+	} {
+		if code := runTrend(args, &stdout, &stderr); code != 1 {
+			t.Errorf("%q: exit code %d, want 1", args, code)
+		}
+	}
+	if recs, _ := readTrend(db); len(recs) != 3 {
+		t.Errorf("a failed run was stored: %d runs", len(recs))
+	}
+}
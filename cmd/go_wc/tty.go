@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// minNameRoom is the fewest columns --truncate and --wrap leave for a file
+// name; on narrower terminals lines are printed as they are.
+const minNameRoom = 12
+
+// terminalWidth returns the number of columns of the terminal w writes to:
+// $COLUMNS if set, else what the terminal reports, else 80. It returns 0
+// when w is not a terminal, so that redirected output is left alone.
+func terminalWidth(w io.Writer) int {
+	if b, ok := w.(*bufferedOutput); ok {
+		w = b.f
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return 0
+	}
+	if st, err := f.Stat(); err != nil || st.Mode()&os.ModeCharDevice == 0 {
+		return 0
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	if n := ttyColumns(f); n > 0 {
+		return n
+	}
+	return 80
+}
+
+// fitName makes line, whose file name starts at byte start, fit in cols
+// columns by shortening the name in the middle with "…", or with wrap by
+// continuing it on lines indented to where it starts.
+func fitName(line string, start, cols int, wrap bool) string {
+	indent := utf8.RuneCountInString(line[:start])
+	name := []rune(line[start:])
+	room := cols - indent
+	if len(name) <= room || room < minNameRoom {
+		return line
+	}
+	if !wrap {
+		tail := (room - 1) / 2
+		return line[:start] + string(name[:room-1-tail]) + "…" + string(name[len(name)-tail:])
+	}
+	var sb strings.Builder
+	sb.WriteString(line[:start])
+	for len(name) > room {
+		sb.WriteString(string(name[:room]))
+		sb.WriteString("\n" + strings.Repeat(" ", indent))
+		name = name[room:]
+	}
+	sb.WriteString(string(name))
+	return sb.String()
+}
@@ -0,0 +1,46 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// ttyColumns asks the terminal f for its width, returning 0 on failure.
+func ttyColumns(f *os.File) int {
+	cols, _ := ttySize(f)
+	return cols
+}
+
+// ttySize asks the terminal f for its width and height, returning zeros
+// on failure.
+func ttySize(f *os.File) (cols, rows int) {
+	var ws struct{ rows, cols, xpixel, ypixel uint16 }
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 {
+		return 0, 0
+	}
+	return int(ws.cols), int(ws.rows)
+}
+
+// cbreak makes the terminal f pass each key on as it is typed, without
+// echoing it, while Ctrl-C still interrupts, and returns a function
+// restoring the mode it had.
+func cbreak(f *os.File) (restore func(), err error) {
+	var old syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlGetTermios, uintptr(unsafe.Pointer(&old))); errno != 0 {
+		return nil, errno
+	}
+	t := old
+	t.Lflag &^= syscall.ICANON | syscall.ECHO
+	t.Cc[syscall.VMIN], t.Cc[syscall.VTIME] = 1, 0
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlSetTermios, uintptr(unsafe.Pointer(&t))); errno != 0 {
+		return nil, errors.Join(errors.New("setting the terminal mode"), errno)
+	}
+	return func() {
+		syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlSetTermios, uintptr(unsafe.Pointer(&old)))
+	}, nil
+}
@@ -0,0 +1,23 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// ttyColumns cannot ask the terminal for its width here.
+func ttyColumns(*os.File) int {
+	return 0
+}
+
+// ttySize cannot ask the terminal for its size here.
+func ttySize(*os.File) (cols, rows int) {
+	return 0, 0
+}
+
+// cbreak cannot change the terminal mode here; keys arrive with Enter.
+func cbreak(*os.File) (restore func(), err error) {
+	return nil, errors.New("no terminal modes on this system")
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFitName(t *testing.T) {
+	line := "      3 /very/long/path/to/some/file.txt"
+	start := strings.Index(line, "/")
+	tests := []struct {
+		cols int
+		wrap bool
+		want string
+	}{
+		{80, false, line},
+		{28, false, "      3 /very/long…/file.txt"},
+		{28, true, "      3 /very/long/path/to/s\n        ome/file.txt"},
+		{24, true, "      3 /very/long/path/\n        to/some/file.txt"},
+		{16, false, line}, // too narrow to bother
+	}
+	for _, tt := range tests {
+		if got := fitName(line, start, tt.cols, tt.wrap); got != tt.want {
+			t.Errorf("fitName(%d, %v) =\n%q\nwant\n%q", tt.cols, tt.wrap, got, tt.want)
+		}
+	}
+	if got := fitName("1 ééééééééééééééééééé", 2, 16, false); got != "1 ééééééé…éééééé" {
+		t.Errorf("fitName() on multi-byte name = %q", got)
+	}
+}
+
+func TestTerminalWidthNotATerminal(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if n := terminalWidth(f); n != 0 {
+		t.Errorf("terminalWidth(file) = %d, want 0", n)
+	}
+	if n := terminalWidth(&strings.Builder{}); n != 0 {
+		t.Errorf("terminalWidth(builder) = %d, want 0", n)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// dashboard is the full-screen view of "go_wc watch --tui": the files
+// followed with their counts and how fast they grow, sortable by any
+// column, then the total and the throughput of all of them.
+type dashboard struct {
+	w       *watcher
+	metrics []treeMetric
+	every   time.Duration
+
+	sortBy int  // 0: the file name, then the metrics, then the rate
+	desc   bool // sort in descending order
+	sizes  map[string]int64
+	rates  map[string]float64 // bytes per second each file grew by in the last round
+	last   time.Time          // of the last round
+	stamp  time.Time          // of the last change
+	note   string             // the last failure
+}
+
+func newDashboard(w *watcher, metrics []treeMetric, every time.Duration) *dashboard {
+	return &dashboard{w: w, metrics: metrics, every: every, sizes: make(map[string]int64), rates: make(map[string]float64)}
+}
+
+// update takes in what a poll found at now.
+func (d *dashboard) update(round watchRound, now time.Time) {
+	elapsed := now.Sub(d.last).Seconds()
+	clear(d.rates)
+	for _, r := range round.changed {
+		if r.Err != nil {
+			d.note = fmt.Sprintf("%s: %v", quoteName(r.Filename), r.Err)
+			continue
+		}
+		f := d.w.files[r.Filename]
+		if old, ok := d.sizes[r.Filename]; ok && elapsed > 0 && f.size > old {
+			d.rates[r.Filename] = float64(f.size-old) / elapsed
+		}
+		d.sizes[r.Filename] = f.size
+	}
+	for _, name := range round.removed {
+		delete(d.sizes, name)
+	}
+	if len(round.changed)+len(round.removed) > 0 {
+		d.stamp = now
+	}
+	d.last = now
+}
+
+// key acts on a key typed: s sorts by the next column, r reverses the
+// order and q quits, which key reports.
+func (d *dashboard) key(k byte) (quit bool) {
+	switch k {
+	case 's':
+		d.sortBy = (d.sortBy + 1) % (len(d.metrics) + 2)
+		d.desc = d.sortBy > 0 // largest first, names from a
+	case 'r':
+		d.desc = !d.desc
+	case 'q':
+		return true
+	}
+	return false
+}
+
+// rate formats a throughput in bytes per second.
+func rate(bps float64) string {
+	switch {
+	case bps >= 1e9:
+		return fmt.Sprintf("%.1f GB/s", bps/1e9)
+	case bps >= 1e6:
+		return fmt.Sprintf("%.1f MB/s", bps/1e6)
+	case bps >= 1e3:
+		return fmt.Sprintf("%.1f kB/s", bps/1e3)
+	}
+	return fmt.Sprintf("%.0f B/s", bps)
+}
+
+// render draws the dashboard in a width by height terminal, as lines
+// without their ends.
+func (d *dashboard) render(width, height int) []string {
+	type row struct {
+		name  string
+		res   wc.FileResult
+		bytes float64
+	}
+	var rows []row
+	for _, name := range d.w.names {
+		if f := d.w.files[name]; f != nil {
+			rows = append(rows, row{name, f.res, d.rates[name]})
+		}
+	}
+	slices.SortStableFunc(rows, func(a, b row) int {
+		var c int
+		switch {
+		case d.sortBy == 0:
+			c = strings.Compare(a.name, b.name)
+		case d.sortBy <= len(d.metrics):
+			m := d.metrics[d.sortBy-1]
+			c = cmp.Compare(m.get(a.res), m.get(b.res))
+		default:
+			c = cmp.Compare(a.bytes, b.bytes)
+		}
+		if d.desc {
+			return -c
+		}
+		return c
+	})
+	total := d.w.total()
+	var throughput float64
+	for _, r := range rows {
+		throughput += r.bytes
+	}
+
+	heads := make([]string, 0, len(d.metrics)+2)
+	for _, m := range d.metrics {
+		heads = append(heads, strings.ToUpper(m.name))
+	}
+	heads = append(heads, "RATE", "FILE")
+	mark := "▲"
+	if d.desc {
+		mark = "▼"
+	}
+	sorted := len(d.metrics) + 1 // FILE
+	if d.sortBy > 0 {
+		sorted = d.sortBy - 1
+	}
+	heads[sorted] += mark
+	widths := make([]int, len(heads)-1)
+	for i := range widths {
+		widths[i] = utf8.RuneCountInString(heads[i])
+	}
+	cells := func(res wc.FileResult, bps float64) []string {
+		out := make([]string, 0, len(widths))
+		for _, m := range d.metrics {
+			out = append(out, strconv.FormatUint(m.get(res), 10))
+		}
+		return append(out, rate(bps))
+	}
+	for i, s := range cells(total, throughput) {
+		widths[i] = max(widths[i], len(s))
+	}
+	for _, r := range rows {
+		for i, s := range cells(r.res, r.bytes) {
+			widths[i] = max(widths[i], len(s))
+		}
+	}
+	line := func(c []string, name string) string {
+		var b strings.Builder
+		for i, s := range c {
+			fmt.Fprintf(&b, "%*s  ", widths[i], s)
+		}
+		b.WriteString(name)
+		return fitName(b.String(), b.Len()-len(name), width, false)
+	}
+
+	stamp := "-"
+	if !d.stamp.IsZero() {
+		stamp = d.stamp.Format("15:04:05")
+	}
+	out := []string{
+		fmt.Sprintf("go_wc watch: %d files, polled every %v, last change %s", len(rows), d.every, stamp),
+		line(heads[:len(heads)-1], heads[len(heads)-1]),
+	}
+	footer := []string{
+		line(cells(total, throughput), "total"),
+		"s: sort by the next column  r: reverse  q: quit",
+	}
+	if d.note != "" {
+		footer = append(footer, "go_wc: "+d.note)
+	}
+	more := 0
+	if room := height - len(out) - len(footer); len(rows) > room && room > 0 {
+		more = len(rows) - room + 1
+		rows = rows[:room-1]
+	}
+	for _, r := range rows {
+		out = append(out, line(cells(r.res, r.bytes), quoteName(r.name)))
+	}
+	if more > 0 {
+		out = append(out, fmt.Sprintf("... %d more", more))
+	}
+	return append(out, footer...)
+}
+
+// runDashboard shows the dashboard of w on the terminal out, polling
+// every interval and redrawing on keys from in, until q or ctx is done.
+func runDashboard(ctx context.Context, out, in *os.File, w *watcher, metrics []treeMetric, interval time.Duration) error {
+	if restore, err := cbreak(in); err == nil {
+		defer restore()
+	}
+	keys := make(chan byte)
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if n, err := in.Read(buf); n == 0 || err != nil {
+				return
+			}
+			keys <- buf[0]
+		}
+	}()
+	io.WriteString(out, "\x1b[?1049h\x1b[?25l") // the alternate screen, no cursor
+	defer io.WriteString(out, "\x1b[?25h\x1b[?1049l")
+
+	d := newDashboard(w, metrics, interval)
+	draw := func() error {
+		cols, rows := ttySize(out)
+		if cols <= 0 {
+			cols = terminalWidth(out)
+		}
+		if rows <= 0 {
+			rows = 24
+		}
+		// no line end after the last line, which would scroll the screen
+		screen := "\x1b[H" + strings.Join(d.render(cols, rows), "\x1b[K\r\n") + "\x1b[K\x1b[J"
+		_, err := io.WriteString(out, screen)
+		return err
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	d.update(w.poll(), time.Now())
+	for {
+		if err := draw(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case k := <-keys:
+			if d.key(k) {
+				return nil
+			}
+		case <-tick.C:
+			d.update(w.poll(), time.Now())
+		}
+	}
+}
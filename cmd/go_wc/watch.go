@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+// watchedFile is a file "go_wc watch" follows: its counts as of the size
+// and modification time it had when last counted.
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+	res     wc.FileResult
+}
+
+// watcher polls the operands of "go_wc watch", files or directories whose
+// files are listed again each round, and recounts the files that are new
+// or whose size or modification time changed.
+type watcher struct {
+	operands []string
+	cs       countSettings
+	jobs     int
+	files    map[string]*watchedFile
+	names    []string        // of files, as last listed
+	failed   map[string]bool // names that failed last round
+}
+
+func newWatcher(operands []string, cs countSettings, jobs int) *watcher {
+	return &watcher{operands: operands, cs: cs, jobs: jobs, files: make(map[string]*watchedFile)}
+}
+
+// watchRound is what a poll found.
+type watchRound struct {
+	changed []wc.FileResult // new or changed files, with failures, in listing order
+	removed []string        // files gone since the last round
+}
+
+// list returns the files of the operands, those of a directory in
+// lexical order beneath it, leaving out .git directories.
+func (w *watcher) list() ([]string, []wc.FileResult) {
+	var names []string
+	var failed []wc.FileResult
+	for _, op := range w.operands {
+		st, err := os.Stat(op)
+		if err != nil {
+			failed = append(failed, wc.FileResult{Filename: op, Err: err})
+			continue
+		}
+		if !st.IsDir() {
+			names = append(names, op)
+			continue
+		}
+		walker := wc.Walker{Root: op, SkipDir: func(rel string) bool { return path.Base(rel) == ".git" }}
+		err = walker.Walk(func(rel string) error {
+			names = append(names, filepath.Join(op, filepath.FromSlash(rel)))
+			return nil
+		})
+		if err != nil {
+			failed = append(failed, wc.FileResult{Filename: op, Err: err})
+		}
+	}
+	return names, failed
+}
+
+// poll lists the files again and counts those that are new or changed.
+// A failure is reported once, until the file can be counted again.
+func (w *watcher) poll() watchRound {
+	var round watchRound
+	names, failed := w.list()
+	seen := make(map[string]bool, len(names))
+	var recount []string
+	var stats []os.FileInfo
+	for _, name := range names {
+		seen[name] = true
+		st, err := os.Stat(name)
+		if err != nil {
+			failed = append(failed, wc.FileResult{Filename: name, Err: err})
+			continue
+		}
+		if f := w.files[name]; f != nil && f.size == st.Size() && f.modTime.Equal(st.ModTime()) {
+			continue
+		}
+		recount = append(recount, name)
+		stats = append(stats, st)
+	}
+	for i, r := range countInputs(recount, w.cs, w.jobs) {
+		if r.Err != nil {
+			failed = append(failed, r)
+			delete(w.files, r.Filename)
+			continue
+		}
+		w.files[r.Filename] = &watchedFile{size: stats[i].Size(), modTime: stats[i].ModTime(), res: r}
+		round.changed = append(round.changed, r)
+	}
+	failing := make(map[string]bool, len(failed))
+	for _, r := range failed {
+		failing[r.Filename] = true
+		if !w.failed[r.Filename] {
+			round.changed = append(round.changed, r)
+		}
+	}
+	w.failed = failing
+	for _, name := range w.names {
+		if !seen[name] && w.files[name] != nil {
+			delete(w.files, name)
+			round.removed = append(round.removed, name)
+		}
+	}
+	w.names = names
+	return round
+}
+
+// total returns the sum of the counts of the files being followed.
+func (w *watcher) total() wc.FileResult {
+	var tot wc.Totals
+	for _, name := range w.names {
+		if f := w.files[name]; f != nil {
+			tot.Add(f.res)
+		}
+	}
+	return tot.Result()
+}
+
+// writeWatchRound prints a line per file of round, stamped with now: the
+// counts of metrics aligned as by wc, or "removed", then the total of
+// the files followed when there are several. Failures go to stderr.
+func writeWatchRound(stdout, stderr io.Writer, w *watcher, round watchRound, metrics []treeMetric, now time.Time) error {
+	if len(round.changed) == 0 && len(round.removed) == 0 {
+		return nil
+	}
+	stamp := now.Format("15:04:05")
+	total := w.total()
+	width := 1
+	for _, m := range metrics {
+		width = max(width, len(strconv.FormatUint(m.get(total), 10)))
+	}
+	var b strings.Builder
+	line := func(r wc.FileResult, name string) {
+		b.WriteString(stamp)
+		for _, m := range metrics {
+			fmt.Fprintf(&b, " %*d", width, m.get(r))
+		}
+		b.WriteString(" " + quoteName(name) + "\n")
+	}
+	for _, r := range round.changed {
+		if r.Err != nil {
+			fmt.Fprintf(stderr, "go_wc: %s: %v\n", quoteName(r.Filename), r.Err)
+			continue
+		}
+		line(r, r.Filename)
+	}
+	for _, name := range round.removed {
+		fmt.Fprintf(&b, "%s removed %s\n", stamp, quoteName(name))
+	}
+	if len(w.files) > 1 {
+		line(total, "total")
+	}
+	_, err := io.WriteString(stdout, b.String())
+	return err
+}
+
+// runWatch implements "go_wc watch FILE...": count the files, and those
+// beneath directories, then poll them every --interval and print the
+// counts of the files that changed, or with --tui show them all on a
+// dashboard, until interrupted.
+func runWatch(args []string, stdout, stderr io.Writer) int {
+	fset := flag.NewFlagSet("go_wc watch", flag.ContinueOnError)
+	fset.SetOutput(io.Discard)
+	selected := metricFlags(fset)
+	interval := fset.Duration("interval", 2*time.Second, "")
+	jobs := fset.Int("jobs", runtime.GOMAXPROCS(0), "")
+	fset.IntVar(jobs, "j", runtime.GOMAXPROCS(0), "")
+	encoding := fset.String("encoding", "", "")
+	tui := fset.Bool("tui", false, "")
+	if err := fset.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if fset.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: go_wc watch [OPTIONS] FILE...")
+		return 1
+	}
+	if *interval <= 0 {
+		fmt.Fprintln(stderr, "go_wc: watch: --interval must be positive")
+		return 1
+	}
+
+	metrics := selected()
+	var m wc.Metrics
+	for _, tm := range metrics {
+		tm.set(&m)
+	}
+	cs := countSettings{
+		metrics: m,
+		opts:    wc.Options{BufferSize: 1024 * 1024, Locale: locale.Detect(*encoding)},
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	w := newWatcher(fset.Args(), cs, *jobs)
+	if *tui {
+		out, ok := stdout.(*os.File)
+		if !ok || terminalWidth(out) == 0 {
+			fmt.Fprintln(stderr, "go_wc: watch: --tui needs a terminal")
+			return 1
+		}
+		if err := runDashboard(ctx, out, os.Stdin, w, metrics, *interval); err != nil {
+			fmt.Fprintf(stderr, "go_wc: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	tick := time.NewTicker(*interval)
+	defer tick.Stop()
+	for {
+		if err := writeWatchRound(stdout, stderr, w, w.poll(), metrics, time.Now()); err != nil {
+			fmt.Fprintf(stderr, "go_wc: %v\n", err)
+			return 1
+		}
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-tick.C:
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+func TestWatcherPoll(t *testing.T) {
+	dir := writeTree(t, map[string]string{"logs/a.log": "one two\n", "b.txt": "x\n"})
+	logs, b := filepath.Join(dir, "logs"), filepath.Join(dir, "b.txt")
+	metrics := []treeMetric{treeMetrics[0], treeMetrics[1]}
+	w := newWatcher([]string{logs, b}, countSettings{metrics: wc.Metrics{Lines: true, Words: true}}, 1)
+	now := time.Date(2026, 10, 16, 9, 14, 2, 0, time.Local)
+
+	round := func(want string) {
+		t.Helper()
+		var stdout, stderr strings.Builder
+		if err := writeWatchRound(&stdout, &stderr, w, w.poll(), metrics, now); err != nil {
+			t.Fatal(err)
+		}
+		if got := stdout.String(); got != want {
+			t.Errorf("got\n%swant\n%s", got, want)
+		}
+	}
+	a := filepath.Join(logs, "a.log")
+	round("09:14:02 1 2 " + a + "\n09:14:02 1 1 " + b + "\n09:14:02 2 3 total\n")
+	round("")
+
+	f, err := os.OpenFile(a, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("three\n")
+	f.Close()
+	if err := os.WriteFile(filepath.Join(logs, "c.log"), []byte("c\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := filepath.Join(logs, "c.log")
+	round("09:14:02 2 3 " + a + "\n09:14:02 1 1 " + c + "\n09:14:02 4 5 total\n")
+
+	if err := os.Remove(c); err != nil {
+		t.Fatal(err)
+	}
+	round("09:14:02 removed " + c + "\n09:14:02 3 4 total\n")
+}
+
+func TestWatcherReportsFailureOnce(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	w := newWatcher([]string{missing}, countSettings{metrics: wc.Metrics{Lines: true}}, 1)
+	for i, want := range []int{1, 0} {
+		if got := len(w.poll().changed); got != want {
+			t.Errorf("round %d: %d changes, want %d", i, got, want)
+		}
+	}
+	if err := os.WriteFile(missing, []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if r := w.poll().changed; len(r) != 1 || r[0].Err != nil || r[0].Lines != 1 {
+		t.Errorf("after creating it: %+v", r)
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.log": "one\n", "b.log": "one two\nthree\n"})
+	a, b := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	w := newWatcher([]string{dir}, countSettings{metrics: wc.Metrics{Lines: true, Words: true}}, 1)
+	d := newDashboard(w, []treeMetric{treeMetrics[0], treeMetrics[1]}, 2*time.Second)
+	start := time.Date(2026, 10, 16, 9, 14, 2, 0, time.Local)
+	d.update(w.poll(), start)
+	if err := os.WriteFile(a, []byte("one\n"+strings.Repeat("x y\n", 1000)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d.update(w.poll(), start.Add(2*time.Second))
+
+	got := strings.Join(d.render(80, 24), "\n")
+	want := "go_wc watch: 2 files, polled every 2s, last change 09:14:04\n" +
+		"LINES  WORDS      RATE  FILE▲\n" +
+		" 1001   2001  2.0 kB/s  " + a + "\n" +
+		"    2      3     0 B/s  " + b + "\n" +
+		" 1003   2004  2.0 kB/s  total\n" +
+		"s: sort by the next column  r: reverse  q: quit"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	d.key('s') // by lines, largest first
+	d.key('r')
+	if got := strings.Fields(d.render(80, 24)[2]); got[0] != "2" || got[len(got)-1] != b {
+		t.Errorf("sorted by lines, smallest first: first row %q", got)
+	}
+	if d.key('q') != true {
+		t.Error("q does not quit")
+	}
+	if got := d.render(80, 5); got[2] != "... 2 more" || len(got) != 5 {
+		t.Errorf("in 5 lines: %q", got)
+	}
+}
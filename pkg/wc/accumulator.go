@@ -0,0 +1,298 @@
+package wc
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Accumulator computes one metric of type T from the bytes of a stream.
+// Feed receives the stream in order, split anywhere. Merge returns the
+// accumulator of this one's bytes followed by next's, leaving both
+// unchanged, so that chunks counted apart combine as if counted in one
+// pass. Result reads the metric for the bytes fed so far.
+//
+// Implementations must be pointers to structs whose exported fields hold
+// what Merge and Result need, so that ChunkResult can be serialized.
+//
+// Metrics of the raw bytes, such as the byte count, line endings and
+// string matches, are Accumulators. Metrics of characters, lines and
+// words are TextAccumulators.
+type Accumulator[T any] interface {
+	Feed(p []byte)
+	Merge(next Accumulator[T]) Accumulator[T]
+	Result() T
+}
+
+// TextAccumulator is an Accumulator fed the characters of a stream rather
+// than its bytes: a Counter decodes every write once, for all of them. A
+// character split between writes or chunks reaches exactly one of them
+// whole, so Merge only joins what runs on across the boundary, such as a
+// line or a word.
+type TextAccumulator[T any] interface {
+	FeedText(t *Text)
+	Merge(next TextAccumulator[T]) TextAccumulator[T]
+	Result() T
+}
+
+// Text is a run of the characters of a stream, as a Counter decodes it.
+// Bytes holds characters of one byte each: ASCII, or any byte in the C
+// locale. Otherwise Chars holds the characters, decoded from Raw. Only one
+// of Bytes and Chars is set.
+type Text struct {
+	Bytes []byte
+	Chars []Char
+	Raw   []byte
+}
+
+// Char is a character of a Text.
+type Char struct {
+	Off     int   // where its bytes start in Text.Raw
+	Rune    rune  // utf8.RuneError for an invalid byte
+	Size    uint8 // its bytes in Text.Raw
+	Space   bool  // white space, set for the metrics that need it
+	Invalid bool  // a byte that starts no character, counted as one
+}
+
+// text returns the bytes of c in t.
+func (t *Text) text(c Char) []byte { return t.Raw[c.Off : c.Off+int(c.Size)] }
+
+// len returns the number of characters in t.
+func (t *Text) len() int { return len(t.Bytes) + len(t.Chars) }
+
+// endsLine reports whether the last character of t, which must not be
+// empty, is a '\n'.
+func (t *Text) endsLine() bool {
+	if len(t.Bytes) > 0 {
+		return t.Bytes[len(t.Bytes)-1] == '\n'
+	}
+	c := t.Chars[len(t.Chars)-1]
+	return c.Rune == '\n'
+}
+
+// invalidText returns the Text of p counted as invalid bytes, the way a
+// partial sequence at the end of the input is.
+func invalidText(p []byte) *Text {
+	t := &Text{Chars: make([]Char, len(p)), Raw: p}
+	for i := range p {
+		t.Chars[i] = invalidChar(p, i)
+	}
+	return t
+}
+
+// accMetric describes a metric computed by an Accumulator, or by a
+// TextAccumulator when it has newText rather than new. Registering one
+// with registerMetric is all it takes for Counter to feed it, for
+// ChunkResult to carry and merge it and for FileResult to receive it.
+type accMetric[T any] struct {
+	name    string // key in ChunkResult.Accumulators
+	on      func(Metrics, Options) bool
+	new     func(m Metrics, opt Options, chunk bool) Accumulator[T] // chunk: see NewChunkCounter
+	newText func(m Metrics, opt Options, chunk bool) TextAccumulator[T]
+	store   func(*FileResult, T)
+}
+
+// accDef is an accMetric with its result type erased.
+type accDef interface {
+	enabled(Metrics, Options) bool
+	start(m Metrics, opt Options, chunk bool) accumulator
+	decode(data []byte) (accumulator, error)
+	text() bool
+}
+
+// accumulator is an Accumulator or TextAccumulator with its result type
+// erased, as counters and chunks hold them. Each ignores what the other
+// kind is fed.
+type accumulator interface {
+	Feed(p []byte)
+	feedText(t *Text)
+	merge(next accumulator) accumulator
+	store(*FileResult)
+	def() accDef
+	state() any // what is serialized
+}
+
+// accMetrics are the registered metrics, by name.
+var accMetrics = map[string]accDef{}
+
+// accOrder lists accMetrics in registration order.
+var accOrder []string
+
+// registerMetric adds d to the metrics counters compute with accumulators.
+// It is meant to be called from init functions.
+func registerMetric[T any](d *accMetric[T]) {
+	if _, dup := accMetrics[d.name]; dup {
+		panic("wc: metric " + d.name + " registered twice")
+	}
+	accMetrics[d.name] = d
+	accOrder = append(accOrder, d.name)
+}
+
+func (d *accMetric[T]) enabled(m Metrics, opt Options) bool { return d.on(m, opt) }
+
+func (d *accMetric[T]) text() bool { return d.newText != nil }
+
+func (d *accMetric[T]) start(m Metrics, opt Options, chunk bool) accumulator {
+	if d.text() {
+		return &boundText[T]{d, d.newText(m, opt, chunk)}
+	}
+	return &boundAcc[T]{d, d.new(m, opt, chunk)}
+}
+
+func (d *accMetric[T]) decode(data []byte) (accumulator, error) {
+	a := d.start(Metrics{}, Options{}, false)
+	if err := json.Unmarshal(data, a.state()); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// boundAcc ties an Accumulator to its metric.
+type boundAcc[T any] struct {
+	d *accMetric[T]
+	Accumulator[T]
+}
+
+func (b *boundAcc[T]) merge(next accumulator) accumulator {
+	return &boundAcc[T]{b.d, b.Accumulator.Merge(next.(*boundAcc[T]).Accumulator)}
+}
+
+func (b *boundAcc[T]) store(r *FileResult) { b.d.store(r, b.Result()) }
+
+func (b *boundAcc[T]) def() accDef { return b.d }
+
+func (b *boundAcc[T]) state() any { return b.Accumulator }
+
+func (b *boundAcc[T]) feedText(*Text) {}
+
+// boundText ties a TextAccumulator to its metric.
+type boundText[T any] struct {
+	d *accMetric[T]
+	TextAccumulator[T]
+}
+
+func (b *boundText[T]) Feed([]byte) {}
+
+func (b *boundText[T]) feedText(t *Text) { b.FeedText(t) }
+
+func (b *boundText[T]) merge(next accumulator) accumulator {
+	return &boundText[T]{b.d, b.TextAccumulator.Merge(next.(*boundText[T]).TextAccumulator)}
+}
+
+func (b *boundText[T]) store(r *FileResult) { b.d.store(r, b.Result()) }
+
+func (b *boundText[T]) def() accDef { return b.d }
+
+func (b *boundText[T]) state() any { return b.TextAccumulator }
+
+// Accumulators holds the state of the accumulator-based metrics of a
+// chunk, keyed by metric name. It serializes as an object of their states.
+type Accumulators map[string]accumulator
+
+// startAccumulators returns fresh accumulators for the metrics m and opt
+// turn on, or nil if there are none, for a stream or, with chunk set, a
+// chunk that may begin anywhere in one.
+func startAccumulators(m Metrics, opt Options, chunk bool) Accumulators {
+	var out Accumulators
+	for _, name := range accOrder {
+		if d := accMetrics[name]; d.enabled(m, opt) {
+			if out == nil {
+				out = make(Accumulators)
+			}
+			out[name] = d.start(m, opt, chunk)
+		}
+	}
+	return out
+}
+
+func (as Accumulators) feed(p []byte) {
+	for _, a := range as {
+		a.Feed(p)
+	}
+}
+
+func (as Accumulators) feedText(t *Text) {
+	for _, a := range as {
+		a.feedText(t)
+	}
+}
+
+// startText returns fresh accumulators for the metrics of as computed
+// from characters, to count characters whose bytes as has been fed
+// elsewhere.
+func (as Accumulators) startText(m Metrics, opt Options, chunk bool) Accumulators {
+	var out Accumulators
+	for name, a := range as {
+		if a.def().text() {
+			if out == nil {
+				out = make(Accumulators)
+			}
+			out[name] = a.def().start(m, opt, chunk)
+		}
+	}
+	return out
+}
+
+// snapshot returns a copy of as that later feeding does not affect.
+func (as Accumulators) snapshot() Accumulators {
+	return Accumulators(nil).merge(as)
+}
+
+// merge returns the accumulators of as followed by next. A metric missing
+// on one side counts as fed nothing there.
+func (as Accumulators) merge(next Accumulators) Accumulators {
+	if len(as)+len(next) == 0 {
+		return nil
+	}
+	out := make(Accumulators, len(as)+len(next))
+	for name, a := range as {
+		b, ok := next[name]
+		if !ok {
+			b = a.def().start(Metrics{}, Options{}, false)
+		}
+		out[name] = a.merge(b)
+	}
+	for name, b := range next {
+		if _, ok := as[name]; !ok {
+			out[name] = b.def().start(Metrics{}, Options{}, false).merge(b)
+		}
+	}
+	return out
+}
+
+// store sets the results of the accumulators in r.
+func (as Accumulators) store(r *FileResult) {
+	for _, a := range as {
+		a.store(r)
+	}
+}
+
+func (as Accumulators) MarshalJSON() ([]byte, error) {
+	states := make(map[string]any, len(as))
+	for name, a := range as {
+		states[name] = a.state()
+	}
+	return json.Marshal(states)
+}
+
+func (as *Accumulators) UnmarshalJSON(data []byte) error {
+	var states map[string]json.RawMessage
+	if err := json.Unmarshal(data, &states); err != nil {
+		return err
+	}
+	*as = nil
+	for name, raw := range states {
+		d, ok := accMetrics[name]
+		if !ok {
+			return fmt.Errorf("wc: unknown metric %q", name)
+		}
+		a, err := d.decode(raw)
+		if err != nil {
+			return fmt.Errorf("wc: metric %q: %w", name, err)
+		}
+		if *as == nil {
+			*as = make(Accumulators)
+		}
+		(*as)[name] = a
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package wc
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestLineEndingsAcrossChunks(t *testing.T) {
+	data := []byte("a\r\nb\rc\n\r\r\n\n\r")
+	m := Metrics{LineEndings: true}
+	want := CountBytes(data, m, Options{})
+	if want.LFEndings != 2 || want.CRLFEndings != 2 || want.CREndings != 3 {
+		t.Fatalf("single pass: LF=%d CRLF=%d CR=%d, want 2 2 3", want.LFEndings, want.CRLFEndings, want.CREndings)
+	}
+	for i := 0; i <= len(data); i++ {
+		for j := i; j <= len(data); j++ {
+			got := MergeChunks([]ChunkResult{
+				CountChunk(data[:i], m, Options{}),
+				CountChunk(data[i:j], m, Options{}),
+				CountChunk(data[j:], m, Options{}),
+			}).Final()
+			if got.LFEndings != want.LFEndings || got.CRLFEndings != want.CRLFEndings || got.CREndings != want.CREndings {
+				t.Errorf("split at %d, %d: LF=%d CRLF=%d CR=%d", i, j, got.LFEndings, got.CRLFEndings, got.CREndings)
+			}
+		}
+	}
+}
+
+func TestLineLengthsAcrossChunks(t *testing.T) {
+	data := []byte("\nab\r\nabcd\n\nx")
+	m := Metrics{LineLengths: true}
+	want := CountBytes(data, m, Options{})
+	// two empty lines, x, ab\r and abcd
+	if !slices.Equal(want.LineLengths, []uint64{2, 1, 1, 1}) {
+		t.Fatalf("single pass: %v, want [2 1 1 1]", want.LineLengths)
+	}
+	for i := 0; i <= len(data); i++ {
+		for j := i; j <= len(data); j++ {
+			got := MergeChunks([]ChunkResult{
+				CountChunk(data[:i], m, Options{}),
+				CountChunk(data[i:j], m, Options{}),
+				CountChunk(data[j:], m, Options{}),
+			}).Final()
+			if !slices.Equal(got.LineLengths, want.LineLengths) {
+				t.Errorf("split at %d, %d: %v", i, j, got.LineLengths)
+			}
+		}
+	}
+	if got := CountBytes(nil, m, Options{}).LineLengths; got != nil {
+		t.Errorf("empty input: %v, want none", got)
+	}
+}
+
+func TestAccumulatorsJSON(t *testing.T) {
+	m := Metrics{LineEndings: true}
+	a := CountChunk([]byte("x\r"), m, Options{})
+	b, err := json.Marshal(CountChunk([]byte("\ny\n"), m, Options{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ChunkResult
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	got := a.Merge(decoded).Final()
+	if got.LFEndings != 1 || got.CRLFEndings != 1 || got.CREndings != 0 {
+		t.Errorf("after a JSON round trip: LF=%d CRLF=%d CR=%d, want 1 1 0", got.LFEndings, got.CRLFEndings, got.CREndings)
+	}
+	if err := json.Unmarshal([]byte(`{"Accumulators":{"nope":{}}}`), &decoded); err == nil {
+		t.Error("expected an error for an unknown metric")
+	}
+}
@@ -0,0 +1,135 @@
+package wc
+
+// multiMatcherMin is the number of Options.CountStrings from which a single
+// Aho-Corasick scan beats running one bytes.Index scan per string. Index is
+// vectorized, so it takes about this many strings for the one pass to win.
+const multiMatcherMin = 16
+
+// multiMatcher counts non-overlapping occurrences of many literal strings
+// in one pass, with the same result as a stringMatcher per string. It runs
+// an Aho-Corasick automaton compiled to a DFA over byte classes: bytes
+// that occur in no string share a class, which keeps the table small. The
+// automaton state carries across writes, so matches spanning two writes
+// need no extra work.
+type multiMatcher struct {
+	class  [256]uint16 // byte -> column in delta
+	stride int         // columns per state
+	delta  []int32     // state*stride + class -> next state
+	own    [][]int32   // strings ending exactly at each state
+	dict   []int32     // next state on the failure chain with own strings, or -1
+	term   []bool      // own or dict strings end at the state
+
+	lens   []uint64 // length of each string
+	next   []uint64 // earliest start of the next counted occurrence
+	counts []uint64
+	state  int32
+	pos    uint64 // bytes consumed so far
+}
+
+func newMultiMatcher(pats []string) *multiMatcher {
+	mm := &multiMatcher{
+		lens:   make([]uint64, len(pats)),
+		next:   make([]uint64, len(pats)),
+		counts: make([]uint64, len(pats)),
+	}
+	classes := 1 // class 0 holds the bytes no string contains
+	for _, p := range pats {
+		for i := 0; i < len(p); i++ {
+			if mm.class[p[i]] == 0 {
+				mm.class[p[i]] = uint16(classes)
+				classes++
+			}
+		}
+	}
+	mm.stride = classes
+	newState := func() int32 {
+		for i := 0; i < mm.stride; i++ {
+			mm.delta = append(mm.delta, -1)
+		}
+		mm.own = append(mm.own, nil)
+		return int32(len(mm.own) - 1)
+	}
+	newState() // the root
+
+	// the trie of the strings
+	for i, p := range pats {
+		mm.lens[i] = uint64(len(p))
+		if len(p) == 0 {
+			continue
+		}
+		s := int32(0)
+		for j := 0; j < len(p); j++ {
+			k := int(s)*mm.stride + int(mm.class[p[j]])
+			if mm.delta[k] < 0 {
+				t := newState()
+				mm.delta[k] = t
+			}
+			s = mm.delta[k]
+		}
+		mm.own[s] = append(mm.own[s], int32(i))
+	}
+
+	// failure links, breadth first, completing delta into a DFA
+	n := len(mm.own)
+	fail := make([]int32, n)
+	mm.dict = make([]int32, n)
+	mm.term = make([]bool, n)
+	mm.dict[0] = -1
+	queue := make([]int32, 0, n)
+	for c := 0; c < mm.stride; c++ {
+		if t := mm.delta[c]; t > 0 {
+			fail[t], mm.dict[t] = 0, -1
+			queue = append(queue, t)
+		} else {
+			mm.delta[c] = 0
+		}
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		mm.term[s] = len(mm.own[s]) > 0 || mm.dict[s] >= 0
+		row := int(s) * mm.stride
+		frow := int(fail[s]) * mm.stride
+		for c := 0; c < mm.stride; c++ {
+			t := mm.delta[row+c]
+			if t < 0 {
+				mm.delta[row+c] = mm.delta[frow+c]
+				continue
+			}
+			f := mm.delta[frow+c]
+			fail[t] = f
+			if len(mm.own[f]) > 0 {
+				mm.dict[t] = f
+			} else {
+				mm.dict[t] = mm.dict[f]
+			}
+			queue = append(queue, t)
+		}
+	}
+	return mm
+}
+
+func (mm *multiMatcher) write(p []byte) {
+	s, pos := mm.state, mm.pos
+	for _, b := range p {
+		s = mm.delta[int(s)*mm.stride+int(mm.class[b])]
+		pos++
+		if mm.term[s] {
+			mm.found(s, pos)
+		}
+	}
+	mm.state, mm.pos = s, pos
+}
+
+// found counts the strings ending at state s, just before offset end,
+// unless they overlap the previous occurrence of the same string.
+func (mm *multiMatcher) found(s int32, end uint64) {
+	for t := s; t >= 0; t = mm.dict[t] {
+		for _, i := range mm.own[t] {
+			if end-mm.lens[i] >= mm.next[i] {
+				mm.counts[i]++
+				mm.next[i] = end
+			}
+		}
+	}
+}
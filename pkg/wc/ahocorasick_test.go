@@ -0,0 +1,73 @@
+package wc
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestMultiMatcherMatchesStringMatcher(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	alphabet := []byte("abE\n\xff")
+	for iter := 0; iter < 2000; iter++ {
+		data := make([]byte, rng.Intn(80))
+		for i := range data {
+			data[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		pats := make([]string, multiMatcherMin+rng.Intn(6))
+		for i := range pats {
+			p := make([]byte, rng.Intn(5)) // may be empty or repeat another
+			for j := range p {
+				p[j] = alphabet[rng.Intn(3)]
+			}
+			pats[i] = string(p)
+		}
+
+		want := make([]uint64, len(pats))
+		for i, p := range pats {
+			sm := newStringMatcher(p)
+			sm.write(data)
+			want[i] = sm.count
+		}
+		mm := newMultiMatcher(pats)
+		for rest := data; len(rest) > 0; {
+			n := 1 + rng.Intn(len(rest))
+			mm.write(rest[:n])
+			rest = rest[n:]
+		}
+		if !reflect.DeepEqual(mm.counts, want) {
+			t.Fatalf("counts of %q in %q: got %v, want %v", pats, data, mm.counts, want)
+		}
+	}
+}
+
+func TestCountManyStrings(t *testing.T) {
+	data := []byte("INFO ok\nERROR one\nERRORERROR\nERR\nOR\n")
+	strs := []string{"ERROR", "\n", "RR", "O", "INFO ok\n"}
+	want := []uint64{3, 5, 4, 5, 1}
+	for len(strs) < multiMatcherMin {
+		strs = append(strs, fmt.Sprintf("absent%d", len(strs)))
+		want = append(want, 0)
+	}
+	opts := Options{BufferSize: 4, CountStrings: strs}
+	got := CountBytes(data, Metrics{Lines: true}, opts)
+	if !reflect.DeepEqual(got.StringCounts, want) {
+		t.Errorf("StringCounts: got %v, want %v", got.StringCounts, want)
+	}
+}
+
+func BenchmarkCountManyStrings(b *testing.B) {
+	pats := make([]string, 200)
+	for i := range pats {
+		pats[i] = fmt.Sprintf("code=%d ", 1000+i*7)
+	}
+	data := bytes.Repeat([]byte("2024-01-01 12:00:00 WARN request failed code=1007 after 3 retries\n"), 16<<10)
+	opts := Options{CountStrings: pats}
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CountBytes(data, Metrics{}, opts)
+	}
+}
@@ -0,0 +1,75 @@
+package wc
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Apostrophe names how Options.Apostrophe treats apostrophes in words.
+type Apostrophe int
+
+const (
+	// ApostropheNone counts an apostrophe like any other character of a
+	// word, as wc does: "don't" is one word, and so is a lone "'" or the
+	// quotes of "'twas'". It is the default.
+	ApostropheNone Apostrophe = iota
+	// ApostropheInternal keeps an apostrophe in a word only between two of
+	// its other characters, so that "don't" and "o’clock" are one word
+	// each, while apostrophes that start or end a word, as single
+	// quotation marks do, belong to no word, and a lone one is no word.
+	ApostropheInternal
+	// ApostropheSplit makes every apostrophe end the word it is in, as a
+	// space does: "don't" is two words, "don" and "t".
+	ApostropheSplit
+)
+
+// ParseApostrophe returns the Apostrophe called name, "internal" or
+// "split".
+func ParseApostrophe(name string) (Apostrophe, error) {
+	switch name {
+	case "internal":
+		return ApostropheInternal, nil
+	case "split":
+		return ApostropheSplit, nil
+	}
+	return ApostropheNone, fmt.Errorf("unknown apostrophe handling %q (want internal or split)", name)
+}
+
+// isApostrophe reports whether text is an apostrophe: U+0027 or U+2019
+// RIGHT SINGLE QUOTATION MARK, the typographic apostrophe.
+func isApostrophe(text []byte) bool {
+	return string(text) == "'" || string(text) == "’"
+}
+
+// apostrophe applies Options.Apostrophe to the next character, space or
+// not, with the given text, and passes it on to Options.Hyphen. Under
+// ApostropheInternal the apostrophes after the text of a word are held
+// back until the next character tells whether the word goes on.
+func (s *wordScan) apostrophe(space bool, text []byte, sink wordSink) {
+	isA := !space && isApostrophe(text)
+	if s.apos == ApostropheSplit {
+		s.hyphenWord(space || isA, text, sink)
+		return
+	}
+	if isA {
+		if s.InWord {
+			s.held = append(s.held, text...)
+			return
+		}
+		// a leading apostrophe is a quotation mark
+		s.hyphenWord(true, text, sink)
+		return
+	}
+	if len(s.held) > 0 {
+		if !space {
+			for p := s.held; len(p) > 0; {
+				_, size := utf8.DecodeRune(p)
+				s.hyphenWord(false, p[:size], sink)
+				p = p[size:]
+			}
+		}
+		// before a space they were closing quotation marks
+		s.held = s.held[:0]
+	}
+	s.hyphenWord(space, text, sink)
+}
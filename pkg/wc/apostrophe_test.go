@@ -0,0 +1,58 @@
+package wc
+
+import (
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+func TestParseApostrophe(t *testing.T) {
+	for name, want := range map[string]Apostrophe{"internal": ApostropheInternal, "split": ApostropheSplit} {
+		if a, err := ParseApostrophe(name); err != nil || a != want {
+			t.Errorf("%s: %v, %v", name, a, err)
+		}
+	}
+	if _, err := ParseApostrophe("keep"); err == nil {
+		t.Error("expected an error for unknown apostrophe handling")
+	}
+}
+
+func TestApostrophe(t *testing.T) {
+	tests := []struct {
+		apos    Apostrophe
+		in      string
+		words   uint64
+		chars   uint64
+		longest string
+	}{
+		{ApostropheNone, "don't rock 'n' roll ' x\n", 6, 18, "don't"},
+		{ApostropheInternal, "don't rock 'n' roll ' x\n", 5, 15, "don't"},
+		{ApostropheInternal, "it’s o’clock’", 2, 11, "o’clock"},
+		{ApostropheInternal, "''tis ends'' x", 3, 8, "ends"},
+		{ApostropheInternal, "rock'n'roll a''b", 2, 15, "rock'n'roll"},
+		{ApostropheInternal, "word'", 1, 4, "word"},
+		{ApostropheInternal, "' ’ ''", 0, 0, ""},
+		{ApostropheSplit, "don't rock 'n' roll\n", 5, 13, "rock"},
+		{ApostropheSplit, "o’clock", 2, 6, "clock"},
+		{ApostropheSplit, "'''", 0, 0, ""},
+	}
+	for _, tt := range tests {
+		for _, bufSize := range []int{1, 3, 64} {
+			opts := Options{BufferSize: bufSize, Locale: locale.Info{IsUTF8: true}, Apostrophe: tt.apos}
+			got := CountBytes([]byte(tt.in), Metrics{Words: true, WordLengths: true, LongestWord: true}, opts)
+			if got.Words != tt.words || got.WordChars != tt.chars || got.LongestWordText != tt.longest {
+				t.Errorf("%d %q (buffer %d): got %d words, %d chars, longest %q; want %d, %d, %q",
+					tt.apos, tt.in, bufSize, got.Words, got.WordChars, got.LongestWordText, tt.words, tt.chars, tt.longest)
+			}
+			if got := CountBytes([]byte(tt.in), Metrics{Words: true}, opts); got.Words != tt.words {
+				t.Errorf("%d %q (buffer %d): got %d words without word lengths, want %d", tt.apos, tt.in, bufSize, got.Words, tt.words)
+			}
+		}
+	}
+
+	// apostrophes are settled before hyphens
+	opts := Options{Apostrophe: ApostropheInternal, Hyphen: HyphenSplit}
+	if got := CountBytes([]byte("don't-care 'state-of-the-art'"), Metrics{Words: true}, opts); got.Words != 6 {
+		t.Errorf("with --hyphen=split: got %d words, want 6", got.Words)
+	}
+}
@@ -0,0 +1,181 @@
+package wc
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CharClass is a set of characters whose occurrences are counted alongside
+// the regular metrics (see Options.CountChars).
+type CharClass struct {
+	// Name labels the class in output, normally the spec it was parsed from.
+	Name  string
+	Match func(r rune) bool
+	// NotAtStart excludes a match on the first character of the input,
+	// e.g. to count only byte order marks that appear mid-stream. Chunks
+	// counted with NewChunkCounter are treated as mid-stream throughout.
+	NotAtStart bool
+}
+
+// posixClasses maps the names accepted inside "[:name:]".
+var posixClasses = map[string]func(rune) bool{
+	"alnum":  func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) },
+	"alpha":  unicode.IsLetter,
+	"blank":  func(r rune) bool { return r == ' ' || r == '\t' || unicode.Is(unicode.Zs, r) },
+	"cntrl":  unicode.IsControl,
+	"digit":  func(r rune) bool { return r >= '0' && r <= '9' },
+	"graph":  func(r rune) bool { return unicode.IsGraphic(r) && !unicode.IsSpace(r) },
+	"lower":  unicode.IsLower,
+	"print":  unicode.IsPrint,
+	"punct":  unicode.IsPunct,
+	"space":  unicode.IsSpace,
+	"upper":  unicode.IsUpper,
+	"xdigit": func(r rune) bool { return r < utf8.RuneSelf && isHex(byte(r)) },
+}
+
+func isHex(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'f') || (b >= 'A' && b <= 'F')
+}
+
+// ParseCharClass parses a character spec: a single character, a Go-style
+// escape such as "\t", "\0", "\x7f" or "\u00e9", or a POSIX class such as
+// "[:digit:]".
+func ParseCharClass(spec string) (CharClass, error) {
+	if len(spec) > 4 && spec[:2] == "[:" && spec[len(spec)-2:] == ":]" {
+		name := spec[2 : len(spec)-2]
+		fn, ok := posixClasses[name]
+		if !ok {
+			return CharClass{}, fmt.Errorf("unknown character class %q", spec)
+		}
+		return CharClass{Name: spec, Match: fn}, nil
+	}
+	var r rune
+	switch {
+	case spec == `\0`:
+		r = 0
+	case len(spec) > 1 && spec[0] == '\\':
+		v, _, tail, err := strconv.UnquoteChar(spec, '\'')
+		if err != nil || tail != "" {
+			return CharClass{}, fmt.Errorf("invalid character escape %q", spec)
+		}
+		r = v
+	default:
+		v, size := utf8.DecodeRuneInString(spec)
+		if spec == "" || size != len(spec) || v == utf8.RuneError {
+			return CharClass{}, fmt.Errorf("expected a single character, escape or [:class:], got %q", spec)
+		}
+		r = v
+	}
+	return CharClass{Name: spec, Match: func(c rune) bool { return c == r }}, nil
+}
+
+// InvisibleClasses returns the classes reported by --count-invisibles:
+// zero-width spaces and joiners, byte order marks after the start of the
+// input, bidirectional controls, and other invisible format or control
+// characters (excluding ordinary whitespace).
+func InvisibleClasses() []CharClass {
+	return []CharClass{
+		{Name: "zero-width", Match: isZeroWidth},
+		{Name: "bom", Match: func(r rune) bool { return r == '\uFEFF' }, NotAtStart: true},
+		{Name: "bidi", Match: isBidiControl},
+		{Name: "other-invisible", Match: isOtherInvisible},
+	}
+}
+
+func isZeroWidth(r rune) bool {
+	switch r {
+	case '\u200B', '\u200C', '\u200D', '\u2060', '\u180E':
+		return true
+	}
+	return false
+}
+
+func isBidiControl(r rune) bool {
+	return r == '\u200E' || r == '\u200F' || r == '\u061C' ||
+		(r >= '\u202A' && r <= '\u202E') || (r >= '\u2066' && r <= '\u2069')
+}
+
+func isOtherInvisible(r rune) bool {
+	if isZeroWidth(r) || isBidiControl(r) || r == '\uFEFF' {
+		return false
+	}
+	switch r {
+	case '\u115F', '\u1160', '\u3164', '\uFFA0', '\u2800': // fillers and blank pattern
+		return true
+	}
+	return unicode.Is(unicode.Cf, r) || (unicode.IsControl(r) && !unicode.IsSpace(r))
+}
+
+// countClasses adds one to every class in classes matching r; atStart
+// reports whether r is the first character of the input.
+func countClasses(counts []uint64, classes []CharClass, r rune, atStart bool) {
+	for i, cl := range classes {
+		if cl.NotAtStart && atStart {
+			continue
+		}
+		if cl.Match(r) {
+			counts[i]++
+		}
+	}
+}
+
+// addCounts returns the element-wise sum of a and b in a new slice.
+func addCounts(a, b []uint64) []uint64 {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	out := make([]uint64, n)
+	copy(out, a)
+	for i, v := range b {
+		out[i] += v
+	}
+	return out
+}
+
+func init() {
+	registerMetric(&accMetric[[]uint64]{
+		name: "char_classes",
+		on:   func(_ Metrics, opt Options) bool { return len(opt.CountChars) > 0 },
+		newText: func(_ Metrics, opt Options, chunk bool) TextAccumulator[[]uint64] {
+			return &charClassesAcc{Counts: make([]uint64, len(opt.CountChars)), classes: opt.CountChars, atStart: !chunk}
+		},
+		store: func(r *FileResult, v []uint64) { r.CharCounts = v },
+	})
+}
+
+// charClassesAcc counts the characters of each of Options.CountChars.
+// Invalid bytes match no class.
+type charClassesAcc struct {
+	Counts  []uint64
+	classes []CharClass
+	atStart bool // nothing was fed yet of a stream, see CharClass.NotAtStart
+}
+
+func (a *charClassesAcc) FeedText(t *Text) {
+	for i, b := range t.Bytes {
+		countClasses(a.Counts, a.classes, rune(b), a.atStart && i == 0)
+	}
+	for i, c := range t.Chars {
+		if !c.Invalid {
+			countClasses(a.Counts, a.classes, c.Rune, a.atStart && i == 0)
+		}
+	}
+	a.atStart = a.atStart && t.len() == 0
+}
+
+func (a *charClassesAcc) Merge(next TextAccumulator[[]uint64]) TextAccumulator[[]uint64] {
+	b := next.(*charClassesAcc)
+	out := &charClassesAcc{Counts: addCounts(a.Counts, b.Counts), classes: a.classes, atStart: a.atStart && b.atStart}
+	if out.classes == nil {
+		out.classes = b.classes
+	}
+	return out
+}
+
+func (a *charClassesAcc) Result() []uint64 { return addCounts(nil, a.Counts) }
@@ -0,0 +1,96 @@
+package wc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+func TestParseCharClass(t *testing.T) {
+	tests := []struct {
+		spec    string
+		match   []rune
+		noMatch []rune
+	}{
+		{";", []rune{';'}, []rune{',', 'a'}},
+		{`\t`, []rune{'\t'}, []rune{' ', 't'}},
+		{`\0`, []rune{0}, []rune{'0'}},
+		{`\x7f`, []rune{0x7f}, []rune{'x'}},
+		{"é", []rune{'é'}, []rune{'e'}},
+		{"[:digit:]", []rune{'0', '9'}, []rune{'a', '٣'}},
+		{"[:space:]", []rune{' ', '\n', ' '}, []rune{'x'}},
+		{"[:upper:]", []rune{'A', 'É'}, []rune{'a'}},
+		{"[:xdigit:]", []rune{'f', 'F', '7'}, []rune{'g'}},
+	}
+	for _, tt := range tests {
+		cl, err := ParseCharClass(tt.spec)
+		if err != nil {
+			t.Errorf("ParseCharClass(%q): %v", tt.spec, err)
+			continue
+		}
+		if cl.Name != tt.spec {
+			t.Errorf("ParseCharClass(%q).Name = %q", tt.spec, cl.Name)
+		}
+		for _, r := range tt.match {
+			if !cl.Match(r) {
+				t.Errorf("%q should match %q", tt.spec, r)
+			}
+		}
+		for _, r := range tt.noMatch {
+			if cl.Match(r) {
+				t.Errorf("%q should not match %q", tt.spec, r)
+			}
+		}
+	}
+
+	for _, bad := range []string{"", "ab", "[:nope:]", `\q`, "\xff"} {
+		if _, err := ParseCharClass(bad); err == nil {
+			t.Errorf("ParseCharClass(%q): expected error", bad)
+		}
+	}
+}
+
+func TestCountChars(t *testing.T) {
+	var classes []CharClass
+	for _, spec := range []string{";", `\t`, "é", "[:digit:]"} {
+		cl, err := ParseCharClass(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		classes = append(classes, cl)
+	}
+	data := []byte("a;b;\tc1\n\xffé2;é\n")
+	opts := Options{BufferSize: 3, Locale: locale.Info{IsUTF8: true}, CountChars: classes}
+
+	got := CountBytes(data, Metrics{Lines: true}, opts)
+	if want := []uint64{3, 1, 2, 2}; !reflect.DeepEqual(got.CharCounts, want) {
+		t.Errorf("CharCounts: got %v, want %v", got.CharCounts, want)
+	}
+	if got.Lines != 2 {
+		t.Errorf("Lines: got %d, want 2", got.Lines)
+	}
+
+	var tot Totals
+	tot.Add(got)
+	tot.Add(got)
+	if want := []uint64{6, 2, 4, 4}; !reflect.DeepEqual(tot.Result().CharCounts, want) {
+		t.Errorf("totals: got %v, want %v", tot.Result().CharCounts, want)
+	}
+}
+
+func TestInvisibleClasses(t *testing.T) {
+	data := []byte("\uFEFFa\u200Bb\u200Dc\n\u202Eevil\u202C \uFEFFx\u00ADy\x00z\t\n")
+	opts := Options{BufferSize: 5, Locale: locale.Info{IsUTF8: true}, CountChars: InvisibleClasses()}
+	got := CountBytes(data, Metrics{}, opts)
+	// zero-width, mid-stream BOM, bidi, other (soft hyphen and NUL)
+	if want := []uint64{2, 1, 2, 2}; !reflect.DeepEqual(got.CharCounts, want) {
+		t.Errorf("CharCounts: got %v, want %v", got.CharCounts, want)
+	}
+
+	// a leading BOM in a chunk that may not start the stream is counted
+	cr := CountChunk([]byte("\uFEFFa"), Metrics{}, opts).Final()
+	if cr.CharCounts[1] != 1 {
+		t.Errorf("chunk BOM: got %d, want 1", cr.CharCounts[1])
+	}
+}
@@ -0,0 +1,167 @@
+package wc
+
+import (
+	"unicode/utf8"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+// ChunkResult holds the counts of one chunk of a larger stream together with
+// the boundary state needed to merge it with its neighbours. Chunks of the
+// same stream can be counted independently (in parallel, or on different
+// machines) and combined in stream order with Merge; Final then yields the
+// same FileResult a single sequential pass would have produced.
+//
+// All fields are exported so that a ChunkResult can be serialized.
+type ChunkResult struct {
+	// FileResult holds the counts of the chunk as if it were a complete
+	// stream, for a look at a chunk on its own. StringCounts and
+	// RegexpCounts are summed as is: occurrences spanning a boundary are
+	// not recovered.
+	FileResult
+
+	Metrics Metrics
+	Locale  locale.Info
+	// POSIXSpace is the Options.POSIXSpace the chunk was counted with.
+	POSIXSpace bool `json:",omitempty"`
+	// CharClasses are the Options.CountChars the chunk was counted with.
+	// They cannot be serialized; set them again on a decoded ChunkResult
+	// so that characters reassembled at chunk boundaries are matched.
+	CharClasses []CharClass `json:"-"`
+
+	// Accumulators hold the state of every metric (see registerMetric),
+	// including the words and lines running on across the chunk's edges,
+	// which Merge joins with those of its neighbours.
+	Accumulators Accumulators `json:",omitempty"`
+
+	// HeadPartial holds leading continuation bytes completing a rune begun
+	// in an earlier chunk; TailPartial holds a trailing incomplete rune.
+	// Neither is reflected in anything but Bytes.
+	HeadPartial []byte
+	TailPartial []byte
+}
+
+// NewChunkCounter returns a Counter for a chunk that may start in the middle
+// of a multibyte sequence. Use Chunk to obtain its mergeable result.
+func NewChunkCounter(m Metrics, opt Options) *Counter {
+	opt.MatchLines = nil
+	opt.MinWordLength = 0
+	opt.Hyphen = HyphenNone
+	opt.Apostrophe = ApostropheNone
+	opt.OnLine = nil
+	opt.NGrams = 0
+	return newCounter(m, opt, true)
+}
+
+// CountChunk counts b as one chunk of a larger stream.
+func CountChunk(b []byte, m Metrics, opt Options) ChunkResult {
+	c := NewChunkCounter(m, opt)
+	_, _ = c.Write(b)
+	return c.Chunk()
+}
+
+// Chunk returns the mergeable result for everything written so far.
+func (c *Counter) Chunk() ChunkResult {
+	cr := ChunkResult{
+		Metrics:      c.m,
+		CharClasses:  c.opt.CountChars,
+		Locale:       c.opt.Locale,
+		POSIXSpace:   c.opt.POSIXSpace,
+		Accumulators: c.accs.snapshot(),
+	}
+	cr.Accumulators.store(&cr.FileResult)
+	cr.NoFinalNewline = c.noFinalNewline()
+	if len(c.head) > 0 {
+		cr.HeadPartial = append([]byte(nil), c.head...)
+	}
+	if len(c.carry) > 0 {
+		cr.TailPartial = append([]byte(nil), c.carry...)
+	}
+	return cr
+}
+
+// hasUnits reports whether the chunk contains any counted character.
+func (a ChunkResult) hasUnits() bool {
+	return a.Bytes > uint64(len(a.HeadPartial)+len(a.TailPartial))
+}
+
+// Merge combines a with b, which must immediately follow a in the stream.
+// Both must have been counted with the same metrics and locale.
+func (a ChunkResult) Merge(b ChunkResult) ChunkResult {
+	if b.Bytes == 0 {
+		return a
+	}
+	if a.Bytes == 0 {
+		return b
+	}
+	out := a
+	var junction Accumulators
+	switch {
+	case !a.hasUnits() && len(a.TailPartial) == 0:
+		// a is nothing but continuation bytes: they extend b's head
+		out.HeadPartial = append(append([]byte(nil), a.HeadPartial...), b.HeadPartial...)
+		out.TailPartial = b.TailPartial
+	default:
+		partial := append(append([]byte(nil), a.TailPartial...), b.HeadPartial...)
+		out.TailPartial = b.TailPartial
+		if !b.hasUnits() && len(b.TailPartial) == 0 && !utf8.FullRune(partial) {
+			// still waiting for the rest of the rune
+			out.TailPartial = partial
+		} else if len(partial) > 0 {
+			junction = countJunction(partial, a)
+		}
+	}
+	out.Accumulators = a.Accumulators.merge(junction).merge(b.Accumulators)
+	out.FileResult = FileResult{
+		Index:          a.Index,
+		Filename:       a.Filename,
+		NoFinalNewline: b.NoFinalNewline,
+		Duration:       a.Duration + b.Duration,
+	}
+	out.Accumulators.store(&out.FileResult)
+	return out
+}
+
+// countJunction counts the characters of the bytes reassembled at a chunk
+// boundary, treating an incomplete sequence as invalid bytes. The chunks
+// on either side have fed the bytes to the other accumulators.
+func countJunction(b []byte, like ChunkResult) Accumulators {
+	opt := Options{Locale: like.Locale, CountChars: like.CharClasses, POSIXSpace: like.POSIXSpace}
+	if v := like.Vocabulary; v != nil {
+		opt.UniqueFold, opt.UniqueApprox, opt.Stem = v.Fold, v.Approximate(), v.Stem
+		opt.StopWords = v.Stop
+	}
+	c := NewCounter(like.Metrics, opt)
+	c.accs = like.Accumulators.startText(like.Metrics, opt, true)
+	_, _ = c.Write(b)
+	c.flush()
+	return c.accs
+}
+
+// Final treats the chunk as a complete stream and returns its FileResult.
+// Partial runes left at either end are counted as invalid bytes.
+func (a ChunkResult) Final() FileResult {
+	accs := a.Accumulators
+	if len(a.HeadPartial) > 0 {
+		accs = countJunction(a.HeadPartial, a).merge(accs)
+	}
+	if len(a.TailPartial) > 0 {
+		accs = accs.merge(countJunction(a.TailPartial, a))
+	}
+	res := a.FileResult
+	accs.store(&res)
+	return res
+}
+
+// MergeChunks folds chunks, given in stream order, into a single result.
+func MergeChunks(chunks []ChunkResult) ChunkResult {
+	var out ChunkResult
+	for i, c := range chunks {
+		if i == 0 {
+			out = c
+			continue
+		}
+		out = out.Merge(c)
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package wc
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+func randomText(rng *rand.Rand, n int) []byte {
+	pieces := []string{"a", "bc", " ", "\n", "\r", "\r\n", "\t", "é", "日本", "\xff", "\xe6", "\x80", "\U0001F600", " ", "x y", "A", "ÉÉ", "42", "a@b.io", "🇫🇷", "\u200d", "\u00a0"}
+	var out []byte
+	for len(out) < n {
+		out = append(out, pieces[rng.Intn(len(pieces))]...)
+	}
+	return out
+}
+
+func splitRandom(rng *rand.Rand, b []byte) [][]byte {
+	var parts [][]byte
+	for len(b) > 0 {
+		n := rng.Intn(6)
+		if n > len(b) {
+			n = len(b)
+		}
+		parts = append(parts, b[:n])
+		b = b[n:]
+	}
+	return parts
+}
+
+func TestChunkMergeMatchesSequential(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	metricSets := []Metrics{AllMetrics(), {Words: true, Chars: true, MaxLineBytes: true, MaxLineChars: true}, {WhitespaceLines: true, LineEndings: true}, {WordLengths: true}, {WordsPerLine: true}, {TokenStats: true}, {WordKinds: true}, {UniqueWords: true}, {LineLengths: true}, {Emoji: true}}
+	locales := []locale.Info{{IsUTF8: true}, {IsCOrPOSIX: true}}
+	var classes []CharClass
+	for _, spec := range []string{"é", "[:space:]", "日"} {
+		cl, err := ParseCharClass(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		classes = append(classes, cl)
+	}
+
+	for iter := 0; iter < 300; iter++ {
+		data := randomText(rng, rng.Intn(40))
+		for _, m := range metricSets {
+			for _, loc := range locales {
+				opts := Options{BufferSize: 1024, Locale: loc, CountChars: classes, UniqueFold: iter%2 == 0, POSIXSpace: iter%3 == 0, Scripts: true}
+				want := CountBytes(data, m, opts)
+
+				parts := splitRandom(rng, data)
+				chunks := make([]ChunkResult, len(parts))
+				for i, p := range parts {
+					chunks[i] = CountChunk(p, m, opts)
+				}
+				// merge left to right
+				got := MergeChunks(chunks).Final()
+				if len(chunks) == 0 {
+					got = CountChunk(nil, m, opts).Final()
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("sequential merge of %q (%d parts, %+v, %+v):\ngot  %+v\nwant %+v", data, len(parts), m, loc, got, want)
+				}
+				// merge in a tree to check associativity
+				for len(chunks) > 1 {
+					next := make([]ChunkResult, 0, (len(chunks)+1)/2)
+					for i := 0; i < len(chunks); i += 2 {
+						if i+1 < len(chunks) {
+							next = append(next, chunks[i].Merge(chunks[i+1]))
+						} else {
+							next = append(next, chunks[i])
+						}
+					}
+					chunks = next
+				}
+				if len(chunks) == 1 {
+					if got := chunks[0].Final(); !reflect.DeepEqual(got, want) {
+						t.Fatalf("tree merge of %q: got %+v, want %+v", data, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestChunkResultSingleChunkFinal(t *testing.T) {
+	opts := Options{Locale: locale.Info{IsUTF8: true}}
+	data := []byte("\x80\x80 hello\nworld \xe6")
+	want := CountBytes(data, AllMetrics(), opts)
+	if got := CountChunk(data, AllMetrics(), opts).Final(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	c := NewChunkCounter(AllMetrics(), opts)
+	c.Write(data)
+	if got := c.Result(); !reflect.DeepEqual(got, want) {
+		t.Errorf("chunk counter Result: got %+v, want %+v", got, want)
+	}
+}
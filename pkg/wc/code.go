@@ -0,0 +1,577 @@
+package wc
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CodeLanguage holds the lexical rules by which Metrics.CodeTokens splits
+// source code into tokens: which delimiters begin comments and string
+// literals, and which bytes make up identifiers. LookupLanguage and
+// LanguageForFile return the built-in languages.
+type CodeLanguage struct {
+	name    string
+	openers []codeOpener
+	byOpen  map[string]int  // index in openers
+	ident   [256]bool       // bytes that continue an identifier
+	start   [256]bool       // bytes that begin one
+	ops     map[string]bool // codeOperators that are neither comments nor strings here
+	// prefix holds the punctuation that may still grow into a longer
+	// opener or operator, and so is kept pending (see codeState.Pend)
+	prefix map[string]bool
+}
+
+// codeOpener is a comment or string literal delimiter.
+type codeOpener struct {
+	open, close string
+	comment     bool // else a string literal, one token
+	escapes     bool // a backslash escapes the byte after it
+	multiline   bool // a newline does not end the literal
+}
+
+// Name returns the name of the language, such as "go" or "python".
+func (l *CodeLanguage) Name() string { return l.name }
+
+// codeOperators are the operators of more than one byte, across
+// languages; any other punctuation byte is an operator of its own. Every
+// prefix of one is an operator too, so that the longest match is found a
+// byte at a time.
+var codeOperators = map[string]bool{
+	"==": true, "===": true, "!=": true, "!==": true, "<=": true, "<=>": true,
+	">=": true, "&&": true, "&&=": true, "||": true, "||=": true,
+	"<<": true, "<<=": true, ">>": true, ">>=": true, ">>>": true, ">>>=": true,
+	"++": true, "--": true, "+=": true, "-=": true, "*=": true, "/=": true,
+	"%=": true, "&=": true, "|=": true, "^=": true, "&^": true, "&^=": true,
+	"->": true, "=>": true, "::": true, ":=": true, "..": true, "...": true,
+	"..=": true, "**": true, "**=": true, "//": true, "//=": true, "?.": true,
+	"??": true, "??=": true, "<-": true, "|>": true, "<>": true, "=~": true,
+	"!~": true,
+}
+
+// codeLanguage describes a built-in language.
+type codeLanguage struct {
+	name       string
+	extensions []string // lower-case, with the dot
+	files      []string // base names, such as "Makefile"
+	line       []string // line comment openers
+	block      [][2]string
+	strings    []codeOpener
+	identChars string // besides letters, digits, '_' and bytes >= 0x80
+}
+
+var (
+	cStrings  = []codeOpener{{open: `"`, close: `"`, escapes: true}, {open: "'", close: "'", escapes: true}}
+	cComments = [][2]string{{"/*", "*/"}}
+	hashLine  = []string{"#"}
+	slashLine = []string{"//"}
+)
+
+var builtinLanguages = []codeLanguage{
+	{name: "c", extensions: []string{".c", ".h"}, line: slashLine, block: cComments, strings: cStrings},
+	{name: "c++", extensions: []string{".cc", ".cpp", ".cxx", ".c++", ".hh", ".hpp", ".hxx", ".inl"}, line: slashLine, block: cComments, strings: cStrings},
+	{name: "c#", extensions: []string{".cs"}, line: slashLine, block: cComments, strings: cStrings},
+	{name: "objective-c", extensions: []string{".m", ".mm"}, line: slashLine, block: cComments, strings: cStrings},
+	{name: "java", extensions: []string{".java"}, line: slashLine, block: cComments, strings: cStrings},
+	{name: "kotlin", extensions: []string{".kt", ".kts"}, line: slashLine, block: cComments, strings: tripleQuoted(cStrings, `"""`)},
+	{name: "scala", extensions: []string{".scala", ".sc"}, line: slashLine, block: cComments, strings: tripleQuoted(cStrings, `"""`)},
+	{name: "groovy", extensions: []string{".groovy", ".gradle"}, files: []string{"Jenkinsfile"}, line: slashLine, block: cComments, strings: tripleQuoted(cStrings, `"""`, "'''")},
+	{name: "swift", extensions: []string{".swift"}, line: slashLine, block: cComments, strings: tripleQuoted([]codeOpener{cStrings[0]}, `"""`)},
+	{name: "dart", extensions: []string{".dart"}, line: slashLine, block: cComments, strings: tripleQuoted(cStrings, `"""`, "'''")},
+	{name: "go", extensions: []string{".go"}, line: slashLine, block: cComments,
+		strings: append(cStrings[:2:2], codeOpener{open: "`", close: "`", multiline: true})},
+	{name: "javascript", extensions: []string{".js", ".mjs", ".cjs", ".jsx", ".ts", ".mts", ".cts", ".tsx"}, line: slashLine, block: cComments,
+		strings: append(cStrings[:2:2], codeOpener{open: "`", close: "`", escapes: true, multiline: true}), identChars: "$"},
+	{name: "rust", extensions: []string{".rs"}, line: slashLine, block: cComments,
+		strings: []codeOpener{{open: `"`, close: `"`, escapes: true, multiline: true}}},
+	{name: "zig", extensions: []string{".zig"}, line: slashLine, strings: cStrings},
+	{name: "php", extensions: []string{".php"}, line: []string{"//", "#"}, block: cComments, strings: cStrings, identChars: "$"},
+	{name: "css", extensions: []string{".css", ".scss", ".less"}, block: cComments, strings: cStrings, identChars: "-"},
+	{name: "python", extensions: []string{".py", ".pyi", ".pyw"}, line: hashLine, strings: tripleQuoted(cStrings, `"""`, "'''")},
+	{name: "ruby", extensions: []string{".rb", ".rake", ".gemspec"}, files: []string{"Rakefile", "Gemfile"}, line: hashLine, strings: cStrings},
+	{name: "perl", extensions: []string{".pl", ".pm"}, line: hashLine, strings: cStrings, identChars: "$"},
+	{name: "shell", extensions: []string{".sh", ".bash", ".zsh", ".ksh"}, files: []string{".bashrc", ".profile", ".zshrc"}, line: hashLine,
+		strings: []codeOpener{{open: `"`, close: `"`, escapes: true, multiline: true}, {open: "'", close: "'", multiline: true}}, identChars: "$"},
+	{name: "powershell", extensions: []string{".ps1", ".psm1"}, line: hashLine, block: [][2]string{{"<#", "#>"}}, strings: cStrings, identChars: "$"},
+	{name: "r", extensions: []string{".r"}, line: hashLine, strings: cStrings},
+	{name: "julia", extensions: []string{".jl"}, line: hashLine, block: [][2]string{{"#=", "=#"}}, strings: tripleQuoted([]codeOpener{cStrings[0]}, `"""`)},
+	{name: "elixir", extensions: []string{".ex", ".exs"}, line: hashLine, strings: tripleQuoted(cStrings, `"""`)},
+	{name: "make", extensions: []string{".mk"}, files: []string{"Makefile", "makefile", "GNUmakefile"}, line: hashLine},
+	{name: "dockerfile", extensions: []string{".dockerfile"}, files: []string{"Dockerfile", "Containerfile"}, line: hashLine, strings: cStrings},
+	{name: "yaml", extensions: []string{".yaml", ".yml"}, line: hashLine, strings: []codeOpener{cStrings[0], {open: "'", close: "'"}}},
+	{name: "toml", extensions: []string{".toml"}, line: hashLine, strings: tripleQuoted([]codeOpener{cStrings[0], {open: "'", close: "'"}}, `"""`, "'''")},
+	{name: "sql", extensions: []string{".sql"}, line: []string{"--"}, block: cComments, strings: []codeOpener{{open: "'", close: "'"}, {open: `"`, close: `"`}}},
+	{name: "lua", extensions: []string{".lua"}, line: []string{"--"}, block: [][2]string{{"--[[", "]]"}},
+		strings: append(cStrings[:2:2], codeOpener{open: "[[", close: "]]", multiline: true})},
+	{name: "haskell", extensions: []string{".hs"}, line: []string{"--"}, block: [][2]string{{"{-", "-}"}}, strings: []codeOpener{cStrings[0]}},
+	{name: "ocaml", extensions: []string{".ml", ".mli"}, block: [][2]string{{"(*", "*)"}}, strings: []codeOpener{cStrings[0]}},
+	{name: "erlang", extensions: []string{".erl", ".hrl"}, line: []string{"%"}, strings: []codeOpener{cStrings[0]}},
+	{name: "lisp", extensions: []string{".lisp", ".el", ".clj", ".cljs", ".scm", ".rkt"}, line: []string{";"}, strings: []codeOpener{cStrings[0]},
+		identChars: "-!?*<>=+/:"},
+}
+
+// tripleQuoted adds multi-line string literals delimited by each of
+// quotes, with backslash escapes, to base.
+func tripleQuoted(base []codeOpener, quotes ...string) []codeOpener {
+	out := append([]codeOpener(nil), base...)
+	for _, q := range quotes {
+		out = append(out, codeOpener{open: q, close: q, escapes: true, multiline: true})
+	}
+	return out
+}
+
+// genericLanguage lexes the files of no known language: double-quoted
+// strings, and no comments.
+var genericLanguage = newCodeLanguage(codeLanguage{name: "text", strings: []codeOpener{cStrings[0]}})
+
+var (
+	languagesByName = map[string]*CodeLanguage{genericLanguage.name: genericLanguage}
+	languagesByExt  = map[string]*CodeLanguage{}
+	languagesByFile = map[string]*CodeLanguage{}
+)
+
+func init() {
+	for _, d := range builtinLanguages {
+		l := newCodeLanguage(d)
+		languagesByName[l.name] = l
+		for _, e := range d.extensions {
+			languagesByExt[e] = l
+		}
+		for _, f := range d.files {
+			languagesByFile[f] = l
+		}
+	}
+}
+
+func newCodeLanguage(d codeLanguage) *CodeLanguage {
+	l := &CodeLanguage{name: d.name, byOpen: map[string]int{}, ops: map[string]bool{}, prefix: map[string]bool{}}
+	for _, o := range d.line {
+		l.openers = append(l.openers, codeOpener{open: o, close: "\n", comment: true})
+	}
+	for _, b := range d.block {
+		l.openers = append(l.openers, codeOpener{open: b[0], close: b[1], comment: true, multiline: true})
+	}
+	l.openers = append(l.openers, d.strings...)
+	for i, o := range l.openers {
+		l.byOpen[o.open] = i
+	}
+	for c := 0; c < 256; c++ {
+		b := byte(c)
+		l.start[c] = isASCIILetter(b) || b == '_' || b >= 0x80 || strings.IndexByte(d.identChars, b) >= 0
+		l.ident[c] = l.start[c] || b >= '0' && b <= '9'
+	}
+	addPrefixes := func(s string) {
+		for i := 1; i < len(s); i++ {
+			l.prefix[s[:i]] = true
+		}
+	}
+	for _, o := range l.openers {
+		addPrefixes(o.open)
+	}
+ops:
+	for op := range codeOperators {
+		if strings.IndexFunc(op, func(r rune) bool { return r < 0x80 && l.ident[r] }) >= 0 {
+			continue
+		}
+		for _, o := range l.openers {
+			if strings.HasPrefix(op, o.open) {
+				continue ops // "//=" is a comment in Go
+			}
+		}
+		l.ops[op] = true
+		addPrefixes(op)
+	}
+	return l
+}
+
+// LookupLanguage returns the built-in language of the name, or nil.
+func LookupLanguage(name string) *CodeLanguage {
+	return languagesByName[name]
+}
+
+// LanguageForFile returns the language of the named file by its base name
+// or, case-insensitively, its extension. Files of no known language are
+// lexed by generic rules, with double-quoted strings and no comments.
+func LanguageForFile(name string) *CodeLanguage {
+	base := filepath.Base(name)
+	if l := languagesByFile[base]; l != nil {
+		return l
+	}
+	if l := languagesByExt[strings.ToLower(filepath.Ext(base))]; l != nil {
+		return l
+	}
+	return genericLanguage
+}
+
+// CodeTokenCounts counts the tokens of source code for Metrics.CodeTokens.
+type CodeTokenCounts struct {
+	Identifiers uint64 // names and keywords
+	Literals    uint64 // numbers and strings, a string being one token
+	Operators   uint64 // operators and other punctuation
+}
+
+// lexer modes
+const (
+	codeSpace   uint8 = iota // between tokens
+	codeIdent                // in an identifier
+	codeNumber               // in a number
+	codeExp                  // in a number, after an exponent letter
+	codeString               // in a string literal
+	codeComment              // in a comment
+)
+
+// codeState is where the lexer stands between two bytes. It is comparable,
+// so that lexers started in different states are known to agree once
+// their states are equal.
+type codeState struct {
+	Mode   uint8
+	Opener uint8 // in codeString and codeComment, index in openers
+	Match  uint8 // bytes of the closer matched so far
+	Esc    bool  // the last byte was an escaping backslash
+	// Pend is punctuation, PendLen bytes of it, that may yet be the
+	// beginning of a longer operator or opener
+	Pend    [3]byte
+	PendLen uint8
+}
+
+// step lexes the byte c in state s, adding the tokens it completes or
+// begins to n. A token is counted once its kind is known: identifiers,
+// numbers and strings at their first byte, punctuation once the longest
+// operator or opener it makes is.
+func (l *CodeLanguage) step(s *codeState, n *CodeTokenCounts, c byte) {
+	switch s.Mode {
+	case codeString, codeComment:
+		o := &l.openers[s.Opener]
+		switch {
+		case s.Esc:
+			s.Esc = false
+		case c == o.close[s.Match]:
+			s.Match++
+			if int(s.Match) == len(o.close) {
+				*s = codeState{}
+			}
+		case o.escapes && c == '\\':
+			s.Esc, s.Match = true, 0
+		case c == '\n' && !o.multiline:
+			*s = codeState{} // an unterminated string ends with its line
+		case c == o.close[0]:
+			s.Match = 1
+		default:
+			s.Match = 0
+		}
+		return
+	case codeIdent:
+		if l.ident[c] {
+			return
+		}
+	case codeNumber, codeExp:
+		switch {
+		case c == '+' || c == '-':
+			if s.Mode == codeExp {
+				s.Mode = codeNumber
+				return
+			}
+		case c == 'e' || c == 'E' || c == 'p' || c == 'P':
+			s.Mode = codeExp
+			return
+		case l.ident[c] && c < 0x80 || c == '.':
+			s.Mode = codeNumber
+			return
+		}
+	}
+	s.Mode = codeSpace
+	if s.PendLen > 0 {
+		if codePunct(c) {
+			var buf [4]byte
+			copy(buf[:], s.Pend[:s.PendLen])
+			buf[s.PendLen] = c
+			p := buf[:s.PendLen+1]
+			if l.prefix[string(p)] {
+				s.Pend[s.PendLen] = c
+				s.PendLen++
+				return
+			}
+			if l.complete(p) {
+				s.Pend, s.PendLen = [3]byte{}, 0
+				l.emit(s, n, p)
+				return
+			}
+		}
+		l.flush(s, n)
+		l.step(s, n, c)
+		return
+	}
+	switch {
+	case l.start[c]:
+		n.Identifiers++
+		s.Mode = codeIdent
+	case c >= '0' && c <= '9':
+		n.Literals++
+		s.Mode = codeNumber
+	case codePunct(c):
+		if l.prefix[string(c)] {
+			s.Pend[0], s.PendLen = c, 1
+		} else {
+			l.emit(s, n, []byte{c})
+		}
+	}
+}
+
+// codePunct reports whether c is ASCII punctuation.
+func codePunct(c byte) bool {
+	return c > ' ' && c < 0x7f && !isASCIILetter(c) && !(c >= '0' && c <= '9')
+}
+
+// complete reports whether p is an opener or an operator.
+func (l *CodeLanguage) complete(p []byte) bool {
+	_, ok := l.byOpen[string(p)]
+	return ok || len(p) == 1 || l.ops[string(p)]
+}
+
+// emit counts the complete opener or operator p, entering the comment or
+// string an opener begins.
+func (l *CodeLanguage) emit(s *codeState, n *CodeTokenCounts, p []byte) {
+	i, ok := l.byOpen[string(p)]
+	switch {
+	case !ok:
+		n.Operators++
+	case l.openers[i].comment:
+		*s = codeState{Mode: codeComment, Opener: uint8(i)}
+	default:
+		n.Literals++
+		*s = codeState{Mode: codeString, Opener: uint8(i)}
+	}
+}
+
+// flush resolves the pending punctuation into the longest opener or
+// operator it begins with, and lexes the bytes after that.
+func (l *CodeLanguage) flush(s *codeState, n *CodeTokenCounts) {
+	p := s.Pend
+	pend := p[:s.PendLen]
+	s.Pend, s.PendLen = [3]byte{}, 0
+	k := len(pend)
+	for k > 1 && !l.complete(pend[:k]) {
+		k--
+	}
+	l.emit(s, n, pend[:k])
+	for _, c := range pend[k:] {
+		l.step(s, n, c)
+	}
+}
+
+// lex lexes p from state s.
+func (l *CodeLanguage) lex(s *codeState, n *CodeTokenCounts, p []byte) {
+	for _, c := range p {
+		l.step(s, n, c)
+	}
+}
+
+// final returns n with the tokens that the end of the input completes in
+// state s.
+func (l *CodeLanguage) final(s codeState, n CodeTokenCounts) CodeTokenCounts {
+	for s.PendLen > 0 {
+		l.flush(&s, &n)
+	}
+	return n
+}
+
+// states returns every state the lexer can be in between two bytes, the
+// states a chunk that begins mid-stream is lexed from.
+func (l *CodeLanguage) states() []codeState {
+	out := []codeState{{}, {Mode: codeIdent}, {Mode: codeNumber}, {Mode: codeExp}}
+	pending := make([]string, 0, len(l.prefix))
+	for p := range l.prefix {
+		pending = append(pending, p)
+	}
+	sort.Strings(pending)
+	for _, p := range pending {
+		s := codeState{PendLen: uint8(len(p))}
+		copy(s.Pend[:], p)
+		out = append(out, s)
+	}
+	for i, o := range l.openers {
+		mode := codeString
+		if o.comment {
+			mode = codeComment
+		}
+		for k := range len(o.close) {
+			out = append(out, codeState{Mode: mode, Opener: uint8(i), Match: uint8(k)})
+		}
+		if o.escapes {
+			out = append(out, codeState{Mode: mode, Opener: uint8(i), Esc: true})
+		}
+	}
+	return out
+}
+
+func init() {
+	registerMetric(&accMetric[CodeTokenCounts]{
+		name: "code_tokens",
+		on:   func(m Metrics, _ Options) bool { return m.CodeTokens },
+		new: func(_ Metrics, opt Options, chunk bool) Accumulator[CodeTokenCounts] {
+			return newCodeAcc(opt.Language, chunk)
+		},
+		store: func(r *FileResult, v CodeTokenCounts) {
+			r.CodeIdentifiers, r.CodeLiterals, r.CodeOperators = v.Identifiers, v.Literals, v.Operators
+		},
+	})
+}
+
+func (n CodeTokenCounts) plus(o CodeTokenCounts) CodeTokenCounts {
+	return CodeTokenCounts{n.Identifiers + o.Identifiers, n.Literals + o.Literals, n.Operators + o.Operators}
+}
+
+// minus wraps around below zero, so that adding o back restores n.
+func (n CodeTokenCounts) minus(o CodeTokenCounts) CodeTokenCounts {
+	return CodeTokenCounts{n.Identifiers - o.Identifiers, n.Literals - o.Literals, n.Operators - o.Operators}
+}
+
+// codeAcc lexes for Metrics.CodeTokens. A chunk, which may begin anywhere
+// in its stream, is lexed from every state the lexer can be in there, so
+// that Merge can continue the run that starts where the chunk before
+// ended. Runs that reach the same state lex alike from then on and are
+// followed as one, the starts that joined it keeping what they counted
+// apart as an offset.
+type codeAcc struct {
+	Lang   string
+	Fed    bool
+	Runs   []codeRun
+	Starts []codeStart
+	lang   *CodeLanguage
+}
+
+// codeRun is a run of the lexer: its state and the tokens it counted.
+type codeRun struct {
+	State  codeState
+	Tokens CodeTokenCounts
+}
+
+// codeStart is a state the bytes were lexed from and the run that
+// continues it, whose tokens plus Offset are those counted from there.
+type codeStart struct {
+	State  codeState
+	Run    int
+	Offset CodeTokenCounts
+}
+
+// codeJoinEvery is how many bytes the runs of a chunk lex between checks
+// for runs to join.
+const codeJoinEvery = 256
+
+func newCodeAcc(l *CodeLanguage, chunk bool) *codeAcc {
+	if l == nil {
+		l = genericLanguage
+	}
+	a := &codeAcc{Lang: l.name, lang: l}
+	states := []codeState{{}}
+	if chunk {
+		states = l.states()
+	}
+	for i, s := range states {
+		a.Runs = append(a.Runs, codeRun{State: s})
+		a.Starts = append(a.Starts, codeStart{State: s, Run: i})
+	}
+	return a
+}
+
+// language returns the rules of a.Lang, which a decoded accumulator only
+// has by name.
+func (a *codeAcc) language() *CodeLanguage {
+	if a.lang == nil {
+		if a.lang = LookupLanguage(a.Lang); a.lang == nil {
+			a.lang = genericLanguage
+		}
+	}
+	return a.lang
+}
+
+func (a *codeAcc) Feed(p []byte) {
+	if len(p) == 0 {
+		return
+	}
+	a.Fed = true
+	l := a.language()
+	for len(p) > 0 {
+		seg := p
+		if len(a.Runs) > 1 && len(seg) > codeJoinEvery {
+			seg = seg[:codeJoinEvery]
+		}
+		for i := range a.Runs {
+			r := &a.Runs[i]
+			l.lex(&r.State, &r.Tokens, seg)
+		}
+		if len(a.Runs) > 1 {
+			a.join()
+		}
+		p = p[len(seg):]
+	}
+}
+
+// join merges the runs that are in the same state.
+func (a *codeAcc) join() {
+	first := make(map[codeState]int, len(a.Runs))
+	to := make([]int, len(a.Runs))
+	var runs []codeRun
+	for i, r := range a.Runs {
+		j, ok := first[r.State]
+		if !ok {
+			j = len(runs)
+			first[r.State] = j
+			runs = append(runs, r)
+		}
+		to[i] = j
+	}
+	if len(runs) == len(a.Runs) {
+		return
+	}
+	for i := range a.Starts {
+		st := &a.Starts[i]
+		st.Offset = st.Offset.plus(a.Runs[st.Run].Tokens.minus(runs[to[st.Run]].Tokens))
+		st.Run = to[st.Run]
+	}
+	a.Runs = runs
+}
+
+// start returns the start for the state s. A stream lexed from its
+// beginning has only the one.
+func (a *codeAcc) start(s codeState) codeStart {
+	for _, st := range a.Starts {
+		if st.State == s {
+			return st
+		}
+	}
+	return a.Starts[0]
+}
+
+func (a *codeAcc) clone() *codeAcc {
+	out := *a
+	out.Runs = append([]codeRun(nil), a.Runs...)
+	out.Starts = append([]codeStart(nil), a.Starts...)
+	return &out
+}
+
+func (a *codeAcc) Merge(next Accumulator[CodeTokenCounts]) Accumulator[CodeTokenCounts] {
+	b := next.(*codeAcc)
+	switch {
+	case !b.Fed:
+		return a.clone()
+	case !a.Fed:
+		return b.clone()
+	}
+	out := &codeAcc{Lang: a.Lang, Fed: true, lang: a.lang}
+	for _, sa := range a.Starts {
+		ra := a.Runs[sa.Run]
+		sb := b.start(ra.State)
+		rb := b.Runs[sb.Run]
+		out.Starts = append(out.Starts, codeStart{State: sa.State, Run: len(out.Runs)})
+		out.Runs = append(out.Runs, codeRun{State: rb.State, Tokens: ra.Tokens.plus(sa.Offset).plus(rb.Tokens).plus(sb.Offset)})
+	}
+	out.join()
+	return out
+}
+
+func (a *codeAcc) Result() CodeTokenCounts {
+	st := a.start(codeState{})
+	r := a.Runs[st.Run]
+	return a.language().final(r.State, r.Tokens.plus(st.Offset))
+}
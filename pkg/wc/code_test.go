@@ -0,0 +1,121 @@
+package wc
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCodeTokens(t *testing.T) {
+	tests := []struct {
+		lang, in                string
+		idents, literals, opers uint64
+	}{
+		{"go", "x := a + 42 // add\n", 2, 1, 2},
+		{"go", "s := \"a // b\" + `raw\n\"`", 1, 2, 2},
+		{"go", "/* a\n b */ f(x, 'c')", 2, 1, 3},
+		{"go", "i++; j >>= 2", 2, 1, 3},
+		{"go", "x//=1", 1, 0, 0},
+		{"go", "1.5e-3 + 0x1F", 0, 2, 1},
+		{"go", "\"unterminated\nx", 1, 1, 0},
+		{"go", `"a\"b" c`, 1, 1, 0},
+		{"python", "def f(a):\n    return a // 2  # halve\n", 5, 1, 4},
+		{"python", "s = \"\"\"one\n\"two\"\n\"\"\"", 1, 1, 1},
+		{"python", `'' + ""`, 0, 2, 1},
+		{"shell", "echo \"$HOME\" # hi\nls -la", 3, 1, 1},
+		{"sql", "SELECT a-1 -- note\nFROM t", 4, 1, 1},
+		{"lua", "--[[ block\n]] x = [[s]] --line", 1, 1, 1},
+		{"text", `He said "hi there", ok.`, 3, 1, 2},
+	}
+	for _, tt := range tests {
+		l := LookupLanguage(tt.lang)
+		if l == nil {
+			t.Fatalf("no language %q", tt.lang)
+		}
+		got := CountBytes([]byte(tt.in), Metrics{CodeTokens: true}, Options{Language: l})
+		if got.CodeIdentifiers != tt.idents || got.CodeLiterals != tt.literals || got.CodeOperators != tt.opers {
+			t.Errorf("%s %q: got %d identifiers, %d literals, %d operators; want %d, %d, %d",
+				tt.lang, tt.in, got.CodeIdentifiers, got.CodeLiterals, got.CodeOperators, tt.idents, tt.literals, tt.opers)
+		}
+	}
+}
+
+func TestLanguageForFile(t *testing.T) {
+	for name, want := range map[string]string{
+		"main.go":          "go",
+		"src/App.TSX":      "javascript",
+		"lib/x.hpp":        "c++",
+		"build/Makefile":   "make",
+		"Dockerfile":       "dockerfile",
+		"setup.py":         "python",
+		"notes.txt":        "text",
+		"no-extension":     "text",
+		"dir.go/README.md": "text",
+	} {
+		if got := LanguageForFile(name).Name(); got != want {
+			t.Errorf("%s: got %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestCountFileCodeLanguage(t *testing.T) {
+	m := Metrics{CodeTokens: true}
+	dir := t.TempDir()
+	py, txt := filepath.Join(dir, "x.py"), filepath.Join(dir, "x.txt")
+	for _, name := range []string{py, txt} {
+		if err := os.WriteFile(name, []byte("a # b\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := CountFile(context.Background(), py, m, Options{}); got.CodeIdentifiers != 1 {
+		t.Errorf("x.py: got %d identifiers, want 1", got.CodeIdentifiers)
+	}
+	if got := CountSmallFile(context.Background(), txt, m, Options{}, make([]byte, 64)); got.CodeIdentifiers != 2 || got.TokensPerLine() != 3 {
+		t.Errorf("x.txt: got %d identifiers, %.2f tokens per line; want 2, 3", got.CodeIdentifiers, got.TokensPerLine())
+	}
+}
+
+func TestCodeTokensAcrossChunks(t *testing.T) {
+	pieces := []string{"x", "foo", "42", "1e", "+", "-", "=", "/", "*", "/*", "*/", "//", "#", "\"", "'", "`",
+		"\\", "\n", " ", "--", "[[", "]]", `"""`, ".", ">", "<", "é"}
+	rng := rand.New(rand.NewSource(1))
+	m := Metrics{CodeTokens: true}
+	for iter := 0; iter < 200; iter++ {
+		var data []byte
+		for n := rng.Intn(700); len(data) < n; {
+			data = append(data, pieces[rng.Intn(len(pieces))]...)
+		}
+		for _, lang := range []string{"go", "python", "lua", "shell", "text"} {
+			opts := Options{Language: LookupLanguage(lang)}
+			want := CountBytes(data, m, opts)
+			var chunks []ChunkResult
+			for b := data; len(b) > 0; {
+				n := min(1+rng.Intn(300), len(b))
+				chunks = append(chunks, CountChunk(b[:n], m, opts))
+				b = b[n:]
+			}
+			if len(chunks) > 1 && iter%2 == 0 {
+				// the chunks of distributed counting arrive as JSON
+				b, err := json.Marshal(chunks[1])
+				if err != nil {
+					t.Fatal(err)
+				}
+				chunks[1] = ChunkResult{}
+				if err := json.Unmarshal(b, &chunks[1]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got := CountChunk(nil, m, opts).Final()
+			if len(chunks) > 0 {
+				got = MergeChunks(chunks).Final()
+			}
+			if got.CodeIdentifiers != want.CodeIdentifiers || got.CodeLiterals != want.CodeLiterals || got.CodeOperators != want.CodeOperators {
+				t.Fatalf("%s %q in %d chunks: got %d %d %d, want %d %d %d", lang, data, len(chunks),
+					got.CodeIdentifiers, got.CodeLiterals, got.CodeOperators, want.CodeIdentifiers, want.CodeLiterals, want.CodeOperators)
+			}
+		}
+	}
+}
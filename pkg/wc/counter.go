@@ -0,0 +1,342 @@
+package wc
+
+import (
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+// textChars is the most characters a Counter decodes before passing them
+// on, which bounds the memory a large write takes.
+const textChars = 4096
+
+// Counter accumulates counts over a stream that arrives in arbitrary chunks.
+// It is the primitive beneath CountReader:
+//
+//	c := wc.NewCounter(m, opts)
+//	c.Write(chunk1)
+//	c.Write(chunk2)
+//	res := c.Result()
+//
+// Multibyte sequences split across Write calls are reassembled. A Counter is
+// not safe for concurrent use.
+//
+// The metrics are computed by accumulators (see Accumulator): a Counter
+// feeds them the bytes written and, decoded once for all of them, the
+// characters.
+type Counter struct {
+	m    Metrics
+	opt  Options
+	accs Accumulators
+
+	written   uint64 // bytes counted so far
+	lastByte  byte
+	asciiMode bool
+	needSpace bool // a metric tells white space from other characters
+	units     bool // a character was counted
+	carry     []byte
+	text      Text   // reused for every Text fed
+	chars     []Char // reused for the characters of a Text
+	lines     *lineReporter
+	guards    guardState
+	chunkMode bool
+	headDone  bool
+	head      []byte
+}
+
+// NewCounter returns a Counter computing m under opt. Options.OnLine turns
+// on the line, word and max-line counters it reports from, and
+// Options.Progress the line counter.
+func NewCounter(m Metrics, opt Options) *Counter {
+	return newCounter(m, opt, false)
+}
+
+func newCounter(m Metrics, opt Options, chunk bool) *Counter {
+	if opt.OnLine != nil {
+		m.Lines, m.Words, m.MaxLineBytes, m.MaxLineChars = true, true, true, true
+	}
+	if opt.Progress != nil {
+		m.Lines = true
+	}
+	if !m.MatchLines {
+		opt.MatchLines = nil
+	}
+	c := &Counter{
+		m:   m,
+		opt: opt,
+		// start in ASCII fast path when possible
+		asciiMode: (opt.Locale.IsCOrPOSIX || opt.Locale.IsUTF8) && opt.Locale.Decoder == nil,
+		needSpace: m.wordScan() || m.WhitespaceLines || opt.NGrams > 0 || opt.OnLine != nil,
+		carry:     make([]byte, 0, utf8.UTFMax),
+		accs:      startAccumulators(m, opt, chunk),
+		chunkMode: chunk,
+	}
+	if opt.OnLine != nil {
+		c.lines = &lineReporter{words: newWordScan(opt, false, false), onLine: opt.OnLine}
+	}
+	return c
+}
+
+// wordScan reports whether a metric needs the words of the text.
+func (m Metrics) wordScan() bool {
+	return m.Words || m.WordsPerLine || m.WordLengths || m.LongestWord || m.UniqueWords || m.TokenStats || m.WordKinds
+}
+
+// Write feeds the next chunk of the stream. It never returns an error and
+// always consumes all of p, so a Counter can be used as an io.Writer.
+func (c *Counter) Write(p []byte) (int, error) {
+	written := len(p)
+	if c.opt.AbortLineBytes > 0 || c.opt.AbortWordBytes > 0 {
+		if c.guards.err != nil {
+			return written, nil
+		}
+		p = c.guard(p)
+	}
+	n := len(p)
+	if n == 0 {
+		return written, nil
+	}
+	prof := c.opt.Profile
+	var t time.Time
+	if prof != nil {
+		t = time.Now()
+	}
+	c.accs.feed(p)
+	c.written += uint64(n)
+	c.lastByte = p[n-1]
+	if c.chunkMode && !c.headDone {
+		p = c.stripHead(p)
+	}
+
+	// If in ASCII mode, check for any non-ASCII to potentially switch
+	if c.asciiMode && !c.opt.Locale.IsCOrPOSIX {
+		for _, b := range p {
+			if b >= 0x80 {
+				c.asciiMode = false
+				break
+			}
+		}
+	}
+	if c.asciiMode {
+		c.text = Text{Bytes: p}
+		c.feedText(&c.text)
+		if prof != nil {
+			since(&prof.scan, t)
+		}
+	} else {
+		if prof != nil {
+			t = since(&prof.scan, t)
+		}
+		c.decode(p)
+		if prof != nil {
+			since(&prof.decode, t)
+		}
+	}
+	if c.opt.Progress != nil {
+		c.opt.Progress.bytes.Add(uint64(n))
+	}
+
+	if c.opt.OnProgress != nil {
+		c.opt.OnProgress(c.written, c.opt.TotalBytes)
+	}
+	return written, nil
+}
+
+// feedText passes t to the accumulators and Options.OnLine.
+func (c *Counter) feedText(t *Text) {
+	if t.len() == 0 {
+		return
+	}
+	c.units = true
+	c.accs.feedText(t)
+	if c.lines != nil {
+		c.lines.feed(t)
+	}
+	if c.opt.Progress != nil {
+		c.opt.Progress.lines.Add(t.newlines())
+	}
+}
+
+// Result returns the counts for everything written so far, treating the
+// current position as end of input. It does not modify the Counter, so
+// more data may be written afterwards.
+func (c *Counter) Result() FileResult {
+	if c.chunkMode {
+		return c.Chunk().Final()
+	}
+	accs := c.accs.snapshot()
+	if len(c.carry) > 0 {
+		// a partial sequence at the end counts as invalid bytes
+		accs.feedText(invalidText(c.carry))
+	}
+	var res FileResult
+	accs.store(&res)
+	res.NoFinalNewline = c.noFinalNewline()
+	return res
+}
+
+func (c *Counter) noFinalNewline() bool {
+	return c.written > 0 && c.lastByte != '\n'
+}
+
+// flush counts any carried partial sequence as invalid bytes.
+func (c *Counter) flush() {
+	if len(c.carry) > 0 {
+		c.feedText(invalidText(c.carry))
+		c.carry = c.carry[:0]
+	}
+}
+
+// stripHead sets aside leading continuation bytes in chunk mode; they
+// belong to a rune that started in an earlier chunk.
+func (c *Counter) stripHead(p []byte) []byte {
+	if c.opt.Locale.IsCOrPOSIX || c.opt.Locale.Decoder != nil {
+		c.headDone = true
+		return p
+	}
+	for len(p) > 0 && len(c.head) < utf8.UTFMax-1 && !utf8.RuneStart(p[0]) {
+		c.head = append(c.head, p[0])
+		p = p[1:]
+	}
+	if len(p) > 0 || len(c.head) == utf8.UTFMax-1 {
+		c.headDone = true
+	}
+	return p
+}
+
+// lastLine reports an unterminated last line to Options.OnLine, counting a
+// partial sequence at the end as invalid bytes the way Result does.
+func (c *Counter) lastLine() {
+	if c.lines == nil || !c.noFinalNewline() {
+		return
+	}
+	last := *c.lines
+	last.words = c.lines.words.clone()
+	if len(c.carry) > 0 {
+		last.feed(invalidText(c.carry))
+	}
+	if last.words.tailDropped() {
+		last.cur.Words--
+	}
+	last.endLine()
+}
+
+// lineReporter passes the counts of every line to Options.OnLine.
+type lineReporter struct {
+	noWordEvents
+	words  wordScan
+	cur    LineCount
+	onLine func(LineCount)
+}
+
+func (r *lineReporter) feed(t *Text) {
+	for i, b := range t.Bytes {
+		r.words.step(asciiSpace[b], t.Bytes[i:i+1], r)
+		r.char(b == '\n', 1)
+	}
+	for _, ch := range t.Chars {
+		r.words.step(ch.Space, t.text(ch), r)
+		r.char(ch.Rune == '\n', uint64(ch.Size))
+	}
+}
+
+// char counts a character of size bytes, after its word counting.
+func (r *lineReporter) char(newline bool, size uint64) {
+	if newline {
+		r.endLine()
+		return
+	}
+	r.cur.Bytes += size
+	r.cur.Chars++
+}
+
+func (r *lineReporter) start() { r.cur.Words++ }
+
+func (r *lineReporter) drop() { r.cur.Words-- }
+
+// endLine reports the current line and starts the next.
+func (r *lineReporter) endLine() {
+	r.cur.Line++
+	r.onLine(r.cur)
+	r.cur = LineCount{Line: r.cur.Line}
+}
+
+// nonPOSIXSpace reports whether r is Unicode white space outside the space
+// class of glibc's locales (see Options.POSIXSpace).
+func nonPOSIXSpace(r rune) bool {
+	return r == '\u00a0' || r == '\u2007' || r == '\u202f' || r == '\u0085'
+}
+
+// isSpace reports whether r separates words, leaving out the characters
+// of nonPOSIXSpace under Options.POSIXSpace.
+func (c *Counter) isSpace(r rune) bool {
+	if r < utf8.RuneSelf {
+		return asciiSpace[r]
+	}
+	return unicode.IsSpace(r) && !(c.opt.POSIXSpace && nonPOSIXSpace(r))
+}
+
+// decode feeds the characters of p, after any partial one carried over
+// from the previous write, to feedText, textChars at a time. A partial
+// character at the end is carried over to the next write.
+func (c *Counter) decode(p []byte) {
+	data := p
+	if len(c.carry) > 0 {
+		data = append(append(make([]byte, 0, len(c.carry)+len(p)), c.carry...), p...)
+		c.carry = c.carry[:0]
+	}
+	dec := c.opt.Locale.Decoder
+	chars := c.chars[:0]
+	for i := 0; i < len(data); {
+		if len(chars) >= textChars {
+			c.text = Text{Chars: chars, Raw: data}
+			c.feedText(&c.text)
+			chars = chars[:0]
+		}
+		var r rune
+		var size int
+		if dec == nil {
+			if !utf8.FullRune(data[i:]) {
+				c.carry = append(c.carry, data[i:]...)
+				break
+			}
+			r, size = utf8.DecodeRune(data[i:])
+			if r == utf8.RuneError && size == 1 {
+				chars = append(chars, invalidChar(data, i))
+				i++
+				continue
+			}
+		} else {
+			var err error
+			r, size, err = dec.Decode(data[i:])
+			if err == locale.ErrIncomplete {
+				c.carry = append(c.carry, data[i:]...)
+				break
+			}
+			if err != nil || size < 1 {
+				size = min(max(size, 1), len(data)-i)
+				for j := i; j < i+size; j++ {
+					chars = append(chars, invalidChar(data, j))
+				}
+				i += size
+				continue
+			}
+		}
+		ch := Char{Off: i, Rune: r, Size: uint8(size)}
+		if c.needSpace {
+			ch.Space = c.isSpace(r)
+		}
+		chars = append(chars, ch)
+		i += size
+	}
+	c.text = Text{Chars: chars, Raw: data}
+	c.feedText(&c.text)
+	c.chars = chars[:0]
+}
+
+// invalidChar returns the Char of the invalid byte data[i].
+func invalidChar(data []byte, i int) Char {
+	return Char{Off: i, Rune: utf8.RuneError, Size: 1, Space: asciiSpace[data[i]], Invalid: true}
+}
@@ -0,0 +1,240 @@
+package wc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+func TestCounterChunkedMatchesWhole(t *testing.T) {
+	input := "héllo wörld\n日本語 テキスト\n\xffbad\nlast line"
+	m := AllMetrics()
+	opts := Options{BufferSize: 1024, Locale: locale.Info{IsUTF8: true}}
+	whole := CountBytes([]byte(input), m, opts)
+
+	for size := 1; size <= 5; size++ {
+		c := NewCounter(m, opts)
+		for i := 0; i < len(input); i += size {
+			end := i + size
+			if end > len(input) {
+				end = len(input)
+			}
+			if n, err := c.Write([]byte(input[i:end])); err != nil || n != end-i {
+				t.Fatalf("Write returned %d, %v", n, err)
+			}
+		}
+		got := c.Result()
+		got.Duration = whole.Duration
+		if !reflect.DeepEqual(got, whole) {
+			t.Errorf("chunk size %d: got %+v, want %+v", size, got, whole)
+		}
+	}
+}
+
+func TestCounterResultIsNonDestructive(t *testing.T) {
+	c := NewCounter(Metrics{Lines: true, Words: true, Chars: true}, Options{Locale: locale.Info{IsUTF8: true}})
+	c.Write([]byte("one tw\xc3"))
+	first := c.Result()
+	if first.Words != 2 || first.Chars != 7 {
+		t.Errorf("partial result: got %+v", first)
+	}
+	c.Write([]byte("\xa9o\n"))
+	res := c.Result()
+	if res.Lines != 1 || res.Words != 2 || res.Chars != 9 {
+		t.Errorf("final result: got %+v", res)
+	}
+}
+
+func TestCounterAsWriter(t *testing.T) {
+	c := NewCounter(DefaultMetrics(), Options{Locale: locale.Info{IsUTF8: true}})
+	if _, err := strings.NewReader("a b c\nd e\n").WriteTo(c); err != nil {
+		t.Fatal(err)
+	}
+	res := c.Result()
+	if res.Lines != 2 || res.Words != 5 || res.Bytes != 10 {
+		t.Errorf("got %+v", res)
+	}
+}
+
+func TestNoFinalNewline(t *testing.T) {
+	for in, want := range map[string]bool{"": false, "a\n": false, "a\nb": true, "\n\n": false, "é": true} {
+		c := NewCounter(Metrics{Lines: true}, Options{Locale: locale.Info{IsUTF8: true}})
+		for i := 0; i < len(in); i++ {
+			_, _ = c.Write([]byte{in[i]})
+		}
+		if got := c.Result().NoFinalNewline; got != want {
+			t.Errorf("%q: got %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestWhitespaceLines(t *testing.T) {
+	tests := map[string]uint64{
+		"":               0,
+		"\n\n":           0, // empty lines do not count
+		"  \n":           1,
+		"a\n \t\n\nb\n ": 2,
+		"　\n x\n\t\r\n":  2,
+		" ":              1,
+	}
+	for in, want := range tests {
+		got := CountBytes([]byte(in), Metrics{WhitespaceLines: true}, Options{BufferSize: 2, Locale: locale.Info{IsUTF8: true}})
+		if got.WhitespaceLines != want {
+			t.Errorf("%q: got %d, want %d", in, got.WhitespaceLines, want)
+		}
+	}
+}
+
+func TestLineEndings(t *testing.T) {
+	in := "unix\nwin\r\nmac\rmixed\r\r\n\n\r"
+	for _, bufSize := range []int{1, 2, 64} {
+		got := CountBytes([]byte(in), Metrics{LineEndings: true}, Options{BufferSize: bufSize})
+		if got.LFEndings != 2 || got.CRLFEndings != 2 || got.CREndings != 3 {
+			t.Errorf("buffer %d: got LF=%d CRLF=%d CR=%d, want 2 2 3", bufSize, got.LFEndings, got.CRLFEndings, got.CREndings)
+		}
+	}
+}
+
+func TestMaxLineLength(t *testing.T) {
+	tests := []struct {
+		in           string
+		bytes, chars uint64
+	}{
+		{"a\nbb\nccc\n", 3, 3},
+		{"héllo\nx\n", 6, 5},
+		{"ab\ncdef", 4, 4},
+		{"\n\n", 0, 0},
+		{"", 0, 0},
+	}
+	opts := Options{Locale: locale.Info{IsUTF8: true}}
+	for _, tt := range tests {
+		for _, bufSize := range []int{1, 3, 64} {
+			opts.BufferSize = bufSize
+			// the longest line is the same whether or not lines are counted too
+			for _, m := range []Metrics{{MaxLineBytes: true, MaxLineChars: true}, {Lines: true, MaxLineBytes: true, MaxLineChars: true}} {
+				got := CountBytes([]byte(tt.in), m, opts)
+				if got.MaxLineBytes != tt.bytes || got.MaxLineChars != tt.chars {
+					t.Errorf("%q (buffer %d, lines %v): got %d bytes, %d chars; want %d, %d",
+						tt.in, bufSize, m.Lines, got.MaxLineBytes, got.MaxLineChars, tt.bytes, tt.chars)
+				}
+			}
+		}
+	}
+}
+
+func TestWordLengths(t *testing.T) {
+	tests := []struct {
+		in      string
+		longest string
+		avg     float64
+	}{
+		{"", "", 0},
+		{"  \n", "", 0},
+		{"a bb ccc dd", "ccc", 2},
+		{"first later", "first", 5},
+		{"héllo wörld\n", "héllo", 5},
+		{"x QUJDREVGR0hJSktMTU5PUA==\n", "QUJDREVGR0hJSktMTU5PUA==", 12.5},
+		{"don't rock 'n' roll", "don't", 4},
+		{"it’s o’clock", "o’clock", 5.5},
+	}
+	for _, tt := range tests {
+		for _, bufSize := range []int{1, 3, 64} {
+			got := CountBytes([]byte(tt.in), Metrics{WordLengths: true, LongestWord: true}, Options{BufferSize: bufSize, Locale: locale.Info{IsUTF8: true}})
+			if got.LongestWordText != tt.longest || got.LongestWord != uint64(utf8.RuneCountInString(tt.longest)) || got.AvgWordLength() != tt.avg {
+				t.Errorf("%q (buffer %d): got %q (%d), avg %v; want %q, avg %v", tt.in, bufSize, got.LongestWordText, got.LongestWord, got.AvgWordLength(), tt.longest, tt.avg)
+			}
+		}
+	}
+}
+
+func TestWordsPerLine(t *testing.T) {
+	tests := []struct {
+		in            string
+		min, max, all uint64
+		avg           float64
+	}{
+		{"", 0, 0, 0, 0},
+		{"\n", 0, 0, 1, 0},
+		{"one two\n\nthree\n", 0, 2, 3, 1},
+		{"a b c\nd e", 2, 3, 2, 2.5},
+		{"  x  \r\n y\n", 1, 1, 2, 1},
+		{"{\"k\":\"v\",\"l\":[1,2,3]}", 1, 1, 1, 1},
+	}
+	for _, tt := range tests {
+		for _, bufSize := range []int{1, 3, 64} {
+			got := CountBytes([]byte(tt.in), Metrics{WordsPerLine: true}, Options{BufferSize: bufSize, Locale: locale.Info{IsUTF8: true}})
+			if got.MinLineWords != tt.min || got.MaxLineWords != tt.max || got.AllLines != tt.all || got.AvgLineWords() != tt.avg {
+				t.Errorf("%q (buffer %d): got min %d max %d over %d lines, avg %v; want %d %d %d %v",
+					tt.in, bufSize, got.MinLineWords, got.MaxLineWords, got.AllLines, got.AvgLineWords(), tt.min, tt.max, tt.all, tt.avg)
+			}
+		}
+	}
+
+	var total FileResult
+	total.Add(CountBytes([]byte("a b\n"), Metrics{WordsPerLine: true}, Options{}))
+	total.Add(CountBytes(nil, Metrics{WordsPerLine: true}, Options{}))
+	total.Add(CountBytes([]byte("a b c d\ne\n"), Metrics{WordsPerLine: true}, Options{}))
+	if total.MinLineWords != 1 || total.MaxLineWords != 4 || total.AllLines != 3 {
+		t.Errorf("total: got min %d max %d over %d lines", total.MinLineWords, total.MaxLineWords, total.AllLines)
+	}
+}
+
+func TestPOSIXSpace(t *testing.T) {
+	data := []byte("10\u00a0km a\u2003b\nc\u202fd\n")
+	m := Metrics{Words: true, WhitespaceLines: true}
+	for _, tt := range []struct {
+		posix bool
+		words uint64
+	}{{false, 6}, {true, 4}} {
+		got := CountBytes(data, m, Options{Locale: locale.Info{IsUTF8: true}, POSIXSpace: tt.posix})
+		if got.Words != tt.words {
+			t.Errorf("POSIXSpace %v: got %d words, want %d", tt.posix, got.Words, tt.words)
+		}
+	}
+	latin1 := Options{Locale: locale.Info{Decoder: locale.Latin1}, POSIXSpace: true}
+	if got := CountBytes([]byte("a\xa0b\x85c d\n"), m, latin1); got.Words != 2 {
+		t.Errorf("Latin-1: got %d words, want 2", got.Words)
+	}
+}
+
+func TestMinWordLength(t *testing.T) {
+	m := Metrics{Words: true, WordsPerLine: true, WordLengths: true, LongestWord: true, UniqueWords: true}
+	tests := []struct {
+		in                  string
+		words, min, max     uint64
+		chars, unique       uint64
+		longest             string
+		lastLineWordsOnLine uint64
+	}{
+		{"", 0, 0, 0, 0, 0, "", 0},
+		{"a bb ccc\nd ee\n", 3, 1, 2, 7, 3, "ccc", 0},
+		{"- x\nok", 1, 0, 1, 2, 1, "ok", 1},
+		{"é日本 ab, c", 2, 2, 2, 6, 2, "é日本", 2},
+		{"\xff\xff x", 1, 1, 1, 2, 1, "\xff\xff", 1},
+	}
+	for _, tt := range tests {
+		for _, bufSize := range []int{1, 3, 64} {
+			var lines []LineCount
+			opts := Options{BufferSize: bufSize, Locale: locale.Info{IsUTF8: true}, MinWordLength: 2, UniqueFold: true,
+				OnLine: func(l LineCount) { lines = append(lines, l) }}
+			got := CountBytes([]byte(tt.in), m, opts)
+			if got.Words != tt.words || got.MinLineWords != tt.min || got.MaxLineWords != tt.max || got.WordChars != tt.chars ||
+				got.UniqueWords != tt.unique || got.LongestWordText != tt.longest {
+				t.Errorf("%q (buffer %d): got %d words (%d to %d a line), %d chars, %d unique, longest %q",
+					tt.in, bufSize, got.Words, got.MinLineWords, got.MaxLineWords, got.WordChars, got.UniqueWords, got.LongestWordText)
+			}
+			if n := len(lines); n > 0 && lines[n-1].Words != tt.lastLineWordsOnLine && tt.in[len(tt.in)-1] != '\n' {
+				t.Errorf("%q (buffer %d): OnLine got %d words on the last line, want %d", tt.in, bufSize, lines[n-1].Words, tt.lastLineWordsOnLine)
+			}
+		}
+	}
+
+	got := CountBytes([]byte("a big b cat a dog\n"), Metrics{Words: true}, Options{MinWordLength: 3, NGrams: 2})
+	want := map[string]uint64{"big cat": 1, "cat dog": 1}
+	if got.Words != 3 || !reflect.DeepEqual(got.NGrams, want) {
+		t.Errorf("n-grams: got %d words, %v; want 3, %v", got.Words, got.NGrams, want)
+	}
+}
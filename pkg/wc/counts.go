@@ -0,0 +1,79 @@
+package wc
+
+import "bytes"
+
+func init() {
+	registerMetric(&accMetric[uint64]{
+		name:  "bytes",
+		on:    func(Metrics, Options) bool { return true },
+		new:   func(Metrics, Options, bool) Accumulator[uint64] { return new(bytesAcc) },
+		store: func(r *FileResult, v uint64) { r.Bytes = v },
+	})
+	registerMetric(&accMetric[uint64]{
+		name:    "chars",
+		on:      func(m Metrics, _ Options) bool { return m.Chars },
+		newText: func(Metrics, Options, bool) TextAccumulator[uint64] { return new(charsAcc) },
+		store:   func(r *FileResult, v uint64) { r.Chars = v },
+	})
+	registerMetric(&accMetric[uint64]{
+		name:    "lines",
+		on:      func(m Metrics, _ Options) bool { return m.Lines || m.CodeTokens },
+		newText: func(Metrics, Options, bool) TextAccumulator[uint64] { return new(linesAcc) },
+		store:   func(r *FileResult, v uint64) { r.Lines = v },
+	})
+}
+
+// bytesAcc counts bytes. Every counter has one, whatever the metrics, so
+// that partial results and chunks know their size.
+type bytesAcc struct {
+	Bytes uint64
+}
+
+func (a *bytesAcc) Feed(p []byte) { a.Bytes += uint64(len(p)) }
+
+func (a *bytesAcc) Merge(next Accumulator[uint64]) Accumulator[uint64] {
+	return &bytesAcc{a.Bytes + next.(*bytesAcc).Bytes}
+}
+
+func (a *bytesAcc) Result() uint64 { return a.Bytes }
+
+// charsAcc counts characters, an invalid byte as one.
+type charsAcc struct {
+	Chars uint64
+}
+
+func (a *charsAcc) FeedText(t *Text) { a.Chars += uint64(t.len()) }
+
+func (a *charsAcc) Merge(next TextAccumulator[uint64]) TextAccumulator[uint64] {
+	return &charsAcc{a.Chars + next.(*charsAcc).Chars}
+}
+
+func (a *charsAcc) Result() uint64 { return a.Chars }
+
+// linesAcc counts '\n' characters, as wc counts lines. CodeTokens needs
+// them too, for FileResult.TokensPerLine.
+type linesAcc struct {
+	Lines uint64
+}
+
+func (a *linesAcc) FeedText(t *Text) { a.Lines += t.newlines() }
+
+func (a *linesAcc) Merge(next TextAccumulator[uint64]) TextAccumulator[uint64] {
+	return &linesAcc{a.Lines + next.(*linesAcc).Lines}
+}
+
+func (a *linesAcc) Result() uint64 { return a.Lines }
+
+// newlines returns the number of '\n' characters in t.
+func (t *Text) newlines() uint64 {
+	if t.Bytes != nil {
+		return uint64(bytes.Count(t.Bytes, []byte{'\n'}))
+	}
+	var n uint64
+	for _, c := range t.Chars {
+		if c.Rune == '\n' {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,67 @@
+package wc
+
+import (
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+// utf16BE decodes big-endian UTF-16 without surrogate pairs.
+type utf16BE struct{}
+
+func (utf16BE) Decode(p []byte) (rune, int, error) {
+	if len(p) < 2 {
+		return 0, 0, locale.ErrIncomplete
+	}
+	r := rune(p[0])<<8 | rune(p[1])
+	if r >= 0xd800 && r < 0xe000 {
+		return 0, 2, locale.ErrInvalid
+	}
+	return r, 2, nil
+}
+
+func TestCustomDecoder(t *testing.T) {
+	var data []byte
+	for _, r := range "héllo wörld\nzwei\n" {
+		data = append(data, byte(r>>8), byte(r))
+	}
+	data = append(data, 0xd8, 0x00, 'x') // a lone surrogate and half a unit
+	m := Metrics{Lines: true, Words: true, Chars: true, Bytes: true, MaxLineChars: true}
+	for _, bufSize := range []int{1, 3, 64} {
+		opts := Options{BufferSize: bufSize, Locale: locale.Info{Encoding: "utf-16be", Decoder: utf16BE{}}}
+		got := CountBytes(data, m, opts)
+		// the surrogate's two bytes and the half unit count as three invalid chars
+		if got.Lines != 2 || got.Words != 4 || got.Chars != 20 || got.Bytes != 37 || got.MaxLineChars != 11 {
+			t.Errorf("buffer %d: got %d lines, %d words, %d chars, %d bytes, max line %d",
+				bufSize, got.Lines, got.Words, got.Chars, got.Bytes, got.MaxLineChars)
+		}
+	}
+}
+
+func TestLatin1Decoder(t *testing.T) {
+	data := []byte("caf\xe9\xa0cr\xe8me\n") // NBSP between the words
+	m := Metrics{Words: true, Chars: true}
+	got := CountBytes(data, m, Options{Locale: locale.Info{Decoder: locale.Latin1}})
+	if got.Words != 2 || got.Chars != 11 {
+		t.Errorf("Latin-1: got %d words, %d chars, want 2, 11", got.Words, got.Chars)
+	}
+	if got := CountBytes(data, m, Options{Locale: locale.Info{IsUTF8: true}}); got.Words != 1 {
+		t.Errorf("UTF-8: got %d words, want 1", got.Words)
+	}
+}
+
+func TestSingleByteChars(t *testing.T) {
+	data := []byte("caf\xe9\xa0cr\xe8me \xc3\xa9t\xe9\n")
+	m := Metrics{Words: true, Chars: true}
+	// without a decoder, ISO-8859-2 is a character per byte, not UTF-8
+	if got := CountBytes(data, m, Options{Locale: locale.Detect("iso-8859-2")}); got.Words != 2 || got.Chars != uint64(len(data)) {
+		t.Errorf("ISO-8859-2: got %d words, %d chars, want 2, %d", got.Words, got.Chars, len(data))
+	}
+	// glibc's counting: NBSP does not separate words
+	if got := CountBytes(data, m, Options{Locale: locale.Detect("iso-8859-1").ByteChars()}); got.Words != 2 || got.Chars != uint64(len(data)) {
+		t.Errorf("ISO-8859-1 bytes: got %d words, %d chars, want 2, %d", got.Words, got.Chars, len(data))
+	}
+	if got := CountBytes(data, m, Options{Locale: locale.Detect("iso-8859-1")}); got.Words != 3 || got.Chars != uint64(len(data)) {
+		t.Errorf("ISO-8859-1 charmap: got %d words, %d chars, want 3, %d", got.Words, got.Chars, len(data))
+	}
+}
@@ -0,0 +1,261 @@
+package wc
+
+import (
+	"slices"
+	"unicode"
+)
+
+// Code points that shape emoji sequences (Unicode Technical Standard #51).
+const (
+	emojiZWJ        = '‍' // zero width joiner, gluing emoji into one
+	emojiVS16       = '️' // variation selector 16: emoji presentation
+	emojiKeycap     = '⃣' // combining enclosing keycap
+	emojiTagFirst   = '\U000E0020'
+	emojiTagLast    = '\U000E007F' // subdivision flags: 🏴 + tags + cancel tag
+	emojiModFirst   = '\U0001F3FB' // skin tone modifiers
+	emojiModLast    = '\U0001F3FF'
+	emojiRegionalA  = '\U0001F1E6' // regional indicators, paired into flags
+	emojiRegionalZ  = '\U0001F1FF'
+	emojiTextTokens = "0123456789#*"
+)
+
+// emojiPresentation holds the emoji shown as emoji on their own
+// (Emoji_Presentation), regional indicators and skin tones excepted.
+var emojiPresentation = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x231a, 0x231b, 1}, {0x23e9, 0x23ec, 1}, {0x23f0, 0x23f3, 3},
+		{0x25fd, 0x25fe, 1}, {0x2614, 0x2615, 1}, {0x2648, 0x2653, 1},
+		{0x267f, 0x2693, 20}, {0x26a1, 0x26a1, 1}, {0x26aa, 0x26ab, 1},
+		{0x26bd, 0x26be, 1}, {0x26c4, 0x26c5, 1}, {0x26ce, 0x26d4, 6},
+		{0x26ea, 0x26f2, 8}, {0x26f3, 0x26f5, 2}, {0x26fa, 0x26fd, 3},
+		{0x2705, 0x270a, 5}, {0x270b, 0x2728, 29}, {0x274c, 0x274e, 2},
+		{0x2753, 0x2755, 1}, {0x2757, 0x2795, 62}, {0x2796, 0x2797, 1},
+		{0x27b0, 0x27bf, 15}, {0x2b1b, 0x2b1c, 1}, {0x2b50, 0x2b55, 5},
+	},
+	R32: []unicode.Range32{
+		{0x1f004, 0x1f0cf, 203}, {0x1f18e, 0x1f191, 3}, {0x1f192, 0x1f19a, 1},
+		{0x1f201, 0x1f21a, 25}, {0x1f22f, 0x1f232, 3}, {0x1f233, 0x1f236, 1},
+		{0x1f238, 0x1f23a, 1}, {0x1f250, 0x1f251, 1}, {0x1f300, 0x1f3fa, 1},
+		{0x1f400, 0x1f64f, 1}, {0x1f680, 0x1f6ff, 1}, {0x1f7e0, 0x1f7eb, 1},
+		{0x1f7f0, 0x1f90c, 284}, {0x1f90d, 0x1f9ff, 1}, {0x1fa70, 0x1faff, 1},
+	},
+}
+
+// emojiText holds the other emoji (Emoji, but not Emoji_Presentation):
+// symbols such as © or ❤ that are emoji only when followed by U+FE0F or a
+// skin tone, or inside a ZWJ sequence. Digits, # and * are handled as
+// keycaps.
+var emojiText = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x00a9, 0x00ae, 5}, {0x203c, 0x2049, 13}, {0x2122, 0x2139, 23},
+		{0x2194, 0x2199, 1}, {0x21a9, 0x21aa, 1}, {0x2328, 0x23cf, 167},
+		{0x23ed, 0x23ef, 1}, {0x23f1, 0x23f2, 1}, {0x23f8, 0x23fa, 1},
+		{0x24c2, 0x25aa, 232}, {0x25ab, 0x25b6, 11}, {0x25c0, 0x25fb, 59},
+		{0x25fc, 0x2600, 4}, {0x2601, 0x2604, 1}, {0x260e, 0x2611, 3},
+		{0x2618, 0x261d, 5}, {0x2620, 0x2622, 2}, {0x2623, 0x2626, 3},
+		{0x262a, 0x262e, 4}, {0x262f, 0x2638, 9}, {0x2639, 0x263a, 1},
+		{0x2640, 0x2642, 2}, {0x265f, 0x2660, 1}, {0x2663, 0x2665, 2},
+		{0x2666, 0x2668, 2}, {0x267b, 0x267e, 3}, {0x2692, 0x2694, 2},
+		{0x2695, 0x2697, 1}, {0x2699, 0x269b, 2}, {0x269c, 0x26a0, 4},
+		{0x26a7, 0x26b0, 9}, {0x26b1, 0x26c8, 23}, {0x26cf, 0x26d1, 2},
+		{0x26d3, 0x26e9, 22}, {0x26f0, 0x26f1, 1}, {0x26f4, 0x26f7, 3},
+		{0x26f8, 0x26f9, 1}, {0x2702, 0x2708, 6}, {0x2709, 0x270c, 3},
+		{0x270d, 0x270f, 2}, {0x2712, 0x2716, 2}, {0x271d, 0x2721, 4},
+		{0x2733, 0x2734, 1}, {0x2744, 0x2747, 3}, {0x2763, 0x2764, 1},
+		{0x27a1, 0x2934, 403}, {0x2935, 0x2b05, 464}, {0x2b06, 0x2b07, 1},
+		{0x3030, 0x303d, 13}, {0x3297, 0x3299, 2},
+	},
+	R32: []unicode.Range32{
+		{0x1f170, 0x1f171, 1}, {0x1f17e, 0x1f17f, 1}, {0x1f202, 0x1f237, 53},
+		{0x1f321, 0x1f324, 3}, {0x1f325, 0x1f32c, 1}, {0x1f336, 0x1f37d, 71},
+		{0x1f396, 0x1f397, 1}, {0x1f399, 0x1f39b, 1}, {0x1f39e, 0x1f39f, 1},
+		{0x1f3cb, 0x1f3ce, 1}, {0x1f3d4, 0x1f3df, 1}, {0x1f3f3, 0x1f3f5, 2},
+		{0x1f3f7, 0x1f43f, 72}, {0x1f441, 0x1f4fd, 188}, {0x1f549, 0x1f54a, 1},
+		{0x1f56f, 0x1f570, 1}, {0x1f573, 0x1f579, 1}, {0x1f587, 0x1f58a, 3},
+		{0x1f58b, 0x1f58d, 1}, {0x1f590, 0x1f5a5, 21}, {0x1f5a8, 0x1f5b1, 9},
+		{0x1f5b2, 0x1f5bc, 10}, {0x1f5c2, 0x1f5c4, 1}, {0x1f5d1, 0x1f5d3, 1},
+		{0x1f5dc, 0x1f5de, 1}, {0x1f5e1, 0x1f5e3, 2}, {0x1f5e8, 0x1f5ef, 7},
+		{0x1f5f3, 0x1f5fa, 7}, {0x1f5fb, 0x1f5ff, 1}, {0x1f6cb, 0x1f6cd, 2},
+		{0x1f6ce, 0x1f6cf, 1}, {0x1f6e0, 0x1f6e5, 1}, {0x1f6e9, 0x1f6f0, 7},
+		{0x1f6f3, 0x1f6f3, 1},
+	},
+}
+
+// emojiSeg counts emoji as users see them: a ZWJ sequence such as 👩‍💻, a
+// flag made of two regional indicators, a keycap like 1️⃣ and an emoji with
+// its skin tone or tags each count once. It follows the emoji grammar of
+// UTS #51 rather than full grapheme segmentation, so it needs no more state
+// than this.
+type emojiSeg struct {
+	In       bool // inside an emoji that a modifier, tag or ZWJ may extend
+	AfterZWJ bool // In, and a ZWJ just joined another emoji to it
+	Text     bool // a text-style emoji waiting for U+FE0F or a skin tone
+	Keycap   bool // a digit, # or * that U+20E3 would make a keycap
+	FlagOpen bool // a regional indicator waiting for its pair
+}
+
+// next feeds r and reports whether it starts a new emoji.
+func (s *emojiSeg) next(r rune) bool {
+	switch {
+	case r == emojiZWJ:
+		s.AfterZWJ = s.In
+		s.Text, s.Keycap, s.FlagOpen = false, false, false
+		return false
+	case r == emojiVS16:
+		if s.Text {
+			s.Text, s.In = false, true
+			return true
+		}
+		return false // keeps s.Keycap for 1 U+FE0F U+20E3
+	case r == emojiKeycap:
+		started := s.Keycap
+		s.Keycap, s.Text, s.In = false, false, started
+		return started
+	case r >= emojiTagFirst && r <= emojiTagLast:
+		return false
+	case r >= emojiModFirst && r <= emojiModLast:
+		if s.Text {
+			s.Text, s.In = false, true
+			return true
+		}
+		started := !s.In || s.AfterZWJ // a lone skin tone shows as a swatch
+		s.In, s.AfterZWJ, s.FlagOpen = true, false, false
+		return started
+	case r >= emojiRegionalA && r <= emojiRegionalZ:
+		started := !s.FlagOpen
+		s.FlagOpen = !s.FlagOpen
+		s.In, s.AfterZWJ, s.Text, s.Keycap = true, false, false, false
+		return started
+	}
+	pres := unicode.Is(emojiPresentation, r)
+	text := !pres && unicode.Is(emojiText, r)
+	if s.AfterZWJ && (pres || text) {
+		s.AfterZWJ = false
+		return false // joined to the emoji before the ZWJ
+	}
+	s.In, s.AfterZWJ, s.FlagOpen = pres, false, false
+	s.Text = text
+	s.Keycap = r < 0x80 && isKeycapBase(byte(r))
+	return pres
+}
+
+// ascii feeds a run of ASCII text, which holds no emoji but may end in a
+// keycap base.
+func (s *emojiSeg) ascii(p []byte) {
+	if len(p) == 0 {
+		return
+	}
+	*s = emojiSeg{Keycap: isKeycapBase(p[len(p)-1])}
+}
+
+func isKeycapBase(b byte) bool {
+	for i := 0; i < len(emojiTextTokens); i++ {
+		if emojiTextTokens[i] == b {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	registerMetric(&accMetric[uint64]{
+		name: "emoji",
+		on:   func(m Metrics, _ Options) bool { return m.Emoji },
+		newText: func(_ Metrics, _ Options, chunk bool) TextAccumulator[uint64] {
+			return &emojiAcc{Cut: chunk, Settled: !chunk}
+		},
+		store: func(r *FileResult, v uint64) { r.Emoji = v },
+	})
+}
+
+// emojiAcc counts emoji with an emojiSeg. An invalid byte ends any emoji
+// sequence. A Cut text may begin inside a sequence, so its characters up
+// to the first that ends any sequence are kept in Head, for Merge to feed
+// after the text before it, or Result at the start of the stream.
+type emojiAcc struct {
+	Emoji   uint64
+	Cut     bool
+	Started bool   // a character was fed
+	Settled bool   // with Cut, a character ended any sequence begun before the text
+	Head    []rune `json:",omitempty"`
+	Seg     emojiSeg
+}
+
+func (a *emojiAcc) FeedText(t *Text) {
+	if t.len() == 0 {
+		return
+	}
+	a.Started = true
+	if t.Bytes != nil {
+		a.Settled = true
+		a.Seg.ascii(t.Bytes)
+		return
+	}
+	for _, c := range t.Chars {
+		if !a.Settled {
+			if !c.Invalid && !endsEmoji(c.Rune) {
+				a.Head = append(a.Head, c.Rune)
+				continue
+			}
+			a.Settled = true
+		}
+		if c.Invalid {
+			a.Seg = emojiSeg{}
+		} else if a.Seg.next(c.Rune) {
+			a.Emoji++
+		}
+	}
+}
+
+// endsEmoji reports whether r, fed to an emojiSeg, ends any sequence
+// before it and leaves the same state whatever came before.
+func endsEmoji(r rune) bool {
+	switch {
+	case r == emojiZWJ, r == emojiVS16, r == emojiKeycap,
+		r >= emojiTagFirst && r <= emojiTagLast,
+		r >= emojiModFirst && r <= emojiModLast,
+		r >= emojiRegionalA && r <= emojiRegionalZ:
+		return false
+	}
+	return !unicode.Is(emojiPresentation, r) && !unicode.Is(emojiText, r)
+}
+
+// feedHead feeds a's Head to seg, returning the emoji it starts.
+func (a *emojiAcc) feedHead(seg *emojiSeg) uint64 {
+	var n uint64
+	for _, r := range a.Head {
+		if seg.next(r) {
+			n++
+		}
+	}
+	return n
+}
+
+func (a *emojiAcc) Merge(next TextAccumulator[uint64]) TextAccumulator[uint64] {
+	b := next.(*emojiAcc)
+	if !a.Started || !b.Started {
+		out := *a
+		if !a.Started {
+			out = *b
+		}
+		out.Head = slices.Clone(out.Head)
+		return &out
+	}
+	out := &emojiAcc{Emoji: a.Emoji + b.Emoji, Cut: a.Cut, Started: true, Settled: a.Settled, Head: slices.Clone(a.Head), Seg: a.Seg}
+	if !a.Settled {
+		// b's head goes on from a's
+		out.Head = append(out.Head, b.Head...)
+		out.Settled, out.Seg = b.Settled, b.Seg
+		return out
+	}
+	out.Emoji += b.feedHead(&out.Seg)
+	if b.Settled {
+		out.Seg = b.Seg
+	}
+	return out
+}
+
+func (a *emojiAcc) Result() uint64 {
+	var seg emojiSeg
+	return a.Emoji + a.feedHead(&seg)
+}
@@ -0,0 +1,47 @@
+package wc
+
+import (
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+func TestEmoji(t *testing.T) {
+	tests := []struct {
+		in   string
+		want uint64
+	}{
+		{"", 0},
+		{"plain text, 42 #1 *", 0},
+		{"😀", 1},
+		{"😀😀 😀", 3},
+		{"👩‍👩‍👧", 1},       // ZWJ family
+		{"👩‍💻 at work", 1}, // ZWJ profession
+		{"🏳️‍🌈", 1},        // text-style flag, VS16 and ZWJ
+		{"🇯🇵🇫🇷", 2},        // two flags
+		{"🇯", 1},           // lone regional indicator
+		{"🏴\U000E0067\U000E0062\U000E0073\U000E0063\U000E0074\U000E007F", 1}, // Scotland
+		{"1️⃣ #⃣ x⃣", 2}, // keycaps
+		{"👍🏽👍", 2},       // skin tone
+		{"🏽", 1},         // lone skin tone
+		{"© ❤ ™", 0},     // text presentation
+		{"©️ ❤️ ☝🏻", 3},  // emoji presentation
+		{"😀‍", 1},        // dangling ZWJ
+		{"‍😀", 1},        // leading ZWJ
+		{"a‍😀", 1},       // ZWJ after text
+		{"😀\xff😀", 2},
+	}
+	for _, tt := range tests {
+		for _, bufSize := range []int{1, 64} {
+			opts := Options{BufferSize: bufSize, Locale: locale.Info{IsUTF8: true}}
+			if got := CountBytes([]byte(tt.in), Metrics{Emoji: true}, opts).Emoji; got != tt.want {
+				t.Errorf("%q (buffer %d): got %d emoji, want %d", tt.in, bufSize, got, tt.want)
+			}
+		}
+	}
+
+	c := CountBytes([]byte("😀 😀"), Metrics{Emoji: true}, Options{Locale: locale.Info{IsCOrPOSIX: true}})
+	if c.Emoji != 0 {
+		t.Errorf("C locale: got %d emoji, want 0", c.Emoji)
+	}
+}
@@ -0,0 +1,78 @@
+package wc
+
+// LineEndingCounts classifies line terminators for Metrics.LineEndings.
+type LineEndingCounts struct {
+	LF   uint64 // '\n' not preceded by '\r'
+	CRLF uint64
+	CR   uint64 // '\r' not followed by '\n'
+}
+
+func init() {
+	registerMetric(&accMetric[LineEndingCounts]{
+		name:  "line_endings",
+		on:    func(m Metrics, _ Options) bool { return m.LineEndings },
+		new:   func(Metrics, Options, bool) Accumulator[LineEndingCounts] { return new(endingsAcc) },
+		store: func(r *FileResult, v LineEndingCounts) { r.LFEndings, r.CRLFEndings, r.CREndings = v.LF, v.CRLF, v.CR },
+	})
+}
+
+// endingsAcc counts line terminators. CR and LF never occur inside a
+// multibyte sequence, so the raw bytes can be scanned regardless of
+// encoding. A CR at the end of the bytes so far counts as lone until an
+// LF follows it, in this accumulator or the next one merged.
+type endingsAcc struct {
+	LineEndingCounts
+	Fed     bool // any bytes were fed
+	FirstLF bool // the first byte is '\n'
+	LastCR  bool // the last byte is '\r'
+}
+
+func (a *endingsAcc) Feed(p []byte) {
+	if len(p) == 0 {
+		return
+	}
+	if !a.Fed {
+		a.Fed, a.FirstLF = true, p[0] == '\n'
+	}
+	prevCR := a.LastCR
+	for _, b := range p {
+		switch b {
+		case '\r':
+			a.CR++
+		case '\n':
+			if prevCR {
+				a.CR--
+				a.CRLF++
+			} else {
+				a.LF++
+			}
+		}
+		prevCR = b == '\r'
+	}
+	a.LastCR = prevCR
+}
+
+func (a *endingsAcc) Merge(next Accumulator[LineEndingCounts]) Accumulator[LineEndingCounts] {
+	b := next.(*endingsAcc)
+	switch {
+	case !b.Fed:
+		out := *a
+		return &out
+	case !a.Fed:
+		out := *b
+		return &out
+	}
+	out := &endingsAcc{Fed: true, FirstLF: a.FirstLF, LastCR: b.LastCR}
+	out.LF = a.LF + b.LF
+	out.CRLF = a.CRLF + b.CRLF
+	out.CR = a.CR + b.CR
+	if a.LastCR && b.FirstLF {
+		// a CRLF split between the two
+		out.CR--
+		out.LF--
+		out.CRLF++
+	}
+	return out
+}
+
+func (a *endingsAcc) Result() LineEndingCounts { return a.LineEndingCounts }
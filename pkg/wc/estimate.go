@@ -0,0 +1,143 @@
+package wc
+
+import (
+	"context"
+	"io"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	// estimateBlockSize is the unit of sampling for EstimateFile.
+	estimateBlockSize = 64 * 1024
+	// estimateMinBlocks keeps the confidence interval meaningful for
+	// small sample fractions.
+	estimateMinBlocks = 32
+)
+
+// Estimate is an extrapolated count for a file too large to read in full.
+type Estimate struct {
+	// FileResult holds the point estimates. Bytes is always exact; the
+	// max-line metrics, the longest word and the unique words only cover
+	// the sampled blocks, so they are lower bounds. The fewest and most
+	// words on a line likewise cover only the sample.
+	FileResult
+
+	// Exact reports that the file was read in full, either because it is
+	// small or because the sample would have covered all of it.
+	Exact bool
+	// SampledBytes is how much of the file was actually read.
+	SampledBytes uint64
+
+	// LinesMargin, WordsMargin and CharsMargin are the half-widths of the
+	// 95% confidence intervals around the estimates.
+	LinesMargin uint64
+	WordsMargin uint64
+	CharsMargin uint64
+}
+
+// EstimateFile counts a random sample of fixed-size blocks making up roughly
+// fraction (0 < fraction <= 1) of the named file and extrapolates the totals.
+// One block is drawn from each of k equal strata, so the sample is spread
+// over the whole file. Files whose size StatSize does not trust, or that
+// are too small to sample, are counted exactly.
+func EstimateFile(name string, m Metrics, opt Options, fraction float64) Estimate {
+	start := time.Now()
+	opt.setLanguage(name)
+	f, err := opt.open(name)
+	if err != nil {
+		return Estimate{FileResult: FileResult{Filename: name, Err: err}}
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return Estimate{FileResult: FileResult{Filename: name, Err: err}}
+	}
+
+	size, trusted := StatSize(name, st)
+	blocks := size / estimateBlockSize
+	k := int64(math.Ceil(float64(blocks) * fraction))
+	if k < estimateMinBlocks {
+		k = estimateMinBlocks
+	}
+	if !trusted || k >= blocks {
+		res := CountFile(context.Background(), name, m, opt)
+		return Estimate{FileResult: res, Exact: true, SampledBytes: res.Bytes}
+	}
+
+	// deterministic, so repeated runs over the same file agree
+	rng := rand.New(rand.NewSource(size))
+	buf := make([]byte, estimateBlockSize)
+	var sum, sumSq [3]float64 // lines, words, chars
+	var sample FileResult
+	for i := int64(0); i < k; i++ {
+		lo, hi := i*blocks/k, (i+1)*blocks/k
+		b := lo + rng.Int63n(hi-lo)
+		n, err := f.ReadAt(buf, b*estimateBlockSize)
+		if err != nil && err != io.EOF {
+			return Estimate{FileResult: FileResult{Filename: name, Err: err}}
+		}
+		cr := CountChunk(buf[:n], m, opt).Final()
+		for j, v := range [3]uint64{cr.Lines, cr.Words, cr.Chars} {
+			sum[j] += float64(v)
+			sumSq[j] += float64(v) * float64(v)
+		}
+		sample.Add(cr)
+	}
+
+	// Stratified sampling with one draw per stratum: the estimate is the
+	// mean per-block count scaled to the whole file, and the variance uses
+	// the sample variance with a finite population correction.
+	scale := float64(size) / estimateBlockSize
+	fpc := 1 - float64(k)/float64(blocks)
+	var est, margin [3]uint64
+	for j := range sum {
+		mean := sum[j] / float64(k)
+		variance := (sumSq[j] - float64(k)*mean*mean) / float64(k-1)
+		if variance < 0 {
+			variance = 0
+		}
+		se := scale * math.Sqrt(variance/float64(k)*fpc)
+		est[j] = uint64(math.Round(mean * scale))
+		margin[j] = uint64(math.Ceil(1.96 * se))
+	}
+
+	res := FileResult{
+		Filename:     name,
+		Lines:        est[0],
+		Words:        est[1],
+		Chars:        est[2],
+		Bytes:        uint64(size),
+		MaxLineBytes: sample.MaxLineBytes,
+		MaxLineChars: sample.MaxLineChars,
+		Duration:     time.Since(start),
+
+		LongestWord:       sample.LongestWord,
+		LongestWordText:   sample.LongestWordText,
+		WordChars:         uint64(math.Round(float64(sample.WordChars) / float64(k) * scale)),
+		Emoji:             uint64(math.Round(float64(sample.Emoji) / float64(k) * scale)),
+		NumberTokens:      uint64(math.Round(float64(sample.NumberTokens) / float64(k) * scale)),
+		URLTokens:         uint64(math.Round(float64(sample.URLTokens) / float64(k) * scale)),
+		EmailTokens:       uint64(math.Round(float64(sample.EmailTokens) / float64(k) * scale)),
+		NumericWords:      uint64(math.Round(float64(sample.NumericWords) / float64(k) * scale)),
+		AlphanumericWords: uint64(math.Round(float64(sample.AlphanumericWords) / float64(k) * scale)),
+		AlphabeticWords:   uint64(math.Round(float64(sample.AlphabeticWords) / float64(k) * scale)),
+		CodeIdentifiers:   uint64(math.Round(float64(sample.CodeIdentifiers) / float64(k) * scale)),
+		CodeLiterals:      uint64(math.Round(float64(sample.CodeLiterals) / float64(k) * scale)),
+		CodeOperators:     uint64(math.Round(float64(sample.CodeOperators) / float64(k) * scale)),
+		UniqueWords:       sample.UniqueWords,
+		Vocabulary:        sample.Vocabulary,
+
+		MinLineWords: sample.MinLineWords,
+		MaxLineWords: sample.MaxLineWords,
+		AllLines:     uint64(math.Round(float64(sample.AllLines) / float64(k) * scale)),
+	}
+	return Estimate{
+		FileResult:   res,
+		SampledBytes: sample.Bytes,
+		LinesMargin:  margin[0],
+		WordsMargin:  margin[1],
+		CharsMargin:  margin[2],
+	}
+}
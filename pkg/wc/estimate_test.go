@@ -0,0 +1,70 @@
+package wc
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+func TestEstimateFile(t *testing.T) {
+	dir := t.TempDir()
+	rng := rand.New(rand.NewSource(3))
+	var sb strings.Builder
+	for sb.Len() < 100*estimateBlockSize {
+		for w := rng.Intn(12); w >= 0; w-- {
+			sb.WriteString(strings.Repeat("x", 1+rng.Intn(8)))
+			sb.WriteByte(' ')
+		}
+		sb.WriteByte('\n')
+	}
+	big := filepath.Join(dir, "big.txt")
+	if err := os.WriteFile(big, []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m := Metrics{Lines: true, Words: true, Bytes: true}
+	opts := Options{Locale: locale.Info{IsUTF8: true}}
+	exact := CountFile(context.Background(), big, m, opts)
+
+	est := EstimateFile(big, m, opts, 0.1)
+	if est.Err != nil {
+		t.Fatal(est.Err)
+	}
+	if est.Exact || est.SampledBytes >= exact.Bytes/2 {
+		t.Errorf("expected a partial sample, got Exact=%v SampledBytes=%d", est.Exact, est.SampledBytes)
+	}
+	if est.Bytes != exact.Bytes {
+		t.Errorf("Bytes should be exact: got %d, want %d", est.Bytes, exact.Bytes)
+	}
+	if est.LinesMargin == 0 || est.WordsMargin == 0 {
+		t.Errorf("expected non-zero margins, got %+v", est)
+	}
+	within := func(got, want, margin uint64) bool {
+		d := int64(got) - int64(want)
+		if d < 0 {
+			d = -d
+		}
+		return uint64(d) <= 2*margin
+	}
+	if !within(est.Lines, exact.Lines, est.LinesMargin) {
+		t.Errorf("lines: estimate %d ± %d, exact %d", est.Lines, est.LinesMargin, exact.Lines)
+	}
+	if !within(est.Words, exact.Words, est.WordsMargin) {
+		t.Errorf("words: estimate %d ± %d, exact %d", est.Words, est.WordsMargin, exact.Words)
+	}
+
+	small := filepath.Join(dir, "small.txt")
+	if err := os.WriteFile(small, []byte("a b\nc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if est := EstimateFile(small, m, opts, 0.1); !est.Exact || est.Lines != 2 || est.Words != 3 || est.LinesMargin != 0 {
+		t.Errorf("small file should be counted exactly, got %+v", est)
+	}
+	if est := EstimateFile(filepath.Join(dir, "missing"), m, opts, 0.1); est.Err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package wc
+
+import "errors"
+
+func mkfifo(string) error {
+	return errors.New("fifos not supported")
+}
@@ -0,0 +1,137 @@
+package wc
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// CountFile opens and counts the named file. If ctx is done before counting
+// finishes, the result carries ctx.Err() and the file is closed; an open or
+// read blocked in the kernel (e.g. a FIFO without a writer, or a hung
+// network mount) is abandoned and left to return in the background.
+func CountFile(ctx context.Context, name string, m Metrics, opt Options) FileResult {
+	start := time.Now()
+	if err := ctx.Err(); err != nil {
+		return FileResult{Filename: name, Err: err}
+	}
+	opt.setLanguage(name)
+	if opt.BufferSize <= 0 {
+		opt.BufferSize = defaultBufferSize
+	}
+
+	var mu sync.Mutex // guards f and abandoned
+	var f *os.File
+	abandoned := false
+	done := make(chan FileResult, 1)
+	go func() {
+		of, err := opt.open(name)
+		if opt.Profile != nil {
+			since(&opt.Profile.readWait, start)
+		}
+		if err != nil {
+			done <- FileResult{Err: err}
+			return
+		}
+		mu.Lock()
+		if abandoned {
+			mu.Unlock()
+			_ = of.Close()
+			return
+		}
+		f = of
+		mu.Unlock()
+		if opt.Offset > 0 {
+			// seek when possible; CountReader discards the offset otherwise
+			if st, err := of.Stat(); err == nil && st.Mode().IsRegular() {
+				if _, err := of.Seek(opt.Offset, io.SeekStart); err == nil {
+					opt.Offset = 0
+				}
+			}
+		}
+		done <- CountReader(bufio.NewReaderSize(of, opt.BufferSize), m, opt)
+	}()
+
+	var res FileResult
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		res = FileResult{Err: ctx.Err()}
+	}
+	mu.Lock()
+	abandoned = true
+	if f != nil {
+		_ = f.Close()
+	}
+	mu.Unlock()
+	res.Filename = name
+	res.Duration = time.Since(start)
+	return res
+}
+
+// CountSmallFile counts the named file by reading it whole into buf, which
+// the caller reuses across files to avoid the per-file buffers and
+// goroutine of CountFile. It is meant for files known to be small: one
+// that does not fit in buf, or options that window or transform the input
+// (Offset, Length, StopAfterLines, StopAfterBytes, Strip), fall back to
+// CountFile. ctx is only checked before the file is opened.
+func CountSmallFile(ctx context.Context, name string, m Metrics, opt Options, buf []byte) FileResult {
+	if opt.Offset > 0 || opt.Length > 0 || opt.StopAfterLines > 0 || opt.StopAfterBytes > 0 || opt.Strip != StripNone {
+		return CountFile(ctx, name, m, opt)
+	}
+	start := time.Now()
+	if err := ctx.Err(); err != nil {
+		return FileResult{Filename: name, Err: err}
+	}
+	opt.setLanguage(name)
+	f, err := opt.open(name)
+	if err != nil {
+		return FileResult{Filename: name, Err: err}
+	}
+	n, err := io.ReadFull(f, buf)
+	_ = f.Close()
+	if opt.Profile != nil {
+		since(&opt.Profile.readWait, start)
+	}
+	switch err {
+	case nil:
+		// grew past buf since it was sized up
+		return CountFile(ctx, name, m, opt)
+	case io.EOF, io.ErrUnexpectedEOF:
+	default:
+		return FileResult{Filename: name, Err: err, Duration: time.Since(start)}
+	}
+	c := NewCounter(m, opt)
+	_, _ = c.Write(buf[:n])
+	var res FileResult
+	if err := c.Err(); err != nil {
+		res = c.partial(err)
+	} else {
+		if opt.OnProgress != nil {
+			opt.OnProgress(uint64(n), opt.TotalBytes)
+		}
+		c.lastLine()
+		res = c.Result()
+	}
+	res.Filename = name
+	res.Duration = time.Since(start)
+	return res
+}
+
+// open opens the named input with Options.Open or os.Open.
+func (opt Options) open(name string) (*os.File, error) {
+	if opt.Open != nil {
+		return opt.Open(name)
+	}
+	return os.Open(name)
+}
+
+// setLanguage sets Options.Language, when it is nil, by the file name.
+func (opt *Options) setLanguage(name string) {
+	if opt.Language == nil {
+		opt.Language = LanguageForFile(name)
+	}
+}
@@ -0,0 +1,131 @@
+package wc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+func TestCountFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f.txt")
+	if err := os.WriteFile(path, []byte("a b\nc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Locale: locale.Info{IsUTF8: true}}
+	res := CountFile(context.Background(), path, DefaultMetrics(), opts)
+	if res.Err != nil || res.Lines != 2 || res.Words != 3 || res.Bytes != 6 || res.Filename != path {
+		t.Errorf("got %+v", res)
+	}
+
+	if res := CountFile(context.Background(), filepath.Join(t.TempDir(), "missing"), DefaultMetrics(), opts); res.Err == nil {
+		t.Error("expected error for missing file")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if res := CountFile(ctx, path, DefaultMetrics(), opts); !errors.Is(res.Err, context.Canceled) {
+		t.Errorf("cancelled context: got err %v", res.Err)
+	}
+}
+
+func TestCountFileWindow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f.txt")
+	if err := os.WriteFile(path, []byte("one two\nthree four\nfive\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		offset, length int64
+		lines, words   uint64
+		bytes          uint64
+	}{
+		{8, 11, 1, 2, 11}, // "three four\n"
+		{8, 0, 2, 3, 16},  // to the end
+		{0, 4, 0, 1, 4},   // "one "
+		{100, 5, 0, 0, 0}, // past the end
+	}
+	for _, tt := range tests {
+		opts := Options{Locale: locale.Info{IsUTF8: true}, Offset: tt.offset, Length: tt.length}
+		res := CountFile(context.Background(), path, DefaultMetrics(), opts)
+		if res.Err != nil || res.Lines != tt.lines || res.Words != tt.words || res.Bytes != tt.bytes {
+			t.Errorf("offset %d length %d: got %+v", tt.offset, tt.length, res)
+		}
+		// the discard path used for pipes must agree with seeking
+		data, _ := os.ReadFile(path)
+		if cb := CountBytes(data, DefaultMetrics(), opts); cb.Lines != res.Lines || cb.Words != res.Words || cb.Bytes != res.Bytes {
+			t.Errorf("offset %d length %d: CountBytes %+v differs from CountFile %+v", tt.offset, tt.length, cb, res)
+		}
+	}
+}
+
+func TestCountFileTimeout(t *testing.T) {
+	dir := t.TempDir()
+	fifo := filepath.Join(dir, "fifo")
+	if err := mkfifo(fifo); err != nil {
+		t.Skipf("mkfifo unavailable: %v", err)
+	}
+	// Keep a writer open so reads block instead of hitting EOF.
+	w, err := os.OpenFile(fifo, os.O_RDWR, 0)
+	if err != nil {
+		t.Skipf("cannot open fifo: %v", err)
+	}
+	defer w.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	res := CountFile(ctx, fifo, DefaultMetrics(), Options{Locale: locale.Info{IsUTF8: true}})
+	if !errors.Is(res.Err, context.DeadlineExceeded) {
+		t.Errorf("got err %v, want deadline exceeded", res.Err)
+	}
+
+	// Without a writer, opening the FIFO blocks; the deadline covers that too.
+	lonely := filepath.Join(dir, "lonely")
+	if err := mkfifo(lonely); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	res = CountFile(ctx, lonely, DefaultMetrics(), Options{Locale: locale.Info{IsUTF8: true}})
+	if !errors.Is(res.Err, context.DeadlineExceeded) {
+		t.Errorf("open without writer: got err %v, want deadline exceeded", res.Err)
+	}
+	// let the abandoned open return, which then closes the FIFO again
+	if w, err := os.OpenFile(lonely, os.O_WRONLY, 0); err == nil {
+		w.Close()
+	}
+}
+
+func TestCountSmallFile(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.txt")
+	big := filepath.Join(dir, "big.txt")
+	if err := os.WriteFile(small, []byte("a b\nc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(big, []byte("one two three\nfour\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Locale: locale.Info{IsUTF8: true}}
+	buf := make([]byte, 8)
+	for _, path := range []string{small, big, small} {
+		want := CountFile(context.Background(), path, DefaultMetrics(), opts)
+		got := CountSmallFile(context.Background(), path, DefaultMetrics(), opts, buf)
+		if got.Err != nil || got.Lines != want.Lines || got.Words != want.Words || got.Bytes != want.Bytes ||
+			got.NoFinalNewline != want.NoFinalNewline || got.Filename != path {
+			t.Errorf("%s: got %+v, want %+v", path, got, want)
+		}
+	}
+
+	if res := CountSmallFile(context.Background(), filepath.Join(dir, "missing"), DefaultMetrics(), opts, buf); res.Err == nil {
+		t.Error("expected error for missing file")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if res := CountSmallFile(ctx, small, DefaultMetrics(), opts, buf); !errors.Is(res.Err, context.Canceled) {
+		t.Errorf("cancelled context: got err %v", res.Err)
+	}
+}
@@ -8,6 +8,11 @@ import (
 
 // ComputeWidth decides the minimum column width required for alignment
 func ComputeWidth(results []wc.FileResult, totals wc.FileResult, m wc.Metrics) int {
+	return ComputeWidthMin(results, totals, m, 7)
+}
+
+// ComputeWidthMin is ComputeWidth with a caller-chosen minimum width
+func ComputeWidthMin(results []wc.FileResult, totals wc.FileResult, m wc.Metrics, minWidth int) int {
 	max := uint64(0)
 	for _, r := range results {
 		if r.Err != nil { continue }
@@ -17,6 +22,19 @@ func ComputeWidth(results []wc.FileResult, totals wc.FileResult, m wc.Metrics) i
 		if m.Bytes && r.Bytes > max { max = r.Bytes }
 		if m.MaxLineBytes && r.MaxLineBytes > max { max = r.MaxLineBytes }
 		if m.MaxLineChars && r.MaxLineChars > max { max = r.MaxLineChars }
+		if m.WhitespaceLines && r.WhitespaceLines > max { max = r.WhitespaceLines }
+		if m.LineEndings { max = maxOf(max, r.LFEndings, r.CRLFEndings, r.CREndings) }
+		if m.WordLengths && r.LongestWord > max { max = r.LongestWord }
+		if m.WordsPerLine && r.MaxLineWords > max { max = r.MaxLineWords }
+		if m.Emoji && r.Emoji > max { max = r.Emoji }
+		if m.TokenStats { max = maxOf(max, r.NumberTokens, r.URLTokens, r.EmailTokens) }
+		if m.WordKinds { max = maxOf(max, r.NumericWords, r.AlphanumericWords, r.AlphabeticWords) }
+		if m.CodeTokens { max = maxOf(max, r.CodeIdentifiers, r.CodeLiterals, r.CodeOperators) }
+		if m.MatchLines { max = maxOf(max, r.MatchingLines, r.NonMatchingLines) }
+		if m.UniqueWords && r.UniqueWords > max { max = r.UniqueWords }
+		for _, v := range r.CharCounts { if v > max { max = v } }
+		for _, v := range r.StringCounts { if v > max { max = v } }
+		for _, v := range r.RegexpCounts { if v > max { max = v } }
 	}
 	if m.Lines && totals.Lines > max { max = totals.Lines }
 	if m.Words && totals.Words > max { max = totals.Words }
@@ -24,25 +42,149 @@ func ComputeWidth(results []wc.FileResult, totals wc.FileResult, m wc.Metrics) i
 	if m.Bytes && totals.Bytes > max { max = totals.Bytes }
 	if m.MaxLineBytes && totals.MaxLineBytes > max { max = totals.MaxLineBytes }
 	if m.MaxLineChars && totals.MaxLineChars > max { max = totals.MaxLineChars }
+	if m.WhitespaceLines && totals.WhitespaceLines > max { max = totals.WhitespaceLines }
+	if m.LineEndings { max = maxOf(max, totals.LFEndings, totals.CRLFEndings, totals.CREndings) }
+	if m.WordLengths && totals.LongestWord > max { max = totals.LongestWord }
+	if m.WordsPerLine && totals.MaxLineWords > max { max = totals.MaxLineWords }
+	if m.Emoji && totals.Emoji > max { max = totals.Emoji }
+	if m.TokenStats { max = maxOf(max, totals.NumberTokens, totals.URLTokens, totals.EmailTokens) }
+	if m.WordKinds { max = maxOf(max, totals.NumericWords, totals.AlphanumericWords, totals.AlphabeticWords) }
+	if m.CodeTokens { max = maxOf(max, totals.CodeIdentifiers, totals.CodeLiterals, totals.CodeOperators) }
+	if m.MatchLines { max = maxOf(max, totals.MatchingLines, totals.NonMatchingLines) }
+	if m.UniqueWords && totals.UniqueWords > max { max = totals.UniqueWords }
+	for _, v := range totals.CharCounts { if v > max { max = v } }
+	for _, v := range totals.StringCounts { if v > max { max = v } }
+	for _, v := range totals.RegexpCounts { if v > max { max = v } }
 	w := len(strconv.FormatUint(max, 10))
-	if w < 7 { w = 7 }
+	if w < minWidth { w = minWidth }
 	return w
 }
 
+// GNUWidth returns the minimum width GNU wc would use: the number of digits
+// in the summed size of the inputs when all of them are regular files, or 7
+// when any input (such as a pipe) has no meaningful size.
+func GNUWidth(regularTotal uint64, allRegular bool) int {
+	if !allRegular { return 7 }
+	return len(strconv.FormatUint(regularTotal, 10))
+}
+
 // FormatLine formats a single file result
 func FormatLine(r wc.FileResult, m wc.Metrics, width int) string {
-	// Order: lines, words, chars, bytes, max-line-bytes, max-line-chars
-	parts := make([]string, 0, 6)
-	if m.Lines { parts = append(parts, padRight(r.Lines, width)) }
-	if m.Words { parts = append(parts, padRight(r.Words, width)) }
-	if m.Chars { parts = append(parts, padRight(r.Chars, width)) }
-	if m.Bytes { parts = append(parts, padRight(r.Bytes, width)) }
-	if m.MaxLineBytes { parts = append(parts, padRight(r.MaxLineBytes, width)) }
-	if m.MaxLineChars { parts = append(parts, padRight(r.MaxLineChars, width)) }
+	parts := pad(cells(r, m), m, width)
 	if r.Filename != "" { parts = append(parts, r.Filename) }
 	return join(parts)
 }
 
+// FormatHeader formats a column header row matching FormatLine's layout
+func FormatHeader(m wc.Metrics, width int) string {
+	return FormatHeaderExtra(m, nil, width)
+}
+
+// FormatHeaderExtra is FormatHeader with labels for extra count columns
+// (wc.FileResult.CharCounts, then StringCounts and RegexpCounts) placed
+// before the file column
+func FormatHeaderExtra(m wc.Metrics, extra []string, width int) string {
+	return join(append(pad(labels(m, extra), m, width), "file"))
+}
+
+// cells returns the columns of r before the file name, unpadded
+func cells(r wc.FileResult, m wc.Metrics) []string {
+	// Order: lines, words, chars, bytes, max-line-bytes, max-line-chars,
+	// whitespace-lines, line endings (lf, crlf, cr), no-final-newline,
+	// word lengths (longest, average), words per line (min, average, max),
+	// emoji, token kinds (numbers, urls, emails), word kinds (numeric,
+	// alnum, alpha), code tokens (identifiers, literals, operators, tokens
+	// per line), lines matching and not matching, unique words; the
+	// longest word goes last
+	num := func(v uint64) string { return strconv.FormatUint(v, 10) }
+	parts := make([]string, 0, 18)
+	if m.Lines { parts = append(parts, num(r.Lines)) }
+	if m.Words { parts = append(parts, num(r.Words)) }
+	if m.Chars { parts = append(parts, num(r.Chars)) }
+	if m.Bytes { parts = append(parts, num(r.Bytes)) }
+	if m.MaxLineBytes { parts = append(parts, num(r.MaxLineBytes)) }
+	if m.MaxLineChars { parts = append(parts, num(r.MaxLineChars)) }
+	if m.WhitespaceLines { parts = append(parts, num(r.WhitespaceLines)) }
+	if m.LineEndings { parts = append(parts, num(r.LFEndings), num(r.CRLFEndings), num(r.CREndings)) }
+	if m.NoFinalNewline { parts = append(parts, num(r.FinalNewlinesMissing())) }
+	if m.WordLengths {
+		parts = append(parts, num(r.LongestWord), strconv.FormatFloat(r.AvgWordLength(), 'f', 2, 64))
+	}
+	if m.WordsPerLine {
+		parts = append(parts, num(r.MinLineWords), strconv.FormatFloat(r.AvgLineWords(), 'f', 2, 64), num(r.MaxLineWords))
+	}
+	if m.Emoji { parts = append(parts, num(r.Emoji)) }
+	if m.TokenStats { parts = append(parts, num(r.NumberTokens), num(r.URLTokens), num(r.EmailTokens)) }
+	if m.WordKinds { parts = append(parts, num(r.NumericWords), num(r.AlphanumericWords), num(r.AlphabeticWords)) }
+	if m.CodeTokens {
+		parts = append(parts, num(r.CodeIdentifiers), num(r.CodeLiterals), num(r.CodeOperators), strconv.FormatFloat(r.TokensPerLine(), 'f', 2, 64))
+	}
+	if m.MatchLines { parts = append(parts, num(r.MatchingLines), num(r.NonMatchingLines)) }
+	if m.UniqueWords { parts = append(parts, num(r.UniqueWords)) }
+	// extra columns for --count-char, --count-string and --patterns-from,
+	// in option order
+	for _, v := range r.CharCounts { parts = append(parts, num(v)) }
+	for _, v := range r.StringCounts { parts = append(parts, num(v)) }
+	for _, v := range r.RegexpCounts { parts = append(parts, num(v)) }
+	if m.LongestWord { parts = append(parts, longestWord(r.LongestWordText)) }
+	return parts
+}
+
+// labels returns the header labels matching cells
+func labels(m wc.Metrics, extra []string) []string {
+	parts := make([]string, 0, 18+len(extra))
+	if m.Lines { parts = append(parts, "lines") }
+	if m.Words { parts = append(parts, "words") }
+	if m.Chars { parts = append(parts, "chars") }
+	if m.Bytes { parts = append(parts, "bytes") }
+	if m.MaxLineBytes { parts = append(parts, "maxline") }
+	if m.MaxLineChars { parts = append(parts, "maxchar") }
+	if m.WhitespaceLines { parts = append(parts, "wslines") }
+	if m.LineEndings { parts = append(parts, "lf", "crlf", "cr") }
+	if m.NoFinalNewline { parts = append(parts, "nofinalnl") }
+	if m.WordLengths { parts = append(parts, "maxword", "avgword") }
+	if m.WordsPerLine { parts = append(parts, "minwpl", "avgwpl", "maxwpl") }
+	if m.Emoji { parts = append(parts, "emoji") }
+	if m.TokenStats { parts = append(parts, "numbers", "urls", "emails") }
+	if m.WordKinds { parts = append(parts, "numeric", "alnum", "alpha") }
+	if m.CodeTokens { parts = append(parts, "idents", "literals", "operators", "tpl") }
+	if m.MatchLines { parts = append(parts, "match", "nomatch") }
+	if m.UniqueWords { parts = append(parts, "unique") }
+	parts = append(parts, extra...)
+	if m.LongestWord { parts = append(parts, "longest") }
+	return parts
+}
+
+// pad right-aligns cells to width, except for the longest word
+func pad(cells []string, m wc.Metrics, width int) []string {
+	out := make([]string, len(cells), len(cells)+1)
+	for i, c := range cells {
+		if m.LongestWord && i == len(cells)-1 {
+			out[i] = c
+		} else {
+			out[i] = padLabel(c, width)
+		}
+	}
+	return out
+}
+
+func maxOf(vs ...uint64) uint64 {
+	var m uint64
+	for _, v := range vs { if v > m { m = v } }
+	return m
+}
+
+// longestWord prints an empty longest word as "-" to keep the column count
+func longestWord(s string) string {
+	if s == "" { return "-" }
+	return s
+}
+
+func padLabel(s string, width int) string {
+	for len(s) < width { s = " " + s }
+	return s
+}
+
 func join(parts []string) string {
 	if len(parts) == 0 { return "" }
 	out := parts[0]
@@ -58,3 +200,17 @@ func padRight(v uint64, width int) string {
 	return s
 }
 
+
+// CSVHeader returns the column names of a CSV record: "file", then
+// FormatHeaderExtra's labels.
+func CSVHeader(m wc.Metrics, extra []string) []string {
+	return append([]string{"file"}, labels(m, extra)...)
+}
+
+// CSVRecord returns the fields of r matching CSVHeader. Unlike FormatLine,
+// an empty longest word is left empty.
+func CSVRecord(r wc.FileResult, m wc.Metrics) []string {
+	rec := append([]string{r.Filename}, cells(r, m)...)
+	if m.LongestWord { rec[len(rec)-1] = r.LongestWordText }
+	return rec
+}
@@ -1,6 +1,7 @@
 package format
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/rajasatyajit/go-wc/pkg/wc"
@@ -73,6 +74,34 @@ func TestComputeWidth(t *testing.T) {
 	}
 }
 
+func TestComputeWidthMin(t *testing.T) {
+	results := []wc.FileResult{{Lines: 12, Words: 345}}
+	totals := wc.FileResult{Lines: 12, Words: 345}
+	m := wc.Metrics{Lines: true, Words: true}
+	if w := ComputeWidthMin(results, totals, m, 1); w != 3 {
+		t.Errorf("ComputeWidthMin(min 1) = %d, want 3", w)
+	}
+	if w := ComputeWidthMin(results, totals, m, 5); w != 5 {
+		t.Errorf("ComputeWidthMin(min 5) = %d, want 5", w)
+	}
+	results[0].CharCounts = []uint64{123456}
+	if w := ComputeWidthMin(results, totals, m, 1); w != 6 {
+		t.Errorf("ComputeWidthMin(char counts) = %d, want 6", w)
+	}
+}
+
+func TestGNUWidth(t *testing.T) {
+	if w := GNUWidth(7111, true); w != 4 {
+		t.Errorf("GNUWidth(regular) = %d, want 4", w)
+	}
+	if w := GNUWidth(0, true); w != 1 {
+		t.Errorf("GNUWidth(empty) = %d, want 1", w)
+	}
+	if w := GNUWidth(7111, false); w != 7 {
+		t.Errorf("GNUWidth(non-regular) = %d, want 7", w)
+	}
+}
+
 func TestFormatLine(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -116,6 +145,48 @@ func TestFormatLine(t *testing.T) {
 			width:    10,
 			expected: " 123456789 big.txt",
 		},
+		{
+			name:     "missing final newline column",
+			result:   wc.FileResult{Lines: 2, WhitespaceLines: 1, NoFinalNewline: true, Filename: "a.txt"},
+			metrics:  wc.Metrics{Lines: true, WhitespaceLines: true, NoFinalNewline: true},
+			width:    3,
+			expected: "  2   1   1 a.txt",
+		},
+		{
+			name:     "line endings",
+			result:   wc.FileResult{LFEndings: 1, CRLFEndings: 20, CREndings: 3, Filename: "mixed.txt"},
+			metrics:  wc.Metrics{LineEndings: true},
+			width:    3,
+			expected: "  1  20   3 mixed.txt",
+		},
+		{
+			name:     "char counts after metrics",
+			result:   wc.FileResult{Lines: 3, CharCounts: []uint64{4, 0}, StringCounts: []uint64{12}, Filename: "semi.txt"},
+			metrics:  wc.Metrics{Lines: true},
+			width:    3,
+			expected: "  3   4   0  12 semi.txt",
+		},
+		{
+			name:     "word lengths",
+			result:   wc.FileResult{Words: 3, LongestWord: 5, LongestWordText: "hello", WordChars: 10, CharCounts: []uint64{1}, Filename: "w.txt"},
+			metrics:  wc.Metrics{Words: true, WordLengths: true, LongestWord: true},
+			width:    5,
+			expected: "    3     5  3.33     1 hello w.txt",
+		},
+		{
+			name:     "no longest word",
+			result:   wc.FileResult{Filename: "empty.txt"},
+			metrics:  wc.Metrics{WordLengths: true, LongestWord: true},
+			width:    4,
+			expected: "   0 0.00 - empty.txt",
+		},
+		{
+			name:     "unique words",
+			result:   wc.FileResult{Words: 9, UniqueWords: 4, Filename: "u.txt"},
+			metrics:  wc.Metrics{Words: true, UniqueWords: true},
+			width:    2,
+			expected: " 9  4 u.txt",
+		},
 	}
 
 	for _, tt := range tests {
@@ -128,6 +199,50 @@ func TestFormatLine(t *testing.T) {
 	}
 }
 
+func TestFormatHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		metrics  wc.Metrics
+		width    int
+		expected string
+	}{
+		{
+			name:     "default metrics",
+			metrics:  wc.Metrics{Lines: true, Words: true, Bytes: true},
+			width:    7,
+			expected: "  lines   words   bytes file",
+		},
+		{
+			name:     "max line columns",
+			metrics:  wc.Metrics{MaxLineBytes: true, MaxLineChars: true},
+			width:    9,
+			expected: "  maxline   maxchar file",
+		},
+		{
+			name:     "word length columns",
+			metrics:  wc.Metrics{WordLengths: true, LongestWord: true, UniqueWords: true},
+			width:    7,
+			expected: "maxword avgword  unique longest file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := FormatHeader(tt.metrics, tt.width)
+			if result != tt.expected {
+				t.Errorf("FormatHeader() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatHeaderExtra(t *testing.T) {
+	got := FormatHeaderExtra(wc.Metrics{Lines: true}, []string{";", "[:digit:]"}, 7)
+	if want := "  lines       ; [:digit:] file"; got != want {
+		t.Errorf("FormatHeaderExtra() = %q, want %q", got, want)
+	}
+}
+
 func TestJoin(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -209,4 +324,78 @@ type testError struct{}
 
 func (e *testError) Error() string {
 	return "test error"
-}
\ No newline at end of file
+}
+
+func TestMarkdownTable(t *testing.T) {
+	results := []wc.FileResult{
+		{Filename: "a|b.txt", Lines: 2, Words: 9, CharCounts: []uint64{1}},
+		{Filename: "my_notes.md", Lines: 1, Words: 2, CharCounts: []uint64{0}},
+	}
+	total := wc.Sum(results)
+	total.Filename = "total"
+	got := MarkdownTable(results, &total, wc.Metrics{Lines: true, Words: true}, []string{";"})
+	want := "| file | lines | words | ; |\n" +
+		"|:---|---:|---:|---:|\n" +
+		"| a\\|b.txt | 2 | 9 | 1 |\n" +
+		"| my\\_notes.md | 1 | 2 | 0 |\n" +
+		"| **total** | **3** | **11** | **1** |\n"
+	if got != want {
+		t.Errorf("MarkdownTable() =\n%s\nwant\n%s", got, want)
+	}
+
+	got = MarkdownTable([]wc.FileResult{{Filename: "-", Words: 9}}, nil, wc.Metrics{Words: true, LongestWord: true}, nil)
+	if want := "| file | words | longest |\n|:---|---:|:---|\n| - | 9 | - |\n"; got != want {
+		t.Errorf("MarkdownTable() without total =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	files := []wc.FileResult{
+		{Filename: "<a>.go", Lines: 4, Words: 9},
+		{Filename: "b.go", Lines: 2, Words: 3},
+	}
+	total := wc.Sum(files)
+	total.Filename = "total"
+	group := total
+	group.Filename = ".go"
+	rep := HTMLReport{Title: "report", Files: files, Total: &total, GroupBy: "extension", Groups: []wc.FileResult{group}}
+	var sb strings.Builder
+	if err := WriteHTML(&sb, rep, wc.Metrics{Lines: true, Words: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	got := sb.String()
+	for _, want := range []string{
+		"<th>file</th><th>lines</th><th>words</th>",
+		"<tr><td>&lt;a&gt;.go</td><td>4</td><td>9</td></tr>",
+		"<tfoot><tr><td>total</td><td>6</td><td>12</td></tr></tfoot>",
+		"<h2>By extension</h2>",
+		`<div class="bar" style="width: 50.0%">2</div>`,
+		`<div class="bar" style="width: 100.0%">6</div>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WriteHTML() output lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "<a>") {
+		t.Error("WriteHTML() did not escape a file name")
+	}
+
+	sb.Reset()
+	if err := WriteHTML(&sb, HTMLReport{Title: "report"}, wc.Metrics{LongestWord: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sb.String(), `class="chart"`) || strings.Contains(sb.String(), "By ") {
+		t.Errorf("WriteHTML() without counts or groups:\n%s", sb.String())
+	}
+}
+
+func TestCSVRecord(t *testing.T) {
+	m := wc.Metrics{Words: true, LongestWord: true}
+	if got := CSVHeader(m, []string{"x"}); strings.Join(got, ",") != "file,words,x,longest" {
+		t.Errorf("CSVHeader() = %v", got)
+	}
+	r := wc.FileResult{Filename: "f", Words: 0, CharCounts: []uint64{3}}
+	if got := CSVRecord(r, m); strings.Join(got, ",") != "f,0,3," {
+		t.Errorf("CSVRecord() = %q", got)
+	}
+}
@@ -0,0 +1,202 @@
+package format
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// Formatter writes counts in one output format. A report is written by
+// calling Begin, WriteResult for each counted input, WriteTotals when
+// there is a total to show, and End. Failed inputs are not passed to a
+// Formatter; the caller reports them.
+type Formatter interface {
+	Begin(w io.Writer, l Layout) error
+	WriteResult(r wc.FileResult) error
+	WriteTotals(t wc.FileResult) error
+	End() error
+}
+
+// Layout describes the report a Formatter writes.
+type Layout struct {
+	Metrics wc.Metrics
+	// Extra labels the CharCounts, StringCounts and RegexpCounts columns,
+	// as for FormatHeaderExtra.
+	Extra []string
+	// Width is the count column width of aligned formats, e.g. from
+	// ComputeWidth, and Header asks them for a header row.
+	Width  int
+	Header bool
+}
+
+// FormatterFactory returns a new Formatter, ready for Begin, each time it
+// is called.
+type FormatterFactory func() Formatter
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]FormatterFactory)
+)
+
+// Register makes a format available by name to New, and so to go_wc's
+// --format=name. Programs that build the CLI with formats of their own
+// register them from an init function. Register panics if name is already
+// registered or f is nil.
+func Register(name string, f FormatterFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if f == nil {
+		panic("format: Register factory is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("format: Register called twice for format " + name)
+	}
+	registry[name] = f
+}
+
+// New returns a new Formatter for the named format.
+func New(name string) (Formatter, error) {
+	registryMu.RLock()
+	f, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q", name)
+	}
+	return f(), nil
+}
+
+// Names returns the registered format names, sorted.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register("text", func() Formatter { return &Table{} })
+	Register("csv", func() Formatter { return &CSV{} })
+	Register("markdown", func() Formatter { return &Markdown{} })
+	Register("parquet", func() Formatter { return &Parquet{} })
+}
+
+// Table is the "text" Formatter: wc's aligned columns as printed by
+// FormatLine, with " (truncated)" after counts cut short by
+// Options.StopAfterLines or StopAfterBytes.
+type Table struct {
+	// Finish, when set, rewrites each line before it is written; r is the
+	// result it was formatted from.
+	Finish func(line string, r wc.FileResult) string
+
+	w io.Writer
+	l Layout
+}
+
+func (t *Table) Begin(w io.Writer, l Layout) error {
+	t.w, t.l = w, l
+	if l.Header {
+		_, err := fmt.Fprintln(w, FormatHeaderExtra(l.Metrics, l.Extra, l.Width))
+		return err
+	}
+	return nil
+}
+
+func (t *Table) WriteResult(r wc.FileResult) error {
+	line := FormatLine(r, t.l.Metrics, t.l.Width)
+	if r.Truncated {
+		line += " (truncated)"
+	}
+	if t.Finish != nil {
+		line = t.Finish(line, r)
+	}
+	_, err := fmt.Fprintln(t.w, line)
+	return err
+}
+
+func (t *Table) WriteTotals(r wc.FileResult) error { return t.WriteResult(r) }
+
+func (t *Table) End() error { return nil }
+
+// CSV is the "csv" Formatter: a CSVHeader row, then a CSVRecord per row.
+type CSV struct {
+	cw *csv.Writer
+	m  wc.Metrics
+}
+
+func (c *CSV) Begin(w io.Writer, l Layout) error {
+	c.cw, c.m = csv.NewWriter(w), l.Metrics
+	return c.cw.Write(CSVHeader(l.Metrics, l.Extra))
+}
+
+func (c *CSV) WriteResult(r wc.FileResult) error { return c.cw.Write(CSVRecord(r, c.m)) }
+
+func (c *CSV) WriteTotals(r wc.FileResult) error { return c.WriteResult(r) }
+
+func (c *CSV) End() error {
+	c.cw.Flush()
+	return c.cw.Error()
+}
+
+// Markdown is the "markdown" Formatter, writing MarkdownTable's layout.
+type Markdown struct {
+	w io.Writer
+	m wc.Metrics
+}
+
+func (md *Markdown) Begin(w io.Writer, l Layout) error {
+	md.w, md.m = w, l.Metrics
+	head := labels(l.Metrics, l.Extra)
+	row := markdownRow(append([]string{"file"}, head...), false) + "|:---"
+	for i := range head {
+		if l.Metrics.LongestWord && i == len(head)-1 {
+			row += "|:---"
+		} else {
+			row += "|---:"
+		}
+	}
+	_, err := io.WriteString(w, row+"|\n")
+	return err
+}
+
+func (md *Markdown) WriteResult(r wc.FileResult) error {
+	_, err := io.WriteString(md.w, markdownRow(append([]string{r.Filename}, cells(r, md.m)...), false))
+	return err
+}
+
+func (md *Markdown) WriteTotals(r wc.FileResult) error {
+	_, err := io.WriteString(md.w, markdownRow(append([]string{r.Filename}, cells(r, md.m)...), true))
+	return err
+}
+
+func (md *Markdown) End() error { return nil }
+
+// Parquet is the "parquet" Formatter. A Parquet file is written whole, so
+// results are kept until End calls WriteParquet; totals are left out, the
+// file holding one row per input.
+type Parquet struct {
+	w    io.Writer
+	l    Layout
+	rows []wc.FileResult
+}
+
+func (p *Parquet) Begin(w io.Writer, l Layout) error {
+	p.w, p.l = w, l
+	return nil
+}
+
+func (p *Parquet) WriteResult(r wc.FileResult) error {
+	p.rows = append(p.rows, r)
+	return nil
+}
+
+func (p *Parquet) WriteTotals(wc.FileResult) error { return nil }
+
+func (p *Parquet) End() error { return WriteParquet(p.w, p.rows, p.l.Metrics, p.l.Extra) }
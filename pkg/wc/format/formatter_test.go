@@ -0,0 +1,95 @@
+package format
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// names is a Formatter that writes only file names, one per line.
+type names struct{ w io.Writer }
+
+func (n *names) Begin(w io.Writer, _ Layout) error { n.w = w; return nil }
+func (n *names) WriteResult(r wc.FileResult) error {
+	_, err := io.WriteString(n.w, r.Filename+"\n")
+	return err
+}
+func (n *names) WriteTotals(wc.FileResult) error { return nil }
+func (n *names) End() error                      { return nil }
+
+func TestRegister(t *testing.T) {
+	Register("test-names", func() Formatter { return &names{} })
+	f, err := New("test-names")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	_ = f.Begin(&buf, Layout{})
+	_ = f.WriteResult(wc.FileResult{Filename: "a"})
+	_ = f.End()
+	if buf.String() != "a\n" {
+		t.Errorf("got %q", buf.String())
+	}
+	if got, want := Names(), []string{"csv", "markdown", "parquet", "test-names", "text"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+	if _, err := New("nope"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("duplicate Register did not panic")
+		}
+	}()
+	Register("text", func() Formatter { return &Table{} })
+}
+
+// write runs a report through f.
+func write(t *testing.T, f Formatter, l Layout, results []wc.FileResult, total *wc.FileResult) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := f.Begin(&buf, l); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if err := f.WriteResult(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if total != nil {
+		if err := f.WriteTotals(*total); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.End(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestFormatters(t *testing.T) {
+	m := wc.Metrics{Lines: true, Words: true}
+	results := []wc.FileResult{{Filename: "a", Lines: 1, Words: 2}, {Filename: "b", Lines: 3, Words: 4, Truncated: true}}
+	total := wc.FileResult{Filename: "total", Lines: 4, Words: 6}
+	l := Layout{Metrics: m, Width: 2, Header: true}
+
+	table := &Table{Finish: func(line string, r wc.FileResult) string { return strings.ToUpper(line) }}
+	if got, want := write(t, table, l, results, &total), "lines words file\n 1  2 A\n 3  4 B (TRUNCATED)\n 4  6 TOTAL\n"; got != want {
+		t.Errorf("text = %q, want %q", got, want)
+	}
+	if got, want := write(t, &CSV{}, l, results, &total), "file,lines,words\na,1,2\nb,3,4\ntotal,4,6\n"; got != want {
+		t.Errorf("csv = %q, want %q", got, want)
+	}
+	if got, want := write(t, &Markdown{}, l, results, &total), MarkdownTable(results, &total, m, nil); got != want {
+		t.Errorf("markdown = %q, want %q", got, want)
+	}
+	var direct bytes.Buffer
+	_ = WriteParquet(&direct, results, m, nil)
+	if got := write(t, &Parquet{}, l, results, &total); got != direct.String() {
+		t.Error("parquet formatter differs from WriteParquet")
+	}
+}
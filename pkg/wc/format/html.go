@@ -0,0 +1,173 @@
+package format
+
+import (
+	"html/template"
+	"io"
+	"strconv"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// HTMLReport is the content of a page written by WriteHTML.
+type HTMLReport struct {
+	Title string
+	Files []wc.FileResult
+	// Total, when non-nil, is shown as the last row of the file table.
+	Total *wc.FileResult
+	// GroupBy heads the first column of the group table, e.g. "extension".
+	GroupBy string
+	// Groups holds the summed counts of each group, named by Filename; the
+	// group table and chart are left out when it is empty.
+	Groups []wc.FileResult
+}
+
+// WriteHTML writes rep as a standalone HTML page, with no external styles or
+// scripts: a table of the files and one of the groups, each sortable by
+// clicking a column heading, and a bar chart of the first count column of
+// each. Columns are those of FormatLine, extra labelling the CharCounts,
+// StringCounts and RegexpCounts columns.
+func WriteHTML(w io.Writer, rep HTMLReport, m wc.Metrics, extra []string) error {
+	head := labels(m, extra)
+	data := htmlPage{Title: rep.Title}
+	files := htmlSection{Title: "Files", Key: "file", Head: head}
+	for _, r := range rep.Files {
+		files.Rows = append(files.Rows, htmlRow(r, m))
+	}
+	if rep.Total != nil {
+		row := htmlRow(*rep.Total, m)
+		files.Total = &row
+	}
+	files.Chart = htmlChart(files.Rows, head, m)
+	data.Sections = append(data.Sections, files)
+	if len(rep.Groups) > 0 {
+		groups := htmlSection{Title: "By " + rep.GroupBy, Key: rep.GroupBy, Head: head}
+		for _, r := range rep.Groups {
+			groups.Rows = append(groups.Rows, htmlRow(r, m))
+		}
+		groups.Chart = htmlChart(groups.Rows, head, m)
+		data.Sections = append(data.Sections, groups)
+	}
+	return htmlTemplate.Execute(w, data)
+}
+
+type htmlPage struct {
+	Title    string
+	Sections []htmlSection
+}
+
+type htmlSection struct {
+	Title, Key string
+	Head       []string
+	Rows       []htmlTableRow
+	Total      *htmlTableRow
+	Chart      *htmlBars
+}
+
+type htmlTableRow struct {
+	Name  string
+	Cells []string
+}
+
+type htmlBars struct {
+	Label string
+	Bars  []htmlBar
+}
+
+type htmlBar struct {
+	Name    string
+	Value   string
+	Percent float64
+}
+
+func htmlRow(r wc.FileResult, m wc.Metrics) htmlTableRow {
+	return htmlTableRow{Name: r.Filename, Cells: cells(r, m)}
+}
+
+// htmlChart charts the first column of rows, or returns nil when there is no
+// count column (only the longest word).
+func htmlChart(rows []htmlTableRow, head []string, m wc.Metrics) *htmlBars {
+	if len(head) == 0 || (m.LongestWord && len(head) == 1) || len(rows) == 0 {
+		return nil
+	}
+	max := 0.0
+	for _, r := range rows {
+		if v, _ := strconv.ParseFloat(r.Cells[0], 64); v > max {
+			max = v
+		}
+	}
+	chart := &htmlBars{Label: head[0]}
+	for _, r := range rows {
+		v, _ := strconv.ParseFloat(r.Cells[0], 64)
+		pct := 0.0
+		if max > 0 {
+			pct = v / max * 100
+		}
+		chart.Bars = append(chart.Bars, htmlBar{Name: r.Name, Value: r.Cells[0], Percent: pct})
+	}
+	return chart
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { padding: 0.25em 0.75em; border-bottom: 1px solid #ddd; }
+th { cursor: pointer; user-select: none; background: #f4f4f4; }
+th:first-child, td:first-child { text-align: left; }
+td { text-align: right; font-variant-numeric: tabular-nums; }
+tfoot td { font-weight: bold; }
+.chart { display: grid; grid-template-columns: max-content 1fr; gap: 0.2em 0.75em; max-width: 60em; }
+.chart .name { text-align: right; white-space: nowrap; }
+.chart .bar { background: #4c78a8; color: #fff; padding: 0 0.3em; min-width: 1px; white-space: nowrap; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Sections}}
+<h2>{{.Title}}</h2>
+<table class="sortable">
+<thead><tr><th>{{.Key}}</th>{{range .Head}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr><td>{{.Name}}</td>{{range .Cells}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+{{- with .Total}}
+<tfoot><tr><td>{{.Name}}</td>{{range .Cells}}<td>{{.}}</td>{{end}}</tr></tfoot>
+{{- end}}
+</table>
+{{- with .Chart}}
+<h3>{{.Label}}</h3>
+<div class="chart">
+{{- range .Bars}}
+<div class="name">{{.Name}}</div><div><div class="bar" style="width: {{printf "%.1f" .Percent}}%">{{.Value}}</div></div>
+{{- end}}
+</div>
+{{- end}}
+{{end}}
+<script>
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  table.querySelectorAll("thead th").forEach(function (th, col) {
+    var asc = false;
+    th.addEventListener("click", function () {
+      asc = !asc;
+      var body = table.tBodies[0];
+      var rows = Array.prototype.slice.call(body.rows);
+      rows.sort(function (a, b) {
+        var x = a.cells[col].textContent, y = b.cells[col].textContent;
+        var nx = parseFloat(x), ny = parseFloat(y);
+        var c = isNaN(nx) || isNaN(ny) ? x.localeCompare(y) : nx - ny;
+        return asc ? c : -c;
+      });
+      rows.forEach(function (r) { body.appendChild(r); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,48 @@
+package format
+
+import (
+	"strings"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// MarkdownTable formats results as a GitHub-flavored Markdown table with a
+// file column followed by FormatLine's columns, extra labelling the
+// CharCounts, StringCounts and RegexpCounts columns. A non-nil total is appended as a
+// bold row named by its Filename. Counts are right-aligned.
+func MarkdownTable(results []wc.FileResult, total *wc.FileResult, m wc.Metrics, extra []string) string {
+	var sb strings.Builder
+	md := &Markdown{}
+	_ = md.Begin(&sb, Layout{Metrics: m, Extra: extra})
+	for _, r := range results {
+		_ = md.WriteResult(r)
+	}
+	if total != nil {
+		_ = md.WriteTotals(*total)
+	}
+	return sb.String()
+}
+
+// markdownRow formats one table row, ending in a newline.
+func markdownRow(cells []string, bold bool) string {
+	var sb strings.Builder
+	for _, c := range cells {
+		c = escapeMarkdown(c)
+		if bold {
+			c = "**" + c + "**"
+		}
+		sb.WriteString("| " + c + " ")
+	}
+	sb.WriteString("|\n")
+	return sb.String()
+}
+
+// markdownEscaper keeps a cell from breaking the table or being formatted.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`", "<", "&lt;",
+	"\n", " ", "\r", " ",
+)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
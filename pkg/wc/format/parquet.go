@@ -0,0 +1,330 @@
+package format
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// Parquet physical types, encodings and other enum values used by
+// WriteParquet, from the format's parquet.thrift.
+const (
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetRequired     = 0
+	parquetRepeated     = 2
+	parquetUTF8         = 0
+	parquetList         = 3
+	parquetPlain        = 0
+	parquetDataPage     = 0
+	parquetRLE          = 3
+	parquetUncompressed = 0
+)
+
+// parquetColumn is one column of a WriteParquet file, PLAIN-encoded.
+// A list column of typ elements has a repetition and a definition level
+// for each element, and one for each empty list, which has none.
+type parquetColumn struct {
+	name     string
+	typ      int32
+	data     []byte
+	list     bool
+	rep, def []byte
+}
+
+// WriteParquet writes results as an uncompressed Parquet file with one row
+// per result and a row group holding all of them. The columns are "file",
+// a UTF-8 string, then those of FormatLine named as in FormatHeaderExtra,
+// where extra names the CharCounts, StringCounts and RegexpCounts columns:
+// 64-bit integers, except avgword, avgwpl and tpl (doubles) and longest (a
+// string). With Metrics.LineLengths, a last column, "line_lengths", lists
+// the 64-bit integers of FileResult.LineLengths.
+func WriteParquet(w io.Writer, results []wc.FileResult, m wc.Metrics, extra []string) error {
+	head := labels(m, extra)
+	cols := []*parquetColumn{{name: "file", typ: parquetByteArray}}
+	for i, l := range head {
+		col := &parquetColumn{name: l, typ: parquetInt64}
+		switch {
+		case m.WordLengths && l == "avgword" && cols[len(cols)-1].name == "maxword":
+			col.typ = parquetDouble
+		case m.WordsPerLine && l == "avgwpl" && cols[len(cols)-1].name == "minwpl":
+			col.typ = parquetDouble
+		case m.CodeTokens && l == "tpl" && cols[len(cols)-1].name == "operators":
+			col.typ = parquetDouble
+		case m.LongestWord && i == len(head)-1:
+			col.typ = parquetByteArray
+		}
+		cols = append(cols, col)
+	}
+	flat := len(cols)
+	if m.LineLengths {
+		cols = append(cols, &parquetColumn{name: "line_lengths", typ: parquetInt64, list: true})
+	}
+	for _, r := range results {
+		for i, c := range append([]string{r.Filename}, cells(r, m)...) {
+			if m.LongestWord && i == flat-1 && c == "-" {
+				c = r.LongestWordText
+			}
+			cols[i].append(c)
+		}
+		if m.LineLengths {
+			cols[flat].appendList(r.LineLengths)
+		}
+	}
+
+	out := []byte("PAR1")
+	var chunks thrift
+	var total int64
+	chunks.listBegin(thriftStruct, len(cols))
+	for _, col := range cols {
+		values, data := len(results), col.data
+		if col.list {
+			values = len(col.rep)
+			data = append(append(parquetLevels(col.rep), parquetLevels(col.def)...), data...)
+		}
+		var page thrift
+		page.i32(1, parquetDataPage)
+		page.i32(2, int32(len(data)))
+		page.i32(3, int32(len(data)))
+		page.structBegin(5)
+		page.i32(1, int32(values))
+		page.i32(2, parquetPlain)
+		page.i32(3, parquetRLE)
+		page.i32(4, parquetRLE)
+		page.stop()
+		page.stop()
+
+		offset := int64(len(out))
+		size := int64(len(page.b) + len(data))
+		out = append(append(out, page.b...), data...)
+		total += size
+
+		path := []string{col.name}
+		if col.list {
+			path = append(path, "list", "element")
+		}
+		chunks.elemBegin()
+		chunks.i64(2, offset)
+		chunks.structBegin(3)
+		chunks.i32(1, col.typ)
+		chunks.listField(2, thriftI32, 1)
+		chunks.varint(parquetPlain)
+		chunks.listField(3, thriftBinary, len(path))
+		for _, p := range path {
+			chunks.bytes([]byte(p))
+		}
+		chunks.i32(4, parquetUncompressed)
+		chunks.i64(5, int64(values))
+		chunks.i64(6, size)
+		chunks.i64(7, size)
+		chunks.i64(9, offset)
+		chunks.stop()
+		chunks.stop()
+	}
+
+	var meta thrift
+	elems := len(cols) + 1
+	if m.LineLengths {
+		elems += 2 // the list and its element
+	}
+	meta.i32(1, 1)
+	meta.listField(2, thriftStruct, elems)
+	meta.elemBegin()
+	meta.binary(4, []byte("schema"))
+	meta.i32(5, int32(len(cols)))
+	meta.stop()
+	for _, col := range cols {
+		if col.list {
+			// the three-level LIST layout: a required group holding a
+			// repeated group "list" of required "element" values
+			meta.elemBegin()
+			meta.i32(3, parquetRequired)
+			meta.binary(4, []byte(col.name))
+			meta.i32(5, 1)
+			meta.i32(6, parquetList)
+			meta.stop()
+			meta.elemBegin()
+			meta.i32(3, parquetRepeated)
+			meta.binary(4, []byte("list"))
+			meta.i32(5, 1)
+			meta.stop()
+			meta.elemBegin()
+			meta.i32(1, col.typ)
+			meta.i32(3, parquetRequired)
+			meta.binary(4, []byte("element"))
+			meta.stop()
+			continue
+		}
+		meta.elemBegin()
+		meta.i32(1, col.typ)
+		meta.i32(3, parquetRequired)
+		meta.binary(4, []byte(col.name))
+		if col.typ == parquetByteArray {
+			meta.i32(6, parquetUTF8)
+		}
+		meta.stop()
+	}
+	meta.i64(3, int64(len(results)))
+	if len(results) > 0 {
+		meta.listField(4, thriftStruct, 1)
+		meta.elemBegin()
+		meta.field(1, thriftList)
+		meta.b = append(meta.b, chunks.b...)
+		meta.i64(2, total)
+		meta.i64(3, int64(len(results)))
+		meta.stop()
+	} else {
+		meta.listField(4, thriftStruct, 0)
+	}
+	meta.binary(6, []byte("go_wc"))
+	meta.stop()
+
+	out = append(out, meta.b...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(meta.b)))
+	out = append(out, "PAR1"...)
+	_, err := w.Write(out)
+	return err
+}
+
+// append adds a cell, as printed by FormatLine, to the column.
+func (c *parquetColumn) append(s string) {
+	switch c.typ {
+	case parquetInt64:
+		v, _ := strconv.ParseUint(s, 10, 64)
+		c.data = binary.LittleEndian.AppendUint64(c.data, v)
+	case parquetDouble:
+		v, _ := strconv.ParseFloat(s, 64)
+		c.data = binary.LittleEndian.AppendUint64(c.data, math.Float64bits(v))
+	default:
+		c.data = binary.LittleEndian.AppendUint32(c.data, uint32(len(s)))
+		c.data = append(c.data, s...)
+	}
+}
+
+// appendList adds a list of integers to a list column.
+func (c *parquetColumn) appendList(v []uint64) {
+	if len(v) == 0 {
+		c.rep = append(c.rep, 0)
+		c.def = append(c.def, 0)
+		return
+	}
+	for i, n := range v {
+		var rep byte = 1 // the list goes on
+		if i == 0 {
+			rep = 0
+		}
+		c.rep = append(c.rep, rep)
+		c.def = append(c.def, 1)
+		c.data = binary.LittleEndian.AppendUint64(c.data, n)
+	}
+}
+
+// parquetLevels encodes levels of 0 and 1 as a data page of version 1
+// holds them: their length in bytes, then runs of the RLE/bit-packing
+// hybrid encoding, each a varint of its length shifted left by one and
+// the repeated value in a byte.
+func parquetLevels(levels []byte) []byte {
+	var runs []byte
+	for i := 0; i < len(levels); {
+		j := i + 1
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		runs = binary.AppendUvarint(runs, uint64(j-i)<<1)
+		runs = append(runs, levels[i])
+		i = j
+	}
+	return append(binary.LittleEndian.AppendUint32(nil, uint32(len(runs))), runs...)
+}
+
+// Thrift compact protocol types.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thrift encodes structs in the Thrift compact protocol, which Parquet uses
+// for its page headers and footer. Fields must be written in increasing id
+// order within each struct.
+type thrift struct {
+	b     []byte
+	last  int16
+	outer []int16
+}
+
+func (t *thrift) varint(v int64) {
+	t.b = binary.AppendUvarint(t.b, uint64(v<<1^v>>63))
+}
+
+func (t *thrift) field(id int16, typ byte) {
+	if d := id - t.last; d > 0 && d <= 15 {
+		t.b = append(t.b, byte(d)<<4|typ)
+	} else {
+		t.b = append(t.b, typ)
+		t.varint(int64(id))
+	}
+	t.last = id
+}
+
+func (t *thrift) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thrift) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thrift) bytes(v []byte) {
+	t.b = binary.AppendUvarint(t.b, uint64(len(v)))
+	t.b = append(t.b, v...)
+}
+
+func (t *thrift) binary(id int16, v []byte) {
+	t.field(id, thriftBinary)
+	t.bytes(v)
+}
+
+func (t *thrift) listBegin(elem byte, n int) {
+	if n < 15 {
+		t.b = append(t.b, byte(n)<<4|elem)
+	} else {
+		t.b = append(t.b, 0xf0|elem)
+		t.b = binary.AppendUvarint(t.b, uint64(n))
+	}
+}
+
+func (t *thrift) listField(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	t.listBegin(elem, n)
+}
+
+// structBegin starts a struct field; stop closes it.
+func (t *thrift) structBegin(id int16) {
+	t.field(id, thriftStruct)
+	t.elemBegin()
+}
+
+// elemBegin starts a struct that is a list element; stop closes it.
+func (t *thrift) elemBegin() {
+	t.outer = append(t.outer, t.last)
+	t.last = 0
+}
+
+// stop ends the current struct.
+func (t *thrift) stop() {
+	t.b = append(t.b, 0)
+	if n := len(t.outer); n > 0 {
+		t.last = t.outer[n-1]
+		t.outer = t.outer[:n-1]
+	}
+}
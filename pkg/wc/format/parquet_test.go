@@ -0,0 +1,255 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc"
+)
+
+// compactReader decodes the Thrift compact protocol into maps of field id
+// to int64, []byte, []any or nested maps, enough to check WriteParquet.
+type compactReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *compactReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *compactReader) zigzag() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *compactReader) value(typ byte) any {
+	switch typ {
+	case thriftTrue, thriftFalse:
+		// a list element; in a field header the type is the value
+		r.pos++
+		return r.b[r.pos-1] == 1
+	case thriftByte:
+		r.pos++
+		return int64(int8(r.b[r.pos-1]))
+	case thriftI16, thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftDouble:
+		r.pos += 8
+		return math.Float64frombits(binary.LittleEndian.Uint64(r.b[r.pos-8:]))
+	case thriftBinary:
+		n := int(r.uvarint())
+		r.pos += n
+		return r.b[r.pos-n : r.pos]
+	case thriftList, thriftSet:
+		h := r.b[r.pos]
+		r.pos++
+		n, elem := int(h>>4), h&0x0f
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = r.value(elem)
+		}
+		return list
+	case thriftMap:
+		n := int(r.uvarint())
+		if n == 0 {
+			return map[any]any{}
+		}
+		kv := r.b[r.pos]
+		r.pos++
+		m := make(map[any]any, n)
+		for i := 0; i < n; i++ {
+			k := r.value(kv >> 4)
+			if b, ok := k.([]byte); ok {
+				k = string(b)
+			}
+			m[k] = r.value(kv & 0x0f)
+		}
+		return m
+	case thriftStruct:
+		return r.object()
+	}
+	panic("unexpected thrift type")
+}
+
+func (r *compactReader) object() map[int16]any {
+	fields := make(map[int16]any)
+	var last int16
+	for {
+		h := r.b[r.pos]
+		r.pos++
+		if h == 0 {
+			return fields
+		}
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		switch typ := h & 0x0f; typ {
+		case thriftTrue, thriftFalse:
+			fields[id] = typ == thriftTrue
+		default:
+			fields[id] = r.value(typ)
+		}
+		last = id
+	}
+}
+
+// Thrift compact protocol types WriteParquet does not write, but other
+// writers do.
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftByte   = 3
+	thriftI16    = 4
+	thriftDouble = 7
+	thriftSet    = 10
+	thriftMap    = 11
+)
+
+// parquetFile is a Parquet file of one row group, as far as the tests
+// check it: its footer, and the data of the one page of each column.
+type parquetFile struct {
+	rows   int64
+	names  []string // of the schema elements, the root first
+	types  []int64  // of the leaf columns, in order
+	values []int64  // of the pages of the columns
+	pages  [][]byte
+}
+
+func readParquet(t *testing.T, b []byte) parquetFile {
+	t.Helper()
+	if !bytes.HasPrefix(b, []byte("PAR1")) || !bytes.HasSuffix(b, []byte("PAR1")) {
+		t.Fatal("missing PAR1 magic")
+	}
+	n := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	footer := &compactReader{b: b[:len(b)-8], pos: len(b) - 8 - n}
+	meta := footer.object()
+	if footer.pos != len(b)-8 {
+		t.Fatalf("footer decoded to %d, want %d", footer.pos, len(b)-8)
+	}
+	f := parquetFile{rows: meta[3].(int64)}
+	for i, el := range meta[2].([]any) {
+		e := el.(map[int16]any)
+		f.names = append(f.names, string(e[4].([]byte)))
+		if i == 0 {
+			continue
+		}
+		if typ, ok := e[1].(int64); ok {
+			f.types = append(f.types, typ)
+		}
+		want := int64(parquetRequired)
+		if f.names[i] == "list" {
+			want = parquetRepeated
+		}
+		if e[3] != want {
+			t.Errorf("column %s repetition = %v, want %d", f.names[i], e[3], want)
+		}
+	}
+	for _, c := range meta[4].([]any)[0].(map[int16]any)[1].([]any) {
+		cm := c.(map[int16]any)[3].(map[int16]any)
+		r := &compactReader{b: b, pos: int(cm[9].(int64))}
+		h := r.object()
+		if h[1] != int64(parquetDataPage) || h[5].(map[int16]any)[2] != int64(parquetPlain) {
+			t.Fatalf("column %d: not a PLAIN data page: %v", len(f.pages), h)
+		}
+		f.values = append(f.values, h[5].(map[int16]any)[1].(int64))
+		f.pages = append(f.pages, b[r.pos:r.pos+int(h[3].(int64))])
+	}
+	return f
+}
+
+func TestWriteParquet(t *testing.T) {
+	results := []wc.FileResult{
+		{Filename: "a.txt", Lines: 3, Words: 4, WordChars: 10, LongestWord: 4, LongestWordText: "long"},
+		{Filename: "empty", Lines: 0},
+	}
+	m := wc.Metrics{Lines: true, Words: true, WordLengths: true, LongestWord: true}
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, results, m, nil); err != nil {
+		t.Fatal(err)
+	}
+	f := readParquet(t, buf.Bytes())
+	if f.rows != 2 {
+		t.Errorf("num_rows = %v, want 2", f.rows)
+	}
+
+	wantNames := []string{"schema", "file", "lines", "words", "maxword", "avgword", "longest"}
+	wantTypes := []int64{parquetByteArray, parquetInt64, parquetInt64, parquetInt64, parquetDouble, parquetByteArray}
+	if !slices.Equal(f.names, wantNames) || !slices.Equal(f.types, wantTypes) {
+		t.Fatalf("schema = %q %v, want %q %v", f.names, f.types, wantNames, wantTypes)
+	}
+	if got, want := f.pages[0], []byte("\x05\x00\x00\x00a.txt\x05\x00\x00\x00empty"); !bytes.Equal(got, want) {
+		t.Errorf("file column = %q, want %q", got, want)
+	}
+	if got := f.pages[1]; binary.LittleEndian.Uint64(got) != 3 || binary.LittleEndian.Uint64(got[8:]) != 0 {
+		t.Errorf("lines column = %v", got)
+	}
+	if got := math.Float64frombits(binary.LittleEndian.Uint64(f.pages[4])); got != 2.5 {
+		t.Errorf("avgword = %v, want 2.5", got)
+	}
+	if got, want := f.pages[5], []byte("\x04\x00\x00\x00long\x00\x00\x00\x00"); !bytes.Equal(got, want) {
+		t.Errorf("longest column = %q, want %q", got, want)
+	}
+}
+
+func TestWriteParquetLineLengths(t *testing.T) {
+	results := []wc.FileResult{
+		{Filename: "a", Lines: 4, LineLengths: []uint64{1, 0, 3}},
+		{Filename: "b"},
+		{Filename: "c", Lines: 1, LineLengths: []uint64{0, 1}},
+	}
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, results, wc.Metrics{Lines: true, LineLengths: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	f := readParquet(t, buf.Bytes())
+	wantNames := []string{"schema", "file", "lines", "line_lengths", "list", "element"}
+	if !slices.Equal(f.names, wantNames) || !slices.Equal(f.types, []int64{parquetByteArray, parquetInt64, parquetInt64}) {
+		t.Fatalf("schema = %q %v", f.names, f.types)
+	}
+	if f.values[2] != 6 {
+		t.Errorf("line_lengths values = %d, want 6: 5 elements and an empty list", f.values[2])
+	}
+	// repetition levels 0 1 1 0 0 1, definition levels 1 1 1 0 1 1, then
+	// the elements
+	want := []byte("\x08\x00\x00\x00\x02\x00\x04\x01\x04\x00\x02\x01" +
+		"\x06\x00\x00\x00\x06\x01\x02\x00\x04\x01")
+	for _, v := range []uint64{1, 0, 3, 0, 1} {
+		want = binary.LittleEndian.AppendUint64(want, v)
+	}
+	if !bytes.Equal(f.pages[2], want) {
+		t.Errorf("line_lengths page = %q, want %q", f.pages[2], want)
+	}
+}
+
+// TestWriteParquetFixture compares WriteParquet's output with
+// testdata/go_wc.parquet, which testdata/pyarrow.py reads back with
+// pyarrow to check that other readers see these rows in it.
+func TestWriteParquetFixture(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("testdata", "go_wc.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := []wc.FileResult{
+		{Filename: "a.txt", Lines: 3, Words: 4, WordChars: 10, LongestWord: 4, LongestWordText: "long", LineLengths: []uint64{1, 0, 2}},
+		{Filename: "empty", Lines: 0},
+	}
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, results, wc.Metrics{Lines: true, Words: true, WordLengths: true, LongestWord: true, LineLengths: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), fixture) {
+		t.Errorf("output differs from testdata/go_wc.parquet; if the change is meant, rewrite it and run testdata/pyarrow.py")
+	}
+}
@@ -0,0 +1,32 @@
+# Reads go_wc.parquet, the WriteParquet output TestWriteParquetFixture
+# keeps, with pyarrow, and checks that it holds the rows of that test:
+# the schema, the line_lengths list column and every value.
+#
+#   python3 pyarrow.py   # in this directory, with pyarrow installed
+
+import pyarrow as pa
+import pyarrow.parquet as pq
+
+schema = pa.schema([
+    pa.field("file", pa.string(), nullable=False),
+    pa.field("lines", pa.int64(), nullable=False),
+    pa.field("words", pa.int64(), nullable=False),
+    pa.field("maxword", pa.int64(), nullable=False),
+    pa.field("avgword", pa.float64(), nullable=False),
+    pa.field("longest", pa.string(), nullable=False),
+    pa.field("line_lengths", pa.list_(pa.field("element", pa.int64(), nullable=False)), nullable=False),
+])
+want = pa.table({
+    "file": ["a.txt", "empty"],
+    "lines": [3, 0],
+    "words": [4, 0],
+    "maxword": [4, 0],
+    "avgword": [2.5, 0.0],
+    "longest": ["long", ""],
+    "line_lengths": [[1, 0, 2], []],
+}, schema=schema)
+
+got = pq.read_table("go_wc.parquet")
+assert got.schema.equals(schema), f"schema:\n{got.schema}\nwant:\n{schema}"
+assert got.equals(want), f"rows:\n{got.to_pylist()}\nwant:\n{want.to_pylist()}"
+print("go_wc.parquet: ok")
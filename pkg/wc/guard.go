@@ -0,0 +1,57 @@
+package wc
+
+import "fmt"
+
+// GuardError is FileResult.Err for an input that Options.AbortLineBytes or
+// AbortWordBytes stopped. The counts beside it cover the input up to the
+// byte that crossed the bound.
+type GuardError struct {
+	Guard string // "line" or "word"
+	Limit uint64 // the bound, in bytes
+	Line  uint64 // 1-based line of the counted input that crossed it
+}
+
+func (e *GuardError) Error() string {
+	return fmt.Sprintf("line %d: %s longer than %d bytes, counting aborted", e.Line, e.Guard, e.Limit)
+}
+
+// guardState tracks the lengths the guards bound, independently of the
+// metrics counted.
+type guardState struct {
+	line  uint64 // bytes since the last '\n'
+	word  uint64 // bytes since the last ASCII white space
+	lines uint64 // '\n' seen
+	err   *GuardError
+}
+
+// guard returns the part of p before the byte that makes a line or word
+// longer than its bound, setting c.guards.err if there is one.
+func (c *Counter) guard(p []byte) []byte {
+	g := &c.guards
+	lineMax, wordMax := c.opt.AbortLineBytes, c.opt.AbortWordBytes
+	for i, b := range p {
+		if b == '\n' {
+			g.lines++
+			g.line = 0
+		} else if g.line++; lineMax > 0 && g.line > lineMax {
+			g.err = &GuardError{Guard: "line", Limit: lineMax, Line: g.lines + 1}
+			return p[:i]
+		}
+		if asciiSpace[b] {
+			g.word = 0
+		} else if g.word++; wordMax > 0 && g.word > wordMax {
+			g.err = &GuardError{Guard: "word", Limit: wordMax, Line: g.lines + 1}
+			return p[:i]
+		}
+	}
+	return p
+}
+
+// Err returns the *GuardError once a guard of Options has stopped the
+// Counter, which then ignores further writes, or nil.
+func (c *Counter) Err() error {
+	if c.guards.err == nil {
+		return nil
+	}
+	return c.guards.err
+}
@@ -0,0 +1,212 @@
+package wc
+
+import (
+	"bufio"
+	"bytes"
+	"html"
+	"io"
+)
+
+// htmlInline are the elements that do not break the text around them, so
+// that "<b>w</b>ord" is one word; any other tag, of HTML or XML, stands
+// for a space between the words on either side.
+var htmlInline = map[string]bool{
+	"a": true, "abbr": true, "b": true, "bdi": true, "bdo": true, "cite": true,
+	"code": true, "data": true, "del": true, "dfn": true, "em": true,
+	"font": true, "i": true, "img": true, "ins": true, "kbd": true,
+	"mark": true, "q": true, "s": true, "samp": true, "small": true,
+	"span": true, "strong": true, "sub": true, "sup": true, "time": true,
+	"u": true, "var": true, "wbr": true,
+}
+
+// htmlRawText are the elements whose content is not text to count.
+var htmlRawText = map[string]bool{"script": true, "style": true}
+
+// htmlReader is the reader NewHTMLReader returns.
+type htmlReader struct {
+	r     *bufio.Reader
+	out   []byte
+	space bool // out last ended in white space, or nothing was written
+	err   error
+}
+
+// NewHTMLReader returns a reader of the text content of the HTML or XML
+// document r, tokenized as it streams: tags with their attributes,
+// comments, declarations, processing instructions and the content of
+// script and style elements are left out, character references are
+// decoded to UTF-8, and the text of CDATA sections is kept. A tag other
+// than one of text-level HTML (<b>, <a>, <span>, ...) separates the words
+// around it, so it is read as a space when the text does not already
+// have one there. Malformed markup is read leniently: a "<" that does not
+// begin a tag is text, and a tag left open ends the document.
+func NewHTMLReader(r io.Reader) io.Reader {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &htmlReader{r: br, space: true}
+}
+
+func (h *htmlReader) Read(p []byte) (int, error) {
+	for len(h.out) == 0 {
+		if h.err != nil {
+			return 0, h.err
+		}
+		h.out = h.out[:0]
+		for len(h.out) < 4096 && h.err == nil {
+			h.step()
+		}
+	}
+	n := copy(p, h.out)
+	h.out = h.out[n:]
+	return n, nil
+}
+
+func (h *htmlReader) emit(b ...byte) {
+	h.out = append(h.out, b...)
+	if len(b) > 0 {
+		h.space = asciiSpace[b[len(b)-1]]
+	}
+}
+
+// step reads the next byte of text, tag or reference.
+func (h *htmlReader) step() {
+	c, err := h.r.ReadByte()
+	if err != nil {
+		h.err = err
+		return
+	}
+	switch c {
+	case '<':
+		h.markup()
+	case '&':
+		h.reference()
+	default:
+		h.emit(c)
+	}
+}
+
+// markup reads what follows a "<".
+func (h *htmlReader) markup() {
+	p, _ := h.r.Peek(1)
+	switch {
+	case len(p) == 0:
+		h.emit('<')
+	case h.skipPrefix("!--", false):
+		h.skipPast("-->", false)
+		h.brk()
+	case h.skipPrefix("![CDATA[", false):
+		h.copyUntil("]]>")
+	case p[0] == '!' || p[0] == '?':
+		h.skipPast(">", false)
+		h.brk()
+	case p[0] == '/' || isASCIILetter(p[0]):
+		opening := p[0] != '/'
+		name, selfClosing := h.tag()
+		if opening && !selfClosing && htmlRawText[name] {
+			h.skipPast("</"+name, true)
+			h.tag()
+		}
+		if !htmlInline[name] {
+			h.brk()
+		}
+	default:
+		h.emit('<')
+	}
+}
+
+// brk separates the text before a tag from the text after it.
+func (h *htmlReader) brk() {
+	if !h.space {
+		h.emit(' ')
+	}
+}
+
+// tag reads the rest of a tag after its "<": its name, lower-cased, then
+// its attributes up to the ">", minding quoted values. It reports whether
+// the tag closes itself with "/>".
+func (h *htmlReader) tag() (name string, selfClosing bool) {
+	if p, _ := h.r.Peek(1); len(p) == 1 && p[0] == '/' {
+		h.r.ReadByte()
+	}
+	var b []byte
+	for {
+		p, _ := h.r.Peek(1)
+		if len(p) == 0 || !(isASCIILetter(p[0]) || p[0] >= '0' && p[0] <= '9' || p[0] == '-' || p[0] == ':' || p[0] == '_' || p[0] == '.') {
+			break
+		}
+		h.r.ReadByte()
+		if len(b) < 32 {
+			b = append(b, p[0]|0x20)
+		}
+	}
+	var quote, last byte
+	for {
+		c, err := h.r.ReadByte()
+		if err != nil {
+			h.err = err
+			return string(b), false
+		}
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return string(b), last == '/'
+		}
+		if !asciiSpace[c] {
+			last = c
+		}
+	}
+}
+
+// reference reads what follows a "&": a character reference, emitted
+// decoded, or else text.
+func (h *htmlReader) reference() {
+	p, _ := h.r.Peek(32)
+	if i := bytes.IndexByte(p, ';'); i > 0 {
+		ref := "&" + string(p[:i+1])
+		if text := html.UnescapeString(ref); text != ref {
+			h.r.Discard(i + 1)
+			h.emit([]byte(text)...)
+			return
+		}
+	}
+	h.emit('&')
+}
+
+// skipPrefix discards s, compared ignoring ASCII case when fold is set,
+// if the input goes on with it, and reports whether it did.
+func (h *htmlReader) skipPrefix(s string, fold bool) bool {
+	p, _ := h.r.Peek(len(s))
+	if string(p) == s || fold && bytes.EqualFold(p, []byte(s)) {
+		h.r.Discard(len(s))
+		return true
+	}
+	return false
+}
+
+// skipPast discards the input up to and including s, or all of it.
+func (h *htmlReader) skipPast(s string, fold bool) {
+	for !h.skipPrefix(s, fold) {
+		if _, err := h.r.ReadByte(); err != nil {
+			h.err = err
+			return
+		}
+	}
+}
+
+// copyUntil emits the input up to s, which it discards, or all of it.
+func (h *htmlReader) copyUntil(s string) {
+	for !h.skipPrefix(s, false) {
+		c, err := h.r.ReadByte()
+		if err != nil {
+			h.err = err
+			return
+		}
+		h.emit(c)
+	}
+}
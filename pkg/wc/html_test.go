@@ -0,0 +1,49 @@
+package wc
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestHTMLReader(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"text", "plain text\n", "plain text\n"},
+		{"inline tags", "<p>Hello, <b>wo</b>rld</p>", "Hello, world "},
+		{"block tags", "<li>one</li><li>two</li>", "one two "},
+		{"no doubled spaces", "<p>one</p>\n<p>two</p>\n", "one \ntwo \n"},
+		{"attributes", `<a href="/x?a>b" title='it"s'>link</a>`, "link"},
+		{"self closing", "one<br/>two<img src=x.png>three", "one twothree"},
+		{"script and style", "a<script type=\"text/javascript\">if (x < 1) s = \"</p>\";</script>b<STYLE>p { }</STYLE>c", "a b c"},
+		{"stray closing script", "a</script>b", "a b"},
+		{"comments", "a<!-- <p>hidden</p> -->b<!---->c", "a b c"},
+		{"declarations", "<?xml version=\"1.0\"?>\n<!DOCTYPE html>\n<doc>x</doc>", "\n\nx "},
+		{"cdata", "<note><![CDATA[a <b> & c]]></note>", "a <b> & c "},
+		{"references", "fish &amp; chips &lt;3 &#65;&#x42; &copy; &bogus; & more", "fish & chips <3 AB © &bogus; & more"},
+		{"lone angle bracket", "a < b and c<", "a < b and c<"},
+		{"unclosed tag", "text<p class=", "text "},
+		{"xml", "<book><title>Go</title><author>Anon</author></book>", "Go Anon "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := io.ReadAll(iotest.OneByteReader(NewHTMLReader(strings.NewReader(tt.in))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountStripHTML(t *testing.T) {
+	in := "<html><head><title>Doc</title><script>var words = 1;</script></head>\n<body><h1>A title</h1>\n<p>Some <em>emphasized</em> text.</p></body></html>\n"
+	got := CountBytes([]byte(in), Metrics{Lines: true, Words: true}, Options{Strip: StripHTML})
+	if got.Lines != 3 || got.Words != 6 {
+		t.Errorf("got %d lines, %d words; want 3, 6", got.Lines, got.Words)
+	}
+}
@@ -0,0 +1,96 @@
+package wc
+
+import "fmt"
+
+// Hyphen names how Options.Hyphen treats hyphens in words.
+type Hyphen int
+
+const (
+	// HyphenNone counts a hyphen like any other character of a word, as
+	// wc does: "state-of-the-art" is one word, and a word hyphenated at a
+	// line break is two. It is the default.
+	HyphenNone Hyphen = iota
+	// HyphenJoin also rejoins a word hyphenated at a line break: a word
+	// ending in a hyphen at the end of a line continues with the first
+	// word of the next one, the line break and any indentation left out
+	// of its length and text. A blank line in between does not join.
+	HyphenJoin
+	// HyphenSplit makes a hyphen after the text of a word end it, so that
+	// "state-of-the-art" is four words and a word hyphenated at a line
+	// break two. The hyphen belongs to no word. Hyphens that start a
+	// word, as in "-5" or "--flag", or stand alone are kept.
+	HyphenSplit
+)
+
+// ParseHyphen returns the Hyphen called name, "join" or "split".
+func ParseHyphen(name string) (Hyphen, error) {
+	switch name {
+	case "join":
+		return HyphenJoin, nil
+	case "split":
+		return HyphenSplit, nil
+	}
+	return HyphenNone, fmt.Errorf("unknown hyphenation %q (want join or split)", name)
+}
+
+// hyphenState is what a wordScan tracks of the current word for
+// Options.Hyphen.
+type hyphenState struct {
+	text   bool  // the word has a character other than a hyphen
+	last   bool  // the word ends in a hyphen
+	gap    bool  // HyphenSplit: the last character was a hyphen ending a word
+	joinAt uint8 // HyphenJoin: 1 past a hyphen ending a line, 2 past its line break too
+}
+
+// isHyphen reports whether text is a hyphen-minus or U+2010 HYPHEN.
+func isHyphen(text []byte) bool {
+	return string(text) == "-" || string(text) == "‐"
+}
+
+// hyphenate applies Options.Hyphen to the next character, space or not, with
+// the given text. It returns whether word counting is to treat it as a
+// space, and whether it is to skip it altogether.
+func (s *wordScan) hyphenate(space bool, text []byte, sink wordSink) (bool, bool) {
+	h := &s.hyph
+	if s.hyphen == HyphenJoin && h.joinAt > 0 {
+		switch {
+		case string(text) == "\n" && h.joinAt == 1:
+			h.joinAt = 2
+			return true, true
+		case space && string(text) != "\n":
+			return true, true
+		case !space && h.joinAt == 2:
+			// the word goes on after the line break
+			h.joinAt = 0
+		default:
+			// a blank line, or more text on the hyphen's line: the word
+			// ended at the hyphen after all
+			h.joinAt = 0
+			s.scanWord(true, nil, sink)
+		}
+	}
+	if space {
+		if s.hyphen == HyphenJoin && s.InWord && h.last && h.text {
+			if string(text) == "\n" {
+				h.joinAt = 2
+			} else {
+				h.joinAt = 1
+			}
+			return true, true
+		}
+		h.gap = false
+		return true, false
+	}
+	isH := isHyphen(text)
+	if s.hyphen == HyphenSplit && isH && (h.gap || s.InWord && h.text) {
+		h.gap = true
+		return true, false
+	}
+	h.gap = false
+	if !s.InWord {
+		h.text = false
+	}
+	h.text = h.text || !isH
+	h.last = isH
+	return false, false
+}
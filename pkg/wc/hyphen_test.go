@@ -0,0 +1,62 @@
+package wc
+
+import (
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+func TestParseHyphen(t *testing.T) {
+	for name, want := range map[string]Hyphen{"join": HyphenJoin, "split": HyphenSplit} {
+		if h, err := ParseHyphen(name); err != nil || h != want {
+			t.Errorf("%s: %v, %v", name, h, err)
+		}
+	}
+	if _, err := ParseHyphen("keep"); err == nil {
+		t.Error("expected an error for unknown hyphenation")
+	}
+}
+
+func TestHyphen(t *testing.T) {
+	tests := []struct {
+		hyphen  Hyphen
+		in      string
+		words   uint64
+		chars   uint64
+		longest string
+	}{
+		{HyphenNone, "state-of-the-art\nexam-\nple\n", 3, 24, "state-of-the-art"},
+		{HyphenSplit, "state-of-the-art\n", 4, 13, "state"},
+		{HyphenSplit, "a--b - -5 --flag x-\n", 6, 12, "--flag"},
+		{HyphenSplit, "well‐known", 2, 9, "known"},
+		{HyphenJoin, "state-of-the-art\n", 1, 16, "state-of-the-art"},
+		{HyphenJoin, "an exam-\n   ple here\n", 3, 14, "exam-ple"},
+		{HyphenJoin, "exam-\r\nple", 1, 8, "exam-ple"},
+		{HyphenJoin, "exam-  \nple", 1, 8, "exam-ple"},
+		{HyphenJoin, "exam-\n\nple\n", 2, 8, "exam-"},
+		{HyphenJoin, "exam- ple\n", 2, 8, "exam-"},
+		{HyphenJoin, "a -\nb\n", 3, 3, "a"},
+		{HyphenJoin, "end-\n", 1, 4, "end-"},
+	}
+	for _, tt := range tests {
+		for _, bufSize := range []int{1, 3, 64} {
+			opts := Options{BufferSize: bufSize, Locale: locale.Info{IsUTF8: true}, Hyphen: tt.hyphen}
+			got := CountBytes([]byte(tt.in), Metrics{Words: true, WordLengths: true, LongestWord: true}, opts)
+			if got.Words != tt.words || got.WordChars != tt.chars || got.LongestWordText != tt.longest {
+				t.Errorf("%d %q (buffer %d): got %d words, %d chars, longest %q; want %d, %d, %q",
+					tt.hyphen, tt.in, bufSize, got.Words, got.WordChars, got.LongestWordText, tt.words, tt.chars, tt.longest)
+			}
+			if got := CountBytes([]byte(tt.in), Metrics{Words: true}, opts); got.Words != tt.words {
+				t.Errorf("%d %q (buffer %d): got %d words without word lengths, want %d", tt.hyphen, tt.in, bufSize, got.Words, tt.words)
+			}
+		}
+	}
+
+	// a joined word counts on the line it starts on
+	m := Metrics{Lines: true, Words: true, WordsPerLine: true}
+	got := CountBytes([]byte("one two thr-\nee\nfour\n"), m, Options{Hyphen: HyphenJoin})
+	if got.Lines != 3 || got.Words != 4 || got.MinLineWords != 0 || got.MaxLineWords != 3 {
+		t.Errorf("words per line: got %d lines, %d words, %d to %d a line; want 3, 4, 0 to 3",
+			got.Lines, got.Words, got.MinLineWords, got.MaxLineWords)
+	}
+}
@@ -0,0 +1,170 @@
+package wc
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// compileGlob compiles a slash-separated glob, matched against a whole
+// path: "*" and "?" match within a path element, "[...]" is a class of
+// characters ("[!...]" or "[^...]" negated), "**" crosses directories and
+// a backslash quotes the character after it. A glob without a slash, but
+// for a trailing one, matches the last element of paths at any depth, as
+// in .gitignore; a leading slash only anchors the glob.
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	g := strings.TrimSuffix(glob, "/")
+	var b strings.Builder
+	b.WriteString("^")
+	if !strings.Contains(g, "/") {
+		b.WriteString("(?:.*/)?")
+	}
+	g = strings.TrimPrefix(g, "/")
+	for i := 0; i < len(g); i++ {
+		c := g[i]
+		switch {
+		case c == '*' && i+1 < len(g) && g[i+1] == '*':
+			i++
+			if i+1 < len(g) && g[i+1] == '/' {
+				i++
+				b.WriteString("(?:.*/)?")
+			} else {
+				b.WriteString(".*")
+			}
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(g):
+			i++
+			b.WriteString(regexp.QuoteMeta(g[i : i+1]))
+		case c == '[':
+			end := classEnd(g, i)
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			b.WriteString(globClass(g[i+1 : end]))
+			i = end
+		default:
+			b.WriteString(regexp.QuoteMeta(g[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q", glob)
+	}
+	return re, nil
+}
+
+// classEnd returns the index of the "]" closing the class that starts at
+// g[i], or -1. A "]" right after the opening (or its negation) is part of
+// the class.
+func classEnd(g string, i int) int {
+	j := i + 1
+	if j < len(g) && (g[j] == '!' || g[j] == '^') {
+		j++
+	}
+	if j < len(g) && g[j] == ']' {
+		j++
+	}
+	for ; j < len(g); j++ {
+		switch g[j] {
+		case '\\':
+			j++
+		case ']':
+			return j
+		}
+	}
+	return -1
+}
+
+// globClass translates the inside of a glob class to a regexp class that
+// never matches "/".
+func globClass(class string) string {
+	var b strings.Builder
+	b.WriteString("[")
+	if class != "" && (class[0] == '!' || class[0] == '^') {
+		b.WriteString("^/")
+		class = class[1:]
+	}
+	for i := 0; i < len(class); i++ {
+		c := class[i]
+		if c == '\\' && i+1 < len(class) {
+			i++
+			c = class[i]
+		}
+		if strings.IndexByte(`\[]^`, c) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// ignoreRule is a pattern of a .gitignore file.
+type ignoreRule struct {
+	re      *regexp.Regexp // matched against paths relative to the file's directory
+	negate  bool           // "!pattern": the path is not ignored after all
+	dirOnly bool           // "pattern/": directories only
+}
+
+// parseIgnore returns the rules of a .gitignore file. Lines git would not
+// take as patterns are skipped, as git does.
+func parseIgnore(data []byte) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		s := strings.TrimSuffix(string(line), "\r")
+		if t := strings.TrimRight(s, " "); !strings.HasSuffix(t, `\`) || len(t) == len(s) {
+			s = t // trailing spaces, unless quoted
+		}
+		if s == "" || s[0] == '#' {
+			continue
+		}
+		var r ignoreRule
+		if s[0] == '!' {
+			r.negate = true
+			s = s[1:]
+		}
+		if strings.HasSuffix(s, "/") {
+			r.dirOnly = true
+		}
+		re, err := compileGlob(s)
+		if err != nil || strings.Trim(s, "/") == "" {
+			continue
+		}
+		r.re = re
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// ignoreLevel holds the rules of the .gitignore file of dir, a path
+// relative to the root of the walk, and those of the directories above.
+type ignoreLevel struct {
+	parent *ignoreLevel
+	dir    string
+	rules  []ignoreRule
+}
+
+// ignored reports whether the .gitignore files ignore rel, a path
+// relative to the root of the walk: the last rule that matches, from the
+// file closest to rel, decides.
+func (l *ignoreLevel) ignored(rel string, isDir bool) bool {
+	for ; l != nil; l = l.parent {
+		p := rel
+		if l.dir != "." {
+			p = strings.TrimPrefix(rel, l.dir+"/")
+		}
+		for i := len(l.rules) - 1; i >= 0; i-- {
+			r := l.rules[i]
+			if (!r.dirOnly || isDir) && r.re.MatchString(p) {
+				return !r.negate
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package wc
+
+import "testing"
+
+func TestCompileGlob(t *testing.T) {
+	cases := []struct {
+		glob  string
+		path  string
+		match bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/x/main.go", true},
+		{"*.go", "main.go/x", false},
+		{"/*.go", "cmd/main.go", false},
+		{"cmd/*.go", "cmd/main.go", true},
+		{"cmd/*.go", "x/cmd/main.go", false},
+		{"docs/**", "docs/a/b.md", true},
+		{"**/testdata", "a/b/testdata", true},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"build/", "src/build", true},
+		{"f?o", "foo", true},
+		{"f?o", "f/o", false},
+		{"[abc].txt", "b.txt", true},
+		{"[!abc].txt", "b.txt", false},
+		{"[!abc].txt", "d.txt", true},
+		{"[a-c].txt", "b.txt", true},
+		{`\*.txt`, "*.txt", true},
+		{`\*.txt`, "a.txt", false},
+		{"[z", "[z", true},
+	}
+	for _, c := range cases {
+		re, err := compileGlob(c.glob)
+		if err != nil {
+			t.Errorf("%q: %v", c.glob, err)
+			continue
+		}
+		if got := re.MatchString(c.path); got != c.match {
+			t.Errorf("%q on %q: got %v, want %v", c.glob, c.path, got, c.match)
+		}
+	}
+}
+
+func TestIgnoreLevel(t *testing.T) {
+	root := &ignoreLevel{dir: ".", rules: parseIgnore([]byte("*.o\n!keep.o\ntmp/\n\\#hash\n  \n"))}
+	sub := &ignoreLevel{parent: root, dir: "src", rules: parseIgnore([]byte("/local.o\r\n!keep2.o\n*.o\n"))}
+	cases := []struct {
+		l     *ignoreLevel
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{root, "a.o", false, true},
+		{root, "keep.o", false, false},
+		{root, "tmp", true, true},
+		{root, "tmp", false, false},
+		{root, "#hash", false, true},
+		{sub, "src/local.o", false, true},
+		{sub, "src/keep.o", false, true}, // the closer file decides
+		{sub, "src/x.c", false, false},
+	}
+	for _, c := range cases {
+		if got := c.l.ignored(c.rel, c.isDir); got != c.want {
+			t.Errorf("ignored(%q, %v) = %v, want %v", c.rel, c.isDir, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+//go:build go1.23
+
+package wc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// Results counts the named files concurrently, one per CPU at a time, and
+// yields their results in the order of names, with Index set. A file that
+// fails yields a result carrying its error. Breaking out of the loop stops
+// scheduling files and cancels the ones being counted; so does ctx.
+// Callbacks in opt may be called from several goroutines at once.
+func Results(ctx context.Context, names []string, m Metrics, opt Options) iter.Seq[FileResult] {
+	return func(yield func(FileResult) bool) {
+		if len(names) == 0 {
+			return
+		}
+		countOrdered(ctx, min(runtime.GOMAXPROCS(0), len(names)), m, opt, yield, func(send func(countJob) bool) {
+			for _, name := range names {
+				if !send(countJob{name: name}) {
+					return
+				}
+			}
+		})
+	}
+}
+
+// Results walks w and counts the files it finds as Results counts names,
+// opening them relative to their directories, kept open meanwhile, as
+// w.Open would, and yields their results in the order of Walk,
+// named by their paths relative to Root. A directory that cannot be read
+// yields a result named after it and carrying the error, and the walk goes
+// on without it; a pattern that does not compile yields an unnamed result
+// carrying the error, and nothing else.
+func (w *Walker) Results(ctx context.Context, m Metrics, opt Options) iter.Seq[FileResult] {
+	return func(yield func(FileResult) bool) {
+		countOrdered(ctx, runtime.GOMAXPROCS(0), m, opt, yield, func(send func(countJob) bool) {
+			s, err := w.start(func(rel string, err error) error {
+				if !send(countJob{name: rel, err: err}) {
+					return errStopWalk
+				}
+				return nil
+			})
+			if err != nil {
+				send(countJob{err: err})
+				return
+			}
+			_ = s.run(func(rel string, at fileAt) error {
+				open, done := s.opener(rel, at)
+				if !send(countJob{name: rel, open: open, done: done}) {
+					done()
+					return errStopWalk
+				}
+				return nil
+			})
+		})
+	}
+}
+
+// errStopWalk ends a walk whose results are no longer wanted.
+var errStopWalk = errors.New("walk stopped")
+
+// countJob is a file for countOrdered to count.
+type countJob struct {
+	index int
+	name  string
+	err   error                          // instead of a count
+	open  func(string) (*os.File, error) // replaces Options.Open
+	done  func()                         // called once the file is counted
+}
+
+// countOrdered counts the files produce sends with workers goroutines and
+// yields the results in the order they were sent, until yield returns
+// false. A job sent with an error is not counted; its result carries the
+// error. send returns false once the loop has ended, having not taken the
+// job, and produce should then return.
+func countOrdered(ctx context.Context, workers int, m Metrics, opt Options, yield func(FileResult) bool, produce func(send func(countJob) bool)) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{}) // closed when the loop ends
+	jobs := make(chan countJob)
+	results := make(chan FileResult)
+	var wg sync.WaitGroup
+	defer func() {
+		close(done)
+		cancel()
+		for range results {
+		}
+	}()
+
+	wg.Add(workers + 1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		i := 0
+		produce(func(j countJob) bool {
+			j.index = i
+			select {
+			case jobs <- j:
+				i++
+				return true
+			case <-done:
+				return false
+			}
+		})
+	}()
+	for range workers {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				fr := FileResult{Filename: j.name, Err: j.err}
+				if j.err == nil {
+					o := opt
+					if j.open != nil {
+						o.Open = j.open
+					}
+					fr = CountFile(ctx, j.name, m, o)
+				}
+				if j.done != nil {
+					j.done()
+				}
+				fr.Index = j.index
+				select {
+				case results <- fr:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Collect in order
+	pending := make(map[int]FileResult)
+	next := 0
+	for fr := range results {
+		pending[fr.Index] = fr
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if !yield(r) {
+				return
+			}
+		}
+	}
+}
+
+// Lines reads r to the end and yields the counts of each line as it is
+// read, as Options.OnLine would receive them, including an unterminated
+// last line. A read error ends the lines like the end of input; count
+// with CountReader and Options.OnLine to see it.
+func Lines(r io.Reader) iter.Seq[LineCount] {
+	return func(yield func(LineCount) bool) {
+		stopped := false
+		c := NewCounter(Metrics{}, Options{OnLine: func(l LineCount) {
+			if !stopped && !yield(l) {
+				stopped = true
+			}
+		}})
+		buf := make([]byte, defaultBufferSize)
+		for !stopped {
+			n, err := r.Read(buf)
+			_, _ = c.Write(buf[:n])
+			if err == io.EOF {
+				c.lastLine()
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+//go:build go1.23
+
+package wc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResults(t *testing.T) {
+	dir := t.TempDir()
+	var names []string
+	for i := 0; i < 20; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("f%d.txt", i))
+		if err := os.WriteFile(name, []byte(strings.Repeat("x\n", i)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	names = append(names, filepath.Join(dir, "missing"))
+
+	var got []FileResult
+	for res := range Results(context.Background(), names, Metrics{Lines: true}, Options{}) {
+		got = append(got, res)
+	}
+	if len(got) != len(names) {
+		t.Fatalf("got %d results, want %d", len(got), len(names))
+	}
+	for i, res := range got[:20] {
+		if res.Index != i || res.Filename != names[i] || res.Lines != uint64(i) || res.Err != nil {
+			t.Errorf("result %d = %+v", i, res)
+		}
+	}
+	if got[20].Err == nil {
+		t.Error("expected an error for the missing file")
+	}
+
+	n := 0
+	for range Results(context.Background(), names, Metrics{Lines: true}, Options{}) {
+		if n++; n == 3 {
+			break
+		}
+	}
+	if n != 3 {
+		t.Errorf("break after 3 results: saw %d", n)
+	}
+}
+
+func TestWalkerResults(t *testing.T) {
+	root := walkTree(t, "a.txt", "b/c.txt", "b/d.log", "e.txt")
+	w := &Walker{Root: root, RestrictToRoot: true, Exclude: []string{"*.log"}}
+	var got []string
+	for res := range w.Results(context.Background(), Metrics{Words: true}, Options{}) {
+		if res.Err != nil || res.Words != 2 || res.Index != len(got) {
+			t.Errorf("result %+v", res)
+		}
+		got = append(got, res.Filename)
+	}
+	if want := []string{"a.txt", "b/c.txt", "e.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	n := 0
+	for range w.Results(context.Background(), Metrics{Words: true}, Options{}) {
+		if n++; n == 1 {
+			break
+		}
+	}
+	for res := range (&Walker{Root: filepath.Join(root, "missing")}).Results(context.Background(), Metrics{}, Options{}) {
+		if res.Err == nil || res.Filename != "." {
+			t.Errorf("unreadable root: %+v", res)
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	var got []LineCount
+	for l := range Lines(strings.NewReader("one two\n\nthree")) {
+		got = append(got, l)
+	}
+	want := []LineCount{{Line: 1, Bytes: 7, Chars: 7, Words: 2}, {Line: 2}, {Line: 3, Bytes: 5, Chars: 5, Words: 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines() = %v, want %v", got, want)
+	}
+	for l := range Lines(strings.NewReader("a\nb\nc\n")) {
+		if l.Line > 1 {
+			t.Fatalf("yielded line %d after break", l.Line)
+		}
+		break
+	}
+}
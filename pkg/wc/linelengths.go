@@ -0,0 +1,93 @@
+package wc
+
+import (
+	"bytes"
+	"math/bits"
+)
+
+func init() {
+	registerMetric(&accMetric[[]uint64]{
+		name:  "line_lengths",
+		on:    func(m Metrics, _ Options) bool { return m.LineLengths },
+		new:   func(_ Metrics, _ Options, chunk bool) Accumulator[[]uint64] { return &lineLengthsAcc{Cut: chunk} },
+		store: func(r *FileResult, v []uint64) { r.LineLengths = v },
+	})
+}
+
+// lineLengthBucket returns the element of FileResult.LineLengths that
+// counts a line of n bytes.
+func lineLengthBucket(n uint64) int { return bits.Len64(n) }
+
+// lineLengthsAcc sorts lines into FileResult.LineLengths by their length
+// in bytes, '\n' excluded. The line after the last '\n' is open until
+// another one ends it; in a chunk, so is the line before the first,
+// which may have begun in the chunk before.
+type lineLengthsAcc struct {
+	Buckets []uint64 // the lines ended so far, but for Head
+	Cut     bool     // the first line may have begun before the bytes fed
+	Ended   bool     // a '\n' was fed
+	Head    uint64   // with Cut and Ended, bytes of the first line
+	Tail    uint64   // bytes after the last '\n'
+}
+
+func (a *lineLengthsAcc) Feed(p []byte) {
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			a.Tail += uint64(len(p))
+			return
+		}
+		a.end(a.Tail + uint64(i))
+		a.Tail = 0
+		p = p[i+1:]
+	}
+}
+
+// end ends a line of n bytes.
+func (a *lineLengthsAcc) end(n uint64) {
+	if a.Cut && !a.Ended {
+		a.Head = n
+	} else {
+		a.Buckets = addLineLength(a.Buckets, n)
+	}
+	a.Ended = true
+}
+
+func addLineLength(buckets []uint64, n uint64) []uint64 {
+	i := lineLengthBucket(n)
+	for len(buckets) <= i {
+		buckets = append(buckets, 0)
+	}
+	buckets[i]++
+	return buckets
+}
+
+func (a *lineLengthsAcc) Merge(next Accumulator[[]uint64]) Accumulator[[]uint64] {
+	b := next.(*lineLengthsAcc)
+	if !a.Ended && a.Tail == 0 {
+		// a was fed nothing
+		out := *b
+		out.Buckets = addCounts(nil, b.Buckets)
+		return &out
+	}
+	out := &lineLengthsAcc{Buckets: addCounts(a.Buckets, b.Buckets), Cut: a.Cut, Ended: a.Ended, Head: a.Head, Tail: a.Tail + b.Tail}
+	if b.Ended {
+		// the line open at the end of a runs on to b's first '\n'
+		out.Tail = 0
+		out.end(a.Tail + b.Head)
+		out.Tail = b.Tail
+	}
+	return out
+}
+
+// Result counts the lines open at either end as they stand.
+func (a *lineLengthsAcc) Result() []uint64 {
+	out := addCounts(nil, a.Buckets)
+	if a.Cut && a.Ended {
+		out = addLineLength(out, a.Head)
+	}
+	if a.Tail > 0 {
+		out = addLineLength(out, a.Tail)
+	}
+	return out
+}
@@ -0,0 +1,306 @@
+package wc
+
+import "bytes"
+
+func init() {
+	registerMetric(&accMetric[uint64]{
+		name: "max_line_bytes",
+		on:   func(m Metrics, _ Options) bool { return m.MaxLineBytes },
+		newText: func(_ Metrics, _ Options, chunk bool) TextAccumulator[uint64] {
+			return &maxLineAcc{Lines: lineEdges[uint64]{Cut: chunk}}
+		},
+		store: func(r *FileResult, v uint64) { r.MaxLineBytes = v },
+	})
+	registerMetric(&accMetric[uint64]{
+		name: "max_line_chars",
+		on:   func(m Metrics, _ Options) bool { return m.MaxLineChars },
+		newText: func(_ Metrics, _ Options, chunk bool) TextAccumulator[uint64] {
+			return &maxLineAcc{Lines: lineEdges[uint64]{Cut: chunk}, Chars: true}
+		},
+		store: func(r *FileResult, v uint64) { r.MaxLineChars = v },
+	})
+	registerMetric(&accMetric[uint64]{
+		name: "whitespace_lines",
+		on:   func(m Metrics, _ Options) bool { return m.WhitespaceLines },
+		newText: func(_ Metrics, _ Options, chunk bool) TextAccumulator[uint64] {
+			return &spaceLinesAcc{Lines: lineEdges[LineContent]{Cut: chunk}}
+		},
+		store: func(r *FileResult, v uint64) { r.WhitespaceLines = v },
+	})
+	registerMetric(&accMetric[LineWordCounts]{
+		name: "words_per_line",
+		on:   func(m Metrics, _ Options) bool { return m.WordsPerLine },
+		newText: func(_ Metrics, opt Options, chunk bool) TextAccumulator[LineWordCounts] {
+			return &lineWordsAcc{Words: newWordScan(opt, chunk, false), Lines: lineEdges[uint64]{Cut: chunk}}
+		},
+		store: func(r *FileResult, v LineWordCounts) {
+			r.MinLineWords, r.MaxLineWords, r.AllLines = v.Min, v.Max, v.Lines
+		},
+	})
+}
+
+// lineEdges keeps what a line metric measures of the lines at the edges
+// of a text, which may run on into the texts before and after it: L is
+// the measure of a line, or of the part of it fed so far.
+type lineEdges[L any] struct {
+	Cut   bool // the first line may have begun before the text fed
+	Ended bool // a '\n' was fed
+	Open  bool // characters were fed after the last '\n'
+	Head  L    // with Cut and Ended, the first line
+	Tail  L    // the line after the last '\n'
+}
+
+// end ends the line in Tail. It returns it unless it is the first line of
+// a Cut text, which is kept in Head.
+func (e *lineEdges[L]) end() (line L, ok bool) {
+	line, ok = e.Tail, !e.Cut || e.Ended
+	if !ok {
+		e.Head = line
+	}
+	var zero L
+	e.Ended, e.Open, e.Tail = true, false, zero
+	return line, ok
+}
+
+// merge returns the edges of e's text followed by n's. join measures a
+// line from its parts before and after the boundary. When both texts hold
+// a '\n', the line they join into is complete and merge returns it too.
+func (e *lineEdges[L]) merge(n *lineEdges[L], join func(a, b L) L) (out lineEdges[L], line L, ok bool) {
+	switch {
+	case !e.Ended && !e.Open:
+		return *n, line, false
+	case !n.Ended && !n.Open:
+		return *e, line, false
+	}
+	out = lineEdges[L]{Cut: e.Cut, Ended: true, Open: n.Open, Head: e.Head, Tail: n.Tail}
+	switch {
+	case !n.Ended:
+		out.Ended, out.Open = e.Ended, e.Open || n.Open
+		out.Tail = join(e.Tail, n.Tail)
+	case !e.Ended && e.Cut:
+		out.Head = join(e.Tail, n.Head)
+	default:
+		line, ok = join(e.Tail, n.Head), true
+	}
+	return out, line, ok
+}
+
+// maxLineAcc finds the longest line, in bytes or with Chars in
+// characters, '\n' excluded.
+type maxLineAcc struct {
+	Lines lineEdges[uint64]
+	Max   uint64 // of the lines ended so far, but for Lines.Head
+	Chars bool
+}
+
+func (a *maxLineAcc) FeedText(t *Text) {
+	if t.Bytes != nil {
+		for p := t.Bytes; ; {
+			i := bytes.IndexByte(p, '\n')
+			if i < 0 {
+				a.add(uint64(len(p)))
+				return
+			}
+			a.add(uint64(i))
+			a.end()
+			p = p[i+1:]
+		}
+	}
+	for _, c := range t.Chars {
+		switch {
+		case c.Rune == '\n':
+			a.end()
+		case a.Chars:
+			a.add(1)
+		default:
+			a.add(uint64(c.Size))
+		}
+	}
+}
+
+func (a *maxLineAcc) add(n uint64) {
+	if n > 0 {
+		a.Lines.Tail += n
+		a.Lines.Open = true
+	}
+}
+
+func (a *maxLineAcc) end() {
+	if n, ok := a.Lines.end(); ok {
+		a.Max = max(a.Max, n)
+	}
+}
+
+func (a *maxLineAcc) Merge(next TextAccumulator[uint64]) TextAccumulator[uint64] {
+	b := next.(*maxLineAcc)
+	out := &maxLineAcc{Max: max(a.Max, b.Max), Chars: a.Chars || b.Chars}
+	lines, n, ok := a.Lines.merge(&b.Lines, func(x, y uint64) uint64 { return x + y })
+	out.Lines = lines
+	if ok {
+		out.Max = max(out.Max, n)
+	}
+	return out
+}
+
+func (a *maxLineAcc) Result() uint64 {
+	n := max(a.Max, a.Lines.Tail)
+	if a.Lines.Cut && a.Lines.Ended {
+		n = max(n, a.Lines.Head)
+	}
+	return n
+}
+
+// spaceLinesAcc counts the lines that hold white space and nothing else.
+type spaceLinesAcc struct {
+	Lines lineEdges[LineContent]
+	Count uint64 // of the lines ended so far, but for Lines.Head
+}
+
+func (a *spaceLinesAcc) FeedText(t *Text) {
+	if t.Bytes != nil {
+		for _, b := range t.Bytes {
+			if b == '\n' {
+				a.end()
+			} else {
+				a.note(asciiSpace[b])
+			}
+		}
+		return
+	}
+	for _, c := range t.Chars {
+		if c.Rune == '\n' {
+			a.end()
+		} else {
+			a.note(c.Space && !c.Invalid)
+		}
+	}
+}
+
+// note records a character other than '\n'.
+func (a *spaceLinesAcc) note(space bool) {
+	l := &a.Lines
+	l.Open = true
+	if !space {
+		l.Tail = LineHasText
+	} else if l.Tail == LineEmpty {
+		l.Tail = LineSpaceOnly
+	}
+}
+
+func (a *spaceLinesAcc) end() {
+	if l, ok := a.Lines.end(); ok && l == LineSpaceOnly {
+		a.Count++
+	}
+}
+
+func (a *spaceLinesAcc) Merge(next TextAccumulator[uint64]) TextAccumulator[uint64] {
+	b := next.(*spaceLinesAcc)
+	out := &spaceLinesAcc{Count: a.Count + b.Count}
+	lines, l, ok := a.Lines.merge(&b.Lines, func(x, y LineContent) LineContent { return max(x, y) })
+	out.Lines = lines
+	if ok && l == LineSpaceOnly {
+		out.Count++
+	}
+	return out
+}
+
+func (a *spaceLinesAcc) Result() uint64 {
+	n := a.Count
+	if a.Lines.Cut && a.Lines.Ended && a.Lines.Head == LineSpaceOnly {
+		n++
+	}
+	if a.Lines.Tail == LineSpaceOnly {
+		n++
+	}
+	return n
+}
+
+// LineWordCounts is the words-per-line distribution of
+// Metrics.WordsPerLine: the fewest and most words on a line, and the
+// number of lines they were taken from.
+type LineWordCounts struct {
+	Min, Max, Lines uint64
+}
+
+// note adds a line of n words.
+func (d *LineWordCounts) note(n uint64) {
+	if d.Lines == 0 || n < d.Min {
+		d.Min = n
+	}
+	d.Max = max(d.Max, n)
+	d.Lines++
+}
+
+// add adds the lines of o.
+func (d *LineWordCounts) add(o LineWordCounts) {
+	if o.Lines == 0 {
+		return
+	}
+	if d.Lines == 0 || o.Min < d.Min {
+		d.Min = o.Min
+	}
+	d.Max = max(d.Max, o.Max)
+	d.Lines += o.Lines
+}
+
+// lineWordsAcc counts the words starting on each line: every
+// newline-terminated line and an unterminated last one.
+type lineWordsAcc struct {
+	Words wordScan
+	Lines lineEdges[uint64]
+	Dist  LineWordCounts // of the lines ended so far, but for Lines.Head
+}
+
+func (a *lineWordsAcc) FeedText(t *Text) {
+	if t.len() == 0 {
+		return
+	}
+	a.Words.feed(t, a)
+	a.Lines.Open = !t.endsLine()
+}
+
+func (a *lineWordsAcc) start() { a.Lines.Tail++ }
+
+func (a *lineWordsAcc) drop() { a.Lines.Tail-- }
+
+func (a *lineWordsAcc) end(wordPart) {}
+
+func (a *lineWordsAcc) line() {
+	if n, ok := a.Lines.end(); ok {
+		a.Dist.note(n)
+	}
+}
+
+func (a *lineWordsAcc) Merge(next TextAccumulator[LineWordCounts]) TextAccumulator[LineWordCounts] {
+	b := next.(*lineWordsAcc)
+	out := &lineWordsAcc{Dist: a.Dist}
+	out.Dist.add(b.Dist)
+	var straddle bool
+	out.Words, straddle = a.Words.merge(&b.Words, nil)
+	lines, n, ok := a.Lines.merge(&b.Lines, func(x, y uint64) uint64 {
+		if straddle {
+			return x + y - 1 // the word was counted on both sides
+		}
+		return x + y
+	})
+	out.Lines = lines
+	if ok {
+		out.Dist.note(n)
+	}
+	return out
+}
+
+func (a *lineWordsAcc) Result() LineWordCounts {
+	d := a.Dist
+	if a.Lines.Cut && a.Lines.Ended {
+		d.note(a.Lines.Head)
+	}
+	if a.Lines.Open {
+		n := a.Lines.Tail
+		if a.Words.tailDropped() {
+			n--
+		}
+		d.note(n)
+	}
+	return d
+}
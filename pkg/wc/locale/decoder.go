@@ -0,0 +1,174 @@
+package locale
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// Decoder turns the bytes of a character encoding into runes. Setting
+// Info.Decoder makes counters decode with it instead of UTF-8, so that
+// legacy or vendor encodings can be counted without changes to the core.
+//
+// Counts made on the raw bytes, such as line endings and string or regexp
+// matches, assume an encoding compatible with ASCII.
+type Decoder interface {
+	// Decode decodes the first character of p, which is never empty, and
+	// returns it with the number of bytes it takes. It returns
+	// ErrIncomplete when p ends inside a sequence that more bytes could
+	// complete, and ErrInvalid, with the size of the invalid sequence,
+	// when p does not start with a character. What to do with invalid
+	// input is the decoder's choice: it may instead return a replacement
+	// rune, or a size covering the whole bad sequence.
+	Decode(p []byte) (r rune, size int, err error)
+}
+
+var (
+	// ErrIncomplete reports that a sequence needs more bytes. If no more
+	// come, its bytes are counted as invalid.
+	ErrIncomplete = errors.New("locale: incomplete sequence")
+	// ErrInvalid reports bytes that are not a character. Each of them
+	// counts as one non-space character, as GNU wc counts invalid bytes.
+	ErrInvalid = errors.New("locale: invalid sequence")
+)
+
+// UTF8 decodes UTF-8, as counters do when Info.Decoder is nil.
+var UTF8 Decoder = utf8Decoder{}
+
+type utf8Decoder struct{}
+
+func (utf8Decoder) Decode(p []byte) (rune, int, error) {
+	if !utf8.FullRune(p) {
+		return 0, 0, ErrIncomplete
+	}
+	r, size := utf8.DecodeRune(p)
+	if r == utf8.RuneError && size == 1 {
+		return r, 1, ErrInvalid
+	}
+	return r, size, nil
+}
+
+// Latin1 decodes ISO-8859-1, where every byte is the rune of the same
+// value.
+var Latin1 Decoder = latin1Decoder{}
+
+type latin1Decoder struct{}
+
+func (latin1Decoder) Decode(p []byte) (rune, int, error) {
+	return rune(p[0]), 1, nil
+}
+
+// Latin9 decodes ISO-8859-15, which replaces eight symbols of Latin-1 with
+// the euro sign and letters for French, Finnish and Estonian.
+var Latin9 Decoder = latin9
+
+var latin9 = newSingleByte(map[byte]rune{
+	0xa4: '€', 0xa6: 'Š', 0xa8: 'š', 0xb4: 'Ž',
+	0xb8: 'ž', 0xbc: 'Œ', 0xbd: 'œ', 0xbe: 'Ÿ',
+})
+
+// Windows1252 decodes the Windows Latin-1 code page, which puts printable
+// characters in the C1 range of ISO-8859-1. The five bytes it leaves
+// undefined are invalid.
+var Windows1252 Decoder = windows1252
+
+var windows1252 = newSingleByte(map[byte]rune{
+	0x80: '€', 0x81: -1, 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡',
+	0x88: 'ˆ', 0x89: '‰', 0x8a: 'Š', 0x8b: '‹', 0x8c: 'Œ', 0x8d: -1, 0x8e: 'Ž', 0x8f: -1,
+	0x90: -1, 0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—',
+	0x98: '˜', 0x99: '™', 0x9a: 'š', 0x9b: '›', 0x9c: 'œ', 0x9d: -1, 0x9e: 'ž', 0x9f: 'Ÿ',
+})
+
+// CP437 decodes the original IBM PC code page, the default of the
+// Windows console in the US.
+var CP437 Decoder = cp437
+
+var cp437 = newUpperHalf("" +
+	"ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜ¢£¥₧ƒ" +
+	"áíóúñÑªº¿⌐¬½¼¡«»░▒▓│┤╡╢╖╕╣║╗╝╜╛┐" +
+	"└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀" +
+	"αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0")
+
+// CP850 decodes the multilingual Latin-1 DOS code page, the default of the
+// Windows console in much of Western Europe.
+var CP850 Decoder = cp850
+
+var cp850 = newUpperHalf("" +
+	"ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜø£Ø×ƒ" +
+	"áíóúñÑªº¿®¬½¼¡«»░▒▓│┤ÁÂÀ©╣║╗╝¢¥┐" +
+	"└┴┬├─┼ãÃ╚╔╩╦╠═╬¤ðÐÊËÈıÍÎÏ┘┌█▄¦Ì▀" +
+	"ÓßÔÒõÕµþÞÚÛÙýÝ¯´\u00ad±‗¾¶§÷¸°¨·¹³²■\u00a0")
+
+// singleByte decodes an encoding of one byte per character, given the
+// rune of every byte; -1 marks an invalid byte.
+type singleByte struct {
+	runes [256]rune
+	bytes map[rune]byte // the bytes of the runes above 0x7f, for Encode
+}
+
+// newSingleByte returns the decoder of Latin-1 with the given bytes changed.
+func newSingleByte(changes map[byte]rune) *singleByte {
+	d := new(singleByte)
+	for i := range d.runes {
+		d.runes[i] = rune(i)
+	}
+	for b, r := range changes {
+		d.runes[b] = r
+	}
+	d.index()
+	return d
+}
+
+// newUpperHalf returns the decoder of an encoding that is ASCII up to 0x7f
+// and whose bytes from 0x80 on are the runes of upper, in order.
+func newUpperHalf(upper string) *singleByte {
+	d := new(singleByte)
+	for i := 0; i < 0x80; i++ {
+		d.runes[i] = rune(i)
+	}
+	i := 0x80
+	for _, r := range upper {
+		d.runes[i] = r
+		i++
+	}
+	if i != 0x100 {
+		panic("locale: upper half of a code page is not 128 runes")
+	}
+	d.index()
+	return d
+}
+
+func (d *singleByte) index() {
+	d.bytes = make(map[rune]byte)
+	for i := 0xff; i >= 0x80; i-- {
+		if r := d.runes[i]; r >= 0x80 {
+			d.bytes[r] = byte(i)
+		}
+	}
+}
+
+func (d *singleByte) Decode(p []byte) (rune, int, error) {
+	if r := d.runes[p[0]]; r >= 0 {
+		return r, 1, nil
+	}
+	return utf8.RuneError, 1, ErrInvalid
+}
+
+// DecoderFor returns the decoder of the canonical encoding name enc, as
+// normalized by Detect, or nil when counters need none: for UTF-8, the
+// single-byte C locale and US-ASCII, and for encodings it does not know,
+// which are decoded as UTF-8.
+func DecoderFor(enc string) Decoder {
+	switch enc {
+	case "iso-8859-1":
+		return Latin1
+	case "iso-8859-15":
+		return Latin9
+	case "windows-1252":
+		return Windows1252
+	case "cp437":
+		return CP437
+	case "cp850":
+		return CP850
+	}
+	return nil
+}
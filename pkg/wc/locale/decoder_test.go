@@ -0,0 +1,32 @@
+package locale
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestUTF8Decoder(t *testing.T) {
+	tests := []struct {
+		in   string
+		r    rune
+		size int
+		err  error
+	}{
+		{"é!", 'é', 2, nil},
+		{"\xc3", 0, 0, ErrIncomplete},
+		{"\xff", utf8.RuneError, 1, ErrInvalid},
+		{"�", utf8.RuneError, 3, nil},
+	}
+	for _, tt := range tests {
+		r, size, err := UTF8.Decode([]byte(tt.in))
+		if r != tt.r || size != tt.size || err != tt.err {
+			t.Errorf("Decode(%q) = %q, %d, %v; want %q, %d, %v", tt.in, r, size, err, tt.r, tt.size, tt.err)
+		}
+	}
+}
+
+func TestLatin1Decoder(t *testing.T) {
+	if r, size, err := Latin1.Decode([]byte{0xe9, 'x'}); r != 'é' || size != 1 || err != nil {
+		t.Errorf("Decode(0xe9) = %q, %d, %v", r, size, err)
+	}
+}
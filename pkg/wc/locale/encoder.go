@@ -0,0 +1,58 @@
+package locale
+
+// Encoder turns runes into the bytes of a character encoding, to print
+// text for a console that does not use UTF-8.
+type Encoder interface {
+	// Encode appends the encoding of r to dst. It reports false, and
+	// appends nothing, when the encoding has no such character.
+	Encode(dst []byte, r rune) ([]byte, bool)
+}
+
+// EncoderFor returns the encoder of the canonical encoding name enc, or
+// nil when text needs no encoding for it: for UTF-8, for the C locale,
+// whose bytes pass through as they are, and for encodings it does not
+// know.
+func EncoderFor(enc string) Encoder {
+	switch enc {
+	case "us-ascii":
+		return asciiEncoder{}
+	case "iso-8859-1":
+		return latin1Decoder{}
+	case "iso-8859-15":
+		return latin9
+	case "windows-1252":
+		return windows1252
+	case "cp437":
+		return cp437
+	case "cp850":
+		return cp850
+	}
+	return nil
+}
+
+type asciiEncoder struct{}
+
+func (asciiEncoder) Encode(dst []byte, r rune) ([]byte, bool) {
+	if r < 0 || r > 0x7f {
+		return dst, false
+	}
+	return append(dst, byte(r)), true
+}
+
+func (latin1Decoder) Encode(dst []byte, r rune) ([]byte, bool) {
+	if r < 0 || r > 0xff {
+		return dst, false
+	}
+	return append(dst, byte(r)), true
+}
+
+func (d *singleByte) Encode(dst []byte, r rune) ([]byte, bool) {
+	if r >= 0 && r < 0x80 && d.runes[r] == r {
+		return append(dst, byte(r)), true
+	}
+	b, ok := d.bytes[r]
+	if !ok {
+		return dst, false
+	}
+	return append(dst, b), true
+}
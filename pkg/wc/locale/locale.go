@@ -9,29 +9,107 @@ type Info struct {
 	Encoding    string
 	IsUTF8      bool
 	IsCOrPOSIX  bool
+	// Decoder, when set, decodes the input in place of UTF-8 (see Decoder).
+	Decoder     Decoder `json:"-"`
+	// SingleByte reports an encoding of one byte per character. Those
+	// without a Decoder are counted like the C locale, a character per
+	// byte, rather than as UTF-8.
+	SingleByte  bool
+
+	// Language, Territory, Codeset and Modifier are the parts of the
+	// locale name language[_territory][.codeset][@modifier] it came from,
+	// as written; Codeset is the source of Encoding. They are empty for an
+	// --encoding override and for the default.
+	Language  string
+	Territory string
+	Codeset   string
+	Modifier  string
 }
 
 // Detect reads environment (LC_ALL > LC_CTYPE > LANG) and returns locale Info.
 // If override is non-empty, it is used directly.
 func Detect(override string) Info {
 	if override != "" {
-		enc := normalizeEncoding(override)
-		return Info{Encoding: enc, IsUTF8: enc == "utf-8", IsCOrPOSIX: enc == "C" || enc == "POSIX"}
+		var info Info
+		info.setEncoding(normalizeEncoding(override))
+		return info
+	}
+	return Parse(firstNonEmpty(os.Getenv("LC_ALL"), os.Getenv("LC_CTYPE"), os.Getenv("LANG")))
+}
+
+// Parse splits a POSIX locale name such as en_US.UTF-8, sr_RS.UTF-8@latin
+// or C into its parts. Without a codeset the encoding is UTF-8, except for
+// the C and POSIX locales, whose characters are bytes, and for the @euro
+// modifier, which implies ISO-8859-15. An empty name is the UTF-8 default.
+func Parse(name string) Info {
+	if name == "" {
+		return Info{Encoding: "utf-8", IsUTF8: true}
+	}
+	var info Info
+	rest := name
+	if i := strings.IndexByte(rest, '@'); i >= 0 {
+		rest, info.Modifier = rest[:i], rest[i+1:]
+	}
+	if i := strings.IndexByte(rest, '.'); i >= 0 {
+		rest, info.Codeset = rest[:i], rest[i+1:]
+	}
+	info.Language, info.Territory, _ = strings.Cut(rest, "_")
+
+	switch {
+	case info.Codeset != "":
+		info.setEncoding(normalizeEncoding(info.Codeset))
+	case info.Language == "C" || info.Language == "POSIX":
+		info.Encoding, info.IsCOrPOSIX, info.SingleByte = "C", true, true
+	case info.Modifier == "euro":
+		info.setEncoding("iso-8859-15")
+	default:
+		info.setEncoding("utf-8")
+	}
+	return info
+}
+
+// setEncoding sets the encoding and what follows from it. US-ASCII has
+// single-byte characters like the C locale; bytes above 0x7f are not
+// characters of it, but count as non-space characters all the same. So do
+// those of single-byte encodings without a Decoder.
+func (info *Info) setEncoding(enc string) {
+	info.Encoding = enc
+	info.IsUTF8 = enc == "utf-8"
+	info.Decoder = DecoderFor(enc)
+	info.SingleByte = isSingleByte(enc)
+	info.IsCOrPOSIX = enc == "C" || enc == "POSIX" || enc == "us-ascii" || info.SingleByte && info.Decoder == nil
+}
+
+// ByteChars returns info counting the characters of a single-byte
+// encoding as its bytes, by the rules of the C locale, instead of decoding
+// them: as glibc's wc counts them, every byte is a character and only
+// ASCII white space separates words. Other encodings are left as they are.
+func (info Info) ByteChars() Info {
+	if info.SingleByte {
+		info.IsCOrPOSIX, info.Decoder = true, nil
 	}
-	val := firstNonEmpty(os.Getenv("LC_ALL"), os.Getenv("LC_CTYPE"), os.Getenv("LANG"))
-	if val == "" { return Info{Encoding: "utf-8", IsUTF8: true} }
-	// Examples: en_US.UTF-8, C, POSIX, de_DE.ISO-8859-1
-	up := val
-	if up == "C" || up == "POSIX" {
-		return Info{Encoding: "C", IsCOrPOSIX: true}
+	return info
+}
+
+// singleBytePrefixes holds the loose names, or their beginnings, of
+// common encodings of one byte per character: the ISO-8859 and Windows
+// code pages, KOI8, the DOS code pages and a few national ones.
+var singleBytePrefixes = []string{"iso8859", "windows12", "cp12", "koi8", "cp4", "cp8", "ibm4", "ibm8",
+	"tis620", "macintosh", "macroman", "armscii8", "georgianps", "pt154", "kz1048"}
+
+// isSingleByte reports whether the canonical encoding name enc is one of
+// one byte per character.
+func isSingleByte(enc string) bool {
+	l := looseName(enc)
+	if l == "c" || l == "posix" || l == "usascii" {
+		return true
 	}
-	// split on '.' to find codeset
-	enc := "utf-8"
-	if i := strings.IndexByte(up, '.'); i >= 0 && i+1 < len(up) {
-		enc = up[i+1:]
+	for _, p := range singleBytePrefixes {
+		if strings.HasPrefix(l, p) {
+			return true
+		}
 	}
-	enc = normalizeEncoding(enc)
-	return Info{Encoding: enc, IsUTF8: enc == "utf-8"}
+	return false
 }
 
 func firstNonEmpty(ss ...string) string {
@@ -41,18 +119,88 @@ func firstNonEmpty(ss ...string) string {
 	return ""
 }
 
+// normalizeEncoding returns the canonical name of an encoding: the
+// aliases in charsetAliases resolve to the names the decoders use, other
+// names are only lowercased, with '_' turned into '-'.
 func normalizeEncoding(s string) string {
-	s = strings.TrimSpace(strings.ToLower(s))
-	s = strings.ReplaceAll(s, "_", "-")
-	s = strings.ReplaceAll(s, "charset=", "")
-	s = strings.ReplaceAll(s, "cs", "")
-	s = strings.TrimPrefix(s, "")
-	// Common aliases
-	switch s {
-	case "utf8": return "utf-8"
-	case "c": return "C"
-	case "posix": return "POSIX"
+	s = strings.TrimSpace(s)
+	if len(s) >= 8 && strings.EqualFold(s[:8], "charset=") {
+		s = s[8:]
+	}
+	if canon, ok := charsetAliases[looseName(s)]; ok {
+		return canon
+	}
+	return strings.ReplaceAll(strings.ToLower(s), "_", "-")
+}
+
+// looseName reduces a charset name to its lowercased letters and digits,
+// so that UTF-8, utf_8 and UTF8 match alike (Unicode TS #22 loose
+// matching).
+func looseName(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+			b.WriteByte(c)
+		case c >= 'A' && c <= 'Z':
+			b.WriteByte(c + 'a' - 'A')
+		}
 	}
-	return s
+	return b.String()
 }
 
+// charsetAliases maps loose names of the IANA charset registry entries, and
+// a few common spellings, to canonical encoding names.
+var charsetAliases = map[string]string{
+	"c":     "C",
+	"posix": "POSIX",
+
+	"utf8":          "utf-8",
+	"csutf8":        "utf-8",
+	"unicode11utf8": "utf-8",
+
+	"usascii":       "us-ascii",
+	"ascii":         "us-ascii",
+	"us":            "us-ascii",
+	"ansix341968":   "us-ascii",
+	"ansix341986":   "us-ascii",
+	"iso646us":      "us-ascii",
+	"iso646irv1991": "us-ascii",
+	"isoir6":        "us-ascii",
+	"ibm367":        "us-ascii",
+	"cp367":         "us-ascii",
+	"csascii":       "us-ascii",
+	"646":           "us-ascii",
+
+	"iso88591":            "iso-8859-1",
+	"iso885911987":        "iso-8859-1",
+	"isoir100":            "iso-8859-1",
+	"latin1":              "iso-8859-1",
+	"l1":                  "iso-8859-1",
+	"ibm819":              "iso-8859-1",
+	"cp819":               "iso-8859-1",
+	"csisolatin1":         "iso-8859-1",
+	"88591":               "iso-8859-1",
+	"iso885915":           "iso-8859-15",
+	"iso8859151998":       "iso-8859-15",
+	"latin9":              "iso-8859-15",
+	"latin0":              "iso-8859-15",
+	"l9":                  "iso-8859-15",
+	"csiso885915":         "iso-8859-15",
+	"885915":              "iso-8859-15",
+	"windows1252":         "windows-1252",
+	"cp1252":              "windows-1252",
+	"cswindows1252":       "windows-1252",
+	"mswindows1252":       "windows-1252",
+	"ansi1252":            "windows-1252",
+	"ibm1252":             "windows-1252",
+	"windowslatin1":       "windows-1252",
+	"cp437":               "cp437",
+	"ibm437":              "cp437",
+	"437":                 "cp437",
+	"cspc8codepage437":    "cp437",
+	"cp850":               "cp850",
+	"ibm850":              "cp850",
+	"850":                 "cp850",
+	"cspc850multilingual": "cp850",
+}
@@ -22,6 +22,14 @@ func TestNormalizeEncoding(t *testing.T) {
 		{"charset=utf-8", "utf-8"},
 		{"csutf8", "utf-8"},
 		{"  UTF-8  ", "utf-8"},
+		{"latin1", "iso-8859-1"},
+		{"CSISOLatin1", "iso-8859-1"},
+		{"ISO_8859-15", "iso-8859-15"},
+		{"ANSI_X3.4-1968", "us-ascii"},
+		{"USASCII", "us-ascii"},
+		{"cp1252", "windows-1252"},
+		{"UCS-2", "ucs-2"},
+		{"Shift_JIS", "shift-jis"},
 	}
 
 	for _, tt := range tests {
@@ -83,30 +91,30 @@ func TestDetect(t *testing.T) {
 			name:     "override takes precedence",
 			override: "iso-8859-1",
 			lcAll:    "en_US.UTF-8",
-			expected: Info{Encoding: "iso-8859-1", IsUTF8: false, IsCOrPOSIX: false},
+			expected: Info{Encoding: "iso-8859-1", IsUTF8: false, IsCOrPOSIX: false, Decoder: Latin1, SingleByte: true},
 		},
 		{
 			name:     "LC_ALL takes precedence",
 			lcAll:    "en_US.UTF-8",
 			lcCtype:  "C",
 			lang:     "de_DE.ISO-8859-1",
-			expected: Info{Encoding: "utf-8", IsUTF8: true, IsCOrPOSIX: false},
+			expected: Info{Encoding: "utf-8", IsUTF8: true, IsCOrPOSIX: false, Language: "en", Territory: "US", Codeset: "UTF-8"},
 		},
 		{
 			name:     "LC_CTYPE when LC_ALL empty",
 			lcCtype:  "C",
 			lang:     "en_US.UTF-8",
-			expected: Info{Encoding: "C", IsUTF8: false, IsCOrPOSIX: true},
+			expected: Info{Encoding: "C", IsUTF8: false, IsCOrPOSIX: true, SingleByte: true, Language: "C"},
 		},
 		{
 			name:     "LANG when others empty",
 			lang:     "de_DE.ISO-8859-1",
-			expected: Info{Encoding: "iso-8859-1", IsUTF8: false, IsCOrPOSIX: false},
+			expected: Info{Encoding: "iso-8859-1", IsUTF8: false, IsCOrPOSIX: false, Decoder: Latin1, SingleByte: true, Language: "de", Territory: "DE", Codeset: "ISO-8859-1"},
 		},
 		{
 			name:     "POSIX locale",
 			lcAll:    "POSIX",
-			expected: Info{Encoding: "C", IsUTF8: false, IsCOrPOSIX: true},
+			expected: Info{Encoding: "C", IsUTF8: false, IsCOrPOSIX: true, SingleByte: true, Language: "POSIX"},
 		},
 		{
 			name:     "default when all empty",
@@ -127,4 +135,62 @@ func TestDetect(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected Info
+	}{
+		{"en_US.UTF-8@euro", Info{Encoding: "utf-8", IsUTF8: true, Language: "en", Territory: "US", Codeset: "UTF-8", Modifier: "euro"}},
+		{"sr_RS.UTF-8@latin", Info{Encoding: "utf-8", IsUTF8: true, Language: "sr", Territory: "RS", Codeset: "UTF-8", Modifier: "latin"}},
+		{"de_DE@euro", Info{Encoding: "iso-8859-15", Decoder: Latin9, SingleByte: true, Language: "de", Territory: "DE", Modifier: "euro"}},
+		{"ca_ES@valencia", Info{Encoding: "utf-8", IsUTF8: true, Language: "ca", Territory: "ES", Modifier: "valencia"}},
+		{"fr", Info{Encoding: "utf-8", IsUTF8: true, Language: "fr"}},
+		{"C.UTF-8", Info{Encoding: "utf-8", IsUTF8: true, Language: "C", Codeset: "UTF-8"}},
+		{"C", Info{Encoding: "C", IsCOrPOSIX: true, SingleByte: true, Language: "C"}},
+		{"pl_PL.ISO8859-2", Info{Encoding: "iso8859-2", IsCOrPOSIX: true, SingleByte: true, Language: "pl", Territory: "PL", Codeset: "ISO8859-2"}},
+		{"ru_RU.KOI8-R", Info{Encoding: "koi8-r", IsCOrPOSIX: true, SingleByte: true, Language: "ru", Territory: "RU", Codeset: "KOI8-R"}},
+		{"ja_JP.eucJP", Info{Encoding: "eucjp", Language: "ja", Territory: "JP", Codeset: "eucJP"}},
+		{"", Info{Encoding: "utf-8", IsUTF8: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.name); got != tt.expected {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.name, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDecoderFor(t *testing.T) {
+	for _, enc := range []string{"utf-8", "C", "us-ascii", "shift-jis"} {
+		if d := DecoderFor(enc); d != nil {
+			t.Errorf("DecoderFor(%q) = %T, want nil", enc, d)
+		}
+	}
+	in := []byte{0x80, 0xa4, 0x81}
+	want := map[Decoder][]rune{Latin1: {0x80, '¤', 0x81}, Latin9: {0x80, '€', 0x81}, Windows1252: {'€', '¤', -1}}
+	for _, enc := range []string{"iso-8859-1", "iso-8859-15", "windows-1252"} {
+		d := DecoderFor(enc)
+		for i, b := range in {
+			r, size, err := d.Decode([]byte{b})
+			if w := want[d][i]; size != 1 || (w < 0) != (err == ErrInvalid) || (w >= 0 && r != w) {
+				t.Errorf("%s: Decode(%#x) = %q, %d, %v; want %q", enc, b, r, size, err, w)
+			}
+		}
+	}
+	if info := Detect("ascii"); !info.IsCOrPOSIX || info.Encoding != "us-ascii" {
+		t.Errorf("Detect(ascii) = %+v", info)
+	}
+}
+
+func TestByteChars(t *testing.T) {
+	latin1 := Parse("de_DE.ISO-8859-1").ByteChars()
+	if !latin1.IsCOrPOSIX || latin1.Decoder != nil || latin1.Encoding != "iso-8859-1" {
+		t.Errorf("ISO-8859-1: got %+v, want bytes counted like the C locale", latin1)
+	}
+	if utf8 := Parse("en_US.UTF-8"); utf8.ByteChars() != utf8 {
+		t.Errorf("UTF-8: got %+v, want it unchanged", utf8.ByteChars())
+	}
+}
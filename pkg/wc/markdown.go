@@ -0,0 +1,310 @@
+package wc
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+)
+
+var (
+	// mdRefDef is a link reference definition: [label]: url "title".
+	mdRefDef = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s*\S`)
+	// mdBreak is a thematic break or the underline of a setext heading.
+	mdBreak = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,}|=+[ \t]*)$`)
+	// mdTableRule is the delimiter row under the header of a table.
+	mdTableRule = regexp.MustCompile(`^[ \t]*\|?(?:[ \t]*:?-+:?[ \t]*\|)+(?:[ \t]*:?-+:?[ \t]*)?$|^[ \t]*\|?[ \t]*:?-+:?[ \t]*\|[ \t]*$`)
+	// mdHeading, mdListItem and mdTask are the markers that begin a line.
+	mdHeading  = regexp.MustCompile(`^( {0,3})#{1,6}(?:[ \t]+|$)`)
+	mdListItem = regexp.MustCompile(`^([ \t]*)(?:[-*+]|[0-9]{1,9}[.)])(?:[ \t]+|$)`)
+	mdTask     = regexp.MustCompile(`^\[[ xX]\](?:[ \t]+|$)`)
+	// mdClosingHashes is the optional closing sequence of an ATX heading.
+	mdClosingHashes = regexp.MustCompile(`[ \t]+#+[ \t]*$`)
+)
+
+// markdownReader is the reader NewMarkdownReader returns.
+type markdownReader struct {
+	r     *bufio.Reader
+	out   []byte // prose of the last line, not yet read
+	first bool   // no line read yet
+	front string // closing line of the front matter being skipped
+	fence []byte // opening fence of the code block being skipped
+	err   error
+}
+
+// NewMarkdownReader returns a reader of the prose of the Markdown document
+// r, for an honest word count: front matter ("---" or "+++" delimited, on
+// the first line), fenced code blocks, link reference definitions,
+// thematic breaks and table delimiter rows are left out, lines and all;
+// heading, block quote, list and task markers and table pipes are
+// removed, as are inline code spans, autolinks and the URLs of links and
+// images, whose text is kept. Markup is recognized line by line, so an
+// inline code span or a link is only found within one line.
+func NewMarkdownReader(r io.Reader) io.Reader {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &markdownReader{r: br, first: true}
+}
+
+func (m *markdownReader) Read(p []byte) (int, error) {
+	for len(m.out) == 0 {
+		if m.err != nil {
+			return 0, m.err
+		}
+		line, err := m.r.ReadBytes('\n')
+		m.err = err
+		if len(line) > 0 {
+			m.out = m.prose(line)
+		}
+	}
+	n := copy(p, m.out)
+	m.out = m.out[n:]
+	return n, nil
+}
+
+// prose returns what is left of line, with its line ending, or nothing to
+// leave the whole line out.
+func (m *markdownReader) prose(line []byte) []byte {
+	text, eol := line, []byte(nil)
+	if i := bytes.IndexAny(line, "\r\n"); i >= 0 {
+		text, eol = line[:i], line[i:]
+	}
+	first := m.first
+	m.first = false
+	switch {
+	case m.front != "":
+		if string(bytes.TrimRight(text, " \t")) == m.front {
+			m.front = ""
+		}
+		return nil
+	case m.fence != nil:
+		if closesFence(text, m.fence) {
+			m.fence = nil
+		}
+		return nil
+	}
+	if first {
+		if t := string(bytes.TrimRight(text, " \t")); t == "---" || t == "+++" {
+			m.front = t
+			return nil
+		}
+	}
+	if fence := openingFence(text); fence != nil {
+		m.fence = fence
+		return nil
+	}
+	if mdRefDef.Match(text) {
+		return nil
+	}
+
+	text = stripQuote(text)
+	if mdBreak.Match(text) || mdTableRule.Match(text) && bytes.IndexByte(text, '|') >= 0 {
+		return nil
+	}
+	if loc := mdHeading.FindSubmatchIndex(text); loc != nil {
+		text = append(text[:loc[3]:loc[3]], text[loc[1]:]...)
+		text = mdClosingHashes.ReplaceAll(text, nil)
+	} else if loc := mdListItem.FindSubmatchIndex(text); loc != nil {
+		rest := text[loc[1]:]
+		if t := mdTask.FindIndex(rest); t != nil {
+			rest = rest[t[1]:]
+		}
+		text = append(text[:loc[3]:loc[3]], rest...)
+	}
+	return append(markdownInline(text), eol...)
+}
+
+// stripQuote removes the block quote markers that begin text.
+func stripQuote(text []byte) []byte {
+	for {
+		t := bytes.TrimLeft(text, " ")
+		if len(text)-len(t) > 3 || len(t) == 0 || t[0] != '>' {
+			return text
+		}
+		text = t[1:]
+		if len(text) > 0 && (text[0] == ' ' || text[0] == '\t') {
+			text = text[1:]
+		}
+	}
+}
+
+// openingFence returns the fence that opens a fenced code block on text,
+// three or more backticks or tildes indented by up to three spaces, or nil.
+func openingFence(text []byte) []byte {
+	t := bytes.TrimLeft(text, " ")
+	if len(text)-len(t) > 3 || len(t) < 3 || (t[0] != '`' && t[0] != '~') {
+		return nil
+	}
+	n := 0
+	for n < len(t) && t[n] == t[0] {
+		n++
+	}
+	if n < 3 || t[0] == '`' && bytes.IndexByte(t[n:], '`') >= 0 {
+		return nil
+	}
+	return t[:n:n]
+}
+
+// closesFence reports whether text closes the code block opened by fence:
+// a run of the same character, at least as long, alone on the line.
+func closesFence(text, fence []byte) bool {
+	t := bytes.TrimLeft(text, " ")
+	if len(text)-len(t) > 3 {
+		return false
+	}
+	n := 0
+	for n < len(t) && t[n] == fence[0] {
+		n++
+	}
+	return n >= len(fence) && len(bytes.TrimRight(t[n:], " \t")) == 0
+}
+
+// markdownInline returns text without its inline code spans, autolinks,
+// link and image URLs, backslashes that escape punctuation, and pipes
+// standing alone between table cells.
+func markdownInline(text []byte) []byte {
+	out := make([]byte, 0, len(text))
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && isASCIIPunct(text[i+1]):
+			out = append(out, text[i+1])
+			i += 2
+			continue
+		case c == '`':
+			n := runLength(text[i:], '`')
+			if end := closingRun(text[i+n:], n); end >= 0 {
+				i += n + end + n
+				continue
+			}
+			out = append(out, text[i:i+n]...)
+			i += n
+			continue
+		case c == '<':
+			if end := autolinkEnd(text[i:]); end > 0 {
+				i += end
+				continue
+			}
+		case c == '[' || c == '!' && i+1 < len(text) && text[i+1] == '[':
+			open := i
+			if c == '!' {
+				open++
+			}
+			if label, next := linkText(text, open); next > 0 {
+				out = append(out, markdownInline(label)...)
+				i = next
+				continue
+			}
+		case c == '|' && (i == 0 || isBlank(text[i-1])) && (i+1 == len(text) || isBlank(text[i+1])):
+			out = append(out, ' ')
+			i++
+			continue
+		}
+		out = append(out, c)
+		i++
+	}
+	return out
+}
+
+// linkText returns the text of the link whose "[" is text[open], and the
+// index just past the link, or 0 if no inline link ("[text](url)") or
+// full reference link ("[text][label]") starts there.
+func linkText(text []byte, open int) ([]byte, int) {
+	closeAt := matching(text, open, '[', ']')
+	if closeAt < 0 || closeAt+1 >= len(text) {
+		return nil, 0
+	}
+	var end int
+	switch text[closeAt+1] {
+	case '(':
+		end = matching(text, closeAt+1, '(', ')')
+	case '[':
+		end = matching(text, closeAt+1, '[', ']')
+	default:
+		return nil, 0
+	}
+	if end < 0 {
+		return nil, 0
+	}
+	return text[open+1 : closeAt], end + 1
+}
+
+// matching returns the index of the delimiter that closes the one at
+// text[i], skipping nested pairs and escaped characters, or -1.
+func matching(text []byte, i int, open, close byte) int {
+	depth := 0
+	for j := i; j < len(text); j++ {
+		switch text[j] {
+		case '\\':
+			j++
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// autolinkEnd returns the length of the autolink, "<scheme:...>" or
+// "<user@host>", that text begins with, or 0.
+func autolinkEnd(text []byte) int {
+	end := bytes.IndexByte(text, '>')
+	if end < 0 {
+		return 0
+	}
+	inner := text[1:end]
+	if len(inner) == 0 || bytes.ContainsAny(inner, " \t<") {
+		return 0
+	}
+	if colon := bytes.IndexByte(inner, ':'); colon >= 2 && isScheme(inner[:colon]) || bytes.IndexByte(inner, '@') > 0 {
+		return end + 1
+	}
+	return 0
+}
+
+func isScheme(s []byte) bool {
+	for i, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && (c >= '0' && c <= '9' || c == '+' || c == '.' || c == '-')) {
+			return false
+		}
+	}
+	return true
+}
+
+// runLength returns how many times c repeats at the start of text.
+func runLength(text []byte, c byte) int {
+	n := 0
+	for n < len(text) && text[n] == c {
+		n++
+	}
+	return n
+}
+
+// closingRun returns the index in text of a run of exactly n backticks,
+// which closes a code span opened by n backticks, or -1.
+func closingRun(text []byte, n int) int {
+	for i := 0; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		m := runLength(text[i:], '`')
+		if m == n {
+			return i
+		}
+		i += m
+	}
+	return -1
+}
+
+func isASCIIPunct(c byte) bool {
+	return c > ' ' && c < 0x7f && !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z')
+}
+
+func isBlank(c byte) bool { return c == ' ' || c == '\t' }
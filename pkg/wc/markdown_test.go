@@ -0,0 +1,60 @@
+package wc
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestMarkdownReader(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain", "Just some prose.\n", "Just some prose.\n"},
+		{"front matter", "---\ntitle: Post\ntags: [a, b]\n---\nBody text\n", "Body text\n"},
+		{"toml front matter", "+++\ntitle = \"Post\"\n+++\nBody\n", "Body\n"},
+		{"break is not front matter later", "Intro\n\n---\n\nMore\n", "Intro\n\n\nMore\n"},
+		{"fenced code", "Before\n```go\nfunc main() {}\n```\nAfter\n", "Before\nAfter\n"},
+		{"tilde fence", "~~~~\ncode\n~~~\nstill code\n~~~~\nprose\n", "prose\n"},
+		{"unclosed fence", "a\n```\ncode\n", "a\n"},
+		{"headings", "# Title #\n## Sub\nSetext\n======\n", "Title\nSub\nSetext\n"},
+		{"lists", "- one\n* two\n3. three\n- [x] done\n  - [ ] nested\n", "one\ntwo\nthree\ndone\n  nested\n"},
+		{"quotes", "> quoted\n> > twice\n", "quoted\ntwice\n"},
+		{"links", "See [the docs](https://example.com/a_b \"Docs\") and ![a logo](logo.png).\n", "See the docs and a logo.\n"},
+		{"reference links", "Read [this][ref].\n\n[ref]: https://example.com\n", "Read this.\n\n"},
+		{"autolinks", "Mail <me@example.com> or <https://example.com>.\n", "Mail  or .\n"},
+		{"inline code", "Run `go test ./...` or ``a ` b`` now\n", "Run  or  now\n"},
+		{"unmatched backtick", "a ` b\n", "a ` b\n"},
+		{"escapes", `\*not emphasis\* and \[not a link\](x)` + "\n", "*not emphasis* and [not a link](x)\n"},
+		{"table", "| Name | Count |\n|------|------:|\n| a | 1 |\n", "  Name   Count  \n  a   1  \n"},
+		{"crlf", "# Title\r\n```\r\ncode\r\n```\r\ntext\r\n", "Title\r\ntext\r\n"},
+		{"no final newline", "- last item", "last item"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := io.ReadAll(iotest.OneByteReader(NewMarkdownReader(strings.NewReader(tt.in))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountStripMarkdown(t *testing.T) {
+	in := "---\ntitle: Counting\n---\n# Counting words\n\nUse `go_wc -w` on [the file](README.md):\n\n```sh\ngo_wc -w README.md\n```\n"
+	m := Metrics{Lines: true, Words: true, Bytes: true}
+	got := CountBytes([]byte(in), m, Options{Strip: StripMarkdown})
+	want := "Counting words\n\nUse  on the file:\n\n"
+	if got.Lines != 4 || got.Words != 6 || got.Bytes != uint64(len(want)) {
+		t.Errorf("got %d lines, %d words, %d bytes; want 4, 6, %d", got.Lines, got.Words, got.Bytes, len(want))
+	}
+
+	got = CountBytes([]byte(in), m, Options{Strip: StripMarkdown, StopAfterLines: 1})
+	if got.Lines != 1 || got.Words != 2 || !got.Truncated {
+		t.Errorf("StopAfterLines: got %d lines, %d words, truncated %v", got.Lines, got.Words, got.Truncated)
+	}
+}
@@ -0,0 +1,255 @@
+package wc
+
+import (
+	"bytes"
+	"regexp"
+)
+
+func init() {
+	registerMetric(&accMetric[[]uint64]{
+		name:  "strings",
+		on:    func(_ Metrics, opt Options) bool { return len(opt.CountStrings) > 0 },
+		new:   func(_ Metrics, opt Options, _ bool) Accumulator[[]uint64] { return newStringsAcc(opt.CountStrings) },
+		store: func(r *FileResult, v []uint64) { r.StringCounts = v },
+	})
+	registerMetric(&accMetric[lineMatches]{
+		name: "line_matches",
+		on:   func(_ Metrics, opt Options) bool { return opt.MatchLines != nil || len(opt.CountRegexps) > 0 },
+		new: func(_ Metrics, opt Options, _ bool) Accumulator[lineMatches] {
+			return &lineMatchAcc{lm: newLineMatcher(opt.MatchLines, opt.CountRegexps, opt.MaxMatchLength)}
+		},
+		store: func(r *FileResult, v lineMatches) {
+			r.MatchingLines, r.NonMatchingLines, r.RegexpCounts = v.Matched, v.Unmatched, v.Counts
+		},
+	})
+}
+
+// stringsAcc counts Options.CountStrings with a stringMatcher each, or a
+// multiMatcher for many. Merge adds up the counts of both sides as they
+// are: occurrences spanning the boundary are not recovered.
+type stringsAcc struct {
+	Counts   []uint64 // of merged accumulators, which have no matchers
+	matchers []*stringMatcher
+	multi    *multiMatcher
+}
+
+func newStringsAcc(pats []string) *stringsAcc {
+	a := new(stringsAcc)
+	if len(pats) >= multiMatcherMin {
+		a.multi = newMultiMatcher(pats)
+		return a
+	}
+	for _, s := range pats {
+		a.matchers = append(a.matchers, newStringMatcher(s))
+	}
+	return a
+}
+
+func (a *stringsAcc) Feed(p []byte) {
+	for _, sm := range a.matchers {
+		sm.write(p)
+	}
+	if a.multi != nil {
+		a.multi.write(p)
+	}
+}
+
+func (a *stringsAcc) Merge(next Accumulator[[]uint64]) Accumulator[[]uint64] {
+	return &stringsAcc{Counts: addCounts(a.Result(), next.Result())}
+}
+
+// Result returns the occurrences found so far of each string.
+func (a *stringsAcc) Result() []uint64 {
+	if a.multi != nil {
+		return addCounts(nil, a.multi.counts)
+	}
+	if len(a.matchers) == 0 {
+		return addCounts(nil, a.Counts)
+	}
+	out := make([]uint64, len(a.matchers))
+	for i, sm := range a.matchers {
+		out[i] = sm.count
+	}
+	return out
+}
+
+// stringMatcher counts non-overlapping occurrences of a literal byte string
+// in a stream fed in arbitrary pieces. Matches spanning two writes are found
+// by keeping the last len(pat)-1 unmatched bytes of each write.
+type stringMatcher struct {
+	pat   []byte
+	carry []byte
+	count uint64
+}
+
+func newStringMatcher(s string) *stringMatcher {
+	return &stringMatcher{pat: []byte(s), carry: make([]byte, 0, 2*len(s))}
+}
+
+func (s *stringMatcher) write(p []byte) {
+	k := len(s.pat)
+	if k == 0 {
+		return
+	}
+	start := 0
+	if len(s.carry) > 0 {
+		// look for matches beginning in the carried bytes
+		n := min(len(p), k-1)
+		win := append(s.carry, p[:n]...)
+		off := 0
+		for {
+			i := bytes.Index(win[off:], s.pat)
+			if i < 0 || off+i >= len(s.carry) {
+				break
+			}
+			s.count++
+			off += i + k
+		}
+		if n == len(p) {
+			s.keep(win, off)
+			return
+		}
+		start = max(0, off-len(s.carry))
+	}
+	end := start
+	for {
+		i := bytes.Index(p[end:], s.pat)
+		if i < 0 {
+			break
+		}
+		s.count++
+		end += i + k
+	}
+	s.keep(p, end)
+}
+
+// keep carries the tail of b after position from, at most len(pat)-1 bytes.
+func (s *stringMatcher) keep(b []byte, from int) {
+	if tail := len(b) - (len(s.pat) - 1); from < tail {
+		from = tail
+	}
+	s.carry = append(s.carry[:0], b[from:]...)
+}
+
+// lineMatcher tests every line against regular expressions: it counts the
+// lines re matches and those it does not, like grep -c and grep -vc, and
+// the non-overlapping matches of each of count. Matches cannot span lines.
+// Each line is tested without its newline; an unterminated last line
+// counts too, but is only tested once the input ends. Lines are fed
+// through a matchWindow, so a long line is not held in memory whole.
+type lineMatcher struct {
+	re                 *windowRegexp // Options.MatchLines, or nil
+	matched, unmatched uint64
+	hit                bool           // re matched the current line
+	count              []windowRegexp // Options.CountRegexps
+	counts             []uint64
+	next               []uint64 // where the next match of each may start in the line
+	win                matchWindow
+}
+
+// lineMatches are the counts of a lineMatcher: the lines MatchLines
+// matches and those it does not, and the matches of each of CountRegexps.
+type lineMatches struct {
+	Matched, Unmatched uint64
+	Counts             []uint64
+}
+
+// lineMatchAcc tests lines with a lineMatcher. Like stringsAcc, Merge adds
+// up the counts of both sides, the lines cut at the boundary tested in
+// their parts.
+type lineMatchAcc struct {
+	lineMatches // of merged accumulators, which have no lineMatcher
+	lm          *lineMatcher
+}
+
+func (a *lineMatchAcc) Feed(p []byte) { a.lm.write(p) }
+
+func (a *lineMatchAcc) Merge(next Accumulator[lineMatches]) Accumulator[lineMatches] {
+	x, y := a.Result(), next.Result()
+	return &lineMatchAcc{lineMatches: lineMatches{x.Matched + y.Matched, x.Unmatched + y.Unmatched, addCounts(x.Counts, y.Counts)}}
+}
+
+// Result returns the counts so far, testing the current line as if the
+// input ended here.
+func (a *lineMatchAcc) Result() lineMatches {
+	if a.lm == nil {
+		return lineMatches{a.Matched, a.Unmatched, addCounts(nil, a.Counts)}
+	}
+	last := a.lm.result()
+	return lineMatches{last.matched, last.unmatched, last.counts}
+}
+
+// newLineMatcher returns a lineMatcher for re and count, or nil when there
+// is nothing to test.
+func newLineMatcher(re *regexp.Regexp, count []*regexp.Regexp, maxMatch int) *lineMatcher {
+	if re == nil && len(count) == 0 {
+		return nil
+	}
+	lm := &lineMatcher{
+		counts: make([]uint64, len(count)),
+		next:   make([]uint64, len(count)),
+		win:    newMatchWindow(maxMatch),
+	}
+	if re != nil {
+		wr := newWindowRegexp(re)
+		lm.re = &wr
+	}
+	for _, re := range count {
+		lm.count = append(lm.count, newWindowRegexp(re))
+	}
+	return lm
+}
+
+func (lm *lineMatcher) write(p []byte) {
+	lm.win.write(p, lm)
+}
+
+func (lm *lineMatcher) scan(win []byte, lo, hi int, off uint64, last bool) {
+	if lm.re != nil && !lm.hit {
+		if lo == 0 && hi == len(win) {
+			lm.hit = lm.re.Match(win)
+		} else {
+			lm.hit = len(lm.re.findFrom(win, lo, hi, 1)) > 0
+		}
+	}
+	for i, re := range lm.count {
+		from := lo
+		if n := lm.next[i]; n > off+uint64(from) {
+			from = int(min(n-off, uint64(len(win))))
+		}
+		for _, m := range re.findFrom(win, from, hi, -1) {
+			lm.counts[i]++
+			lm.next[i] = off + uint64(m[1])
+			if m[0] == m[1] {
+				lm.next[i]++ // as FindAllIndex moves past an empty match
+			}
+		}
+	}
+	if !last {
+		return
+	}
+	if lm.re != nil {
+		if lm.hit {
+			lm.matched++
+		} else {
+			lm.unmatched++
+		}
+	}
+	lm.hit = false
+	clear(lm.next)
+}
+
+// result returns the counts so far, testing the current line as if the
+// input ended here. A nil lineMatcher has nothing to count.
+func (lm *lineMatcher) result() lineMatcher {
+	if lm == nil {
+		return lineMatcher{}
+	}
+	last := *lm
+	last.counts = addCounts(nil, lm.counts)
+	if lm.win.pending() {
+		last.next = append([]uint64(nil), lm.next...)
+		last.win.endLine(&last)
+	}
+	return last
+}
@@ -0,0 +1,95 @@
+package wc
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestStringMatcherAcrossWrites(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	alphabet := []byte("ab\nE")
+	for iter := 0; iter < 2000; iter++ {
+		data := make([]byte, rng.Intn(60))
+		for i := range data {
+			data[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		pat := make([]byte, 1+rng.Intn(4))
+		for i := range pat {
+			pat[i] = alphabet[rng.Intn(2)]
+		}
+		want := uint64(bytes.Count(data, pat))
+
+		sm := newStringMatcher(string(pat))
+		for rest := data; len(rest) > 0; {
+			n := 1 + rng.Intn(len(rest))
+			sm.write(rest[:n])
+			rest = rest[n:]
+		}
+		if sm.count != want {
+			t.Fatalf("count of %q in %q: got %d, want %d", pat, data, sm.count, want)
+		}
+	}
+}
+
+func TestCountStrings(t *testing.T) {
+	data := []byte("INFO ok\nERROR one\nERRORERROR\nERR\nOR\n")
+	opts := Options{BufferSize: 4, CountStrings: []string{"ERROR", "\n", "RR"}}
+	got := CountBytes(data, Metrics{Lines: true}, opts)
+	if want := []uint64{3, 5, 4}; !reflect.DeepEqual(got.StringCounts, want) {
+		t.Errorf("StringCounts: got %v, want %v", got.StringCounts, want)
+	}
+}
+
+func TestMatchLines(t *testing.T) {
+	re := regexp.MustCompile(`^ERR|ok$`)
+	tests := []struct {
+		in                 string
+		matched, unmatched uint64
+	}{
+		{"", 0, 0},
+		{"\n", 0, 1},
+		{"INFO ok\nERROR one\nwarn\n", 2, 1},
+		{"INFO ok\nERROR one\nwarn", 2, 1},
+		{"ERROR\r\nok\r\n", 1, 1}, // "ok\r" does not end in ok
+		{"x\n\nERR", 1, 2},
+	}
+	for _, tt := range tests {
+		for _, bufSize := range []int{1, 3, 64} {
+			got := CountBytes([]byte(tt.in), Metrics{MatchLines: true}, Options{BufferSize: bufSize, MatchLines: re})
+			if got.MatchingLines != tt.matched || got.NonMatchingLines != tt.unmatched {
+				t.Errorf("%q (buffer %d): got %d/%d matching/non-matching lines, want %d/%d",
+					tt.in, bufSize, got.MatchingLines, got.NonMatchingLines, tt.matched, tt.unmatched)
+			}
+		}
+	}
+
+	// Result tests the unterminated line without consuming it
+	c := NewCounter(Metrics{MatchLines: true}, Options{MatchLines: re})
+	_, _ = c.Write([]byte("ok\nER"))
+	if r := c.Result(); r.MatchingLines != 1 || r.NonMatchingLines != 1 {
+		t.Errorf("mid-line Result: got %d/%d", r.MatchingLines, r.NonMatchingLines)
+	}
+	_, _ = c.Write([]byte("R\n"))
+	if r := c.Result(); r.MatchingLines != 2 || r.NonMatchingLines != 0 {
+		t.Errorf("final Result: got %d/%d", r.MatchingLines, r.NonMatchingLines)
+	}
+}
+
+func TestCountRegexps(t *testing.T) {
+	data := []byte("ERROR a time=12ms time=3ms\nWARN\ntime=\ntime=7ms")
+	opts := Options{CountRegexps: []*regexp.Regexp{regexp.MustCompile(`time=\d+ms`), regexp.MustCompile(`^\w+$`)}}
+	want := []uint64{3, 1}
+	for _, bufSize := range []int{1, 5, 64} {
+		opts.BufferSize = bufSize
+		if got := CountBytes(data, Metrics{Lines: true}, opts); !reflect.DeepEqual(got.RegexpCounts, want) {
+			t.Errorf("buffer %d: RegexpCounts = %v, want %v", bufSize, got.RegexpCounts, want)
+		}
+	}
+	total := Sum([]FileResult{CountBytes(data, Metrics{}, opts), CountBytes(data[27:], Metrics{}, opts)})
+	if want := []uint64{4, 2}; !reflect.DeepEqual(total.RegexpCounts, want) {
+		t.Errorf("Sum: RegexpCounts = %v, want %v", total.RegexpCounts, want)
+	}
+}
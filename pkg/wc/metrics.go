@@ -0,0 +1,93 @@
+package wc
+
+import "fmt"
+
+// metricFields lists every counter in output order together with its name
+// and a pointer accessor, so presets and name parsing stay in sync with
+// the Metrics struct.
+var metricFields = []struct {
+	name  string
+	field func(*Metrics) *bool
+}{
+	{"lines", func(m *Metrics) *bool { return &m.Lines }},
+	{"words", func(m *Metrics) *bool { return &m.Words }},
+	{"chars", func(m *Metrics) *bool { return &m.Chars }},
+	{"bytes", func(m *Metrics) *bool { return &m.Bytes }},
+	{"max-line-bytes", func(m *Metrics) *bool { return &m.MaxLineBytes }},
+	{"max-line-chars", func(m *Metrics) *bool { return &m.MaxLineChars }},
+	{"whitespace-lines", func(m *Metrics) *bool { return &m.WhitespaceLines }},
+	{"line-endings", func(m *Metrics) *bool { return &m.LineEndings }},
+	{"no-final-newline", func(m *Metrics) *bool { return &m.NoFinalNewline }},
+	{"word-lengths", func(m *Metrics) *bool { return &m.WordLengths }},
+	{"words-per-line", func(m *Metrics) *bool { return &m.WordsPerLine }},
+	{"emoji", func(m *Metrics) *bool { return &m.Emoji }},
+	{"token-stats", func(m *Metrics) *bool { return &m.TokenStats }},
+	{"word-kinds", func(m *Metrics) *bool { return &m.WordKinds }},
+	{"code-tokens", func(m *Metrics) *bool { return &m.CodeTokens }},
+	{"longest-word", func(m *Metrics) *bool { return &m.LongestWord }},
+	{"unique-words", func(m *Metrics) *bool { return &m.UniqueWords }},
+}
+
+// metricAliases maps alternative spellings to canonical metric names.
+var metricAliases = map[string]string{
+	"newlines":        "lines",
+	"characters":      "chars",
+	"max-line-length": "max-line-bytes",
+}
+
+// AllMetrics returns Metrics with every counter enabled.
+func AllMetrics() Metrics {
+	var m Metrics
+	for _, f := range metricFields {
+		*f.field(&m) = true
+	}
+	return m
+}
+
+// DefaultMetrics returns the wc default selection: lines, words and bytes.
+func DefaultMetrics() Metrics {
+	return Metrics{Lines: true, Words: true, Bytes: true}
+}
+
+// MetricsFromStrings builds Metrics from counter names such as "lines" or
+// "max-line-chars". The name "all" enables every counter.
+func MetricsFromStrings(names []string) (Metrics, error) {
+	var m Metrics
+	for _, n := range names {
+		if n == "all" {
+			m = AllMetrics()
+			continue
+		}
+		if alias, ok := metricAliases[n]; ok {
+			n = alias
+		}
+		found := false
+		for _, f := range metricFields {
+			if f.name == n {
+				*f.field(&m) = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return Metrics{}, fmt.Errorf("unknown metric %q", n)
+		}
+	}
+	return m, nil
+}
+
+// Names returns the enabled counters in output order.
+func (m Metrics) Names() []string {
+	out := make([]string, 0, len(metricFields))
+	for _, f := range metricFields {
+		if *f.field(&m) {
+			out = append(out, f.name)
+		}
+	}
+	return out
+}
+
+// IsZero reports whether no counter is enabled.
+func (m Metrics) IsZero() bool {
+	return m == Metrics{}
+}
@@ -0,0 +1,52 @@
+package wc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMetricsPresets(t *testing.T) {
+	all := AllMetrics()
+	want := Metrics{Lines: true, Words: true, Bytes: true, Chars: true, MaxLineBytes: true, MaxLineChars: true, WhitespaceLines: true, LineEndings: true, NoFinalNewline: true, WordLengths: true, LongestWord: true, WordsPerLine: true, Emoji: true, TokenStats: true, WordKinds: true, UniqueWords: true, CodeTokens: true}
+	if all != want {
+		t.Errorf("AllMetrics: got %+v, want %+v", all, want)
+	}
+	if d := DefaultMetrics(); d != (Metrics{Lines: true, Words: true, Bytes: true}) {
+		t.Errorf("DefaultMetrics: got %+v", d)
+	}
+	if !(Metrics{}).IsZero() || DefaultMetrics().IsZero() {
+		t.Error("IsZero mismatch")
+	}
+}
+
+func TestMetricsFromStrings(t *testing.T) {
+	tests := []struct {
+		names   []string
+		want    Metrics
+		wantErr bool
+	}{
+		{names: nil, want: Metrics{}},
+		{names: []string{"lines", "bytes"}, want: Metrics{Lines: true, Bytes: true}},
+		{names: []string{"max-line-length", "characters"}, want: Metrics{MaxLineBytes: true, Chars: true}},
+		{names: []string{"all"}, want: AllMetrics()},
+		{names: []string{"bogus"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := MetricsFromStrings(tt.names)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("MetricsFromStrings(%v) error = %v", tt.names, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("MetricsFromStrings(%v) = %+v, want %+v", tt.names, got, tt.want)
+		}
+	}
+}
+
+func TestMetricsNames(t *testing.T) {
+	got := Metrics{Bytes: true, Lines: true, MaxLineChars: true}.Names()
+	want := []string{"lines", "bytes", "max-line-chars"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Names: got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+package wc
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// NGram is a sequence of consecutive words, joined by single spaces, and
+// the number of times it occurred.
+type NGram struct {
+	Text  string `json:"ngram"`
+	Count uint64 `json:"count"`
+}
+
+// TopNGrams returns the k most frequent n-grams of counts, most frequent
+// first and ties in lexical order; k <= 0 returns all of them.
+func TopNGrams(counts map[string]uint64, k int) []NGram {
+	out := make([]NGram, 0, len(counts))
+	for text, n := range counts {
+		out = append(out, NGram{Text: text, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Text < out[j].Text
+	})
+	if k > 0 && len(out) > k {
+		out = out[:k]
+	}
+	return out
+}
+
+func init() {
+	registerMetric(&accMetric[map[string]uint64]{
+		name: "ngrams",
+		on:   func(_ Metrics, opt Options) bool { return opt.NGrams > 0 },
+		newText: func(_ Metrics, opt Options, chunk bool) TextAccumulator[map[string]uint64] {
+			return &ngramAcc{wordScan: newWordScan(opt, chunk, true), opt: opt}
+		},
+		store: func(r *FileResult, v map[string]uint64) { r.NGrams = v },
+	})
+}
+
+// ngramAcc counts the n-grams of Options.NGrams. Merge adds up the n-grams
+// of both sides but cannot recover those spanning the boundary, which is
+// why chunk counters leave NGrams out.
+type ngramAcc struct {
+	wordScan
+	noWordEvents
+	NGrams map[string]uint64
+	Window []string // the last words, up to Options.NGrams of them
+	opt    Options
+}
+
+func (a *ngramAcc) FeedText(t *Text) { a.feed(t, a) }
+
+// end appends a complete word to the window, counting the n-gram it
+// completes unless that holds a stopword, which is kept in the window as
+// "".
+func (a *ngramAcc) end(w wordPart) {
+	n := a.opt.NGrams
+	if n <= 0 || len(w.Text) == 0 {
+		return
+	}
+	text := ""
+	if !a.opt.StopWords.contains(w.Text) {
+		text = string(normalizeWord(w.Text, a.opt.UniqueFold, a.opt.Locale.IsCOrPOSIX, a.opt.Stem))
+	}
+	if len(a.Window) == n {
+		a.Window = append(a.Window[:0], a.Window[1:]...)
+	}
+	a.Window = append(a.Window, text)
+	if len(a.Window) == n && !slices.Contains(a.Window, "") {
+		if a.NGrams == nil {
+			a.NGrams = make(map[string]uint64)
+		}
+		a.NGrams[strings.Join(a.Window, " ")]++
+	}
+}
+
+func (a *ngramAcc) Merge(next TextAccumulator[map[string]uint64]) TextAccumulator[map[string]uint64] {
+	b := next.(*ngramAcc)
+	out := *b
+	if a.Started {
+		out.opt = a.opt
+	}
+	out.NGrams = addNGrams(maps.Clone(a.NGrams), b.NGrams)
+	out.Window = slices.Clone(b.Window)
+	out.wordScan, _ = a.wordScan.merge(&b.wordScan, nil)
+	return &out
+}
+
+func (a *ngramAcc) Result() map[string]uint64 {
+	last := *a
+	last.NGrams = maps.Clone(a.NGrams)
+	last.Window = slices.Clone(a.Window)
+	_, tail := a.edges() // no head: chunk counters leave NGrams out
+	last.end(tail)
+	return last.NGrams
+}
+
+// addNGrams adds the counts of b to a, allocating a when nil.
+func addNGrams(a, b map[string]uint64) map[string]uint64 {
+	if len(b) == 0 {
+		return a
+	}
+	if a == nil {
+		return maps.Clone(b)
+	}
+	for text, n := range b {
+		a[text] += n
+	}
+	return a
+}
@@ -0,0 +1,46 @@
+package wc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+func TestNGrams(t *testing.T) {
+	in := []byte("the cat and The cat\nsat on the mat")
+	for _, bufSize := range []int{1, 5, 64} {
+		opt := Options{BufferSize: bufSize, Locale: locale.Info{IsUTF8: true}, NGrams: 2, UniqueFold: true}
+		got := CountBytes(in, Metrics{Lines: true}, opt)
+		want := map[string]uint64{"the cat": 2, "cat and": 1, "and the": 1, "cat sat": 1, "sat on": 1, "on the": 1, "the mat": 1}
+		if !reflect.DeepEqual(got.NGrams, want) {
+			t.Errorf("buffer %d: got %v, want %v", bufSize, got.NGrams, want)
+		}
+	}
+
+	got := CountBytes([]byte("a b a"), Metrics{}, Options{NGrams: 1})
+	if want := map[string]uint64{"a": 2, "b": 1}; !reflect.DeepEqual(got.NGrams, want) {
+		t.Errorf("unigrams: got %v, want %v", got.NGrams, want)
+	}
+	if got := CountBytes([]byte("one two"), Metrics{}, Options{NGrams: 3}); got.NGrams != nil {
+		t.Errorf("too few words: got %v", got.NGrams)
+	}
+}
+
+func TestTopNGrams(t *testing.T) {
+	counts := map[string]uint64{"b c": 2, "a b": 2, "c d": 5, "d e": 1}
+	want := []NGram{{"c d", 5}, {"a b", 2}, {"b c", 2}}
+	if got := TopNGrams(counts, 3); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := TopNGrams(counts, 0); len(got) != 4 {
+		t.Errorf("k=0: got %d n-grams, want 4", len(got))
+	}
+
+	var tot Totals
+	tot.Add(FileResult{NGrams: map[string]uint64{"a b": 1}})
+	tot.Add(FileResult{NGrams: map[string]uint64{"a b": 2, "b c": 1}})
+	if want := map[string]uint64{"a b": 3, "b c": 1}; !reflect.DeepEqual(tot.Result().NGrams, want) {
+		t.Errorf("totals: got %v, want %v", tot.Result().NGrams, want)
+	}
+}
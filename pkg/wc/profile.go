@@ -0,0 +1,40 @@
+package wc
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Profile accumulates where counting spends its time, summed over the
+// counters that share it through Options.Profile, so that a caller can
+// tell an I/O-bound scan from a CPU-bound one. Counters running
+// concurrently add to it at once, so the sums may exceed the wall time.
+// The zero value is ready to use.
+type Profile struct {
+	readWait atomic.Int64 // nanoseconds
+	decode   atomic.Int64
+	scan     atomic.Int64
+}
+
+// ReadWait returns the time spent opening inputs and waiting for reads.
+func (p *Profile) ReadWait() time.Duration { return time.Duration(p.readWait.Load()) }
+
+// AddReadWait adds d to ReadWait, for callers that read the input
+// themselves and feed it to a Counter.
+func (p *Profile) AddReadWait(d time.Duration) { p.readWait.Add(int64(d)) }
+
+// Decode returns the time spent on input with multibyte characters, which
+// are decoded and scanned for words in a single pass.
+func (p *Profile) Decode() time.Duration { return time.Duration(p.decode.Load()) }
+
+// Scan returns the time spent scanning single-byte input for lines and
+// words, and matching strings, patterns and line endings.
+func (p *Profile) Scan() time.Duration { return time.Duration(p.scan.Load()) }
+
+// since adds the time from start to now to the phase d and returns now, the
+// start of the next phase.
+func since(d *atomic.Int64, start time.Time) time.Time {
+	now := time.Now()
+	d.Add(int64(now.Sub(start)))
+	return now
+}
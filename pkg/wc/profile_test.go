@@ -0,0 +1,38 @@
+package wc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+func TestProfile(t *testing.T) {
+	var p Profile
+	opts := Options{BufferSize: 64, Locale: locale.Info{IsUTF8: true}, Profile: &p}
+	ascii := strings.Repeat("hello world\n", 5000)
+	CountBytes([]byte(ascii), DefaultMetrics(), opts)
+	if p.Scan() <= 0 || p.Decode() != 0 || p.ReadWait() <= 0 {
+		t.Errorf("ASCII input: scan %v, decode %v, read wait %v; want scan and read wait only", p.Scan(), p.Decode(), p.ReadWait())
+	}
+	CountBytes([]byte(strings.Repeat("héllo wörld\n", 5000)), DefaultMetrics(), opts)
+	if p.Decode() <= 0 {
+		t.Errorf("multibyte input: decode %v, want > 0", p.Decode())
+	}
+
+	var fp Profile
+	name := filepath.Join(t.TempDir(), "f.txt")
+	if err := os.WriteFile(name, []byte(ascii), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res := CountFile(context.Background(), name, DefaultMetrics(), Options{Locale: locale.Info{IsUTF8: true}, Profile: &fp})
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if fp.ReadWait() <= 0 || fp.ReadWait()+fp.Scan() > res.Duration {
+		t.Errorf("read wait %v and scan %v do not fit in the file's %v", fp.ReadWait(), fp.Scan(), res.Duration)
+	}
+}
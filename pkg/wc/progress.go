@@ -0,0 +1,18 @@
+package wc
+
+import "sync/atomic"
+
+// Progress accumulates how much input has been counted so far, for
+// reporting on a scan while it runs. Counters sharing a Progress through
+// Options.Progress add to it as they go, so it may be read and updated
+// concurrently. The zero value is ready to use.
+type Progress struct {
+	bytes atomic.Uint64
+	lines atomic.Uint64
+}
+
+// Bytes returns the bytes counted so far.
+func (p *Progress) Bytes() uint64 { return p.bytes.Load() }
+
+// Lines returns the newlines counted so far.
+func (p *Progress) Lines() uint64 { return p.lines.Load() }
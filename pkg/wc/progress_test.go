@@ -0,0 +1,26 @@
+package wc
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	var p Progress
+	opts := Options{BufferSize: 7, Progress: &p}
+	data := []byte(strings.Repeat("héllo wörld\n", 50))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Metrics without Lines still report lines to Progress
+			CountBytes(data, Metrics{Bytes: true}, opts)
+		}()
+	}
+	wg.Wait()
+	if p.Bytes() != 4*uint64(len(data)) || p.Lines() != 4*50 {
+		t.Errorf("got %d bytes and %d lines, want %d and %d", p.Bytes(), p.Lines(), 4*len(data), 4*50)
+	}
+}
@@ -0,0 +1,139 @@
+package wc
+
+import (
+	"maps"
+	"sort"
+	"sync"
+	"unicode"
+)
+
+// ScriptUnknown is the script of code points no script claims, and of bytes
+// above ASCII in the C locale.
+const ScriptUnknown = "Unknown"
+
+// ScriptCount is the number of characters of one Unicode script.
+type ScriptCount struct {
+	Script string `json:"script"`
+	Chars  uint64 `json:"chars"`
+}
+
+// SortScripts returns the script counts of Options.Scripts, most frequent
+// first and ties in name order.
+func SortScripts(counts map[string]uint64) []ScriptCount {
+	out := make([]ScriptCount, 0, len(counts))
+	for s, n := range counts {
+		out = append(out, ScriptCount{Script: s, Chars: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Chars != out[j].Chars {
+			return out[i].Chars > out[j].Chars
+		}
+		return out[i].Script < out[j].Script
+	})
+	return out
+}
+
+type scriptTable struct {
+	name  string
+	table *unicode.RangeTable
+}
+
+// scriptTables lists unicode.Scripts in a fixed order, the scripts most
+// text is written in first, so lookups of other runes stop early.
+var scriptTables = sync.OnceValue(func() []scriptTable {
+	first := []string{"Latin", "Common", "Inherited", "Cyrillic", "Greek", "Han", "Arabic", "Hebrew", "Devanagari", "Hiragana", "Katakana", "Hangul", "Thai"}
+	seen := make(map[string]bool, len(first))
+	out := make([]scriptTable, 0, len(unicode.Scripts))
+	for _, name := range first {
+		out = append(out, scriptTable{name, unicode.Scripts[name]})
+		seen[name] = true
+	}
+	rest := make([]string, 0, len(unicode.Scripts))
+	for name := range unicode.Scripts {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	for _, name := range rest {
+		out = append(out, scriptTable{name, unicode.Scripts[name]})
+	}
+	return out
+})
+
+func init() {
+	registerMetric(&accMetric[map[string]uint64]{
+		name:    "scripts",
+		on:      func(_ Metrics, opt Options) bool { return opt.Scripts },
+		newText: func(Metrics, Options, bool) TextAccumulator[map[string]uint64] { return new(scriptsAcc) },
+		store:   func(r *FileResult, v map[string]uint64) { r.Scripts = v },
+	})
+}
+
+// scriptsAcc counts the characters of each script for Options.Scripts.
+// Invalid bytes belong to none.
+type scriptsAcc struct {
+	Scripts map[string]uint64
+	last    scriptTable
+}
+
+func (a *scriptsAcc) FeedText(t *Text) {
+	if t.Bytes != nil {
+		a.ascii(t.Bytes)
+		return
+	}
+	for _, c := range t.Chars {
+		if !c.Invalid {
+			a.note(c.Rune)
+		}
+	}
+}
+
+// note counts r under its script. Scripts come in runs, so the script of
+// the previous rune is tried first.
+func (a *scriptsAcc) note(r rune) {
+	if a.last.table == nil || !unicode.Is(a.last.table, r) {
+		a.last = scriptTable{name: ScriptUnknown}
+		for _, st := range scriptTables() {
+			if unicode.Is(st.table, r) {
+				a.last = st
+				break
+			}
+		}
+	}
+	a.add(a.last.name, 1)
+}
+
+// ascii counts characters of one byte: letters are Latin and the other
+// ASCII characters Common.
+func (a *scriptsAcc) ascii(p []byte) {
+	var latin, other uint64
+	for _, b := range p {
+		switch {
+		case b|0x20 >= 'a' && b|0x20 <= 'z':
+			latin++
+		case b >= 0x80:
+			other++ // C locale only
+		}
+	}
+	a.add("Latin", latin)
+	a.add(ScriptUnknown, other)
+	a.add("Common", uint64(len(p))-latin-other)
+}
+
+func (a *scriptsAcc) add(name string, n uint64) {
+	if n == 0 {
+		return
+	}
+	if a.Scripts == nil {
+		a.Scripts = make(map[string]uint64)
+	}
+	a.Scripts[name] += n
+}
+
+func (a *scriptsAcc) Merge(next TextAccumulator[map[string]uint64]) TextAccumulator[map[string]uint64] {
+	b := next.(*scriptsAcc)
+	return &scriptsAcc{Scripts: addNGrams(maps.Clone(a.Scripts), b.Scripts), last: b.last}
+}
+
+func (a *scriptsAcc) Result() map[string]uint64 { return maps.Clone(a.Scripts) }
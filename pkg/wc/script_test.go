@@ -0,0 +1,41 @@
+package wc
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+func TestScripts(t *testing.T) {
+	tests := []struct {
+		in   string
+		loc  locale.Info
+		want map[string]uint64
+	}{
+		{"", locale.Info{IsUTF8: true}, nil},
+		{"Hi, 42!\n", locale.Info{IsUTF8: true}, map[string]uint64{"Latin": 2, "Common": 6}},
+		{"Привет world", locale.Info{IsUTF8: true}, map[string]uint64{"Cyrillic": 6, "Common": 1, "Latin": 5}},
+		{"日本語のテキスト", locale.Info{IsUTF8: true}, map[string]uint64{"Han": 3, "Hiragana": 1, "Katakana": 4}},
+		{"é\xff\U000e0080", locale.Info{IsUTF8: true}, map[string]uint64{"Latin": 1, "Inherited": 1, ScriptUnknown: 1}},
+		{"caf\xe9", locale.Info{IsCOrPOSIX: true}, map[string]uint64{"Latin": 3, ScriptUnknown: 1}},
+	}
+	for _, tt := range tests {
+		for _, bufSize := range []int{1, 64} {
+			got := CountBytes([]byte(tt.in), Metrics{}, Options{BufferSize: bufSize, Locale: tt.loc, Scripts: true})
+			if !maps.Equal(got.Scripts, tt.want) {
+				t.Errorf("%q (buffer %d): got %v, want %v", tt.in, bufSize, got.Scripts, tt.want)
+			}
+		}
+	}
+
+	total := Sum([]FileResult{
+		CountBytes([]byte("ab"), Metrics{}, Options{Scripts: true}),
+		CountBytes([]byte("αβγ d"), Metrics{}, Options{Scripts: true, Locale: locale.Info{IsUTF8: true}}),
+	})
+	want := []ScriptCount{{"Greek", 3}, {"Latin", 3}, {"Common", 1}}
+	if got := SortScripts(total.Scripts); !slices.Equal(got, want) {
+		t.Errorf("SortScripts(total) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,16 @@
+package wc
+
+import "os"
+
+// StatSize returns the size stat reported for the named file, st, when it
+// can be trusted to be the number of bytes reading the file returns: for
+// regular files outside pseudo filesystems such as /proc and /sys, which
+// report 0 or a fixed size whatever their contents. Shortcuts taken on the
+// size, such as sampling or reading into a buffer of that size, are only
+// safe when ok is true.
+func StatSize(name string, st os.FileInfo) (size int64, ok bool) {
+	if !st.Mode().IsRegular() || pseudoFS(name) {
+		return 0, false
+	}
+	return st.Size(), true
+}
@@ -0,0 +1,29 @@
+package wc
+
+import "syscall"
+
+// pseudoFilesystems are the statfs f_type magic numbers of filesystems
+// whose files are generated on read.
+var pseudoFilesystems = map[uint32]bool{
+	0x9fa0:     true, // proc
+	0x62656572: true, // sysfs
+	0x64626720: true, // debugfs
+	0x74726163: true, // tracefs
+	0x73636673: true, // securityfs
+	0x0027e0eb: true, // cgroup
+	0x63677270: true, // cgroup2
+	0x62656570: true, // configfs
+	0xcafe4a11: true, // bpf
+	0x6165676c: true, // pstore
+	0xde5e81e4: true, // efivarfs
+	0x65735543: true, // fusectl
+}
+
+// pseudoFS reports whether the named file lives on a pseudo filesystem.
+func pseudoFS(name string) bool {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(name, &fs); err != nil {
+		return false
+	}
+	return pseudoFilesystems[uint32(fs.Type)]
+}
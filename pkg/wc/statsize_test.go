@@ -0,0 +1,48 @@
+package wc
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestStatSize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f.txt")
+	if err := os.WriteFile(path, []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name string
+		size int64
+		ok   bool
+	}{
+		{path, 6, true},
+		{dir, 0, false},
+	} {
+		st, err := os.Stat(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size, ok := StatSize(tt.name, st); size != tt.size || ok != tt.ok {
+			t.Errorf("StatSize(%s) = %d, %v; want %d, %v", tt.name, size, ok, tt.size, tt.ok)
+		}
+	}
+
+	if runtime.GOOS != "linux" {
+		return
+	}
+	// /proc files report size 0 but have contents
+	const proc = "/proc/self/status"
+	st, err := os.Stat(proc)
+	if err != nil {
+		t.Skipf("no procfs: %v", err)
+	}
+	if _, ok := StatSize(proc, st); ok {
+		t.Errorf("StatSize(%s) trusted a size of %d", proc, st.Size())
+	}
+	if est := EstimateFile(proc, DefaultMetrics(), Options{}, 0.01); est.Err != nil || !est.Exact || est.Bytes == 0 {
+		t.Errorf("EstimateFile(%s) = %+v, want an exact count", proc, est)
+	}
+}
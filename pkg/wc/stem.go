@@ -0,0 +1,286 @@
+package wc
+
+import "fmt"
+
+// Stemmer reduces a word to its stem so that inflected forms are counted
+// together by Metrics.UniqueWords and Options.NGrams (see Options.Stem).
+// It may return word itself or a new slice.
+type Stemmer func(word []byte) []byte
+
+// ParseStemmer returns the stemmer called name; "porter" is the only one.
+func ParseStemmer(name string) (Stemmer, error) {
+	switch name {
+	case "porter":
+		return PorterStem, nil
+	}
+	return nil, fmt.Errorf("unknown stemmer %q (want porter)", name)
+}
+
+// PorterStem applies the Porter (1980) stemming algorithm for English, in
+// the revised form of the reference implementation, so that "running" and
+// "runs" both become "run". Only words made of lower-case ASCII letters are
+// stemmed; anything else is returned unchanged, so fold case first for
+// capitalized words.
+func PorterStem(word []byte) []byte {
+	if len(word) <= 2 {
+		return word
+	}
+	for _, b := range word {
+		if b < 'a' || b > 'z' {
+			return word
+		}
+	}
+	p := porter{b: append([]byte(nil), word...), k: len(word) - 1}
+	p.step1ab()
+	if p.k > 0 {
+		p.step1c()
+		p.step2()
+		p.step3()
+		p.step4()
+		p.step5()
+	}
+	return p.b[:p.k+1]
+}
+
+// porter holds the word being stemmed: b[:k+1] is the current word and j
+// marks the end of the stem before a matched suffix.
+type porter struct {
+	b    []byte
+	k, j int
+}
+
+// cons reports whether b[i] is a consonant.
+func (p *porter) cons(i int) bool {
+	switch p.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !p.cons(i-1)
+	}
+	return true
+}
+
+// m measures the number of consonant-vowel sequences in b[:j+1]: for
+// <c>(vc)^m<v>, it returns m.
+func (p *porter) m() int {
+	n, i := 0, 0
+	for ; ; i++ {
+		if i > p.j {
+			return n
+		}
+		if !p.cons(i) {
+			break
+		}
+	}
+	i++
+	for {
+		for ; ; i++ {
+			if i > p.j {
+				return n
+			}
+			if p.cons(i) {
+				break
+			}
+		}
+		i++
+		n++
+		for ; ; i++ {
+			if i > p.j {
+				return n
+			}
+			if !p.cons(i) {
+				break
+			}
+		}
+		i++
+	}
+}
+
+// vowelInStem reports whether b[:j+1] contains a vowel.
+func (p *porter) vowelInStem() bool {
+	for i := 0; i <= p.j; i++ {
+		if !p.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doubleC reports whether b[j-1:j+1] is a double consonant.
+func (p *porter) doubleC(j int) bool {
+	return j >= 1 && p.b[j] == p.b[j-1] && p.cons(j)
+}
+
+// cvc reports whether b[i-2:i+1] is consonant-vowel-consonant with the
+// last consonant not w, x or y, as in "hop" but not "snow".
+func (p *porter) cvc(i int) bool {
+	if i < 2 || !p.cons(i) || p.cons(i-1) || !p.cons(i-2) {
+		return false
+	}
+	switch p.b[i] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+// ends reports whether b[:k+1] ends with s, setting j to the end of the
+// stem before it.
+func (p *porter) ends(s string) bool {
+	n := len(s)
+	if n > p.k+1 || string(p.b[p.k+1-n:p.k+1]) != s {
+		return false
+	}
+	p.j = p.k - n
+	return true
+}
+
+// setTo replaces the suffix after j with s.
+func (p *porter) setTo(s string) {
+	p.b = append(p.b[:p.j+1], s...)
+	p.k = p.j + len(s)
+}
+
+// r replaces the suffix after j with s when the stem has m() > 0.
+func (p *porter) r(s string) {
+	if p.m() > 0 {
+		p.setTo(s)
+	}
+}
+
+// step1ab removes plurals and -ed or -ing:
+//
+//	caresses -> caress, ponies -> poni, cats -> cat, feed -> feed,
+//	agreed -> agree, plastered -> plaster, motoring -> motor,
+//	hopping -> hop, filing -> file
+func (p *porter) step1ab() {
+	if p.b[p.k] == 's' {
+		switch {
+		case p.ends("sses"):
+			p.k -= 2
+		case p.ends("ies"):
+			p.setTo("i")
+		case p.b[p.k-1] != 's':
+			p.k--
+		}
+	}
+	if p.ends("eed") {
+		if p.m() > 0 {
+			p.k--
+		}
+		return
+	}
+	if (p.ends("ed") || p.ends("ing")) && p.vowelInStem() {
+		p.k = p.j
+		switch {
+		case p.ends("at"):
+			p.setTo("ate")
+		case p.ends("bl"):
+			p.setTo("ble")
+		case p.ends("iz"):
+			p.setTo("ize")
+		case p.doubleC(p.k):
+			switch p.b[p.k] {
+			case 'l', 's', 'z':
+			default:
+				p.k--
+			}
+		default:
+			p.j = p.k
+			if p.m() == 1 && p.cvc(p.k) {
+				p.setTo("e")
+			}
+		}
+	}
+}
+
+// step1c turns a terminal y into i when there is another vowel in the stem.
+func (p *porter) step1c() {
+	if p.ends("y") && p.vowelInStem() {
+		p.b[p.k] = 'i'
+	}
+}
+
+// suffixRule replaces a suffix by another, in steps 2 and 3.
+type suffixRule struct{ from, to string }
+
+// step2 maps double suffixes to single ones, e.g. -ization to -ize.
+var step2Rules = map[byte][]suffixRule{
+	'a': {{"ational", "ate"}, {"tional", "tion"}},
+	'c': {{"enci", "ence"}, {"anci", "ance"}},
+	'e': {{"izer", "ize"}},
+	'l': {{"bli", "ble"}, {"alli", "al"}, {"entli", "ent"}, {"eli", "e"}, {"ousli", "ous"}},
+	'o': {{"ization", "ize"}, {"ation", "ate"}, {"ator", "ate"}},
+	's': {{"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"}, {"ousness", "ous"}},
+	't': {{"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"}},
+	'g': {{"logi", "log"}},
+}
+
+// step3 deals with -ic-, -full, -ness and the like.
+var step3Rules = map[byte][]suffixRule{
+	'e': {{"icate", "ic"}, {"ative", ""}, {"alize", "al"}},
+	'i': {{"iciti", "ic"}},
+	'l': {{"ical", "ic"}, {"ful", ""}},
+	's': {{"ness", ""}},
+}
+
+// step4 removes these suffixes from stems with m() > 1.
+var step4Suffixes = map[byte][]string{
+	'a': {"al"},
+	'c': {"ance", "ence"},
+	'e': {"er"},
+	'i': {"ic"},
+	'l': {"able", "ible"},
+	'n': {"ant", "ement", "ment", "ent"},
+	'o': {"ion", "ou"},
+	's': {"ism"},
+	't': {"ate", "iti"},
+	'u': {"ous"},
+	'v': {"ive"},
+	'z': {"ize"},
+}
+
+func (p *porter) applyRules(rules []suffixRule) {
+	for _, rule := range rules {
+		if p.ends(rule.from) {
+			p.r(rule.to)
+			return
+		}
+	}
+}
+
+func (p *porter) step2() {
+	p.applyRules(step2Rules[p.b[p.k-1]])
+}
+
+func (p *porter) step3() {
+	p.applyRules(step3Rules[p.b[p.k]])
+}
+
+func (p *porter) step4() {
+	for _, s := range step4Suffixes[p.b[p.k-1]] {
+		if !p.ends(s) {
+			continue
+		}
+		if s == "ion" && (p.j < 0 || (p.b[p.j] != 's' && p.b[p.j] != 't')) {
+			return // -ion only goes after s or t
+		}
+		if p.m() > 1 {
+			p.k = p.j
+		}
+		return
+	}
+}
+
+// step5 removes a final -e and turns -ll into -l on longer stems.
+func (p *porter) step5() {
+	p.j = p.k
+	if p.b[p.k] == 'e' {
+		if a := p.m(); a > 1 || (a == 1 && !p.cvc(p.k-1)) {
+			p.k--
+		}
+	}
+	if p.b[p.k] == 'l' && p.doubleC(p.k) && p.m() > 1 {
+		p.k--
+	}
+}
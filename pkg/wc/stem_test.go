@@ -0,0 +1,38 @@
+package wc
+
+import "testing"
+
+func TestPorterStem(t *testing.T) {
+	tests := map[string]string{
+		"caresses": "caress", "ponies": "poni", "ties": "ti", "caress": "caress",
+		"cats": "cat", "feed": "feed", "agreed": "agre", "plastered": "plaster",
+		"bled": "bled", "motoring": "motor", "sing": "sing", "conflated": "conflat",
+		"troubled": "troubl", "sized": "size", "hopping": "hop", "tanned": "tan",
+		"falling": "fall", "hissing": "hiss", "fizzed": "fizz", "failing": "fail",
+		"filing": "file", "happy": "happi", "sky": "sky", "relational": "relat",
+		"conditional": "condit", "rational": "ration", "digitizer": "digit",
+		"vietnamization": "vietnam", "operator": "oper", "decisiveness": "decis",
+		"hopefulness": "hope", "sensibiliti": "sensibl", "triplicate": "triplic",
+		"formative": "form", "electrical": "electr", "goodness": "good",
+		"allowance": "allow", "inference": "infer", "adjustable": "adjust",
+		"replacement": "replac", "adoption": "adopt", "communism": "commun",
+		"effective": "effect", "bowdlerize": "bowdler", "rate": "rate",
+		"cease": "ceas", "controll": "control", "roll": "roll", "running": "run",
+		"runs": "run", "generalizations": "gener", "analogousli": "analog",
+		"a": "a", "is": "is", "Running": "Running", "naïve": "naïve",
+	}
+	for in, want := range tests {
+		if got := string(PorterStem([]byte(in))); got != want {
+			t.Errorf("PorterStem(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseStemmer(t *testing.T) {
+	if s, err := ParseStemmer("porter"); err != nil || string(s([]byte("runs"))) != "run" {
+		t.Errorf("porter: %v", err)
+	}
+	if _, err := ParseStemmer("snowball"); err == nil {
+		t.Error("expected an error for an unknown stemmer")
+	}
+}
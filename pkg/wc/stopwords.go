@@ -0,0 +1,96 @@
+package wc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// StopWords is a set of common words left out of Metrics.UniqueWords and
+// of n-grams (see Options.StopWords). Words are matched case-insensitively,
+// before stemming.
+type StopWords map[string]struct{}
+
+// builtinStopWords holds the lists available as builtin:LANG.
+var builtinStopWords = map[string]string{
+	"en": `a about above after again against all am an and any are as at be
+		because been before being below between both but by can could did do
+		does doing down during each few for from further had has have having
+		he her here hers herself him himself his how i if in into is it its
+		itself just me more most my myself no nor not now of off on once only
+		or other our ours ourselves out over own same she should so some such
+		than that the their theirs them themselves then there these they this
+		those through to too under until up very was we were what when where
+		which while who whom why will with would you your yours yourself
+		yourselves`,
+	"de": `aber alle allem allen aller alles als also am an ander andere anderem
+		anderen anderer anderes auch auf aus bei bin bis bist da damit dann das
+		dass dein deine dem den denn der des dich die dies diese diesem diesen
+		dieser dieses dir doch dort du durch ein eine einem einen einer eines
+		er es etwas euch euer eure für hab habe haben hat hatte hier hin hinter
+		ich ihm ihn ihnen ihr ihre im in indem ins ist jede jedem jeden jeder
+		jedes jetzt kann kein keine können man manche mein meine mich mir mit
+		muss nach nicht nichts noch nun nur ob oder ohne sehr sein seine sich
+		sie sind so solche soll sondern sonst über um und uns unser unter viel
+		vom von vor war waren warst was weil weiter welche wenn wer werde werden
+		wie wieder will wir wird wo wollen zu zum zur zwar zwischen`,
+	"fr": `à au aux avec ce ces cette dans de des du elle elles en et eux il
+		ils je la le les leur leurs lui ma mais me même mes moi mon ne nos
+		notre nous on ou où par pas pour qu que qui sa se ses son sont sur ta
+		te tes toi ton tu un une vos votre vous c d j l m n s t y été être
+		avoir ai as avons avez ont était étaient est sera serait fait faire
+		comme plus si tout tous toute toutes très sans sous entre aussi bien`,
+	"es": `a al algo algunas algunos ante antes como con contra cual cuando de
+		del desde donde durante e el él ella ellas ellos en entre era erais
+		eran eras eres es esa esas ese eso esos esta estaba estado estar estas
+		este esto estos fue fueron ha han hasta hay la las le les lo los más
+		me mi mis mucho muy nada ni no nos nosotros o os otra otros para pero
+		poco por porque que quien se sea ser si sí sin sobre son su sus también
+		tanto te tiene tienen todo todos tu tus un una uno unos y ya yo`,
+}
+
+// BuiltinStopWordLanguages returns the languages with a built-in list.
+func BuiltinStopWordLanguages() []string {
+	langs := make([]string, 0, len(builtinStopWords))
+	for lang := range builtinStopWords {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// BuiltinStopWords returns the built-in list for a language code such as
+// "en" (see BuiltinStopWordLanguages).
+func BuiltinStopWords(lang string) (StopWords, error) {
+	list, ok := builtinStopWords[lang]
+	if !ok {
+		return nil, fmt.Errorf("no built-in stopwords for %q (have %s)", lang, strings.Join(BuiltinStopWordLanguages(), ", "))
+	}
+	sw, _ := ReadStopWords(strings.NewReader(list))
+	return sw, nil
+}
+
+// ReadStopWords reads a stopword list: words separated by whitespace, with
+// '#' starting a comment that runs to the end of the line.
+func ReadStopWords(r io.Reader) (StopWords, error) {
+	sw := make(StopWords)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		for _, w := range strings.Fields(line) {
+			sw[string(foldWord([]byte(w), false))] = struct{}{}
+		}
+	}
+	return sw, sc.Err()
+}
+
+// contains reports whether word, in any case, is a stopword.
+func (sw StopWords) contains(word []byte) bool {
+	if len(sw) == 0 {
+		return false
+	}
+	_, ok := sw[string(foldWord(word, false))]
+	return ok
+}
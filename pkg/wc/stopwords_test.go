@@ -0,0 +1,49 @@
+package wc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+func TestReadStopWords(t *testing.T) {
+	sw, err := ReadStopWords(strings.NewReader("# common words\nThe a\tof # trailing\n\nÜber\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range []string{"the", "THE", "a", "of", "über"} {
+		if !sw.contains([]byte(w)) {
+			t.Errorf("%q should be a stopword", w)
+		}
+	}
+	if sw.contains([]byte("trailing")) || sw.contains([]byte("cat")) {
+		t.Error("unexpected stopword")
+	}
+}
+
+func TestBuiltinStopWords(t *testing.T) {
+	for _, lang := range BuiltinStopWordLanguages() {
+		sw, err := BuiltinStopWords(lang)
+		if err != nil || len(sw) < 50 {
+			t.Errorf("%s: %d words, %v", lang, len(sw), err)
+		}
+	}
+	if _, err := BuiltinStopWords("xx"); err == nil {
+		t.Error("expected an error for an unknown language")
+	}
+}
+
+func TestStopWordsFiltering(t *testing.T) {
+	sw, _ := BuiltinStopWords("en")
+	in := []byte("The cat sat on the mat and the cat slept")
+	opt := Options{Locale: locale.Info{IsUTF8: true}, StopWords: sw, NGrams: 2}
+	got := CountBytes(in, Metrics{Words: true, UniqueWords: true}, opt)
+	if got.Words != 10 || got.UniqueWords != 4 { // cat sat mat slept
+		t.Errorf("words %d, unique %d", got.Words, got.UniqueWords)
+	}
+	want := map[string]uint64{"cat sat": 1, "cat slept": 1}
+	if len(got.NGrams) != len(want) || got.NGrams["cat sat"] != 1 || got.NGrams["cat slept"] != 1 {
+		t.Errorf("n-grams: got %v, want %v", got.NGrams, want)
+	}
+}
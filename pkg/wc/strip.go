@@ -0,0 +1,42 @@
+package wc
+
+import (
+	"fmt"
+	"io"
+)
+
+// Strip names markup that Options.Strip leaves out of the input.
+type Strip int
+
+const (
+	// StripNone counts the input as it is. It is the default.
+	StripNone Strip = iota
+	// StripMarkdown counts the prose of a Markdown document: see
+	// NewMarkdownReader.
+	StripMarkdown
+	// StripHTML counts the text content of an HTML or XML document: see
+	// NewHTMLReader.
+	StripHTML
+)
+
+// ParseStrip returns the Strip called name, "markdown" or "html".
+func ParseStrip(name string) (Strip, error) {
+	switch name {
+	case "markdown":
+		return StripMarkdown, nil
+	case "html":
+		return StripHTML, nil
+	}
+	return StripNone, fmt.Errorf("unknown markup %q (want markdown or html)", name)
+}
+
+// Reader returns a reader of what is left of r once s is stripped from it.
+func (s Strip) Reader(r io.Reader) io.Reader {
+	switch s {
+	case StripMarkdown:
+		return NewMarkdownReader(r)
+	case StripHTML:
+		return NewHTMLReader(r)
+	}
+	return r
+}
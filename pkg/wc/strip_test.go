@@ -0,0 +1,27 @@
+package wc
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseStrip(t *testing.T) {
+	for name, want := range map[string]Strip{"markdown": StripMarkdown, "html": StripHTML} {
+		if s, err := ParseStrip(name); err != nil || s != want {
+			t.Errorf("%s: %v, %v", name, s, err)
+		}
+	}
+	if _, err := ParseStrip("rtf"); err == nil {
+		t.Error("expected an error for unknown markup")
+	}
+}
+
+func TestStripReader(t *testing.T) {
+	for s, want := range map[Strip]string{StripNone: "# <b>x</b>\n", StripMarkdown: "<b>x</b>\n", StripHTML: "# x\n"} {
+		got, err := io.ReadAll(s.Reader(strings.NewReader("# <b>x</b>\n")))
+		if err != nil || string(got) != want {
+			t.Errorf("Strip(%d): got %q, %v; want %q", s, got, err, want)
+		}
+	}
+}
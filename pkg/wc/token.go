@@ -0,0 +1,266 @@
+package wc
+
+import (
+	"bytes"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenTrim holds the punctuation stripped from either end of a word before
+// it is classified, so that "(42)," is a number and <a@b.org> an email.
+// Word kinds are taken after it too, so that "Hello," is alphabetic.
+const tokenTrim = "\"'()[]{}<>,.;:!?"
+
+func init() {
+	registerMetric(&accMetric[TokenCounts]{
+		name: "token_stats",
+		on:   func(m Metrics, _ Options) bool { return m.TokenStats },
+		newText: func(_ Metrics, opt Options, chunk bool) TextAccumulator[TokenCounts] {
+			return &tokensAcc{wordScan: newWordScan(opt, chunk, true)}
+		},
+		store: func(r *FileResult, v TokenCounts) {
+			r.NumberTokens, r.URLTokens, r.EmailTokens = v.Numbers, v.URLs, v.Emails
+		},
+	})
+	registerMetric(&accMetric[WordKindCounts]{
+		name: "word_kinds",
+		on:   func(m Metrics, _ Options) bool { return m.WordKinds },
+		newText: func(_ Metrics, opt Options, chunk bool) TextAccumulator[WordKindCounts] {
+			return &wordKindsAcc{wordScan: newWordScan(opt, chunk, true)}
+		},
+		store: func(r *FileResult, v WordKindCounts) {
+			r.NumericWords, r.AlphanumericWords, r.AlphabeticWords = v.Numeric, v.Alphanumeric, v.Alphabetic
+		},
+	})
+}
+
+// TokenCounts counts the words of each kind Metrics.TokenStats tells
+// apart.
+type TokenCounts struct {
+	Numbers, URLs, Emails uint64
+}
+
+// note classifies a complete word: a number such as 42, -3.5 or 1,000, a
+// URL such as https://go.dev or www.go.dev, or an email address such as
+// me@example.org. Other words are not counted.
+func (c *TokenCounts) note(word []byte) {
+	w := bytes.Trim(word, tokenTrim)
+	switch {
+	case len(w) == 0:
+	case isNumberToken(w):
+		c.Numbers++
+	case isURLToken(w):
+		c.URLs++
+	case isEmailToken(w):
+		c.Emails++
+	}
+}
+
+func (c TokenCounts) plus(o TokenCounts) TokenCounts {
+	return TokenCounts{c.Numbers + o.Numbers, c.URLs + o.URLs, c.Emails + o.Emails}
+}
+
+// WordKindCounts counts the words of each kind Metrics.WordKinds tells
+// apart.
+type WordKindCounts struct {
+	Numeric, Alphanumeric, Alphabetic uint64
+}
+
+// note classifies a complete word: a number as TokenStats has it; an
+// alphabetic word of letters, which single apostrophes or hyphens may
+// join, as in don't or well-known; or an alphanumeric identifier of
+// letters, digits and '_' with at least one letter and one digit or '_',
+// such as x86 or max_len. Other words, such as URLs or "a+b", are not
+// counted.
+func (c *WordKindCounts) note(word []byte) {
+	w := bytes.Trim(word, tokenTrim)
+	switch {
+	case len(w) == 0:
+	case isNumberToken(w):
+		c.Numeric++
+	case isAlphabeticWord(w):
+		c.Alphabetic++
+	case isIdentifierWord(w):
+		c.Alphanumeric++
+	}
+}
+
+func (c WordKindCounts) plus(o WordKindCounts) WordKindCounts {
+	return WordKindCounts{c.Numeric + o.Numeric, c.Alphanumeric + o.Alphanumeric, c.Alphabetic + o.Alphabetic}
+}
+
+// tokensAcc classifies words for Metrics.TokenStats.
+type tokensAcc struct {
+	wordScan
+	noWordEvents
+	Counts TokenCounts
+}
+
+func (a *tokensAcc) FeedText(t *Text) { a.feed(t, a) }
+
+func (a *tokensAcc) end(w wordPart) { a.Counts.note(w.Text) }
+
+func (a *tokensAcc) Merge(next TextAccumulator[TokenCounts]) TextAccumulator[TokenCounts] {
+	b := next.(*tokensAcc)
+	out := &tokensAcc{Counts: a.Counts.plus(b.Counts)}
+	out.wordScan, _ = a.wordScan.merge(&b.wordScan, out.end)
+	return out
+}
+
+func (a *tokensAcc) Result() TokenCounts {
+	c := a.Counts
+	head, tail := a.edges()
+	c.note(head.Text)
+	c.note(tail.Text)
+	return c
+}
+
+// wordKindsAcc classifies words for Metrics.WordKinds.
+type wordKindsAcc struct {
+	wordScan
+	noWordEvents
+	Counts WordKindCounts
+}
+
+func (a *wordKindsAcc) FeedText(t *Text) { a.feed(t, a) }
+
+func (a *wordKindsAcc) end(w wordPart) { a.Counts.note(w.Text) }
+
+func (a *wordKindsAcc) Merge(next TextAccumulator[WordKindCounts]) TextAccumulator[WordKindCounts] {
+	b := next.(*wordKindsAcc)
+	out := &wordKindsAcc{Counts: a.Counts.plus(b.Counts)}
+	out.wordScan, _ = a.wordScan.merge(&b.wordScan, out.end)
+	return out
+}
+
+func (a *wordKindsAcc) Result() WordKindCounts {
+	c := a.Counts
+	head, tail := a.edges()
+	c.note(head.Text)
+	c.note(tail.Text)
+	return c
+}
+
+// addTokens adds the token counts and word kinds of o to r.
+func (r *FileResult) addTokens(o FileResult) {
+	r.add(&r.NumberTokens, o.NumberTokens)
+	r.add(&r.URLTokens, o.URLTokens)
+	r.add(&r.EmailTokens, o.EmailTokens)
+	r.add(&r.NumericWords, o.NumericWords)
+	r.add(&r.AlphanumericWords, o.AlphanumericWords)
+	r.add(&r.AlphabeticWords, o.AlphabeticWords)
+}
+
+func isDigit(b byte) bool { return b >= '0' && b <= '9' }
+
+// isNumberToken accepts an optional sign followed by digits, which single
+// '.', ',' or '_' separators may group, and an optional '%'.
+func isNumberToken(w []byte) bool {
+	if w[0] == '+' || w[0] == '-' {
+		w = w[1:]
+	}
+	w = bytes.TrimSuffix(w, []byte("%"))
+	if len(w) == 0 || !isDigit(w[0]) || !isDigit(w[len(w)-1]) {
+		return false
+	}
+	for i := 1; i < len(w); i++ {
+		switch b := w[i]; {
+		case isDigit(b):
+		case b == '.' || b == ',' || b == '_':
+			if !isDigit(w[i-1]) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isURLToken accepts scheme://rest with a letter-led scheme, and www.host.
+func isURLToken(w []byte) bool {
+	if host, ok := bytes.CutPrefix(w, []byte("www.")); ok {
+		return bytes.IndexByte(host, '.') > 0
+	}
+	scheme, rest, ok := bytes.Cut(w, []byte("://"))
+	if !ok || len(scheme) == 0 || len(rest) == 0 || !isASCIILetter(scheme[0]) {
+		return false
+	}
+	for _, b := range scheme {
+		if !isASCIILetter(b) && !isDigit(b) && b != '+' && b != '-' && b != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// isEmailToken accepts local@domain, optionally after "mailto:", where the
+// domain has at least two dot-separated labels of letters, digits and '-'.
+func isEmailToken(w []byte) bool {
+	w = bytes.TrimPrefix(w, []byte("mailto:"))
+	local, domain, ok := bytes.Cut(w, []byte("@"))
+	if !ok || len(local) == 0 || bytes.IndexByte(domain, '@') >= 0 {
+		return false
+	}
+	for _, b := range local {
+		if b <= ' ' || bytes.IndexByte([]byte(`"(),:;<>[\]`), b) >= 0 {
+			return false
+		}
+	}
+	labels := bytes.Split(domain, []byte("."))
+	if len(labels) < 2 {
+		return false
+	}
+	for _, l := range labels {
+		if len(l) == 0 || l[0] == '-' || l[len(l)-1] == '-' {
+			return false
+		}
+		for _, b := range l {
+			if !isASCIILetter(b) && !isDigit(b) && b != '-' && b < 0x80 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isAlphabeticWord accepts letters, with combining marks after them, that
+// single apostrophes or hyphens (straight or typographic) may join.
+func isAlphabeticWord(w []byte) bool {
+	joined := true // at the start or after a joiner: a letter must follow
+	for len(w) > 0 {
+		r, size := utf8.DecodeRune(w)
+		w = w[size:]
+		switch {
+		case unicode.IsLetter(r):
+			joined = false
+		case unicode.IsMark(r) && !joined:
+		case (r == '\'' || r == '’' || r == '-' || r == '‐') && !joined:
+			joined = true
+		default:
+			return false
+		}
+	}
+	return !joined
+}
+
+// isIdentifierWord accepts letters, digits and '_', at least one of them a
+// letter and one not.
+func isIdentifierWord(w []byte) bool {
+	var letter, other bool
+	for len(w) > 0 {
+		r, size := utf8.DecodeRune(w)
+		w = w[size:]
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r) || r == '_':
+			other = true
+		default:
+			return false
+		}
+	}
+	return letter && other
+}
+
+func isASCIILetter(b byte) bool { return b|0x20 >= 'a' && b|0x20 <= 'z' }
@@ -0,0 +1,69 @@
+package wc
+
+import (
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+func TestTokenStats(t *testing.T) {
+	tests := []struct {
+		in                    string
+		numbers, urls, emails uint64
+	}{
+		{"", 0, 0, 0},
+		{"plain words only", 0, 0, 0},
+		{"42 -7 +3.5 1,000 1_000 99% (12), 0.", 8, 0, 0},
+		{"1. 2.. 3,,4 1e5 v2 - 4-5", 2, 0, 0},
+		{"https://go.dev/doc ftp://x www.example.com <http://a.b>.", 0, 4, 0},
+		{"www.localhost ://x 1http://x http://", 0, 0, 0},
+		{"me@example.org mailto:a.b+c@x.co.uk, <x@y.io>", 0, 0, 3},
+		{"@x.org a@b a@@b.org a@b..org a@-b.org a@b.org@c", 0, 0, 0},
+		{"see https://x.org or mail me@x.org about 2 items\n", 1, 1, 1},
+	}
+	for _, tt := range tests {
+		for _, bufSize := range []int{1, 64} {
+			opts := Options{BufferSize: bufSize, Locale: locale.Info{IsUTF8: true}}
+			got := CountBytes([]byte(tt.in), Metrics{TokenStats: true}, opts)
+			if got.NumberTokens != tt.numbers || got.URLTokens != tt.urls || got.EmailTokens != tt.emails {
+				t.Errorf("%q (buffer %d): got %d numbers, %d urls, %d emails, want %d, %d, %d",
+					tt.in, bufSize, got.NumberTokens, got.URLTokens, got.EmailTokens, tt.numbers, tt.urls, tt.emails)
+			}
+		}
+	}
+
+	total := Sum([]FileResult{
+		CountBytes([]byte("1 2 x@y.org"), Metrics{TokenStats: true}, Options{}),
+		CountBytes([]byte("3 www.go.dev"), Metrics{TokenStats: true}, Options{}),
+	})
+	if total.NumberTokens != 3 || total.URLTokens != 1 || total.EmailTokens != 1 {
+		t.Errorf("Sum: got %d numbers, %d urls, %d emails", total.NumberTokens, total.URLTokens, total.EmailTokens)
+	}
+}
+
+func TestWordKinds(t *testing.T) {
+	tests := []struct {
+		in                    string
+		numeric, alnum, alpha uint64
+	}{
+		{"", 0, 0, 0},
+		{"Hello, world! don't well-known naïve it’s", 0, 0, 6},
+		{"42 (3.5) 1,000 x86 max_len v2 _tmp 2nd", 3, 5, 0},
+		{"https://go.dev a+b - _ don''t -x x- ''", 0, 0, 0},
+		{"id,name\n1,alice\n2,bob\n", 0, 0, 0},
+		{"Ünïcode 日本語 ٣", 0, 0, 2},
+	}
+	for _, tt := range tests {
+		for _, bufSize := range []int{1, 64} {
+			opts := Options{BufferSize: bufSize, Locale: locale.Info{IsUTF8: true}}
+			got := CountBytes([]byte(tt.in), Metrics{WordKinds: true}, opts)
+			if got.NumericWords != tt.numeric || got.AlphanumericWords != tt.alnum || got.AlphabeticWords != tt.alpha {
+				t.Errorf("%q (buffer %d): got %d numeric, %d alnum, %d alpha, want %d, %d, %d",
+					tt.in, bufSize, got.NumericWords, got.AlphanumericWords, got.AlphabeticWords, tt.numeric, tt.alnum, tt.alpha)
+			}
+			if got.NumberTokens != 0 {
+				t.Errorf("%q (buffer %d): got %d number tokens without TokenStats", tt.in, bufSize, got.NumberTokens)
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package wc
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// Add accumulates other into r. Counters and line length histograms are
+// summed, while the max-line metrics and the longest word keep the larger
+// of the two values, the words-per-line distributions are combined, and
+// Truncated is set if either result has it, as is Overflow, which a sum
+// that wraps around also sets. r becomes a total: NoFinalNewline is
+// cleared and the inputs lacking a final newline are counted in
+// NoFinalNewlineFiles. The vocabulary, n-grams and scripts of other are
+// merged into r's, which r then owns. Filename, Index and Err are left
+// untouched.
+func (r *FileResult) Add(other FileResult) {
+	r.add(&r.Lines, other.Lines)
+	r.add(&r.Words, other.Words)
+	r.add(&r.Bytes, other.Bytes)
+	r.add(&r.Chars, other.Chars)
+	r.add(&r.WhitespaceLines, other.WhitespaceLines)
+	r.add(&r.LFEndings, other.LFEndings)
+	r.add(&r.CRLFEndings, other.CRLFEndings)
+	r.add(&r.CREndings, other.CREndings)
+	r.add(&r.WordChars, other.WordChars)
+	r.add(&r.Emoji, other.Emoji)
+	r.addTokens(other)
+	r.add(&r.CodeIdentifiers, other.CodeIdentifiers)
+	r.add(&r.CodeLiterals, other.CodeLiterals)
+	r.add(&r.CodeOperators, other.CodeOperators)
+	r.add(&r.MatchingLines, other.MatchingLines)
+	r.add(&r.NonMatchingLines, other.NonMatchingLines)
+	r.noteWord(other.LongestWord, other.LongestWordText)
+	r.addLineWords(other)
+	r.NGrams = addNGrams(r.NGrams, other.NGrams)
+	r.Scripts = addNGrams(r.Scripts, other.Scripts)
+	if other.Vocabulary != nil {
+		if r.Vocabulary == nil {
+			r.Vocabulary = other.Vocabulary.clone()
+		} else {
+			r.Vocabulary.merge(other.Vocabulary)
+		}
+		r.UniqueWords = r.Vocabulary.Len()
+	}
+	r.LineLengths = r.addSlice(r.LineLengths, other.LineLengths)
+	r.CharCounts = r.addSlice(r.CharCounts, other.CharCounts)
+	r.StringCounts = r.addSlice(r.StringCounts, other.StringCounts)
+	r.RegexpCounts = r.addSlice(r.RegexpCounts, other.RegexpCounts)
+	if other.MaxLineBytes > r.MaxLineBytes {
+		r.MaxLineBytes = other.MaxLineBytes
+	}
+	if other.MaxLineChars > r.MaxLineChars {
+		r.MaxLineChars = other.MaxLineChars
+	}
+	r.Truncated = r.Truncated || other.Truncated
+	r.NoFinalNewlineFiles = r.FinalNewlinesMissing()
+	r.NoFinalNewline = false
+	r.add(&r.NoFinalNewlineFiles, other.FinalNewlinesMissing())
+	r.Overflow = r.Overflow || other.Overflow
+	r.Duration += other.Duration
+}
+
+// add adds v to the count *dst, noting a wrap-around in r.Overflow.
+func (r *FileResult) add(dst *uint64, v uint64) {
+	var carry uint64
+	*dst, carry = bits.Add64(*dst, v, 0)
+	if carry != 0 {
+		r.Overflow = true
+	}
+}
+
+// addSlice is addCounts, noting a wrap-around in r.Overflow.
+func (r *FileResult) addSlice(a, b []uint64) []uint64 {
+	out := addCounts(a, b)
+	for i, v := range b {
+		if out[i] < v {
+			r.Overflow = true
+		}
+	}
+	return out
+}
+
+// Totals aggregates per-file results into a grand total. Results carrying an
+// error are tallied as failures and do not contribute to the counts.
+type Totals struct {
+	sum    FileResult
+	Files  int
+	Failed int
+	// carries counts the wrap-arounds of the exactCounts of sum.
+	carries [len(exactCounts)]uint64
+}
+
+// exactCounts are the counts whose exact sums Totals.Exact returns, by
+// their names as metrics.
+var exactCounts = [...]struct {
+	name  string
+	count func(*FileResult) uint64
+}{
+	{"lines", func(r *FileResult) uint64 { return r.Lines }},
+	{"words", func(r *FileResult) uint64 { return r.Words }},
+	{"bytes", func(r *FileResult) uint64 { return r.Bytes }},
+	{"chars", func(r *FileResult) uint64 { return r.Chars }},
+}
+
+// Add folds a single file result into the totals.
+func (t *Totals) Add(r FileResult) {
+	if r.Err != nil {
+		t.Failed++
+		return
+	}
+	t.Files++
+	var before [len(exactCounts)]uint64
+	for i, c := range exactCounts {
+		before[i] = c.count(&t.sum)
+	}
+	t.sum.Add(r)
+	for i, c := range exactCounts {
+		if c.count(&t.sum) < before[i] {
+			t.carries[i]++
+		}
+	}
+}
+
+// Result returns the aggregated counts.
+func (t *Totals) Result() FileResult {
+	return t.sum
+}
+
+// Exact returns the exact sums of the lines, words, bytes and chars that
+// wrapped around in Result, whose Overflow is then set, keyed by those
+// names; it is empty when none did. The other counts of an overflowing
+// Result are only known modulo 2^64.
+func (t *Totals) Exact() map[string]*big.Int {
+	exact := make(map[string]*big.Int)
+	for i, c := range exactCounts {
+		if t.carries[i] == 0 {
+			continue
+		}
+		v := new(big.Int).SetUint64(t.carries[i])
+		v.Lsh(v, 64)
+		exact[c.name] = v.Add(v, new(big.Int).SetUint64(c.count(&t.sum)))
+	}
+	return exact
+}
+
+// Sum is a convenience wrapper returning the totals of results.
+func Sum(results []FileResult) FileResult {
+	var t Totals
+	for _, r := range results {
+		t.Add(r)
+	}
+	return t.Result()
+}
@@ -0,0 +1,83 @@
+package wc
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFileResultAdd(t *testing.T) {
+	r := FileResult{Filename: "a", Lines: 1, Words: 2, Bytes: 3, Chars: 3, MaxLineBytes: 10, MaxLineChars: 4}
+	r.Add(FileResult{Filename: "b", Lines: 4, Words: 5, Bytes: 6, Chars: 5, MaxLineBytes: 7, MaxLineChars: 9})
+
+	want := FileResult{Filename: "a", Lines: 5, Words: 7, Bytes: 9, Chars: 8, MaxLineBytes: 10, MaxLineChars: 9}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("Add: got %+v, want %+v", r, want)
+	}
+}
+
+func TestTotals(t *testing.T) {
+	var tot Totals
+	tot.Add(FileResult{Lines: 1, Words: 1, Bytes: 10, MaxLineBytes: 8})
+	tot.Add(FileResult{Lines: 99, Err: errors.New("boom")})
+	tot.Add(FileResult{Lines: 2, Words: 3, Bytes: 20, MaxLineBytes: 5})
+
+	res := tot.Result()
+	if res.Lines != 3 || res.Words != 4 || res.Bytes != 30 {
+		t.Errorf("sums: got %+v", res)
+	}
+	if res.MaxLineBytes != 8 {
+		t.Errorf("MaxLineBytes: got %d, want 8", res.MaxLineBytes)
+	}
+	if tot.Files != 2 || tot.Failed != 1 {
+		t.Errorf("Files/Failed: got %d/%d, want 2/1", tot.Files, tot.Failed)
+	}
+}
+
+func TestTotalsNoFinalNewline(t *testing.T) {
+	res := Sum([]FileResult{{NoFinalNewline: true}, {Lines: 1}, {NoFinalNewline: true}})
+	if res.NoFinalNewline || res.FinalNewlinesMissing() != 2 {
+		t.Errorf("got %+v, want 2 files missing a final newline", res)
+	}
+}
+
+func TestSum(t *testing.T) {
+	res := Sum([]FileResult{{Words: 2}, {Words: 5}, {Words: 7, Err: errors.New("x")}})
+	if res.Words != 7 {
+		t.Errorf("Words: got %d, want 7", res.Words)
+	}
+}
+
+func TestTotalsOverflow(t *testing.T) {
+	var tot Totals
+	huge := FileResult{Lines: 1, Bytes: math.MaxUint64 - 1, CharCounts: []uint64{math.MaxUint64}}
+	tot.Add(huge)
+	if r := tot.Result(); r.Overflow || len(tot.Exact()) != 0 {
+		t.Fatalf("one file overflowed: %+v, %v", r, tot.Exact())
+	}
+	tot.Add(huge)
+	tot.Add(huge)
+	r := tot.Result()
+	if !r.Overflow {
+		t.Error("Overflow not set")
+	}
+	exact := tot.Exact()
+	if len(exact) != 1 || exact["bytes"] == nil {
+		t.Fatalf("Exact() = %v, want bytes alone", exact)
+	}
+	// 3 * (2^64 - 2)
+	if got, want := exact["bytes"].String(), "55340232221128654842"; got != want {
+		t.Errorf("exact bytes = %s, want %s", got, want)
+	}
+	if r.Lines != 3 {
+		t.Errorf("lines = %d", r.Lines)
+	}
+
+	var sum FileResult
+	sum.Add(FileResult{Emoji: math.MaxUint64})
+	sum.Add(FileResult{Emoji: 1})
+	if !sum.Overflow {
+		t.Error("Add: Overflow not set by a wrapping count")
+	}
+}
@@ -0,0 +1,227 @@
+package wc
+
+import (
+	"math"
+	"math/bits"
+	"unicode"
+	"unicode/utf8"
+)
+
+// hllPrecision sets the size of the approximate sketch: 2^14 one-byte
+// registers, for a standard error of about 0.8%.
+const hllPrecision = 14
+
+// WordSet collects the distinct words of one or more inputs for
+// Metrics.UniqueWords. It either remembers every word or, when approximate,
+// keeps a fixed-size HyperLogLog sketch. Fields are exported so that a
+// ChunkResult can be serialized; use the methods to query a set.
+type WordSet struct {
+	// Fold compares words case-insensitively; FoldASCII restricts that to
+	// ASCII letters, for the C locale.
+	Fold      bool
+	FoldASCII bool
+	// Stem and Stop are Options.Stem and StopWords. They are not
+	// serialized; set them again on a decoded set before adding to it.
+	Stem Stemmer   `json:"-"`
+	Stop StopWords `json:"-"`
+	// Words holds the exact set, Registers the sketch; exactly one is set.
+	Words     map[string]struct{} `json:",omitempty"`
+	Registers []uint8             `json:",omitempty"`
+}
+
+func newWordSet(opt Options) *WordSet {
+	s := &WordSet{Fold: opt.UniqueFold, FoldASCII: opt.UniqueFold && opt.Locale.IsCOrPOSIX, Stem: opt.Stem, Stop: opt.StopWords}
+	if opt.UniqueApprox {
+		s.Registers = make([]uint8, 1<<hllPrecision)
+	} else {
+		s.Words = make(map[string]struct{})
+	}
+	return s
+}
+
+// Approximate reports whether the set is a sketch.
+func (s *WordSet) Approximate() bool {
+	return s != nil && s.Registers != nil
+}
+
+// Len returns the number of distinct words, estimated for a sketch.
+func (s *WordSet) Len() uint64 {
+	switch {
+	case s == nil:
+		return 0
+	case s.Registers == nil:
+		return uint64(len(s.Words))
+	}
+	m := float64(len(s.Registers))
+	sum, zeros := 0.0, 0
+	for _, r := range s.Registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	est := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if est <= 2.5*m && zeros > 0 {
+		est = m * math.Log(m/float64(zeros)) // linear counting for small sets
+	}
+	return uint64(math.Round(est))
+}
+
+// add records word, which may be empty.
+func (s *WordSet) add(word []byte) {
+	if s == nil || len(word) == 0 || s.Stop.contains(word) {
+		return
+	}
+	word = normalizeWord(word, s.Fold, s.FoldASCII, s.Stem)
+	if s.Registers == nil {
+		if _, ok := s.Words[string(word)]; !ok {
+			s.Words[string(word)] = struct{}{}
+		}
+		return
+	}
+	h := hashWord(word)
+	i := h >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(h<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rank > s.Registers[i] {
+		s.Registers[i] = rank
+	}
+}
+
+// clone returns a copy of s that can be modified independently.
+func (s *WordSet) clone() *WordSet {
+	if s == nil {
+		return nil
+	}
+	out := *s
+	if s.Registers != nil {
+		out.Registers = append([]uint8(nil), s.Registers...)
+	} else {
+		out.Words = make(map[string]struct{}, len(s.Words))
+		for w := range s.Words {
+			out.Words[w] = struct{}{}
+		}
+	}
+	return &out
+}
+
+// merge adds the words of o to s. Both must be exact or both approximate.
+func (s *WordSet) merge(o *WordSet) {
+	if o == nil {
+		return
+	}
+	if s.Registers != nil {
+		for i, r := range o.Registers {
+			s.Registers[i] = max(s.Registers[i], r)
+		}
+		return
+	}
+	for w := range o.Words {
+		s.Words[w] = struct{}{}
+	}
+}
+
+// union returns a new set holding the words of a and b.
+func union(a, b *WordSet) *WordSet {
+	if a == nil {
+		return b.clone()
+	}
+	out := a.clone()
+	out.merge(b)
+	return out
+}
+
+// normalizeWord folds case and stems word as configured.
+func normalizeWord(word []byte, fold, asciiOnly bool, stem Stemmer) []byte {
+	if fold {
+		word = foldWord(word, asciiOnly)
+	}
+	if stem != nil {
+		word = stem(word)
+	}
+	return word
+}
+
+// foldWord maps every character of word to a canonical lower case, so that
+// "K", "k" and the Kelvin sign agree. Invalid bytes are kept as they are.
+func foldWord(word []byte, asciiOnly bool) []byte {
+	out := make([]byte, 0, len(word))
+	for len(word) > 0 {
+		b := word[0]
+		if b < utf8.RuneSelf || asciiOnly {
+			if 'A' <= b && b <= 'Z' {
+				b += 'a' - 'A'
+			}
+			out = append(out, b)
+			word = word[1:]
+			continue
+		}
+		r, size := utf8.DecodeRune(word)
+		if r == utf8.RuneError && size == 1 {
+			out = append(out, b)
+		} else {
+			out = utf8.AppendRune(out, foldRune(r))
+		}
+		word = word[size:]
+	}
+	return out
+}
+
+func foldRune(r rune) rune {
+	return unicode.ToLower(unicode.ToUpper(r))
+}
+
+// hashWord is FNV-1a followed by a 64-bit finalizer, so that the high bits
+// used to pick sketch registers are well mixed.
+func hashWord(word []byte) uint64 {
+	h := uint64(14695981039346656037)
+	for _, b := range word {
+		h ^= uint64(b)
+		h *= 1099511628211
+	}
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9a05fe6ec53
+	h ^= h >> 33
+	return h
+}
+
+func init() {
+	registerMetric(&accMetric[*WordSet]{
+		name: "vocabulary",
+		on:   func(m Metrics, _ Options) bool { return m.UniqueWords },
+		newText: func(_ Metrics, opt Options, chunk bool) TextAccumulator[*WordSet] {
+			return &vocabAcc{wordScan: newWordScan(opt, chunk, true), Set: newWordSet(opt)}
+		},
+		store: func(r *FileResult, v *WordSet) { r.Vocabulary, r.UniqueWords = v, v.Len() },
+	})
+}
+
+// vocabAcc collects the distinct words for Metrics.UniqueWords.
+type vocabAcc struct {
+	wordScan
+	noWordEvents
+	Set *WordSet
+}
+
+func (a *vocabAcc) FeedText(t *Text) { a.feed(t, a) }
+
+func (a *vocabAcc) end(w wordPart) { a.Set.add(w.Text) }
+
+func (a *vocabAcc) Merge(next TextAccumulator[*WordSet]) TextAccumulator[*WordSet] {
+	b := next.(*vocabAcc)
+	out := &vocabAcc{Set: b.Set.clone()}
+	if a.Started {
+		out.Set = union(a.Set, b.Set)
+	}
+	out.wordScan, _ = a.wordScan.merge(&b.wordScan, out.end)
+	return out
+}
+
+func (a *vocabAcc) Result() *WordSet {
+	set := a.Set.clone()
+	head, tail := a.edges()
+	set.add(head.Text)
+	set.add(tail.Text)
+	return set
+}
@@ -0,0 +1,70 @@
+package wc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
+)
+
+func TestUniqueWords(t *testing.T) {
+	in := []byte("The cat saw the CAT\nKelvin \u212Aelvin kelvin \xff \xff")
+	utf8 := locale.Info{IsUTF8: true}
+	tests := []struct {
+		opt  Options
+		want uint64
+	}{
+		{Options{Locale: utf8}, 9},
+		{Options{Locale: utf8, UniqueFold: true}, 5},
+		{Options{Locale: locale.Info{IsCOrPOSIX: true}, UniqueFold: true}, 6}, // the Kelvin sign is not folded
+	}
+	for _, tt := range tests {
+		for _, bufSize := range []int{1, 4, 64} {
+			tt.opt.BufferSize = bufSize
+			got := CountBytes(in, Metrics{UniqueWords: true}, tt.opt)
+			if got.UniqueWords != tt.want {
+				t.Errorf("fold=%v C=%v buffer %d: got %d, want %d", tt.opt.UniqueFold, tt.opt.Locale.IsCOrPOSIX, bufSize, got.UniqueWords, tt.want)
+			}
+		}
+	}
+}
+
+func TestUniqueWordsTotals(t *testing.T) {
+	opt := Options{Locale: locale.Info{IsUTF8: true}}
+	a := CountBytes([]byte("one two three"), Metrics{UniqueWords: true}, opt)
+	b := CountBytes([]byte("three four"), Metrics{UniqueWords: true}, opt)
+	tot := Sum([]FileResult{a, b})
+	if tot.UniqueWords != 4 {
+		t.Errorf("totals: got %d, want 4", tot.UniqueWords)
+	}
+	if a.UniqueWords != 3 || a.Vocabulary.Len() != 3 {
+		t.Errorf("summing modified an input: %d", a.Vocabulary.Len())
+	}
+}
+
+func TestUniqueWordsApprox(t *testing.T) {
+	for _, n := range []int{10, 1000, 200000} {
+		var sb strings.Builder
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&sb, "w%d w%d\n", i, i/2)
+		}
+		got := CountBytes([]byte(sb.String()), Metrics{UniqueWords: true}, Options{UniqueApprox: true})
+		if err := math.Abs(float64(got.UniqueWords)-float64(n)) / float64(n); err > 0.03 {
+			t.Errorf("%d words: estimated %d", n, got.UniqueWords)
+		}
+		if !got.Vocabulary.Approximate() {
+			t.Error("expected a sketch")
+		}
+	}
+}
+
+func TestUniqueWordsStem(t *testing.T) {
+	in := []byte("Run run running runs ran")
+	opt := Options{Locale: locale.Info{IsUTF8: true}, UniqueFold: true, Stem: PorterStem, NGrams: 1}
+	got := CountBytes(in, Metrics{UniqueWords: true}, opt)
+	if got.UniqueWords != 2 || got.NGrams["run"] != 4 || got.NGrams["ran"] != 1 {
+		t.Errorf("got %d unique, n-grams %v", got.UniqueWords, got.NGrams)
+	}
+}
@@ -0,0 +1,447 @@
+package wc
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrEscapesRoot is the error of Walker.Open for a path that leads out of
+// the root, through ".." or a symbolic link.
+var ErrEscapesRoot = errors.New("path escapes the root")
+
+// Walker lists the files beneath a directory and opens them for counting,
+// for example with Options.Open, or counts them with Results. A Walker
+// must not be copied after first use.
+type Walker struct {
+	// Root is the directory walked. Paths are relative to it.
+	Root string
+	// RestrictToRoot keeps every path the Walker resolves beneath Root, as
+	// for trees from untrusted sources: ".." or a symbolic link that leads
+	// out, an absolute link included, fails with ErrEscapesRoot. On Linux
+	// 5.6 and later the kernel enforces it (openat2 with RESOLVE_BENEATH),
+	// so that a tree changed during the walk cannot escape either;
+	// elsewhere links are resolved and checked before opening, which such
+	// changes can race.
+	RestrictToRoot bool
+	// SkipDir, when set, is called with the path of every directory below
+	// Root; returning true leaves it out of the walk.
+	SkipDir func(rel string) bool
+	// Include, when not empty, limits the walk to the files matching one
+	// of its patterns; Exclude leaves out the files and directories
+	// matching one of its own. Patterns are slash-separated globs matched
+	// against paths relative to Root, where "**" crosses directories and
+	// [...] is a class; a pattern without a slash matches the last element
+	// at any depth, as in .gitignore.
+	Include, Exclude []string
+	// Symlinks is what the walk does with symbolic links.
+	Symlinks SymlinkPolicy
+	// Gitignore leaves out what the .gitignore files beneath Root ignore,
+	// as git does, and the .git directories. The global excludes file and
+	// .git/info/exclude are not read.
+	Gitignore bool
+	// Workers bounds how many directories are read at once; 0 means
+	// GOMAXPROCS. Files are reported in the same order either way.
+	Workers int
+
+	root atomic.Pointer[os.File] // opened by Open, see Close
+}
+
+// SymlinkPolicy is what a Walker does with a symbolic link.
+type SymlinkPolicy int
+
+const (
+	// SymlinksReport reports a link as a file without following it, so
+	// that counting it reads its target. It is the default.
+	SymlinksReport SymlinkPolicy = iota
+	// SymlinksSkip leaves links out.
+	SymlinksSkip
+	// SymlinksFollow walks the directories links lead to as if they were
+	// beneath the link and reports the other links as files. A link back
+	// to a directory being walked is left out, and with RestrictToRoot so
+	// is a link that leads out of Root.
+	SymlinksFollow
+)
+
+// Walk calls fn with the path, slash-separated and relative to Root, of
+// every file beneath Root that is not a directory, in lexical order. The
+// directories are read ahead by up to Workers goroutines. The first error,
+// from fn, from reading a directory or from a pattern that does not
+// compile, ends the walk and is returned.
+//
+// Each directory is kept open while the walk is in it, and its entries
+// are opened and stat'ed relative to it, by name: paths are not resolved
+// from Root again at every level, and a directory renamed or replaced by a
+// link during the walk cannot redirect it.
+func (w *Walker) Walk(fn func(rel string) error) error {
+	s, err := w.start(func(rel string, err error) error { return err })
+	if err != nil {
+		return err
+	}
+	return s.run(func(rel string, _ fileAt) error { return fn(rel) })
+}
+
+// Close closes Root, which Open keeps open once it has opened it. The
+// Walker can be used again afterwards.
+func (w *Walker) Close() error {
+	if f := w.root.Swap(nil); f != nil {
+		return f.Close()
+	}
+	return nil
+}
+
+// walkState is a walk in progress.
+type walkState struct {
+	w                *Walker
+	include, exclude []*regexp.Regexp
+	sem              chan struct{} // bounds the directories read at once
+	root             *walkDir      // set once Root has been read
+	// onError returns the error that ends the walk when the directory rel
+	// cannot be read, or nil to go on without it.
+	onError func(rel string, err error) error
+}
+
+func (w *Walker) start(onError func(rel string, err error) error) (*walkState, error) {
+	s := &walkState{w: w, onError: onError}
+	for _, pats := range []struct {
+		dst  *[]*regexp.Regexp
+		list []string
+	}{{&s.include, w.Include}, {&s.exclude, w.Exclude}} {
+		for _, pat := range pats.list {
+			re, err := compileGlob(pat)
+			if err != nil {
+				return nil, err
+			}
+			*pats.dst = append(*pats.dst, re)
+		}
+	}
+	workers := w.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	s.sem = make(chan struct{}, workers)
+	return s, nil
+}
+
+// run walks Root, calling fn for every file with where it was found.
+func (s *walkState) run(fn func(rel string, at fileAt) error) error {
+	return s.walk(".", s.read(nil, walkEntry{}, "."), nil, nil, fn)
+}
+
+// walkDir is a directory open during a walk. It is closed when the last
+// of the walk and the files being opened in it lets go of it.
+type walkDir struct {
+	f    *os.File
+	refs atomic.Int32
+}
+
+func newWalkDir(f *os.File) *walkDir {
+	d := &walkDir{f: f}
+	d.refs.Store(1)
+	return d
+}
+
+func (d *walkDir) hold() { d.refs.Add(1) }
+
+func (d *walkDir) release() {
+	if d.refs.Add(-1) == 0 {
+		d.f.Close()
+	}
+}
+
+// fileAt is where a walk found a file: the entry name of dir.
+type fileAt struct {
+	dir  *walkDir
+	name string
+	link bool
+}
+
+// listing is a directory read by walkState.read.
+type listing struct {
+	done    chan struct{} // closed once the fields are set
+	dir     *walkDir      // the directory, open, unless err is set
+	info    fs.FileInfo   // of the directory, to detect cycles
+	entries []walkEntry   // sorted by name
+	ignore  []ignoreRule  // of its .gitignore
+	err     error
+}
+
+type walkEntry struct {
+	name string
+	dir  bool // a directory, or a followed link to one
+	link bool // a symbolic link
+}
+
+// read starts reading the directory rel, the entry e of parent, or Root
+// when parent is nil, in the background.
+func (s *walkState) read(parent *walkDir, e walkEntry, rel string) *listing {
+	l := &listing{done: make(chan struct{})}
+	go func() {
+		defer close(l.done)
+		s.sem <- struct{}{}
+		defer func() { <-s.sem }()
+		var f *os.File
+		var err error
+		switch {
+		case parent == nil:
+			f, err = os.Open(s.w.Root)
+		case e.link && s.w.RestrictToRoot:
+			f, err = openBeneath(s.root.f, rel)
+		default:
+			f, err = openAt(parent.f, e.name, e.link)
+		}
+		if err != nil {
+			l.err = err
+			return
+		}
+		if l.info, l.err = f.Stat(); l.err != nil {
+			f.Close()
+			return
+		}
+		des, err := f.ReadDir(-1)
+		if err != nil {
+			f.Close()
+			l.err = err
+			return
+		}
+		l.dir = newWalkDir(f)
+		if parent == nil {
+			s.root = l.dir
+		}
+		slices.SortFunc(des, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+		for _, de := range des {
+			e := walkEntry{name: de.Name(), dir: de.IsDir(), link: de.Type()&fs.ModeSymlink != 0}
+			if e.link {
+				switch s.w.Symlinks {
+				case SymlinksSkip:
+					continue
+				case SymlinksFollow:
+					st, err := s.statLink(l.dir, e.name, path.Join(rel, e.name))
+					if errors.Is(err, ErrEscapesRoot) {
+						continue
+					}
+					e.dir = err == nil && st.IsDir() // a broken link is reported, to fail when counted
+				}
+			}
+			if s.w.Gitignore && e.name == ".gitignore" && de.Type().IsRegular() {
+				if data, err := readAt(l.dir.f, e.name); err == nil {
+					l.ignore = parseIgnore(data)
+				}
+			}
+			l.entries = append(l.entries, e)
+		}
+	}()
+	return l
+}
+
+// walk reports the files of the directory dir, which l lists, and those
+// beneath it. ign holds the .gitignore rules of the directories above and
+// ancestors the directories being walked. It reads at most as many
+// subdirectories ahead as directories are read at once, each of which
+// stays open until walked.
+func (s *walkState) walk(dir string, l *listing, ign *ignoreLevel, ancestors []fs.FileInfo, fn func(rel string, at fileAt) error) error {
+	<-l.done
+	if l.err != nil {
+		return s.onError(dir, l.err)
+	}
+	defer l.dir.release()
+	for _, a := range ancestors {
+		if os.SameFile(a, l.info) {
+			return nil // a link back up the tree
+		}
+	}
+	ancestors = append(ancestors, l.info)
+	if len(l.ignore) > 0 {
+		ign = &ignoreLevel{parent: ign, dir: dir, rules: l.ignore}
+	}
+
+	type item struct {
+		rel string
+		e   walkEntry
+		sub *listing // read ahead and not walked yet
+	}
+	items := make([]item, 0, len(l.entries))
+	for _, e := range l.entries {
+		rel := path.Join(dir, e.name)
+		if e.dir && !s.skipDir(rel, ign) || !e.dir && s.keepFile(rel, ign) {
+			items = append(items, item{rel: rel, e: e})
+		}
+	}
+	next, ahead := 0, 0 // the next item to read ahead, and those being read
+	defer func() {
+		// on an early return, close the subdirectories read ahead
+		for _, it := range items {
+			if it.sub != nil {
+				<-it.sub.done
+				if it.sub.err == nil {
+					it.sub.dir.release()
+				}
+			}
+		}
+	}()
+	for i := range items {
+		// start reading the subdirectories while the items before them
+		// are handled
+		for ; next < len(items) && ahead < cap(s.sem); next++ {
+			if items[next].e.dir {
+				items[next].sub = s.read(l.dir, items[next].e, items[next].rel)
+				ahead++
+			}
+		}
+		it := &items[i]
+		var err error
+		if it.e.dir {
+			sub := it.sub
+			it.sub = nil
+			ahead--
+			err = s.walk(it.rel, sub, ign, ancestors, fn)
+		} else {
+			err = fn(it.rel, fileAt{dir: l.dir, name: it.e.name, link: it.e.link})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *walkState) skipDir(rel string, ign *ignoreLevel) bool {
+	if s.w.SkipDir != nil && s.w.SkipDir(rel) || matchAny(s.exclude, rel) {
+		return true
+	}
+	return s.w.Gitignore && (path.Base(rel) == ".git" || ign.ignored(rel, true))
+}
+
+func (s *walkState) keepFile(rel string, ign *ignoreLevel) bool {
+	if matchAny(s.exclude, rel) || s.w.Gitignore && ign.ignored(rel, false) {
+		return false
+	}
+	return len(s.include) == 0 || matchAny(s.include, rel)
+}
+
+func matchAny(res []*regexp.Regexp, rel string) bool {
+	for _, re := range res {
+		if re.MatchString(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// statLink returns what the link name in dir, at rel, leads to, without
+// opening it, which would block on a FIFO.
+func (s *walkState) statLink(dir *walkDir, name, rel string) (fs.FileInfo, error) {
+	if s.w.RestrictToRoot {
+		return statBeneath(s.root.f, rel)
+	}
+	return statAt(dir.f, name)
+}
+
+// opener returns how to open the file found at rel: relative to its
+// directory, or beneath Root for a link under RestrictToRoot, which is
+// kept open until done is called.
+func (s *walkState) opener(rel string, at fileAt) (open func(string) (*os.File, error), done func()) {
+	if at.link && s.w.RestrictToRoot {
+		root := s.root
+		root.hold()
+		return func(string) (*os.File, error) { return openBeneath(root.f, rel) }, root.release
+	}
+	at.dir.hold()
+	return func(string) (*os.File, error) { return openAt(at.dir.f, at.name, at.link) }, at.dir.release
+}
+
+// readAt reads the file name in dir, which must not be a link.
+func readAt(dir *os.File, name string) ([]byte, error) {
+	f, err := openAt(dir, name, false)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// Open opens the file at rel, a path relative to Root in either
+// separator, for reading. It resolves rel from Root, which it opens on
+// the first call and keeps open for the next ones until Close.
+func (w *Walker) Open(rel string) (*os.File, error) {
+	if w.RestrictToRoot && filepath.IsAbs(rel) {
+		return nil, &fs.PathError{Op: "open", Path: rel, Err: ErrEscapesRoot}
+	}
+	root, err := w.rootDir()
+	if err != nil {
+		return nil, err
+	}
+	if !w.RestrictToRoot {
+		return openAt(root, filepath.Join(".", filepath.FromSlash(rel)), true)
+	}
+	return openBeneath(root, filepath.ToSlash(rel))
+}
+
+// rootDir returns Root, open, opening it if Open has not yet.
+func (w *Walker) rootDir() (*os.File, error) {
+	if f := w.root.Load(); f != nil {
+		return f, nil
+	}
+	f, err := os.Open(w.Root)
+	if err != nil {
+		return nil, err
+	}
+	if !w.root.CompareAndSwap(nil, f) {
+		f.Close() // another call opened it first
+		return w.rootDir()
+	}
+	return f, nil
+}
+
+// resolveBeneath resolves rel beneath root one element at a time, following
+// relative symbolic links, and returns the path to open: one without links
+// as of the check, or an error wrapping ErrEscapesRoot.
+func resolveBeneath(root, rel string) (string, error) {
+	var done []string // resolved elements
+	todo := strings.Split(rel, "/")
+	links := 0
+	for len(todo) > 0 {
+		elem := todo[0]
+		todo = todo[1:]
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			if len(done) == 0 {
+				return "", &fs.PathError{Op: "open", Path: rel, Err: ErrEscapesRoot}
+			}
+			done = done[:len(done)-1]
+			continue
+		}
+		p := filepath.Join(root, filepath.Join(append(done, elem)...))
+		st, err := os.Lstat(p)
+		if err != nil {
+			return "", err
+		}
+		if st.Mode()&fs.ModeSymlink == 0 {
+			done = append(done, elem)
+			continue
+		}
+		if links++; links > 40 {
+			return "", &fs.PathError{Op: "open", Path: rel, Err: errors.New("too many levels of symbolic links")}
+		}
+		target, err := os.Readlink(p)
+		if err != nil {
+			return "", err
+		}
+		target = filepath.ToSlash(target)
+		if path.IsAbs(target) || filepath.VolumeName(target) != "" {
+			return "", &fs.PathError{Op: "open", Path: rel, Err: ErrEscapesRoot}
+		}
+		todo = append(strings.Split(target, "/"), todo...)
+	}
+	return filepath.Join(root, filepath.Join(done...)), nil
+}
@@ -0,0 +1,151 @@
+package wc
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// openat2 and its resolve flags (linux/openat2.h).
+const (
+	sysOpenat2              = 437
+	resolveFlagNoMagiclinks = 0x02
+	resolveFlagBeneath      = 0x08
+)
+
+// oPath is O_PATH, which package syscall lacks; it has this value on every
+// architecture Go runs Linux on.
+const oPath = 0x200000
+
+type openHow struct {
+	flags   uint64
+	mode    uint64
+	resolve uint64
+}
+
+// withFD calls fn with the descriptor of dir, which closing dir meanwhile
+// cannot release.
+func withFD(dir *os.File, fn func(fd int) error) error {
+	rc, err := dir.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ferr error
+	if err := rc.Control(func(fd uintptr) { ferr = fn(int(fd)) }); err != nil {
+		return err
+	}
+	return ferr
+}
+
+// openatFile opens name relative to the directory dir with openat.
+func openatFile(dir *os.File, name string, flags int) (*os.File, error) {
+	p := filepath.Join(dir.Name(), name)
+	var fd int
+	err := withFD(dir, func(dirfd int) (err error) {
+		for {
+			fd, err = syscall.Openat(dirfd, name, flags|syscall.O_CLOEXEC, 0)
+			if err != syscall.EINTR {
+				return err
+			}
+		}
+	})
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: p, Err: err}
+	}
+	return os.NewFile(uintptr(fd), p), nil
+}
+
+// openAt opens name, relative to the open directory dir, for reading.
+// Unless follow is set, a symbolic link fails to open.
+func openAt(dir *os.File, name string, follow bool) (*os.File, error) {
+	flags := syscall.O_RDONLY
+	if !follow {
+		flags |= syscall.O_NOFOLLOW
+	}
+	return openatFile(dir, name, flags)
+}
+
+// statAt returns what name, relative to the open directory dir, leads to,
+// as fstatat would: package syscall has no Fstatat on every architecture,
+// so it stats a descriptor opened with O_PATH, which reads nothing and
+// does not block on a FIFO.
+func statAt(dir *os.File, name string) (fs.FileInfo, error) {
+	f, err := openatFile(dir, name, oPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+// openat2Beneath opens rel beneath the open directory root with openat2,
+// which fails with EXDEV if resolving it would leave root. Kernels before
+// 5.6 lack openat2; it then returns ENOSYS as is.
+func openat2Beneath(root *os.File, rel string, flags uint64) (*os.File, error) {
+	p, err := syscall.BytePtrFromString(rel)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: rel, Err: err}
+	}
+	how := openHow{
+		flags:   flags | syscall.O_CLOEXEC,
+		resolve: resolveFlagBeneath | resolveFlagNoMagiclinks,
+	}
+	var fd uintptr
+	err = withFD(root, func(dir int) error {
+		for {
+			var errno syscall.Errno
+			fd, _, errno = syscall.Syscall6(sysOpenat2, uintptr(dir), uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&how)), unsafe.Sizeof(how), 0, 0)
+			switch errno {
+			case 0:
+				return nil
+			case syscall.EINTR, syscall.EAGAIN:
+				continue // EAGAIN: a rename raced the resolution of ".."
+			}
+			return errno
+		}
+	})
+	name := filepath.Join(root.Name(), filepath.FromSlash(rel))
+	switch err {
+	case nil:
+		return os.NewFile(fd, name), nil
+	case syscall.ENOSYS:
+		return nil, err
+	case syscall.EXDEV:
+		return nil, &fs.PathError{Op: "open", Path: rel, Err: ErrEscapesRoot}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+}
+
+// openBeneath opens rel beneath the open directory root, which the kernel
+// enforces; without openat2 the path is checked first.
+func openBeneath(root *os.File, rel string) (*os.File, error) {
+	f, err := openat2Beneath(root, rel, syscall.O_RDONLY)
+	if err != syscall.ENOSYS {
+		return f, err
+	}
+	p, err := resolveBeneath(root.Name(), rel)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+// statBeneath returns what rel beneath the open directory root leads to,
+// like openBeneath but with O_PATH, which does not block on a FIFO.
+func statBeneath(root *os.File, rel string) (fs.FileInfo, error) {
+	f, err := openat2Beneath(root, rel, oPath)
+	if err == syscall.ENOSYS {
+		p, err := resolveBeneath(root.Name(), rel)
+		if err != nil {
+			return nil, err
+		}
+		return os.Stat(p)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
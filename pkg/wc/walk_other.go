@@ -0,0 +1,40 @@
+//go:build !linux
+
+package wc
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// openAt opens name relative to the open directory dir, by the path dir
+// was opened by, lacking openat here. Links are followed either way.
+func openAt(dir *os.File, name string, follow bool) (*os.File, error) {
+	return os.Open(filepath.Join(dir.Name(), name))
+}
+
+// statAt returns what name, relative to the open directory dir, leads to.
+func statAt(dir *os.File, name string) (fs.FileInfo, error) {
+	return os.Stat(filepath.Join(dir.Name(), name))
+}
+
+// openBeneath opens rel beneath the open directory root after checking its
+// links, there being no kernel support for confined resolution here.
+func openBeneath(root *os.File, rel string) (*os.File, error) {
+	p, err := resolveBeneath(root.Name(), rel)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+// statBeneath returns what rel beneath the open directory root leads to,
+// checked as by openBeneath.
+func statBeneath(root *os.File, rel string) (fs.FileInfo, error) {
+	p, err := resolveBeneath(root.Name(), rel)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(p)
+}
@@ -0,0 +1,229 @@
+package wc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestWalker(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"b.txt", "a/x.txt", "a/.git/config", "c/y.txt"} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		os.WriteFile(p, []byte("one two\n"), 0o644)
+	}
+	w := &Walker{Root: root, RestrictToRoot: true, SkipDir: func(rel string) bool { return filepath.Base(rel) == ".git" }}
+	var got []string
+	if err := w.Walk(func(rel string) error { got = append(got, rel); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a/x.txt", "b.txt", "c/y.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Walk: got %v, want %v", got, want)
+	}
+
+	res := CountFile(context.Background(), "a/x.txt", DefaultMetrics(), Options{Open: w.Open})
+	if res.Err != nil || res.Words != 2 {
+		t.Errorf("CountFile through Walker.Open: %+v", res)
+	}
+}
+
+func TestWalkerRestrictToRoot(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges")
+	}
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "secret"), []byte("s\n"), 0o644)
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "sub"), 0o755)
+	os.WriteFile(filepath.Join(root, "sub", "f.txt"), []byte("f\n"), 0o644)
+	links := map[string]string{
+		"inside":     "sub/f.txt",
+		"sub/up":     "../sub/f.txt",
+		"abs":        filepath.Join(root, "sub", "f.txt"), // absolute links escape even when pointing back in
+		"out":        filepath.Join(outside, "secret"),
+		"sub/escape": "../../" + filepath.Base(outside) + "/secret",
+		"dirout":     outside,
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cases := map[string]bool{ // path: confined
+		"sub/f.txt":     true,
+		"inside":        true,
+		"sub/up":        true,
+		"sub/../inside": true,
+		"abs":           false,
+		"out":           false,
+		"sub/escape":    false,
+		"dirout/secret": false,
+		"../x":          false,
+		"/etc/passwd":   false,
+	}
+	open := map[string]func(rel string) (*os.File, error){
+		"Walker.Open": (&Walker{Root: root, RestrictToRoot: true}).Open,
+		"fallback": func(rel string) (*os.File, error) {
+			p, err := resolveBeneath(root, rel)
+			if err != nil {
+				return nil, err
+			}
+			return os.Open(p)
+		},
+	}
+	for how, open := range open {
+		for rel, confined := range cases {
+			if how == "fallback" && filepath.IsAbs(rel) {
+				continue // Walker.Open rejects those first
+			}
+			f, err := open(rel)
+			if err == nil {
+				f.Close()
+			}
+			if confined && err != nil {
+				t.Errorf("%s(%q): %v", how, rel, err)
+			}
+			if !confined && !errors.Is(err, ErrEscapesRoot) {
+				t.Errorf("%s(%q): err = %v, want ErrEscapesRoot", how, rel, err)
+			}
+		}
+	}
+
+	unrestricted := &Walker{Root: root}
+	if f, err := unrestricted.Open("out"); err != nil {
+		t.Errorf("unrestricted Open of a link out: %v", err)
+	} else {
+		f.Close()
+	}
+}
+
+// walkTree creates the files named in files beneath a new directory,
+// each holding "one two\n", and returns the directory.
+func walkTree(t *testing.T, files ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, name := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("one two\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func walkAll(t *testing.T, w *Walker) []string {
+	t.Helper()
+	got := []string{}
+	if err := w.Walk(func(rel string) error { got = append(got, rel); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestWalkerFilters(t *testing.T) {
+	root := walkTree(t, "a.go", "a_test.go", "b.txt", "docs/x.md", "docs/old/y.md", "vendor/v.go", "z/deep/c.go")
+	w := &Walker{Root: root, Include: []string{"*.go", "docs/**"}, Exclude: []string{"*_test.go", "vendor", "docs/old/"}, Workers: 2}
+	want := []string{"a.go", "docs/x.md", "z/deep/c.go"}
+	if got := walkAll(t, w); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	w = &Walker{Root: root, Include: []string{"[z"}}
+	if err := w.Walk(func(string) error { return nil }); err != nil {
+		t.Errorf("an unclosed class is literal: %v", err)
+	}
+}
+
+func TestWalkerGitignore(t *testing.T) {
+	root := walkTree(t, "a.log", "keep.log", "b.txt", "build/out.txt", "src/gen.txt", "src/main.go", "src/sub/gen.txt", ".git/config")
+	os.WriteFile(filepath.Join(root, ".gitignore"), []byte("# logs\n*.log\n!keep.log\nbuild/\n"), 0o644)
+	os.WriteFile(filepath.Join(root, "src", ".gitignore"), []byte("/gen.txt\n"), 0o644)
+	w := &Walker{Root: root, Gitignore: true}
+	want := []string{".gitignore", "b.txt", "keep.log", "src/.gitignore", "src/main.go", "src/sub/gen.txt"}
+	if got := walkAll(t, w); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestWalkerSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges")
+	}
+	outside := walkTree(t, "o.txt")
+	root := walkTree(t, "d/f.txt")
+	for name, target := range map[string]string{"d/loop": "..", "dl": "d", "fl": "d/f.txt", "out": outside} {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cases := []struct {
+		w    *Walker
+		want []string
+	}{
+		{&Walker{}, []string{"d/f.txt", "d/loop", "dl", "fl", "out"}},
+		{&Walker{Symlinks: SymlinksSkip}, []string{"d/f.txt"}},
+		{&Walker{Symlinks: SymlinksFollow}, []string{"d/f.txt", "dl/f.txt", "fl", "out/o.txt"}},
+		{&Walker{Symlinks: SymlinksFollow, RestrictToRoot: true}, []string{"d/f.txt", "dl/f.txt", "fl"}},
+	}
+	for _, c := range cases {
+		c.w.Root = root
+		if got := walkAll(t, c.w); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Symlinks %d, RestrictToRoot %v: got %v, want %v", c.w.Symlinks, c.w.RestrictToRoot, got, c.want)
+		}
+	}
+}
+
+func TestWalkerOpensRelativeToDirectories(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("entries are opened by path without openat")
+	}
+	root := t.TempDir()
+	outside := t.TempDir()
+	for name, data := range map[string]string{
+		"a/1.txt":     "one\n",
+		"a/2.txt":     "one two\n",
+		"a/sub/3.txt": "one two three\n",
+	} {
+		for _, dir := range []string{root, outside} {
+			p := filepath.Join(dir, filepath.FromSlash(name))
+			os.MkdirAll(filepath.Dir(p), 0o755)
+			if dir == outside {
+				data = "x\n"
+			}
+			os.WriteFile(p, []byte(data), 0o644)
+		}
+	}
+	s, err := (&Walker{Root: root}).start(func(rel string, err error) error { return err })
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]uint64{}
+	err = s.run(func(rel string, at fileAt) error {
+		if rel == "a/1.txt" {
+			// swap the directory being walked for a link out of the tree
+			if err := os.Rename(filepath.Join(root, "a"), filepath.Join(root, "moved")); err != nil {
+				return err
+			}
+			if err := os.Symlink(filepath.Join(outside, "a"), filepath.Join(root, "a")); err != nil {
+				return err
+			}
+		}
+		open, done := s.opener(rel, at)
+		defer done()
+		got[rel] = CountFile(context.Background(), rel, Metrics{Words: true}, Options{Open: open}).Words
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]uint64{"a/1.txt": 1, "a/2.txt": 2, "a/sub/3.txt": 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("words = %v, want %v, as in the tree walked", got, want)
+	}
+}
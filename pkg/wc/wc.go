@@ -2,14 +2,18 @@ package wc
 
 import (
 	"bufio"
+	"bytes"
 	"io"
+	"os"
+	"regexp"
 	"time"
-	"unicode"
-	"unicode/utf8"
 
 	"github.com/rajasatyajit/go-wc/pkg/wc/locale"
 )
 
+// defaultBufferSize is used when Options.BufferSize is not set
+const defaultBufferSize = 64 * 1024
+
 // Metrics selects which counters to compute
  type Metrics struct {
 	Lines         bool
@@ -18,14 +22,178 @@ import (
 	Chars         bool
 	MaxLineBytes  bool
 	MaxLineChars  bool
+	// WhitespaceLines counts lines made only of whitespace (not empty ones)
+	WhitespaceLines bool
+	// LineEndings counts LF, CRLF and lone CR terminators separately
+	LineEndings bool
+	// NoFinalNewline prints FileResult.NoFinalNewline as a 0/1 column; the
+	// flag itself is always computed.
+	NoFinalNewline bool
+	// WordLengths reports the length of the longest word and the average
+	// word length, in characters
+	WordLengths bool
+	// LongestWord also reports the longest word itself
+	LongestWord bool
+	// WordsPerLine reports the fewest, average and most words on a line
+	WordsPerLine bool
+	// Emoji counts emoji as they are displayed, so that a ZWJ sequence,
+	// a flag or a keycap is one emoji however many code points it takes
+	Emoji bool
+	// TokenStats counts the words that are numbers, URLs and email
+	// addresses
+	TokenStats bool
+	// WordKinds counts the words that are numbers, alphanumeric
+	// identifiers such as x86 or max_len, and alphabetic words, to tell
+	// data from prose
+	WordKinds bool
+	// MatchLines counts the lines Options.MatchLines matches and those it
+	// does not. Having no meaning without the expression, it is not one of
+	// the counters named in MetricsFromStrings.
+	MatchLines bool
+	// UniqueWords counts distinct words (see Options.UniqueFold and
+	// UniqueApprox)
+	UniqueWords bool
+	// CodeTokens lexes the input as source code of Options.Language and
+	// counts its identifiers, literals and operators, leaving comments
+	// and white space out
+	CodeTokens bool
+	// LineLengths sorts the lines into FileResult.LineLengths by their
+	// length. A distribution rather than a count, it is not one of the
+	// counters named in MetricsFromStrings either.
+	LineLengths bool
  }
 
-// Options control scanning behavior
+// LineContent classifies the characters of a line, excluding its terminator.
+ type LineContent uint8
+
+ const (
+	LineEmpty     LineContent = iota // no characters
+	LineSpaceOnly                    // whitespace only
+	LineHasText                      // at least one non-space character
+ )
+
+// Options control scanning behavior. CountReader and the functions built
+// on it honor them all; chunk counters (NewChunkCounter, CountChunk)
+// ignore MatchLines, Strip, NGrams, MinWordLength, Hyphen, Apostrophe and
+// OnLine, whose results depend on what came before the chunk.
  type Options struct {
 	BufferSize int
 	Locale     locale.Info
+	// OnProgress, when set, is called after every read with the bytes
+	// consumed so far and TotalBytes, and once more when the input ends.
+	OnProgress func(bytesDone, bytesTotal uint64)
+	// TotalBytes is the expected input size passed to OnProgress; 0 if unknown.
+	TotalBytes uint64
+	// CountChars lists character classes whose occurrences are reported in
+	// FileResult.CharCounts, in the same order.
+	CountChars []CharClass
+	// CountStrings lists literal strings whose non-overlapping occurrences
+	// are reported in FileResult.StringCounts, in the same order.
+	CountStrings []string
+	// MatchLines is the regular expression Metrics.MatchLines tests every
+	// line against.
+	MatchLines *regexp.Regexp
+	// CountRegexps lists regular expressions whose non-overlapping matches
+	// within each line are reported in FileResult.RegexpCounts, in the
+	// same order. Like StringCounts, chunk counters count the lines cut at
+	// their edges as they are.
+	CountRegexps []*regexp.Regexp
+	// MaxMatchLength is the longest match of MatchLines and CountRegexps
+	// that is sure to be found in a line longer than twice that: such
+	// lines are scanned in overlapping windows rather than whole, to
+	// bound memory. 0 means DefaultMaxMatchLength.
+	MaxMatchLength int
+	// Offset skips that many bytes of input before counting, and Length,
+	// when positive, stops counting after that many bytes. Counts cover
+	// only the window, which may begin or end inside a line or character.
+	Offset int64
+	Length int64
+	// StopAfterLines and StopAfterBytes, when positive, end counting early
+	// once that many lines or bytes have been read. FileResult.Truncated
+	// then reports whether the input continued past the limit.
+	StopAfterLines uint64
+	StopAfterBytes uint64
+	// Strip leaves markup out of the input before it is counted, after
+	// Offset and Length are applied, so that every count, bytes included,
+	// covers what is left, StopAfterLines and StopAfterBytes too:
+	// StripMarkdown counts the prose of a Markdown document, StripHTML the
+	// text of an HTML or XML one.
+	Strip Strip
+	// AbortLineBytes and AbortWordBytes, when positive, guard services
+	// that count untrusted input against pathological lines: counting
+	// stops at the byte that makes a line, or a run of bytes without ASCII
+	// white space, longer than that many bytes, and FileResult.Err is a
+	// *GuardError. The counts cover the input up to there. CountReader and
+	// the functions built on it honor them; a Counter used directly stops
+	// too and reports the error from Counter.Err.
+	AbortLineBytes uint64
+	AbortWordBytes uint64
+	// UniqueFold makes Metrics.UniqueWords compare words case-insensitively,
+	// and UniqueApprox estimates the count in bounded memory instead of
+	// remembering every word.
+	UniqueFold   bool
+	UniqueApprox bool
+	// NGrams, when positive, counts every sequence of that many consecutive
+	// words in FileResult.NGrams, case-folded under UniqueFold. N-grams run
+	// across line breaks.
+	NGrams int
+	// MinWordLength, when positive, leaves the words of fewer characters
+	// out of every word metric: the word count, words per line, word
+	// lengths, UniqueWords, TokenStats and n-grams, which run across them.
+	MinWordLength int
+	// Hyphen decides whether "state-of-the-art" is one word or four, and
+	// whether a word hyphenated at a line break is rejoined: see
+	// HyphenJoin and HyphenSplit. Byte and line counts are unaffected.
+	Hyphen Hyphen
+	// Apostrophe decides whether "don't" is one word or two, and whether
+	// apostrophes quoting a word belong to it: see ApostropheInternal and
+	// ApostropheSplit. It applies before Hyphen; byte and line counts are
+	// unaffected.
+	Apostrophe Apostrophe
+	// POSIXSpace takes white space to be the space class of glibc's
+	// locales, which POSIX wc separates words by, rather than Unicode's:
+	// the no-break spaces U+00A0, U+2007 and U+202F and U+0085 NEXT LINE
+	// are then word characters.
+	POSIXSpace bool
+	// Stem, when set, reduces words to their stems (after case folding)
+	// for UniqueWords and NGrams, e.g. PorterStem.
+	Stem Stemmer
+	// StopWords are left out of UniqueWords, and n-grams containing one
+	// are not counted.
+	StopWords StopWords
+	// OnLine, when set, is called with the counts of every line as its
+	// newline is read, and once more for an unterminated last line when
+	// the input ends.
+	OnLine func(LineCount)
+	// Language is the source language Metrics.CodeTokens lexes.
+	// CountFile, CountSmallFile and EstimateFile pick it by the file name
+	// when it is nil (see LanguageForFile); other inputs, nil, are lexed
+	// by generic rules.
+	Language *CodeLanguage
+	// Scripts counts the characters of each Unicode script in
+	// FileResult.Scripts.
+	Scripts bool
+	// Progress, when set, has every counted chunk added to it, so that
+	// counters running concurrently can report their combined progress.
+	Progress *Progress
+	// Profile, when set, has the time counters spend reading, decoding
+	// and scanning added to it.
+	Profile *Profile
+	// Open, when set, opens the named inputs of CountFile, CountSmallFile
+	// and EstimateFile in place of os.Open, e.g. to confine them beneath
+	// a directory with Walker.Open.
+	Open func(name string) (*os.File, error)
  }
 
+// LineCount holds the counts of a single line for Options.OnLine. Bytes and
+// Chars exclude the newline but include a CR before it.
+type LineCount struct {
+	Line  uint64 // 1-based, counted from the start of the counted window
+	Bytes uint64
+	Chars uint64
+	Words uint64 // words starting on the line
+}
+
 // FileResult holds counts for a single file
  type FileResult struct {
 	Index         int
@@ -36,186 +204,268 @@ import (
 	Chars         uint64
 	MaxLineBytes  uint64
 	MaxLineChars  uint64
+	WhitespaceLines uint64
+	LFEndings       uint64 // '\n' not preceded by '\r'
+	CRLFEndings     uint64
+	CREndings       uint64 // '\r' not followed by '\n'
+	// LongestWord is the length in characters of the first longest word,
+	// which is kept in LongestWordText when Metrics.LongestWord is set.
+	// WordChars sums the lengths of all words, for the average.
+	LongestWord     uint64
+	LongestWordText string
+	WordChars       uint64
+	// MinLineWords and MaxLineWords are the fewest and most words on a
+	// line, and AllLines the number of lines they were taken from: every
+	// newline-terminated line and an unterminated last one. They need
+	// Metrics.WordsPerLine.
+	MinLineWords    uint64
+	MaxLineWords    uint64
+	AllLines        uint64
+	Emoji           uint64 // see Metrics.Emoji
+	// NumberTokens, URLTokens and EmailTokens count the words of each kind,
+	// for Metrics.TokenStats.
+	NumberTokens    uint64
+	URLTokens       uint64
+	EmailTokens     uint64
+	// NumericWords, AlphanumericWords and AlphabeticWords count the words
+	// of each kind, for Metrics.WordKinds.
+	NumericWords      uint64
+	AlphanumericWords uint64
+	AlphabeticWords   uint64
+	// CodeIdentifiers, CodeLiterals and CodeOperators count the tokens of
+	// source code, for Metrics.CodeTokens.
+	CodeIdentifiers uint64
+	CodeLiterals    uint64
+	CodeOperators   uint64
+	// MatchingLines and NonMatchingLines count the lines by whether
+	// Options.MatchLines matches them, for Metrics.MatchLines.
+	MatchingLines    uint64
+	NonMatchingLines uint64
+	// UniqueWords is Vocabulary.Len(); the set is kept so that totals
+	// count words shared between inputs once.
+	UniqueWords     uint64
+	Vocabulary      *WordSet
+	// NGrams maps each n-gram (words joined by a space) to its count, for
+	// Options.NGrams.
+	NGrams map[string]uint64
+	// Scripts maps Unicode script names (see unicode.Scripts and
+	// ScriptUnknown) to their characters, for Options.Scripts.
+	Scripts map[string]uint64
+	// LineLengths is a histogram of the lengths of the lines in bytes,
+	// '\n' excluded, for Metrics.LineLengths: element 0 counts the empty
+	// lines and element i those of 2^(i-1) to 2^i-1 bytes, up to the last
+	// element that counts any. An unterminated last line is one of them.
+	LineLengths []uint64
+	CharCounts    []uint64 // one per Options.CountChars entry
+	StringCounts  []uint64 // one per Options.CountStrings entry
+	RegexpCounts  []uint64 // one per Options.CountRegexps entry
+	Truncated     bool // counting stopped at Options.StopAfterLines/StopAfterBytes
+	// NoFinalNewline reports that the input is non-empty and its last line
+	// lacks a trailing '\n'. A total has no last line; its
+	// NoFinalNewlineFiles counts the inputs summed into it that had one.
+	NoFinalNewline      bool
+	NoFinalNewlineFiles uint64
+	// Overflow is set on a sum of results when one of its counts exceeded
+	// the range of uint64 and wrapped around; see Totals.Exact.
+	Overflow bool
 	Err           error
 	Duration      time.Duration
  }
 
+// AvgWordLength returns the mean word length in characters, or 0 when
+// there are no words. It needs Metrics.WordLengths.
+ func (r FileResult) AvgWordLength() float64 {
+	if r.Words == 0 {
+		return 0
+	}
+	return float64(r.WordChars) / float64(r.Words)
+ }
+
+// AvgLineWords returns the mean number of words per line, or 0 when there
+// are no lines. It needs Metrics.WordsPerLine.
+ func (r FileResult) AvgLineWords() float64 {
+	if r.AllLines == 0 {
+		return 0
+	}
+	return float64(r.Words) / float64(r.AllLines)
+ }
+
+// CodeTokens returns the number of source code tokens. It needs
+// Metrics.CodeTokens.
+ func (r FileResult) CodeTokens() uint64 {
+	return r.CodeIdentifiers + r.CodeLiterals + r.CodeOperators
+ }
+
+// FinalNewlinesMissing returns how many inputs of r lack a final newline:
+// 0 or 1 for an input, NoFinalNewlineFiles for a total.
+ func (r FileResult) FinalNewlinesMissing() uint64 {
+	if r.NoFinalNewline {
+		return r.NoFinalNewlineFiles + 1
+	}
+	return r.NoFinalNewlineFiles
+ }
+
+// TokensPerLine returns the mean number of source code tokens per line,
+// counting an unterminated last line, or 0 when there are no lines. It
+// needs Metrics.CodeTokens.
+ func (r FileResult) TokensPerLine() float64 {
+	lines := r.Lines + r.FinalNewlinesMissing()
+	if lines == 0 {
+		return 0
+	}
+	return float64(r.CodeTokens()) / float64(lines)
+ }
+
+// addLineWords merges the words-per-line distribution of o into r's.
+ func (r *FileResult) addLineWords(o FileResult) {
+	if o.AllLines == 0 {
+		return
+	}
+	if r.AllLines == 0 || o.MinLineWords < r.MinLineWords {
+		r.MinLineWords = o.MinLineWords
+	}
+	if o.MaxLineWords > r.MaxLineWords {
+		r.MaxLineWords = o.MaxLineWords
+	}
+	r.add(&r.AllLines, o.AllLines)
+ }
+
+// noteWord records a word of n characters if it is longer than the longest
+// so far; fed in stream order, the first of equally long words is kept.
+ func (r *FileResult) noteWord(n uint64, text string) {
+	if n > r.LongestWord {
+		r.LongestWord = n
+		r.LongestWordText = text
+	}
+ }
+
 // CountReader processes counts from an io.Reader
  func CountReader(r *bufio.Reader, m Metrics, opt Options) FileResult {
+	if opt.BufferSize <= 0 {
+		opt.BufferSize = defaultBufferSize
+	}
+	var win io.Reader = r
+	if opt.Offset > 0 {
+		if _, err := io.CopyN(io.Discard, r, opt.Offset); err != nil && err != io.EOF {
+			return FileResult{Err: err}
+		}
+	}
+	if opt.Length > 0 {
+		win = io.LimitReader(r, opt.Length)
+	}
+	if opt.Strip != StripNone {
+		win = opt.Strip.Reader(win)
+	}
+	src := win
+	if opt.StopAfterBytes > 0 {
+		src = io.LimitReader(win, int64(opt.StopAfterBytes))
+	}
 	buf := make([]byte, opt.BufferSize)
-	var res FileResult
-	prevSpace := true
-	var curLineBytes uint64
-	var curLineChars uint64
-	localeInfo := opt.Locale
-	asciiMode := localeInfo.IsCOrPOSIX || localeInfo.IsUTF8 // start in ASCII fast path when possible
-	carry := make([]byte, 0, 4)
-
+	c := NewCounter(m, opt)
+	linesLeft := opt.StopAfterLines
+	truncated := false
+	var readErr error
 	for {
-		n, err := r.Read(buf)
+		var t time.Time
+		if opt.Profile != nil {
+			t = time.Now()
+		}
+		n, err := src.Read(buf)
+		if opt.Profile != nil {
+			since(&opt.Profile.readWait, t)
+		}
 		if n > 0 {
 			chunk := buf[:n]
-			res.Bytes += uint64(n)
-
-			if asciiMode {
-				// If in ASCII mode, check for any non-ASCII to potentially switch
-				if !localeInfo.IsCOrPOSIX {
-					for _, b := range chunk {
-						if b >= 0x80 {
-							asciiMode = false
-							break
-						}
-					}
-				}
-				if asciiMode {
-					// Process with ASCII fast path
-					for _, b := range chunk {
-					if m.Lines && b == '\n' {
-						res.Lines++
-						if m.MaxLineBytes && curLineBytes > res.MaxLineBytes {
-							res.MaxLineBytes = curLineBytes
-						}
-						if m.MaxLineChars && curLineChars > res.MaxLineChars {
-							res.MaxLineChars = curLineChars
-						}
-						curLineBytes = 0
-						curLineChars = 0
-					} else {
-							if m.MaxLineBytes {
-								curLineBytes++
-							}
-							if m.MaxLineChars {
-								curLineChars++
-							}
-						}
-						// word counting in ASCII space
-						if m.Words {
-							isSpace := asciiSpace[b]
-							if !isSpace && prevSpace {
-								res.Words++
-							}
-							prevSpace = isSpace
-						}
-					}
-					// ASCII mode: chars equals bytes if requested
-					if m.Chars {
-						res.Chars += uint64(n)
-					}
-					continue
-				}
-			}
-
-			// UTF-8 or multibyte path: use rune decoding
-			data := append(carry, chunk...)
-			carry = carry[:0]
-			for len(data) > 0 {
-				r, size := utf8.DecodeRune(data)
-				if r == utf8.RuneError && size == 1 {
-					// invalid byte; count as one char and advance one
-					if m.Chars {
-						res.Chars++
-					}
-					if m.MaxLineBytes {
-						curLineBytes++
-					}
-					if m.MaxLineChars {
-						curLineChars++
-					}
-					b := data[0]
-					if m.Lines && b == '\n' {
-						res.Lines++
-						if m.MaxLineBytes && curLineBytes > res.MaxLineBytes {
-							res.MaxLineBytes = curLineBytes
-						}
-						if m.MaxLineChars && curLineChars > res.MaxLineChars {
-							res.MaxLineChars = curLineChars
-						}
-						curLineBytes = 0
-						curLineChars = 0
-					}
-					data = data[1:]
-					if m.Words {
-						sp := asciiSpace[b]
-						if !sp && prevSpace {
-							res.Words++
-						}
-						prevSpace = sp
-					}
-					continue
-				}
-
-				if m.Chars {
-					res.Chars++
-				}
-				if m.Words {
-					sp := unicode.IsSpace(r)
-					if !sp && prevSpace {
-						res.Words++
-					}
-					prevSpace = sp
-				}
-				if m.Lines {
-					// lines counted by raw '\n' byte, but we can infer from rune if newline
-					if r == '\n' {
-						res.Lines++
-						if m.MaxLineBytes && curLineBytes > res.MaxLineBytes {
-							res.MaxLineBytes = curLineBytes
-						}
-						if m.MaxLineChars && curLineChars > res.MaxLineChars {
-							res.MaxLineChars = curLineChars
-						}
-						curLineBytes = 0
-						curLineChars = 0
-					} else {
-						if m.MaxLineBytes {
-							curLineBytes += uint64(size)
-						}
-						if m.MaxLineChars {
-							curLineChars++
-						}
-					}
+			stop := false
+			if opt.StopAfterLines > 0 {
+				if cut := nthLineEnd(chunk, linesLeft); cut >= 0 {
+					stop = true
+					truncated = cut+1 < n
+					chunk = chunk[:cut+1]
 				} else {
-					// not counting lines, still need to advance max len counters per byte/char
-					if m.MaxLineBytes {
-						curLineBytes += uint64(size)
-					}
-					if m.MaxLineChars {
-						curLineChars++
-					}
+					linesLeft -= uint64(bytes.Count(chunk, []byte{'\n'}))
 				}
-
-				data = data[size:]
 			}
-			// keep any partial for the next read
-			if len(chunk) > 0 {
-				// Any leftover in data are partial rune bytes (0..3)
-				if len(data) > 0 {
-					carry = append(carry, data...)
+			_, _ = c.Write(chunk)
+			if err := c.Err(); err != nil {
+				readErr = err
+				break
+			}
+			if stop {
+				if !truncated {
+					truncated = hasMore(win)
 				}
+				break
 			}
 		}
 		if err == io.EOF {
+			if opt.StopAfterBytes > 0 && c.written == opt.StopAfterBytes {
+				truncated = hasMore(win)
+			}
 			break
 		}
 		if err != nil {
-			res.Err = err
+			readErr = err
 			break
 		}
 	}
-	// EOF: finalize max line metrics (for last line without trailing newline)
-	if res.Err == nil {
-		if m.MaxLineBytes && curLineBytes > res.MaxLineBytes {
-			res.MaxLineBytes = curLineBytes
-		}
-		if m.MaxLineChars && curLineChars > res.MaxLineChars {
-			res.MaxLineChars = curLineChars
-		}
+	if readErr != nil {
+		return c.partial(readErr)
 	}
+	if opt.OnProgress != nil {
+		opt.OnProgress(c.written, opt.TotalBytes)
+	}
+	c.lastLine()
+	res := c.Result()
+	res.Truncated = truncated
 	return res
  }
 
+// partial returns the counts so far of an input that ended with err,
+// skipping end-of-input finalization.
+func (c *Counter) partial(err error) FileResult {
+	var res FileResult
+	c.accs.store(&res)
+	res.NoFinalNewline = c.noFinalNewline()
+	res.Err = err
+	return res
+}
+
+// nthLineEnd returns the index of the n-th '\n' in b, or -1 if b has fewer.
+func nthLineEnd(b []byte, n uint64) int {
+	off := 0
+	for ; n > 0; n-- {
+		i := bytes.IndexByte(b[off:], '\n')
+		if i < 0 {
+			return -1
+		}
+		off += i + 1
+	}
+	return off - 1
+}
+
+// hasMore reports whether r has at least one more byte to read.
+func hasMore(r io.Reader) bool {
+	var one [1]byte
+	for {
+		n, err := r.Read(one[:])
+		if n > 0 {
+			return true
+		}
+		if err != nil {
+			return false
+		}
+	}
+}
+
 // CountBytes is a helper to count from an in-memory byte slice efficiently
  func CountBytes(b []byte, m Metrics, opt Options) FileResult {
+	if opt.TotalBytes == 0 {
+		opt.TotalBytes = uint64(len(b))
+	}
+	if opt.BufferSize <= 0 {
+		opt.BufferSize = defaultBufferSize
+	}
 	br := bufio.NewReaderSize(&bytesReader{b: b}, opt.BufferSize)
 	return CountReader(br, m, opt)
  }
@@ -2,6 +2,7 @@ package wc
 
 import (
 	"bufio"
+	"slices"
 	"strings"
 	"testing"
 
@@ -84,7 +85,7 @@ func TestCountBytes(t *testing.T) {
 			input:   "a\nbb\nccc\n",
 			metrics: Metrics{MaxLineBytes: true},
 			expected: FileResult{
-				MaxLineBytes: 9, // NOTE: Current implementation has a bug - it accumulates total length
+				MaxLineBytes: 3,
 			},
 		},
 		{
@@ -92,7 +93,7 @@ func TestCountBytes(t *testing.T) {
 			input:   "a\nbb\nccc\n",
 			metrics: Metrics{MaxLineChars: true},
 			expected: FileResult{
-				MaxLineChars: 9, // NOTE: Current implementation has a bug - it accumulates total length
+				MaxLineChars: 3,
 			},
 		},
 		{
@@ -207,6 +208,12 @@ func TestCountReader(t *testing.T) {
 			if tt.metrics.Chars && result.Chars != tt.expected.Chars {
 				t.Errorf("Chars: got %d, want %d", result.Chars, tt.expected.Chars)
 			}
+			if tt.metrics.MaxLineBytes && result.MaxLineBytes != tt.expected.MaxLineBytes {
+				t.Errorf("MaxLineBytes: got %d, want %d", result.MaxLineBytes, tt.expected.MaxLineBytes)
+			}
+			if tt.metrics.MaxLineChars && result.MaxLineChars != tt.expected.MaxLineChars {
+				t.Errorf("MaxLineChars: got %d, want %d", result.MaxLineChars, tt.expected.MaxLineChars)
+			}
 		})
 	}
 }
@@ -290,4 +297,155 @@ func BenchmarkCountBytesASCII(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		CountBytes(data, metrics, opts)
 	}
-}
\ No newline at end of file
+}
+
+// BenchmarkCountMetric times each of -c, -l, -m and -w alone on ASCII and
+// UTF-8 text, so a cost added to one path shows up on its own.
+func BenchmarkCountMetric(b *testing.B) {
+	inputs := []struct {
+		name string
+		data []byte
+	}{
+		{"ascii", []byte(strings.Repeat("hello world, the quick brown fox\n", 4096))},
+		{"utf8", []byte(strings.Repeat("héllo wörld, ünïcode tëxt — ok\n", 4096))},
+	}
+	metrics := []struct {
+		name string
+		m    Metrics
+	}{
+		{"c", Metrics{Bytes: true}},
+		{"l", Metrics{Lines: true}},
+		{"m", Metrics{Chars: true}},
+		{"w", Metrics{Words: true}},
+	}
+	opts := Options{BufferSize: 64 * 1024, Locale: locale.Info{IsUTF8: true}}
+	for _, in := range inputs {
+		for _, mt := range metrics {
+			b.Run(in.name+"/"+mt.name, func(b *testing.B) {
+				b.SetBytes(int64(len(in.data)))
+				for i := 0; i < b.N; i++ {
+					CountBytes(in.data, mt.m, opts)
+				}
+			})
+		}
+	}
+}
+
+func TestOnProgress(t *testing.T) {
+	data := []byte(strings.Repeat("abc\n", 100))
+	var calls int
+	var lastDone, lastTotal uint64
+	opts := Options{
+		BufferSize: 64,
+		Locale:     locale.Info{IsUTF8: true},
+		OnProgress: func(done, total uint64) {
+			if done < lastDone {
+				t.Errorf("progress went backwards: %d after %d", done, lastDone)
+			}
+			calls++
+			lastDone, lastTotal = done, total
+		},
+	}
+	CountBytes(data, Metrics{Lines: true}, opts)
+	if calls < 2 {
+		t.Errorf("expected several progress calls, got %d", calls)
+	}
+	if lastDone != uint64(len(data)) || lastTotal != uint64(len(data)) {
+		t.Errorf("final progress: got %d/%d, want %d/%d", lastDone, lastTotal, len(data), len(data))
+	}
+}
+
+func TestOnLine(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []LineCount
+	}{
+		{"", nil},
+		{"\n", []LineCount{{1, 0, 0, 0}}},
+		{"one two\n\r\nthree", []LineCount{{1, 7, 7, 2}, {2, 1, 1, 0}, {3, 5, 5, 1}}},
+		{"héllo wörld\n ü", []LineCount{{1, 13, 11, 2}, {2, 3, 2, 1}}},
+		{"ab\xc3", []LineCount{{1, 3, 3, 1}}},
+	}
+	for _, tt := range tests {
+		for _, bufSize := range []int{1, 2, 64} {
+			var got []LineCount
+			opts := Options{
+				BufferSize: bufSize,
+				Locale:     locale.Info{IsUTF8: true},
+				OnLine:     func(l LineCount) { got = append(got, l) },
+			}
+			res := CountBytes([]byte(tt.in), Metrics{Bytes: true}, opts)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("%q (buffer %d): got %v, want %v", tt.in, bufSize, got, tt.want)
+			}
+			if res.Bytes != uint64(len(tt.in)) {
+				t.Errorf("%q (buffer %d): got %d bytes", tt.in, bufSize, res.Bytes)
+			}
+		}
+	}
+}
+
+func TestStopAfter(t *testing.T) {
+	data := []byte("a\nbb\nccc\n")
+	tests := []struct {
+		name      string
+		lines     uint64
+		bytes     uint64
+		bufSize   int
+		wantLines uint64
+		wantBytes uint64
+		truncated bool
+	}{
+		{"lines within buffer", 2, 0, 64, 2, 5, true},
+		{"lines across buffers", 2, 0, 1, 2, 5, true},
+		{"lines exactly all", 3, 0, 4, 3, 9, false},
+		{"lines more than input", 10, 0, 4, 3, 9, false},
+		{"bytes", 0, 4, 64, 1, 4, true},
+		{"bytes exactly all", 0, 9, 2, 3, 9, false},
+		{"bytes before lines", 2, 3, 64, 1, 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{BufferSize: tt.bufSize, StopAfterLines: tt.lines, StopAfterBytes: tt.bytes}
+			got := CountReader(bufio.NewReaderSize(&bytesReader{b: data}, 16), Metrics{Lines: true, Bytes: true}, opts)
+			if got.Lines != tt.wantLines || got.Bytes != tt.wantBytes || got.Truncated != tt.truncated {
+				t.Errorf("got lines=%d bytes=%d truncated=%v, want %d %d %v", got.Lines, got.Bytes, got.Truncated, tt.wantLines, tt.wantBytes, tt.truncated)
+			}
+		})
+	}
+}
+
+func TestAbortGuards(t *testing.T) {
+	data := []byte("one two\nthree fourfive\nsix\n")
+	tests := []struct {
+		name      string
+		line      uint64
+		word      uint64
+		bufSize   int
+		wantErr   *GuardError
+		wantBytes uint64
+		wantLines uint64
+	}{
+		{"within bounds", 14, 8, 64, nil, 27, 3},
+		{"line", 10, 0, 64, &GuardError{Guard: "line", Limit: 10, Line: 2}, 18, 1},
+		{"line across buffers", 10, 0, 3, &GuardError{Guard: "line", Limit: 10, Line: 2}, 18, 1},
+		{"word", 0, 5, 64, &GuardError{Guard: "word", Limit: 5, Line: 2}, 19, 1},
+		{"word before line", 20, 5, 2, &GuardError{Guard: "word", Limit: 5, Line: 2}, 19, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{BufferSize: tt.bufSize, AbortLineBytes: tt.line, AbortWordBytes: tt.word}
+			got := CountReader(bufio.NewReaderSize(&bytesReader{b: data}, 16), Metrics{Lines: true, Bytes: true}, opts)
+			if tt.wantErr == nil {
+				if got.Err != nil {
+					t.Fatalf("unexpected error: %v", got.Err)
+				}
+			} else if ge, ok := got.Err.(*GuardError); !ok || *ge != *tt.wantErr {
+				t.Fatalf("err = %#v, want %#v", got.Err, tt.wantErr)
+			}
+			if got.Bytes != tt.wantBytes || got.Lines != tt.wantLines {
+				t.Errorf("got bytes=%d lines=%d, want %d %d", got.Bytes, got.Lines, tt.wantBytes, tt.wantLines)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package wc
+
+import (
+	"bytes"
+	"regexp"
+	"regexp/syntax"
+)
+
+// DefaultMaxMatchLength is the Options.MaxMatchLength used when it is not
+// set.
+const DefaultMaxMatchLength = 64 * 1024
+
+// matchWindow splits a stream into lines and hands them to a scan function
+// in bounded memory, however long the lines, and however the stream is cut
+// into writes. A line of up to 2*max bytes is scanned whole. A longer one
+// is scanned in windows of about 2*max bytes, each overlapping the next by
+// max bytes, so that every match of at most max bytes lies wholly inside
+// one window. The scanner counts the matches starting in [lo, hi) of the
+// window: the ones before lo were settled by the previous window, the ones
+// at hi or later are left to the next, which sees what follows them.
+//
+// A window after the first of a line keeps one byte before lo, for
+// expressions that look back at it (see findFrom).
+type matchWindow struct {
+	max int
+	buf []byte // the current line from byte off on
+	off uint64
+	lo  int // buf[:lo] is context, already scanned
+}
+
+// windowScanner looks for matches in a window of the line that starts at
+// byte off of it; last is set for the window that ends the line.
+type windowScanner interface {
+	scan(win []byte, lo, hi int, off uint64, last bool)
+}
+
+func newMatchWindow(max int) matchWindow {
+	if max <= 0 {
+		max = DefaultMaxMatchLength
+	}
+	return matchWindow{max: max}
+}
+
+func (w *matchWindow) write(p []byte, s windowScanner) {
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.add(p, s)
+			return
+		}
+		if len(w.buf) == 0 {
+			s.scan(p[:i], 0, i, 0, true) // scan whole lines in place
+		} else {
+			w.add(p[:i], s)
+			w.endLine(s)
+		}
+		p = p[i+1:]
+	}
+}
+
+// add appends part of the current line, scanning windows as they fill.
+func (w *matchWindow) add(p []byte, s windowScanner) {
+	for len(p) > 0 {
+		n := min(len(p), 2*w.max+1-len(w.buf))
+		w.buf = append(w.buf, p[:n]...)
+		p = p[n:]
+		if len(w.buf) > 2*w.max {
+			hi := len(w.buf) - w.max
+			s.scan(w.buf, w.lo, hi, w.off, false)
+			// keep the unsettled bytes and one byte of context
+			keep := hi - 1
+			w.off += uint64(keep)
+			w.buf = append(w.buf[:0], w.buf[keep:]...)
+			w.lo = 1
+		}
+	}
+}
+
+// endLine scans the rest of the current line and starts the next one.
+func (w *matchWindow) endLine(s windowScanner) {
+	s.scan(w.buf, w.lo, len(w.buf), w.off, true)
+	w.buf, w.off, w.lo = w.buf[:0], 0, 0
+}
+
+// pending reports whether an unterminated line has been started.
+func (w *matchWindow) pending() bool {
+	return len(w.buf) > 0 || w.off > 0
+}
+
+// windowRegexp is a regular expression searched in the windows of a
+// matchWindow.
+type windowRegexp struct {
+	*regexp.Regexp
+	// lookBehind is set when a match depends on the text before it: the
+	// expression holds ^, \A, \b or \B.
+	lookBehind bool
+}
+
+func newWindowRegexp(re *regexp.Regexp) windowRegexp {
+	wr := windowRegexp{Regexp: re, lookBehind: true}
+	if t, err := syntax.Parse(re.String(), syntax.Perl); err == nil {
+		wr.lookBehind = looksBehind(t)
+	}
+	return wr
+}
+
+func looksBehind(t *syntax.Regexp) bool {
+	switch t.Op {
+	case syntax.OpBeginLine, syntax.OpBeginText, syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return true
+	}
+	for _, sub := range t.Sub {
+		if looksBehind(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// findFrom returns the non-overlapping matches of re in win that start in
+// [from, hi), or [from, hi] when the window ends the line, searching from from on as FindAllIndex does after a match
+// ending there, and at most n of them if n >= 0. Without look-behind the
+// search just starts at from. Otherwise it starts a byte earlier, and a
+// match starting at that byte is dropped; in the rare case that it hides
+// a match starting right after it, that one is missed.
+func (re windowRegexp) findFrom(win []byte, from, hi, n int) [][]int {
+	base := from
+	if re.lookBehind && from > 0 {
+		base--
+	}
+	var out [][]int
+	for _, m := range re.FindAllIndex(win[base:], -1) {
+		m[0], m[1] = m[0]+base, m[1]+base
+		if m[0] < from {
+			continue
+		}
+		if m[0] >= hi && hi < len(win) || len(out) == n {
+			break
+		}
+		out = append(out, m)
+	}
+	return out
+}
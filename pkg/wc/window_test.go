@@ -0,0 +1,83 @@
+package wc
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestMatchWindowLongLines(t *testing.T) {
+	exprs := []string{`ab`, `a.?b`, `[ab]{2}`, `ba|abb`, `b$`, `^ab`, `x*`}
+	var count []*regexp.Regexp
+	for _, e := range exprs {
+		count = append(count, regexp.MustCompile(e))
+	}
+	match := regexp.MustCompile(`bbab`)
+	rng := rand.New(rand.NewSource(3))
+	for iter := 0; iter < 300; iter++ {
+		data := make([]byte, rng.Intn(200))
+		for i := range data {
+			data[i] = "aabb\n"[rng.Intn(5)]
+			if rng.Intn(8) > 0 && data[i] == '\n' {
+				data[i] = 'a' // mostly long lines
+			}
+		}
+		want := make([]uint64, len(count))
+		var matched, unmatched uint64
+		lines := bytes.Split(data, []byte("\n"))
+		if len(lines[len(lines)-1]) == 0 {
+			lines = lines[:len(lines)-1]
+		}
+		for _, line := range lines {
+			for i, re := range count {
+				want[i] += uint64(len(re.FindAllIndex(line, -1)))
+			}
+			if match.Match(line) {
+				matched++
+			} else {
+				unmatched++
+			}
+		}
+		for _, bufSize := range []int{1, 5, 64} {
+			opts := Options{BufferSize: bufSize, MatchLines: match, CountRegexps: count, MaxMatchLength: 4}
+			got := CountBytes(data, Metrics{MatchLines: true}, opts)
+			if !reflect.DeepEqual(got.RegexpCounts, want) {
+				t.Fatalf("%q, buffer %d: RegexpCounts = %v, want %v", data, bufSize, got.RegexpCounts, want)
+			}
+			if got.MatchingLines != matched || got.NonMatchingLines != unmatched {
+				t.Fatalf("%q, buffer %d: lines %d/%d, want %d/%d", data, bufSize,
+					got.MatchingLines, got.NonMatchingLines, matched, unmatched)
+			}
+		}
+	}
+}
+
+func TestMatchWindowLookBehind(t *testing.T) {
+	data := []byte(strings.Repeat("ab ", 1000) + "\n")
+	count := []*regexp.Regexp{regexp.MustCompile(`^ab`), regexp.MustCompile(`\bab\b`), regexp.MustCompile(`\Bb`)}
+	got := CountBytes(data, Metrics{}, Options{BufferSize: 7, CountRegexps: count, MaxMatchLength: 8})
+	if want := []uint64{1, 1000, 1000}; !reflect.DeepEqual(got.RegexpCounts, want) {
+		t.Errorf("RegexpCounts = %v, want %v", got.RegexpCounts, want)
+	}
+}
+
+func TestMatchWindowResultMidLine(t *testing.T) {
+	c := NewCounter(Metrics{MatchLines: true}, Options{
+		MatchLines:     regexp.MustCompile(`ERROR`),
+		CountRegexps:   []*regexp.Regexp{regexp.MustCompile(`E`)},
+		MaxMatchLength: 5,
+	})
+	c.Write([]byte(strings.Repeat("x", 40) + "ERROR" + strings.Repeat("y", 40)))
+	r := c.Result()
+	if r.MatchingLines != 1 || r.NonMatchingLines != 0 || r.RegexpCounts[0] != 1 {
+		t.Errorf("mid-line result: %d/%d lines, counts %v", r.MatchingLines, r.NonMatchingLines, r.RegexpCounts)
+	}
+	c.Write([]byte("E\n"))
+	r = c.Result()
+	if r.MatchingLines != 1 || r.NonMatchingLines != 0 || r.RegexpCounts[0] != 2 {
+		t.Errorf("after the line: %d/%d lines, counts %v", r.MatchingLines, r.NonMatchingLines, r.RegexpCounts)
+	}
+}
@@ -0,0 +1,392 @@
+package wc
+
+import "bytes"
+
+func init() {
+	registerMetric(&accMetric[uint64]{
+		name: "words",
+		on:   func(m Metrics, _ Options) bool { return m.Words || m.WordsPerLine || m.WordLengths },
+		newText: func(_ Metrics, opt Options, chunk bool) TextAccumulator[uint64] {
+			return &wordsAcc{wordScan: newWordScan(opt, chunk, false)}
+		},
+		store: func(r *FileResult, v uint64) { r.Words = v },
+	})
+	registerMetric(&accMetric[WordLengths]{
+		name: "word_lengths",
+		on:   func(m Metrics, _ Options) bool { return m.WordLengths || m.LongestWord },
+		newText: func(m Metrics, opt Options, chunk bool) TextAccumulator[WordLengths] {
+			return &wordLengthsAcc{wordScan: newWordScan(opt, chunk, m.LongestWord)}
+		},
+		store: func(r *FileResult, v WordLengths) {
+			r.LongestWord, r.LongestWordText, r.WordChars = v.Longest, v.LongestText, v.Chars
+		},
+	})
+}
+
+// wordScan finds the words of a text for the accumulators of word
+// metrics: runs of characters other than white space, as Options.Apostrophe,
+// Hyphen and MinWordLength have them. It passes every word to a wordSink
+// but those at the edges of a Cut text, which may run on into the texts
+// before and after it: it keeps them in Head and Tail until merge joins
+// them to their continuations or the text ends.
+type wordScan struct {
+	Cut          bool     // the text may begin inside a word
+	Started      bool     // a character was fed
+	StartsInWord bool     // with Cut, the first character is part of a word
+	Break        bool     // a character that is part of no word was fed
+	InWord       bool     // the last character fed is part of a word
+	Head         wordPart // with StartsInWord and Break, the first word
+	Tail         wordPart // with InWord, the word fed last so far
+
+	keepText bool // keep the text of words
+	minLen   uint64
+	apos     Apostrophe
+	hyphen   Hyphen
+	hyph     hyphenState
+	held     []byte // apostrophes ending the current word so far, see apostrophe
+}
+
+// wordPart is a word, or the part of one a text holds.
+type wordPart struct {
+	Chars uint64
+	Text  []byte `json:",omitempty"` // if the metric needs it
+}
+
+// wordSink receives the words a wordScan finds.
+type wordSink interface {
+	start()         // a word starts
+	drop()          // the word just ended is shorter than MinWordLength
+	end(w wordPart) // a word ended; w.Text is only valid during the call
+	line()          // a '\n' ended a line, after its word counting
+}
+
+// noWordEvents implements the wordSink methods an accumulator has no use
+// for.
+type noWordEvents struct{}
+
+func (noWordEvents) start()       {}
+func (noWordEvents) drop()        {}
+func (noWordEvents) end(wordPart) {}
+func (noWordEvents) line()        {}
+
+func newWordScan(opt Options, chunk, keepText bool) wordScan {
+	return wordScan{
+		Cut:      chunk,
+		keepText: keepText,
+		minLen:   uint64(opt.MinWordLength),
+		apos:     opt.Apostrophe,
+		hyphen:   opt.Hyphen,
+	}
+}
+
+// feed scans the characters of t, passing what it finds to sink.
+func (s *wordScan) feed(t *Text, sink wordSink) {
+	if !s.begin(t) {
+		return
+	}
+	rules := s.apos != ApostropheNone || s.hyphen != HyphenNone
+	if t.Bytes != nil {
+		for i, b := range t.Bytes {
+			if rules {
+				s.step(asciiSpace[b], t.Bytes[i:i+1], sink)
+			} else {
+				s.scanWord(asciiSpace[b], t.Bytes[i:i+1], sink)
+			}
+			if b == '\n' {
+				sink.line()
+			}
+		}
+		return
+	}
+	for _, c := range t.Chars {
+		if rules {
+			s.step(c.Space, t.text(c), sink)
+		} else {
+			s.scanWord(c.Space, t.text(c), sink)
+		}
+		if c.Rune == '\n' {
+			sink.line()
+		}
+	}
+}
+
+// begin notes the first character of the stream, if t holds it. It
+// reports whether t holds any.
+func (s *wordScan) begin(t *Text) bool {
+	if t.len() == 0 {
+		return false
+	}
+	if !s.Started {
+		s.Started = true
+		if t.Bytes != nil {
+			s.StartsInWord = s.Cut && !asciiSpace[t.Bytes[0]]
+		} else {
+			s.StartsInWord = s.Cut && !t.Chars[0].Space
+		}
+	}
+	return true
+}
+
+// counts reports whether count can scan for s: the words need no
+// rules applied and no text kept.
+func (s *wordScan) counts() bool {
+	return s.apos == ApostropheNone && s.hyphen == HyphenNone && s.minLen == 0 && !s.keepText
+}
+
+// count is feed for a sink that only counts the words started, which it
+// returns. It needs counts.
+func (s *wordScan) count(t *Text) uint64 {
+	if !s.begin(t) {
+		return 0
+	}
+	var n uint64
+	for _, b := range t.Bytes {
+		n += s.countChar(asciiSpace[b])
+	}
+	for _, c := range t.Chars {
+		n += s.countChar(c.Space)
+	}
+	return n
+}
+
+// countChar is scanWord for count, returning 1 if a word starts.
+func (s *wordScan) countChar(space bool) uint64 {
+	if space {
+		if s.InWord {
+			if s.Cut && s.StartsInWord && !s.Break {
+				s.Head.Chars = s.Tail.Chars
+			}
+			s.Tail.Chars = 0
+		}
+		s.Break, s.InWord = true, false
+		return 0
+	}
+	s.Tail.Chars++
+	if s.InWord {
+		return 0
+	}
+	s.InWord = true
+	return 1
+}
+
+// step scans a character, space or not, with the given text, after
+// Options.Apostrophe and Options.Hyphen have had their say.
+func (s *wordScan) step(space bool, text []byte, sink wordSink) {
+	if s.apos != ApostropheNone {
+		s.apostrophe(space, text, sink)
+		return
+	}
+	s.hyphenWord(space, text, sink)
+}
+
+// hyphenWord scans a character, space or not, with the given text, after
+// Options.Hyphen has had its say.
+func (s *wordScan) hyphenWord(space bool, text []byte, sink wordSink) {
+	if s.hyphen != HyphenNone {
+		var skip bool
+		if space, skip = s.hyphenate(space, text, sink); skip {
+			return
+		}
+	}
+	s.scanWord(space, text, sink)
+}
+
+// scanWord scans a character, space or not, with the given text.
+func (s *wordScan) scanWord(space bool, text []byte, sink wordSink) {
+	if space {
+		if s.InWord {
+			s.endWord(sink)
+		}
+		s.Break = true
+		s.InWord = false
+		return
+	}
+	if !s.InWord {
+		s.InWord = true
+		sink.start()
+	}
+	s.Tail.Chars++
+	if s.keepText {
+		s.Tail.Text = append(s.Tail.Text, text...)
+	}
+}
+
+func (s *wordScan) endWord(sink wordSink) {
+	switch {
+	case s.Tail.Chars < s.minLen:
+		sink.drop()
+	case s.Cut && s.StartsInWord && !s.Break:
+		// the first word may continue an earlier chunk; keep it apart
+		s.Head = wordPart{s.Tail.Chars, bytes.Clone(s.Tail.Text)}
+	default:
+		sink.end(s.Tail)
+	}
+	s.Tail.Chars, s.Tail.Text = 0, s.Tail.Text[:0]
+}
+
+// tailDropped reports whether MinWordLength drops the word the text ends
+// in, should it end there.
+func (s *wordScan) tailDropped() bool {
+	return s.InWord && s.Tail.Chars < s.minLen
+}
+
+// edges returns the words kept at the edges of the text, taken as
+// complete: Head, and Tail unless it is dropped. A missing one is empty.
+func (s *wordScan) edges() (head, tail wordPart) {
+	if s.InWord && !s.tailDropped() {
+		tail = s.Tail
+	}
+	return s.Head, tail
+}
+
+// merge returns the scan of s's text followed by n's, passing end, if not
+// nil, the words that the two complete between them. It also reports
+// whether a word straddles the boundary, and so was started on both sides.
+func (s *wordScan) merge(n *wordScan, end func(wordPart)) (wordScan, bool) {
+	switch {
+	case !s.Started:
+		return n.clone(), false
+	case !n.Started:
+		return s.clone(), false
+	}
+	out := wordScan{
+		Cut:          s.Cut,
+		Started:      true,
+		StartsInWord: s.StartsInWord,
+		Break:        s.Break || n.Break,
+		InWord:       n.InWord,
+		Head:         s.Head,
+		Tail:         n.Tail,
+		keepText:     s.keepText,
+	}
+	straddle := s.InWord && n.StartsInWord
+	switch {
+	case !n.Break:
+		// n is one word fragment, going on from the word s ends in if any
+		out.Tail = s.Tail.join(n.Tail)
+	case !s.Break:
+		// s is one word fragment, which the start of n completes
+		first := s.Tail.join(n.Head)
+		if s.Cut {
+			out.Head = first
+		} else if end != nil {
+			end(first)
+		}
+	case end == nil:
+	case straddle:
+		end(s.Tail.join(n.Head))
+	default:
+		if s.InWord {
+			end(s.Tail)
+		}
+		if n.StartsInWord {
+			end(n.Head)
+		}
+	}
+	out.Head.Text = bytes.Clone(out.Head.Text)
+	out.Tail.Text = bytes.Clone(out.Tail.Text)
+	return out, straddle
+}
+
+// clone returns a copy of s that can be fed independently.
+func (s *wordScan) clone() wordScan {
+	out := *s
+	out.Head.Text = bytes.Clone(s.Head.Text)
+	out.Tail.Text = bytes.Clone(s.Tail.Text)
+	out.held = bytes.Clone(s.held)
+	return out
+}
+
+// join returns w followed by n.
+func (w wordPart) join(n wordPart) wordPart {
+	return wordPart{w.Chars + n.Chars, append(bytes.Clone(w.Text), n.Text...)}
+}
+
+// wordsAcc counts words as they start, so that a word straddling two
+// chunks is counted in both until Merge subtracts it.
+type wordsAcc struct {
+	wordScan
+	noWordEvents
+	Words uint64
+}
+
+func (a *wordsAcc) FeedText(t *Text) {
+	if a.counts() {
+		a.Words += a.count(t)
+		return
+	}
+	a.feed(t, a)
+}
+
+func (a *wordsAcc) start() { a.Words++ }
+
+func (a *wordsAcc) drop() { a.Words-- }
+
+func (a *wordsAcc) Merge(next TextAccumulator[uint64]) TextAccumulator[uint64] {
+	b := next.(*wordsAcc)
+	out := &wordsAcc{Words: a.Words + b.Words}
+	var straddle bool
+	out.wordScan, straddle = a.wordScan.merge(&b.wordScan, nil)
+	if straddle {
+		out.Words--
+	}
+	return out
+}
+
+func (a *wordsAcc) Result() uint64 {
+	if a.tailDropped() {
+		return a.Words - 1
+	}
+	return a.Words
+}
+
+// WordLengths are the word length metrics of Metrics.WordLengths and
+// LongestWord: the length in characters of the first longest word, its
+// text for Metrics.LongestWord, and the sum of the lengths of all words.
+type WordLengths struct {
+	Longest     uint64
+	LongestText string
+	Chars       uint64
+}
+
+// note records a word of n characters if it is longer than the longest
+// so far.
+func (l *WordLengths) note(w wordPart) {
+	if w.Chars > l.Longest {
+		l.Longest, l.LongestText = w.Chars, string(w.Text)
+	}
+}
+
+// wordLengthsAcc measures words for WordLengths. Longest only covers the
+// words between Head and Tail, which Result notes in stream order.
+type wordLengthsAcc struct {
+	wordScan
+	noWordEvents
+	WordLengths
+}
+
+func (a *wordLengthsAcc) FeedText(t *Text) { a.feed(t, a) }
+
+func (a *wordLengthsAcc) end(w wordPart) {
+	a.Chars += w.Chars
+	a.note(w)
+}
+
+func (a *wordLengthsAcc) Merge(next TextAccumulator[WordLengths]) TextAccumulator[WordLengths] {
+	b := next.(*wordLengthsAcc)
+	out := &wordLengthsAcc{WordLengths: a.WordLengths}
+	out.wordScan, _ = a.wordScan.merge(&b.wordScan, out.end)
+	out.Chars += b.Chars
+	out.note(wordPart{b.Longest, []byte(b.LongestText)})
+	return out
+}
+
+func (a *wordLengthsAcc) Result() WordLengths {
+	head, tail := a.edges()
+	var out WordLengths
+	out.note(head)
+	out.note(wordPart{a.Longest, []byte(a.LongestText)})
+	out.note(tail)
+	out.Chars = a.Chars + head.Chars + tail.Chars
+	return out
+}